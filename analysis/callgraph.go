@@ -0,0 +1,248 @@
+// Package analysis provides static analysis of compiled GoScript programs
+package analysis
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lengzhao/goscript"
+)
+
+// NodeKind classifies the functions in a call graph
+type NodeKind string
+
+const (
+	// A function or method declared in the script
+	KindScript NodeKind = "script"
+
+	// A host function, including builtins such as len
+	KindHost NodeKind = "host"
+
+	// A function of an imported module (e.g., strings.ToUpper)
+	KindModule NodeKind = "module"
+
+	// A method on a value the script does not declare (e.g., a time value);
+	// the receiver type is unknown, so the name is "*.Method"
+	KindMethod NodeKind = "method"
+)
+
+// Node is a function in the call graph
+type Node struct {
+	// Name identifies the function: "add", "Rect.Area", "len",
+	// "strings.ToUpper" or "*.Format"
+	Name string
+	Kind NodeKind
+}
+
+// Edge is a call from one function to another
+type Edge struct {
+	From string
+	To   string
+
+	// Calls is the number of call sites
+	Calls int
+}
+
+// Graph is the static call graph of a program
+type Graph struct {
+	Nodes map[string]*Node
+	Edges []Edge
+}
+
+// CallGraph extracts which script functions call which script, host and
+// module functions. Calls through method selectors are resolved to every
+// script method with that name.
+func CallGraph(program *goscript.Program) (*Graph, error) {
+	file := program.AST()
+	if file == nil {
+		return nil, fmt.Errorf("program has no syntax tree")
+	}
+
+	b := &builder{
+		graph:   &Graph{Nodes: make(map[string]*Node)},
+		imports: make(map[string]string),
+		funcs:   make(map[string]bool),
+		methods: make(map[string][]string),
+		edges:   make(map[[2]string]int),
+	}
+	b.collectDecls(file)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			b.collectCalls(funcName(fn), fn.Body)
+		}
+	}
+	b.finish()
+	return b.graph, nil
+}
+
+// Callers returns the sorted names of the functions that call name directly
+func (g *Graph) Callers(name string) []string {
+	var callers []string
+	for _, edge := range g.Edges {
+		if edge.To == name {
+			callers = append(callers, edge.From)
+		}
+	}
+	sort.Strings(callers)
+	return callers
+}
+
+// Callees returns the sorted names of the functions name calls directly
+func (g *Graph) Callees(name string) []string {
+	var callees []string
+	for _, edge := range g.Edges {
+		if edge.From == name {
+			callees = append(callees, edge.To)
+		}
+	}
+	sort.Strings(callees)
+	return callees
+}
+
+// DOT renders the graph in Graphviz DOT format. Script functions are boxes,
+// host functions ellipses and module functions diamonds.
+func (g *Graph) DOT() string {
+	shapes := map[NodeKind]string{
+		KindScript: "box",
+		KindHost:   "ellipse",
+		KindModule: "diamond",
+		KindMethod: "ellipse",
+	}
+
+	names := make([]string, 0, len(g.Nodes))
+	for name := range g.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("digraph calls {\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "\t%s [shape=%s];\n", strconv.Quote(name), shapes[g.Nodes[name].Kind])
+	}
+	for _, edge := range g.Edges {
+		if edge.Calls > 1 {
+			fmt.Fprintf(&sb, "\t%s -> %s [label=%d];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), edge.Calls)
+		} else {
+			fmt.Fprintf(&sb, "\t%s -> %s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To))
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// builder accumulates a call graph
+type builder struct {
+	graph *Graph
+
+	// Imported package names mapped to module names (import paths)
+	imports map[string]string
+
+	// Declared functions, and declared methods by method name
+	funcs   map[string]bool
+	methods map[string][]string
+
+	edges map[[2]string]int
+}
+
+// collectDecls records imports and declared functions
+func (b *builder) collectDecls(file *ast.File) {
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		b.imports[name] = path
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := funcName(fn)
+		b.addNode(name, KindScript)
+		if fn.Recv == nil {
+			b.funcs[name] = true
+		} else {
+			b.methods[fn.Name.Name] = append(b.methods[fn.Name.Name], name)
+		}
+	}
+}
+
+// collectCalls records the calls made in a function body
+func (b *builder) collectCalls(caller string, body *ast.BlockStmt) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			kind := KindHost
+			if b.funcs[fun.Name] {
+				kind = KindScript
+			}
+			b.addEdge(caller, fun.Name, kind)
+		case *ast.SelectorExpr:
+			if x, ok := fun.X.(*ast.Ident); ok {
+				if module, isModule := b.imports[x.Name]; isModule {
+					b.addEdge(caller, module+"."+fun.Sel.Name, KindModule)
+					return true
+				}
+			}
+			if targets := b.methods[fun.Sel.Name]; len(targets) > 0 {
+				for _, target := range targets {
+					b.addEdge(caller, target, KindScript)
+				}
+			} else {
+				b.addEdge(caller, "*."+fun.Sel.Name, KindMethod)
+			}
+		}
+		return true
+	})
+}
+
+func (b *builder) addNode(name string, kind NodeKind) {
+	if _, exists := b.graph.Nodes[name]; !exists {
+		b.graph.Nodes[name] = &Node{Name: name, Kind: kind}
+	}
+}
+
+func (b *builder) addEdge(from, to string, kind NodeKind) {
+	b.addNode(to, kind)
+	b.edges[[2]string{from, to}]++
+}
+
+// finish sorts the edges by caller, then callee
+func (b *builder) finish() {
+	for key, calls := range b.edges {
+		b.graph.Edges = append(b.graph.Edges, Edge{From: key[0], To: key[1], Calls: calls})
+	}
+	sort.Slice(b.graph.Edges, func(i, j int) bool {
+		if b.graph.Edges[i].From != b.graph.Edges[j].From {
+			return b.graph.Edges[i].From < b.graph.Edges[j].From
+		}
+		return b.graph.Edges[i].To < b.graph.Edges[j].To
+	})
+}
+
+// funcName returns the graph name of a declared function: "add" for
+// functions and "Rect.Area" for methods (pointer receivers included)
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const callGraphSource = `
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+type Rect struct {
+	Width  int
+	Height int
+}
+
+func (r *Rect) Area() int {
+	return r.Width * r.Height
+}
+
+func label(s string) string {
+	return strings.ToUpper(s) + strings.ToUpper("!")
+}
+
+func main() {
+	r := Rect{Width: 2, Height: 3}
+	d := now()
+	return label("area") + d.Format("15:04") + fmt(r.Area())
+}
+`
+
+func compile(t *testing.T, source string) *goscript.Program {
+	t.Helper()
+	program, err := goscript.NewScript([]byte(source)).Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	return program
+}
+
+func TestCallGraph(t *testing.T) {
+	graph, err := CallGraph(compile(t, callGraphSource))
+	if err != nil {
+		t.Fatalf("Failed to build call graph: %v", err)
+	}
+
+	expected := []Edge{
+		{From: "label", To: "strings.ToUpper", Calls: 2},
+		{From: "main", To: "*.Format", Calls: 1},
+		{From: "main", To: "Rect.Area", Calls: 1},
+		{From: "main", To: "fmt", Calls: 1},
+		{From: "main", To: "label", Calls: 1},
+		{From: "main", To: "now", Calls: 1},
+	}
+	if !reflect.DeepEqual(graph.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, graph.Edges)
+	}
+
+	kinds := map[string]NodeKind{
+		"main":            KindScript,
+		"Rect.Area":       KindScript,
+		"now":             KindHost,
+		"strings.ToUpper": KindModule,
+		"*.Format":        KindMethod,
+	}
+	for name, kind := range kinds {
+		if node := graph.Nodes[name]; node == nil || node.Kind != kind {
+			t.Errorf("Expected %s to be a %s node, got %v", name, kind, node)
+		}
+	}
+
+	if callers := graph.Callers("strings.ToUpper"); !reflect.DeepEqual(callers, []string{"label"}) {
+		t.Errorf("Expected strings.ToUpper callers [label], got %v", callers)
+	}
+	if callees := graph.Callees("label"); !reflect.DeepEqual(callees, []string{"strings.ToUpper"}) {
+		t.Errorf("Expected label callees [strings.ToUpper], got %v", callees)
+	}
+}
+
+func TestCallGraphDOT(t *testing.T) {
+	graph, err := CallGraph(compile(t, callGraphSource))
+	if err != nil {
+		t.Fatalf("Failed to build call graph: %v", err)
+	}
+
+	dot := graph.DOT()
+	for _, want := range []string{
+		"digraph calls {",
+		`"main" [shape=box];`,
+		`"strings.ToUpper" [shape=diamond];`,
+		`"label" -> "strings.ToUpper" [label=2];`,
+		`"main" -> "label";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", want, dot)
+		}
+	}
+}
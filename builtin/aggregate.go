@@ -0,0 +1,228 @@
+package builtin
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// GroupBy groups the elements of a slice: groupBy(slice, "field.path") or
+// groupBy(slice, keyFn) with a script or host function. It returns a map from the
+// group key (formatted as a string) to the slice of elements in the group,
+// preserving their original order.
+func GroupBy(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("groupBy expects 2 arguments, got %d", len(args))
+	}
+	slice, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("groupBy: first argument must be a slice, got %T", args[0])
+	}
+	keyOf, err := keyFunc("groupBy", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]interface{})
+	for _, elem := range slice {
+		key, err := keyOf(elem)
+		if err != nil {
+			return nil, err
+		}
+		group, _ := groups[key].([]interface{})
+		groups[key] = append(group, elem)
+	}
+	return groups, nil
+}
+
+// CountBy counts the elements of a slice per group key, with the same key
+// arguments as GroupBy
+func CountBy(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("countBy expects 2 arguments, got %d", len(args))
+	}
+	slice, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("countBy: first argument must be a slice, got %T", args[0])
+	}
+	keyOf, err := keyFunc("countBy", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]interface{})
+	for _, elem := range slice {
+		key, err := keyOf(elem)
+		if err != nil {
+			return nil, err
+		}
+		count, _ := counts[key].(int)
+		counts[key] = count + 1
+	}
+	return counts, nil
+}
+
+// Sum returns the sum of a slice of numbers, or of a field of each element:
+// sum(slice) or sum(slice, "field.path"). The result is an int when every
+// value is an int, float64 otherwise.
+func Sum(args ...interface{}) (interface{}, error) {
+	values, err := aggregateValues("sum", args)
+	if err != nil {
+		return nil, err
+	}
+	intSum, floatSum, allInts := 0, 0.0, true
+	for _, v := range values {
+		if n, ok := v.(int); ok {
+			intSum += n
+			floatSum += float64(n)
+			continue
+		}
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("sum: non-numeric value %v (%T)", v, v)
+		}
+		allInts = false
+		floatSum += f
+	}
+	if allInts {
+		return intSum, nil
+	}
+	return floatSum, nil
+}
+
+// Avg returns the average of a slice of numbers, or of a field of each
+// element, as a float64
+func Avg(args ...interface{}) (interface{}, error) {
+	values, err := aggregateValues("avg", args)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("avg: empty slice")
+	}
+	total := 0.0
+	for _, v := range values {
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("avg: non-numeric value %v (%T)", v, v)
+		}
+		total += f
+	}
+	return total / float64(len(values)), nil
+}
+
+// Min returns the smallest of its arguments, as Go's min(a, b, ...), or the
+// smallest value of a slice, or of a field of each element
+func Min(args ...interface{}) (interface{}, error) {
+	return extremum("min", args, -1)
+}
+
+// Max returns the largest of its arguments, as Go's max(a, b, ...), or the
+// largest value of a slice, or of a field of each element
+func Max(args ...interface{}) (interface{}, error) {
+	return extremum("max", args, 1)
+}
+
+// extremum returns the value v for which compareValues(v, other) == sign
+// holds against every other value
+func extremum(name string, args []interface{}, sign int) (interface{}, error) {
+	// Without a leading slice the arguments are the values themselves
+	values := args
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s expects at least 1 argument", name)
+	}
+	if _, isSlice := args[0].([]interface{}); isSlice {
+		var err error
+		if values, err = aggregateValues(name, args); err != nil {
+			return nil, err
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s: empty slice", name)
+	}
+	best := values[0]
+	for _, v := range values[1:] {
+		cmp, err := compareValues(v, best)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if cmp == sign {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+// aggregateValues extracts the values to aggregate from (slice[, "field"]),
+// skipping elements whose field is missing
+func aggregateValues(name string, args []interface{}) ([]interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("%s expects 1 or 2 arguments, got %d", name, len(args))
+	}
+	slice, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: first argument must be a slice, got %T", name, args[0])
+	}
+	if len(args) == 1 {
+		return slice, nil
+	}
+
+	path, ok := args[1].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("%s: field path must be a non-empty string", name)
+	}
+	fields := strings.Split(path, ".")
+	values := make([]interface{}, 0, len(slice))
+	for _, elem := range slice {
+		if v := fieldByPath(elem, fields); v != nil {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// keyFunc builds the group key function from a field path or host function
+func keyFunc(name string, key interface{}) (func(interface{}) (string, error), error) {
+	switch k := key.(type) {
+	case string:
+		if k == "" {
+			return nil, fmt.Errorf("%s: field path must be a non-empty string", name)
+		}
+		fields := strings.Split(k, ".")
+		return func(elem interface{}) (string, error) {
+			return fmt.Sprint(fieldByPath(elem, fields)), nil
+		}, nil
+	}
+
+	if fn, ok := callableFunc(key); ok {
+		return hostKeyFunc(name, fn), nil
+	}
+	return nil, fmt.Errorf("%s: key must be a field path or function, got %T", name, key)
+}
+
+// callableFunc returns the function of a script function value or of a
+// host function of any type with the Function signature
+func callableFunc(value interface{}) (Function, bool) {
+	if fn, ok := value.(types.Callable); ok {
+		return fn.Call, true
+	}
+	rv := reflect.ValueOf(value)
+	functionType := reflect.TypeOf(Function(nil))
+	if rv.Kind() == reflect.Func && !rv.IsNil() && rv.Type().ConvertibleTo(functionType) {
+		return rv.Convert(functionType).Interface().(Function), true
+	}
+	return nil, false
+}
+
+// hostKeyFunc wraps a host function computing the group key of an element
+func hostKeyFunc(name string, fn Function) func(interface{}) (string, error) {
+	return func(elem interface{}) (string, error) {
+		key, err := fn(elem)
+		if err != nil {
+			return "", fmt.Errorf("%s: key function failed: %w", name, err)
+		}
+		return fmt.Sprint(key), nil
+	}
+}
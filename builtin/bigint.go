@@ -0,0 +1,183 @@
+package builtin
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Big integers. The bigint module creates arbitrary-precision integers
+// (*big.Int values) for arithmetic that would overflow int, such as ID
+// math. The VM applies +, -, *, /, % and the comparisons to them, also
+// mixed with ints; the results are new values, so big integers behave like
+// numbers and are never changed in place.
+
+// BigInt returns the big integer an int, rune or *big.Int stands for
+func BigInt(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, v != nil
+	case int:
+		return big.NewInt(int64(v)), true
+	case int32:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	}
+	return nil, false
+}
+
+// ParseBigInt parses an integer in the given base; base 0 accepts the
+// prefixes 0x, 0o and 0b and underscores between digits, as in Go literals
+func ParseBigInt(s string, base int) (*big.Int, error) {
+	if base != 0 && (base < 2 || base > 62) {
+		return nil, fmt.Errorf("invalid base %d", base)
+	}
+	n, ok := new(big.Int).SetString(strings.TrimSpace(s), base)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	return n, nil
+}
+
+// BigIntModule holds the functions of the bigint module
+var BigIntModule = map[string]types.Function{
+	"New": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("New function requires 1 argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return ParseBigInt(v, 10)
+		case float64:
+			if v != math.Trunc(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("New function requires an integer, got %v", v)
+			}
+			n, _ := big.NewFloat(v).Int(nil)
+			return n, nil
+		}
+		n, ok := BigInt(args[0])
+		if !ok {
+			return nil, fmt.Errorf("New function requires an integer or string, got %T", args[0])
+		}
+		return new(big.Int).Set(n), nil
+	},
+	"Parse": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("Parse function requires 2 arguments")
+		}
+		s, ok1 := args[0].(string)
+		base, ok2 := args[1].(int)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("Parse function requires a string and an int base")
+		}
+		return ParseBigInt(s, base)
+	},
+	"Text": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("Text function requires 2 arguments")
+		}
+		n, err := bigIntArg("Text", args[0])
+		if err != nil {
+			return nil, err
+		}
+		base, ok := args[1].(int)
+		if !ok || base < 2 || base > 62 {
+			return nil, fmt.Errorf("Text function requires a base from 2 to 62")
+		}
+		return n.Text(base), nil
+	},
+	"ToInt": func(args ...interface{}) (interface{}, error) {
+		n, err := bigIntSingle("ToInt", args)
+		if err != nil {
+			return nil, err
+		}
+		if !n.IsInt64() || n.Int64() > math.MaxInt || n.Int64() < math.MinInt {
+			return nil, fmt.Errorf("%s overflows int", n)
+		}
+		return int(n.Int64()), nil
+	},
+	"IsInt": func(args ...interface{}) (interface{}, error) {
+		n, err := bigIntSingle("IsInt", args)
+		if err != nil {
+			return nil, err
+		}
+		return n.IsInt64() && n.Int64() <= math.MaxInt && n.Int64() >= math.MinInt, nil
+	},
+	"Pow": func(args ...interface{}) (interface{}, error) {
+		x, y, err := bigIntPair("Pow", args)
+		if err != nil {
+			return nil, err
+		}
+		if y.Sign() < 0 {
+			return nil, fmt.Errorf("Pow function requires a non-negative exponent")
+		}
+		return new(big.Int).Exp(x, y, nil), nil
+	},
+	"Abs": func(args ...interface{}) (interface{}, error) {
+		n, err := bigIntSingle("Abs", args)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).Abs(n), nil
+	},
+	"Neg": func(args ...interface{}) (interface{}, error) {
+		n, err := bigIntSingle("Neg", args)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).Neg(n), nil
+	},
+	"Sign": func(args ...interface{}) (interface{}, error) {
+		n, err := bigIntSingle("Sign", args)
+		if err != nil {
+			return nil, err
+		}
+		return n.Sign(), nil
+	},
+	"Cmp": func(args ...interface{}) (interface{}, error) {
+		x, y, err := bigIntPair("Cmp", args)
+		if err != nil {
+			return nil, err
+		}
+		return x.Cmp(y), nil
+	},
+}
+
+// bigIntArg returns an integer argument as a big integer
+func bigIntArg(name string, arg interface{}) (*big.Int, error) {
+	n, ok := BigInt(arg)
+	if !ok {
+		return nil, fmt.Errorf("%s function requires integer arguments, got %T", name, arg)
+	}
+	return n, nil
+}
+
+// bigIntSingle checks that a function has one integer argument and
+// returns it as a big integer
+func bigIntSingle(name string, args []interface{}) (*big.Int, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s function requires 1 argument", name)
+	}
+	return bigIntArg(name, args[0])
+}
+
+// bigIntPair checks that a function has two integer arguments and returns
+// them as big integers
+func bigIntPair(name string, args []interface{}) (*big.Int, *big.Int, error) {
+	if len(args) != 2 {
+		return nil, nil, fmt.Errorf("%s function requires 2 arguments", name)
+	}
+	x, err := bigIntArg(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	y, err := bigIntArg(name, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return x, y, nil
+}
@@ -3,6 +3,8 @@ package builtin
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"reflect"
 
 	"github.com/lengzhao/goscript/types"
@@ -14,11 +16,38 @@ type Function = types.Function
 
 // BuiltInFunctions holds all built-in functions
 var BuiltInFunctions = map[string]Function{
-	"len":   Len,
-	"make":  Make,
-	"copy":  Copy,
-	"print": Print,
-	"int":   Int,
+	"len":     Len,
+	"cap":     Cap,
+	"append":  Append,
+	"make":    Make,
+	"copy":    Copy,
+	"print":   Print,
+	"println": Println,
+	"int":     Int,
+	"string":  String,
+	"rune":    Rune,
+	"byte":    Byte,
+	"[]byte":  Bytes,
+	"[]rune":  Runes,
+	"sortBy":  SortBy,
+
+	// Data processing helpers
+	"groupBy": GroupBy,
+	"countBy": CountBy,
+	"sum":     Sum,
+	"avg":     Avg,
+	"min":     Min,
+	"max":     Max,
+
+	// Sets
+	"newSet":     SetNew,
+	"add":        SetAdd,
+	"has":        SetHas,
+	"delete":     Delete,
+	"union":      SetUnion,
+	"intersect":  SetIntersect,
+	"difference": SetDifference,
+	"toSlice":    SetToSlice,
 }
 
 // Len returns the length of a string, array, slice, or map
@@ -28,12 +57,23 @@ func Len(args ...interface{}) (interface{}, error) {
 	}
 
 	switch v := args[0].(type) {
+	case nil:
+		// A nil slice or map
+		return 0, nil
 	case string:
 		return len(v), nil
 	case []interface{}:
 		return len(v), nil
 	case map[string]interface{}:
 		return len(v), nil
+	case map[interface{}]interface{}:
+		return len(v), nil
+	case *types.Struct:
+		return len(v.Fields), nil
+	case *Set:
+		return v.Len(), nil
+	case *Container:
+		return v.Len(), nil
 	default:
 		// Use reflection for other types
 		rv := reflect.ValueOf(v)
@@ -46,36 +86,81 @@ func Len(args ...interface{}) (interface{}, error) {
 	}
 }
 
-// Make creates a slice, map, or channel
-func Make(args ...interface{}) (interface{}, error) {
+// Cap returns the capacity of a slice
+func Cap(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("cap expects 1 argument, got %d", len(args))
+	}
+
+	switch v := args[0].(type) {
+	case nil:
+		return 0, nil
+	case []interface{}:
+		return cap(v), nil
+	}
+	rv := reflect.ValueOf(args[0])
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rv.Cap(), nil
+	default:
+		return nil, fmt.Errorf("cap: unsupported type %T", args[0])
+	}
+}
+
+// Append appends elements to a slice, growing its capacity as needed, and
+// returns the resulting slice. The slice may be nil.
+func Append(args ...interface{}) (interface{}, error) {
 	if len(args) < 1 {
-		return nil, fmt.Errorf("make expects at least 1 argument, got %d", len(args))
+		return nil, fmt.Errorf("append expects at least 1 argument, got %d", len(args))
 	}
 
-	// For now, we only support slice creation
-	// In a full implementation, we would need type information
-	switch args[0].(type) {
-	case string:
-		// Create a slice
-		if len(args) >= 2 {
-			if size, ok := args[1].(int); ok {
-				slice := make([]interface{}, size)
-				// If capacity is provided, use it
-				if len(args) >= 3 {
-					if cap, ok := args[2].(int); ok && cap > size {
-						// Extend the slice to capacity
-						for i := size; i < cap; i++ {
-							slice = append(slice, nil)
-						}
-					}
-				}
-				return slice, nil
+	var slice []interface{}
+	switch v := args[0].(type) {
+	case nil:
+	case []interface{}:
+		slice = v
+	case []byte:
+		for i, arg := range args[1:] {
+			c, err := ByteValue(arg)
+			if err != nil {
+				return nil, fmt.Errorf("append: argument %d: %w", i+2, err)
 			}
+			v = append(v, c)
 		}
-		return make([]interface{}, 0), nil
+		return v, nil
 	default:
+		return nil, fmt.Errorf("append: first argument must be a slice, got %T", args[0])
+	}
+	return append(slice, args[1:]...), nil
+}
+
+// Make creates a slice of nil values. The compiler emits dedicated
+// instructions for make([]T), make(map[K]V) and make(chan T); this function
+// handles calls with a type name, e.g. make("[]int", 2, 10).
+func Make(args ...interface{}) (interface{}, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("make expects at least 1 argument, got %d", len(args))
+	}
+	if _, ok := args[0].(string); !ok {
 		return nil, fmt.Errorf("make: unsupported type %T", args[0])
 	}
+
+	size, capacity := 0, 0
+	if len(args) >= 2 {
+		n, ok := args[1].(int)
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("make: invalid length %v", args[1])
+		}
+		size, capacity = n, n
+	}
+	if len(args) >= 3 {
+		n, ok := args[2].(int)
+		if !ok || n < size {
+			return nil, fmt.Errorf("make: invalid capacity %v", args[2])
+		}
+		capacity = n
+	}
+	return make([]interface{}, size, capacity), nil
 }
 
 // Copy copies elements from a source slice to a destination slice
@@ -84,32 +169,49 @@ func Copy(args ...interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("copy expects 2 arguments, got %d", len(args))
 	}
 
+	// Bytes are copied into byte slices from byte slices and strings
+	if dst, ok := args[0].([]byte); ok {
+		switch src := args[1].(type) {
+		case []byte:
+			return copy(dst, src), nil
+		case string:
+			return copy(dst, src), nil
+		}
+		return nil, fmt.Errorf("copy: cannot copy %T into []byte", args[1])
+	}
+
 	dst, ok1 := args[0].([]interface{})
 	src, ok2 := args[1].([]interface{})
-	if !ok1 || !ok2 {
+	if !ok1 && args[0] != nil || !ok2 && args[1] != nil {
 		return nil, fmt.Errorf("copy: both arguments must be slices")
 	}
 
-	// Copy elements
-	count := 0
-	for i := 0; i < len(dst) && i < len(src); i++ {
-		dst[i] = src[i]
-		count++
-	}
-
-	return count, nil
+	// Copy elements, which may overlap
+	return copy(dst, src), nil
 }
 
 // Print prints the arguments to stdout
 func Print(args ...interface{}) (interface{}, error) {
-	for i, arg := range args {
-		if i > 0 {
-			fmt.Print(" ")
+	return NewPrint(nil)(args...)
+}
+
+// NewPrint returns a print function writing to w (os.Stdout if nil). It
+// separates the arguments with spaces and ends the line.
+func NewPrint(w io.Writer) Function {
+	return func(args ...interface{}) (interface{}, error) {
+		out := w
+		if out == nil {
+			out = os.Stdout
 		}
-		fmt.Print(arg)
+		_, err := fmt.Fprintln(out, args...)
+		return nil, err
 	}
-	fmt.Println()
-	return nil, nil
+}
+
+// Println prints the arguments to stdout. It behaves like print, which
+// already separates the arguments with spaces and ends the line.
+func Println(args ...interface{}) (interface{}, error) {
+	return Print(args...)
 }
 
 // Int converts a value to an integer
@@ -131,3 +233,75 @@ func Int(args ...interface{}) (interface{}, error) {
 		return 0, fmt.Errorf("int: unsupported type %T", v)
 	}
 }
+
+// String converts a value to a string: string(b) copies the bytes of a
+// byte slice, string(r) encodes a rune or integer code point as UTF-8 and
+// string(rs) joins a slice of runes
+func String(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("string expects 1 argument, got %d", len(args))
+	}
+
+	switch v := args[0].(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case int32:
+		return string(v), nil
+	case int:
+		return string(rune(v)), nil
+	case []interface{}:
+		runes := make([]rune, len(v))
+		for i, elem := range v {
+			switch r := elem.(type) {
+			case int32:
+				runes[i] = r
+			case int:
+				runes[i] = rune(r)
+			default:
+				return nil, fmt.Errorf("string: element %d: cannot use %v (%T) as rune", i, elem, elem)
+			}
+		}
+		return string(runes), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %v (%T) to string", v, v)
+	}
+}
+
+// Rune converts an integer to a rune (int32)
+func Rune(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("rune expects 1 argument, got %d", len(args))
+	}
+
+	switch v := args[0].(type) {
+	case int32:
+		return v, nil
+	case int:
+		return int32(v), nil
+	case uint8:
+		return int32(v), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %v (%T) to rune", v, v)
+	}
+}
+
+// Byte converts an integer to a byte, keeping its low 8 bits as Go does.
+// Bytes are ints in scripts, so the result is an int from 0 to 255.
+func Byte(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("byte expects 1 argument, got %d", len(args))
+	}
+
+	switch v := args[0].(type) {
+	case int:
+		return int(uint8(v)), nil
+	case int32:
+		return int(uint8(v)), nil
+	case uint8:
+		return int(v), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %v (%T) to byte", v, v)
+	}
+}
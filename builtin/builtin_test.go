@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -60,8 +61,8 @@ func TestMake(t *testing.T) {
 	}
 	if slice, ok := result.([]interface{}); !ok {
 		t.Errorf("Expected slice, got %T", result)
-	} else if len(slice) != 5 {
-		t.Errorf("Expected slice of length 5, got %d", len(slice))
+	} else if len(slice) != 2 || cap(slice) != 5 {
+		t.Errorf("Expected slice of length 2 and capacity 5, got %d and %d", len(slice), cap(slice))
 	}
 
 	// Test with no arguments
@@ -71,6 +72,34 @@ func TestMake(t *testing.T) {
 	}
 }
 
+func TestAppend(t *testing.T) {
+	// Appending to a nil slice creates one
+	result, err := Append(nil, 1, 2)
+	if err != nil {
+		t.Errorf("Append failed: %v", err)
+	}
+	slice, ok := result.([]interface{})
+	if !ok || len(slice) != 2 || slice[0] != 1 || slice[1] != 2 {
+		t.Errorf("Expected [1 2], got %v", result)
+	}
+
+	// Appending beyond the capacity grows the slice
+	base := make([]interface{}, 1, 1)
+	result, err = Append(base, 3)
+	if err != nil {
+		t.Errorf("Append failed: %v", err)
+	}
+	if slice, ok := result.([]interface{}); !ok || len(slice) != 2 || cap(slice) < 2 {
+		t.Errorf("Expected a grown slice, got %v", result)
+	}
+
+	// Test with a non-slice
+	_, err = Append("a", 1)
+	if err == nil {
+		t.Error("Expected error for a non-slice")
+	}
+}
+
 func TestCopy(t *testing.T) {
 	// Test copy
 	src := []interface{}{1, 2, 3, 4, 5}
@@ -272,3 +301,186 @@ func TestGetModuleFunctions(t *testing.T) {
 		t.Error("nonexistent module should not exist")
 	}
 }
+
+func TestSortBy(t *testing.T) {
+	people := []interface{}{
+		map[string]interface{}{"name": "Carol", "info": map[string]interface{}{"age": 35}},
+		map[string]interface{}{"name": "Alice", "info": map[string]interface{}{"age": 30.5}},
+		map[string]interface{}{"name": "Bob"},
+		map[string]interface{}{"name": "Dave", "info": map[string]interface{}{"age": 20}},
+	}
+
+	names := func(slice []interface{}) []string {
+		result := make([]string, len(slice))
+		for i, p := range slice {
+			result[i] = p.(map[string]interface{})["name"].(string)
+		}
+		return result
+	}
+
+	result, err := SortBy(people, "info.age")
+	if err != nil {
+		t.Fatalf("SortBy failed: %v", err)
+	}
+	if got := names(result.([]interface{})); !reflect.DeepEqual(got, []string{"Dave", "Alice", "Carol", "Bob"}) {
+		t.Errorf("Unexpected ascending order: %v", got)
+	}
+
+	result, err = SortBy(people, "name", false)
+	if err != nil {
+		t.Fatalf("SortBy failed: %v", err)
+	}
+	if got := names(result.([]interface{})); !reflect.DeepEqual(got, []string{"Dave", "Carol", "Bob", "Alice"}) {
+		t.Errorf("Unexpected descending order: %v", got)
+	}
+
+	mixed := []interface{}{
+		map[string]interface{}{"v": 1},
+		map[string]interface{}{"v": "a"},
+	}
+	if _, err := SortBy(mixed, "v"); err == nil {
+		t.Error("Expected error comparing int and string")
+	}
+}
+
+func TestGroupByAndAggregates(t *testing.T) {
+	sales := []interface{}{
+		map[string]interface{}{"region": "east", "amount": 10},
+		map[string]interface{}{"region": "west", "amount": 5},
+		map[string]interface{}{"region": "east", "amount": 7},
+	}
+
+	groups, err := GroupBy(sales, "region")
+	if err != nil {
+		t.Fatalf("GroupBy failed: %v", err)
+	}
+	east := groups.(map[string]interface{})["east"].([]interface{})
+	if len(east) != 2 || east[1].(map[string]interface{})["amount"] != 7 {
+		t.Errorf("Unexpected east group: %v", east)
+	}
+
+	byParity, err := GroupBy([]interface{}{1, 2, 3}, func(args ...interface{}) (interface{}, error) {
+		return args[0].(int)%2 == 0, nil
+	})
+	if err != nil {
+		t.Fatalf("GroupBy with key function failed: %v", err)
+	}
+	if len(byParity.(map[string]interface{})["false"].([]interface{})) != 2 {
+		t.Errorf("Unexpected parity groups: %v", byParity)
+	}
+
+	counts, err := CountBy(sales, "region")
+	if err != nil {
+		t.Fatalf("CountBy failed: %v", err)
+	}
+	if !reflect.DeepEqual(counts, map[string]interface{}{"east": 2, "west": 1}) {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+
+	tests := []struct {
+		name     string
+		fn       Function
+		args     []interface{}
+		expected interface{}
+	}{
+		{"sum field", Sum, []interface{}{sales, "amount"}, 22},
+		{"sum mixed", Sum, []interface{}{[]interface{}{1, 2.5}}, 3.5},
+		{"avg", Avg, []interface{}{east, "amount"}, 8.5},
+		{"min", Min, []interface{}{sales, "amount"}, 5},
+		{"max", Max, []interface{}{[]interface{}{"b", "c", "a"}}, "c"},
+	}
+	for _, tt := range tests {
+		result, err := tt.fn(tt.args...)
+		if err != nil {
+			t.Errorf("%s failed: %v", tt.name, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, result)
+		}
+	}
+
+	if _, err := Avg([]interface{}{}); err == nil {
+		t.Error("Expected error for avg of empty slice")
+	}
+}
+
+func TestSet(t *testing.T) {
+	a, err := SetNew(1, 2, 3, 2)
+	if err != nil {
+		t.Fatalf("SetNew failed: %v", err)
+	}
+	b, _ := SetNew(2, 3, 4)
+
+	if n, _ := Len(a); n != 3 {
+		t.Errorf("Expected 3 items, got %v", n)
+	}
+	if has, _ := SetHas(a, 2); has != true {
+		t.Error("Expected set to contain 2")
+	}
+
+	tests := []struct {
+		name     string
+		fn       Function
+		expected []interface{}
+	}{
+		{"union", SetUnion, []interface{}{1, 2, 3, 4}},
+		{"intersect", SetIntersect, []interface{}{2, 3}},
+		{"difference", SetDifference, []interface{}{1}},
+	}
+	for _, tt := range tests {
+		result, err := tt.fn(a, b)
+		if err != nil {
+			t.Errorf("%s failed: %v", tt.name, err)
+			continue
+		}
+		if got := result.(*Set).ToSlice(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	Delete(a, 2)
+	SetAdd(a, 5)
+	if got, _ := SetToSlice(a); !reflect.DeepEqual(got, []interface{}{1, 3, 5}) {
+		t.Errorf("Expected [1 3 5] after delete and add, got %v", got)
+	}
+
+	if _, err := SetAdd(a, []interface{}{1}); err == nil {
+		t.Error("Expected error adding an unhashable value")
+	}
+
+	m := map[string]interface{}{"k": 1}
+	Delete(m, "k")
+	if len(m) != 0 {
+		t.Errorf("Expected delete to remove the map key, got %v", m)
+	}
+}
+
+func TestContainerRingBuffer(t *testing.T) {
+	c := NewContainer(KindDeque, 0)
+	// Interleave pushes at both ends past the initial buffer size
+	for i := 0; i < 20; i++ {
+		if i%2 == 0 {
+			c.PushBack(i)
+		} else {
+			c.PushFront(i)
+		}
+	}
+	if c.Len() != 20 {
+		t.Fatalf("Expected 20 items, got %d", c.Len())
+	}
+	front, _ := c.PopFront()
+	back, _ := c.PopBack()
+	if front != 19 || back != 18 {
+		t.Errorf("Expected front 19 and back 18, got %v and %v", front, back)
+	}
+
+	q := NewContainer(KindQueue, 1)
+	q.Push("x")
+	if err := q.Push("y"); err == nil {
+		t.Error("Expected error pushing onto a full queue")
+	}
+	if _, err := NewContainer(KindStack, 0).Pop(); err == nil {
+		t.Error("Expected error popping an empty stack")
+	}
+}
@@ -0,0 +1,306 @@
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Byte slices. []byte values are kept as Go byte slices rather than
+// []interface{}, so binary payloads take one byte per element and pass to
+// and from the host unchanged. Scripts read their elements as ints from 0
+// to 255, and store ints and runes in that range.
+
+// ByteValue returns the byte an element value stands for: an int, rune or
+// byte from 0 to 255
+func ByteValue(value interface{}) (byte, error) {
+	var n int
+	switch v := value.(type) {
+	case int:
+		n = v
+	case int32:
+		n = int(v)
+	case int64:
+		n = int(v)
+	case uint8:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("cannot use %v (%T) as byte value", value, value)
+	}
+	if n < 0 || n > 255 {
+		return 0, fmt.Errorf("cannot use %d as byte value (overflows)", n)
+	}
+	return byte(n), nil
+}
+
+// Bytes converts a value to a byte slice: []byte(s) copies the bytes of a
+// string or byte slice, and a slice of byte values is packed into one
+func Bytes(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("[]byte expects 1 argument, got %d", len(args))
+	}
+	switch v := args[0].(type) {
+	case nil:
+		return []byte(nil), nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return append([]byte(nil), v...), nil
+	case []interface{}:
+		b := make([]byte, len(v))
+		for i, elem := range v {
+			c, err := ByteValue(elem)
+			if err != nil {
+				return nil, fmt.Errorf("[]byte: element %d: %w", i, err)
+			}
+			b[i] = c
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %v (%T) to []byte", args[0], args[0])
+	}
+}
+
+// Runes converts a string to a slice of its runes: []rune(s)
+func Runes(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("[]rune expects 1 argument, got %d", len(args))
+	}
+	var s string
+	switch v := args[0].(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return nil, fmt.Errorf("cannot convert %v (%T) to []rune", args[0], args[0])
+	}
+	runes := make([]interface{}, 0, len(s))
+	for _, r := range s {
+		runes = append(runes, r)
+	}
+	return runes, nil
+}
+
+// Buffer is a growable byte buffer for scripts, created with
+// bytes.NewBuffer and bytes.NewBufferString. Scripts call its methods
+// (buf.WriteString("x"), buf.Bytes()) like those of host objects.
+type Buffer struct {
+	buf bytes.Buffer
+}
+
+// NewBuffer creates a buffer holding a copy of b
+func NewBuffer(b []byte) *Buffer {
+	buffer := &Buffer{}
+	buffer.buf.Write(b)
+	return buffer
+}
+
+// Write appends the bytes of p and returns their number
+func (b *Buffer) Write(p []byte) int {
+	n, _ := b.buf.Write(p)
+	return n
+}
+
+// WriteString appends the bytes of s and returns their number
+func (b *Buffer) WriteString(s string) int {
+	n, _ := b.buf.WriteString(s)
+	return n
+}
+
+// WriteByte appends a byte
+func (b *Buffer) WriteByte(c byte) error {
+	return b.buf.WriteByte(c)
+}
+
+// WriteRune appends the UTF-8 encoding of r and returns its length
+func (b *Buffer) WriteRune(r rune) int {
+	n, _ := b.buf.WriteRune(r)
+	return n
+}
+
+// Bytes returns a copy of the unread bytes, so the script cannot change
+// the buffer through it
+func (b *Buffer) Bytes() []byte {
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+// String returns the unread bytes as a string
+func (b *Buffer) String() string {
+	return b.buf.String()
+}
+
+// Len returns the number of unread bytes
+func (b *Buffer) Len() int {
+	return b.buf.Len()
+}
+
+// Truncate discards all but the first n unread bytes
+func (b *Buffer) Truncate(n int) error {
+	if n < 0 || n > b.buf.Len() {
+		return fmt.Errorf("truncation out of range: %d", n)
+	}
+	b.buf.Truncate(n)
+	return nil
+}
+
+// Reset empties the buffer
+func (b *Buffer) Reset() {
+	b.buf.Reset()
+}
+
+// Bytes module functions
+var BytesModule = map[string]types.Function{
+	"Contains": func(args ...interface{}) (interface{}, error) {
+		b, sub, err := bytesPair("Contains", args)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Contains(b, sub), nil
+	},
+	"Equal": func(args ...interface{}) (interface{}, error) {
+		a, b, err := bytesPair("Equal", args)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Equal(a, b), nil
+	},
+	"Compare": func(args ...interface{}) (interface{}, error) {
+		a, b, err := bytesPair("Compare", args)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Compare(a, b), nil
+	},
+	"Index": func(args ...interface{}) (interface{}, error) {
+		b, sep, err := bytesPair("Index", args)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Index(b, sep), nil
+	},
+	"HasPrefix": func(args ...interface{}) (interface{}, error) {
+		b, prefix, err := bytesPair("HasPrefix", args)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.HasPrefix(b, prefix), nil
+	},
+	"HasSuffix": func(args ...interface{}) (interface{}, error) {
+		b, suffix, err := bytesPair("HasSuffix", args)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.HasSuffix(b, suffix), nil
+	},
+	"Split": func(args ...interface{}) (interface{}, error) {
+		b, sep, err := bytesPair("Split", args)
+		if err != nil {
+			return nil, err
+		}
+		parts := bytes.Split(b, sep)
+		result := make([]interface{}, len(parts))
+		for i, part := range parts {
+			// Copy the parts, as they share the array of b
+			result[i] = append([]byte{}, part...)
+		}
+		return result, nil
+	},
+	"Join": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("Join function requires 2 arguments")
+		}
+		parts, ok := args[0].([]interface{})
+		if !ok && args[0] != nil {
+			return nil, fmt.Errorf("Join function requires a slice of byte slices, got %T", args[0])
+		}
+		sep, err := bytesArg("Join", args[1])
+		if err != nil {
+			return nil, err
+		}
+		slices := make([][]byte, len(parts))
+		for i, part := range parts {
+			if slices[i], err = bytesArg("Join", part); err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return bytes.Join(slices, sep), nil
+	},
+	"ToUpper": func(args ...interface{}) (interface{}, error) {
+		b, err := bytesSingle("ToUpper", args)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.ToUpper(b), nil
+	},
+	"ToLower": func(args ...interface{}) (interface{}, error) {
+		b, err := bytesSingle("ToLower", args)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.ToLower(b), nil
+	},
+	"TrimSpace": func(args ...interface{}) (interface{}, error) {
+		b, err := bytesSingle("TrimSpace", args)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, bytes.TrimSpace(b)...), nil
+	},
+	"NewBuffer": func(args ...interface{}) (interface{}, error) {
+		b, err := bytesSingle("NewBuffer", args)
+		if err != nil {
+			return nil, err
+		}
+		return NewBuffer(b), nil
+	},
+	"NewBufferString": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("NewBufferString function requires 1 argument")
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("NewBufferString function requires a string, got %T", args[0])
+		}
+		return NewBuffer([]byte(s)), nil
+	},
+}
+
+// bytesArg returns a byte slice argument; nil is an empty slice
+func bytesArg(name string, arg interface{}) ([]byte, error) {
+	switch v := arg.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s function requires []byte arguments, got %T", name, arg)
+	}
+}
+
+// bytesSingle checks that a function has one byte slice argument and
+// returns it
+func bytesSingle(name string, args []interface{}) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s function requires 1 argument", name)
+	}
+	return bytesArg(name, args[0])
+}
+
+// bytesPair checks that a function has two byte slice arguments and
+// returns them
+func bytesPair(name string, args []interface{}) ([]byte, []byte, error) {
+	if len(args) != 2 {
+		return nil, nil, fmt.Errorf("%s function requires 2 arguments", name)
+	}
+	a, err := bytesArg(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := bytesArg(name, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
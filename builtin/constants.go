@@ -0,0 +1,64 @@
+package builtin
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// ModuleConstants holds the constants exported by builtin modules, keyed by
+// module name and then by constant name. Scripts see integer constants as
+// ints when they fit an int; 64-bit limits are int64 and uint64 values, so
+// that they are the same on every platform.
+var ModuleConstants = map[string]map[string]interface{}{
+	"math": {
+		"E":       math.E,
+		"Pi":      math.Pi,
+		"Phi":     math.Phi,
+		"Sqrt2":   math.Sqrt2,
+		"SqrtE":   math.SqrtE,
+		"SqrtPi":  math.SqrtPi,
+		"SqrtPhi": math.SqrtPhi,
+		"Ln2":     math.Ln2,
+		"Log2E":   math.Log2E,
+		"Ln10":    math.Ln10,
+		"Log10E":  math.Log10E,
+
+		"MaxFloat32":             math.MaxFloat32,
+		"SmallestNonzeroFloat32": math.SmallestNonzeroFloat32,
+		"MaxFloat64":             math.MaxFloat64,
+		"SmallestNonzeroFloat64": math.SmallestNonzeroFloat64,
+
+		"MaxInt":    math.MaxInt,
+		"MinInt":    math.MinInt,
+		"MaxInt8":   math.MaxInt8,
+		"MinInt8":   math.MinInt8,
+		"MaxInt16":  math.MaxInt16,
+		"MinInt16":  math.MinInt16,
+		"MaxInt32":  math.MaxInt32,
+		"MinInt32":  math.MinInt32,
+		"MaxInt64":  int64(math.MaxInt64),
+		"MinInt64":  int64(math.MinInt64),
+		"MaxUint8":  math.MaxUint8,
+		"MaxUint16": math.MaxUint16,
+		"MaxUint32": uint64(math.MaxUint32),
+		"MaxUint64": uint64(math.MaxUint64),
+		"MaxUint":   uint64(math.MaxUint),
+	},
+	"strconv": {
+		"IntSize": strconv.IntSize,
+	},
+	"time": {
+		"RFC3339":  time.RFC3339,
+		"DateTime": time.DateTime,
+		"DateOnly": time.DateOnly,
+		"TimeOnly": time.TimeOnly,
+		"Kitchen":  time.Kitchen,
+	},
+}
+
+// GetModuleConstant returns a constant exported by a builtin module
+func GetModuleConstant(moduleName, name string) (interface{}, bool) {
+	value, exists := ModuleConstants[moduleName][name]
+	return value, exists
+}
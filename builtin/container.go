@@ -0,0 +1,276 @@
+package builtin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// ContainerKind selects which ends of a Container are used by Push and Pop
+type ContainerKind int
+
+const (
+	// KindDeque allows pushing and popping at both ends
+	KindDeque ContainerKind = iota
+
+	// KindStack pushes and pops at the back (LIFO)
+	KindStack
+
+	// KindQueue pushes at the back and pops at the front (FIFO)
+	KindQueue
+)
+
+// Container is a double-ended queue backed by a growable ring buffer, so
+// pushes and pops at either end are O(1). A positive capacity bounds the
+// number of items; pushing onto a full container is an error.
+type Container struct {
+	kind     ContainerKind
+	capacity int
+
+	buf   []interface{}
+	head  int
+	count int
+}
+
+// NewContainer creates an empty container (capacity 0 means unbounded)
+func NewContainer(kind ContainerKind, capacity int) *Container {
+	return &Container{kind: kind, capacity: capacity, buf: make([]interface{}, 8)}
+}
+
+// Len returns the number of items
+func (c *Container) Len() int {
+	return c.count
+}
+
+// PushBack appends an item at the back
+func (c *Container) PushBack(item interface{}) error {
+	if err := c.reserve(); err != nil {
+		return err
+	}
+	c.buf[(c.head+c.count)%len(c.buf)] = item
+	c.count++
+	return nil
+}
+
+// PushFront inserts an item at the front
+func (c *Container) PushFront(item interface{}) error {
+	if err := c.reserve(); err != nil {
+		return err
+	}
+	c.head = (c.head - 1 + len(c.buf)) % len(c.buf)
+	c.buf[c.head] = item
+	c.count++
+	return nil
+}
+
+// PopBack removes and returns the item at the back
+func (c *Container) PopBack() (interface{}, error) {
+	if c.count == 0 {
+		return nil, fmt.Errorf("pop from empty container")
+	}
+	idx := (c.head + c.count - 1) % len(c.buf)
+	item := c.buf[idx]
+	c.buf[idx] = nil
+	c.count--
+	return item, nil
+}
+
+// PopFront removes and returns the item at the front
+func (c *Container) PopFront() (interface{}, error) {
+	if c.count == 0 {
+		return nil, fmt.Errorf("pop from empty container")
+	}
+	item := c.buf[c.head]
+	c.buf[c.head] = nil
+	c.head = (c.head + 1) % len(c.buf)
+	c.count--
+	return item, nil
+}
+
+// Front returns the item at the front without removing it
+func (c *Container) Front() (interface{}, error) {
+	if c.count == 0 {
+		return nil, fmt.Errorf("peek at empty container")
+	}
+	return c.buf[c.head], nil
+}
+
+// Back returns the item at the back without removing it
+func (c *Container) Back() (interface{}, error) {
+	if c.count == 0 {
+		return nil, fmt.Errorf("peek at empty container")
+	}
+	return c.buf[(c.head+c.count-1)%len(c.buf)], nil
+}
+
+// Push adds an item at the back
+func (c *Container) Push(item interface{}) error {
+	return c.PushBack(item)
+}
+
+// Pop removes the next item: the back for stacks and deques, the front
+// for queues
+func (c *Container) Pop() (interface{}, error) {
+	if c.kind == KindQueue {
+		return c.PopFront()
+	}
+	return c.PopBack()
+}
+
+// Peek returns the item Pop would remove without removing it
+func (c *Container) Peek() (interface{}, error) {
+	if c.kind == KindQueue {
+		return c.Front()
+	}
+	return c.Back()
+}
+
+// ToSlice returns the items from front to back
+func (c *Container) ToSlice() []interface{} {
+	result := make([]interface{}, c.count)
+	for i := range result {
+		result[i] = c.buf[(c.head+i)%len(c.buf)]
+	}
+	return result
+}
+
+// String returns the items formatted from front to back
+func (c *Container) String() string {
+	parts := make([]string, c.count)
+	for i, item := range c.ToSlice() {
+		parts[i] = fmt.Sprint(item)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// reserve makes room for one more item, enforcing the capacity
+func (c *Container) reserve() error {
+	if c.capacity > 0 && c.count >= c.capacity {
+		return fmt.Errorf("container is full (capacity %d)", c.capacity)
+	}
+	if c.count < len(c.buf) {
+		return nil
+	}
+	grown := make([]interface{}, len(c.buf)*2)
+	copy(grown, c.ToSlice())
+	c.buf = grown
+	c.head = 0
+	return nil
+}
+
+// Container module functions
+var ContainerModule = map[string]types.Function{
+	"NewDeque": func(args ...interface{}) (interface{}, error) {
+		return newContainerFromArgs("NewDeque", KindDeque, args)
+	},
+	"NewStack": func(args ...interface{}) (interface{}, error) {
+		return newContainerFromArgs("NewStack", KindStack, args)
+	},
+	"NewQueue": func(args ...interface{}) (interface{}, error) {
+		return newContainerFromArgs("NewQueue", KindQueue, args)
+	},
+	"Push": func(args ...interface{}) (interface{}, error) {
+		return containerPush("Push", args, (*Container).Push)
+	},
+	"PushBack": func(args ...interface{}) (interface{}, error) {
+		return containerPush("PushBack", args, (*Container).PushBack)
+	},
+	"PushFront": func(args ...interface{}) (interface{}, error) {
+		return containerPush("PushFront", args, (*Container).PushFront)
+	},
+	"Pop": func(args ...interface{}) (interface{}, error) {
+		return containerGet("Pop", args, (*Container).Pop)
+	},
+	"PopBack": func(args ...interface{}) (interface{}, error) {
+		return containerGet("PopBack", args, (*Container).PopBack)
+	},
+	"PopFront": func(args ...interface{}) (interface{}, error) {
+		return containerGet("PopFront", args, (*Container).PopFront)
+	},
+	"Peek": func(args ...interface{}) (interface{}, error) {
+		return containerGet("Peek", args, (*Container).Peek)
+	},
+	"Front": func(args ...interface{}) (interface{}, error) {
+		return containerGet("Front", args, (*Container).Front)
+	},
+	"Back": func(args ...interface{}) (interface{}, error) {
+		return containerGet("Back", args, (*Container).Back)
+	},
+	"Len": func(args ...interface{}) (interface{}, error) {
+		c, err := containerArg("Len", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return c.Len(), nil
+	},
+	"IsEmpty": func(args ...interface{}) (interface{}, error) {
+		c, err := containerArg("IsEmpty", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return c.Len() == 0, nil
+	},
+	"ToSlice": func(args ...interface{}) (interface{}, error) {
+		c, err := containerArg("ToSlice", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return c.ToSlice(), nil
+	},
+}
+
+// newContainerFromArgs creates a container with an optional capacity argument
+func newContainerFromArgs(name string, kind ContainerKind, args []interface{}) (interface{}, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("%s function requires at most 1 argument", name)
+	}
+	capacity := 0
+	if len(args) == 1 {
+		n, ok := args[0].(int)
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("%s capacity must be a non-negative int", name)
+		}
+		capacity = n
+	}
+	return NewContainer(kind, capacity), nil
+}
+
+// containerArg checks the argument count and returns the container in args[0]
+func containerArg(name string, args []interface{}, count int) (*Container, error) {
+	if len(args) != count {
+		return nil, fmt.Errorf("%s function requires %d argument(s)", name, count)
+	}
+	c, ok := args[0].(*Container)
+	if !ok {
+		return nil, fmt.Errorf("%s function requires a container, got %T", name, args[0])
+	}
+	return c, nil
+}
+
+// containerPush implements the push functions: Push(c, item)
+func containerPush(name string, args []interface{}, push func(*Container, interface{}) error) (interface{}, error) {
+	c, err := containerArg(name, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	if c.kind != KindDeque && name != "Push" {
+		return nil, fmt.Errorf("%s is only supported on deques", name)
+	}
+	if err := push(c, args[1]); err != nil {
+		return nil, err
+	}
+	return c.Len(), nil
+}
+
+// containerGet implements the pop and peek functions: Pop(c)
+func containerGet(name string, args []interface{}, get func(*Container) (interface{}, error)) (interface{}, error) {
+	c, err := containerArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if c.kind != KindDeque && name != "Pop" && name != "Peek" {
+		return nil, fmt.Errorf("%s is only supported on deques", name)
+	}
+	return get(c)
+}
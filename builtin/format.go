@@ -0,0 +1,111 @@
+package builtin
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Script struct values (*types.Struct) print as TypeName{field: value, ...}
+// through their String method. The fmt functions also make %T report the
+// script type name instead of the Go type, and format numbers by the verb
+// rather than by the Go type the VM holds them in: integral floats (as
+// decoded from JSON) print with %d, and ints with %f, %e and %g.
+
+// sprintf formats like fmt.Sprintf, with the verbs adapted to script values
+func sprintf(format string, args []interface{}) string {
+	format, args = rewriteVerbs(format, args)
+	return fmt.Sprintf(format, args...)
+}
+
+// errorf formats like fmt.Errorf, so %w wraps an error that hosts can
+// unwrap with errors.Is and errors.As
+func errorf(format string, args []interface{}) error {
+	format, args = rewriteVerbs(format, args)
+	return fmt.Errorf(format, args...)
+}
+
+// typeName returns the type name reported by %T: the script type name of
+// struct values, the Go type name otherwise
+func typeName(value interface{}) string {
+	if name, isStruct := types.StructTypeName(value); isStruct {
+		return name
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// rewriteVerbs replaces each %T directive of format by %s applied to the
+// type name of its argument, and converts the numbers formatted by integer
+// and float verbs. Directives with explicit argument indexes are left to
+// fmt.
+func rewriteVerbs(format string, args []interface{}) (string, []interface{}) {
+	if !strings.Contains(format, "%") {
+		return format, args
+	}
+
+	var b strings.Builder
+	rewritten := append([]interface{}(nil), args...)
+	argIndex := 0
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		b.WriteByte(c)
+		if c != '%' {
+			continue
+		}
+
+		// Flags, width and precision; * consumes an argument
+		j := i + 1
+		for ; j < len(format); j++ {
+			c := format[j]
+			if c == '*' {
+				argIndex++
+			} else if c == '[' {
+				return format, args
+			} else if !strings.ContainsRune("+-# 0.", rune(c)) && (c < '0' || c > '9') {
+				break
+			}
+		}
+		if j == len(format) {
+			b.WriteString(format[i+1:])
+			break
+		}
+		verb := format[j]
+		b.WriteString(format[i+1 : j])
+		switch {
+		case verb == '%':
+			b.WriteByte('%')
+		case verb == 'T':
+			b.WriteByte('s')
+			if argIndex < len(rewritten) {
+				rewritten[argIndex] = typeName(rewritten[argIndex])
+			}
+			argIndex++
+		default:
+			b.WriteByte(verb)
+			if argIndex < len(rewritten) {
+				rewritten[argIndex] = verbArg(verb, rewritten[argIndex])
+			}
+			argIndex++
+		}
+		i = j
+	}
+	return b.String(), rewritten
+}
+
+// verbArg converts a number to the type a verb formats: integral float64
+// values to int for %d, ints to float64 for the float verbs
+func verbArg(verb byte, arg interface{}) interface{} {
+	switch v := arg.(type) {
+	case int:
+		if strings.IndexByte("eEfFgG", verb) >= 0 {
+			return float64(v)
+		}
+	case float64:
+		if verb == 'd' && v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int(v)
+		}
+	}
+	return arg
+}
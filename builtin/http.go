@@ -0,0 +1,210 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Defaults of the limits of the http module
+const (
+	DefaultHTTPTimeout         = 10 * time.Second
+	DefaultHTTPMaxResponseSize = 1 << 20
+)
+
+// HTTPOptions selects what the http module may do. The zero value allows
+// no host: every request fails with an error wrapping fs.ErrPermission.
+type HTTPOptions struct {
+	// AllowedHosts lists the hosts requests can be sent to, by name
+	// ("api.example.com"), name and port ("localhost:8080") or domain
+	// ("*.example.com" matches its subdomains). Redirects are followed to
+	// allowed hosts only.
+	AllowedHosts []string
+
+	// MaxResponseSize is the maximum size of a response body in bytes
+	// (DefaultHTTPMaxResponseSize if 0)
+	MaxResponseSize int64
+
+	// Timeout limits each request, including reading the response body
+	// (DefaultHTTPTimeout if 0)
+	Timeout time.Duration
+
+	// Client sends the requests (a client with the default transport if
+	// nil)
+	Client *http.Client
+}
+
+// NewHTTPModule returns the functions of the http module, sending requests
+// within the limits of opts. It is registered by the host, e.g.
+// RegisterModule("http", NewHTTPModule(HTTPOptions{AllowedHosts: hosts})).
+//
+// Requests and responses are script structs of type http.Request (Method,
+// URL, Header, Body) and http.Response (StatusCode, Status, Header, Body).
+// Headers are maps from canonical names to the first value, and bodies are
+// strings. As in Go, a response with an error status is not an error.
+func NewHTTPModule(opts HTTPOptions) map[string]types.Function {
+	return map[string]types.Function{
+		"Get": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("Get", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return opts.do("Get", newHTTPRequest(http.MethodGet, strs[0], ""))
+		},
+		"Post": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("Post", args, 3)
+			if err != nil {
+				return nil, err
+			}
+			req := newHTTPRequest(http.MethodPost, strs[0], strs[2])
+			req.Fields["Header"].(map[string]interface{})["Content-Type"] = strs[1]
+			return opts.do("Post", req)
+		},
+		// NewRequest returns a request to send with Do, whose fields can be
+		// changed first, e.g. req.Header["Authorization"] = token
+		"NewRequest": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("NewRequest", args, 3)
+			if err != nil {
+				return nil, err
+			}
+			return newHTTPRequest(strs[0], strs[1], strs[2]), nil
+		},
+		"Do": func(args ...interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("Do function requires 1 argument")
+			}
+			req, ok := args[0].(*types.Struct)
+			if !ok || req.Type != "http.Request" {
+				return nil, fmt.Errorf("Do function requires an http.Request, got %T", args[0])
+			}
+			return opts.do("Do", req)
+		},
+	}
+}
+
+// newHTTPRequest returns an http.Request script struct
+func newHTTPRequest(method, rawURL, body string) *types.Struct {
+	req := types.NewStruct("http.Request")
+	req.Fields["Method"] = method
+	req.Fields["URL"] = rawURL
+	req.Fields["Header"] = make(map[string]interface{})
+	req.Fields["Body"] = body
+	return req
+}
+
+// do sends an http.Request script struct and returns the response as an
+// http.Response script struct
+func (opts HTTPOptions) do(fn string, req *types.Struct) (interface{}, error) {
+	method, _ := req.Fields["Method"].(string)
+	rawURL, _ := req.Fields["URL"].(string)
+	body, _ := req.Fields["Body"].(string)
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("http.%s: %w", fn, err)
+	}
+	if err := opts.checkHost(target); err != nil {
+		return nil, fmt.Errorf("http.%s %s: %w", fn, rawURL, err)
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, target.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("http.%s: %w", fn, err)
+	}
+	if header, ok := req.Fields["Header"].(map[string]interface{}); ok {
+		for name, value := range header {
+			httpReq.Header.Set(name, fmt.Sprint(value))
+		}
+	}
+
+	resp, err := opts.client().Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("http.%s: %w", fn, err)
+	}
+	defer resp.Body.Close()
+
+	maxSize := opts.MaxResponseSize
+	if maxSize <= 0 {
+		maxSize = DefaultHTTPMaxResponseSize
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("http.%s: %w", fn, err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("http.%s %s: response body exceeds %d bytes", fn, rawURL, maxSize)
+	}
+
+	result := types.NewStruct("http.Response")
+	result.Fields["StatusCode"] = resp.StatusCode
+	result.Fields["Status"] = resp.Status
+	result.Fields["Header"] = headerMap(resp.Header)
+	result.Fields["Body"] = string(data)
+	return result, nil
+}
+
+// client returns the client of the options, which follows redirects to
+// allowed hosts only
+func (opts HTTPOptions) client() *http.Client {
+	client := http.Client{}
+	if opts.Client != nil {
+		client = *opts.Client
+	}
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := opts.checkHost(req.URL); err != nil {
+			return fmt.Errorf("redirect to %s: %w", req.URL, err)
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &client
+}
+
+// checkHost returns an error wrapping fs.ErrPermission unless the options
+// allow requests to the host of u
+func (opts HTTPOptions) checkHost(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q: %w", u.Scheme, fs.ErrPermission)
+	}
+	hostname := u.Hostname()
+	for _, allowed := range opts.AllowedHosts {
+		switch {
+		case allowed == u.Host, allowed == hostname:
+			return nil
+		case strings.HasPrefix(allowed, "*.") && strings.HasSuffix(hostname, allowed[1:]):
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not allowed: %w", u.Host, fs.ErrPermission)
+}
+
+// headerMap converts a header to a map from canonical names to the first
+// value
+func headerMap(header http.Header) map[string]interface{} {
+	result := make(map[string]interface{}, len(header))
+	for name := range header {
+		result[name] = header.Get(name)
+	}
+	return result
+}
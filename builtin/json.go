@@ -0,0 +1,356 @@
+package builtin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Script structs in JSON. Given the struct types of the program (see
+// ModuleOptions), the json module marshals a script struct as Go marshals
+// a struct: its fields in declaration order, named and omitted as their
+// json tags say (`json:"name,omitempty"`, `json:"-"`), the fields of
+// untagged embedded structs promoted, and unset fields as their zero
+// value. Unmarshal(data, &s) fills a struct the same way, converting values
+// to the declared types of its fields: objects decode to the struct types
+// of fields, integral numbers to int and other numbers to float64. Numbers
+// in fields without a declared type decode to int when lossless.
+
+// jsonCodec converts values between scripts and JSON
+type jsonCodec struct {
+	mode    types.NumberMode
+	structs types.StructTypes
+}
+
+// structType returns the fields of a script struct type, if known
+func (c jsonCodec) structType(name string) ([]types.StructField, bool) {
+	if c.structs == nil {
+		return nil, false
+	}
+	return c.structs(name)
+}
+
+// jsonObject is a JSON object with its keys in order
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// set sets the value of a key, keeping the position of existing keys
+func (o *jsonObject) set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON marshals the object with its keys in order
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encode replaces the script structs of known types in a value with the
+// JSON objects they marshal to
+func (c jsonCodec) encode(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *types.Struct:
+		fields, known := c.structType(v.Type)
+		if !known {
+			return v
+		}
+		obj := &jsonObject{values: make(map[string]interface{}, len(fields))}
+		c.encodeFields(v, fields, obj, false)
+		return obj
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = c.encode(item)
+		}
+		return items
+	case map[string]interface{}:
+		items := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			items[key] = c.encode(item)
+		}
+		return items
+	}
+	return value
+}
+
+// encodeFields adds the fields of a struct to obj. Promoted fields do not
+// replace the fields of the outer struct.
+func (c jsonCodec) encodeFields(s *types.Struct, fields []types.StructField, obj *jsonObject, promoted bool) {
+	for _, field := range fields {
+		name, omitEmpty, skip := jsonTag(field)
+		if skip {
+			continue
+		}
+		value, set := s.Fields[field.Name]
+		if field.Embedded && name == "" {
+			if inner, ok := value.(*types.Struct); ok {
+				if innerFields, known := c.structType(inner.Type); known {
+					c.encodeFields(inner, innerFields, obj, true)
+					continue
+				}
+			}
+			if !set {
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if !set {
+			value = zeroValue(field.Type)
+		}
+		if omitEmpty && isEmptyValue(value) {
+			continue
+		}
+		if _, exists := obj.values[name]; exists && promoted {
+			continue
+		}
+		obj.set(name, c.encode(value))
+	}
+}
+
+// decodeStruct fills a script struct from a decoded JSON value
+func (c jsonCodec) decodeStruct(target *types.Struct, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("cannot unmarshal %s into %s", jsonKind(value), target.Type)
+	}
+	fields, known := c.structType(target.Type)
+	if !known {
+		for key, item := range obj {
+			target.Fields[key] = ConvertNumbers(item, c.untypedMode())
+		}
+		return nil
+	}
+	return c.decodeFields(target, fields, obj)
+}
+
+// decodeFields sets the fields of a struct from the keys of a JSON object,
+// matching field names exactly first and then case-insensitively, as Go
+func (c jsonCodec) decodeFields(target *types.Struct, fields []types.StructField, obj map[string]interface{}) error {
+	for _, field := range fields {
+		name, _, skip := jsonTag(field)
+		if skip {
+			continue
+		}
+		if field.Embedded && name == "" {
+			if innerFields, known := c.structType(field.Type); known {
+				inner, ok := target.Fields[field.Name].(*types.Struct)
+				if !ok {
+					inner = types.NewStruct(field.Type)
+					target.Fields[field.Name] = inner
+				}
+				if err := c.decodeFields(inner, innerFields, obj); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		value, found := objectKey(obj, name)
+		if !found || value == nil {
+			continue
+		}
+		decoded, err := c.decodeValue(field.Type, value)
+		if err != nil {
+			return fmt.Errorf("field %s.%s: %w", target.Type, field.Name, err)
+		}
+		target.Fields[field.Name] = decoded
+	}
+	return nil
+}
+
+// decodeValue converts a decoded JSON value to a declared type
+func (c jsonCodec) decodeValue(typ string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch {
+	case strings.HasPrefix(typ, "*"):
+		return c.decodeValue(typ[1:], value)
+	case strings.HasPrefix(typ, "[]"):
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot unmarshal %s into %s", jsonKind(value), typ)
+		}
+		decoded := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if decoded[i], err = c.decodeValue(typ[2:], item); err != nil {
+				return nil, err
+			}
+		}
+		return decoded, nil
+	case strings.HasPrefix(typ, "map[string]"):
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot unmarshal %s into %s", jsonKind(value), typ)
+		}
+		decoded := make(map[string]interface{}, len(obj))
+		for key, item := range obj {
+			var err error
+			if decoded[key], err = c.decodeValue(typ[len("map[string]"):], item); err != nil {
+				return nil, err
+			}
+		}
+		return decoded, nil
+	}
+
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		if n, ok := value.(json.Number); ok {
+			i, err := strconv.ParseInt(n.String(), 10, strconv.IntSize)
+			if err != nil {
+				return nil, fmt.Errorf("cannot unmarshal number %s into %s", n, typ)
+			}
+			return int(i), nil
+		}
+	case "float64", "float32":
+		if n, ok := value.(json.Number); ok {
+			return n.Float64()
+		}
+	case "string":
+		if _, ok := value.(string); ok {
+			return value, nil
+		}
+	case "bool":
+		if _, ok := value.(bool); ok {
+			return value, nil
+		}
+	default:
+		if _, known := c.structType(typ); known {
+			s := types.NewStruct(typ)
+			if err := c.decodeStruct(s, value); err != nil {
+				return nil, err
+			}
+			return s, nil
+		}
+		return ConvertNumbers(value, c.untypedMode()), nil
+	}
+	return nil, fmt.Errorf("cannot unmarshal %s into %s", jsonKind(value), typ)
+}
+
+// untypedMode is how numbers decode into struct fields without a declared
+// type: to int when lossless, unless the mode keeps exact decimals
+func (c jsonCodec) untypedMode() types.NumberMode {
+	if c.mode == types.NumberFloat64 {
+		return types.NumberPreserveInt
+	}
+	return c.mode
+}
+
+// jsonTag parses the json tag of a field: the name it is marshaled by (empty
+// for its field name), whether it is omitted when empty, and whether it is
+// skipped
+func jsonTag(field types.StructField) (name string, omitEmpty, skip bool) {
+	tag := reflect.StructTag(field.Tag).Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// objectKey looks up the value of a field in a JSON object
+func objectKey(obj map[string]interface{}, name string) (interface{}, bool) {
+	if value, found := obj[name]; found {
+		return value, true
+	}
+	for key, value := range obj {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// zeroValue returns the value an unset field of a declared type marshals as
+func zeroValue(typ string) interface{} {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+		return 0
+	case "string":
+		return ""
+	case "bool":
+		return false
+	}
+	return nil
+}
+
+// isEmptyValue reports whether a field with the omitempty option is omitted
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// jsonKind names the kind of a decoded JSON value in errors
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -3,8 +3,11 @@ package builtin
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"strings"
 
 	"github.com/lengzhao/goscript/types"
@@ -125,44 +128,99 @@ var StringsModule = map[string]types.Function{
 	},
 }
 
-// Fmt module functions
-var FmtModule = map[string]types.Function{
-	"Printf": func(args ...interface{}) (interface{}, error) {
-		if len(args) < 1 {
-			return nil, fmt.Errorf("printf function requires at least 1 argument")
+// Fmt module functions, printing to os.Stdout
+var FmtModule = NewFmtModule(nil)
+
+// NewFmtModule returns the fmt module functions, printing to w (os.Stdout
+// if nil). Print, Println and Printf return the number of bytes written;
+// Errorf returns an error value wrapping the operands of %w.
+func NewFmtModule(w io.Writer) map[string]types.Function {
+	out := func() io.Writer {
+		if w == nil {
+			return os.Stdout
+		}
+		return w
+	}
+	return map[string]types.Function{
+		"Print": func(args ...interface{}) (interface{}, error) {
+			return fmt.Fprint(out(), args...)
+		},
+		"Printf": func(args ...interface{}) (interface{}, error) {
+			format, err := formatArg("printf", args)
+			if err != nil {
+				return nil, err
+			}
+			return fmt.Fprint(out(), sprintf(format, args[1:]))
+		},
+		"Println": func(args ...interface{}) (interface{}, error) {
+			return fmt.Fprintln(out(), args...)
+		},
+		"Sprintf": func(args ...interface{}) (interface{}, error) {
+			format, err := formatArg("sprintf", args)
+			if err != nil {
+				return nil, err
+			}
+			return sprintf(format, args[1:]), nil
+		},
+		"Errorf": func(args ...interface{}) (interface{}, error) {
+			format, err := formatArg("errorf", args)
+			if err != nil {
+				return nil, err
+			}
+			return errorf(format, args[1:]), nil
+		},
+		"Sprint": func(args ...interface{}) (interface{}, error) {
+			if len(args) < 1 {
+				return nil, fmt.Errorf("sprint function requires at least 1 argument")
+			}
+			return fmt.Sprint(args...), nil
+		},
+	}
+}
+
+// formatArg returns the format string of a fmt function
+func formatArg(fn string, args []interface{}) (string, error) {
+	if len(args) < 1 {
+		return "", fmt.Errorf("%s function requires at least 1 argument", fn)
+	}
+	format, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("first argument to %s must be a string", fn)
+	}
+	return format, nil
+}
+
+// Errors module functions. Error values are returned by script functions
+// as ordinary values (e.g., return 0, errors.New("empty")), compared with
+// nil and described with err.Error().
+var ErrorsModule = map[string]types.Function{
+	"New": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("new function requires 1 argument")
 		}
-		format, ok := args[0].(string)
+		text, ok := args[0].(string)
 		if !ok {
-			return nil, fmt.Errorf("first argument to printf must be a string")
+			return nil, fmt.Errorf("new function requires string argument")
 		}
-		// For simplicity, we'll just return the formatted string
-		// In a real implementation, this would actually print to stdout
-		if len(args) == 1 {
-			return format, nil
-		}
-		return fmt.Sprintf(format, args[1:]...), nil
-	},
-	"Println": func(args ...interface{}) (interface{}, error) {
-		// Print all arguments with spaces between them and a newline at the end
-		fmt.Println(args...)
-		// Return nil as Println doesn't return a value
-		return nil, nil
+		return errors.New(text), nil
 	},
-	"Sprintf": func(args ...interface{}) (interface{}, error) {
-		if len(args) < 1 {
-			return nil, fmt.Errorf("sprintf function requires at least 1 argument")
-		}
-		format, ok := args[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("first argument to sprintf must be a string")
+	"Is": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("is function requires 2 arguments")
 		}
-		return fmt.Sprintf(format, args[1:]...), nil
+		err, _ := args[0].(error)
+		target, _ := args[1].(error)
+		return errors.Is(err, target), nil
 	},
-	"Sprint": func(args ...interface{}) (interface{}, error) {
-		if len(args) < 1 {
-			return nil, fmt.Errorf("sprint function requires at least 1 argument")
+	"Unwrap": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("unwrap function requires 1 argument")
 		}
-		return fmt.Sprint(args...), nil
+		err, _ := args[0].(error)
+		if unwrapped := errors.Unwrap(err); unwrapped != nil {
+			return unwrapped, nil
+		}
+		return nil, nil
 	},
 }
 
@@ -234,34 +292,98 @@ var MathModule = map[string]types.Function{
 }
 
 // JSON module functions
-var JSONModule = map[string]types.Function{
-	"Marshal": func(args ...interface{}) (interface{}, error) {
-		if len(args) != 1 {
-			return nil, fmt.Errorf("marshal function requires 1 argument")
-		}
-		// Convert Go value to JSON
-		jsonData, err := json.Marshal(args[0])
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal to JSON: %w", err)
-		}
-		return string(jsonData), nil
-	},
-	"Unmarshal": func(args ...interface{}) (interface{}, error) {
-		if len(args) != 1 {
-			return nil, fmt.Errorf("unmarshal function requires 1 argument")
-		}
-		jsonStr, ok := args[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("unmarshal function requires string argument")
-		}
-		// Convert JSON string to Go value
-		var result interface{}
-		err := json.Unmarshal([]byte(jsonStr), &result)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
-		}
-		return result, nil
-	},
+var JSONModule = NewJSONModule(types.NumberFloat64)
+
+// ModuleOptions configures the builtin modules of a VM
+type ModuleOptions struct {
+	// NumberMode is how the json module decodes numbers
+	NumberMode types.NumberMode
+
+	// StructTypes looks up the script struct types the json module
+	// marshals and unmarshals by their declared fields and tags
+	StructTypes types.StructTypes
+
+	// Stdout is where the fmt module prints (os.Stdout if nil)
+	Stdout io.Writer
+}
+
+// NewJSONModule returns the json module functions, decoding numbers
+// according to mode
+func NewJSONModule(mode types.NumberMode) map[string]types.Function {
+	return NewJSONModuleWithOptions(ModuleOptions{NumberMode: mode})
+}
+
+// NewJSONModuleWithOptions returns the json module functions for the
+// number mode and struct types of opts
+func NewJSONModuleWithOptions(opts ModuleOptions) map[string]types.Function {
+	codec := jsonCodec{mode: opts.NumberMode, structs: opts.StructTypes}
+	mode := opts.NumberMode
+	return map[string]types.Function{
+		"Marshal": func(args ...interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("marshal function requires 1 argument")
+			}
+			// Numbers are encoded as the script sees them in the mode
+			value := args[0]
+			if mode != types.NumberFloat64 {
+				value = ConvertNumbers(value, mode)
+			}
+			jsonData, err := json.Marshal(codec.encode(value))
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal to JSON: %w", err)
+			}
+			return string(jsonData), nil
+		},
+		// Unmarshal(data) returns the decoded value; Unmarshal(data, &s)
+		// fills the script struct s instead
+		"Unmarshal": func(args ...interface{}) (interface{}, error) {
+			if len(args) != 1 && len(args) != 2 {
+				return nil, fmt.Errorf("unmarshal function requires 1 or 2 arguments")
+			}
+			jsonStr, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("unmarshal function requires string argument")
+			}
+			if len(args) == 2 {
+				target, ok := args[1].(*types.Struct)
+				if !ok {
+					return nil, fmt.Errorf("unmarshal function requires a struct to fill, got %T", args[1])
+				}
+				var data interface{}
+				decoder := json.NewDecoder(strings.NewReader(jsonStr))
+				decoder.UseNumber()
+				if err := decoder.Decode(&data); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+				}
+				if decoder.More() {
+					return nil, fmt.Errorf("failed to unmarshal JSON: unexpected data after top-level value")
+				}
+				if err := codec.decodeStruct(target, data); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+				}
+				return nil, nil
+			}
+			// Convert JSON string to Go value
+			var result interface{}
+			if mode == types.NumberFloat64 {
+				if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+				}
+				return result, nil
+			}
+
+			// Keep the exact text of numbers until they are converted
+			decoder := json.NewDecoder(strings.NewReader(jsonStr))
+			decoder.UseNumber()
+			if err := decoder.Decode(&result); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+			}
+			if decoder.More() {
+				return nil, fmt.Errorf("failed to unmarshal JSON: unexpected data after top-level value")
+			}
+			return ConvertNumbers(result, mode), nil
+		},
+	}
 }
 
 // GetModuleFunctions returns the functions for a given module
@@ -271,12 +393,22 @@ func GetModuleFunctions(moduleName string) (map[string]types.Function, bool) {
 		return StringsModule, true
 	case "fmt":
 		return FmtModule, true
+	case "errors":
+		return ErrorsModule, true
 	case "math":
 		return MathModule, true
 	case "json":
 		return JSONModule, true
+	case "container":
+		return ContainerModule, true
+	case "bytes":
+		return BytesModule, true
+	case "bigint":
+		return BigIntModule, true
+	case "atomic":
+		return AtomicModule, true
 	default:
-		return nil, false
+		return registeredModule(moduleName)
 	}
 }
 
@@ -287,7 +419,29 @@ func GetModuleExecutor(moduleName string) (types.ModuleExecutor, bool) {
 	if !exists {
 		return nil, false
 	}
+	return newModuleExecutor(moduleName, moduleFuncs), true
+}
 
+// GetModuleExecutorWithNumberMode is like GetModuleExecutor, but modules
+// that decode numbers (json) use the given NumberMode
+func GetModuleExecutorWithNumberMode(moduleName string, mode types.NumberMode) (types.ModuleExecutor, bool) {
+	return GetModuleExecutorWithOptions(moduleName, ModuleOptions{NumberMode: mode})
+}
+
+// GetModuleExecutorWithOptions is like GetModuleExecutor, but the json and
+// fmt modules use the given options
+func GetModuleExecutorWithOptions(moduleName string, opts ModuleOptions) (types.ModuleExecutor, bool) {
+	switch moduleName {
+	case "json":
+		return newModuleExecutor(moduleName, NewJSONModuleWithOptions(opts)), true
+	case "fmt":
+		return newModuleExecutor(moduleName, NewFmtModule(opts.Stdout)), true
+	}
+	return GetModuleExecutor(moduleName)
+}
+
+// newModuleExecutor wraps module functions in a ModuleExecutor
+func newModuleExecutor(moduleName string, moduleFuncs map[string]types.Function) types.ModuleExecutor {
 	// Create a ModuleExecutor that delegates to the module functions
 	moduleExecutor := func(entrypoint string, args ...interface{}) (interface{}, error) {
 		// Look up the function in the module
@@ -298,9 +452,21 @@ func GetModuleExecutor(moduleName string) (types.ModuleExecutor, bool) {
 		return nil, fmt.Errorf("function %s not found in module %s", entrypoint, moduleName)
 	}
 
-	return moduleExecutor, true
+	return moduleExecutor
+}
+
+// HasModuleFunction reports whether a builtin module provides the given function
+func HasModuleFunction(moduleName, funcName string) bool {
+	moduleFuncs, exists := GetModuleFunctions(moduleName)
+	if !exists {
+		return false
+	}
+	_, exists = moduleFuncs[funcName]
+	return exists
 }
 
+// ListAllModules returns the names of the builtin modules, followed by those
+// registered with RegisterModule
 func ListAllModules() []string {
-	return []string{"strings", "fmt", "math", "json"}
+	return append(append([]string{}, builtinModules...), registeredModules()...)
 }
@@ -0,0 +1,153 @@
+package builtin
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// ConvertNumbers converts the numbers in v, including those nested in
+// slices, maps and structs, according to mode. Values decoded with
+// json.Number keep their exact text until converted here. v is not
+// modified: containers holding numbers to convert are copied, and others
+// are returned as they are.
+func ConvertNumbers(v interface{}, mode types.NumberMode) interface{} {
+	switch n := v.(type) {
+	case json.Number:
+		return convertNumberText(n.String(), mode)
+	case []interface{}:
+		var converted []interface{}
+		for i, item := range n {
+			if c := ConvertNumbers(item, mode); !same(c, item) {
+				if converted == nil {
+					converted = append([]interface{}(nil), n...)
+				}
+				converted[i] = c
+			}
+		}
+		if converted == nil {
+			return n
+		}
+		return converted
+	case map[string]interface{}:
+		var converted map[string]interface{}
+		for key, item := range n {
+			if c := ConvertNumbers(item, mode); !same(c, item) {
+				if converted == nil {
+					converted = make(map[string]interface{}, len(n))
+					for k, v := range n {
+						converted[k] = v
+					}
+				}
+				converted[key] = c
+			}
+		}
+		if converted == nil {
+			return n
+		}
+		return converted
+	case map[interface{}]interface{}:
+		var converted map[interface{}]interface{}
+		for key, item := range n {
+			if c := ConvertNumbers(item, mode); !same(c, item) {
+				if converted == nil {
+					converted = make(map[interface{}]interface{}, len(n))
+					for k, v := range n {
+						converted[k] = v
+					}
+				}
+				converted[key] = c
+			}
+		}
+		if converted == nil {
+			return n
+		}
+		return converted
+	case *types.Struct:
+		fields, ok := ConvertNumbers(n.Fields, mode).(map[string]interface{})
+		if !ok || same(fields, n.Fields) {
+			return n
+		}
+		return &types.Struct{Type: n.Type, Fields: fields, Pointer: n.Pointer}
+	}
+
+	if mode == types.NumberFloat64 {
+		return v
+	}
+
+	switch n := v.(type) {
+	case int8:
+		return int(n)
+	case int16:
+		return int(n)
+	case int32:
+		// int32 is the rune type and is kept as is
+		return n
+	case int64:
+		if n >= math.MinInt && n <= math.MaxInt {
+			return int(n)
+		}
+		return inexactNumber(strconv.FormatInt(n, 10), float64(n), mode)
+	case uint:
+		return convertUnsigned(uint64(n), mode)
+	case uint8:
+		return int(n)
+	case uint16:
+		return int(n)
+	case uint32:
+		return convertUnsigned(uint64(n), mode)
+	case uint64:
+		return convertUnsigned(n, mode)
+	case float32:
+		return float64(n)
+	}
+	return v
+}
+
+// same reports whether ConvertNumbers returned its argument unchanged: the
+// same container, or a value of the same type, as converted numbers change
+// their type
+func same(converted, original interface{}) bool {
+	switch c := converted.(type) {
+	case []interface{}:
+		o, ok := original.([]interface{})
+		return ok && len(c) == len(o) && (len(c) == 0 || &c[0] == &o[0])
+	case map[string]interface{}, map[interface{}]interface{}, *types.Struct:
+		return reflect.TypeOf(converted) == reflect.TypeOf(original) &&
+			reflect.ValueOf(converted).UnsafePointer() == reflect.ValueOf(original).UnsafePointer()
+	}
+	return reflect.TypeOf(converted) == reflect.TypeOf(original)
+}
+
+// convertNumberText converts the text of a JSON number
+func convertNumberText(text string, mode types.NumberMode) interface{} {
+	if mode != types.NumberFloat64 {
+		if i, err := strconv.ParseInt(text, 10, strconv.IntSize); err == nil {
+			return int(i)
+		}
+		if mode == types.NumberDecimalString {
+			return text
+		}
+	}
+	f, _ := strconv.ParseFloat(text, 64)
+	return f
+}
+
+// convertUnsigned converts an unsigned host integer
+func convertUnsigned(n uint64, mode types.NumberMode) interface{} {
+	if n <= math.MaxInt {
+		return int(n)
+	}
+	return inexactNumber(strconv.FormatUint(n, 10), float64(n), mode)
+}
+
+// inexactNumber returns the representation of a number that does not fit in int
+func inexactNumber(text string, f float64, mode types.NumberMode) interface{} {
+	if mode == types.NumberDecimalString {
+		return text
+	}
+	return f
+}
@@ -0,0 +1,180 @@
+package builtin
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// OSOptions selects what the os module may do. The zero value allows
+// nothing: every function fails with an error wrapping fs.ErrPermission.
+type OSOptions struct {
+	// Env allows reading all environment variables with Getenv and Environ
+	Env bool
+
+	// EnvVars lists the environment variables that can be read when Env
+	// is false
+	EnvVars []string
+
+	// Args are the command-line arguments returned by Args. Nil denies
+	// Args.
+	Args []string
+
+	// Dir is the directory files are read from and written to; paths are
+	// relative to it and cannot leave it. Empty denies file access.
+	Dir string
+
+	// Write allows WriteFile, Mkdir and Remove in Dir
+	Write bool
+}
+
+// NewOSModule returns the functions of the os module, wrapping the os
+// package within the limits of opts. It is registered by the host, e.g.
+// RegisterModule("os", NewOSModule(OSOptions{Dir: "data"})).
+func NewOSModule(opts OSOptions) map[string]types.Function {
+	return map[string]types.Function{
+		"Getenv": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("Getenv", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			if !opts.envAllowed(strs[0]) {
+				return nil, fmt.Errorf("os.Getenv %s: %w", strs[0], fs.ErrPermission)
+			}
+			return os.Getenv(strs[0]), nil
+		},
+		// Environ returns the allowed environment variables as sorted
+		// "key=value" strings
+		"Environ": func(args ...interface{}) (interface{}, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("os.Environ expects no arguments, got %d", len(args))
+			}
+			if !opts.Env && len(opts.EnvVars) == 0 {
+				return nil, fmt.Errorf("os.Environ: %w", fs.ErrPermission)
+			}
+			var env []string
+			for _, kv := range os.Environ() {
+				if key, _, _ := strings.Cut(kv, "="); opts.envAllowed(key) {
+					env = append(env, kv)
+				}
+			}
+			sort.Strings(env)
+			return stringSlice(env), nil
+		},
+		"Args": func(args ...interface{}) (interface{}, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("os.Args expects no arguments, got %d", len(args))
+			}
+			if opts.Args == nil {
+				return nil, fmt.Errorf("os.Args: %w", fs.ErrPermission)
+			}
+			return stringSlice(opts.Args), nil
+		},
+		"ReadFile": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("ReadFile", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			var data []byte
+			err = opts.inDir("ReadFile", false, func(root *os.Root) error {
+				file, err := root.Open(strs[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				data, err = io.ReadAll(file)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			return string(data), nil
+		},
+		// ReadDir returns the names of the entries of a directory, sorted
+		"ReadDir": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("ReadDir", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			var names []string
+			err = opts.inDir("ReadDir", false, func(root *os.Root) error {
+				dir, err := root.Open(strs[0])
+				if err != nil {
+					return err
+				}
+				defer dir.Close()
+				entries, err := dir.ReadDir(-1)
+				for _, entry := range entries {
+					names = append(names, entry.Name())
+				}
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(names)
+			return stringSlice(names), nil
+		},
+		"WriteFile": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("WriteFile", args, 2)
+			if err != nil {
+				return nil, err
+			}
+			return nil, opts.inDir("WriteFile", true, func(root *os.Root) error {
+				file, err := root.OpenFile(strs[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+				if err != nil {
+					return err
+				}
+				if _, err := io.WriteString(file, strs[1]); err != nil {
+					file.Close()
+					return err
+				}
+				return file.Close()
+			})
+		},
+		"Mkdir": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("Mkdir", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return nil, opts.inDir("Mkdir", true, func(root *os.Root) error {
+				return root.Mkdir(strs[0], 0o755)
+			})
+		},
+		"Remove": func(args ...interface{}) (interface{}, error) {
+			strs, err := stringArgs("Remove", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return nil, opts.inDir("Remove", true, func(root *os.Root) error {
+				return root.Remove(strs[0])
+			})
+		},
+	}
+}
+
+// envAllowed reports whether the options allow reading an environment
+// variable
+func (opts OSOptions) envAllowed(name string) bool {
+	return opts.Env || slices.Contains(opts.EnvVars, name)
+}
+
+// inDir runs a file operation of the os module in the directory of the
+// options, if they allow it
+func (opts OSOptions) inDir(fn string, write bool, op func(root *os.Root) error) error {
+	if opts.Dir == "" || (write && !opts.Write) {
+		return fmt.Errorf("os.%s: %w", fn, fs.ErrPermission)
+	}
+	root, err := os.OpenRoot(opts.Dir)
+	if err != nil {
+		return fmt.Errorf("os.%s: %w", fn, err)
+	}
+	defer root.Close()
+	return op(root)
+}
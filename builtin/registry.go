@@ -0,0 +1,76 @@
+package builtin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Registered modules. Embedders add modules of Go functions that every
+// script in the process can import, like the modules built into this
+// package, without patching it. Ready-made modules wrapping parts of the Go
+// standard library are registered with RegisterStdModules, and the os and
+// http modules with RegisterModule("os", NewOSModule(opts)) and
+// RegisterModule("http", NewHTTPModule(opts)).
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]map[string]types.Function)
+)
+
+// builtinModules are the modules built into the package, which cannot be
+// registered
+var builtinModules = []string{"strings", "fmt", "errors", "math", "json", "container", "bytes", "bigint", "atomic", "mutex"}
+
+// RegisterModule registers a module of functions for all scripts, replacing
+// a module registered under the same name. Modules registered by a script's
+// host (Script.RegisterModule) take precedence. It panics if name is the
+// name of a module built into the package.
+func RegisterModule(name string, funcs map[string]types.Function) {
+	for _, builtin := range builtinModules {
+		if name == builtin {
+			panic(fmt.Sprintf("builtin: cannot register module %s: it is built in", name))
+		}
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = funcs
+}
+
+// UnregisterModule removes a module registered with RegisterModule
+func UnregisterModule(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, name)
+}
+
+// registeredModule returns the functions of a registered module
+func registeredModule(name string) (map[string]types.Function, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	funcs, exists := registry[name]
+	return funcs, exists
+}
+
+// registeredModules returns the sorted names of the registered modules
+func registeredModules() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterStdModules registers the modules wrapping the Go standard library
+// that scripts cannot use to reach the host: time, strconv, regexp and sort
+func RegisterStdModules() {
+	RegisterModule("time", TimeModule)
+	RegisterModule("strconv", StrconvModule)
+	RegisterModule("regexp", RegexpModule)
+	RegisterModule("sort", SortModule)
+}
@@ -0,0 +1,45 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+func TestRegisterModule(t *testing.T) {
+	RegisterModule("greeting", map[string]types.Function{
+		"Hello": func(args ...interface{}) (interface{}, error) {
+			return "hello", nil
+		},
+	})
+	defer UnregisterModule("greeting")
+
+	if !HasModuleFunction("greeting", "Hello") {
+		t.Errorf("Expected greeting.Hello to be a module function")
+	}
+	executor, exists := GetModuleExecutor("greeting")
+	if !exists {
+		t.Fatalf("Expected an executor for the greeting module")
+	}
+	if result, err := executor("Hello"); err != nil || result != "hello" {
+		t.Errorf("Expected hello, got %v (%v)", result, err)
+	}
+	modules := ListAllModules()
+	if modules[len(modules)-1] != "greeting" {
+		t.Errorf("Expected greeting among the modules, got %v", modules)
+	}
+
+	UnregisterModule("greeting")
+	if _, exists := GetModuleFunctions("greeting"); exists {
+		t.Errorf("Expected the greeting module to be unregistered")
+	}
+}
+
+func TestRegisterBuiltinModulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering the strings module to panic")
+		}
+	}()
+	RegisterModule("strings", map[string]types.Function{})
+}
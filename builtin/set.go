@@ -0,0 +1,227 @@
+package builtin
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Set is an insertion-ordered set of comparable values backed by a Go map
+type Set struct {
+	index map[interface{}]int
+	items []interface{}
+}
+
+// NewSet creates a set holding the given items
+func NewSet(items ...interface{}) (*Set, error) {
+	s := &Set{index: make(map[interface{}]int)}
+	for _, item := range items {
+		if err := s.Add(item); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add inserts an item; adding an existing item is a no-op
+func (s *Set) Add(item interface{}) error {
+	if item != nil && !reflect.TypeOf(item).Comparable() {
+		return fmt.Errorf("set: unhashable type %T", item)
+	}
+	if _, exists := s.index[item]; !exists {
+		s.index[item] = len(s.items)
+		s.items = append(s.items, item)
+	}
+	return nil
+}
+
+// Has reports whether the set contains item
+func (s *Set) Has(item interface{}) bool {
+	if item != nil && !reflect.TypeOf(item).Comparable() {
+		return false
+	}
+	_, exists := s.index[item]
+	return exists
+}
+
+// Delete removes an item, preserving the order of the remaining items
+func (s *Set) Delete(item interface{}) {
+	if !s.Has(item) {
+		return
+	}
+	pos := s.index[item]
+	delete(s.index, item)
+	s.items = append(s.items[:pos], s.items[pos+1:]...)
+	for i := pos; i < len(s.items); i++ {
+		s.index[s.items[i]] = i
+	}
+}
+
+// Len returns the number of items
+func (s *Set) Len() int {
+	return len(s.items)
+}
+
+// ToSlice returns the items in insertion order
+func (s *Set) ToSlice() []interface{} {
+	result := make([]interface{}, len(s.items))
+	copy(result, s.items)
+	return result
+}
+
+// String returns the set formatted as "set[a b c]"
+func (s *Set) String() string {
+	parts := make([]string, len(s.items))
+	for i, item := range s.items {
+		parts[i] = fmt.Sprint(item)
+	}
+	return "set[" + strings.Join(parts, " ") + "]"
+}
+
+// MarshalJSON encodes the set as a JSON array
+func (s *Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.items)
+}
+
+// SetNew creates a set: newSet(items...)
+func SetNew(args ...interface{}) (interface{}, error) {
+	return NewSet(args...)
+}
+
+// SetAdd adds items to a set and returns it: add(s, items...)
+func SetAdd(args ...interface{}) (interface{}, error) {
+	s, err := setArg("add", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range args[1:] {
+		if err := s.Add(item); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// SetHas reports whether a set contains an item: has(s, item)
+func SetHas(args ...interface{}) (interface{}, error) {
+	s, err := setArg("has", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("has expects 2 arguments, got %d", len(args))
+	}
+	return s.Has(args[1]), nil
+}
+
+// Delete removes an item from a set or a key from a map: delete(s, item)
+func Delete(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("delete expects 2 arguments, got %d", len(args))
+	}
+	// delete also removes keys from maps, as in Go
+	if m, ok := args[0].(map[string]interface{}); ok {
+		key, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("delete: map key must be a string, got %T", args[1])
+		}
+		delete(m, key)
+		return nil, nil
+	}
+	if m, ok := args[0].(map[interface{}]interface{}); ok {
+		if args[1] != nil && !reflect.TypeOf(args[1]).Comparable() {
+			return nil, fmt.Errorf("delete: invalid map key type %T", args[1])
+		}
+		delete(m, args[1])
+		return nil, nil
+	}
+	s, err := setArg("delete", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	s.Delete(args[1])
+	return s, nil
+}
+
+// SetUnion returns the items in any of the sets: union(a, b, ...)
+func SetUnion(args ...interface{}) (interface{}, error) {
+	return combineSets("union", args, func(item interface{}, others []*Set) bool {
+		return true
+	})
+}
+
+// SetIntersect returns the items of a that are in every other set: intersect(a, b, ...)
+func SetIntersect(args ...interface{}) (interface{}, error) {
+	return combineSets("intersect", args, func(item interface{}, others []*Set) bool {
+		for _, other := range others {
+			if !other.Has(item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// SetDifference returns the items of a that are in no other set: difference(a, b, ...)
+func SetDifference(args ...interface{}) (interface{}, error) {
+	return combineSets("difference", args, func(item interface{}, others []*Set) bool {
+		for _, other := range others {
+			if other.Has(item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// SetToSlice returns the items of a set in insertion order: toSlice(s)
+func SetToSlice(args ...interface{}) (interface{}, error) {
+	s, err := setArg("toSlice", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return s.ToSlice(), nil
+}
+
+// setArg validates the argument count and returns the set in args[0]
+func setArg(name string, args []interface{}, minArgs int) (*Set, error) {
+	if len(args) < minArgs {
+		return nil, fmt.Errorf("%s expects at least %d arguments, got %d", name, minArgs, len(args))
+	}
+	s, ok := args[0].(*Set)
+	if !ok {
+		return nil, fmt.Errorf("%s: first argument must be a set, got %T", name, args[0])
+	}
+	return s, nil
+}
+
+// combineSets builds a new set from the items of all sets (union) or of
+// the first set (intersect, difference) for which keep returns true
+func combineSets(name string, args []interface{}, keep func(item interface{}, others []*Set) bool) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("%s expects at least 2 arguments, got %d", name, len(args))
+	}
+	sets := make([]*Set, len(args))
+	for i, arg := range args {
+		s, ok := arg.(*Set)
+		if !ok {
+			return nil, fmt.Errorf("%s: argument %d must be a set, got %T", name, i, arg)
+		}
+		sets[i] = s
+	}
+
+	sources := sets[:1]
+	if name == "union" {
+		sources = sets
+	}
+	result, _ := NewSet()
+	for _, source := range sources {
+		for _, item := range source.items {
+			if keep(item, sets[1:]) {
+				result.Add(item)
+			}
+		}
+	}
+	return result, nil
+}
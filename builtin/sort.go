@@ -0,0 +1,171 @@
+package builtin
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// SortBy sorts a slice of structs or maps in place by a dotted field path
+// and returns it: sortBy(slice, "field.subfield", asc). asc defaults to
+// true. Numbers, strings, times and durations are compared natively;
+// elements whose field is missing or nil sort last.
+func SortBy(args ...interface{}) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("sortBy expects 2 or 3 arguments, got %d", len(args))
+	}
+	slice, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("sortBy: first argument must be a slice, got %T", args[0])
+	}
+	path, ok := args[1].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("sortBy: field path must be a non-empty string")
+	}
+	asc := true
+	if len(args) == 3 {
+		if asc, ok = args[2].(bool); !ok {
+			return nil, fmt.Errorf("sortBy: asc must be a bool, got %T", args[2])
+		}
+	}
+
+	// Resolve the keys once instead of on every comparison
+	fields := strings.Split(path, ".")
+	keys := make([]interface{}, len(slice))
+	for i, elem := range slice {
+		keys[i] = fieldByPath(elem, fields)
+	}
+
+	var sortErr error
+	indexes := make([]int, len(slice))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		ka, kb := keys[indexes[a]], keys[indexes[b]]
+		// Missing values sort last regardless of direction
+		if ka == nil || kb == nil {
+			return ka != nil && kb == nil
+		}
+		cmp, err := compareValues(ka, kb)
+		if err != nil {
+			if sortErr == nil {
+				sortErr = fmt.Errorf("sortBy %s: %w", path, err)
+			}
+			return false
+		}
+		if asc {
+			return cmp < 0
+		}
+		return cmp > 0
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	sorted := make([]interface{}, len(slice))
+	for i, idx := range indexes {
+		sorted[i] = slice[idx]
+	}
+	copy(slice, sorted)
+	return slice, nil
+}
+
+// fieldByPath follows a field path through maps and Go structs, returning
+// nil if any step is missing
+func fieldByPath(value interface{}, fields []string) interface{} {
+	for _, field := range fields {
+		switch v := value.(type) {
+		case nil:
+			return nil
+		case map[string]interface{}:
+			value = v[field]
+		case *types.Struct:
+			value = v.Fields[field]
+		default:
+			rv := reflect.ValueOf(v)
+			for rv.Kind() == reflect.Pointer {
+				if rv.IsNil() {
+					return nil
+				}
+				rv = rv.Elem()
+			}
+			if rv.Kind() != reflect.Struct {
+				return nil
+			}
+			fv := rv.FieldByName(field)
+			if !fv.IsValid() || !fv.CanInterface() {
+				return nil
+			}
+			value = fv.Interface()
+		}
+	}
+	return value
+}
+
+// compareValues returns -1, 0 or 1 comparing a and b
+func compareValues(a, b interface{}) (int, error) {
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), nil
+		}
+	case time.Duration:
+		if y, ok := b.(time.Duration); ok {
+			return compareFloats(float64(x), float64(y)), nil
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, nil
+			case !x:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	}
+
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return compareFloats(x, y), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %T and %T", a, b)
+}
+
+// compareFloats returns -1, 0 or 1 comparing x and y
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
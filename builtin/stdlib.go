@@ -0,0 +1,506 @@
+package builtin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Standard library modules. These modules wrap functions of the Go packages
+// of the same name for scripts; they are not available until registered
+// (see RegisterStdModules). As with the other builtin modules, functions
+// that return an error in Go fail the call with it.
+
+// TimeModule wraps the time package. Times and durations are time.Time and
+// time.Duration values, whose methods scripts call directly (e.g.,
+// t.Format(time.RFC3339) or d.Seconds()).
+var TimeModule = map[string]types.Function{
+	"Now": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("Now function requires no arguments")
+		}
+		return time.Now(), nil
+	},
+	"Unix": func(args ...interface{}) (interface{}, error) {
+		sec, err := intArgs("Unix", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(int64(sec[0]), 0).UTC(), nil
+	},
+	"UnixMilli": func(args ...interface{}) (interface{}, error) {
+		msec, err := intArgs("UnixMilli", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return time.UnixMilli(int64(msec[0])).UTC(), nil
+	},
+	// Date(year, month, day[, hour, min, sec]) returns a time in UTC
+	"Date": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 && len(args) != 6 {
+			return nil, fmt.Errorf("Date function requires 3 or 6 arguments")
+		}
+		parts, err := intArgs("Date", args, len(args))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, 0, 0, 0)
+		return time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, time.UTC), nil
+	},
+	"Parse": func(args ...interface{}) (interface{}, error) {
+		strs, err := stringArgs("Parse", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return time.Parse(strs[0], strs[1])
+	},
+	"ParseDuration": func(args ...interface{}) (interface{}, error) {
+		strs, err := stringArgs("ParseDuration", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return time.ParseDuration(strs[0])
+	},
+	"Since": func(args ...interface{}) (interface{}, error) {
+		t, err := timeArg("Since", args)
+		if err != nil {
+			return nil, err
+		}
+		return time.Since(t), nil
+	},
+	"Until": func(args ...interface{}) (interface{}, error) {
+		t, err := timeArg("Until", args)
+		if err != nil {
+			return nil, err
+		}
+		return time.Until(t), nil
+	},
+}
+
+// StrconvModule wraps the strconv package. Integers are parsed to and
+// formatted from script ints.
+var StrconvModule = map[string]types.Function{
+	"Itoa": func(args ...interface{}) (interface{}, error) {
+		n, err := intArgs("Itoa", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.Itoa(n[0]), nil
+	},
+	"Atoi": func(args ...interface{}) (interface{}, error) {
+		strs, err := stringArgs("Atoi", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.Atoi(strs[0])
+	},
+	// ParseInt(s, base[, bitSize])
+	"ParseInt": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("ParseInt function requires 2 or 3 arguments")
+		}
+		strs, err := stringArgs("ParseInt", args[:1], 1)
+		if err != nil {
+			return nil, err
+		}
+		ints, err := intArgs("ParseInt", args[1:], len(args)-1)
+		if err != nil {
+			return nil, err
+		}
+		bitSize := strconv.IntSize
+		if len(ints) == 2 {
+			bitSize = ints[1]
+		}
+		n, err := strconv.ParseInt(strs[0], ints[0], bitSize)
+		if err != nil {
+			return nil, err
+		}
+		return int(n), nil
+	},
+	// ParseFloat(s[, bitSize])
+	"ParseFloat": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("ParseFloat function requires 1 or 2 arguments")
+		}
+		strs, err := stringArgs("ParseFloat", args[:1], 1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.ParseFloat(strs[0], 64)
+	},
+	"ParseBool": func(args ...interface{}) (interface{}, error) {
+		strs, err := stringArgs("ParseBool", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.ParseBool(strs[0])
+	},
+	"FormatInt": func(args ...interface{}) (interface{}, error) {
+		ints, err := intArgs("FormatInt", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		if ints[1] < 2 || ints[1] > 36 {
+			return nil, fmt.Errorf("FormatInt function requires a base between 2 and 36, got %d", ints[1])
+		}
+		return strconv.FormatInt(int64(ints[0]), ints[1]), nil
+	},
+	// FormatFloat(f, fmt, prec[, bitSize]), where fmt is a rune or a
+	// one-letter string such as 'f' or "e"
+	"FormatFloat": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 && len(args) != 4 {
+			return nil, fmt.Errorf("FormatFloat function requires 3 or 4 arguments")
+		}
+		f, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("FormatFloat function requires a numeric value, got %T", args[0])
+		}
+		var format byte
+		switch v := args[1].(type) {
+		case int32:
+			format = byte(v)
+		case string:
+			if len(v) == 1 {
+				format = v[0]
+			}
+		}
+		if format == 0 {
+			return nil, fmt.Errorf("FormatFloat function requires a format such as 'f', got %v", args[1])
+		}
+		ints, err := intArgs("FormatFloat", args[2:], len(args)-2)
+		if err != nil {
+			return nil, err
+		}
+		bitSize := 64
+		if len(ints) == 2 {
+			bitSize = ints[1]
+		}
+		return strconv.FormatFloat(f, format, ints[0], bitSize), nil
+	},
+	"FormatBool": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("FormatBool function requires 1 argument")
+		}
+		b, ok := args[0].(bool)
+		if !ok {
+			return nil, fmt.Errorf("FormatBool function requires a bool argument, got %T", args[0])
+		}
+		return strconv.FormatBool(b), nil
+	},
+	"Quote": func(args ...interface{}) (interface{}, error) {
+		strs, err := stringArgs("Quote", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.Quote(strs[0]), nil
+	},
+	"Unquote": func(args ...interface{}) (interface{}, error) {
+		strs, err := stringArgs("Unquote", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.Unquote(strs[0])
+	},
+}
+
+// RegexpModule wraps the regexp package. Functions take the pattern as
+// their first argument; compiled patterns are cached.
+var RegexpModule = map[string]types.Function{
+	"MatchString": func(args ...interface{}) (interface{}, error) {
+		re, strs, err := regexpArgs("MatchString", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(strs[0]), nil
+	},
+	"FindString": func(args ...interface{}) (interface{}, error) {
+		re, strs, err := regexpArgs("FindString", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return re.FindString(strs[0]), nil
+	},
+	"FindStringSubmatch": func(args ...interface{}) (interface{}, error) {
+		re, strs, err := regexpArgs("FindStringSubmatch", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return stringSlice(re.FindStringSubmatch(strs[0])), nil
+	},
+	// FindAllString(pattern, s, n) returns at most n matches, or all for n < 0
+	"FindAllString": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("FindAllString function requires 3 arguments")
+		}
+		re, strs, err := regexpArgs("FindAllString", args[:2], 1)
+		if err != nil {
+			return nil, err
+		}
+		n, err := intArgs("FindAllString", args[2:], 1)
+		if err != nil {
+			return nil, err
+		}
+		return stringSlice(re.FindAllString(strs[0], n[0])), nil
+	},
+	"ReplaceAllString": func(args ...interface{}) (interface{}, error) {
+		re, strs, err := regexpArgs("ReplaceAllString", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return re.ReplaceAllString(strs[0], strs[1]), nil
+	},
+	// Split(pattern, s, n) returns at most n substrings, or all for n < 0
+	"Split": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("Split function requires 3 arguments")
+		}
+		re, strs, err := regexpArgs("Split", args[:2], 1)
+		if err != nil {
+			return nil, err
+		}
+		n, err := intArgs("Split", args[2:], 1)
+		if err != nil {
+			return nil, err
+		}
+		return stringSlice(re.Split(strs[0], n[0])), nil
+	},
+	"QuoteMeta": func(args ...interface{}) (interface{}, error) {
+		strs, err := stringArgs("QuoteMeta", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return regexp.QuoteMeta(strs[0]), nil
+	},
+}
+
+// maxCachedPatterns limits the compiled patterns the regexp module keeps
+const maxCachedPatterns = 256
+
+var (
+	patternsMu sync.Mutex
+	patterns   = make(map[string]*regexp.Regexp)
+)
+
+// compilePattern compiles a pattern, or returns it from the cache
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternsMu.Lock()
+	re, cached := patterns[pattern]
+	patternsMu.Unlock()
+	if cached {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternsMu.Lock()
+	if len(patterns) >= maxCachedPatterns {
+		clear(patterns)
+	}
+	patterns[pattern] = re
+	patternsMu.Unlock()
+	return re, nil
+}
+
+// regexpArgs parses a pattern followed by n string arguments
+func regexpArgs(fn string, args []interface{}, n int) (*regexp.Regexp, []string, error) {
+	strs, err := stringArgs(fn, args, n+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := compilePattern(strs[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return re, strs[1:], nil
+}
+
+// SortModule wraps the sort package. Slices are sorted in place; Slice and
+// SliceStable order them with a less(i, j int) bool function of the script
+// (e.g., a function literal), or of the host.
+var SortModule = map[string]types.Function{
+	"Ints": func(args ...interface{}) (interface{}, error) {
+		return nil, sortSlice("Ints", args, isInt)
+	},
+	"Float64s": func(args ...interface{}) (interface{}, error) {
+		return nil, sortSlice("Float64s", args, isNumber)
+	},
+	"Strings": func(args ...interface{}) (interface{}, error) {
+		return nil, sortSlice("Strings", args, isString)
+	},
+	"IntsAreSorted": func(args ...interface{}) (interface{}, error) {
+		return sliceIsSorted("IntsAreSorted", args, isInt)
+	},
+	"Float64sAreSorted": func(args ...interface{}) (interface{}, error) {
+		return sliceIsSorted("Float64sAreSorted", args, isNumber)
+	},
+	"StringsAreSorted": func(args ...interface{}) (interface{}, error) {
+		return sliceIsSorted("StringsAreSorted", args, isString)
+	},
+	"Slice": func(args ...interface{}) (interface{}, error) {
+		return nil, sortSliceFunc("Slice", args, sort.Slice)
+	},
+	"SliceStable": func(args ...interface{}) (interface{}, error) {
+		return nil, sortSliceFunc("SliceStable", args, sort.SliceStable)
+	},
+}
+
+// sortSliceFunc sorts a slice argument in place with a less function
+// argument. The sort stops comparing after the first error of less.
+func sortSliceFunc(fn string, args []interface{}, sortFunc func(interface{}, func(i, j int) bool)) error {
+	if len(args) != 2 {
+		return fmt.Errorf("%s function requires 2 arguments, got %d", fn, len(args))
+	}
+	slice, ok := args[0].([]interface{})
+	if !ok {
+		return fmt.Errorf("%s function requires a slice, got %T", fn, args[0])
+	}
+	less, ok := callableFunc(args[1])
+	if !ok {
+		return fmt.Errorf("%s function requires a less function, got %T", fn, args[1])
+	}
+	var err error
+	sortFunc(slice, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var result interface{}
+		if result, err = less(i, j); err != nil {
+			err = fmt.Errorf("%s: %w", fn, err)
+			return false
+		}
+		b, ok := result.(bool)
+		if !ok {
+			err = fmt.Errorf("%s function requires less to return a bool, got %T", fn, result)
+		}
+		return b
+	})
+	return err
+}
+
+// sortedSlice checks that the argument is a slice whose elements all have
+// the expected kind
+func sortedSlice(fn string, args []interface{}, valid func(interface{}) bool) ([]interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s function requires 1 argument", fn)
+	}
+	slice, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s function requires a slice, got %T", fn, args[0])
+	}
+	for _, elem := range slice {
+		if !valid(elem) {
+			return nil, fmt.Errorf("%s function cannot sort element %v (%T)", fn, elem, elem)
+		}
+	}
+	return slice, nil
+}
+
+// sortSlice sorts a slice argument in place
+func sortSlice(fn string, args []interface{}, valid func(interface{}) bool) error {
+	slice, err := sortedSlice(fn, args, valid)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(slice, func(i, j int) bool {
+		cmp, _ := compareValues(slice[i], slice[j])
+		return cmp < 0
+	})
+	return nil
+}
+
+// sliceIsSorted reports whether a slice argument is sorted
+func sliceIsSorted(fn string, args []interface{}, valid func(interface{}) bool) (interface{}, error) {
+	slice, err := sortedSlice(fn, args, valid)
+	if err != nil {
+		return nil, err
+	}
+	return sort.SliceIsSorted(slice, func(i, j int) bool {
+		cmp, _ := compareValues(slice[i], slice[j])
+		return cmp < 0
+	}), nil
+}
+
+func isInt(v interface{}) bool {
+	switch v.(type) {
+	case int, int32, int64:
+		return true
+	}
+	return false
+}
+
+func isNumber(v interface{}) bool {
+	_, ok := toFloat(v)
+	return ok
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+// stringArgs checks that args are n strings
+func stringArgs(fn string, args []interface{}, n int) ([]string, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("%s function requires %d arguments, got %d", fn, n, len(args))
+	}
+	strs := make([]string, n)
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s function requires string arguments, got %T", fn, arg)
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+// intArgs checks that args are n integers
+func intArgs(fn string, args []interface{}, n int) ([]int, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("%s function requires %d arguments, got %d", fn, n, len(args))
+	}
+	ints := make([]int, n)
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case int:
+			ints[i] = v
+		case int32:
+			ints[i] = int(v)
+		case int64:
+			ints[i] = int(v)
+		default:
+			return nil, fmt.Errorf("%s function requires integer arguments, got %T", fn, arg)
+		}
+	}
+	return ints, nil
+}
+
+// timeArg checks that args are a single time
+func timeArg(fn string, args []interface{}) (time.Time, error) {
+	if len(args) != 1 {
+		return time.Time{}, fmt.Errorf("%s function requires 1 argument", fn)
+	}
+	t, ok := args[0].(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s function requires a time, got %T", fn, args[0])
+	}
+	return t, nil
+}
+
+// stringSlice converts strings to a script slice
+func stringSlice(strs []string) []interface{} {
+	if strs == nil {
+		return nil
+	}
+	slice := make([]interface{}, len(strs))
+	for i, s := range strs {
+		slice[i] = s
+	}
+	return slice
+}
@@ -0,0 +1,178 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// SyncRegistry holds named mutexes and atomic counters. Scripts that use the
+// same registry coordinate through it, even when they run concurrently in
+// separate VMs against shared host state.
+type SyncRegistry struct {
+	mu       sync.Mutex
+	mutexes  map[string]*Mutex
+	counters map[string]*int64
+}
+
+// NewSyncRegistry creates an empty registry
+func NewSyncRegistry() *SyncRegistry {
+	return &SyncRegistry{
+		mutexes:  make(map[string]*Mutex),
+		counters: make(map[string]*int64),
+	}
+}
+
+// SharedSync is the registry behind the mutex and atomic modules
+var SharedSync = NewSyncRegistry()
+
+// Mutex returns the mutex with the given name, creating it if needed. Hosts
+// lock it to coordinate with scripts that use mutex.Lock(name).
+func (r *SyncRegistry) Mutex(name string) *Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, exists := r.mutexes[name]
+	if !exists {
+		m = &Mutex{ch: make(chan struct{}, 1)}
+		r.mutexes[name] = m
+	}
+	return m
+}
+
+// Mutex is a named mutex of a SyncRegistry. Unlike sync.Mutex, waiting for
+// it can be given up when a context is done, so a script blocked on a lock
+// still honors the cancellation and timeout of its execution.
+type Mutex struct {
+	// ch holds a token while the mutex is locked
+	ch chan struct{}
+}
+
+// Lock locks the mutex, waiting until it is available
+func (m *Mutex) Lock() {
+	m.ch <- struct{}{}
+}
+
+// LockContext locks the mutex, waiting until it is available or ctx is
+// done, in which case it returns ctx.Err()
+func (m *Mutex) LockContext(ctx context.Context) error {
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryLock locks the mutex if it is available and reports whether it did
+func (m *Mutex) TryLock() bool {
+	select {
+	case m.ch <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Unlock unlocks the mutex. Like sync.Mutex, it panics if the mutex is not
+// locked.
+func (m *Mutex) Unlock() {
+	select {
+	case <-m.ch:
+	default:
+		panic("sync: unlock of unlocked mutex")
+	}
+}
+
+// Counter returns the current value of the named counter
+func (r *SyncRegistry) Counter(name string) int64 {
+	return atomic.LoadInt64(r.counter(name))
+}
+
+// SetCounter sets the named counter
+func (r *SyncRegistry) SetCounter(name string, value int64) {
+	atomic.StoreInt64(r.counter(name), value)
+}
+
+func (r *SyncRegistry) counter(name string) *int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, exists := r.counters[name]
+	if !exists {
+		c = new(int64)
+		r.counters[name] = c
+	}
+	return c
+}
+
+// NewAtomicModule returns the functions of the atomic module over a registry.
+// Counters are addressed by name and hold int64 values.
+func NewAtomicModule(r *SyncRegistry) map[string]types.Function {
+	return map[string]types.Function{
+		"AddInt": func(args ...interface{}) (interface{}, error) {
+			name, delta, err := counterArgs("AddInt", args)
+			if err != nil {
+				return nil, err
+			}
+			return int(atomic.AddInt64(r.counter(name), delta)), nil
+		},
+		"LoadInt": func(args ...interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("LoadInt function requires 1 argument")
+			}
+			name, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("LoadInt function requires a string counter name")
+			}
+			return int(r.Counter(name)), nil
+		},
+		"StoreInt": func(args ...interface{}) (interface{}, error) {
+			name, value, err := counterArgs("StoreInt", args)
+			if err != nil {
+				return nil, err
+			}
+			r.SetCounter(name, value)
+			return nil, nil
+		},
+		"CompareAndSwapInt": func(args ...interface{}) (interface{}, error) {
+			if len(args) != 3 {
+				return nil, fmt.Errorf("CompareAndSwapInt function requires 3 arguments")
+			}
+			name, old, err := counterArgs("CompareAndSwapInt", args[:2])
+			if err != nil {
+				return nil, err
+			}
+			_, new, err := counterArgs("CompareAndSwapInt", []interface{}{name, args[2]})
+			if err != nil {
+				return nil, err
+			}
+			return atomic.CompareAndSwapInt64(r.counter(name), old, new), nil
+		},
+	}
+}
+
+// AtomicModule is the atomic module over SharedSync
+var AtomicModule = NewAtomicModule(SharedSync)
+
+// counterArgs parses a (name, int) argument pair
+func counterArgs(fn string, args []interface{}) (string, int64, error) {
+	if len(args) != 2 {
+		return "", 0, fmt.Errorf("%s function requires 2 arguments", fn)
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return "", 0, fmt.Errorf("%s function requires a string counter name", fn)
+	}
+	switch v := args[1].(type) {
+	case int:
+		return name, int64(v), nil
+	case int64:
+		return name, v, nil
+	case int32:
+		return name, int64(v), nil
+	default:
+		return "", 0, fmt.Errorf("%s function requires an integer value, got %T", fn, args[1])
+	}
+}
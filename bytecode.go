@@ -0,0 +1,70 @@
+package goscript
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+// CompileToBytes compiles the script and serializes the program, so it can
+// be cached or shipped and run later with LoadProgram without parsing and
+// compiling the source again. The format is versioned (see
+// BytecodeVersion).
+func (s *Script) CompileToBytes() ([]byte, error) {
+	program, err := s.Compile()
+	if err != nil {
+		return nil, err
+	}
+	meta := vm.ProgramMetadata{Modules: s.scriptModules}
+	for _, constant := range program.constants {
+		meta.Constants = append(meta.Constants, vm.ProgramConstant{Name: constant.Name, Value: constant.Value, Type: constant.Type})
+	}
+	return s.vm.MarshalProgram(meta)
+}
+
+// LoadProgram loads a program serialized by CompileToBytes in place of
+// compiling the source, which is ignored. Host functions, modules and
+// options are not part of the program: they are set on the script as
+// usual, and script modules the program imports are compiled again from
+// the script's packages (see AddFile), the import resolver or the standard
+// library. The loaded program has no
+// syntax tree.
+func (s *Script) LoadProgram(data []byte) (*Program, error) {
+	if s.program != nil {
+		return nil, fmt.Errorf("script is already compiled")
+	}
+	meta, err := s.vm.LoadProgram(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, importPath := range meta.Modules {
+		module, found, err := s.findModule(importPath)
+		if err != nil {
+			return nil, fmt.Errorf("import %q: %w", importPath, err)
+		}
+		if !found {
+			return nil, fmt.Errorf("import %q: script module not found", importPath)
+		}
+		if err := s.loadScriptModule(importPath, module); err != nil {
+			return nil, err
+		}
+	}
+
+	s.program = &Program{vm: s.vm, image: s.vm.Image(), modules: s.modules}
+	for _, constant := range meta.Constants {
+		s.program.constants = append(s.program.constants, Constant{Name: constant.Name, Value: constant.Value, Type: constant.Type})
+	}
+	return s.program, nil
+}
+
+// DumpBytecode compiles the script and writes an annotated listing of
+// every instruction set (see VM.Disassemble), for debugging compiler
+// output
+func (s *Script) DumpBytecode(w io.Writer) error {
+	program, err := s.Compile()
+	if err != nil {
+		return err
+	}
+	return program.Disassemble(w)
+}
@@ -0,0 +1,208 @@
+// Command goscript runs, compiles and disassembles GoScript programs.
+//
+// Usage:
+//
+//	goscript run [flags] file.gs|file.gsc
+//	goscript build [-o file.gsc] file.gs
+//	goscript disasm [-func name] file.gs|file.gsc
+//	goscript repl
+//
+// Files ending in .gsc hold bytecode written by build; any other file is
+// compiled from source.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/repl"
+)
+
+// bytecodeExt is the extension of files holding serialized bytecode
+const bytecodeExt = ".gsc"
+
+const usage = `usage: goscript <command> [flags] file
+
+commands:
+  run     execute a script
+  build   compile a script to bytecode
+  disasm  print the instructions of a script
+  repl    start an interactive interpreter
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "run":
+		err = runCommand(args[1:], stdout, stderr)
+	case "build":
+		err = buildCommand(args[1:], stderr)
+	case "disasm":
+		err = disasmCommand(args[1:], stdout, stderr)
+	case "repl":
+		err = repl.New().Run(stdin, stdout)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "goscript: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	var usageErr usageError
+	var runtimeErr *goscript.RuntimeError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "goscript %s: %v\n", args[0], err)
+		return 2
+	case errors.As(err, &runtimeErr):
+		fmt.Fprintln(stderr, runtimeErr.StackTrace())
+		return 1
+	default:
+		fmt.Fprintf(stderr, "goscript %s: %v\n", args[0], err)
+		return 1
+	}
+}
+
+// usageError is an error in the command line
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// runCommand implements "goscript run"
+func runCommand(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	maxInstructions := flags.Int64("max-instructions", 0, "maximum number of executed instructions (0 means no limit)")
+	maxMemory := flags.Int64("max-memory", 0, "approximate maximum bytes allocated (0 means no limit)")
+	timeout := flags.Duration("timeout", 0, "maximum execution time (0 means no limit)")
+	file, err := parseFile(flags, args)
+	if err != nil {
+		return err
+	}
+
+	script, err := loadScript(file)
+	if err != nil {
+		return err
+	}
+	script.SetMaxInstructions(*maxInstructions)
+	script.SetMaxMemory(*maxMemory)
+	script.SetTimeout(*timeout)
+	script.SetStdout(stdout)
+	script.SetStderr(stderr)
+
+	result, err := script.Run()
+	if err != nil {
+		return err
+	}
+	if result != nil {
+		fmt.Fprintln(stdout, result)
+	}
+	return nil
+}
+
+// buildCommand implements "goscript build"
+func buildCommand(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "", "output file (default: the source file with the "+bytecodeExt+" extension)")
+	file, err := parseFile(flags, args)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(file, filepath.Ext(file)) + bytecodeExt
+	}
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	data, err := goscript.NewScript(source).CompileToBytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
+
+// disasmCommand implements "goscript disasm"
+func disasmCommand(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	function := flags.String("func", "", "only list the given function or instruction set key")
+	file, err := parseFile(flags, args)
+	if err != nil {
+		return err
+	}
+
+	script, err := loadScript(file)
+	if err != nil {
+		return err
+	}
+	program, err := script.Compile()
+	if err != nil {
+		return err
+	}
+	if *function != "" {
+		return program.DisassembleFunction(stdout, *function)
+	}
+	return program.Disassemble(stdout)
+}
+
+// parseFile parses the flags of a command and returns its file argument.
+// Flags may come before or after the file.
+func parseFile(flags *flag.FlagSet, args []string) (string, error) {
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if flags.NArg() == 0 {
+		return "", usageError("missing file argument")
+	}
+	file := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return "", err
+	}
+	if flags.NArg() > 0 {
+		return "", usageError(fmt.Sprintf("unexpected arguments after %s: %s", file, strings.Join(flags.Args(), " ")))
+	}
+	return file, nil
+}
+
+// loadScript reads a script from source, or from bytecode for a file with
+// the bytecode extension
+func loadScript(file string) (*goscript.Script, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(file) != bytecodeExt {
+		return goscript.NewScript(data), nil
+	}
+	script := goscript.NewScript(nil)
+	if _, err := script.LoadProgram(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return script, nil
+}
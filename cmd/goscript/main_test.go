@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testScript = `package main
+
+import "fmt"
+
+func add(a, b int) int {
+	return a + b
+}
+
+func main() {
+	fmt.Println("hello")
+	return add(1, 2)
+}
+`
+
+func TestRunBuildDisasm(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "add.gs")
+	if err := os.WriteFile(source, []byte(testScript), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"run", source}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited with %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "hello\n3\n" {
+		t.Errorf("Expected the output and the result 3, got %q", stdout.String())
+	}
+
+	// Flags may follow the file
+	if code := run([]string{"build", source, "-o", filepath.Join(dir, "out.gsc")}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("build exited with %d: %s", code, stderr.String())
+	}
+	stdout.Reset()
+	if code := run([]string{"run", filepath.Join(dir, "out.gsc")}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run of bytecode exited with %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "hello\n3\n" {
+		t.Errorf("Expected the output and the result 3, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"disasm", "-func", "add", source}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("disasm exited with %d: %s", code, stderr.String())
+	}
+	listing := stdout.String()
+	if !strings.HasPrefix(listing, "main.func.add:\n") || !strings.Contains(listing, "6:2") || !strings.Contains(listing, "BINARY_OP") {
+		t.Errorf("Unexpected listing:\n%s", listing)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := t.TempDir()
+	loop := filepath.Join(dir, "loop.gs")
+	if err := os.WriteFile(loop, []byte("package main\n\nfunc main() {\n\tfor {\n\t}\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"no command", nil, 2, "usage"},
+		{"unknown command", []string{"fmt"}, 2, "unknown command"},
+		{"missing file", []string{"run"}, 2, "missing file"},
+		{"extra arguments", []string{"run", loop, "x"}, 2, "unexpected arguments"},
+		{"no such file", []string{"run", filepath.Join(dir, "none.gs")}, 1, "no such file"},
+		{"instruction limit", []string{"run", "-max-instructions", "100", loop}, 1, "instruction limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, nil, &stdout, &stderr); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d", tt.code, code)
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("Expected %q in stderr, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRepl(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("x := 40\nx + 2\n:quit\n")
+	if code := run([]string{"repl"}, stdin, &stdout, &stderr); code != 0 {
+		t.Fatalf("repl exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "42\n") {
+		t.Errorf("Expected 42 in the output, got %q", stdout.String())
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/lengzhao/goscript"
 	"github.com/lengzhao/goscript/instruction"
-	"github.com/lengzhao/goscript/vm"
 )
 
 func main() {
-	// Create a new VM
-	vmInstance := vm.NewVM()
+	// The VM of an empty script
+	vmInstance := goscript.NewScript(nil).GetVM()
 
 	// Create a simple "add" function that takes two arguments and returns their sum
 	// The function expects two arguments: arg0 and arg1
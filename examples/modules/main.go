@@ -4,13 +4,13 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/lengzhao/goscript"
 	"github.com/lengzhao/goscript/builtin"
-	"github.com/lengzhao/goscript/vm"
 )
 
 func main() {
-	// Create a new VM
-	vmInstance := vm.NewVM()
+	// The VM of an empty script
+	vmInstance := goscript.NewScript(nil).GetVM()
 
 	// Register builtin modules using the new ModuleExecutor interface
 	modules := []string{"strings", "math"}
@@ -0,0 +1,98 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Source files. A script can consist of several source files: the files of
+// its package are compiled together, so functions, types and variables
+// declared in one file are visible in the others. Files in subdirectories
+// belong to other packages, which the script imports by directory (e.g.,
+// import "lib/pricing" for the files in lib/pricing) and which are compiled
+// as script modules.
+
+// sourceFile is a source file of a script, named by its slash-separated
+// path
+type sourceFile struct {
+	name   string
+	source []byte
+}
+
+// NewScriptFromDir returns a script made of the GoScript files (*.gs) in
+// dir: those directly in dir form the script's package, and those in each
+// subdirectory a package the script can import by its path relative to dir
+func NewScriptFromDir(dir string) (*Script, error) {
+	s := NewScript(nil)
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(file) != ".gs" {
+			return err
+		}
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		s.AddFile(filepath.ToSlash(name), source)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(s.files) == 0 {
+		return nil, fmt.Errorf("no GoScript files in %s", dir)
+	}
+	return s, nil
+}
+
+// AddFile adds a source file to the script. name is the file's
+// slash-separated path: files without a directory (e.g., "util.gs") are
+// compiled with the script's source as one package, and files in a
+// directory (e.g., "lib/pricing/tax.gs") form the package with that import
+// path. It must be called before the script is compiled.
+func (s *Script) AddFile(name string, src []byte) {
+	file := sourceFile{name: name, source: src}
+	dir := path.Dir(name)
+	if dir == "." {
+		s.files = append(s.files, file)
+		return
+	}
+	if s.packages == nil {
+		s.packages = make(map[string][]sourceFile)
+	}
+	s.packages[dir] = append(s.packages[dir], file)
+}
+
+// mergeFiles merges the syntax trees of the files of a package into one
+func mergeFiles(files []sourceFile, parsed []*ast.File) (*ast.File, error) {
+	first := parsed[0]
+	if len(parsed) == 1 {
+		return first, nil
+	}
+	merged := &ast.File{
+		Doc:       first.Doc,
+		Package:   first.Package,
+		Name:      first.Name,
+		FileStart: first.FileStart,
+		FileEnd:   parsed[len(parsed)-1].FileEnd,
+		GoVersion: first.GoVersion,
+	}
+	for i, file := range parsed {
+		if file.Name.Name != first.Name.Name {
+			return nil, fmt.Errorf("found packages %s (%s) and %s (%s)",
+				first.Name.Name, files[0].name, file.Name.Name, files[i].name)
+		}
+		merged.Decls = append(merged.Decls, file.Decls...)
+		merged.Imports = append(merged.Imports, file.Imports...)
+		merged.Unresolved = append(merged.Unresolved, file.Unresolved...)
+		merged.Comments = append(merged.Comments, file.Comments...)
+	}
+	return merged, nil
+}
@@ -0,0 +1,83 @@
+package goscript
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+// Handler returns a Go function that calls the script function name, e.g.
+// to register it as an event handler. The script is compiled and the
+// function looked up by Handler, so a missing function is reported before
+// any event. Arguments are converted to script values and the first result
+// is stored in the Go variable outPtr points to, unless outPtr is nil; a
+// non-nil error returned as the last result of the function is returned by
+// the call. Like the script, the
+// handler is not safe for concurrent use: use a Runner per goroutine.
+func (s *Script) Handler(name string, outPtr interface{}) (func(args ...interface{}) error, error) {
+	call, err := s.handler(name)
+	if err != nil {
+		return nil, err
+	}
+	return func(args ...interface{}) error {
+		result, err := call(args)
+		if err != nil || outPtr == nil {
+			return err
+		}
+		if err := vm.Scan(result, outPtr); err != nil {
+			return fmt.Errorf("result of %s: %w", name, err)
+		}
+		return nil
+	}, nil
+}
+
+// Func returns a Go function that calls the script function name and
+// returns its first result as a T, like Script.Handler
+func Func[T any](s *Script, name string) (func(args ...interface{}) (T, error), error) {
+	call, err := s.handler(name)
+	if err != nil {
+		return nil, err
+	}
+	return func(args ...interface{}) (T, error) {
+		var out T
+		result, err := call(args)
+		if err != nil {
+			return out, err
+		}
+		if err := vm.Scan(result, &out); err != nil {
+			return out, fmt.Errorf("result of %s: %w", name, err)
+		}
+		return out, nil
+	}, nil
+}
+
+// handler resolves a script function and returns a function calling it
+// with converted arguments, which returns its first result
+func (s *Script) handler(name string) (func(args []interface{}) (interface{}, error), error) {
+	program, err := s.Compile()
+	if err != nil {
+		return nil, err
+	}
+	key, ok := program.resolveEntry(name)
+	if !ok {
+		return nil, fmt.Errorf("function %s not found", name)
+	}
+	return func(args []interface{}) (interface{}, error) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			values[i] = s.vm.ScriptValue(arg)
+		}
+		s.vm.SetMaxInstructions(s.maxInstructions)
+		s.vm.SetHostContext(s.hostContext(context.Background()))
+		result, err := s.vm.Execute(key, values...)
+		if err != nil {
+			return nil, err
+		}
+		results := vm.Results(result)
+		if last, ok := results[len(results)-1].(error); ok && len(results) > 1 {
+			return nil, last
+		}
+		return results[0], nil
+	}, nil
+}
@@ -0,0 +1,185 @@
+package goscript
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"strconv"
+
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/scripts"
+)
+
+// ImportResolver returns the GoScript source of the module with the given
+// import path. found is false for paths the resolver does not provide.
+type ImportResolver func(importPath string) (source []byte, found bool, err error)
+
+// SetImportResolver sets the resolver that provides script modules: modules
+// written in GoScript that the script imports like any other module (e.g.,
+// import "lib/pricing"). Paths the resolver does not provide fall back to
+// the standard library ("std/..."). It must be set before the script is
+// compiled.
+func (s *Script) SetImportResolver(resolver ImportResolver) {
+	s.importResolver = resolver
+}
+
+// ModuleResolver returns the GoScript source of the module with the given
+// import path. It reports paths it does not provide with a nil source or an
+// error wrapping fs.ErrNotExist, so a resolver reading files, e.g.
+//
+//	func(path string) ([]byte, error) {
+//		return os.ReadFile(filepath.Join(dir, path+".gs"))
+//	}
+//
+// leaves other paths to the standard library.
+type ModuleResolver func(importPath string) ([]byte, error)
+
+// SetModuleResolver sets the resolver that provides script modules, like
+// SetImportResolver
+func (s *Script) SetModuleResolver(resolver ModuleResolver) {
+	s.SetImportResolver(func(importPath string) ([]byte, bool, error) {
+		source, err := resolver(importPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return source, err == nil && source != nil, err
+	})
+}
+
+// SetAllowedModules restricts the modules the script may import to the
+// given import paths; importing another module fails with an error
+// wrapping ErrModuleNotAllowed. nil allows every module that is not
+// denied; an empty list allows none. Script modules inherit the policy.
+func (s *Script) SetAllowedModules(modules []string) {
+	s.vm.SetAllowedModules(modules)
+}
+
+// SetDeniedModules forbids the script to import the given import paths,
+// even when they are allowed by SetAllowedModules
+func (s *Script) SetDeniedModules(modules []string) {
+	s.vm.SetDeniedModules(modules)
+}
+
+// loadScriptModules compiles the script modules imported by file and
+// registers each with the VM under its import path. Builtin modules and
+// modules registered by the host take precedence; unknown paths are left
+// to fail when the script uses them.
+// Imports of modules the host does not allow fail.
+func (s *Script) loadScriptModules(file *ast.File) error {
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return fmt.Errorf("invalid import path %s", spec.Path.Value)
+		}
+		if err := s.vm.CheckModule(importPath); err != nil {
+			return fmt.Errorf("%s: %w", s.fset.Position(spec.Pos()), err)
+		}
+		if s.isBuiltinModule(importPath) {
+			continue
+		}
+		if _, exists := s.vm.GetModule(importPath); exists {
+			continue
+		}
+
+		module, found, err := s.findModule(importPath)
+		if err != nil {
+			return fmt.Errorf("import %q: %w", importPath, err)
+		}
+		if !found {
+			continue
+		}
+		if err := s.loadScriptModule(importPath, module); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findModule returns an uncompiled script for a script module: a package
+// of the script's files (see AddFile), or the source the resolver or the
+// standard library provides
+func (s *Script) findModule(importPath string) (*Script, bool, error) {
+	if files, exists := s.packages[importPath]; exists {
+		module := NewScript(nil)
+		module.files = files
+		return module, true, nil
+	}
+	source, found, err := s.resolveImport(importPath)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	return NewScript(source), true, nil
+}
+
+// resolveImport looks up the source of a script module, first with the
+// resolver, then in the standard library
+func (s *Script) resolveImport(importPath string) ([]byte, bool, error) {
+	if s.importResolver != nil {
+		source, found, err := s.importResolver(importPath)
+		if err != nil || found {
+			return source, found, err
+		}
+	}
+	source, found := scripts.Std(importPath)
+	return source, found, nil
+}
+
+// loadScriptModule compiles a script module in a VM of its own and exposes
+// its exported functions as module functions
+func (s *Script) loadScriptModule(importPath string, module *Script) error {
+	if s.importing[importPath] {
+		return fmt.Errorf("import cycle not allowed: %s", importPath)
+	}
+
+	module.importResolver = s.importResolver
+	module.compileLimits = s.compileLimits
+	module.packages = s.packages
+	allowed, denied := s.vm.ModulePolicy()
+	module.SetAllowedModules(allowed)
+	module.SetDeniedModules(denied)
+	module.importing = map[string]bool{importPath: true}
+	for path := range s.importing {
+		module.importing[path] = true
+	}
+	program, err := module.Compile()
+	if err != nil {
+		return fmt.Errorf("import %q: %w", importPath, err)
+	}
+	s.addScriptModule(importPath, module)
+	s.modules = append(s.modules, programModule{path: importPath, program: program})
+	return nil
+}
+
+// addScriptModule exposes the exported functions of a compiled script
+// module as module functions
+func (s *Script) addScriptModule(importPath string, module *Script) {
+	// Each call is a complete execution of the module, so the module's
+	// package-level code (its own imports) runs before the function. It is
+	// nested in the execution of the script, whose context and limits apply.
+	functions := module.vm.GetAllScriptFunctions()
+	module.vm.SetStdout(s.vm.StdoutWriter())
+	s.vm.RegisterModule(importPath, func(entrypoint string, args ...interface{}) (interface{}, error) {
+		if !token.IsExported(entrypoint) {
+			return nil, fmt.Errorf("cannot refer to unexported function %s of module %s", entrypoint, importPath)
+		}
+		info, exists := functions[entrypoint]
+		if !exists {
+			return nil, fmt.Errorf("function %s not found in module %s", entrypoint, importPath)
+		}
+		return s.vm.ExecuteNested(module.vm, info.Key, args...)
+	})
+	s.OnClose(module.Close)
+	s.scriptModules = append(s.scriptModules, importPath)
+}
+
+// isBuiltinModule reports whether importPath names a builtin module
+func (s *Script) isBuiltinModule(importPath string) bool {
+	for _, name := range builtin.ListAllModules() {
+		if name == importPath {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package instruction
+
+import "testing"
+
+// Dispatch benchmarks comparing the executor's []*Instruction layout with a
+// compact one: one opcode byte per instruction, an int operand and an index
+// into a constant pool for other operands. See "Instruction Format" in
+// docs/architecture.md for the measured result.
+
+// compactCode is a program in the compact layout
+type compactCode struct {
+	ops    []OpCode
+	tags   []Tag
+	ints   []int
+	consts []interface{}
+}
+
+// encodeCompact converts a program to the compact layout; int operands are
+// stored inline and other operands as constant pool indices
+func encodeCompact(program []*Instruction) *compactCode {
+	code := &compactCode{
+		ops:  make([]OpCode, len(program)),
+		tags: make([]Tag, len(program)),
+		ints: make([]int, len(program)),
+	}
+	for i, instr := range program {
+		code.ops[i], code.tags[i] = instr.Op, instr.Tags
+		if n, ok := instr.Arg.(int); ok {
+			code.ints[i] = n
+			continue
+		}
+		code.ints[i] = len(code.consts)
+		code.consts = append(code.consts, instr.Arg)
+	}
+	return code
+}
+
+// loopProgram mirrors the instructions of a counting loop:
+// for i := 0; i < 100; i++ { total = total + i }
+func loopProgram() []*Instruction {
+	return []*Instruction{
+		NewInstruction(OpLoadConst, 0),
+		NewInstruction(OpCreateVar, "i"),
+		NewInstruction(OpLoadName, "i"),
+		NewInstruction(OpLoadConst, 100),
+		NewInstruction(OpBinaryOp, OpLess),
+		NewInstruction(OpJumpIf, 14),
+		NewInstruction(OpLoadName, "total"),
+		NewInstruction(OpLoadName, "i"),
+		NewInstruction(OpBinaryOp, OpAdd),
+		NewInstruction(OpStoreName, "total"),
+		NewInstruction(OpLoadName, "i"),
+		NewInstruction(OpLoadConst, 1),
+		NewInstruction(OpBinaryOp, OpAdd),
+		NewInstruction(OpJump, 2),
+		NewInstruction(OpCall, "fmt.Println", 1).WithTags(TagHostCall),
+		NewInstruction(OpReturn, nil),
+	}
+}
+
+// dispatchPointers walks a program the way the executor does
+func dispatchPointers(program []*Instruction) int {
+	sum := 0
+	for _, instr := range program {
+		switch instr.Op {
+		case OpJump, OpJumpIf:
+			sum += instr.Arg.(int)
+		default:
+			sum += int(instr.Op) + int(instr.Tags)
+		}
+	}
+	return sum
+}
+
+// dispatchCompact walks the same program in the compact layout
+func dispatchCompact(code *compactCode) int {
+	sum := 0
+	for i, op := range code.ops {
+		switch op {
+		case OpJump, OpJumpIf:
+			sum += code.ints[i]
+		default:
+			sum += int(op) + int(code.tags[i])
+		}
+	}
+	return sum
+}
+
+func TestDispatchEquivalence(t *testing.T) {
+	program := loopProgram()
+	if got, want := dispatchCompact(encodeCompact(program)), dispatchPointers(program); got != want {
+		t.Errorf("Expected %d, got %d", want, got)
+	}
+}
+
+func benchmarkProgram() []*Instruction {
+	var program []*Instruction
+	for i := 0; i < 64; i++ {
+		program = append(program, loopProgram()...)
+	}
+	return program
+}
+
+func BenchmarkDispatchPointers(b *testing.B) {
+	program := benchmarkProgram()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dispatchPointers(program)
+	}
+}
+
+func BenchmarkDispatchCompact(b *testing.B) {
+	code := encodeCompact(benchmarkProgram())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dispatchCompact(code)
+	}
+}
@@ -91,10 +91,11 @@ const (
 	// Exit a scope with a specific key
 	OpExitScopeWithKey
 
-	// Create a new variable
+	// Create a new variable; with an int second argument, declare the local
+	// variable in that slot of the current frame instead
 	OpCreateVar
 
-	// Break from loop
+	// Break from loop (not emitted; break and continue compile to jumps)
 	OpBreak
 
 	// Start of switch statement
@@ -112,6 +113,82 @@ const (
 	// Define a label
 	OpLabel
 
+	// Replace the tuple on top of the stack by its values
+	OpUnpack
+
+	// Create a new map with the key type given by the argument
+	OpNewMap
+
+	// Create a function value for the instruction set given by the argument
+	OpMakeClosure
+
+	// Start a call like OpCall in a new goroutine
+	OpGo
+
+	// Create a channel of the element type given by the argument with the
+	// capacity on top of the stack
+	OpMakeChan
+
+	// Send the value on top of the stack on the channel below it
+	OpSend
+
+	// Receive from the channel on top of the stack (with ok if the argument
+	// is true)
+	OpRecv
+
+	// Wait until one of the cases given by the argument can proceed
+	OpSelect
+
+	// Mark the slice on top of the stack to be expanded into the arguments
+	// of the following call (f(xs...))
+	OpSpread
+
+	// Push a pointer to the variable given by the argument
+	OpAddrOf
+
+	// Push a pointer to the field given by the argument of the struct on
+	// top of the stack
+	OpAddrField
+
+	// Push a pointer to the element of the slice below the index on top of
+	// the stack
+	OpAddrIndex
+
+	// Replace the pointer on top of the stack by the value it points to
+	OpDeref
+
+	// Store the value on top of the stack through the pointer below it
+	OpStoreDeref
+
+	// Create a slice of the element type given by the argument, with the
+	// length and capacity on top of the stack
+	OpMakeSlice
+
+	// Check that the value on top of the stack has the type given by the
+	// argument; the second argument is the mode (AssertValue, AssertCommaOk
+	// or AssertTest)
+	OpTypeAssert
+
+	// Check that the value on top of the stack, which stays there, is nil
+	// or has the methods of an interface; the arguments are the interface
+	// name and its methods ("interface{Area,Perimeter}")
+	OpImplements
+
+	// Push the local variable in the slot of the current frame given by the
+	// argument; the second argument is the variable's name
+	OpLoadLocal
+
+	// Store the value on top of the stack in the local variable slot given
+	// by the argument; the second argument is the variable's name
+	OpStoreLocal
+
+	// Push copies of the top values of the stack, as many as the argument
+	// gives, in the same order
+	OpDup
+
+	// Replace the struct on top of the stack by a pointer to it (&T{...})
+	OpAddrValue
+
 	OpCodeLast
 )
 
@@ -186,11 +263,88 @@ func (op OpCode) String() string {
 		return "OpSwitchEnd"
 	case OpLabel:
 		return "OpLabel"
+	case OpUnpack:
+		return "OpUnpack"
+	case OpNewMap:
+		return "OpNewMap"
+	case OpMakeClosure:
+		return "OpMakeClosure"
+	case OpGo:
+		return "OpGo"
+	case OpMakeChan:
+		return "OpMakeChan"
+	case OpSend:
+		return "OpSend"
+	case OpRecv:
+		return "OpRecv"
+	case OpSelect:
+		return "OpSelect"
+	case OpSpread:
+		return "OpSpread"
+	case OpAddrOf:
+		return "OpAddrOf"
+	case OpAddrField:
+		return "OpAddrField"
+	case OpAddrIndex:
+		return "OpAddrIndex"
+	case OpDeref:
+		return "OpDeref"
+	case OpStoreDeref:
+		return "OpStoreDeref"
+	case OpMakeSlice:
+		return "OpMakeSlice"
+	case OpTypeAssert:
+		return "OpTypeAssert"
+	case OpImplements:
+		return "OpImplements"
+	case OpLoadLocal:
+		return "OpLoadLocal"
+	case OpStoreLocal:
+		return "OpStoreLocal"
+	case OpDup:
+		return "OpDup"
+	case OpAddrValue:
+		return "OpAddrValue"
 	default:
 		return fmt.Sprintf("OpCode(%d)", op)
 	}
 }
 
+// SelectCase is the direction of a case of a SELECT instruction. The
+// instruction's argument lists its cases in source order.
+type SelectCase byte
+
+const (
+	// SelectRecv receives from the channel on the stack
+	SelectRecv SelectCase = iota
+
+	// SelectSend sends the value on top of the stack on the channel below it
+	SelectSend
+)
+
+// String returns the string representation of a SelectCase
+func (c SelectCase) String() string {
+	if c == SelectSend {
+		return "send"
+	}
+	return "recv"
+}
+
+// Modes of a TYPE_ASSERT instruction, given by its second argument
+const (
+	// AssertValue pushes the value, failing if it does not have the type
+	// (x.(T))
+	AssertValue = iota
+
+	// AssertCommaOk pushes the value, or the zero value of the type, and
+	// whether the value has the type (v, ok := x.(T))
+	AssertCommaOk
+
+	// AssertTest pushes whether the value has the type (a case of a type
+	// switch)
+	AssertTest
+)
+
 // BinaryOp represents a binary operation
 type BinaryOp byte
 
@@ -210,6 +364,31 @@ const (
 	OpOr
 )
 
+// binaryOpNames are the Go operators of the binary operations
+var binaryOpNames = [...]string{
+	OpAdd:          "+",
+	OpSub:          "-",
+	OpMul:          "*",
+	OpDiv:          "/",
+	OpMod:          "%",
+	OpEqual:        "==",
+	OpNotEqual:     "!=",
+	OpLess:         "<",
+	OpLessEqual:    "<=",
+	OpGreater:      ">",
+	OpGreaterEqual: ">=",
+	OpAnd:          "&&",
+	OpOr:           "||",
+}
+
+// String returns the Go operator of a BinaryOp (e.g., "+")
+func (op BinaryOp) String() string {
+	if int(op) < len(binaryOpNames) {
+		return binaryOpNames[op]
+	}
+	return fmt.Sprintf("BinaryOp(%d)", byte(op))
+}
+
 // UnaryOp represents a unary operation
 type UnaryOp byte
 
@@ -218,11 +397,65 @@ const (
 	OpNot
 )
 
+// String returns the Go operator of a UnaryOp (e.g., "!")
+func (op UnaryOp) String() string {
+	switch op {
+	case OpNeg:
+		return "-"
+	case OpNot:
+		return "!"
+	default:
+		return fmt.Sprintf("UnaryOp(%d)", byte(op))
+	}
+}
+
+// Tag annotates instructions that cross the script/host boundary
+type Tag uint8
+
+const (
+	// Call to a host-provided (registered or builtin) function
+	TagHostCall Tag = 1 << iota
+
+	// Call to a function of an imported module
+	TagModuleCall
+
+	// Import of a module
+	TagImport
+)
+
+// String returns the string representation of a Tag
+func (t Tag) String() string {
+	switch t {
+	case TagHostCall:
+		return "host_call"
+	case TagModuleCall:
+		return "module_call"
+	case TagImport:
+		return "import"
+	default:
+		return fmt.Sprintf("Tag(%d)", t)
+	}
+}
+
 // Instruction represents a single VM instruction
 type Instruction struct {
 	Op   OpCode
 	Arg  interface{}
 	Arg2 interface{}
+
+	// Tags marks security-relevant instructions (0 for ordinary ones)
+	Tags Tag
+
+	// Line is the source line the instruction was compiled from (0 if
+	// unknown); StmtStart marks the first instruction of a statement.
+	// Debuggers use them to step by line or statement.
+	Line      int
+	StmtStart bool
+
+	// File and Column complete the position of the statement, which
+	// runtime errors are reported at
+	File   string
+	Column int
 }
 
 // NewInstruction creates a new instruction
@@ -239,6 +472,17 @@ func NewInstruction(op OpCode, arg interface{}, arg2 ...interface{}) *Instructio
 	return instr
 }
 
+// WithTags sets the tags of an instruction and returns it
+func (i *Instruction) WithTags(tags Tag) *Instruction {
+	i.Tags = tags
+	return i
+}
+
+// HasTag reports whether the instruction carries the given tag
+func (i *Instruction) HasTag(tag Tag) bool {
+	return i.Tags&tag != 0
+}
+
 // String returns the string representation of an instruction
 func (i *Instruction) String() string {
 	switch i.Op {
@@ -253,16 +497,25 @@ func (i *Instruction) String() string {
 	case OpPop:
 		return "POP"
 	case OpCall:
+		if i.Arg == nil {
+			return fmt.Sprintf("CALL_VALUE %v", i.Arg2)
+		}
 		return fmt.Sprintf("CALL %v %v", i.Arg, i.Arg2)
 	case OpCallMethod:
 		return fmt.Sprintf("CALL_METHOD %v %v", i.Arg, i.Arg2)
 	case OpRegistFunction:
 		return fmt.Sprintf("REGIST_FUNCTION %v %v", i.Arg, i.Arg2)
 	case OpReturn:
+		if n, ok := i.Arg.(int); ok && n > 1 {
+			return fmt.Sprintf("RETURN %d", n)
+		}
 		return "RETURN"
 	case OpJump:
 		return fmt.Sprintf("JUMP %v", i.Arg)
 	case OpJumpIf:
+		if i.Arg2 == nil {
+			return fmt.Sprintf("JUMP_IF %v", i.Arg)
+		}
 		return fmt.Sprintf("JUMP_IF %v %v", i.Arg, i.Arg2)
 	case OpBinaryOp:
 		return fmt.Sprintf("BINARY_OP %v", i.Arg)
@@ -277,6 +530,9 @@ func (i *Instruction) String() string {
 	case OpSetStructField:
 		return fmt.Sprintf("SET_STRUCT_FIELD %v", i.Arg)
 	case OpGetIndex:
+		if i.Arg2 != nil {
+			return fmt.Sprintf("GET_INDEX %v %v", i.Arg, i.Arg2)
+		}
 		return fmt.Sprintf("GET_INDEX %v", i.Arg)
 	case OpSetIndex:
 		return fmt.Sprintf("SET_INDEX %v", i.Arg)
@@ -297,6 +553,9 @@ func (i *Instruction) String() string {
 	case OpExitScopeWithKey:
 		return fmt.Sprintf("EXIT_SCOPE_WITH_KEY %v", i.Arg)
 	case OpCreateVar:
+		if slot, ok := i.Arg2.(int); ok {
+			return fmt.Sprintf("CREATE_VAR %v %d", i.Arg, slot)
+		}
 		return fmt.Sprintf("CREATE_VAR %v", i.Arg)
 	case OpBreak:
 		return "BREAK"
@@ -310,6 +569,63 @@ func (i *Instruction) String() string {
 		return "SWITCH_END"
 	case OpLabel:
 		return fmt.Sprintf("LABEL %v", i.Arg)
+	case OpUnpack:
+		return fmt.Sprintf("UNPACK %v", i.Arg)
+	case OpNewMap:
+		return fmt.Sprintf("NEW_MAP %v", i.Arg)
+	case OpMakeClosure:
+		return fmt.Sprintf("MAKE_CLOSURE %v", i.Arg)
+	case OpGo:
+		if i.Arg == nil {
+			return fmt.Sprintf("GO_VALUE %v", i.Arg2)
+		}
+		return fmt.Sprintf("GO %v %v", i.Arg, i.Arg2)
+	case OpMakeChan:
+		return fmt.Sprintf("MAKE_CHAN %v", i.Arg)
+	case OpSend:
+		return "SEND"
+	case OpRecv:
+		if ok, _ := i.Arg.(bool); ok {
+			return "RECV_OK"
+		}
+		return "RECV"
+	case OpSelect:
+		if hasDefault, _ := i.Arg2.(bool); hasDefault {
+			return fmt.Sprintf("SELECT %v default", i.Arg)
+		}
+		return fmt.Sprintf("SELECT %v", i.Arg)
+	case OpSpread:
+		return "SPREAD"
+	case OpAddrOf:
+		return fmt.Sprintf("ADDR_OF %v", i.Arg)
+	case OpAddrField:
+		return fmt.Sprintf("ADDR_FIELD %v", i.Arg)
+	case OpAddrIndex:
+		return "ADDR_INDEX"
+	case OpDeref:
+		return "DEREF"
+	case OpStoreDeref:
+		return "STORE_DEREF"
+	case OpMakeSlice:
+		return fmt.Sprintf("MAKE_SLICE %v", i.Arg)
+	case OpTypeAssert:
+		switch i.Arg2 {
+		case AssertCommaOk:
+			return fmt.Sprintf("TYPE_ASSERT %v ok", i.Arg)
+		case AssertTest:
+			return fmt.Sprintf("TYPE_ASSERT %v test", i.Arg)
+		}
+		return fmt.Sprintf("TYPE_ASSERT %v", i.Arg)
+	case OpImplements:
+		return fmt.Sprintf("IMPLEMENTS %v %v", i.Arg, i.Arg2)
+	case OpLoadLocal:
+		return fmt.Sprintf("LOAD_LOCAL %v %v", i.Arg, i.Arg2)
+	case OpStoreLocal:
+		return fmt.Sprintf("STORE_LOCAL %v %v", i.Arg, i.Arg2)
+	case OpDup:
+		return fmt.Sprintf("DUP %v", i.Arg)
+	case OpAddrValue:
+		return "ADDR_VALUE"
 	default:
 		return fmt.Sprintf("UNKNOWN(%d) %v %v", i.Op, i.Arg, i.Arg2)
 	}
@@ -0,0 +1,136 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// branchTarget is an enclosing statement that break leaves: a loop, switch
+// or select. Loops can also be continued.
+type branchTarget struct {
+	// Label of the statement ("" if it is not labeled)
+	label string
+
+	// Labels emitted after the statement and, for loops, before the post
+	// statement or the next iteration ("" for switch and select)
+	breakLabel    string
+	continueLabel string
+
+	// Number of runtime scopes entered where the labels are emitted; a
+	// loop with per-iteration variables continues in the scope of the
+	// iteration
+	depth         int
+	continueDepth int
+}
+
+// pushBranchTarget opens a statement that break (and continue, for loops)
+// can target, labeled with the label of an enclosing labeled statement
+func (c *Compiler) pushBranchTarget(loop bool) *branchTarget {
+	target := &branchTarget{
+		label:      c.pendingLabel,
+		breakLabel: c.generateKey("break"),
+		depth:      c.scopeDepth,
+	}
+	target.continueDepth = target.depth
+	if loop {
+		target.continueLabel = c.generateKey("continue")
+	}
+	c.pendingLabel = ""
+	c.branchTargets = append(c.branchTargets, target)
+	return target
+}
+
+// popBranchTarget closes the innermost branch target
+func (c *Compiler) popBranchTarget() {
+	c.branchTargets = c.branchTargets[:len(c.branchTargets)-1]
+}
+
+// compileLabeledStmt compiles a labeled statement
+func (c *Compiler) compileLabeledStmt(stmt *ast.LabeledStmt) error {
+	// Record the position of this label
+	labelName := stmt.Label.Name
+	c.labelPositions[labelName] = len(c.currentInstructions)
+
+	// Emit a label instruction
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, labelName, nil))
+
+	// A labeled loop, switch or select can be the target of break and
+	// continue with the label
+	switch stmt.Stmt.(type) {
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		c.pendingLabel = labelName
+		defer func() { c.pendingLabel = "" }()
+	}
+
+	// Compile the statement that follows the label
+	return c.compileStmt(stmt.Stmt)
+}
+
+// compileBranchStmt compiles a branch statement (goto, break, continue, fallthrough)
+func (c *Compiler) compileBranchStmt(stmt *ast.BranchStmt) error {
+	switch stmt.Tok {
+	case token.GOTO:
+		// Handle goto statement
+		if stmt.Label != nil {
+			// Emit a goto instruction with the label name
+			// The actual target position will be resolved later during linking
+			c.emitInstruction(instruction.NewInstruction(instruction.OpJump, stmt.Label.Name, nil))
+		} else {
+			return fmt.Errorf("goto statement must have a label")
+		}
+	case token.BREAK, token.CONTINUE:
+		target, err := c.branchTarget(stmt)
+		if err != nil {
+			return err
+		}
+		jumpLabel, depth := target.breakLabel, target.depth
+		if stmt.Tok == token.CONTINUE {
+			jumpLabel, depth = target.continueLabel, target.continueDepth
+		}
+		// Leave the scopes entered since the target statement. These exits
+		// only run on the branch, so they are not counted in scopeDepth.
+		for i := c.scopeDepth; i > depth; i-- {
+			c.emitInstruction(instruction.NewInstruction(instruction.OpExitScopeWithKey, jumpLabel, nil))
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpJump, jumpLabel, nil))
+	case token.FALLTHROUGH:
+		// compileSwitchStmt handles fallthrough as the last statement of a
+		// case body; anywhere else it is an error
+		return fmt.Errorf("fallthrough statement out of place")
+	default:
+		return fmt.Errorf("unsupported branch statement: %s", stmt.Tok)
+	}
+	return nil
+}
+
+// branchTarget finds the statement a break or continue refers to: the
+// innermost enclosing one, or the one with its label
+func (c *Compiler) branchTarget(stmt *ast.BranchStmt) (*branchTarget, error) {
+	continues := stmt.Tok == token.CONTINUE
+	for i := len(c.branchTargets) - 1; i >= 0; i-- {
+		target := c.branchTargets[i]
+		if stmt.Label != nil {
+			if target.label != stmt.Label.Name {
+				continue
+			}
+			if continues && target.continueLabel == "" {
+				return nil, fmt.Errorf("invalid continue label %s", stmt.Label.Name)
+			}
+			return target, nil
+		}
+		if !continues || target.continueLabel != "" {
+			return target, nil
+		}
+	}
+	switch {
+	case stmt.Label != nil:
+		return nil, fmt.Errorf("invalid %s label %s", stmt.Tok, stmt.Label.Name)
+	case continues:
+		return nil, fmt.Errorf("continue is not in a loop")
+	default:
+		return nil, fmt.Errorf("break is not in a loop, switch, or select")
+	}
+}
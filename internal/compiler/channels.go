@@ -0,0 +1,205 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// compileGoStmt compiles a go statement: the call is compiled as usual and
+// its CALL instruction becomes a GO, which runs it in a new goroutine
+func (c *Compiler) compileGoStmt(stmt *ast.GoStmt) error {
+	start := len(c.currentInstructions)
+	if err := c.compileCallExpr(stmt.Call); err != nil {
+		return err
+	}
+	last := len(c.currentInstructions) - 1
+	if last < start || c.currentInstructions[last].Op != instruction.OpCall {
+		return fmt.Errorf("expression in go must be function call")
+	}
+	c.currentInstructions[last].Op = instruction.OpGo
+	return nil
+}
+
+// compileMakeChan compiles make(chan T[, n])
+func (c *Compiler) compileMakeChan(call *ast.CallExpr, chanType *ast.ChanType) error {
+	if len(call.Args) > 2 {
+		return fmt.Errorf("too many arguments in call to make")
+	}
+	if len(call.Args) == 2 {
+		if err := c.compileExpr(call.Args[1]); err != nil {
+			return err
+		}
+	} else {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, 0, nil))
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpMakeChan, c.getTypeName(chanType.Value), nil))
+	return nil
+}
+
+// compileSendStmt compiles ch <- v
+func (c *Compiler) compileSendStmt(stmt *ast.SendStmt) error {
+	if err := c.compileExpr(stmt.Chan); err != nil {
+		return err
+	}
+	if err := c.compileExpr(stmt.Value); err != nil {
+		return err
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpSend, nil, nil))
+	return nil
+}
+
+// compileRecv compiles <-ch; withOk also pushes whether a value was sent,
+// for v, ok := <-ch
+func (c *Compiler) compileRecv(expr *ast.UnaryExpr, withOk bool) error {
+	if err := c.compileExpr(expr.X); err != nil {
+		return err
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpRecv, withOk, nil))
+	return nil
+}
+
+// isRecv reports whether expr is a receive operation
+func isRecv(expr ast.Expr) (*ast.UnaryExpr, bool) {
+	if paren, ok := expr.(*ast.ParenExpr); ok {
+		return isRecv(paren.X)
+	}
+	unary, ok := expr.(*ast.UnaryExpr)
+	return unary, ok && unary.Op == token.ARROW
+}
+
+// compileSelectStmt compiles a select statement. The operands of all cases
+// are evaluated, in source order, before SELECT chooses a case; SELECT
+// pushes the received value, the ok flag and the index of the chosen case,
+// which are stored in temporary variables and dispatched like a switch.
+func (c *Compiler) compileSelectStmt(stmt *ast.SelectStmt) error {
+	scopeKey := c.generateKey("select")
+	c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+	target := c.pushBranchTarget(false)
+	defer c.popBranchTarget()
+
+	var cases []instruction.SelectCase
+	clauses := make([]*ast.CommClause, len(stmt.Body.List))
+	caseIndex := make([]int, len(stmt.Body.List))
+	hasDefault := false
+	for i, clause := range stmt.Body.List {
+		commClause, ok := clause.(*ast.CommClause)
+		if !ok {
+			return fmt.Errorf("unexpected clause type in select: %T", clause)
+		}
+		clauses[i] = commClause
+
+		switch comm := commClause.Comm.(type) {
+		case nil:
+			if hasDefault {
+				return fmt.Errorf("multiple defaults in select")
+			}
+			hasDefault = true
+			caseIndex[i] = -1
+			continue
+		case *ast.SendStmt:
+			if err := c.compileExpr(comm.Chan); err != nil {
+				return err
+			}
+			if err := c.compileExpr(comm.Value); err != nil {
+				return err
+			}
+			cases = append(cases, instruction.SelectSend)
+		default:
+			recv, ok := selectRecv(comm)
+			if !ok {
+				return fmt.Errorf("select case must be receive, send or assign recv")
+			}
+			if err := c.compileExpr(recv.X); err != nil {
+				return err
+			}
+			cases = append(cases, instruction.SelectRecv)
+		}
+		caseIndex[i] = len(cases) - 1
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpSelect, cases, hasDefault))
+
+	indexVar := c.generateKey("select_index")
+	okVar := c.generateKey("select_ok")
+	valueVar := c.generateKey("select_value")
+	for _, name := range []string{indexVar, okVar, valueVar} {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, name, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, name, nil))
+	}
+
+	endLabel := c.generateKey("end_select")
+	for i, clause := range clauses {
+		nextLabel := c.generateKey("next_case")
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, indexVar, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, caseIndex[i], nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpBinaryOp, instruction.OpEqual, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpJumpIf, nextLabel, nil))
+
+		if err := c.compileCommClause(clause, valueVar, okVar); err != nil {
+			return err
+		}
+
+		c.emitInstruction(instruction.NewInstruction(instruction.OpJump, endLabel, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, nextLabel, nil))
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, endLabel, nil))
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, target.breakLabel, nil))
+
+	c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
+	return nil
+}
+
+// compileCommClause compiles the body of a select case in a scope of its
+// own, first assigning the received value of v := <-ch or v, ok = <-ch
+func (c *Compiler) compileCommClause(clause *ast.CommClause, valueVar, okVar string) error {
+	scopeKey := c.generateKey("case")
+	c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+	c.pushScope()
+	defer c.popScope()
+
+	if assign, ok := clause.Comm.(*ast.AssignStmt); ok {
+		var isNew []bool
+		if assign.Tok == token.DEFINE {
+			var err error
+			if isNew, err = c.defineTargets(assign.Lhs); err != nil {
+				return err
+			}
+		}
+		sources := []string{valueVar, okVar}
+		for i, lhs := range assign.Lhs {
+			ident, ok := lhs.(*ast.Ident)
+			if !ok {
+				return fmt.Errorf("unsupported assignment target in select case: %s", exprString(lhs))
+			}
+			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, sources[i], nil))
+			c.storeTarget(ident.Name, isNew != nil && isNew[i])
+		}
+	}
+
+	for _, stmt := range clause.Body {
+		if err := c.compileStmt(stmt); err != nil {
+			return err
+		}
+	}
+
+	c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
+	return nil
+}
+
+// selectRecv returns the receive operation of a select case: <-ch,
+// v := <-ch or v, ok = <-ch
+func selectRecv(comm ast.Stmt) (*ast.UnaryExpr, bool) {
+	switch s := comm.(type) {
+	case *ast.ExprStmt:
+		return isRecv(s.X)
+	case *ast.AssignStmt:
+		if len(s.Rhs) != 1 || len(s.Lhs) > 2 || (s.Tok != token.ASSIGN && s.Tok != token.DEFINE) {
+			return nil, false
+		}
+		return isRecv(s.Rhs[0])
+	default:
+		return nil, false
+	}
+}
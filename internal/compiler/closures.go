@@ -0,0 +1,96 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+// compileFuncLit compiles a function literal into an instruction set of its
+// own, named after the enclosing function (e.g., main.main.func1), and emits
+// the closure that captures the current scope
+func (c *Compiler) compileFuncLit(lit *ast.FuncLit) error {
+	c.funcLitCounts[c.currentScopeKey]++
+	funcKey := fmt.Sprintf("%s.func%d", c.currentScopeKey, c.funcLitCounts[c.currentScopeKey])
+
+	// Save current state; the literal is compiled in the middle of a statement
+	prevScopeKey := c.currentScopeKey
+	prevInstructions := c.currentInstructions
+	prevStmtPending := c.stmtPending
+	prevBranchTargets, prevScopeDepth := c.branchTargets, c.scopeDepth
+	c.currentScopeKey = funcKey
+	c.currentInstructions = make([]*instruction.Instruction, 0)
+	c.stmtPending = false
+
+	// break and continue cannot leave the literal
+	c.branchTargets, c.scopeDepth = nil, 0
+
+	// The body scope is nested in the enclosing scopes, so names of the
+	// enclosing function resolve to the captured variables
+	paramNames := c.compileParams(lit.Type.Params, nil)
+	c.checkParams(lit.Type.Params)
+	c.pendingParams = paramNames
+	c.pendingParamTypes = paramTypes(lit.Type.Params)
+	err := c.compileBlockStmt(lit.Body)
+	locals := 0
+	if err == nil {
+		locals = resolveLocals(c.currentInstructions)
+		c.compileContext.SetInstructions(funcKey, c.currentInstructions)
+	}
+
+	// Restore previous state
+	c.currentScopeKey = prevScopeKey
+	c.currentInstructions = prevInstructions
+	c.stmtPending = prevStmtPending
+	c.branchTargets, c.scopeDepth = prevBranchTargets, prevScopeDepth
+	if err != nil {
+		return err
+	}
+
+	c.vm.RegisterFunctionLiteral(&vm.ScriptFunctionInfo{
+		Name:       funcKey,
+		Key:        funcKey,
+		ParamCount: len(paramNames),
+		ParamNames: paramNames,
+		Variadic:   isVariadic(lit.Type.Params),
+		Locals:     locals,
+	})
+	c.emitInstruction(instruction.NewInstruction(instruction.OpMakeClosure, funcKey, true))
+	return nil
+}
+
+// compileFunctionValue emits a declared function used as a value (e.g.,
+// apply(double, 3)) unless a variable shadows it
+func (c *Compiler) compileFunctionValue(ident *ast.Ident) bool {
+	if !c.scriptFunctions[ident.Name] || c.isLocal(ident.Name) {
+		return false
+	}
+	funcKey := c.generateFunctionKey(&ast.FuncDecl{Name: ident})
+	c.emitInstruction(instruction.NewInstruction(instruction.OpMakeClosure, funcKey, nil))
+	return true
+}
+
+// compileValueCall compiles a call of a function value: the value, then the
+// arguments, then a CALL without a function name
+func (c *Compiler) compileValueCall(call *ast.CallExpr) error {
+	if err := c.compileExpr(call.Fun); err != nil {
+		return err
+	}
+	if err := c.compileCallArgs(call); err != nil {
+		return err
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpCall, nil, len(call.Args)))
+	return nil
+}
+
+// isLocal reports whether name is a variable declared in an open scope
+func (c *Compiler) isLocal(name string) bool {
+	for _, scope := range c.scopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
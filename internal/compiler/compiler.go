@@ -5,13 +5,16 @@ package compiler
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
+	gotypes "go/types"
 	"strconv"
 	"strings"
 
-	"github.com/lengzhao/goscript/context"
+	"github.com/lengzhao/goscript/builtin"
 	"github.com/lengzhao/goscript/instruction"
-	"github.com/lengzhao/goscript/vm"
+	"github.com/lengzhao/goscript/internal/context"
+	"github.com/lengzhao/goscript/internal/vm"
 )
 
 // Compiler compiles AST nodes to bytecode with key-based instruction management
@@ -39,6 +42,71 @@ type Compiler struct {
 
 	// Label positions map (label name -> instruction index)
 	labelPositions map[string]int
+
+	// Names of functions declared in the script (used to tell script calls
+	// apart from host calls when tagging instructions)
+	scriptFunctions map[string]bool
+
+	// Declared number of results of script functions that declare them
+	resultCounts map[string]int
+
+	// Package-level type declarations (name -> type expression)
+	typeDecls map[string]ast.Expr
+
+	// Number of init functions compiled
+	initCount int
+
+	// Number of function literals compiled in each function, used to name
+	// their instruction sets
+	funcLitCounts map[string]int
+
+	// Names declared in each open lexical scope, innermost last, and the
+	// parameters to declare in the next scope (a function body)
+	scopes        []map[string]bool
+	pendingParams []string
+
+	// Declared types of the variables of each open scope and of the
+	// package-level variables, and of the parameters to declare next
+	scopeTypes        []map[string]ast.Expr
+	globalTypes       map[string]ast.Expr
+	pendingParamTypes map[string]ast.Expr
+
+	// Enclosing statements that break and continue can target, innermost
+	// last, the label for the next one, and the number of runtime scopes
+	// entered in the current function
+	branchTargets []*branchTarget
+	pendingLabel  string
+	scopeDepth    int
+
+	// Package-level constants (by name and in declaration order) and the
+	// constants declared in each open lexical scope
+	constants   map[string]constant.Value
+	constList   []Constant
+	scopeConsts []map[string]constant.Value
+
+	// Per-node compile hooks and the nodes they are currently handling
+	interceptors []Interceptor
+	intercepting map[ast.Node]bool
+
+	// Nodes compiled by interceptors, which the type checker skips
+	intercepted map[ast.Node]bool
+
+	// File set used to resolve positions in diagnostics (optional)
+	fset *token.FileSet
+
+	// Source position stamped on emitted instructions, and whether the next
+	// emitted instruction starts a statement
+	currentPos  token.Position
+	stmtPending bool
+
+	// Diagnostics collected during compilation
+	diagnostics CompileDiagnostics
+
+	// Whether the strict diagnostics pass runs (see SetStrict)
+	strict bool
+
+	// Size limits of the compiled script
+	limits Limits
 }
 
 // NewCompiler creates a new compiler with key-based instruction management
@@ -54,11 +122,27 @@ func NewCompiler(vmInstance *vm.VM) *Compiler {
 		currentInstructions: make([]*instruction.Instruction, 0),
 		importedModules:     make(map[string]string),
 		labelPositions:      make(map[string]int),
+		scriptFunctions:     make(map[string]bool),
+		resultCounts:        make(map[string]int),
+		typeDecls:           make(map[string]ast.Expr),
+		globalTypes:         make(map[string]ast.Expr),
+		funcLitCounts:       make(map[string]int),
+		constants:           make(map[string]constant.Value),
 	}
 }
 
+// SetFileSet sets the file set used to resolve source positions in diagnostics
+func (c *Compiler) SetFileSet(fset *token.FileSet) {
+	c.fset = fset
+}
+
 // Compile compiles an AST file to bytecode with key-based instruction management
+// Compilation continues past errors so that all problems are reported at once;
+// if any errors were found, a CompileDiagnostics value is returned and no
+// instructions are transferred to the VM.
 func (c *Compiler) Compile(file *ast.File) error {
+	c.diagnostics = nil
+
 	// Get package name from AST
 	if file.Name != nil {
 		c.packageName = file.Name.Name
@@ -69,15 +153,44 @@ func (c *Compiler) Compile(file *ast.File) error {
 	c.currentScopeKey = c.packageName
 	c.currentInstructions = make([]*instruction.Instruction, 0)
 
-	// Process import declarations first
+	// Reject oversized syntax trees before compiling them
+	c.checkSyntaxLimits(file)
+	if c.diagnostics.HasErrors() {
+		return c.diagnostics
+	}
+
+	// Collect script-defined function names and types up front so calls
+	// can be classified regardless of declaration order
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name != "init" {
+			c.scriptFunctions[fn.Name.Name] = true
+			if n := fn.Type.Results.NumFields(); n > 0 {
+				c.resultCounts[fn.Name.Name] = n
+			}
+		}
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.TYPE {
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				c.typeDecls[typeSpec.Name.Name] = typeSpec.Type
+			}
+		}
+	}
+
+	// Process import and type declarations first
 	for _, decl := range file.Decls {
-		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && (genDecl.Tok == token.IMPORT || genDecl.Tok == token.TYPE) {
 			if err := c.compileGenDecl(genDecl); err != nil {
-				return err
+				c.report(genDecl.Pos(), SeverityError, "%v", err)
 			}
 		}
 	}
 
+	// Evaluate package-level constants, which may refer to module constants
+	c.compilePackageConsts(file)
+
+	// Initialize package-level variables in dependency order
+	c.compileGlobals(file)
+
 	// Store package-level instructions if any
 	if len(c.currentInstructions) > 0 {
 		c.compileContext.SetInstructions(c.packageName, c.currentInstructions)
@@ -87,11 +200,29 @@ func (c *Compiler) Compile(file *ast.File) error {
 	for _, decl := range file.Decls {
 		if fn, ok := decl.(*ast.FuncDecl); ok {
 			if err := c.compileFunction(fn); err != nil {
-				return err
+				c.report(fn.Pos(), SeverityError, "%v", err)
 			}
 		}
 	}
 
+	// Resolve label positions for goto instructions
+	c.resolveLabelPositions()
+
+	// Flag loops and recursion that can only end at the instruction limit
+	c.lint(file)
+	if c.strict {
+		c.lintStrict(file)
+	}
+
+	// Report undefined names, wrong argument counts and operator type
+	// errors before the script runs
+	c.typeCheck(file)
+	c.checkInstructionLimits()
+
+	if c.diagnostics.HasErrors() {
+		return c.diagnostics
+	}
+
 	// Transfer all compiled instructions to the VM
 	return c.transferInstructions()
 }
@@ -105,6 +236,9 @@ func (c *Compiler) compileGenDecl(decl *ast.GenDecl) error {
 	case token.VAR:
 		// Handle variable declarations
 		return c.compileVarDecl(decl)
+	case token.CONST:
+		// Constants are evaluated at compile time
+		return c.compileConstDecl(decl)
 	case token.TYPE:
 		// Handle type declarations (structs, etc.)
 		return c.compileTypeDecl(decl)
@@ -136,7 +270,7 @@ func (c *Compiler) compileImportDecl(decl *ast.GenDecl) error {
 			c.importedModules[pkgName] = path
 
 			// Emit the import instruction
-			c.emitInstruction(instruction.NewInstruction(instruction.OpImport, path, pkgName))
+			c.emitInstruction(instruction.NewInstruction(instruction.OpImport, path, pkgName).WithTags(instruction.TagImport))
 
 			// Also create a variable for the module with "module" type
 			// This will allow us to handle module calls uniformly with method calls
@@ -152,18 +286,37 @@ func (c *Compiler) compileVarDecl(decl *ast.GenDecl) error {
 		if valueSpec, ok := spec.(*ast.ValueSpec); ok {
 			// Handle each variable in the declaration
 			for i, name := range valueSpec.Names {
+				if c.declaredInScope(name.Name) {
+					return fmt.Errorf("%s redeclared in this block", name.Name)
+				}
+
 				// Create the variable
 				c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, name.Name, nil))
+				c.declare(name.Name)
+				if valueSpec.Type != nil {
+					c.declareType(name.Name, valueSpec.Type)
+				} else if i < len(valueSpec.Values) {
+					if typ := c.staticType(valueSpec.Values[i]); typ != nil {
+						c.declareType(name.Name, typ)
+					}
+				}
 
 				// If there's an initial value, compile it and assign it
 				if i < len(valueSpec.Values) && valueSpec.Values[i] != nil {
 					if err := c.compileExpr(valueSpec.Values[i]); err != nil {
 						return err
 					}
+					c.emitImplements(valueSpec.Type)
+					c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, name.Name, nil))
+				} else if structName, ok := c.structTypeName(valueSpec.Type); ok {
+					// A struct variable starts as a struct with zero fields
+					if err := c.compileCompositeLit(&ast.CompositeLit{Type: ast.NewIdent(structName)}); err != nil {
+						return err
+					}
 					c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, name.Name, nil))
 				} else {
-					// Initialize with nil if no initial value
-					c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, nil, nil))
+					// Initialize with the zero value of the type if no initial value
+					c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, c.zeroValue(valueSpec.Type), nil))
 					c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, name.Name, nil))
 				}
 			}
@@ -172,23 +325,100 @@ func (c *Compiler) compileVarDecl(decl *ast.GenDecl) error {
 	return nil
 }
 
-// compileTypeDecl compiles type declarations
+// zeroValue returns the zero value of a variable of the given type: that of
+// its underlying basic type, or nil for other types and when no type is given
+func (c *Compiler) zeroValue(typ ast.Expr) interface{} {
+	if typ == nil {
+		return nil
+	}
+	switch c.typeDescriptor(typ) {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune", "uintptr":
+		return 0
+	case "float32", "float64":
+		return 0.0
+	case "string":
+		return ""
+	case "bool":
+		return false
+	default:
+		return nil
+	}
+}
+
+// structTypeName returns the script struct type a variable of the given
+// type holds, following named types
+func (c *Compiler) structTypeName(typ ast.Expr) (string, bool) {
+	if typ == nil {
+		return "", false
+	}
+	name := c.typeDescriptor(typ)
+	_, ok := c.typeDecls[name].(*ast.StructType)
+	return name, ok
+}
+
+// compileTypeDecl compiles type declarations. The fields of struct types
+// are recorded in the VM, which promotes the fields and methods of embedded
+// structs; their types and tags are kept for the json module.
 func (c *Compiler) compileTypeDecl(decl *ast.GenDecl) error {
-	// For now, we'll just acknowledge type declarations
-	// In a more complete implementation, we would process struct definitions, etc.
 	for _, spec := range decl.Specs {
-		if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-			fmt.Printf("Compiling type declaration: %s\n", typeSpec.Name.Name)
-			// TODO: Process struct types and other complex types
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok {
+			continue
+		}
+		structType, ok := typeSpec.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		var fields []vm.StructField
+		for _, field := range structType.Fields.List {
+			if len(field.Names) == 0 {
+				typeName := c.getTypeName(field.Type)
+				if typeName == "" {
+					return fmt.Errorf("invalid embedded field type in %s", typeSpec.Name.Name)
+				}
+				name := typeName[strings.LastIndex(typeName, ".")+1:]
+				fields = append(fields, vm.StructField{Name: name, Type: typeName, Tag: fieldTag(field), Embedded: true})
+				continue
+			}
+			for _, name := range field.Names {
+				fields = append(fields, vm.StructField{Name: name.Name, Type: gotypes.ExprString(field.Type), Tag: fieldTag(field)})
+			}
 		}
+		c.vm.RegisterStructType(typeSpec.Name.Name, fields)
 	}
 	return nil
 }
 
+// fieldTag returns the tag of a struct field without its quotes
+func fieldTag(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return tag
+}
+
 // compileFunction compiles a function declaration
 func (c *Compiler) compileFunction(fn *ast.FuncDecl) error {
-	// Generate function key
+	// Function declarations without a body (external functions) are not supported
+	if fn.Body == nil {
+		return fmt.Errorf("function %s has no body", fn.Name.Name)
+	}
+
+	// Generate function key; init functions are numbered in declaration
+	// order and run before main
 	funcKey := c.generateFunctionKey(fn)
+	isInit := fn.Recv == nil && fn.Name.Name == "init"
+	if isInit {
+		if fn.Type.Params.NumFields() > 0 || fn.Type.Results.NumFields() > 0 {
+			return fmt.Errorf("func init must have no arguments and no return values")
+		}
+		c.initCount++
+		funcKey = vm.InitFunctionKey(c.packageName, c.initCount)
+	}
 
 	// Save current state
 	prevScopeKey := c.currentScopeKey
@@ -198,6 +428,11 @@ func (c *Compiler) compileFunction(fn *ast.FuncDecl) error {
 	c.currentScopeKey = funcKey
 	c.currentInstructions = make([]*instruction.Instruction, 0)
 
+	// The prologue is attributed to the declaration
+	prevPos := c.currentPos
+	c.currentPos = c.positionOf(fn.Pos())
+	defer func() { c.currentPos = prevPos }()
+
 	// Collect parameter names
 	var paramNames []string
 
@@ -205,6 +440,11 @@ func (c *Compiler) compileFunction(fn *ast.FuncDecl) error {
 	if fn.Recv != nil && len(fn.Recv.List) > 0 {
 		// This is a method, compile the receiver parameter
 		for _, param := range fn.Recv.List {
+			// An unnamed receiver, func (Point) M(), still takes the first
+			// argument
+			if len(param.Names) == 0 {
+				paramNames = append(paramNames, "_")
+			}
 			for _, name := range param.Names {
 				c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, name.Name, nil))
 				// Note: We don't load parameter values here because they will be set by VM when calling the function
@@ -215,30 +455,12 @@ func (c *Compiler) compileFunction(fn *ast.FuncDecl) error {
 	}
 
 	// Compile function parameters as local variables
-	if fn.Type.Params != nil {
-		for _, param := range fn.Type.Params.List {
-			// Handle parameters with explicit names
-			if len(param.Names) > 0 {
-				for _, name := range param.Names {
-					c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, name.Name, nil))
-					// Note: We don't load parameter values here because they will be set by VM when calling the function
-					// The VM will map the actual arguments to these parameter names
-					paramNames = append(paramNames, name.Name)
-				}
-			} else {
-				// Handle parameters without explicit names (e.g., in simplified syntax where name is in the type field)
-				// In GoScript's simplified syntax, the parameter name might be stored in the type field
-				if ident, ok := param.Type.(*ast.Ident); ok {
-					// The parameter name is stored in the type field
-					paramName := ident.Name
-					c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, paramName, nil))
-					paramNames = append(paramNames, paramName)
-				}
-			}
-		}
-	}
+	paramNames = c.compileParams(fn.Type.Params, paramNames)
+	c.checkParams(fn.Type.Params)
 
-	// Compile function body
+	// Compile function body, with the parameters declared in its scope
+	c.pendingParams = paramNames
+	c.pendingParamTypes = paramTypes(fn.Recv, fn.Type.Params)
 	if err := c.compileBlockStmt(fn.Body); err != nil {
 		// Restore previous state
 		c.currentScopeKey = prevScopeKey
@@ -246,8 +468,14 @@ func (c *Compiler) compileFunction(fn *ast.FuncDecl) error {
 		return err
 	}
 
-	// Store instructions in compile context with function key
+	// Store instructions in compile context with function key. init
+	// functions have no function info to record slots in, so their
+	// variables keep name-based access.
+	locals := 0
 	if len(c.currentInstructions) > 0 {
+		if !isInit {
+			locals = resolveLocals(c.currentInstructions)
+		}
 		c.compileContext.SetInstructions(funcKey, c.currentInstructions)
 	}
 
@@ -255,33 +483,87 @@ func (c *Compiler) compileFunction(fn *ast.FuncDecl) error {
 	c.currentScopeKey = prevScopeKey
 	c.currentInstructions = prevInstructions
 
+	// init functions cannot be called
+	if isInit {
+		return nil
+	}
+
 	// Register function with VM
 	scriptFunc := &vm.ScriptFunctionInfo{
 		Name:       fn.Name.Name,
 		Key:        funcKey,
 		ParamCount: c.getParamCount(fn),
 		ParamNames: paramNames,
+		Variadic:   isVariadic(fn.Type.Params),
+		Pure:       hasDirective(fn.Doc, "goscript:pure"),
+		Locals:     locals,
 	}
-	c.vm.RegisterScriptFunction(fn.Name.Name, scriptFunc)
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		scriptFunc.Receiver = c.getTypeName(fn.Recv.List[0].Type)
+	}
+	// Methods are registered under their key, as methods of different
+	// types may share a name
+	name := fn.Name.Name
+	if fn.Recv != nil {
+		name = funcKey
+	}
+	c.vm.RegisterScriptFunction(name, scriptFunc)
 
 	return nil
 }
 
+// compileParams creates the parameters of a function as local variables and
+// returns their names appended to paramNames
+func (c *Compiler) compileParams(params *ast.FieldList, paramNames []string) []string {
+	if params == nil {
+		return paramNames
+	}
+	for _, param := range params.List {
+		// Handle parameters with explicit names
+		if len(param.Names) > 0 {
+			for _, name := range param.Names {
+				c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, name.Name, nil))
+				// Note: We don't load parameter values here because they will be set by VM when calling the function
+				// The VM will map the actual arguments to these parameter names
+				paramNames = append(paramNames, name.Name)
+			}
+		} else {
+			// Handle parameters without explicit names (e.g., in simplified syntax where name is in the type field)
+			// In GoScript's simplified syntax, the parameter name might be stored in the type field
+			if ident, ok := param.Type.(*ast.Ident); ok {
+				// The parameter name is stored in the type field
+				paramName := ident.Name
+				c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, paramName, nil))
+				paramNames = append(paramNames, paramName)
+			}
+		}
+	}
+	return paramNames
+}
+
+// hasDirective reports whether a doc comment contains a directive line such
+// as //goscript:pure. Directives require the comments to have been parsed.
+func hasDirective(doc *ast.CommentGroup, directive string) bool {
+	if doc == nil {
+		return false
+	}
+	for _, comment := range doc.List {
+		if comment.Text == "//"+directive {
+			return true
+		}
+	}
+	return false
+}
+
 // generateFunctionKey generates a unique key for a function
 func (c *Compiler) generateFunctionKey(fn *ast.FuncDecl) string {
 	// Check if this is a method (has receiver)
 	if fn.Recv != nil && len(fn.Recv.List) > 0 {
 		// This is a method, generate key in format "struct.method"
 		// Get the receiver type name
-		if len(fn.Recv.List) > 0 {
-			receiver := fn.Recv.List[0]
-			if len(receiver.Names) > 0 {
-				// Get receiver type name
-				typeName := c.getTypeNameWithPointer(receiver.Type)
-				if typeName != "" {
-					return fmt.Sprintf("%s.%s", typeName, fn.Name.Name)
-				}
-			}
+		typeName := c.getTypeNameWithPointer(fn.Recv.List[0].Type)
+		if typeName != "" {
+			return fmt.Sprintf("%s.%s", typeName, fn.Name.Name)
 		}
 	}
 
@@ -338,29 +620,47 @@ func (c *Compiler) getParamCount(fn *ast.FuncDecl) int {
 	return count
 }
 
+// isVariadic reports whether the last parameter is variadic (...T)
+func isVariadic(params *ast.FieldList) bool {
+	if params == nil || len(params.List) == 0 {
+		return false
+	}
+	_, variadic := params.List[len(params.List)-1].Type.(*ast.Ellipsis)
+	return variadic
+}
+
 // compileBlockStmt compiles a block statement with key-based scope management
 func (c *Compiler) compileBlockStmt(block *ast.BlockStmt) error {
 	// Generate a unique scope key for this block
 	scopeKey := c.generateKey("block")
 
 	// Emit instruction to enter the block scope
-	c.emitInstruction(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+	c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+	c.pushScope()
+	defer c.popScope()
 
-	// Compile each statement in the block
+	// Compile each statement in the block, recording errors and moving on
+	// so that later statements are still checked
 	for _, stmt := range block.List {
 		if err := c.compileStmt(stmt); err != nil {
-			return err
+			c.report(stmt.Pos(), SeverityError, "%v", err)
 		}
 	}
 
 	// Emit instruction to exit the block scope
-	c.emitInstruction(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
+	c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
 
 	return nil
 }
 
 // compileStmt compiles a statement
 func (c *Compiler) compileStmt(stmt ast.Stmt) error {
+	defer c.markStatement(stmt)()
+
+	if handled, err := c.intercept(stmt); handled {
+		return err
+	}
+
 	switch s := stmt.(type) {
 	case *ast.ExprStmt:
 		return c.compileExprStmt(s)
@@ -385,6 +685,14 @@ func (c *Compiler) compileStmt(stmt ast.Stmt) error {
 		}
 	case *ast.SwitchStmt:
 		return c.compileSwitchStmt(s)
+	case *ast.TypeSwitchStmt:
+		return c.compileTypeSwitchStmt(s)
+	case *ast.SelectStmt:
+		return c.compileSelectStmt(s)
+	case *ast.GoStmt:
+		return c.compileGoStmt(s)
+	case *ast.SendStmt:
+		return c.compileSendStmt(s)
 	case *ast.LabeledStmt:
 		// Handle labeled statements
 		return c.compileLabeledStmt(s)
@@ -412,19 +720,66 @@ func (c *Compiler) compileRangeStmt(stmt *ast.RangeStmt) error {
 	// Store the collection in a temporary variable
 	c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, rangeVarName, nil))
 
+	// A range with at most one variable can also receive from a channel
+	// until it is closed, which is only known at run time: such loops
+	// check whether the value is a channel, and receive into a temporary
+	// variable instead of indexing the collection.
+	var isChanVarName, keyVarName string
+	var skipLenInstr *instruction.Instruction
+	if stmt.Value == nil {
+		isChanVarName = c.generateKey("range_is_chan")
+		keyVarName = c.generateKey("range_key")
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, rangeVarName, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpTypeAssert, "chan interface{}", instruction.AssertTest))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, isChanVarName, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, isChanVarName, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, false, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpBinaryOp, instruction.OpEqual, nil))
+		skipLenInstr = instruction.NewInstruction(instruction.OpJumpIf, 0, nil) // Placeholder target
+		c.emitInstruction(skipLenInstr)
+	}
+
 	// Get the length of the collection and store it
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, rangeVarName, nil))
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLen, nil, nil))
 	c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, lengthVarName, nil))
+	if skipLenInstr != nil {
+		skipLenInstr.Arg = len(c.currentInstructions)
+	}
 
 	// Create loop counter variable (initialized to 0)
 	c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, counterVarName, nil))
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, 0, nil))
 	c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, counterVarName, nil))
 
+	// The loop can be the target of break and continue
+	target := c.pushBranchTarget(true)
+	defer c.popBranchTarget()
+
 	// Save the start IP for looping
 	startIP := len(c.currentInstructions)
 
+	// Receive the next value from a channel, leaving the loop when the
+	// channel is closed
+	var exitInstrs []*instruction.Instruction
+	var indexInstr, bindInstr *instruction.Instruction
+	if isChanVarName != "" {
+		okVarName := c.generateKey("range_ok")
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, isChanVarName, nil))
+		indexInstr = instruction.NewInstruction(instruction.OpJumpIf, 0, nil) // Placeholder target
+		c.emitInstruction(indexInstr)
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, rangeVarName, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpRecv, true, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, okVarName, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, keyVarName, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, okVarName, nil))
+		exitInstrs = append(exitInstrs, instruction.NewInstruction(instruction.OpJumpIf, 0, nil)) // Placeholder target
+		c.emitInstruction(exitInstrs[0])
+		bindInstr = instruction.NewInstruction(instruction.OpJump, 0, nil) // Placeholder target
+		c.emitInstruction(bindInstr)
+		indexInstr.Arg = len(c.currentInstructions)
+	}
+
 	// Check loop condition: counter < length
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, counterVarName, nil))
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, lengthVarName, nil))
@@ -433,14 +788,35 @@ func (c *Compiler) compileRangeStmt(stmt *ast.RangeStmt) error {
 	// Emit a conditional jump to exit the loop (when condition is false)
 	jumpIfInstr := instruction.NewInstruction(instruction.OpJumpIf, 0, nil) // Placeholder target
 	c.emitInstruction(jumpIfInstr)
+	exitInstrs = append(exitInstrs, jumpIfInstr)
+	if keyVarName != "" {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, counterVarName, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, keyVarName, nil))
+	}
+	if bindInstr != nil {
+		bindInstr.Arg = len(c.currentInstructions)
+	}
+
+	// Variables the body captures are declared in the scope of the
+	// iteration
+	var iterationKey string
+	if names := rangeVarNames(stmt); capturesLoopVars(names, stmt.Body) {
+		iterationKey = c.generateKey("range_iteration")
+		c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, iterationKey, nil))
+	}
 
 	// Set up loop variables if needed
 	if stmt.Key != nil {
 		// For range with key (index)
 		if keyIdent, ok := stmt.Key.(*ast.Ident); ok {
-			// Set the key variable to the current counter value
+			// Set the key variable to the current counter value, or to
+			// the value received from a channel
+			source := counterVarName
+			if keyVarName != "" {
+				source = keyVarName
+			}
 			c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, keyIdent.Name, nil))
-			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, counterVarName, nil))
+			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, source, nil))
 			c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, keyIdent.Name, nil))
 		}
 	}
@@ -461,8 +837,12 @@ func (c *Compiler) compileRangeStmt(stmt *ast.RangeStmt) error {
 	if err := c.compileBlockStmt(stmt.Body); err != nil {
 		return err
 	}
+	if iterationKey != "" {
+		c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, iterationKey, nil))
+	}
 
-	// Increment the counter
+	// Increment the counter (continue jumps here)
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, target.continueLabel, nil))
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, counterVarName, nil))
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, 1, nil))
 	c.emitInstruction(instruction.NewInstruction(instruction.OpBinaryOp, instruction.OpAdd, nil))
@@ -471,19 +851,44 @@ func (c *Compiler) compileRangeStmt(stmt *ast.RangeStmt) error {
 	// Emit an unconditional jump back to the start
 	c.emitInstruction(instruction.NewInstruction(instruction.OpJump, startIP, nil))
 
-	// Update the conditional jump target to after the loop
-	jumpIfInstr.Arg = len(c.currentInstructions)
+	// Update the conditional jump targets to after the loop
+	for _, instr := range exitInstrs {
+		instr.Arg = len(c.currentInstructions)
+	}
+
+	// Break jumps here
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, target.breakLabel, nil))
 
 	return nil
 }
 
 // compileExprStmt compiles an expression statement
 func (c *Compiler) compileExprStmt(stmt *ast.ExprStmt) error {
+	// A receive statement discards the value it receives
+	if recv, ok := isRecv(stmt.X); ok {
+		if err := c.compileRecv(recv, false); err != nil {
+			return err
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpPop, nil, nil))
+		return nil
+	}
 	return c.compileExpr(stmt.X)
 }
 
 // compileAssignStmt compiles an assignment statement
 func (c *Compiler) compileAssignStmt(stmt *ast.AssignStmt) error {
+	if stmt.Tok != token.DEFINE {
+		for _, lhs := range stmt.Lhs {
+			if err := c.checkAssignable(lhs); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(stmt.Lhs) > 1 || len(stmt.Rhs) > 1 {
+		return c.compileMultiAssign(stmt)
+	}
+
 	// Handle the left-hand side first for index expressions and selector expressions
 	switch lhs := stmt.Lhs[0].(type) {
 	case *ast.IndexExpr:
@@ -506,9 +911,11 @@ func (c *Compiler) compileAssignStmt(stmt *ast.AssignStmt) error {
 
 		// Handle compound assignment operators for index expressions
 		if stmt.Tok != token.ASSIGN { // Not a simple assignment
-			// For compound assignment, we need to load the current value first
-			// Emit GET_INDEX to get the current value
-			c.emitInstruction(instruction.NewInstruction(instruction.OpGetIndex, nil, nil))
+			// For compound assignment, we need to load the current value first.
+			// GET_INDEX consumes its operands, so it gets copies of them and
+			// the collection and index are only evaluated once
+			c.emitInstruction(instruction.NewInstruction(instruction.OpDup, 2, nil))
+			c.emitInstruction(instruction.NewInstruction(instruction.OpGetIndex, nil, c.elementZero(lhs.X)))
 
 			// Compile the right-hand side expression
 			if err := c.compileExpr(stmt.Rhs[0]); err != nil {
@@ -552,10 +959,11 @@ func (c *Compiler) compileAssignStmt(stmt *ast.AssignStmt) error {
 		// Handle compound assignment operators for selector expressions
 		if stmt.Tok != token.ASSIGN { // Not a simple assignment
 			// For compound assignment, we need to:
-			// 1. Load the struct
+			// 1. Load the struct, once, keeping a copy for SET_FIELD
 			if err := c.compileExpr(lhs.X); err != nil {
 				return err
 			}
+			c.emitInstruction(instruction.NewInstruction(instruction.OpDup, 1, nil))
 			// 2. Get the current value
 			c.emitInstruction(instruction.NewInstruction(instruction.OpGetField, lhs.Sel.Name, nil))
 
@@ -579,16 +987,7 @@ func (c *Compiler) compileAssignStmt(stmt *ast.AssignStmt) error {
 			default:
 				return fmt.Errorf("unsupported compound assignment operator: %s", stmt.Tok)
 			}
-
-			// For compound assignment, we need to load the struct again for SET_FIELD
-			// The stack at this point is: [new_value]
-			// We need to get: [struct, new_value]
-			if err := c.compileExpr(lhs.X); err != nil {
-				return err
-			}
-			// Stack is now: [new_value, struct]
-			// We need to swap to get: [struct, new_value]
-			c.emitInstruction(instruction.NewInstruction(instruction.OpSwap, nil, nil))
+			// The stack is now: [struct, new_value]
 		} else {
 			// Simple assignment
 			// Compile the expression being selected (e.g., struct)
@@ -608,6 +1007,9 @@ func (c *Compiler) compileAssignStmt(stmt *ast.AssignStmt) error {
 		// Emit the SET_FIELD instruction with field name as argument
 		c.emitInstruction(instruction.NewInstruction(instruction.OpSetField, lhs.Sel.Name, nil))
 		return nil
+	case *ast.StarExpr:
+		// Assignment through a pointer (e.g., *p = value)
+		return c.compileStoreDeref(lhs, stmt.Tok, stmt.Rhs[0])
 	}
 
 	// Handle compound assignment operators for regular variables
@@ -652,11 +1054,22 @@ func (c *Compiler) compileAssignStmt(stmt *ast.AssignStmt) error {
 	switch lhs := stmt.Lhs[0].(type) {
 	case *ast.Ident:
 		// For short variable declaration (:=), create the variable first
+		// unless it is already declared in the current scope
+		create := false
 		if stmt.Tok == token.DEFINE {
-			c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, lhs.Name, nil))
+			isNew, err := c.defineTargets(stmt.Lhs)
+			if err != nil {
+				return err
+			}
+			create = isNew[0]
 		}
 		// Store the result in the variable
-		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, lhs.Name, nil))
+		c.storeTarget(lhs.Name, create)
+		if create {
+			if typ := c.staticType(stmt.Rhs[0]); typ != nil {
+				c.declareType(lhs.Name, typ)
+			}
+		}
 	default:
 		return fmt.Errorf("unsupported assignment target: %T", lhs)
 	}
@@ -667,22 +1080,52 @@ func (c *Compiler) compileAssignStmt(stmt *ast.AssignStmt) error {
 func (c *Compiler) compileReturnStmt(stmt *ast.ReturnStmt) error {
 	// If there are return values, compile them
 	if len(stmt.Results) > 0 {
-		if err := c.compileExpr(stmt.Results[0]); err != nil {
-			return err
+		for _, result := range stmt.Results {
+			if err := c.compileExpr(result); err != nil {
+				return err
+			}
 		}
 	} else {
 		// If no return value, return nil
 		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, nil, nil))
 	}
 
-	// Emit return instruction
-	c.emitInstruction(instruction.NewInstruction(instruction.OpReturn, nil, nil))
+	// Emit return instruction; several results are returned as a tuple
+	var count interface{}
+	if len(stmt.Results) > 1 {
+		count = len(stmt.Results)
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpReturn, count, nil))
 	return nil
 }
 
 // compileIfStmt compiles an if statement using goto-based approach
 func (c *Compiler) compileIfStmt(stmt *ast.IfStmt) error {
+	// Variables declared by the init statement (e.g., if v := f(); v > 10)
+	// are scoped to the whole if/else chain
+	if stmt.Init != nil {
+		scopeKey := c.generateKey("if_scope")
+		c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+		c.pushScope()
+		defer c.popScope()
+		if err := c.compileStmt(stmt.Init); err != nil {
+			return err
+		}
+		if err := c.compileIfChain(stmt); err != nil {
+			return err
+		}
+		c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
+		return nil
+	}
+	return c.compileIfChain(stmt)
+}
+
+// compileIfChain compiles the condition, body and else branch of an if statement
+func (c *Compiler) compileIfChain(stmt *ast.IfStmt) error {
 	// Compile the condition
+	if err := c.checkCondition(stmt.Cond, "if statement"); err != nil {
+		return err
+	}
 	if err := c.compileExpr(stmt.Cond); err != nil {
 		return err
 	}
@@ -701,16 +1144,19 @@ func (c *Compiler) compileIfStmt(stmt *ast.IfStmt) error {
 
 	// False branch (else part)
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, falseLabel, nil))
-	if stmt.Else != nil {
-		// Compile the else block
-		if elseStmt, ok := stmt.Else.(*ast.BlockStmt); ok {
-			if err := c.compileBlockStmt(elseStmt); err != nil {
-				return err
-			}
+	switch elseStmt := stmt.Else.(type) {
+	case *ast.BlockStmt:
+		if err := c.compileBlockStmt(elseStmt); err != nil {
+			return err
+		}
+	case *ast.IfStmt:
+		// else if: nested inside the outer init scope, if any
+		if err := c.compileIfStmt(elseStmt); err != nil {
+			return err
 		}
 	}
 	// Jump to end after executing else block
-	c.emitInstruction(instruction.NewInstruction(instruction.OpJump, endLabel, nil))
+	c.emitUntracked(instruction.NewInstruction(instruction.OpJump, endLabel, nil))
 
 	// True branch (if body)
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, trueLabel, nil))
@@ -718,77 +1164,122 @@ func (c *Compiler) compileIfStmt(stmt *ast.IfStmt) error {
 		return err
 	}
 	// Jump to end after executing if body
-	c.emitInstruction(instruction.NewInstruction(instruction.OpJump, endLabel, nil))
+	c.emitUntracked(instruction.NewInstruction(instruction.OpJump, endLabel, nil))
 
 	// End of if statement
-	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, endLabel, nil))
+	c.emitUntracked(instruction.NewInstruction(instruction.OpLabel, endLabel, nil))
 
 	return nil
 }
 
 // compileForStmt compiles a for statement with key-based block management
 func (c *Compiler) compileForStmt(stmt *ast.ForStmt) error {
-	// Compile the init statement if it exists
+	// Variables declared by the init statement (e.g., i := 0) are scoped to
+	// the loop, so they neither leak after it nor overwrite an outer variable
+	// of the same name
 	if stmt.Init != nil {
+		scopeKey := c.generateKey("for_scope")
+		c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+		c.pushScope()
+		defer c.popScope()
 		if err := c.compileStmt(stmt.Init); err != nil {
 			return err
 		}
+		var perIteration []string
+		if names := loopVarNames(stmt.Init); capturesLoopVars(names, stmt.Cond, stmt.Post, stmt.Body) {
+			perIteration = names
+		}
+		if err := c.compileForLoop(stmt, perIteration); err != nil {
+			return err
+		}
+		c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
+		return nil
 	}
+	return c.compileForLoop(stmt, nil)
+}
+
+// compileForLoop compiles the condition, body and post statement of a for
+// loop. The three forms of Go for loops differ only in what they omit: a
+// condition-only loop (for x < 10) has no post statement and an infinite
+// loop (for {}) no condition, so it only leaves by break, return or the
+// execution limits, which are checked on every instruction including the
+// jump back to the start. The variables in perIteration get a copy in each
+// iteration, which continue does not leave before copying them back.
+func (c *Compiler) compileForLoop(stmt *ast.ForStmt, perIteration []string) error {
+	// The loop can be the target of break and continue
+	target := c.pushBranchTarget(true)
+	defer c.popBranchTarget()
 
 	// Save the start IP for looping
 	startIP := len(c.currentInstructions)
 
-	// Compile the condition if it exists
+	// Exit the loop when the condition is false
+	var jumpIfInstr *instruction.Instruction
 	if stmt.Cond != nil {
-		if err := c.compileExpr(stmt.Cond); err != nil {
+		if err := c.checkCondition(stmt.Cond, "for statement"); err != nil {
 			return err
 		}
-
-		// Emit a conditional jump to exit the loop (when condition is false)
-		// We need to jump to the instruction after the loop body when condition is false
-		jumpIfInstr := instruction.NewInstruction(instruction.OpJumpIf, 0, nil) // Placeholder target
-		c.emitInstruction(jumpIfInstr)
-
-		// Compile the loop body with its own scope
-		if err := c.compileBlockStmt(stmt.Body); err != nil {
+		if err := c.compileExpr(stmt.Cond); err != nil {
 			return err
 		}
+		jumpIfInstr = instruction.NewInstruction(instruction.OpJumpIf, 0, nil) // Placeholder target
+		c.emitInstruction(jumpIfInstr)
+	}
 
-		// Compile the post statement if it exists
-		if stmt.Post != nil {
-			if err := c.compileStmt(stmt.Post); err != nil {
-				return err
-			}
-		}
+	var iterationKey string
+	if len(perIteration) > 0 {
+		iterationKey = c.enterIteration(perIteration)
+		target.continueDepth = c.scopeDepth
+	}
 
-		// Emit an unconditional jump back to the start
-		c.emitInstruction(instruction.NewInstruction(instruction.OpJump, startIP, nil))
+	// Compile the loop body with its own scope
+	if err := c.compileBlockStmt(stmt.Body); err != nil {
+		return err
+	}
 
-		// Update the conditional jump target to after the loop
-		// This is where we exit the loop when condition is false
-		jumpIfInstr.Arg = len(c.currentInstructions)
-	} else {
-		// Infinite loop - compile the body with its own scope
-		if err := c.compileBlockStmt(stmt.Body); err != nil {
+	// Compile the post statement if it exists (continue jumps here)
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, target.continueLabel, nil))
+	if iterationKey != "" {
+		c.exitIteration(iterationKey, perIteration)
+	}
+	if stmt.Post != nil {
+		if err := c.compileStmt(stmt.Post); err != nil {
 			return err
 		}
+	}
 
-		// Compile the post statement if it exists
-		if stmt.Post != nil {
-			if err := c.compileStmt(stmt.Post); err != nil {
-				return err
-			}
-		}
+	// Emit an unconditional jump back to the start
+	c.emitInstruction(instruction.NewInstruction(instruction.OpJump, startIP, nil))
 
-		// Emit an unconditional jump back to the start
-		c.emitInstruction(instruction.NewInstruction(instruction.OpJump, startIP, nil))
+	// The condition jumps after the loop when it is false
+	if jumpIfInstr != nil {
+		jumpIfInstr.Arg = len(c.currentInstructions)
 	}
 
+	// Break jumps here
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, target.breakLabel, nil))
+
 	return nil
 }
 
 // compileIncDecStmt compiles an increment or decrement statement
 func (c *Compiler) compileIncDecStmt(stmt *ast.IncDecStmt) error {
+	if err := c.checkAssignable(stmt.X); err != nil {
+		return err
+	}
+
+	// Elements, fields and pointers are updated like compound assignments
+	// (e.g., counts[k] += 1)
+	switch stmt.X.(type) {
+	case *ast.IndexExpr, *ast.SelectorExpr, *ast.StarExpr:
+		op := token.ADD_ASSIGN
+		if stmt.Tok == token.DEC {
+			op = token.SUB_ASSIGN
+		}
+		one := &ast.BasicLit{Kind: token.INT, Value: "1"}
+		return c.compileAssignStmt(&ast.AssignStmt{Lhs: []ast.Expr{stmt.X}, Tok: op, Rhs: []ast.Expr{one}})
+	}
+
 	// Load the current value of the variable
 	switch x := stmt.X.(type) {
 	case *ast.Ident:
@@ -818,6 +1309,10 @@ func (c *Compiler) compileIncDecStmt(stmt *ast.IncDecStmt) error {
 
 // compileExpr compiles an expression
 func (c *Compiler) compileExpr(expr ast.Expr) error {
+	if handled, err := c.intercept(expr); handled {
+		return err
+	}
+
 	switch e := expr.(type) {
 	case *ast.BasicLit:
 		return c.compileBasicLit(e)
@@ -841,6 +1336,12 @@ func (c *Compiler) compileExpr(expr ast.Expr) error {
 		return c.compileSelectorExpr(e)
 	case *ast.UnaryExpr:
 		return c.compileUnaryExpr(e)
+	case *ast.StarExpr:
+		return c.compileStarExpr(e)
+	case *ast.FuncLit:
+		return c.compileFuncLit(e)
+	case *ast.TypeAssertExpr:
+		return c.compileTypeAssertExpr(e, instruction.AssertValue)
 	default:
 		return fmt.Errorf("unsupported expression type: %T", expr)
 	}
@@ -848,11 +1349,11 @@ func (c *Compiler) compileExpr(expr ast.Expr) error {
 
 // compileUnaryExpr compiles a unary expression
 func (c *Compiler) compileUnaryExpr(expr *ast.UnaryExpr) error {
-	// For now, we only handle the address operator (&)
 	if expr.Op == token.AND {
-		// For & expression, we just compile the operand
-		// In a more complete implementation, we would need to handle pointers properly
-		return c.compileExpr(expr.X)
+		return c.compileAddressOf(expr)
+	}
+	if expr.Op == token.ARROW {
+		return c.compileRecv(expr, false)
 	}
 
 	return fmt.Errorf("unsupported unary operator: %s", expr.Op)
@@ -860,9 +1361,16 @@ func (c *Compiler) compileUnaryExpr(expr *ast.UnaryExpr) error {
 
 // compileCompositeLit compiles a composite literal (e.g., []int{1, 2, 3} or Person{name: "Alice"})
 func (c *Compiler) compileCompositeLit(lit *ast.CompositeLit) error {
-	// Check if this is a slice literal (no key specified for elements)
-	isSlice := len(lit.Elts) > 0
-	if isSlice {
+	typ := c.underlyingType(lit.Type)
+	if mapType, ok := typ.(*ast.MapType); ok {
+		return c.compileMapLit(lit, mapType)
+	}
+
+	// Check if this is a slice literal (a slice type, or no key specified
+	// for elements of a literal that is not a struct)
+	sliceType, isSlice := typ.(*ast.ArrayType)
+	fields, isStruct := typ.(*ast.StructType)
+	if !isSlice && !isStruct && len(lit.Elts) > 0 {
 		// Check if the first element is not a KeyValueExpr, which indicates a slice
 		_, isKeyValue := lit.Elts[0].(*ast.KeyValueExpr)
 		isSlice = !isKeyValue
@@ -886,7 +1394,11 @@ func (c *Compiler) compileCompositeLit(lit *ast.CompositeLit) error {
 			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, i, nil))
 
 			// Compile the element value
-			if err := c.compileExpr(elem); err != nil {
+			var elemType ast.Expr
+			if sliceType != nil {
+				elemType = sliceType.Elt
+			}
+			if err := c.compileElement(elem, elemType); err != nil {
 				return err
 			}
 
@@ -897,6 +1409,11 @@ func (c *Compiler) compileCompositeLit(lit *ast.CompositeLit) error {
 
 		// Load the final slice onto the stack
 		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, tempVarName, nil))
+
+		// Byte slice literals are packed into a []byte
+		if c.isByteSlice(lit.Type) {
+			c.emitInstruction(instruction.NewInstruction(instruction.OpCall, "[]byte", 1).WithTags(instruction.TagHostCall))
+		}
 	} else {
 		// Handle struct literals like Person{name: "Alice"}
 		// Create a new struct with type information if available
@@ -911,8 +1428,31 @@ func (c *Compiler) compileCompositeLit(lit *ast.CompositeLit) error {
 		tempVarName := c.generateKey("composite_lit")
 		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, tempVarName, nil))
 
+		// Elements without keys are the values of the fields in order
+		var fieldNames []string
+		if isStruct && len(lit.Elts) > 0 {
+			if _, isKeyValue := lit.Elts[0].(*ast.KeyValueExpr); !isKeyValue {
+				fieldNames = c.structFieldNames(fields)
+				if len(lit.Elts) != len(fieldNames) {
+					return fmt.Errorf("wrong number of values in struct literal of type %s: %d, want %d", exprString(lit.Type), len(lit.Elts), len(fieldNames))
+				}
+			}
+		}
+
 		// Compile each element and add it to the struct
-		for _, elem := range lit.Elts {
+		for i, elem := range lit.Elts {
+			if fieldNames != nil {
+				if _, isKeyValue := elem.(*ast.KeyValueExpr); isKeyValue {
+					return fmt.Errorf("mixture of field:value and value elements in struct literal")
+				}
+				c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, tempVarName, nil))
+				if err := c.compileExpr(elem); err != nil {
+					return err
+				}
+				c.emitInstruction(instruction.NewInstruction(instruction.OpSetField, fieldNames[i], nil))
+				continue
+			}
+
 			// Handle KeyValueExpr (for struct fields) or regular expressions (for slice elements)
 			switch e := elem.(type) {
 			case *ast.KeyValueExpr:
@@ -937,8 +1477,9 @@ func (c *Compiler) compileCompositeLit(lit *ast.CompositeLit) error {
 				// Stack should be: [..., struct, value]
 				c.emitInstruction(instruction.NewInstruction(instruction.OpSetField, fieldName, nil))
 			default:
-				// For slice elements or other types, we would need different handling
-				// But for now, let's focus on struct support
+				if isStruct {
+					return fmt.Errorf("mixture of field:value and value elements in struct literal")
+				}
 				return fmt.Errorf("unsupported composite literal element type: %T", elem)
 			}
 		}
@@ -950,6 +1491,41 @@ func (c *Compiler) compileCompositeLit(lit *ast.CompositeLit) error {
 	return nil
 }
 
+// compileElement compiles an element or key of a composite literal whose
+// elements have the given type (nil when unknown). A composite literal with
+// its type elided takes that type, e.g., {n: 1} in []Counter{{n: 1}}, and
+// is taken as &T{...} when the type is *T.
+func (c *Compiler) compileElement(expr ast.Expr, typ ast.Expr) error {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok || lit.Type != nil || typ == nil {
+		return c.compileExpr(expr)
+	}
+	typed := *lit
+	if ptr, ok := typ.(*ast.StarExpr); ok {
+		typed.Type = ptr.X
+		return c.compileExpr(&ast.UnaryExpr{OpPos: lit.Lbrace, Op: token.AND, X: &typed})
+	}
+	typed.Type = typ
+	return c.compileCompositeLit(&typed)
+}
+
+// structFieldNames returns the names of the fields of a struct type in
+// order; embedded fields are named after their type
+func (c *Compiler) structFieldNames(structType *ast.StructType) []string {
+	var names []string
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			typeName := c.getTypeName(field.Type)
+			names = append(names, typeName[strings.LastIndex(typeName, ".")+1:])
+			continue
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
+
 // compileIndexExpr compiles an index expression (e.g., array[index])
 func (c *Compiler) compileIndexExpr(expr *ast.IndexExpr) error {
 	// Compile the expression being indexed (e.g., array)
@@ -963,7 +1539,7 @@ func (c *Compiler) compileIndexExpr(expr *ast.IndexExpr) error {
 	}
 
 	// Emit the GET_INDEX instruction
-	c.emitInstruction(instruction.NewInstruction(instruction.OpGetIndex, nil, nil))
+	c.emitInstruction(instruction.NewInstruction(instruction.OpGetIndex, nil, c.elementZero(expr.X)))
 
 	return nil
 }
@@ -986,9 +1562,19 @@ func (c *Compiler) compileBasicLit(lit *ast.BasicLit) error {
 		}
 		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, value, nil))
 	case token.STRING:
-		// Remove quotes from string literal
-		value := lit.Value[1 : len(lit.Value)-1]
+		// Interpreted ("a\n") and raw (`a\n`) string literals
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return fmt.Errorf("invalid string literal: %s", lit.Value)
+		}
 		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, value, nil))
+	case token.CHAR:
+		// Rune literals (e.g., 'a', '\n', '\u00e9') are int32 values
+		value, _, tail, err := strconv.UnquoteChar(lit.Value[1:len(lit.Value)-1], '\'')
+		if err != nil || tail != "" {
+			return fmt.Errorf("invalid rune literal: %s", lit.Value)
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, int32(value), nil))
 	default:
 		return fmt.Errorf("unsupported literal kind: %s", lit.Kind)
 	}
@@ -1047,17 +1633,40 @@ func (c *Compiler) compileCallExpr(expr *ast.CallExpr) error {
 	// Handle different types of function calls
 	switch fun := expr.Fun.(type) {
 	case *ast.Ident:
+		// Variables holding function values shadow functions of the same name
+		if c.isLocal(fun.Name) {
+			return c.compileValueCall(expr)
+		}
+
 		// Regular function calls (e.g., add(1, 2))
+		if fun.Name == "make" && len(expr.Args) > 0 && !c.shadowed(fun.Name) {
+			if mapType, ok := expr.Args[0].(*ast.MapType); ok {
+				return c.compileMakeMap(expr, mapType)
+			}
+			if chanType, ok := expr.Args[0].(*ast.ChanType); ok {
+				return c.compileMakeChan(expr, chanType)
+			}
+			if sliceType, ok := expr.Args[0].(*ast.ArrayType); ok {
+				return c.compileMakeSlice(expr, sliceType)
+			}
+		}
+		if err := c.checkUniverseCall(fun.Name, len(expr.Args)); err != nil {
+			return err
+		}
+
 		// Compile all arguments
 		argCount := len(expr.Args)
-		for _, arg := range expr.Args {
-			if err := c.compileExpr(arg); err != nil {
-				return err
-			}
+		if err := c.compileCallArgs(expr); err != nil {
+			return err
 		}
 
 		// Emit the function call instruction with key-based calling
-		c.emitInstruction(instruction.NewInstruction(instruction.OpCall, fun.Name, argCount))
+		// Calls to anything not declared in the script cross the host boundary
+		callInstr := instruction.NewInstruction(instruction.OpCall, fun.Name, argCount)
+		if !c.scriptFunctions[fun.Name] {
+			callInstr.WithTags(instruction.TagHostCall)
+		}
+		c.emitInstruction(callInstr)
 	case *ast.SelectorExpr:
 		// Method calls (e.g., p.SetWidth(20)) or module calls (e.g., math.Max(1, 2))
 		// For unified handling, we'll compile the receiver and then use OpCall
@@ -1068,10 +1677,8 @@ func (c *Compiler) compileCallExpr(expr *ast.CallExpr) error {
 
 		// Compile all arguments
 		argCount := len(expr.Args)
-		for _, arg := range expr.Args {
-			if err := c.compileExpr(arg); err != nil {
-				return err
-			}
+		if err := c.compileCallArgs(expr); err != nil {
+			return err
 		}
 
 		// For unified handling, we use the format "receiver.functionName"
@@ -1079,7 +1686,21 @@ func (c *Compiler) compileCallExpr(expr *ast.CallExpr) error {
 		functionName := fun.Sel.Name
 		// Emit the function call instruction with the function name only
 		// The receiver is already on the stack as the first argument
-		c.emitInstruction(instruction.NewInstruction(instruction.OpCall, functionName, argCount+1))
+		callInstr := instruction.NewInstruction(instruction.OpCall, functionName, argCount+1)
+		if ident, ok := fun.X.(*ast.Ident); ok && !c.isLocal(ident.Name) {
+			if path, isModule := c.importedModules[ident.Name]; isModule {
+				if err := c.checkModuleMember(ident.Name, path, functionName); err != nil {
+					return err
+				}
+				callInstr.WithTags(instruction.TagModuleCall)
+			}
+		}
+		c.emitInstruction(callInstr)
+	case *ast.ArrayType:
+		return c.compileSliceConversion(expr, fun)
+	case *ast.FuncLit, *ast.CallExpr, *ast.ParenExpr, *ast.IndexExpr:
+		// Calls of function values (e.g., makeAdder(1)(2) or handlers[i](x))
+		return c.compileValueCall(expr)
 	default:
 		return fmt.Errorf("unsupported function call type: %T", expr.Fun)
 	}
@@ -1087,8 +1708,26 @@ func (c *Compiler) compileCallExpr(expr *ast.CallExpr) error {
 	return nil
 }
 
+// compileCallArgs compiles the arguments of a call. The last argument of
+// f(a, xs...) is marked to be spread into the arguments.
+func (c *Compiler) compileCallArgs(call *ast.CallExpr) error {
+	for _, arg := range call.Args {
+		if err := c.compileExpr(arg); err != nil {
+			return err
+		}
+	}
+	if call.Ellipsis.IsValid() {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpSpread, nil, nil))
+	}
+	return nil
+}
+
 // compileIdent compiles an identifier
 func (c *Compiler) compileIdent(ident *ast.Ident) error {
+	if c.compileConstIdent(ident) || c.compilePredeclared(ident) || c.compileFunctionValue(ident) {
+		return nil
+	}
+
 	// Emit a load name instruction
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, ident.Name, nil))
 	return nil
@@ -1101,6 +1740,27 @@ func (c *Compiler) compileSelectorExpr(expr *ast.SelectorExpr) error {
 	// 2. Emit the GET_FIELD instruction with the field name as argument
 	// But according to the new architecture, we should emit OpLoadName with the qualified name
 
+	// Module constants (e.g., math.Pi) are resolved at compile time, and
+	// other module members are functions used as values (e.g.,
+	// f := strings.ToUpper)
+	if ident, ok := expr.X.(*ast.Ident); ok && !c.isLocal(ident.Name) {
+		if path, isModule := c.importedModules[ident.Name]; isModule {
+			if value, exists := c.vm.GetModuleConstant(path, expr.Sel.Name); exists {
+				// Constants that fit an int are ints, like other integers
+				if cv, err := makeConst(value); err == nil {
+					value = constValue(cv)
+				}
+				c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, value, nil))
+				return nil
+			}
+			if err := c.checkModuleMember(ident.Name, path, expr.Sel.Name); err != nil {
+				return err
+			}
+			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, ident.Name+"."+expr.Sel.Name, nil))
+			return nil
+		}
+	}
+
 	// Compile the expression being selected (e.g., p)
 	if err := c.compileExpr(expr.X); err != nil {
 		return err
@@ -1112,6 +1772,22 @@ func (c *Compiler) compileSelectorExpr(expr *ast.SelectorExpr) error {
 	return nil
 }
 
+// checkModuleMember reports a selector of a builtin module that names
+// neither a function nor a constant of the module, e.g. math.Pie. Members
+// of host and script modules are only known when the module is loaded.
+func (c *Compiler) checkModuleMember(pkgName, path, name string) error {
+	if _, isBuiltin := builtin.GetModuleFunctions(path); !isBuiltin {
+		return nil
+	}
+	if _, isHost := c.vm.GetModule(path); isHost {
+		return nil
+	}
+	if c.vm.HasFunction(path+"."+name) || c.vm.CheckModule(path) != nil {
+		return nil
+	}
+	return fmt.Errorf("undefined: %s.%s", pkgName, name)
+}
+
 // generateKey generates a unique key for a code block
 func (c *Compiler) generateKey(prefix string) string {
 	c.keyCounter++
@@ -1120,21 +1796,23 @@ func (c *Compiler) generateKey(prefix string) string {
 
 // emitInstruction adds an instruction to the current scope
 func (c *Compiler) emitInstruction(instr *instruction.Instruction) {
+	if instr.Line == 0 {
+		instr.File, instr.Line, instr.Column = c.currentPos.Filename, c.currentPos.Line, c.currentPos.Column
+	}
+	if c.stmtPending {
+		instr.StmtStart = true
+		c.stmtPending = false
+	}
 	c.currentInstructions = append(c.currentInstructions, instr)
 }
 
 // transferInstructions transfers all compiled instructions from the compile context to the VM
 func (c *Compiler) transferInstructions() error {
-	// First, resolve label positions for goto instructions
-	c.resolveLabelPositions()
-
 	// Transfer instructions from the compile context
 	instructions := c.compileContext.GetAllInstructions()
 
 	// Transfer each set of instructions with their keys
 	for key, instrs := range instructions {
-		fmt.Printf("Transferring instructions for key: %s, count: %d\n", key, len(instrs))
-
 		// Add instruction set with key to the VM
 		c.vm.AddInstructionSet(key, instrs)
 	}
@@ -1171,7 +1849,7 @@ func (c *Compiler) resolveLabelPositions() {
 					} else {
 						// Label not found in current scope, check if it's a forward reference
 						// For now, we'll leave it as is and let the VM handle it
-						fmt.Printf("Warning: Label '%s' not found in scope '%s'\n", labelName, key)
+						c.report(token.NoPos, SeverityWarning, "label '%s' not found in scope '%s'", labelName, key)
 					}
 				}
 			}
@@ -1184,8 +1862,21 @@ func (c *Compiler) compileSwitchStmt(stmt *ast.SwitchStmt) error {
 	// Generate a unique scope key for this switch statement
 	scopeKey := c.generateKey("switch")
 
-	// Emit instruction to enter the switch scope
-	c.emitInstruction(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+	// Emit instruction to enter the switch scope, which holds the variables
+	// declared by the init statement (e.g., switch v := f(); v)
+	c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+	c.pushScope()
+	defer c.popScope()
+
+	// The switch can be the target of break
+	target := c.pushBranchTarget(false)
+	defer c.popBranchTarget()
+
+	if stmt.Init != nil {
+		if err := c.compileStmt(stmt.Init); err != nil {
+			return err
+		}
+	}
 
 	// Compile the switch tag (expression to switch on) and store it in a variable
 	var tagVarName string
@@ -1228,52 +1919,12 @@ func (c *Compiler) compileSwitchStmt(stmt *ast.SwitchStmt) error {
 		defaultLabel = endLabel
 	}
 
-	// Generate condition checks and jumps
+	// Generate condition checks and jumps (the first pass checked the
+	// clause types)
 	for i, clause := range stmt.Body.List {
-		caseClause, ok := clause.(*ast.CaseClause)
-		if !ok {
-			return fmt.Errorf("unexpected clause type in switch: %T", clause)
-		}
-
-		if len(caseClause.List) == 0 {
-			// Default case - no condition check needed
-			continue
-		} else {
-			// Regular case with conditions
-			// For each expression in the case list, check if it matches the tag
-			for _, expr := range caseClause.List {
-				// Load the tag value
-				if tagVarName != "" {
-					c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, tagVarName, nil))
-				}
-
-				// Compile the case expression
-				if err := c.compileExpr(expr); err != nil {
-					return err
-				}
-
-				// Emit a binary equality operation
-				c.emitInstruction(instruction.NewInstruction(instruction.OpBinaryOp, instruction.OpEqual, nil))
-
-				// Emit a conditional jump to the case body if the condition is true
-				// Since JUMP_IF jumps when the condition is FALSE, we need to invert our logic.
-				// We want to jump to the case body when the condition is TRUE.
-				// So we use a trick: we put the jump to the case body AFTER the JUMP_IF instruction.
-				// If the condition is TRUE, JUMP_IF won't jump and we'll continue to the GOTO.
-				// If the condition is FALSE, JUMP_IF will jump to skip the GOTO.
-
-				// Create a label to skip the goto instruction
-				skipGotoLabel := c.generateKey("skip_goto")
-
-				// Jump to skipGotoLabel if condition is FALSE
-				c.emitInstruction(instruction.NewInstruction(instruction.OpJumpIf, skipGotoLabel, nil))
-
-				// If we reach here, condition was TRUE, so jump to case body
-				c.emitInstruction(instruction.NewInstruction(instruction.OpJump, caseLabels[i], nil))
-
-				// Label to skip the goto instruction
-				c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, skipGotoLabel, nil))
-			}
+		caseClause := clause.(*ast.CaseClause)
+		if err := c.compileCaseTests(caseClause, tagVarName, caseLabels[i]); err != nil {
+			return err
 		}
 	}
 
@@ -1282,70 +1933,76 @@ func (c *Compiler) compileSwitchStmt(stmt *ast.SwitchStmt) error {
 
 	// Process each case clause body
 	for i, clause := range stmt.Body.List {
-		caseClause, ok := clause.(*ast.CaseClause)
-		if !ok {
-			return fmt.Errorf("unexpected clause type in switch: %T", clause)
-		}
+		caseClause := clause.(*ast.CaseClause)
 
 		// Emit label for this case
 		c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, caseLabels[i], nil))
 
+		// A body ending in fallthrough continues with the body of the next
+		// case, without checking its condition
+		body, nextLabel := caseClause.Body, endLabel
+		if n := len(body); n > 0 {
+			if branch, ok := body[n-1].(*ast.BranchStmt); ok && branch.Tok == token.FALLTHROUGH {
+				if i == len(stmt.Body.List)-1 {
+					return fmt.Errorf("cannot fallthrough final case in switch")
+				}
+				body, nextLabel = body[:n-1], caseLabels[i+1]
+			}
+		}
+
 		// Compile each statement in the case body
-		for _, caseStmt := range caseClause.Body {
+		for _, caseStmt := range body {
 			if err := c.compileStmt(caseStmt); err != nil {
 				return err
 			}
 		}
 
-		// Jump to end of switch after executing the case body
-		c.emitInstruction(instruction.NewInstruction(instruction.OpJump, endLabel, nil))
+		// Jump to end of switch, or to the next case body on fallthrough
+		c.emitInstruction(instruction.NewInstruction(instruction.OpJump, nextLabel, nil))
 	}
 
 	// Emit label for end of switch (this is also the default label if no default case exists)
 	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, endLabel, nil))
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, target.breakLabel, nil))
 
 	// Emit instruction to exit the switch scope
-	c.emitInstruction(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
+	c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
 
 	return nil
 }
 
-// compileLabeledStmt compiles a labeled statement
-func (c *Compiler) compileLabeledStmt(stmt *ast.LabeledStmt) error {
-	// Record the position of this label
-	labelName := stmt.Label.Name
-	c.labelPositions[labelName] = len(c.currentInstructions)
+// compileCaseTests emits the tests of a case clause, jumping to its body
+// when one passes. The expressions of a case are tested in order and the
+// first match wins, so `case a, b:` is `tag == a || tag == b` and the later
+// expressions are not evaluated. Without a tag each expression is a
+// condition, which makes the switch an if/else chain. A default clause has
+// no tests.
+func (c *Compiler) compileCaseTests(clause *ast.CaseClause, tagVarName, caseLabel string) error {
+	for _, expr := range clause.List {
+		// Load the tag value
+		if tagVarName != "" {
+			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, tagVarName, nil))
+		} else if err := c.checkCondition(expr, "case clause"); err != nil {
+			return err
+		}
 
-	// Emit a label instruction
-	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, labelName, nil))
+		// Compile the case expression
+		if err := c.compileExpr(expr); err != nil {
+			return err
+		}
 
-	// Compile the statement that follows the label
-	return c.compileStmt(stmt.Stmt)
-}
+		// Emit a binary equality operation; without a tag the case
+		// expression is the condition itself
+		if tagVarName != "" {
+			c.emitInstruction(instruction.NewInstruction(instruction.OpBinaryOp, instruction.OpEqual, nil))
+		}
 
-// compileBranchStmt compiles a branch statement (goto, break, continue, fallthrough)
-func (c *Compiler) compileBranchStmt(stmt *ast.BranchStmt) error {
-	switch stmt.Tok {
-	case token.GOTO:
-		// Handle goto statement
-		if stmt.Label != nil {
-			// Emit a goto instruction with the label name
-			// The actual target position will be resolved later during linking
-			c.emitInstruction(instruction.NewInstruction(instruction.OpJump, stmt.Label.Name, nil))
-		} else {
-			return fmt.Errorf("goto statement must have a label")
-		}
-	case token.BREAK:
-		// Handle break statement
-		c.emitInstruction(instruction.NewInstruction(instruction.OpBreak, nil, nil))
-	case token.CONTINUE:
-		// For now, we don't support continue, but we could add it later
-		return fmt.Errorf("continue statement not yet supported")
-	case token.FALLTHROUGH:
-		// For now, we don't support fallthrough, but we could add it later
-		return fmt.Errorf("fallthrough statement not yet supported")
-	default:
-		return fmt.Errorf("unsupported branch statement: %s", stmt.Tok)
+		// JUMP_IF jumps when the condition is false, so it skips the jump
+		// to the case body that follows it
+		skipGotoLabel := c.generateKey("skip_goto")
+		c.emitInstruction(instruction.NewInstruction(instruction.OpJumpIf, skipGotoLabel, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpJump, caseLabel, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, skipGotoLabel, nil))
 	}
 	return nil
 }
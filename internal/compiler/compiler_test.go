@@ -5,7 +5,7 @@ import (
 	"go/token"
 	"testing"
 
-	"github.com/lengzhao/goscript/vm"
+	"github.com/lengzhao/goscript/internal/vm"
 )
 
 func TestCompilerWithKeyBasedInstructions(t *testing.T) {
@@ -0,0 +1,445 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"sort"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Constants are evaluated at compile time, as in Go: a const declaration
+// emits no instructions and every use of the constant loads its value.
+
+// Constant is a constant declared at package level
+type Constant struct {
+	Name  string
+	Value interface{}
+
+	// Type is the declared type name (e.g., "Level" for an enum declared
+	// with iota), empty for untyped constants
+	Type string
+}
+
+// Constants returns the package-level constants in declaration order
+func (c *Compiler) Constants() []Constant {
+	return c.constList
+}
+
+// constSpec is a constant of a const declaration with the expression and
+// type it is declared with, which a spec without values repeats from the
+// previous spec with iota incremented
+type constSpec struct {
+	name  *ast.Ident
+	value ast.Expr
+	typ   ast.Expr
+	iota  int
+}
+
+// constSpecs lists the constants of a const declaration in order
+func constSpecs(decl *ast.GenDecl) ([]*constSpec, error) {
+	var specs []*constSpec
+	var values []ast.Expr
+	var typ ast.Expr
+	for iota, spec := range decl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if len(valueSpec.Values) > 0 {
+			values, typ = valueSpec.Values, valueSpec.Type
+		}
+		if len(values) != len(valueSpec.Names) {
+			return nil, fmt.Errorf("missing init expr for const declaration")
+		}
+		for i, name := range valueSpec.Names {
+			specs = append(specs, &constSpec{name: name, value: values[i], typ: typ, iota: iota})
+		}
+	}
+	return specs, nil
+}
+
+// compileConstDecl evaluates a local const declaration, whose constants are
+// scoped to the current block
+func (c *Compiler) compileConstDecl(decl *ast.GenDecl) error {
+	specs, err := constSpecs(decl)
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if err := c.evalConstSpec(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compilePackageConsts evaluates the package-level const declarations of a
+// file. As in Go, a constant may refer to constants declared after it, so
+// constants are evaluated after those they refer to; they are recorded for
+// Constants in declaration order.
+func (c *Compiler) compilePackageConsts(file *ast.File) {
+	var specs []*constSpec
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.CONST {
+			declSpecs, err := constSpecs(genDecl)
+			if err != nil {
+				c.report(genDecl.Pos(), SeverityError, "%v", err)
+				continue
+			}
+			specs = append(specs, declSpecs...)
+		}
+	}
+
+	byName := make(map[string]*constSpec)
+	for _, spec := range specs {
+		if _, exists := byName[spec.name.Name]; !exists && spec.name.Name != "_" {
+			byName[spec.name.Name] = spec
+		}
+	}
+	deps := make(map[*constSpec]map[*constSpec]bool)
+	for _, spec := range specs {
+		deps[spec] = make(map[*constSpec]bool)
+		ast.Inspect(spec.value, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				// Module constants are not package-level names
+				return false
+			case *ast.Ident:
+				if dep, ok := byName[n.Name]; ok && dep != spec {
+					deps[spec][dep] = true
+				}
+			}
+			return true
+		})
+	}
+
+	order, cycle := initOrder(specs, deps)
+	if cycle != nil {
+		c.report(cycle.name.Pos(), SeverityError, "initialization cycle for %s", cycle.name.Name)
+		return
+	}
+	for _, spec := range order {
+		if err := c.evalConstSpec(spec); err != nil {
+			c.report(spec.name.Pos(), SeverityError, "%v", err)
+		}
+	}
+
+	declared := make(map[string]int, len(specs))
+	for i, spec := range specs {
+		if _, exists := declared[spec.name.Name]; !exists {
+			declared[spec.name.Name] = i
+		}
+	}
+	sort.SliceStable(c.constList, func(i, j int) bool {
+		return declared[c.constList[i].Name] < declared[c.constList[j].Name]
+	})
+}
+
+// evalConstSpec evaluates a constant and declares it
+func (c *Compiler) evalConstSpec(spec *constSpec) error {
+	value, err := c.evalConst(spec.value, spec.iota)
+	if err != nil {
+		return fmt.Errorf("const %s: %w", spec.name.Name, err)
+	}
+	typeName := ""
+	if spec.typ != nil {
+		if value, err = convertConst(value, spec.typ); err != nil {
+			return fmt.Errorf("const %s: %w", spec.name.Name, err)
+		}
+		typeName = exprString(spec.typ)
+	}
+	if spec.name.Name == "_" {
+		return nil
+	}
+	return c.declareConst(spec.name.Name, value, typeName)
+}
+
+// declareConst records a constant in the innermost scope, or at package
+// level outside functions
+func (c *Compiler) declareConst(name string, value constant.Value, typeName string) error {
+	if len(c.scopes) == 0 {
+		if _, exists := c.constants[name]; exists {
+			return fmt.Errorf("%s redeclared in this block", name)
+		}
+		c.constants[name] = value
+		c.constList = append(c.constList, Constant{Name: name, Value: constValue(value), Type: typeName})
+		return nil
+	}
+	if c.declaredInScope(name) {
+		return fmt.Errorf("%s redeclared in this block", name)
+	}
+	top := len(c.scopes) - 1
+	if c.scopeConsts[top] == nil {
+		c.scopeConsts[top] = make(map[string]constant.Value)
+	}
+	c.scopeConsts[top][name] = value
+	c.declare(name)
+	return nil
+}
+
+// lookupConst resolves an identifier to a constant unless a variable of the
+// same name shadows it
+func (c *Compiler) lookupConst(name string) (constant.Value, bool) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if value, exists := c.scopeConsts[i][name]; exists {
+			return value, true
+		}
+		if c.scopes[i][name] {
+			return nil, false
+		}
+	}
+	value, exists := c.constants[name]
+	return value, exists
+}
+
+// compileConstIdent emits the value of a constant identifier
+func (c *Compiler) compileConstIdent(ident *ast.Ident) bool {
+	value, exists := c.lookupConst(ident.Name)
+	if !exists {
+		return false
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, constValue(value), nil))
+	return true
+}
+
+// checkAssignable rejects assignments to constants
+func (c *Compiler) checkAssignable(expr ast.Expr) error {
+	if ident, ok := expr.(*ast.Ident); ok {
+		if _, isConst := c.lookupConst(ident.Name); isConst {
+			return fmt.Errorf("cannot assign to %s (constant)", ident.Name)
+		}
+	}
+	return nil
+}
+
+// evalConst evaluates a constant expression
+func (c *Compiler) evalConst(expr ast.Expr, iota int) (constant.Value, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		value := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		if value.Kind() == constant.Unknown {
+			return nil, fmt.Errorf("invalid literal %s", e.Value)
+		}
+		return value, nil
+	case *ast.Ident:
+		switch e.Name {
+		case "iota":
+			return constant.MakeInt64(int64(iota)), nil
+		case "true", "false":
+			return constant.MakeBool(e.Name == "true"), nil
+		}
+		if value, exists := c.lookupConst(e.Name); exists {
+			return value, nil
+		}
+		return nil, fmt.Errorf("%s is not constant", e.Name)
+	case *ast.SelectorExpr:
+		// Module constants, e.g., math.Pi
+		if x, ok := e.X.(*ast.Ident); ok {
+			if path, isModule := c.importedModules[x.Name]; isModule {
+				if value, exists := c.vm.GetModuleConstant(path, e.Sel.Name); exists {
+					return makeConst(value)
+				}
+			}
+		}
+		return nil, fmt.Errorf("%s is not constant", exprString(e))
+	case *ast.ParenExpr:
+		return c.evalConst(e.X, iota)
+	case *ast.UnaryExpr:
+		x, err := c.evalConst(e.X, iota)
+		if err != nil {
+			return nil, err
+		}
+		// Unary + and - apply to numbers only, like binary -
+		op := e.Op
+		if op == token.ADD {
+			op = token.SUB
+		}
+		if !constOpDefined(op, x) {
+			return nil, fmt.Errorf("invalid operation: operator %s not defined on %s", e.Op, x)
+		}
+		return constant.UnaryOp(e.Op, x, 0), nil
+	case *ast.BinaryExpr:
+		return c.evalConstBinary(e, iota)
+	case *ast.CallExpr:
+		// Conversions such as float64(1) or Level(2)
+		if len(e.Args) != 1 {
+			return nil, fmt.Errorf("%s is not constant", exprString(e))
+		}
+		x, err := c.evalConst(e.Args[0], iota)
+		if err != nil {
+			return nil, err
+		}
+		return convertConst(x, e.Fun)
+	default:
+		return nil, fmt.Errorf("%s is not constant", exprString(expr))
+	}
+}
+
+// evalConstBinary evaluates a binary constant expression
+func (c *Compiler) evalConstBinary(e *ast.BinaryExpr, iota int) (constant.Value, error) {
+	x, err := c.evalConst(e.X, iota)
+	if err != nil {
+		return nil, err
+	}
+	y, err := c.evalConst(e.Y, iota)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Op {
+	case token.SHL, token.SHR:
+		shift, ok := constant.Uint64Val(constant.ToInt(y))
+		if !ok || x.Kind() != constant.Int {
+			return nil, fmt.Errorf("invalid shift %s %s %s", x, e.Op, y)
+		}
+		return constant.Shift(x, e.Op, uint(shift)), nil
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		if !comparableConsts(x, y) {
+			return nil, fmt.Errorf("invalid operation: mismatched types in %s %s %s", x, e.Op, y)
+		}
+		if x.Kind() == constant.Bool && e.Op != token.EQL && e.Op != token.NEQ {
+			return nil, fmt.Errorf("invalid operation: operator %s not defined on %s", e.Op, x)
+		}
+		return constant.MakeBool(constant.Compare(x, e.Op, y)), nil
+	}
+
+	if !comparableConsts(x, y) {
+		return nil, fmt.Errorf("invalid operation: mismatched types in %s %s %s", x, e.Op, y)
+	}
+	if !constOpDefined(e.Op, x) || !constOpDefined(e.Op, y) {
+		return nil, fmt.Errorf("invalid operation: operator %s not defined on %s", e.Op, x)
+	}
+	op := e.Op
+	if (op == token.QUO || op == token.REM) && constant.Sign(y) == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	// Integer division truncates, as in Go
+	if op == token.QUO && x.Kind() == constant.Int && y.Kind() == constant.Int {
+		op = token.QUO_ASSIGN
+	}
+	return constant.BinaryOp(x, op, y), nil
+}
+
+// comparableConsts reports whether two constants can be combined: both
+// numeric, both strings or both booleans
+func comparableConsts(x, y constant.Value) bool {
+	numeric := func(v constant.Value) bool {
+		return v.Kind() == constant.Int || v.Kind() == constant.Float
+	}
+	return x.Kind() == y.Kind() || (numeric(x) && numeric(y))
+}
+
+// constOpDefined reports whether an arithmetic or logical operator applies
+// to a constant of the given kind
+func constOpDefined(op token.Token, x constant.Value) bool {
+	switch op {
+	case token.ADD:
+		return x.Kind() != constant.Bool
+	case token.SUB, token.MUL, token.QUO:
+		return x.Kind() == constant.Int || x.Kind() == constant.Float
+	case token.REM, token.AND, token.OR, token.XOR, token.AND_NOT:
+		return x.Kind() == constant.Int
+	case token.LAND, token.LOR, token.NOT:
+		return x.Kind() == constant.Bool
+	default:
+		return false
+	}
+}
+
+// convertConst converts a constant to a type given by name. Declared types
+// (e.g., an enum type) keep the value as is.
+func convertConst(value constant.Value, typ ast.Expr) (constant.Value, error) {
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("invalid constant type %s", exprString(typ))
+	}
+	switch ident.Name {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune":
+		converted := constant.ToInt(value)
+		if converted.Kind() != constant.Int {
+			return nil, fmt.Errorf("cannot convert %s to type %s", value, ident.Name)
+		}
+		return converted, nil
+	case "float32", "float64":
+		converted := constant.ToFloat(value)
+		if converted.Kind() != constant.Float {
+			return nil, fmt.Errorf("cannot convert %s to type %s", value, ident.Name)
+		}
+		return converted, nil
+	case "string":
+		if value.Kind() != constant.String {
+			return nil, fmt.Errorf("cannot convert %s to type string", value)
+		}
+		return value, nil
+	case "bool":
+		if value.Kind() != constant.Bool {
+			return nil, fmt.Errorf("cannot convert %s to type bool", value)
+		}
+		return value, nil
+	}
+	return value, nil
+}
+
+// makeConst converts a host value into a constant
+func makeConst(value interface{}) (constant.Value, error) {
+	switch v := value.(type) {
+	case int:
+		return constant.MakeInt64(int64(v)), nil
+	case int64:
+		return constant.MakeInt64(v), nil
+	case uint64:
+		return constant.MakeUint64(v), nil
+	case float64:
+		return constant.MakeFloat64(v), nil
+	case string:
+		return constant.MakeString(v), nil
+	case bool:
+		return constant.MakeBool(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported constant value %v (%T)", value, value)
+	}
+}
+
+// constValue converts a constant into the value scripts see: int (int64 or
+// uint64 when out of range), float64, string or bool
+func constValue(value constant.Value) interface{} {
+	switch value.Kind() {
+	case constant.Int:
+		if i, exact := constant.Int64Val(value); exact {
+			if int64(int(i)) == i {
+				return int(i)
+			}
+			return i
+		}
+		if u, exact := constant.Uint64Val(value); exact {
+			return u
+		}
+		f, _ := constant.Float64Val(value)
+		return f
+	case constant.Float:
+		f, _ := constant.Float64Val(value)
+		return f
+	case constant.String:
+		return constant.StringVal(value)
+	case constant.Bool:
+		return constant.BoolVal(value)
+	default:
+		return value.ExactString()
+	}
+}
+
+// checkCondition rejects constant non-bool conditions when the VM requires
+// conditions to be bool
+func (c *Compiler) checkCondition(cond ast.Expr, context string) error {
+	if !c.vm.GetStrictConditions() {
+		return nil
+	}
+	value, err := c.evalConst(cond, 0)
+	if err != nil || value.Kind() == constant.Bool {
+		return nil
+	}
+	return fmt.Errorf("non-boolean condition in %s", context)
+}
@@ -0,0 +1,110 @@
+package compiler
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// Severity represents the severity of a compiler diagnostic
+type Severity int
+
+const (
+	// SeverityError marks a problem that prevents the script from running
+	SeverityError Severity = iota
+
+	// SeverityWarning marks a suspicious construct that still compiles
+	SeverityWarning
+)
+
+// String returns the string representation of a Severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", s)
+	}
+}
+
+// Diagnostic is a single problem reported by the compiler
+type Diagnostic struct {
+	// Pos is the resolved source position (zero if unknown)
+	Pos token.Position
+
+	// Severity of the diagnostic
+	Severity Severity
+
+	// Message describes the problem
+	Message string
+}
+
+// String returns the diagnostic formatted as "file:line:col: severity: message"
+func (d Diagnostic) String() string {
+	if d.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+}
+
+// CompileDiagnostics is the error returned by Compile when one or more
+// errors were found. It lists every diagnostic, including warnings.
+type CompileDiagnostics []Diagnostic
+
+// Error implements the error interface
+func (d CompileDiagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diag := range d {
+		lines[i] = diag.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors reports whether any diagnostic has error severity
+func (d CompileDiagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns only the error-severity diagnostics
+func (d CompileDiagnostics) Errors() CompileDiagnostics {
+	return d.filter(SeverityError)
+}
+
+// Warnings returns only the warning-severity diagnostics
+func (d CompileDiagnostics) Warnings() CompileDiagnostics {
+	return d.filter(SeverityWarning)
+}
+
+func (d CompileDiagnostics) filter(severity Severity) CompileDiagnostics {
+	var result CompileDiagnostics
+	for _, diag := range d {
+		if diag.Severity == severity {
+			result = append(result, diag)
+		}
+	}
+	return result
+}
+
+// report records a diagnostic at the given position
+func (c *Compiler) report(pos token.Pos, severity Severity, format string, args ...interface{}) {
+	diag := Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if c.fset != nil && pos.IsValid() {
+		diag.Pos = c.fset.Position(pos)
+	}
+	c.diagnostics = append(c.diagnostics, diag)
+}
+
+// Diagnostics returns all diagnostics collected by the last Compile call
+func (c *Compiler) Diagnostics() CompileDiagnostics {
+	return c.diagnostics
+}
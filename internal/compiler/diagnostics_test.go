@@ -0,0 +1,89 @@
+package compiler
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+func TestCompileReportsAllErrors(t *testing.T) {
+	vm := vm.NewVM()
+	compiler := NewCompiler(vm)
+
+	code := `package main
+
+func helper() int {
+	defer cleanup()
+	return 1
+}
+
+func main() {
+	x := 1 << 2
+	y := 3
+	return x + y
+}
+`
+
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, "script.gs", code, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse code: %v", err)
+	}
+
+	compiler.SetFileSet(fset)
+	err = compiler.Compile(astFile)
+	if err == nil {
+		t.Fatal("Expected compile error")
+	}
+
+	var diags CompileDiagnostics
+	if !errors.As(err, &diags) {
+		t.Fatalf("Expected CompileDiagnostics, got %T", err)
+	}
+
+	errs := diags.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), diags)
+	}
+
+	if errs[0].Pos.Line != 4 || errs[0].Pos.Filename != "script.gs" {
+		t.Errorf("Expected first error at script.gs:4, got %s", errs[0].Pos)
+	}
+	if errs[1].Pos.Line != 9 {
+		t.Errorf("Expected second error at line 9, got %s", errs[1].Pos)
+	}
+
+	// Nothing should reach the VM when compilation fails
+	if _, exists := vm.GetInstructionSet("main.main"); exists {
+		t.Error("Expected no instructions to be transferred on error")
+	}
+}
+
+func TestCompileWarningsDoNotFail(t *testing.T) {
+	vm := vm.NewVM()
+	compiler := NewCompiler(vm)
+
+	code := `package main
+
+func main() {
+	goto missing
+}
+`
+
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, "", code, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse code: %v", err)
+	}
+
+	if err := compiler.Compile(astFile); err != nil {
+		t.Fatalf("Expected warnings only, got error: %v", err)
+	}
+
+	if len(compiler.Diagnostics().Warnings()) != 1 {
+		t.Errorf("Expected 1 warning, got %v", compiler.Diagnostics())
+	}
+}
@@ -0,0 +1,41 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// CompileExpression compiles a standalone expression (e.g., "count > 10"
+// or "p.name") into instructions that leave its value as the return value.
+// Function calls are rejected so the expression can be evaluated at any
+// point without side effects.
+func (c *Compiler) CompileExpression(src string) ([]*instruction.Instruction, error) {
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+
+	var callErr error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if _, ok := n.(*ast.CallExpr); ok && callErr == nil {
+			callErr = fmt.Errorf("invalid expression %q: function calls are not allowed", src)
+		}
+		return callErr == nil
+	})
+	if callErr != nil {
+		return nil, callErr
+	}
+
+	prevInstructions := c.currentInstructions
+	c.currentInstructions = make([]*instruction.Instruction, 0)
+	defer func() { c.currentInstructions = prevInstructions }()
+
+	if err := c.compileExpr(expr); err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpReturn, nil, nil))
+	return c.currentInstructions, nil
+}
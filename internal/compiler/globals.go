@@ -0,0 +1,195 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Package-level variables. They are initialized by the package-level code,
+// which runs before the init functions and main, in dependency order as in
+// Go: a variable is initialized after the variables its initializer refers
+// to, directly or through the functions it calls, and otherwise in
+// declaration order. A variable that depends on itself is a compile error.
+
+// compileGlobals compiles the package-level variable declarations of a file
+// into the package-level instructions
+func (c *Compiler) compileGlobals(file *ast.File) {
+	graph := &initGraph{
+		vars:  make(map[string]*ast.ValueSpec),
+		funcs: make(map[string]*ast.FuncDecl),
+	}
+	var specs []*ast.ValueSpec
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range d.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				specs = append(specs, valueSpec)
+				for _, name := range valueSpec.Names {
+					graph.vars[name.Name] = valueSpec
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Body != nil {
+				graph.funcs[d.Name.Name] = d
+			}
+		}
+	}
+
+	deps := make(map[*ast.ValueSpec]map[*ast.ValueSpec]bool)
+	for _, spec := range specs {
+		deps[spec] = make(map[*ast.ValueSpec]bool)
+		for _, value := range spec.Values {
+			graph.varRefs(value, nil, make(map[string]bool), deps[spec])
+		}
+	}
+
+	order, cycle := initOrder(specs, deps)
+	if cycle != nil {
+		c.report(cycle.Pos(), SeverityError, "initialization cycle for %s", cycle.Names[0].Name)
+		return
+	}
+	prevPos := c.currentPos
+	defer func() { c.currentPos = prevPos }()
+	for _, spec := range order {
+		c.currentPos = c.positionOf(spec.Pos())
+		if err := c.compileVarDecl(&ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{spec}}); err != nil {
+			c.report(spec.Pos(), SeverityError, "%v", err)
+		}
+	}
+}
+
+// initOrder orders declarations (of variables or constants) for
+// initialization: each time, the first declaration whose dependencies are
+// all initialized. If none is ready, it returns the first declaration
+// left, which is in a cycle.
+func initOrder[T comparable](specs []T, deps map[T]map[T]bool) ([]T, T) {
+	order := make([]T, 0, len(specs))
+	done := make(map[T]bool)
+	for len(order) < len(specs) {
+		var next T
+		found := false
+		for _, spec := range specs {
+			if done[spec] {
+				continue
+			}
+			ready := true
+			for dep := range deps[spec] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next, found = spec, true
+				break
+			}
+		}
+		if !found {
+			for _, spec := range specs {
+				if !done[spec] {
+					return nil, spec
+				}
+			}
+		}
+		order = append(order, next)
+		done[next] = true
+	}
+	var none T
+	return order, none
+}
+
+// initGraph holds the package-level variables and functions whose
+// references order the initialization
+type initGraph struct {
+	vars  map[string]*ast.ValueSpec
+	funcs map[string]*ast.FuncDecl
+}
+
+// varRefs adds the declarations of the package-level variables a node
+// refers to, directly or through the functions it calls, to refs. Names
+// declared anywhere in a function are taken to shadow package-level names
+// throughout it; visited holds the functions already followed.
+func (g *initGraph) varRefs(node ast.Node, locals map[string]bool, visited map[string]bool, refs map[*ast.ValueSpec]bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			inner := declaredNames(n)
+			for name := range locals {
+				inner[name] = true
+			}
+			g.varRefs(n.Body, inner, visited, refs)
+			return false
+		case *ast.SelectorExpr:
+			// Fields and methods are not package-level names
+			g.varRefs(n.X, locals, visited, refs)
+			return false
+		case *ast.KeyValueExpr:
+			// Field names in struct literals are not package-level names
+			if _, isIdent := n.Key.(*ast.Ident); !isIdent {
+				g.varRefs(n.Key, locals, visited, refs)
+			}
+			g.varRefs(n.Value, locals, visited, refs)
+			return false
+		case *ast.Ident:
+			if locals[n.Name] {
+				return false
+			}
+			if spec, ok := g.vars[n.Name]; ok {
+				refs[spec] = true
+			} else if fn, ok := g.funcs[n.Name]; ok && !visited[n.Name] {
+				visited[n.Name] = true
+				g.varRefs(fn.Body, declaredNames(fn), visited, refs)
+			}
+		}
+		return true
+	})
+}
+
+// declaredNames returns the names a function declares: its parameters and
+// results and the variables declared in its body
+func declaredNames(fn ast.Node) map[string]bool {
+	names := make(map[string]bool)
+	addFields := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				names[name.Name] = true
+			}
+		}
+	}
+	ast.Inspect(fn, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncType:
+			addFields(n.Params)
+			addFields(n.Results)
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok {
+						names[ident.Name] = true
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				for _, expr := range []ast.Expr{n.Key, n.Value} {
+					if ident, ok := expr.(*ast.Ident); ok {
+						names[ident.Name] = true
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				names[name.Name] = true
+			}
+		}
+		return true
+	})
+	return names
+}
@@ -0,0 +1,64 @@
+package compiler
+
+import (
+	"go/ast"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Interceptor is called before the compiler compiles a statement or an
+// expression. It may emit its own instructions through Emit, CompileStmt and
+// CompileExpr and return handled=true to replace the stock compilation, or
+// return false to let the node compile normally. Returning an error reports
+// it as a compile error.
+//
+// An interceptor that compiles the node it was given (for example, to wrap a
+// statement with instrumentation) gets the stock compilation for that node;
+// interceptors are not re-entered for it.
+type Interceptor func(c *Compiler, node ast.Node) (handled bool, err error)
+
+// AddInterceptor registers an interceptor. Interceptors run in registration
+// order; the first that handles a node wins.
+func (c *Compiler) AddInterceptor(interceptor Interceptor) {
+	c.interceptors = append(c.interceptors, interceptor)
+}
+
+// Emit appends an instruction to the code being compiled
+func (c *Compiler) Emit(instr *instruction.Instruction) {
+	c.emitInstruction(instr)
+}
+
+// CompileStmt compiles a statement with the stock backend
+func (c *Compiler) CompileStmt(stmt ast.Stmt) error {
+	return c.compileStmt(stmt)
+}
+
+// CompileExpr compiles an expression with the stock backend; the value is
+// left on the stack
+func (c *Compiler) CompileExpr(expr ast.Expr) error {
+	return c.compileExpr(expr)
+}
+
+// intercept offers a node to the registered interceptors
+func (c *Compiler) intercept(node ast.Node) (bool, error) {
+	if len(c.interceptors) == 0 || c.intercepting[node] {
+		return false, nil
+	}
+	if c.intercepting == nil {
+		c.intercepting = make(map[ast.Node]bool)
+	}
+	c.intercepting[node] = true
+	defer delete(c.intercepting, node)
+
+	for _, interceptor := range c.interceptors {
+		handled, err := interceptor(c, node)
+		if handled || err != nil {
+			if c.intercepted == nil {
+				c.intercepted = make(map[ast.Node]bool)
+			}
+			c.intercepted[node] = true
+			return true, err
+		}
+	}
+	return false, nil
+}
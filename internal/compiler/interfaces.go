@@ -0,0 +1,98 @@
+package compiler
+
+import (
+	"go/ast"
+	"strings"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Interface-typed variables. The compiler records the declared types of
+// variables and parameters, and checks values stored in those with a
+// non-empty interface type with IMPLEMENTS, so that a value without the
+// methods of the interface fails where it is assigned rather than where a
+// method is called.
+
+// interfaceOf returns the name and descriptor of the interface a type
+// expression denotes, if it is an interface with methods
+func (c *Compiler) interfaceOf(typ ast.Expr) (name, iface string, ok bool) {
+	if typ == nil {
+		return "", "", false
+	}
+	iface = c.typeDescriptor(typ)
+	if !strings.HasPrefix(iface, "interface{") || iface == "interface{}" {
+		return "", "", false
+	}
+	name = iface
+	if ident, isIdent := typ.(*ast.Ident); isIdent {
+		name = ident.Name
+	}
+	return name, iface, true
+}
+
+// emitImplements checks that the value on top of the stack can be stored
+// in a variable of the given type
+func (c *Compiler) emitImplements(typ ast.Expr) {
+	if name, iface, ok := c.interfaceOf(typ); ok {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpImplements, name, iface))
+	}
+}
+
+// checkParams checks the arguments of the parameters declared with an
+// interface type, at the start of a function
+func (c *Compiler) checkParams(params *ast.FieldList) {
+	if params == nil {
+		return
+	}
+	for _, param := range params.List {
+		if _, _, ok := c.interfaceOf(param.Type); !ok {
+			continue
+		}
+		for _, name := range param.Names {
+			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, name.Name, nil))
+			c.emitImplements(param.Type)
+			c.emitInstruction(instruction.NewInstruction(instruction.OpPop, nil, nil))
+		}
+	}
+}
+
+// paramTypes returns the declared types of named parameters
+func paramTypes(fieldLists ...*ast.FieldList) map[string]ast.Expr {
+	types := make(map[string]ast.Expr)
+	for _, fields := range fieldLists {
+		if fields == nil {
+			continue
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				types[name.Name] = field.Type
+			}
+		}
+	}
+	return types
+}
+
+// declareType records the declared type of a variable of the innermost
+// scope, or of a package-level variable outside functions
+func (c *Compiler) declareType(name string, typ ast.Expr) {
+	if len(c.scopes) == 0 {
+		c.globalTypes[name] = typ
+		return
+	}
+	scope := len(c.scopeTypes) - 1
+	if c.scopeTypes[scope] == nil {
+		c.scopeTypes[scope] = make(map[string]ast.Expr)
+	}
+	c.scopeTypes[scope][name] = typ
+}
+
+// declaredType returns the declared type of the variable a name refers to,
+// or nil when it was declared without a type
+func (c *Compiler) declaredType(name string) ast.Expr {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if c.scopes[i][name] {
+			return c.scopeTypes[i][name]
+		}
+	}
+	return c.globalTypes[name]
+}
@@ -0,0 +1,105 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// Limits bounds the size of the scripts a compiler accepts, so that huge or
+// malicious sources are rejected before they exhaust memory. Zero fields
+// mean no limit.
+type Limits struct {
+	// MaxSourceSize is the maximum total size of the source files in bytes.
+	// It is checked before parsing by the caller of the compiler (see
+	// goscript.Script.SetCompileLimits).
+	MaxSourceSize int
+
+	// MaxASTDepth is the maximum nesting depth of the syntax tree
+	MaxASTDepth int
+
+	// MaxFunctions is the maximum number of functions, methods and
+	// function literals
+	MaxFunctions int
+
+	// MaxFunctionInstructions is the maximum number of instructions
+	// compiled for one function
+	MaxFunctionInstructions int
+}
+
+// SetLimits sets the limits enforced by Compile
+func (c *Compiler) SetLimits(limits Limits) {
+	c.limits = limits
+}
+
+// checkSyntaxLimits reports a syntax tree nested deeper than MaxASTDepth or
+// declaring more than MaxFunctions functions. It runs before compiling, as
+// compiling deeply nested code recurses as deep.
+func (c *Compiler) checkSyntaxLimits(file *ast.File) {
+	maxDepth, maxFunctions := c.limits.MaxASTDepth, c.limits.MaxFunctions
+	if maxDepth <= 0 && maxFunctions <= 0 {
+		return
+	}
+	depth, functions := 0, 0
+	var tooDeep, tooMany ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			depth--
+			return true
+		}
+		depth++
+		if maxDepth > 0 && depth > maxDepth {
+			tooDeep = n
+		}
+		switch n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			functions++
+			if maxFunctions > 0 && functions > maxFunctions && tooMany == nil {
+				tooMany = n
+			}
+		}
+		// Children of a node over the depth limit are not visited, so the
+		// walk itself stays bounded
+		return tooDeep == nil
+	})
+	if tooDeep != nil {
+		c.report(tooDeep.Pos(), SeverityError, "syntax tree nesting exceeds the limit of %d levels", maxDepth)
+	}
+	if tooMany != nil {
+		c.report(tooMany.Pos(), SeverityError, "script declares more than %d functions", maxFunctions)
+	}
+}
+
+// checkInstructionLimits reports the compiled functions with more than
+// MaxFunctionInstructions instructions, at the position of their first
+// instruction
+func (c *Compiler) checkInstructionLimits() {
+	limit := c.limits.MaxFunctionInstructions
+	if limit <= 0 {
+		return
+	}
+	sets := c.compileContext.GetAllInstructions()
+	keys := make([]string, 0, len(sets))
+	for key := range sets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		instructions := sets[key]
+		if len(instructions) <= limit {
+			continue
+		}
+		diag := Diagnostic{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("function %s compiles to %d instructions, exceeding the limit of %d", key, len(instructions), limit),
+		}
+		for _, instr := range instructions {
+			if instr.Line > 0 {
+				diag.Pos = token.Position{Filename: instr.File, Line: instr.Line, Column: instr.Column}
+				break
+			}
+		}
+		c.diagnostics = append(c.diagnostics, diag)
+	}
+}
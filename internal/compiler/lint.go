@@ -0,0 +1,216 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// lint runs whole-program checks that catch scripts which would only stop
+// at the instruction limit. Findings are reported as warnings:
+//
+//   - a `for {}` loop with no break, return or goto that can leave it
+//   - a function that calls itself unconditionally and has no conditional
+//     return path (no base case)
+func (c *Compiler) lint(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		c.lintUnboundedLoops(fn.Body)
+		c.lintRecursion(fn)
+	}
+}
+
+// lintUnboundedLoops reports condition-less for loops that cannot exit
+func (c *Compiler) lintUnboundedLoops(body *ast.BlockStmt) {
+	labels := make(map[*ast.ForStmt]string)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch stmt := n.(type) {
+		case *ast.LabeledStmt:
+			if loop, ok := stmt.Stmt.(*ast.ForStmt); ok {
+				labels[loop] = stmt.Label.Name
+			}
+		case *ast.ForStmt:
+			if stmt.Cond == nil && !loopCanExit(stmt.Body, labels[stmt]) {
+				c.report(stmt.Pos(), SeverityWarning, "for loop without condition has no break, return or goto")
+			}
+		}
+		return true
+	})
+}
+
+// loopCanExit reports whether a loop body contains a statement that leaves
+// the loop: a return, a goto, an unlabeled break not captured by a nested
+// breakable statement, a break to the loop's label, or a break or continue
+// to the label of an enclosing statement
+func loopCanExit(body *ast.BlockStmt, label string) bool {
+	// Labels declared in the body name nested statements
+	inner := make(map[string]bool)
+	ast.Inspect(body, func(node ast.Node) bool {
+		if stmt, ok := node.(*ast.LabeledStmt); ok {
+			inner[stmt.Label.Name] = true
+		}
+		_, isFuncLit := node.(*ast.FuncLit)
+		return !isFuncLit
+	})
+
+	exits := false
+	var walk func(n ast.Node, nested bool)
+	walk = func(n ast.Node, nested bool) {
+		ast.Inspect(n, func(node ast.Node) bool {
+			if exits {
+				return false
+			}
+			switch stmt := node.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ReturnStmt:
+				exits = true
+			case *ast.BranchStmt:
+				switch stmt.Tok {
+				case token.GOTO:
+					exits = true
+				case token.BREAK, token.CONTINUE:
+					switch {
+					case stmt.Label == nil:
+						exits = stmt.Tok == token.BREAK && !nested
+					case stmt.Label.Name == label:
+						exits = stmt.Tok == token.BREAK
+					default:
+						exits = !inner[stmt.Label.Name]
+					}
+				}
+			case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				if node != n {
+					// An unlabeled break inside belongs to the nested statement
+					walk(node, true)
+					return false
+				}
+			}
+			return true
+		})
+	}
+	walk(body, false)
+	return exits
+}
+
+// lintRecursion reports self-recursive functions without a base case
+func (c *Compiler) lintRecursion(fn *ast.FuncDecl) {
+	v := &recursionVisitor{name: fn.Name.Name}
+	if fn.Recv != nil && len(fn.Recv.List) > 0 && len(fn.Recv.List[0].Names) > 0 {
+		v.receiver = fn.Recv.List[0].Names[0].Name
+	}
+	v.walkStmts(fn.Body.List, false)
+
+	if v.unconditionalCall && !v.baseCase {
+		c.report(fn.Pos(), SeverityWarning, "recursive function %s has no base case", fn.Name.Name)
+	}
+}
+
+// recursionVisitor tracks self-calls and returns of a function, and whether
+// they only happen on some paths (inside if, switch or loop bodies)
+type recursionVisitor struct {
+	name     string
+	receiver string
+
+	unconditionalCall bool
+	baseCase          bool
+}
+
+func (v *recursionVisitor) walkStmts(stmts []ast.Stmt, conditional bool) {
+	for _, stmt := range stmts {
+		v.walkStmt(stmt, conditional)
+	}
+}
+
+func (v *recursionVisitor) walkStmt(stmt ast.Stmt, conditional bool) {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		v.walkStmts(s.List, conditional)
+	case *ast.LabeledStmt:
+		v.walkStmt(s.Stmt, conditional)
+	case *ast.IfStmt:
+		v.walkStmt(s.Init, conditional)
+		v.walkExpr(s.Cond, conditional)
+		v.walkStmt(s.Body, true)
+		v.walkStmt(s.Else, true)
+	case *ast.ForStmt:
+		v.walkStmt(s.Init, conditional)
+		v.walkExpr(s.Cond, conditional)
+		v.walkStmt(s.Post, true)
+		v.walkStmt(s.Body, true)
+	case *ast.RangeStmt:
+		v.walkExpr(s.X, conditional)
+		v.walkStmt(s.Body, true)
+	case *ast.SwitchStmt:
+		v.walkStmt(s.Init, conditional)
+		v.walkExpr(s.Tag, conditional)
+		v.walkStmt(s.Body, true)
+	case *ast.TypeSwitchStmt:
+		v.walkStmt(s.Init, conditional)
+		v.walkStmt(s.Assign, conditional)
+		v.walkStmt(s.Body, true)
+	case *ast.CaseClause:
+		v.walkStmts(s.Body, true)
+	case *ast.ReturnStmt:
+		recursive := false
+		for _, result := range s.Results {
+			if v.containsSelfCall(result) {
+				recursive = true
+			}
+			v.walkExpr(result, conditional)
+		}
+		if conditional && !recursive {
+			v.baseCase = true
+		}
+	case nil:
+	default:
+		v.walkExpr(stmt, conditional)
+	}
+}
+
+// walkExpr records self-calls found in any node that is not a statement
+// handled above
+func (v *recursionVisitor) walkExpr(node ast.Node, conditional bool) {
+	if node == nil || conditional {
+		return
+	}
+	if v.containsSelfCall(node) {
+		v.unconditionalCall = true
+	}
+}
+
+// containsSelfCall reports whether node calls the function being checked,
+// ignoring calls inside function literals
+func (v *recursionVisitor) containsSelfCall(node ast.Node) bool {
+	if node == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch expr := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			found = v.isSelf(expr.Fun)
+		}
+		return true
+	})
+	return found
+}
+
+// isSelf reports whether fun names the function being checked
+func (v *recursionVisitor) isSelf(fun ast.Expr) bool {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return v.receiver == "" && f.Name == v.name
+	case *ast.SelectorExpr:
+		x, ok := f.X.(*ast.Ident)
+		return ok && v.receiver != "" && x.Name == v.receiver && f.Sel.Name == v.name
+	}
+	return false
+}
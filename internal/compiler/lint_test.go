@@ -0,0 +1,87 @@
+package compiler
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+// lintWarnings compiles code and returns the warning messages
+func lintWarnings(t *testing.T, code string) []string {
+	t.Helper()
+	compiler := NewCompiler(vm.NewVM())
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, "", code, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse code: %v", err)
+	}
+	compiler.SetFileSet(fset)
+	if err := compiler.Compile(astFile); err != nil {
+		t.Fatalf("Failed to compile code: %v", err)
+	}
+
+	var messages []string
+	for _, diag := range compiler.Diagnostics().Warnings() {
+		messages = append(messages, diag.Message)
+	}
+	return messages
+}
+
+func TestLintUnboundedLoop(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		warn bool
+	}{
+		{"no exit", "x := 0\n\tfor {\n\t\tx = x + 1\n\t}", true},
+		{"break", "for {\n\t\tbreak\n\t}", false},
+		{"return", "for {\n\t\treturn\n\t}", false},
+		{"break only inner loop", "for {\n\t\tfor i := 0; i < 3; i++ {\n\t\t\tbreak\n\t\t}\n\t}", true},
+		{"break only switch", "x := 1\n\tfor {\n\t\tswitch x {\n\t\tcase 1:\n\t\t\tbreak\n\t\t}\n\t}", true},
+		{"labeled break from inner loop", "outer:\n\tfor {\n\t\tfor i := 0; i < 3; i++ {\n\t\t\tbreak outer\n\t\t}\n\t}", false},
+		{"inner loop left by labeled break", "outer:\n\tfor i := 0; i < 3; i++ {\n\t\tfor {\n\t\t\tbreak outer\n\t\t}\n\t}", false},
+		{"inner loop left by labeled continue", "outer:\n\tfor i := 0; i < 3; i++ {\n\t\tfor {\n\t\t\tcontinue outer\n\t\t}\n\t}", false},
+		{"continue own label", "loop:\n\tfor {\n\t\tcontinue loop\n\t}", true},
+		{"break to nested label", "for {\n\tinner:\n\t\tfor i := 0; i < 3; i++ {\n\t\t\tbreak inner\n\t\t}\n\t}", true},
+		{"conditional loop", "x := 0\n\tfor x < 10 {\n\t\tx = x + 1\n\t}", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "package main\n\nfunc main() {\n\t" + tt.body + "\n}\n"
+			warnings := lintWarnings(t, code)
+			if got := len(warnings) == 1 && strings.Contains(warnings[0], "for loop"); got != tt.warn {
+				t.Errorf("Expected warning=%v, got %v", tt.warn, warnings)
+			}
+		})
+	}
+}
+
+func TestLintRecursionWithoutBaseCase(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   string
+		warn bool
+	}{
+		{"no base case", "func f(n int) int {\n\treturn n * f(n-1)\n}", true},
+		{"statement call", "func f(n int) {\n\tf(n + 1)\n}", true},
+		{"base case", "func f(n int) int {\n\tif n <= 1 {\n\t\treturn 1\n\t}\n\treturn n * f(n-1)\n}", false},
+		{"guarded call", "func f(n int) {\n\tif n > 0 {\n\t\tf(n - 1)\n\t}\n}", false},
+		{"recursive conditional return", "func f(n int) int {\n\tif n > 0 {\n\t\treturn f(n - 1)\n\t}\n\treturn f(n + 1)\n}", true},
+		{"method", "type T struct{}\n\nfunc (t T) M() int {\n\treturn t.M()\n}", true},
+		{"not recursive", "func f(n int) int {\n\treturn n\n}", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "package main\n\n" + tt.fn + "\n\nfunc main() {\n}\n"
+			warnings := lintWarnings(t, code)
+			if got := len(warnings) == 1 && strings.Contains(warnings[0], "no base case"); got != tt.warn {
+				t.Errorf("Expected warning=%v, got %v", tt.warn, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Local variable slots. Once a function is compiled, its local variables
+// that are only created, loaded and stored by name are resolved to slots of
+// the function's frame: CREATE_VAR gets the slot as its second argument and
+// LOAD_NAME and STORE_NAME become LOAD_LOCAL and STORE_LOCAL. Instructions
+// are rewritten in place, so jump targets are unchanged. Other variables
+// keep name-based access: package variables, variables of functions that
+// create closures (which capture scopes by name), and variables that other
+// instructions refer to by name (e.g., &x, x.f or a call of x).
+
+// localVar is a variable declared in a function
+type localVar struct {
+	name    string
+	dynamic bool
+	slot    int
+}
+
+// resolveLocals resolves the local variables of a function's instructions
+// to slots and returns the number of slots, which is recorded in the
+// function's info
+func resolveLocals(instructions []*instruction.Instruction) int {
+	for _, instr := range instructions {
+		if instr.Op == instruction.OpMakeClosure {
+			return 0
+		}
+	}
+
+	// Declarations by CREATE_VAR instruction, and the variable each load
+	// or store refers to. Scopes follow ENTER_SCOPE_WITH_KEY and
+	// EXIT_SCOPE_WITH_KEY; exits that do not close the innermost scope
+	// are those of break and continue, which end no scope.
+	type scope struct {
+		key  interface{}
+		vars map[string]*localVar
+	}
+	scopes := []scope{{vars: make(map[string]*localVar)}}
+	lookup := func(name string) *localVar {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if v, ok := scopes[i].vars[name]; ok {
+				return v
+			}
+		}
+		return nil
+	}
+	refs := make(map[*instruction.Instruction]*localVar)
+	var vars []*localVar
+
+	for _, instr := range instructions {
+		switch instr.Op {
+		case instruction.OpEnterScopeWithKey:
+			scopes = append(scopes, scope{key: instr.Arg, vars: make(map[string]*localVar)})
+			continue
+		case instruction.OpExitScopeWithKey:
+			if len(scopes) > 1 && scopes[len(scopes)-1].key == instr.Arg {
+				scopes = scopes[:len(scopes)-1]
+			}
+			continue
+		case instruction.OpCreateVar:
+			// Compiler temporaries with qualified names are left alone
+			name, ok := instr.Arg.(string)
+			if !ok || instr.Arg2 != nil || strings.Contains(name, ".") {
+				continue
+			}
+			// Declaring a name again in the same scope keeps the variable
+			top := scopes[len(scopes)-1].vars
+			v, exists := top[name]
+			if !exists {
+				v = &localVar{name: name}
+				top[name] = v
+				vars = append(vars, v)
+			}
+			refs[instr] = v
+			continue
+		case instruction.OpLoadName, instruction.OpStoreName:
+			if name, ok := instr.Arg.(string); ok && !strings.Contains(name, ".") {
+				if v := lookup(name); v != nil {
+					refs[instr] = v
+				}
+				continue
+			}
+		case instruction.OpLoadConst, instruction.OpJump, instruction.OpJumpIf, instruction.OpLabel:
+			continue
+		}
+
+		// Any other reference by name needs the variable in its scope
+		for _, arg := range []interface{}{instr.Arg, instr.Arg2} {
+			name, ok := arg.(string)
+			if !ok {
+				continue
+			}
+			if i := strings.IndexByte(name, '.'); i >= 0 {
+				name = name[:i]
+			}
+			if v := lookup(name); v != nil {
+				v.dynamic = true
+			}
+		}
+	}
+
+	slots := 0
+	for _, v := range vars {
+		if !v.dynamic {
+			v.slot = slots
+			slots++
+		}
+	}
+	if slots == 0 {
+		return 0
+	}
+	for instr, v := range refs {
+		if v.dynamic {
+			continue
+		}
+		switch instr.Op {
+		case instruction.OpCreateVar:
+			instr.Arg2 = v.slot
+		case instruction.OpLoadName:
+			instr.Op, instr.Arg, instr.Arg2 = instruction.OpLoadLocal, v.slot, v.name
+		case instruction.OpStoreName:
+			instr.Op, instr.Arg, instr.Arg2 = instruction.OpStoreLocal, v.slot, v.name
+		}
+	}
+	return slots
+}
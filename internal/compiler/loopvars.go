@@ -0,0 +1,101 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Per-iteration loop variables. As in Go 1.22 and later, each iteration of
+// a loop has its own copy of the variables the loop declares, so closures
+// and pointers created in one iteration do not see later ones. Copies only
+// make a difference when the body captures or addresses a variable, so
+// loops that do neither keep a single variable. A range loop then declares
+// its variables in a scope of their own for each iteration. A for loop runs
+// each iteration in a scope holding copies of its variables, which are
+// copied back before the post statement, as Go copies them into the
+// variables of the next iteration.
+
+// loopVarNames returns the variables declared by the init statement of a
+// for loop
+func loopVarNames(init ast.Stmt) []string {
+	assign, ok := init.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.DEFINE {
+		return nil
+	}
+	var names []string
+	for _, lhs := range assign.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" {
+			names = append(names, ident.Name)
+		}
+	}
+	return names
+}
+
+// rangeVarNames returns the variables declared by a range statement
+func rangeVarNames(stmt *ast.RangeStmt) []string {
+	if stmt.Tok != token.DEFINE {
+		return nil
+	}
+	var names []string
+	for _, expr := range []ast.Expr{stmt.Key, stmt.Value} {
+		if ident, ok := expr.(*ast.Ident); ok && ident.Name != "_" {
+			names = append(names, ident.Name)
+		}
+	}
+	return names
+}
+
+// capturesLoopVars reports whether the nodes of a loop create function
+// literals or take the address of one of its variables
+func capturesLoopVars(names []string, nodes ...ast.Node) bool {
+	if len(names) == 0 {
+		return false
+	}
+	captured := false
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				captured = true
+			case *ast.UnaryExpr:
+				if ident, ok := n.X.(*ast.Ident); ok && n.Op == token.AND {
+					for _, name := range names {
+						captured = captured || ident.Name == name
+					}
+				}
+			}
+			return !captured
+		})
+	}
+	return captured
+}
+
+// enterIteration enters the scope of a loop iteration and declares copies
+// of the loop variables in it
+func (c *Compiler) enterIteration(names []string) string {
+	key := c.generateKey("for_iteration")
+	c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, key, nil))
+	for _, name := range names {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, name, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, name, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, name, nil))
+	}
+	return key
+}
+
+// exitIteration leaves the scope of a loop iteration, copying the loop
+// variables back to the variables of the loop
+func (c *Compiler) exitIteration(key string, names []string) {
+	for _, name := range names {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, name, nil))
+	}
+	c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, key, nil))
+	for i := len(names) - 1; i >= 0; i-- {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, names[i], nil))
+	}
+}
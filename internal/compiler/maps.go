@@ -0,0 +1,137 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// compileMapLit compiles a map literal (e.g., map[string]int{"a": 1})
+func (c *Compiler) compileMapLit(lit *ast.CompositeLit, mapType *ast.MapType) error {
+	c.emitInstruction(instruction.NewInstruction(instruction.OpNewMap, c.getTypeName(mapType.Key), nil))
+	if len(lit.Elts) == 0 {
+		return nil
+	}
+
+	// Store the map in a temporary variable so we can reference it multiple times
+	tempVarName := c.generateKey("map_lit")
+	c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, tempVarName, nil))
+
+	// Constant keys must be unique, as in Go
+	keys := make(map[interface{}]bool)
+	for _, elem := range lit.Elts {
+		kv, ok := elem.(*ast.KeyValueExpr)
+		if !ok {
+			return fmt.Errorf("missing key in map literal")
+		}
+		if key, err := c.evalConst(kv.Key, 0); err == nil {
+			if keys[constValue(key)] {
+				return fmt.Errorf("duplicate key %s in map literal", key)
+			}
+			keys[constValue(key)] = true
+		}
+
+		// Stack should be: [..., map, key, value]
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, tempVarName, nil))
+		if err := c.compileElement(kv.Key, mapType.Key); err != nil {
+			return err
+		}
+		if err := c.compileElement(kv.Value, mapType.Value); err != nil {
+			return err
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpSetIndex, nil, nil))
+	}
+
+	// Load the final map onto the stack
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, tempVarName, nil))
+	return nil
+}
+
+// compileMakeMap compiles make(map[K]V) and make(map[K]V, size). The size
+// is only a hint and is ignored.
+func (c *Compiler) compileMakeMap(call *ast.CallExpr, mapType *ast.MapType) error {
+	if len(call.Args) > 2 {
+		return fmt.Errorf("too many arguments in call to make")
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpNewMap, c.getTypeName(mapType.Key), nil))
+	return nil
+}
+
+// Element types of maps. Maps do not record the type of their values, so a
+// missing key would read as nil; the compiler passes the element type of
+// the maps it knows the type of to GET_INDEX, which reads missing keys as
+// its zero value. The type is known for map literals, make, variables
+// declared with a type or initialized with such an expression, parameters,
+// and the struct fields and elements of those.
+
+// staticType returns the type of an expression when it is evident from the
+// source, or nil
+func (c *Compiler) staticType(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return c.staticType(e.X)
+	case *ast.Ident:
+		return c.declaredType(e.Name)
+	case *ast.CompositeLit:
+		return e.Type
+	case *ast.CallExpr:
+		if ident, ok := e.Fun.(*ast.Ident); ok && ident.Name == "make" && len(e.Args) > 0 && !c.isLocal("make") {
+			return e.Args[0]
+		}
+	case *ast.SelectorExpr:
+		typ := c.underlyingType(c.staticType(e.X))
+		if ptr, ok := typ.(*ast.StarExpr); ok {
+			typ = c.underlyingType(ptr.X)
+		}
+		if structType, ok := typ.(*ast.StructType); ok {
+			for _, field := range structType.Fields.List {
+				for _, name := range field.Names {
+					if name.Name == e.Sel.Name {
+						return field.Type
+					}
+				}
+			}
+		}
+	case *ast.IndexExpr:
+		switch t := c.underlyingType(c.staticType(e.X)).(type) {
+		case *ast.MapType:
+			return t.Value
+		case *ast.ArrayType:
+			return t.Elt
+		}
+	case *ast.StarExpr:
+		if ptr, ok := c.underlyingType(c.staticType(e.X)).(*ast.StarExpr); ok {
+			return ptr.X
+		}
+	}
+	return nil
+}
+
+// underlyingType follows the declarations of named types
+func (c *Compiler) underlyingType(typ ast.Expr) ast.Expr {
+	for i := 0; i < len(c.typeDecls)+1; i++ {
+		switch t := typ.(type) {
+		case *ast.ParenExpr:
+			typ = t.X
+		case *ast.Ident:
+			decl, ok := c.typeDecls[t.Name]
+			if !ok {
+				return typ
+			}
+			typ = decl
+		default:
+			return typ
+		}
+	}
+	return typ
+}
+
+// elementZero returns the GET_INDEX argument giving the element type of an
+// indexed map, or nil when its type is not known
+func (c *Compiler) elementZero(x ast.Expr) interface{} {
+	if mapType, ok := c.underlyingType(c.staticType(x)).(*ast.MapType); ok {
+		return c.typeDescriptor(mapType.Value)
+	}
+	return nil
+}
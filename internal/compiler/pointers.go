@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// compileAddressOf compiles &x. Variables, struct fields and slice elements
+// are addressed with a pointer; &T{...} is a pointer to a new struct.
+func (c *Compiler) compileAddressOf(expr *ast.UnaryExpr) error {
+	x := ast.Unparen(expr.X)
+	switch e := x.(type) {
+	case *ast.CompositeLit:
+		if err := c.compileExpr(e); err != nil {
+			return err
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpAddrValue, nil, nil))
+	case *ast.Ident:
+		if _, isConst := c.lookupConst(e.Name); isConst || !c.isLocal(e.Name) && c.scriptFunctions[e.Name] {
+			return fmt.Errorf("cannot take address of %s", e.Name)
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpAddrOf, e.Name, nil))
+	case *ast.SelectorExpr:
+		if ident, ok := e.X.(*ast.Ident); ok {
+			if _, isModule := c.importedModules[ident.Name]; isModule {
+				return fmt.Errorf("cannot take address of %s", exprString(e))
+			}
+		}
+		if err := c.compileExpr(e.X); err != nil {
+			return err
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpAddrField, e.Sel.Name, nil))
+	case *ast.IndexExpr:
+		if err := c.compileExpr(e.X); err != nil {
+			return err
+		}
+		if err := c.compileExpr(e.Index); err != nil {
+			return err
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpAddrIndex, nil, nil))
+	case *ast.StarExpr:
+		// &*p is p
+		return c.compileExpr(e.X)
+	default:
+		return fmt.Errorf("cannot take address of %s", exprString(x))
+	}
+	return nil
+}
+
+// compileStarExpr compiles *p
+func (c *Compiler) compileStarExpr(expr *ast.StarExpr) error {
+	if err := c.compileExpr(expr.X); err != nil {
+		return err
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpDeref, nil, nil))
+	return nil
+}
+
+// compileStoreDeref compiles *p = v and compound assignments such as
+// *p += v
+func (c *Compiler) compileStoreDeref(lhs *ast.StarExpr, tok token.Token, rhs ast.Expr) error {
+	if err := c.compileExpr(lhs.X); err != nil {
+		return err
+	}
+	value := rhs
+	if tok != token.ASSIGN {
+		// *p op= v stores *p op v
+		op, ok := compoundOps[tok]
+		if !ok {
+			return fmt.Errorf("unsupported compound assignment operator: %s", tok)
+		}
+		value = &ast.BinaryExpr{X: lhs, OpPos: rhs.Pos(), Op: op, Y: rhs}
+	}
+	if err := c.compileExpr(value); err != nil {
+		return err
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpStoreDeref, nil, nil))
+	return nil
+}
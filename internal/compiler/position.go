@@ -0,0 +1,51 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Source positions on instructions. Every emitted instruction carries the
+// position of the statement it was compiled from, and the first instruction
+// of each statement is flagged, so a debugger can step by statement or line
+// instead of by stack operation, and runtime errors report where they
+// happened.
+
+// markStatement makes stmt the source of the instructions emitted next and
+// flags the first of them as a statement start. The returned function
+// restores the position of the enclosing statement.
+func (c *Compiler) markStatement(stmt ast.Stmt) func() {
+	prevPos := c.currentPos
+	if pos := c.positionOf(stmt.Pos()); pos.IsValid() {
+		c.currentPos = pos
+	}
+	// A block is not a step of its own; its statements are
+	if _, isBlock := stmt.(*ast.BlockStmt); !isBlock {
+		c.stmtPending = true
+	}
+	return func() { c.currentPos = prevPos }
+}
+
+// positionOf resolves a position, or returns the zero position without a
+// file set
+func (c *Compiler) positionOf(pos token.Pos) token.Position {
+	if c.fset == nil || !pos.IsValid() {
+		return token.Position{}
+	}
+	return c.fset.Position(pos)
+}
+
+// emitUntracked emits a bookkeeping instruction (such as entering or
+// leaving a scope) that has no source line, so stepping skips over it. It
+// also tracks the scope depth used to leave scopes on break and continue.
+func (c *Compiler) emitUntracked(instr *instruction.Instruction) {
+	switch instr.Op {
+	case instruction.OpEnterScopeWithKey:
+		c.scopeDepth++
+	case instruction.OpExitScopeWithKey:
+		c.scopeDepth--
+	}
+	c.currentInstructions = append(c.currentInstructions, instr)
+}
@@ -0,0 +1,204 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Lexical scope tracking. The compiler mirrors the runtime scopes opened by
+// ENTER_SCOPE_WITH_KEY so that := can tell variables declared in the current
+// scope (which it assigns) from new ones (which it creates), following Go's
+// rule that at least one variable on the left side of := must be new.
+
+// pushScope opens a lexical scope. Parameters collected for a function body
+// are declared in the body's scope, as in Go.
+func (c *Compiler) pushScope() {
+	scope := make(map[string]bool)
+	for _, name := range c.pendingParams {
+		scope[name] = true
+	}
+	c.pendingParams = nil
+	c.scopes = append(c.scopes, scope)
+	c.scopeConsts = append(c.scopeConsts, nil)
+	c.scopeTypes = append(c.scopeTypes, c.pendingParamTypes)
+	c.pendingParamTypes = nil
+}
+
+// popScope closes the innermost lexical scope
+func (c *Compiler) popScope() {
+	if len(c.scopes) > 0 {
+		c.scopes = c.scopes[:len(c.scopes)-1]
+		c.scopeConsts = c.scopeConsts[:len(c.scopeConsts)-1]
+		c.scopeTypes = c.scopeTypes[:len(c.scopeTypes)-1]
+	}
+}
+
+// declare records a variable declared in the innermost scope
+func (c *Compiler) declare(name string) {
+	if len(c.scopes) > 0 && name != "_" {
+		c.scopes[len(c.scopes)-1][name] = true
+	}
+}
+
+// declaredInScope reports whether name is declared in the innermost scope
+func (c *Compiler) declaredInScope(name string) bool {
+	return len(c.scopes) > 0 && c.scopes[len(c.scopes)-1][name]
+}
+
+// defineTargets checks the left side of a := statement and reports which
+// identifiers are new in the current scope
+func (c *Compiler) defineTargets(lhs []ast.Expr) ([]bool, error) {
+	isNew := make([]bool, len(lhs))
+	seen := make(map[string]bool)
+	anyNew := false
+	for i, expr := range lhs {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("non-name %s on left side of :=", exprString(expr))
+		}
+		if ident.Name == "_" {
+			continue
+		}
+		if seen[ident.Name] {
+			return nil, fmt.Errorf("%s repeated on left side of :=", ident.Name)
+		}
+		seen[ident.Name] = true
+		if !c.declaredInScope(ident.Name) {
+			isNew[i] = true
+			anyNew = true
+		}
+	}
+	if !anyNew {
+		return nil, fmt.Errorf("no new variables on left side of :=")
+	}
+	return isNew, nil
+}
+
+// compileMultiAssign compiles assignments with several targets, such as
+// a, b = b, a, a, err := x, y or v, err := f(). All values are evaluated
+// before any target is assigned.
+func (c *Compiler) compileMultiAssign(stmt *ast.AssignStmt) error {
+	if stmt.Tok != token.ASSIGN && stmt.Tok != token.DEFINE {
+		return fmt.Errorf("assignment operator %s requires single-valued expressions", stmt.Tok)
+	}
+	// A call to a function with several results, e.g., v, err := f()
+	_, isCall := stmt.Rhs[0].(*ast.CallExpr)
+	unpack := len(stmt.Rhs) == 1 && isCall
+	// A receive, type assertion or map index with ok (v, ok := <-ch,
+	// v, ok := x.(T), v, ok := m[k]) pushes both values
+	recv, isRecvExpr := isRecv(stmt.Rhs[0])
+	assert, isAssert := stmt.Rhs[0].(*ast.TypeAssertExpr)
+	index, isIndex := stmt.Rhs[0].(*ast.IndexExpr)
+	commaOk := len(stmt.Rhs) == 1 && len(stmt.Lhs) == 2 && (isRecvExpr || isAssert || isIndex)
+	if unpack {
+		if n, known := c.resultCount(stmt.Rhs[0].(*ast.CallExpr)); known && n != len(stmt.Lhs) {
+			if n == 1 {
+				return fmt.Errorf("assignment mismatch: %d variables but %s returns 1 value", len(stmt.Lhs), exprString(stmt.Rhs[0]))
+			}
+			return fmt.Errorf("assignment mismatch: %d variables but %s returns %d values", len(stmt.Lhs), exprString(stmt.Rhs[0]), n)
+		}
+	} else if len(stmt.Rhs) != len(stmt.Lhs) && !commaOk {
+		return fmt.Errorf("assignment mismatch: %d variables but %d values", len(stmt.Lhs), len(stmt.Rhs))
+	}
+
+	var isNew []bool
+	if stmt.Tok == token.DEFINE {
+		var err error
+		if isNew, err = c.defineTargets(stmt.Lhs); err != nil {
+			return err
+		}
+	} else {
+		for _, expr := range stmt.Lhs {
+			if _, ok := expr.(*ast.Ident); !ok {
+				return fmt.Errorf("unsupported assignment target in multiple assignment: %s", exprString(expr))
+			}
+		}
+	}
+
+	switch {
+	case commaOk && isRecvExpr:
+		if err := c.compileRecv(recv, true); err != nil {
+			return err
+		}
+	case commaOk && isAssert:
+		if err := c.compileTypeAssertExpr(assert, instruction.AssertCommaOk); err != nil {
+			return err
+		}
+	case commaOk && isIndex:
+		if err := c.compileExpr(index.X); err != nil {
+			return err
+		}
+		if err := c.compileExpr(index.Index); err != nil {
+			return err
+		}
+		c.emitInstruction(instruction.NewInstruction(instruction.OpGetIndex, true, c.elementZero(index.X)))
+	default:
+		for _, rhs := range stmt.Rhs {
+			if err := c.compileExpr(rhs); err != nil {
+				return err
+			}
+		}
+	}
+	if unpack {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpUnpack, len(stmt.Lhs), nil))
+	}
+
+	// Values are on the stack in order, so assign from the last target
+	for i := len(stmt.Lhs) - 1; i >= 0; i-- {
+		c.storeTarget(stmt.Lhs[i].(*ast.Ident).Name, isNew != nil && isNew[i])
+	}
+	return nil
+}
+
+// storeTarget stores the value on top of the stack in a variable, creating
+// it first when create is set. The blank identifier discards the value.
+func (c *Compiler) storeTarget(name string, create bool) {
+	if name == "_" {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpPop, nil, nil))
+		return
+	}
+	if create {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, name, nil))
+		c.declare(name)
+	} else {
+		c.emitImplements(c.declaredType(name))
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, name, nil))
+}
+
+// exprString formats an expression for error messages
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.CallExpr:
+		return exprString(e.Fun) + "()"
+	case *ast.IndexExpr:
+		return exprString(e.X) + "[...]"
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
+
+// resultCount returns the number of results of a call when it is known at
+// compile time: declared by a script function, or one for universe builtins.
+// Other calls are checked when the tuple is unpacked.
+func (c *Compiler) resultCount(call *ast.CallExpr) (int, bool) {
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok {
+		return 0, false
+	}
+	if c.scriptFunctions[ident.Name] {
+		n, declared := c.resultCounts[ident.Name]
+		return n, declared
+	}
+	if _, isBuiltin := universeArity[ident.Name]; isBuiltin && !c.shadowed(ident.Name) {
+		return 1, true
+	}
+	return 0, false
+}
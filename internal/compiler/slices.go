@@ -0,0 +1,65 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	gotypes "go/types"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// compileMakeSlice compiles make([]T, len) and make([]T, len, cap)
+func (c *Compiler) compileMakeSlice(call *ast.CallExpr, sliceType *ast.ArrayType) error {
+	if sliceType.Len != nil {
+		return fmt.Errorf("invalid argument: cannot make %s", exprString(sliceType))
+	}
+	if len(call.Args) < 2 {
+		return fmt.Errorf("invalid operation: %s expects 2 or 3 arguments; found %d", exprString(call), len(call.Args))
+	}
+	if len(call.Args) > 3 {
+		return fmt.Errorf("too many arguments in call to make")
+	}
+	for _, arg := range call.Args[1:] {
+		if err := c.compileExpr(arg); err != nil {
+			return err
+		}
+	}
+	if len(call.Args) == 2 {
+		// The capacity defaults to the length
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, nil, nil))
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpMakeSlice, c.getTypeName(sliceType.Elt), nil))
+	return nil
+}
+
+// sliceConversions maps the element types of the slice types values can be
+// converted to, []byte(s) and []rune(s), to the builtins converting them
+var sliceConversions = map[string]string{
+	"byte":  "[]byte",
+	"uint8": "[]byte",
+	"rune":  "[]rune",
+	"int32": "[]rune",
+}
+
+// compileSliceConversion compiles a conversion to a byte or rune slice,
+// e.g. []byte(s), as a call to the builtin of the same name
+func (c *Compiler) compileSliceConversion(call *ast.CallExpr, sliceType *ast.ArrayType) error {
+	builtin, ok := sliceConversions[c.getTypeName(sliceType.Elt)]
+	if !ok || sliceType.Len != nil {
+		return fmt.Errorf("unsupported conversion to %s", gotypes.ExprString(sliceType))
+	}
+	if len(call.Args) != 1 {
+		return fmt.Errorf("wrong argument count in conversion to %s", gotypes.ExprString(sliceType))
+	}
+	if err := c.compileExpr(call.Args[0]); err != nil {
+		return err
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpCall, builtin, 1).WithTags(instruction.TagHostCall))
+	return nil
+}
+
+// isByteSlice reports whether a type is []byte
+func (c *Compiler) isByteSlice(typ ast.Expr) bool {
+	sliceType, ok := typ.(*ast.ArrayType)
+	return ok && sliceType.Len == nil && sliceConversions[c.getTypeName(sliceType.Elt)] == "[]byte"
+}
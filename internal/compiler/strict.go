@@ -0,0 +1,350 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// SetStrict enables or disables the strict diagnostics pass, which reports
+// as warnings:
+//
+//   - local variables that are declared and never used
+//   - statements after a return, goto, break, continue or panic
+//   - local declarations that shadow a variable of an enclosing scope
+//
+// Assignments to undeclared names are errors in both modes.
+func (c *Compiler) SetStrict(enabled bool) {
+	c.strict = enabled
+}
+
+// strictVar is a local variable tracked by the strict pass
+type strictVar struct {
+	name string
+	pos  token.Pos
+	used bool
+
+	// Parameters, results and constants are never reported as unused
+	param bool
+}
+
+// strictChecker resolves the local names of functions and records which
+// variables are used
+type strictChecker struct {
+	c *Compiler
+
+	// Variables of each open scope, innermost last, and every variable in
+	// declaration order
+	scopes   []map[string]*strictVar
+	declared []*strictVar
+}
+
+// lintStrict runs the strict diagnostics pass (see SetStrict)
+func (c *Compiler) lintStrict(file *ast.File) {
+	s := &strictChecker{c: c}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Body == nil {
+				continue
+			}
+			s.push()
+			s.declareFields(d.Recv)
+			s.declareFields(d.Type.Params)
+			s.declareFields(d.Type.Results)
+			// Parameters and the function body share a scope
+			s.stmts(d.Body.List)
+			s.pop()
+		case *ast.GenDecl:
+			// Function literals in package-level initializers
+			for _, spec := range d.Specs {
+				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+					for _, value := range valueSpec.Values {
+						s.expr(value)
+					}
+				}
+			}
+		}
+	}
+
+	unused := make([]*strictVar, 0)
+	for _, v := range s.declared {
+		if !v.used && !v.param {
+			unused = append(unused, v)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool { return unused[i].pos < unused[j].pos })
+	for _, v := range unused {
+		c.report(v.pos, SeverityWarning, "declared and not used: %s", v.name)
+	}
+}
+
+func (s *strictChecker) push() {
+	s.scopes = append(s.scopes, make(map[string]*strictVar))
+}
+
+func (s *strictChecker) pop() {
+	s.scopes = s.scopes[:len(s.scopes)-1]
+}
+
+// declare adds a variable to the innermost scope, reporting the variables
+// of enclosing scopes it shadows
+func (s *strictChecker) declare(ident *ast.Ident, param bool) *strictVar {
+	if ident == nil || ident.Name == "_" {
+		return nil
+	}
+	if outer := s.lookup(ident.Name); outer != nil {
+		if s.c.fset != nil && outer.pos.IsValid() {
+			s.c.report(ident.Pos(), SeverityWarning, "declaration of %q shadows declaration at line %d",
+				ident.Name, s.c.fset.Position(outer.pos).Line)
+		} else {
+			s.c.report(ident.Pos(), SeverityWarning, "declaration of %q shadows an enclosing declaration", ident.Name)
+		}
+	}
+	v := &strictVar{name: ident.Name, pos: ident.Pos(), param: param}
+	s.scopes[len(s.scopes)-1][ident.Name] = v
+	s.declared = append(s.declared, v)
+	return v
+}
+
+// declareFields declares the named parameters, results or receiver of a
+// function
+func (s *strictChecker) declareFields(fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			s.declare(name, true)
+		}
+	}
+}
+
+// lookup returns the innermost variable with the given name, or nil if the
+// name is not a local variable
+func (s *strictChecker) lookup(name string) *strictVar {
+	for i := len(s.scopes) - 1; i >= 0; i-- {
+		if v, ok := s.scopes[i][name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// stmts checks a statement list, reporting the first statement of each run
+// that cannot be reached. A labeled statement can be reached by goto.
+func (s *strictChecker) stmts(list []ast.Stmt) {
+	terminated, reported := false, false
+	for _, stmt := range list {
+		if _, labeled := stmt.(*ast.LabeledStmt); labeled {
+			terminated, reported = false, false
+		}
+		if terminated && !reported {
+			s.c.report(stmt.Pos(), SeverityWarning, "unreachable code")
+			reported = true
+		}
+		s.stmt(stmt)
+		if isTerminating(stmt) {
+			terminated = true
+		}
+	}
+}
+
+// block checks a statement list in a new scope
+func (s *strictChecker) block(list []ast.Stmt) {
+	s.push()
+	s.stmts(list)
+	s.pop()
+}
+
+func (s *strictChecker) stmt(stmt ast.Stmt) {
+	switch st := stmt.(type) {
+	case nil:
+	case *ast.BlockStmt:
+		s.block(st.List)
+	case *ast.LabeledStmt:
+		s.stmt(st.Stmt)
+	case *ast.ExprStmt:
+		s.expr(st.X)
+	case *ast.SendStmt:
+		s.expr(st.Chan)
+		s.expr(st.Value)
+	case *ast.IncDecStmt:
+		s.target(st.X)
+	case *ast.GoStmt:
+		s.expr(st.Call)
+	case *ast.DeferStmt:
+		s.expr(st.Call)
+	case *ast.ReturnStmt:
+		for _, result := range st.Results {
+			s.expr(result)
+		}
+	case *ast.AssignStmt:
+		for _, rhs := range st.Rhs {
+			s.expr(rhs)
+		}
+		for _, lhs := range st.Lhs {
+			ident, ok := lhs.(*ast.Ident)
+			if st.Tok != token.DEFINE || !ok {
+				s.target(lhs)
+			} else if _, redeclared := s.scopes[len(s.scopes)-1][ident.Name]; !redeclared {
+				s.declare(ident, false)
+			}
+		}
+	case *ast.DeclStmt:
+		genDecl, ok := st.Decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok == token.TYPE {
+			return
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			for _, value := range valueSpec.Values {
+				s.expr(value)
+			}
+			for _, name := range valueSpec.Names {
+				s.declare(name, genDecl.Tok == token.CONST)
+			}
+		}
+	case *ast.IfStmt:
+		s.push()
+		s.stmt(st.Init)
+		s.expr(st.Cond)
+		s.stmt(st.Body)
+		s.stmt(st.Else)
+		s.pop()
+	case *ast.ForStmt:
+		s.push()
+		s.stmt(st.Init)
+		s.expr(st.Cond)
+		s.stmt(st.Post)
+		s.stmt(st.Body)
+		s.pop()
+	case *ast.RangeStmt:
+		s.expr(st.X)
+		s.push()
+		for _, target := range []ast.Expr{st.Key, st.Value} {
+			if ident, ok := target.(*ast.Ident); ok && st.Tok == token.DEFINE {
+				s.declare(ident, false)
+			} else if target != nil {
+				s.target(target)
+			}
+		}
+		s.stmt(st.Body)
+		s.pop()
+	case *ast.SwitchStmt:
+		s.push()
+		s.stmt(st.Init)
+		s.expr(st.Tag)
+		for _, clause := range st.Body.List {
+			caseClause := clause.(*ast.CaseClause)
+			for _, value := range caseClause.List {
+				s.expr(value)
+			}
+			s.block(caseClause.Body)
+		}
+		s.pop()
+	case *ast.TypeSwitchStmt:
+		s.typeSwitch(st)
+	case *ast.SelectStmt:
+		for _, clause := range st.Body.List {
+			commClause := clause.(*ast.CommClause)
+			s.push()
+			s.stmt(commClause.Comm)
+			s.stmts(commClause.Body)
+			s.pop()
+		}
+	}
+}
+
+// typeSwitch checks a type switch. The variable bound by "v := x.(type)" is
+// declared in every clause and is used if any clause uses it.
+func (s *strictChecker) typeSwitch(st *ast.TypeSwitchStmt) {
+	s.push()
+	defer s.pop()
+	s.stmt(st.Init)
+
+	var binding *strictVar
+	switch assign := st.Assign.(type) {
+	case *ast.ExprStmt:
+		s.expr(assign.X)
+	case *ast.AssignStmt:
+		for _, rhs := range assign.Rhs {
+			s.expr(rhs)
+		}
+		if ident, ok := assign.Lhs[0].(*ast.Ident); ok {
+			binding = s.declare(ident, false)
+		}
+	}
+
+	for _, clause := range st.Body.List {
+		caseClause := clause.(*ast.CaseClause)
+		s.push()
+		if binding != nil {
+			s.scopes[len(s.scopes)-1][binding.name] = binding
+		}
+		s.stmts(caseClause.Body)
+		s.pop()
+	}
+}
+
+// target checks the target of an assignment or of ++ and --. Assigning to a
+// variable does not use it, but indexing it or selecting a field does.
+func (s *strictChecker) target(expr ast.Expr) {
+	if _, ok := expr.(*ast.Ident); ok {
+		return
+	}
+	s.expr(expr)
+}
+
+// expr marks the local variables an expression refers to as used
+func (s *strictChecker) expr(expr ast.Expr) {
+	if expr == nil {
+		return
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.Ident:
+			if v := s.lookup(node.Name); v != nil {
+				v.used = true
+			}
+		case *ast.SelectorExpr:
+			// The selected name is a field, method or module member
+			s.expr(node.X)
+			return false
+		case *ast.FuncLit:
+			s.push()
+			s.declareFields(node.Type.Params)
+			s.declareFields(node.Type.Results)
+			s.stmts(node.Body.List)
+			s.pop()
+			return false
+		}
+		return true
+	})
+}
+
+// isTerminating reports whether control never continues after stmt: a
+// return, goto, break, continue or panic call, a block ending with one, or
+// an if statement whose branches all terminate
+func isTerminating(stmt ast.Stmt) bool {
+	switch st := stmt.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.BranchStmt:
+		return st.Tok != token.FALLTHROUGH
+	case *ast.ExprStmt:
+		call, ok := st.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		fun, ok := call.Fun.(*ast.Ident)
+		return ok && fun.Name == "panic"
+	case *ast.BlockStmt:
+		return len(st.List) > 0 && isTerminating(st.List[len(st.List)-1])
+	case *ast.IfStmt:
+		return st.Else != nil && isTerminating(st.Body) && isTerminating(st.Else)
+	case *ast.LabeledStmt:
+		return isTerminating(st.Stmt)
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package compiler
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+// strictWarnings compiles code in strict mode and returns the warnings as
+// "line: message"
+func strictWarnings(t *testing.T, code string) []string {
+	t.Helper()
+	compiler := NewCompiler(vm.NewVM())
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, "", code, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse code: %v", err)
+	}
+	compiler.SetFileSet(fset)
+	compiler.SetStrict(true)
+	if err := compiler.Compile(astFile); err != nil {
+		t.Fatalf("Failed to compile code: %v", err)
+	}
+
+	var messages []string
+	for _, diag := range compiler.Diagnostics().Warnings() {
+		messages = append(messages, fmt.Sprintf("%d: %s", diag.Pos.Line, diag.Message))
+	}
+	return messages
+}
+
+func TestStrictDiagnostics(t *testing.T) {
+	// The body of main starts at line 4
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{"all used", "x := 1\n\ty := x + 1\n\treturn y", nil},
+		{"unused", "x := 1\n\ty := 2\n\treturn y", []string{"4: declared and not used: x"}},
+		{"assignment is not a use", "x := 1\n\tx = 2\n\tx++\n\treturn 0", []string{"4: declared and not used: x"}},
+		{"index target is a use", "s := []int{1}\n\ts[0] = 2\n\treturn 0", nil},
+		{"var declaration", "var x int\n\treturn 0", []string{"4: declared and not used: x"}},
+		{"unused constant", "const c = 1\n\treturn 0", nil},
+		{"blank", "_ = 1\n\treturn 0", nil},
+		{"redeclaration", "a, err := 1, 2\n\tb, err := 3, 4\n\treturn a + b + err", nil},
+		{"range", "total := 0\n\tfor i, v := range []int{1, 2} {\n\t\ttotal += v\n\t}\n\treturn total", []string{"5: declared and not used: i"}},
+		{"used in closure", "x := 1\n\tf := func() int {\n\t\treturn x\n\t}\n\treturn f()", nil},
+		{"unused in closure", "f := func(a int) int {\n\t\tb := a\n\t\treturn a\n\t}\n\treturn f(1)", []string{"5: declared and not used: b"}},
+		{"type switch binding", "var v interface{} = 1\n\tswitch x := v.(type) {\n\tcase int:\n\t\treturn x\n\t}\n\treturn 0", nil},
+		{"unused type switch binding", "var v interface{} = 1\n\tswitch x := v.(type) {\n\tcase int:\n\t\treturn 1\n\t}\n\treturn 0", []string{"5: declared and not used: x"}},
+		{"unreachable after return", "return 1\n\tx := 2\n\treturn x", []string{"5: unreachable code"}},
+		{"unreachable after panic", "panic(\"no\")\n\treturn 1", []string{"5: unreachable code"}},
+		{"unreachable in loop", "for i := 0; i < 3; i++ {\n\t\tcontinue\n\t\treturn i\n\t}\n\treturn 0", []string{"6: unreachable code"}},
+		{"unreachable after if else", "x := 1\n\tif x > 0 {\n\t\treturn 1\n\t} else {\n\t\treturn 2\n\t}\n\treturn 3", []string{"10: unreachable code"}},
+		{"labeled statement is reachable", "goto end\nend:\n\treturn 0", nil},
+		{"shadowing", "x := 1\n\tif x > 0 {\n\t\tx := 2\n\t\treturn x\n\t}\n\treturn x", []string{"6: declaration of \"x\" shadows declaration at line 4"}},
+		{"shadowing in if init", "x := 1\n\tif x := 2; x > 1 {\n\t\treturn x\n\t}\n\treturn x", []string{"5: declaration of \"x\" shadows declaration at line 4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "package main\n\nfunc main() {\n\t" + tt.body + "\n}\n"
+			warnings := strictWarnings(t, code)
+			if !reflect.DeepEqual(warnings, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, warnings)
+			}
+		})
+	}
+}
+
+func TestStrictParameters(t *testing.T) {
+	code := `package main
+
+func add(a int, b int) (sum int) {
+	return 1
+}
+
+func main() {
+	a := 1
+	f := func(a int) int {
+		return 2
+	}
+	return f(add(a, 2))
+}
+`
+	expected := []string{`9: declaration of "a" shadows declaration at line 8`}
+	if warnings := strictWarnings(t, code); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected %q, got %q", expected, warnings)
+	}
+}
+
+func TestStrictDisabled(t *testing.T) {
+	code := "package main\n\nfunc main() {\n\tx := 1\n\treturn 0\n\treturn x\n}\n"
+	if warnings := lintWarnings(t, code); len(warnings) != 0 {
+		t.Errorf("Expected no warnings without strict mode, got %v", warnings)
+	}
+}
@@ -0,0 +1,1046 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	gotypes "go/types"
+	"sort"
+)
+
+// typeCheck runs after the function bodies are compiled and reports, as
+// errors with source positions, problems that would otherwise only stop
+// the script at runtime:
+//
+//   - identifiers that are not declared in any enclosing scope
+//   - calls to script functions and methods with the wrong number of
+//     arguments
+//   - operators applied to operands of incompatible types, such as "a" - 1
+//
+// Types are only tracked for operands whose type is evident from the
+// source (literals, constants, declared variables and parameters, results
+// of conversions and script functions); operations on other operands are
+// left to the VM. Ints and floats mix freely, as they do at runtime, and
+// string/number mismatches are allowed when the VM coerces them.
+func (c *Compiler) typeCheck(file *ast.File) {
+	tc := &typeChecker{
+		c:       c,
+		funcs:   make(map[string]*ast.FuncType),
+		methods: make(map[string]map[string]*ast.FuncType),
+		pointer: make(map[string]bool),
+		types:   make(map[string]ast.Expr),
+		vars:    make(map[string]string),
+	}
+	tc.collect(file)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			tc.checkFunc(fn.Recv, fn.Type, fn.Body)
+		}
+	}
+}
+
+// typeChecker holds the declarations visible while checking a file
+type typeChecker struct {
+	c *Compiler
+
+	// Package-level functions, methods by receiver type, methods with
+	// pointer receivers ("T.Method"), declared types (name -> type
+	// expression) and variables (name -> type name)
+	funcs   map[string]*ast.FuncType
+	methods map[string]map[string]*ast.FuncType
+	pointer map[string]bool
+	types   map[string]ast.Expr
+	vars    map[string]string
+
+	// Names declared in each open scope with their type name ("" when
+	// unknown), innermost last
+	scopes []map[string]string
+}
+
+// Kinds of types the checker tells apart for operators
+type typeKind int
+
+const (
+	kindUnknown typeKind = iota
+	kindNumber
+	kindString
+	kindBool
+)
+
+// Type names the checker gives to untyped constants and to identifiers
+// that are not values
+const (
+	untypedInt    = "untyped int"
+	untypedFloat  = "untyped float"
+	untypedRune   = "untyped rune"
+	untypedString = "untyped string"
+	untypedBool   = "untyped bool"
+	typeFunc      = "func"
+	typeName      = "type"
+)
+
+// collect records the package-level declarations
+func (tc *typeChecker) collect(file *ast.File) {
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				// init functions cannot be referred to
+				if d.Name.Name != "init" {
+					tc.funcs[d.Name.Name] = d.Type
+				}
+				continue
+			}
+			if len(d.Recv.List) == 0 {
+				continue
+			}
+			recv := tc.c.getTypeName(d.Recv.List[0].Type)
+			if tc.methods[recv] == nil {
+				tc.methods[recv] = make(map[string]*ast.FuncType)
+			}
+			tc.methods[recv][d.Name.Name] = d.Type
+			if _, isPointer := d.Recv.List[0].Type.(*ast.StarExpr); isPointer {
+				tc.pointer[recv+"."+d.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					tc.types[s.Name.Name] = s.Type
+				case *ast.ValueSpec:
+					if d.Tok != token.VAR {
+						continue
+					}
+					for _, name := range s.Names {
+						tc.vars[name.Name] = ""
+					}
+				}
+			}
+		}
+	}
+
+	// Variables may be initialized from variables declared after them
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.VAR {
+			for _, spec := range d.Specs {
+				s := spec.(*ast.ValueSpec)
+				for i, name := range s.Names {
+					tc.vars[name.Name] = tc.specType(s, i)
+				}
+				if s.Type != nil {
+					for _, value := range s.Values {
+						tc.checkImplements(value, tc.typeOf(value), tc.c.getTypeName(s.Type), "variable declaration")
+					}
+				}
+			}
+		}
+	}
+}
+
+// specType returns the type name of the i-th name of a var declaration
+func (tc *typeChecker) specType(spec *ast.ValueSpec, i int) string {
+	if spec.Type != nil {
+		return tc.c.getTypeName(spec.Type)
+	}
+	if len(spec.Values) == len(spec.Names) {
+		return defaultType(tc.typeOf(spec.Values[i]))
+	}
+	return ""
+}
+
+// checkFunc checks the body of a function, method or function literal
+func (tc *typeChecker) checkFunc(recv *ast.FieldList, typ *ast.FuncType, body *ast.BlockStmt) {
+	tc.push()
+	defer tc.pop()
+	for _, fields := range []*ast.FieldList{recv, typ.Params, typ.Results} {
+		if fields == nil {
+			continue
+		}
+		for _, field := range fields.List {
+			// Untyped parameters, func add(a, b), are parsed as types
+			if ident, ok := field.Type.(*ast.Ident); ok && len(field.Names) == 0 && fields == typ.Params {
+				tc.declare(ident.Name, "")
+				continue
+			}
+			fieldType := tc.c.getTypeName(field.Type)
+			if _, variadic := field.Type.(*ast.Ellipsis); variadic {
+				fieldType = ""
+			}
+			for _, name := range field.Names {
+				tc.declare(name.Name, fieldType)
+			}
+		}
+	}
+	tc.checkStmts(body.List)
+}
+
+// push opens a scope
+func (tc *typeChecker) push() {
+	tc.scopes = append(tc.scopes, make(map[string]string))
+}
+
+// pop closes the innermost scope
+func (tc *typeChecker) pop() {
+	tc.scopes = tc.scopes[:len(tc.scopes)-1]
+}
+
+// declare records a name in the innermost scope
+func (tc *typeChecker) declare(name, typ string) {
+	if name != "_" {
+		tc.scopes[len(tc.scopes)-1][name] = typ
+	}
+}
+
+// lookup resolves a name to its type name. found is false for names that
+// are declared nowhere: not in the script, the universe or the VM.
+func (tc *typeChecker) lookup(name string) (typ string, found bool) {
+	for i := len(tc.scopes) - 1; i >= 0; i-- {
+		if typ, exists := tc.scopes[i][name]; exists {
+			return typ, true
+		}
+	}
+	if typ, exists := tc.vars[name]; exists {
+		return typ, true
+	}
+	if value, exists := tc.c.constants[name]; exists {
+		return tc.constType(name, value), true
+	}
+	if _, exists := tc.funcs[name]; exists {
+		return typeFunc, true
+	}
+	if _, exists := tc.types[name]; exists || isBasicType(name) {
+		return typeName, true
+	}
+	if value, exists := predeclared[name]; exists {
+		if value == nil {
+			return "", true
+		}
+		return untypedBool, true
+	}
+	if _, exists := tc.c.importedModules[name]; exists {
+		return "", true
+	}
+	if _, exists := universeArity[name]; exists || name == "make" || name == "new" || name == "iota" {
+		return "", true
+	}
+	if tc.c.vm.HasFunction(name) || tc.c.vm.HasModule(name) {
+		return "", true
+	}
+	if _, exists := tc.c.vm.GlobalCtx.GetVariable(name); exists {
+		return "", true
+	}
+	return "", false
+}
+
+// constType returns the type name of a package-level constant
+func (tc *typeChecker) constType(name string, value constant.Value) string {
+	for _, decl := range tc.c.constList {
+		if decl.Name == name && decl.Type != "" {
+			return decl.Type
+		}
+	}
+	switch value.Kind() {
+	case constant.Int:
+		return untypedInt
+	case constant.Float:
+		return untypedFloat
+	case constant.String:
+		return untypedString
+	case constant.Bool:
+		return untypedBool
+	}
+	return ""
+}
+
+// skip reports whether a node was compiled by an interceptor, which may
+// give it a meaning of its own
+func (tc *typeChecker) skip(node ast.Node) bool {
+	return tc.c.intercepted[node]
+}
+
+// checkStmts checks a list of statements in the current scope
+func (tc *typeChecker) checkStmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		tc.checkStmt(stmt)
+	}
+}
+
+// checkBlock checks a list of statements in a scope of their own
+func (tc *typeChecker) checkBlock(stmts []ast.Stmt) {
+	tc.push()
+	defer tc.pop()
+	tc.checkStmts(stmts)
+}
+
+// checkStmt checks a statement
+func (tc *typeChecker) checkStmt(stmt ast.Stmt) {
+	if stmt == nil {
+		return
+	}
+	if tc.skip(stmt) {
+		// The names a skipped statement defines stay visible
+		if assign, ok := stmt.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+			for _, lhs := range assign.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					tc.declare(ident.Name, "")
+				}
+			}
+		}
+		if decl, ok := stmt.(*ast.DeclStmt); ok {
+			tc.declareNames(decl.Decl)
+		}
+		return
+	}
+	switch s := stmt.(type) {
+	case *ast.ExprStmt:
+		tc.typeOf(s.X)
+	case *ast.AssignStmt:
+		tc.checkAssign(s)
+	case *ast.IncDecStmt:
+		if typ := tc.typeOf(s.X); kindOf(tc.underlying(typ)) != kindUnknown && kindOf(tc.underlying(typ)) != kindNumber {
+			tc.errorf(s.Pos(), "invalid operation: %s%s (non-numeric type %s)", gotypes.ExprString(s.X), s.Tok, typ)
+		}
+	case *ast.DeclStmt:
+		tc.checkDecl(s.Decl)
+	case *ast.ReturnStmt:
+		for _, result := range s.Results {
+			tc.typeOf(result)
+		}
+	case *ast.BlockStmt:
+		tc.checkBlock(s.List)
+	case *ast.IfStmt:
+		tc.push()
+		tc.checkStmt(s.Init)
+		tc.typeOf(s.Cond)
+		tc.checkBlock(s.Body.List)
+		tc.checkStmt(s.Else)
+		tc.pop()
+	case *ast.ForStmt:
+		tc.push()
+		tc.checkStmt(s.Init)
+		if s.Cond != nil {
+			tc.typeOf(s.Cond)
+		}
+		tc.checkStmt(s.Post)
+		tc.checkBlock(s.Body.List)
+		tc.pop()
+	case *ast.RangeStmt:
+		tc.checkRange(s)
+	case *ast.SwitchStmt:
+		tc.push()
+		tc.checkStmt(s.Init)
+		if s.Tag != nil {
+			tc.typeOf(s.Tag)
+		}
+		for _, clause := range s.Body.List {
+			caseClause := clause.(*ast.CaseClause)
+			for _, expr := range caseClause.List {
+				tc.typeOf(expr)
+			}
+			tc.checkBlock(caseClause.Body)
+		}
+		tc.pop()
+	case *ast.TypeSwitchStmt:
+		tc.checkTypeSwitch(s)
+	case *ast.SelectStmt:
+		for _, clause := range s.Body.List {
+			tc.checkCommClause(clause.(*ast.CommClause))
+		}
+	case *ast.GoStmt:
+		tc.typeOf(s.Call)
+	case *ast.DeferStmt:
+		tc.typeOf(s.Call)
+	case *ast.SendStmt:
+		tc.typeOf(s.Chan)
+		tc.typeOf(s.Value)
+	case *ast.LabeledStmt:
+		tc.checkStmt(s.Stmt)
+	}
+}
+
+// checkTypeSwitch checks a type switch. In each clause the bound variable
+// has the clause's type when it lists one type, and is untracked otherwise.
+func (tc *typeChecker) checkTypeSwitch(s *ast.TypeSwitchStmt) {
+	tc.push()
+	defer tc.pop()
+	tc.checkStmt(s.Init)
+	var bound string
+	switch guard := s.Assign.(type) {
+	case *ast.AssignStmt:
+		bound = guard.Lhs[0].(*ast.Ident).Name
+		tc.typeOf(guard.Rhs[0])
+	case *ast.ExprStmt:
+		tc.typeOf(guard.X)
+	}
+	for _, clause := range s.Body.List {
+		caseClause := clause.(*ast.CaseClause)
+		tc.push()
+		if bound != "" {
+			typ := ""
+			if len(caseClause.List) == 1 {
+				if ident, ok := caseClause.List[0].(*ast.Ident); ok && ident.Name != "nil" {
+					typ = ident.Name
+				}
+			}
+			tc.declare(bound, typ)
+		}
+		tc.checkStmts(caseClause.Body)
+		tc.pop()
+	}
+}
+
+// checkAssign checks an assignment and declares the new variables of :=
+func (tc *typeChecker) checkAssign(s *ast.AssignStmt) {
+	rhsTypes := make([]string, len(s.Rhs))
+	for i, rhs := range s.Rhs {
+		rhsTypes[i] = tc.typeOf(rhs)
+	}
+	rhsType := func(i int) string {
+		if len(s.Rhs) == len(s.Lhs) {
+			return rhsTypes[i]
+		}
+		return ""
+	}
+
+	if s.Tok == token.DEFINE {
+		for i, lhs := range s.Lhs {
+			ident, ok := lhs.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			if _, exists := tc.scopes[len(tc.scopes)-1][ident.Name]; !exists {
+				tc.declare(ident.Name, defaultType(rhsType(i)))
+			}
+		}
+		return
+	}
+
+	for i, lhs := range s.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name == "_" {
+			continue
+		}
+		lhsType := tc.typeOf(lhs)
+		if s.Tok != token.ASSIGN {
+			// x op= y is checked as x op y
+			op := compoundOps[s.Tok]
+			tc.checkBinary(&ast.BinaryExpr{X: lhs, OpPos: s.TokPos, Op: op, Y: s.Rhs[0]}, lhsType, rhsTypes[0])
+			continue
+		}
+		tc.checkImplements(s.Rhs[min(i, len(s.Rhs)-1)], rhsType(i), lhsType, "assignment")
+		// A variable assigned a value of another kind is no longer tracked
+		if ident, ok := lhs.(*ast.Ident); ok && kindOf(tc.underlying(lhsType)) != kindOf(tc.underlying(rhsType(i))) {
+			tc.retype(ident.Name, "")
+		}
+	}
+}
+
+// compoundOps maps assignment operators to their binary operators
+var compoundOps = map[token.Token]token.Token{
+	token.ADD_ASSIGN: token.ADD,
+	token.SUB_ASSIGN: token.SUB,
+	token.MUL_ASSIGN: token.MUL,
+	token.QUO_ASSIGN: token.QUO,
+	token.REM_ASSIGN: token.REM,
+}
+
+// retype changes the type of the innermost variable with the given name
+func (tc *typeChecker) retype(name, typ string) {
+	for i := len(tc.scopes) - 1; i >= 0; i-- {
+		if _, exists := tc.scopes[i][name]; exists {
+			tc.scopes[i][name] = typ
+			return
+		}
+	}
+}
+
+// checkDecl checks a local declaration and declares its names
+func (tc *typeChecker) checkDecl(decl ast.Decl) {
+	genDecl, ok := decl.(*ast.GenDecl)
+	if !ok {
+		return
+	}
+	var values []ast.Expr
+	var typ ast.Expr
+	for _, spec := range genDecl.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			tc.declare(s.Name.Name, typeName)
+		case *ast.ValueSpec:
+			if genDecl.Tok == token.VAR {
+				for _, value := range s.Values {
+					valueType := tc.typeOf(value)
+					if s.Type != nil {
+						tc.checkImplements(value, valueType, tc.c.getTypeName(s.Type), "variable declaration")
+					}
+				}
+				for i, name := range s.Names {
+					tc.declare(name.Name, tc.specType(s, i))
+				}
+				continue
+			}
+			// Constants without values repeat the previous ones
+			if len(s.Values) > 0 {
+				values, typ = s.Values, s.Type
+			}
+			for i, name := range s.Names {
+				constType := ""
+				if typ != nil {
+					constType = tc.c.getTypeName(typ)
+				} else if i < len(values) {
+					constType = tc.typeOf(values[i])
+				}
+				tc.declare(name.Name, constType)
+			}
+		}
+	}
+}
+
+// declareNames declares the names of a declaration with unknown types
+func (tc *typeChecker) declareNames(decl ast.Decl) {
+	genDecl, ok := decl.(*ast.GenDecl)
+	if !ok {
+		return
+	}
+	for _, spec := range genDecl.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			tc.declare(s.Name.Name, typeName)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				tc.declare(name.Name, "")
+			}
+		}
+	}
+}
+
+// checkRange checks a range statement
+func (tc *typeChecker) checkRange(s *ast.RangeStmt) {
+	xType := tc.typeOf(s.X)
+	tc.push()
+	defer tc.pop()
+	if s.Tok == token.DEFINE {
+		keyType, valueType := "", ""
+		switch kindOf(tc.underlying(xType)) {
+		case kindString:
+			keyType, valueType = "int", "rune"
+		case kindNumber:
+			keyType = "int"
+		}
+		if ident, ok := s.Key.(*ast.Ident); ok {
+			tc.declare(ident.Name, keyType)
+		}
+		if ident, ok := s.Value.(*ast.Ident); ok {
+			tc.declare(ident.Name, valueType)
+		}
+	} else {
+		if s.Key != nil {
+			tc.typeOf(s.Key)
+		}
+		if s.Value != nil {
+			tc.typeOf(s.Value)
+		}
+	}
+	tc.checkBlock(s.Body.List)
+}
+
+// checkCommClause checks a case of a select statement
+func (tc *typeChecker) checkCommClause(clause *ast.CommClause) {
+	tc.push()
+	defer tc.pop()
+	if assign, ok := clause.Comm.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+		for _, rhs := range assign.Rhs {
+			tc.typeOf(rhs)
+		}
+		for _, lhs := range assign.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok {
+				tc.declare(ident.Name, "")
+			}
+		}
+	} else {
+		tc.checkStmt(clause.Comm)
+	}
+	tc.checkStmts(clause.Body)
+}
+
+// typeOf checks an expression and returns its type name, "" if unknown
+func (tc *typeChecker) typeOf(expr ast.Expr) string {
+	if expr == nil || tc.skip(expr) {
+		return ""
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if e.Name == "_" {
+			return ""
+		}
+		typ, found := tc.lookup(e.Name)
+		if !found {
+			tc.errorf(e.Pos(), "undefined: %s", e.Name)
+		}
+		return typ
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			return untypedInt
+		case token.FLOAT:
+			return untypedFloat
+		case token.CHAR:
+			return untypedRune
+		case token.STRING:
+			return untypedString
+		}
+	case *ast.ParenExpr:
+		return tc.typeOf(e.X)
+	case *ast.CompositeLit:
+		tc.checkCompositeLit(e, e.Type)
+		if ident, ok := e.Type.(*ast.Ident); ok {
+			return ident.Name
+		}
+	case *ast.FuncLit:
+		tc.checkFunc(nil, e.Type, e.Body)
+		return typeFunc
+	case *ast.SelectorExpr:
+		tc.typeOf(e.X)
+	case *ast.IndexExpr:
+		tc.typeOf(e.X)
+		tc.typeOf(e.Index)
+	case *ast.SliceExpr:
+		tc.typeOf(e.X)
+		tc.typeOf(e.Low)
+		tc.typeOf(e.High)
+		tc.typeOf(e.Max)
+	case *ast.StarExpr:
+		// &x has the type of x, so *p has the type of p
+		return tc.typeOf(e.X)
+	case *ast.TypeAssertExpr:
+		tc.typeOf(e.X)
+		if ident, ok := e.Type.(*ast.Ident); ok {
+			return ident.Name
+		}
+	case *ast.KeyValueExpr:
+		tc.typeOf(e.Key)
+		tc.typeOf(e.Value)
+	case *ast.UnaryExpr:
+		return tc.checkUnary(e)
+	case *ast.BinaryExpr:
+		return tc.checkBinary(e, tc.typeOf(e.X), tc.typeOf(e.Y))
+	case *ast.CallExpr:
+		return tc.checkCall(e)
+	}
+	return ""
+}
+
+// checkCompositeLit checks the elements of a composite literal of the
+// given type; keys are only expressions in map literals
+func (tc *typeChecker) checkCompositeLit(lit *ast.CompositeLit, typ ast.Expr) {
+	typ = tc.resolveType(typ)
+	for _, elt := range lit.Elts {
+		value := elt
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			value = kv.Value
+			if mapType, isMap := typ.(*ast.MapType); isMap {
+				tc.checkElement(kv.Key, mapType.Key)
+			}
+		}
+		var elemType ast.Expr
+		switch t := typ.(type) {
+		case *ast.ArrayType:
+			elemType = t.Elt
+		case *ast.MapType:
+			elemType = t.Value
+		}
+		tc.checkElement(value, elemType)
+	}
+}
+
+// checkElement checks an element of a composite literal, whose type may
+// be elided when it is itself a composite literal
+func (tc *typeChecker) checkElement(expr ast.Expr, typ ast.Expr) {
+	if lit, ok := expr.(*ast.CompositeLit); ok && lit.Type == nil {
+		tc.checkCompositeLit(lit, typ)
+		return
+	}
+	tc.typeOf(expr)
+}
+
+// resolveType follows declared type names to their definition
+func (tc *typeChecker) resolveType(typ ast.Expr) ast.Expr {
+	for i := 0; i < 10; i++ {
+		ident, ok := typ.(*ast.Ident)
+		if !ok {
+			return typ
+		}
+		definition, exists := tc.types[ident.Name]
+		if !exists {
+			return typ
+		}
+		typ = definition
+	}
+	return typ
+}
+
+// checkUnary checks a unary expression
+func (tc *typeChecker) checkUnary(e *ast.UnaryExpr) string {
+	typ := tc.typeOf(e.X)
+	kind := kindOf(tc.underlying(typ))
+	switch e.Op {
+	case token.NOT:
+		if kind != kindUnknown && kind != kindBool {
+			tc.errorf(e.Pos(), "invalid operation: operator ! not defined on %s", tc.describe(e.X, typ))
+		}
+		return untypedBool
+	case token.SUB, token.ADD:
+		if kind != kindUnknown && kind != kindNumber {
+			tc.errorf(e.Pos(), "invalid operation: operator %s not defined on %s", e.Op, tc.describe(e.X, typ))
+		}
+		return typ
+	case token.AND:
+		return typ
+	}
+	return ""
+}
+
+// checkBinary checks the operand types of a binary expression and returns
+// the type of its result
+func (tc *typeChecker) checkBinary(e *ast.BinaryExpr, xType, yType string) string {
+	// Operands of && and || are conditions, checked by the VM according to
+	// its condition mode
+	if e.Op == token.LAND || e.Op == token.LOR {
+		return untypedBool
+	}
+	xKind, yKind := kindOf(tc.underlying(xType)), kindOf(tc.underlying(yType))
+	comparison := e.Op == token.EQL || e.Op == token.NEQ || e.Op == token.LSS ||
+		e.Op == token.LEQ || e.Op == token.GTR || e.Op == token.GEQ
+	result := xType
+	if comparison {
+		result = untypedBool
+	}
+	if xKind == kindUnknown || yKind == kindUnknown {
+		if comparison {
+			return untypedBool
+		}
+		return ""
+	}
+	// Coercion converts between strings and numbers at runtime
+	if tc.c.vm.GetCoercion() && (xKind == kindString || yKind == kindString) {
+		return ""
+	}
+
+	if xKind != yKind {
+		tc.errorf(e.OpPos, "invalid operation: %s (mismatched types %s and %s)", gotypes.ExprString(e), xType, yType)
+		return ""
+	}
+	if !operatorDefined(e.Op, xKind) {
+		tc.errorf(e.OpPos, "invalid operation: operator %s not defined on %s", e.Op, tc.describe(e.X, xType))
+		return ""
+	}
+	// The result of mixing an untyped constant with a typed operand has
+	// the operand's type
+	if !comparison && isUntyped(xType) && !isUntyped(yType) {
+		result = yType
+	}
+	return result
+}
+
+// operatorDefined reports whether a binary operator applies to operands of
+// the given kind
+func operatorDefined(op token.Token, kind typeKind) bool {
+	switch op {
+	case token.ADD:
+		return kind == kindNumber || kind == kindString
+	case token.SUB, token.MUL, token.QUO, token.REM:
+		return kind == kindNumber
+	case token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return kind == kindNumber || kind == kindString
+	default:
+		return true
+	}
+}
+
+// checkCall checks a call and returns the type of its result when known
+func (tc *typeChecker) checkCall(call *ast.CallExpr) string {
+	args := call.Args
+	result := ""
+	var fnType *ast.FuncType
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		typ, declared := tc.lookup(fun.Name)
+		local := tc.isLocal(fun.Name)
+		switch {
+		case typ == typeName && !local:
+			// A conversion, e.g., float64(x) or Level(2)
+			result = fun.Name
+		case (fun.Name == "make" || fun.Name == "new") && !local:
+			// The first argument is a type
+			if len(args) > 0 {
+				args = args[1:]
+			}
+		case (fun.Name == "len" || fun.Name == "cap" || fun.Name == "copy") && !local:
+			result = "int"
+		case typ == typeFunc && !local:
+			fnType = tc.funcs[fun.Name]
+			tc.checkArity(call, fun.Name, fnType)
+			result = singleResult(tc.c, fnType)
+		case local:
+			tc.typeOf(fun)
+		case !declared && fun.Name == "init":
+			tc.errorf(fun.Pos(), "undefined: init")
+		case !declared:
+			// Host functions may be registered after compiling, so unknown
+			// functions are left to the VM
+		}
+	case *ast.SelectorExpr:
+		recvType := tc.typeOf(fun.X)
+		if methods, exists := tc.methods[recvType]; exists {
+			if method, isMethod := methods[fun.Sel.Name]; isMethod {
+				fnType = method
+				tc.checkArity(call, gotypes.ExprString(fun), fnType)
+				result = singleResult(tc.c, fnType)
+			}
+		}
+		if iface := tc.interfaceType(recvType); iface != nil && !contains(tc.c.interfaceMethods(iface, make(map[string]bool)), fun.Sel.Name) {
+			tc.errorf(fun.Sel.Pos(), "%s undefined (type %s has no field or method %s)", gotypes.ExprString(fun), recvType, fun.Sel.Name)
+		}
+	default:
+		tc.typeOf(call.Fun)
+	}
+	for i, arg := range args {
+		argType := tc.typeOf(arg)
+		if fnType != nil && !call.Ellipsis.IsValid() {
+			if paramType := paramTypeAt(fnType, i); paramType != nil {
+				tc.checkImplements(arg, argType, tc.c.getTypeName(paramType), "argument to "+gotypes.ExprString(call.Fun))
+			}
+		}
+	}
+	return result
+}
+
+// paramTypeAt returns the type of the parameter that takes the i-th
+// argument of a call, nil for variadic parameters
+func paramTypeAt(fnType *ast.FuncType, i int) ast.Expr {
+	if fnType.Params == nil {
+		return nil
+	}
+	n := 0
+	for _, field := range fnType.Params.List {
+		names := len(field.Names)
+		if names == 0 {
+			names = 1
+		}
+		if i < n+names {
+			if _, variadic := field.Type.(*ast.Ellipsis); variadic || len(field.Names) == 0 {
+				return nil
+			}
+			return field.Type
+		}
+		n += names
+	}
+	return nil
+}
+
+// interfaceType returns the interface a declared type name denotes, or nil
+func (tc *typeChecker) interfaceType(typ string) *ast.InterfaceType {
+	if typ == "" {
+		return nil
+	}
+	iface, _ := tc.resolveType(&ast.Ident{Name: typ}).(*ast.InterfaceType)
+	return iface
+}
+
+// checkImplements reports a value of a script struct type used as a value
+// of an interface type it does not implement, e.g., assigned to a variable
+// or passed as an argument
+func (tc *typeChecker) checkImplements(value ast.Expr, valueType, targetType, context string) {
+	iface := tc.interfaceType(targetType)
+	if iface == nil {
+		return
+	}
+	if _, isStruct := tc.resolveType(&ast.Ident{Name: valueType}).(*ast.StructType); !isStruct || valueType == "" {
+		return
+	}
+	methods := tc.c.interfaceMethods(iface, make(map[string]bool))
+	sort.Strings(methods)
+	// The checker does not tell struct pointers from structs, except for
+	// literals, which are values
+	_, isLiteral := ast.Unparen(value).(*ast.CompositeLit)
+	for _, method := range methods {
+		if isLiteral && tc.pointer[valueType+"."+method] {
+			tc.errorf(value.Pos(), "cannot use %s (value of type %s) as %s value in %s: %s does not implement %s (method %s has pointer receiver)",
+				gotypes.ExprString(value), valueType, targetType, context, valueType, targetType, method)
+			return
+		}
+		if tc.hasMethod(valueType, method, make(map[string]bool)) {
+			continue
+		}
+		tc.errorf(value.Pos(), "cannot use %s (value of type %s) as %s value in %s: %s does not implement %s (missing method %s)",
+			gotypes.ExprString(value), valueType, targetType, context, valueType, targetType, method)
+		return
+	}
+}
+
+// hasMethod reports whether a script struct type has a method, declared
+// for it, implemented by a host function or promoted from one of its
+// embedded structs
+func (tc *typeChecker) hasMethod(typeName, method string, seen map[string]bool) bool {
+	if _, declared := tc.methods[typeName][method]; declared || tc.c.vm.HasFunction(typeName+"."+method) {
+		return true
+	}
+	seen[typeName] = true
+	structType, ok := tc.resolveType(&ast.Ident{Name: typeName}).(*ast.StructType)
+	if !ok {
+		return false
+	}
+	for _, field := range structType.Fields.List {
+		embedded := tc.c.getTypeName(field.Type)
+		if len(field.Names) == 0 && !seen[embedded] && tc.hasMethod(embedded, method, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether a list of names contains a name
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocal reports whether name is declared in a function scope
+func (tc *typeChecker) isLocal(name string) bool {
+	for i := len(tc.scopes) - 1; i >= 0; i-- {
+		if _, exists := tc.scopes[i][name]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// checkArity reports calls with a number of arguments that does not match
+// the parameters of a script function or method
+func (tc *typeChecker) checkArity(call *ast.CallExpr, name string, fnType *ast.FuncType) {
+	if call.Ellipsis.IsValid() {
+		return
+	}
+	// A single call argument may provide several values, e.g., f(g())
+	if len(call.Args) == 1 {
+		if _, isCall := call.Args[0].(*ast.CallExpr); isCall {
+			return
+		}
+	}
+	want := fnType.Params.NumFields()
+	variadic := false
+	if params := fnType.Params; params != nil && len(params.List) > 0 {
+		_, variadic = params.List[len(params.List)-1].Type.(*ast.Ellipsis)
+	}
+	have := len(call.Args)
+	switch {
+	case have < want && !(variadic && have == want-1):
+		tc.errorf(call.Rparen, "not enough arguments in call to %s: have %d, want %d", name, have, want)
+	case have > want && !variadic:
+		tc.errorf(call.Args[want].Pos(), "too many arguments in call to %s: have %d, want %d", name, have, want)
+	}
+}
+
+// singleResult returns the type name of the only result of a function
+func singleResult(c *Compiler, fnType *ast.FuncType) string {
+	if fnType.Results.NumFields() != 1 {
+		return ""
+	}
+	return c.getTypeName(fnType.Results.List[0].Type)
+}
+
+// underlying returns the basic type name behind a declared type name, for
+// example int for type Level int
+func (tc *typeChecker) underlying(typ string) string {
+	for i := 0; i < 10; i++ {
+		definition, exists := tc.types[typ]
+		if !exists {
+			return typ
+		}
+		ident, ok := definition.(*ast.Ident)
+		if !ok {
+			return ""
+		}
+		typ = ident.Name
+	}
+	return typ
+}
+
+// describe formats an operand for error messages
+func (tc *typeChecker) describe(expr ast.Expr, typ string) string {
+	text := gotypes.ExprString(expr)
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return text + " (" + typ + " constant)"
+	case *ast.Ident:
+		if _, isConst := tc.c.constants[e.Name]; isConst && !tc.isLocal(e.Name) {
+			return text + " (constant of type " + typ + ")"
+		}
+		return text + " (variable of type " + typ + ")"
+	}
+	return text + " (value of type " + typ + ")"
+}
+
+// errorf reports a type error
+func (tc *typeChecker) errorf(pos token.Pos, format string, args ...interface{}) {
+	tc.c.report(pos, SeverityError, format, args...)
+}
+
+// kindOf returns the kind of a basic type name
+func kindOf(typ string) typeKind {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", untypedInt, untypedFloat:
+		return kindNumber
+	case "string", untypedString:
+		return kindString
+	case "bool", untypedBool:
+		return kindBool
+	default:
+		// Runes and bytes also combine with strings at runtime
+		return kindUnknown
+	}
+}
+
+// isBasicType reports whether name is a predeclared type
+func isBasicType(name string) bool {
+	switch name {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+		"uintptr", "float32", "float64", "complex64", "complex128", "string", "bool", "byte",
+		"rune", "error", "any":
+		return true
+	}
+	return false
+}
+
+// isUntyped reports whether a type name is that of an untyped constant
+func isUntyped(typ string) bool {
+	switch typ {
+	case untypedInt, untypedFloat, untypedRune, untypedString, untypedBool:
+		return true
+	}
+	return false
+}
+
+// defaultType returns the type a variable declared with := takes from a
+// value of the given type
+func defaultType(typ string) string {
+	switch typ {
+	case untypedInt:
+		return "int"
+	case untypedFloat:
+		return "float64"
+	case untypedRune:
+		return "rune"
+	case untypedString:
+		return "string"
+	case untypedBool:
+		return "bool"
+	case typeFunc, typeName:
+		return ""
+	}
+	return typ
+}
@@ -0,0 +1,128 @@
+package compiler
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+func TestTypeCheckErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{"undefined variable", `
+func main() {
+	x := 1
+	return x + y
+}`, "script.gs:5:13: error: undefined: y"},
+		{"variable out of scope", `
+func main() {
+	if v := 1; v > 0 {
+		w := v
+	}
+	return w
+}`, "script.gs:7:9: error: undefined: w"},
+		{"string minus number", `
+func main() {
+	return "a" - 1
+}`, `script.gs:4:13: error: invalid operation: "a" - 1 (mismatched types untyped string and untyped int)`},
+		{"typed operands", `
+func main() {
+	var s string = "a"
+	n := 2
+	return s + n
+}`, "script.gs:6:11: error: invalid operation: s + n (mismatched types string and int)"},
+		{"operator not defined", `
+func main() {
+	s := "a"
+	return s * s
+}`, "script.gs:5:11: error: invalid operation: operator * not defined on s (variable of type string)"},
+		{"not enough arguments", `
+func add(a int, b int) int {
+	return a + b
+}
+
+func main() {
+	return add(1)
+}`, "script.gs:8:14: error: not enough arguments in call to add: have 1, want 2"},
+		{"too many arguments", `
+type Point struct {
+	X int
+}
+
+func (p *Point) Move(dx int) {
+	p.X = p.X + dx
+}
+
+func main() {
+	p := &Point{X: 1}
+	p.Move(1, 2)
+	return p.X
+}`, "script.gs:13:12: error: too many arguments in call to p.Move: have 2, want 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compileSource(t, "package main\n"+tt.code)
+			var diags CompileDiagnostics
+			if !errors.As(err, &diags) {
+				t.Fatalf("Expected CompileDiagnostics, got %v", err)
+			}
+			if !strings.Contains(diags.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, diags.Error())
+			}
+		})
+	}
+}
+
+func TestTypeCheckAcceptsValidCode(t *testing.T) {
+	code := `package main
+
+const Limit = 10
+
+type Level int
+
+func sum(values ...int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func main() {
+	var level Level = 2
+	f := 1.5
+	n := 3
+	s := "n=" + "3"
+	if len(s) > 0 && n < Limit {
+		n = n * int(level)
+	}
+	double := func(x int) int {
+		return x * 2
+	}
+	return sum(n, double(2)) + f + println(s)
+}
+`
+	if err := compileSource(t, code); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+}
+
+// compileSource parses and compiles code as script.gs
+func compileSource(t *testing.T, code string) error {
+	t.Helper()
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, "script.gs", code, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse code: %v", err)
+	}
+	compiler := NewCompiler(vm.NewVM())
+	compiler.SetFileSet(fset)
+	return compiler.Compile(astFile)
+}
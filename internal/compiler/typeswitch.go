@@ -0,0 +1,188 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	gotypes "go/types"
+	"sort"
+	"strings"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Type assertions and type switches. Types are passed to TYPE_ASSERT as
+// strings written as in Go, with declared names resolved: struct types
+// keep their name, other named types become their underlying type (the
+// VM does not tag values with named basic types) and interfaces become
+// the sorted list of their method names, "interface{Area,Perimeter}".
+
+// typeDescriptor returns the type string TYPE_ASSERT checks values against
+func (c *Compiler) typeDescriptor(expr ast.Expr) string {
+	return c.describeType(expr, make(map[string]bool))
+}
+
+// describeType builds a type descriptor; seen guards against recursive
+// type declarations
+func (c *Compiler) describeType(expr ast.Expr, seen map[string]bool) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if t.Name == "any" {
+			return "interface{}"
+		}
+		decl, declared := c.typeDecls[t.Name]
+		if !declared || seen[t.Name] {
+			return t.Name
+		}
+		if _, isStruct := decl.(*ast.StructType); isStruct {
+			return t.Name
+		}
+		seen[t.Name] = true
+		return c.describeType(decl, seen)
+	case *ast.ParenExpr:
+		return c.describeType(t.X, seen)
+	case *ast.StarExpr:
+		return "*" + c.describeType(t.X, seen)
+	case *ast.ArrayType:
+		return "[]" + c.describeType(t.Elt, seen)
+	case *ast.MapType:
+		return "map[" + c.describeType(t.Key, seen) + "]" + c.describeType(t.Value, seen)
+	case *ast.ChanType:
+		return "chan " + c.describeType(t.Value, seen)
+	case *ast.InterfaceType:
+		methods := c.interfaceMethods(t, seen)
+		sort.Strings(methods)
+		return "interface{" + strings.Join(methods, ",") + "}"
+	default:
+		return gotypes.ExprString(expr)
+	}
+}
+
+// interfaceMethods returns the method names of an interface, including
+// those of embedded interfaces
+func (c *Compiler) interfaceMethods(iface *ast.InterfaceType, seen map[string]bool) []string {
+	var methods []string
+	for _, field := range iface.Methods.List {
+		if len(field.Names) > 0 {
+			for _, name := range field.Names {
+				methods = append(methods, name.Name)
+			}
+			continue
+		}
+		ident, ok := field.Type.(*ast.Ident)
+		if !ok || seen[ident.Name] {
+			continue
+		}
+		if embedded, ok := c.typeDecls[ident.Name].(*ast.InterfaceType); ok {
+			seen[ident.Name] = true
+			methods = append(methods, c.interfaceMethods(embedded, seen)...)
+		} else if ident.Name == "error" {
+			methods = append(methods, "Error")
+		}
+	}
+	return methods
+}
+
+// compileTypeAssertExpr compiles x.(T)
+func (c *Compiler) compileTypeAssertExpr(expr *ast.TypeAssertExpr, mode int) error {
+	if expr.Type == nil {
+		return fmt.Errorf("use of .(type) outside type switch")
+	}
+	if err := c.compileExpr(expr.X); err != nil {
+		return err
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpTypeAssert, c.typeDescriptor(expr.Type), mode))
+	return nil
+}
+
+// compileTypeSwitchStmt compiles a type switch. The value is evaluated once
+// into a temporary variable, each case type is tested in order, and each
+// clause runs in a scope of its own where the bound variable, if any,
+// holds the value.
+func (c *Compiler) compileTypeSwitchStmt(stmt *ast.TypeSwitchStmt) error {
+	scopeKey := c.generateKey("type_switch")
+	c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, scopeKey, nil))
+	c.pushScope()
+	defer c.popScope()
+
+	// The switch can be the target of break
+	target := c.pushBranchTarget(false)
+	defer c.popBranchTarget()
+
+	if stmt.Init != nil {
+		if err := c.compileStmt(stmt.Init); err != nil {
+			return err
+		}
+	}
+
+	// switch v := x.(type) or switch x.(type)
+	var bound string
+	var assert *ast.TypeAssertExpr
+	switch s := stmt.Assign.(type) {
+	case *ast.AssignStmt:
+		bound = s.Lhs[0].(*ast.Ident).Name
+		assert, _ = s.Rhs[0].(*ast.TypeAssertExpr)
+	case *ast.ExprStmt:
+		assert, _ = s.X.(*ast.TypeAssertExpr)
+	}
+	if assert == nil {
+		return fmt.Errorf("invalid type switch guard")
+	}
+	if err := c.compileExpr(assert.X); err != nil {
+		return err
+	}
+	valueVar := c.generateKey("type_switch_value")
+	c.emitInstruction(instruction.NewInstruction(instruction.OpCreateVar, valueVar, nil))
+	c.emitInstruction(instruction.NewInstruction(instruction.OpStoreName, valueVar, nil))
+
+	caseLabels := make([]string, len(stmt.Body.List))
+	endLabel := c.generateKey("end_switch")
+	defaultLabel := endLabel
+	for i, clause := range stmt.Body.List {
+		caseClause, ok := clause.(*ast.CaseClause)
+		if !ok {
+			return fmt.Errorf("unexpected clause type in switch: %T", clause)
+		}
+		caseLabels[i] = c.generateKey("case")
+		if len(caseClause.List) == 0 {
+			defaultLabel = caseLabels[i]
+		}
+	}
+
+	// Test the case types in order, jumping to the first clause that matches
+	for i, clause := range stmt.Body.List {
+		for _, typ := range clause.(*ast.CaseClause).List {
+			skipLabel := c.generateKey("skip_goto")
+			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, valueVar, nil))
+			c.emitInstruction(instruction.NewInstruction(instruction.OpTypeAssert, c.typeDescriptor(typ), instruction.AssertTest))
+			c.emitInstruction(instruction.NewInstruction(instruction.OpJumpIf, skipLabel, nil))
+			c.emitInstruction(instruction.NewInstruction(instruction.OpJump, caseLabels[i], nil))
+			c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, skipLabel, nil))
+		}
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpJump, defaultLabel, nil))
+
+	for i, clause := range stmt.Body.List {
+		c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, caseLabels[i], nil))
+		clauseKey := c.generateKey("case_scope")
+		c.emitUntracked(instruction.NewInstruction(instruction.OpEnterScopeWithKey, clauseKey, nil))
+		c.pushScope()
+		if bound != "" && bound != "_" {
+			c.emitInstruction(instruction.NewInstruction(instruction.OpLoadName, valueVar, nil))
+			c.storeTarget(bound, true)
+		}
+		for _, caseStmt := range clause.(*ast.CaseClause).Body {
+			if err := c.compileStmt(caseStmt); err != nil {
+				c.popScope()
+				return err
+			}
+		}
+		c.popScope()
+		c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, clauseKey, nil))
+		c.emitInstruction(instruction.NewInstruction(instruction.OpJump, endLabel, nil))
+	}
+
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, endLabel, nil))
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLabel, target.breakLabel, nil))
+	c.emitUntracked(instruction.NewInstruction(instruction.OpExitScopeWithKey, scopeKey, nil))
+	return nil
+}
@@ -0,0 +1,74 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Builtins of the universe block (len, print, min, ...) are registered on
+// every VM, so calls to them compile to ordinary host calls. The compiler
+// checks their argument count so that mistakes fail at compile time. The
+// predeclared nil, true and false compile to constants.
+
+// universeArity maps the universe builtins to their minimum and maximum
+// number of arguments (-1 when variadic)
+var universeArity = map[string][2]int{
+	"len":     {1, 1},
+	"cap":     {1, 1},
+	"append":  {1, -1},
+	"copy":    {2, 2},
+	"print":   {0, -1},
+	"println": {0, -1},
+	"min":     {1, -1},
+	"max":     {1, -1},
+	"close":   {1, 1},
+}
+
+// checkUniverseCall checks the argument count of a call to a universe
+// builtin, unless the name refers to a script function, variable or
+// constant
+func (c *Compiler) checkUniverseCall(name string, argc int) error {
+	arity, isBuiltin := universeArity[name]
+	if !isBuiltin || c.scriptFunctions[name] || c.shadowed(name) {
+		return nil
+	}
+	if argc < arity[0] {
+		return fmt.Errorf("not enough arguments in call to %s: have %d, want %d", name, argc, arity[0])
+	}
+	if arity[1] >= 0 && argc > arity[1] {
+		return fmt.Errorf("too many arguments in call to %s: have %d, want %d", name, argc, arity[1])
+	}
+	return nil
+}
+
+// shadowed reports whether name is declared as a variable or constant in
+// an enclosing scope or as a package-level constant
+func (c *Compiler) shadowed(name string) bool {
+	for _, scope := range c.scopes {
+		if scope[name] {
+			return true
+		}
+	}
+	_, isConst := c.constants[name]
+	return isConst
+}
+
+// predeclared holds the values of the predeclared identifiers
+var predeclared = map[string]interface{}{
+	"nil":   nil,
+	"true":  true,
+	"false": false,
+}
+
+// compilePredeclared emits the value of nil, true or false unless the name
+// is shadowed
+func (c *Compiler) compilePredeclared(ident *ast.Ident) bool {
+	value, isPredeclared := predeclared[ident.Name]
+	if !isPredeclared || c.shadowed(ident.Name) {
+		return false
+	}
+	c.emitInstruction(instruction.NewInstruction(instruction.OpLoadConst, value, nil))
+	return true
+}
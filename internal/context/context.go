@@ -23,14 +23,13 @@ type Context struct {
 	children map[string]*Context
 }
 
-// NewContext creates a new context with the given path key and parent
+// NewContext creates a new context with the given path key and parent.
+// Its maps are allocated when the first variable or child is added, as
+// most contexts of function calls and blocks hold few of them.
 func NewContext(pathKey string, parent *Context) *Context {
 	return &Context{
-		pathKey:   pathKey,
-		parent:    parent,
-		variables: make(map[string]interface{}),
-		types:     make(map[string]string),
-		children:  make(map[string]*Context),
+		pathKey: pathKey,
+		parent:  parent,
 	}
 }
 
@@ -91,6 +90,10 @@ func (ctx *Context) CreateVariableWithType(name string, value interface{}, varTy
 		// panic(fmt.Sprintf("variable %s already exists", name))
 		return fmt.Errorf("variable %s already exists", name)
 	}
+	if ctx.variables == nil {
+		ctx.variables = make(map[string]interface{})
+		ctx.types = make(map[string]string)
+	}
 	ctx.variables[name] = value
 	ctx.types[name] = varType
 	return nil
@@ -111,6 +114,16 @@ func (ctx *Context) GetVariableType(name string) (string, bool) {
 	return "", false
 }
 
+// Lookup returns the context in the hierarchy that declares a variable
+func (ctx *Context) Lookup(name string) (*Context, bool) {
+	for c := ctx; c != nil; c = c.parent {
+		if _, exists := c.variables[name]; exists {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
 // HasVariable checks if a variable exists in the current context (not in hierarchy)
 func (ctx *Context) HasVariable(name string) bool {
 	_, exists := ctx.variables[name]
@@ -152,6 +165,9 @@ func (ctx *Context) GetAllVariablesWithTypes() (map[string]interface{}, map[stri
 
 // AddChild adds a child context
 func (ctx *Context) AddChild(child *Context) {
+	if ctx.children == nil {
+		ctx.children = make(map[string]*Context)
+	}
 	ctx.children[child.pathKey] = child
 }
 
@@ -0,0 +1,164 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Numeric operations. Script numbers are ints and float64s, and every
+// arithmetic operator and comparison on them goes through numericOp, so the
+// rules are the same wherever numbers meet:
+//
+//   - Two ints give an int (+, -, *, / and %); / truncates towards zero
+//     unless the division mode is PromoteToFloat.
+//   - An int and a float64 give a float64: 1 + 0.5 is 1.5, and 1 == 1.0.
+//   - % is only defined on ints.
+//   - Dividing by zero is an error, for floats as well.
+//
+// Runes, big integers, time values and coerced strings are converted to
+// these operands first (see rune.go, bigint.go, timevalue.go and
+// coercion.go).
+
+// DivisionMode selects the result of dividing two ints
+type DivisionMode int
+
+const (
+	// TruncatingInt divides ints as Go does: 7 / 2 is 3
+	TruncatingInt DivisionMode = iota
+
+	// PromoteToFloat divides ints as float64s: 7 / 2 is 3.5 and 6 / 3 is
+	// the float64 2. Other operators on ints still give ints.
+	PromoteToFloat
+)
+
+// String returns the name of a division mode
+func (m DivisionMode) String() string {
+	switch m {
+	case TruncatingInt:
+		return "truncating-int"
+	case PromoteToFloat:
+		return "promote-to-float"
+	default:
+		return fmt.Sprintf("DivisionMode(%d)", int(m))
+	}
+}
+
+// SetDivisionMode sets the result of dividing two ints. Defaults to
+// TruncatingInt. Constants (const half = 7 / 2) are computed at compile
+// time as in Go.
+func (vm *VM) SetDivisionMode(mode DivisionMode) {
+	vm.divisionMode = mode
+}
+
+// GetDivisionMode returns the current division mode
+func (vm *VM) GetDivisionMode() DivisionMode {
+	return vm.divisionMode
+}
+
+// binaryOpDescriptions name the operations in errors about their operands
+var binaryOpDescriptions = map[instruction.BinaryOp]string{
+	instruction.OpAdd:          "addition",
+	instruction.OpSub:          "subtraction",
+	instruction.OpMul:          "multiplication",
+	instruction.OpDiv:          "division",
+	instruction.OpMod:          "modulo",
+	instruction.OpLess:         "less than comparison",
+	instruction.OpLessEqual:    "less than or equal comparison",
+	instruction.OpGreater:      "greater than comparison",
+	instruction.OpGreaterEqual: "greater than or equal comparison",
+}
+
+// numericOp applies an arithmetic operator or comparison to two numbers.
+// handled is false when an operand is not an int or float64, or the
+// operator is not defined on the operands (% on floats, && and ||).
+func numericOp(op instruction.BinaryOp, left, right interface{}, mode DivisionMode) (result interface{}, handled bool, err error) {
+	l, lIsInt := left.(int)
+	r, rIsInt := right.(int)
+	if lIsInt && rIsInt && (op != instruction.OpDiv || mode == TruncatingInt) {
+		return intOp(op, l, r)
+	}
+
+	lf, lok := floatOperand(left)
+	rf, rok := floatOperand(right)
+	if !lok || !rok {
+		return nil, false, nil
+	}
+	return floatOp(op, lf, rf)
+}
+
+// floatOperand returns an int or float64 operand as a float64
+func floatOperand(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// intOp applies an operator to two ints
+func intOp(op instruction.BinaryOp, l, r int) (interface{}, bool, error) {
+	switch op {
+	case instruction.OpAdd:
+		return l + r, true, nil
+	case instruction.OpSub:
+		return l - r, true, nil
+	case instruction.OpMul:
+		return l * r, true, nil
+	case instruction.OpDiv:
+		if r == 0 {
+			return nil, true, fmt.Errorf("division by zero")
+		}
+		return l / r, true, nil
+	case instruction.OpMod:
+		if r == 0 {
+			return nil, true, fmt.Errorf("modulo by zero")
+		}
+		return l % r, true, nil
+	case instruction.OpEqual:
+		return l == r, true, nil
+	case instruction.OpNotEqual:
+		return l != r, true, nil
+	case instruction.OpLess:
+		return l < r, true, nil
+	case instruction.OpLessEqual:
+		return l <= r, true, nil
+	case instruction.OpGreater:
+		return l > r, true, nil
+	case instruction.OpGreaterEqual:
+		return l >= r, true, nil
+	}
+	return nil, false, nil
+}
+
+// floatOp applies an operator to two float64s
+func floatOp(op instruction.BinaryOp, l, r float64) (interface{}, bool, error) {
+	switch op {
+	case instruction.OpAdd:
+		return l + r, true, nil
+	case instruction.OpSub:
+		return l - r, true, nil
+	case instruction.OpMul:
+		return l * r, true, nil
+	case instruction.OpDiv:
+		if r == 0 {
+			return nil, true, fmt.Errorf("division by zero")
+		}
+		return l / r, true, nil
+	case instruction.OpEqual:
+		return l == r, true, nil
+	case instruction.OpNotEqual:
+		return l != r, true, nil
+	case instruction.OpLess:
+		return l < r, true, nil
+	case instruction.OpLessEqual:
+		return l <= r, true, nil
+	case instruction.OpGreater:
+		return l > r, true, nil
+	case instruction.OpGreaterEqual:
+		return l >= r, true, nil
+	}
+	return nil, false, nil
+}
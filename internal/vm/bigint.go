@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// bigBinaryOp applies a binary operation where at least one operand is a
+// big integer created by the bigint module and the other is an integer:
+// bigint.New(1) + 1 is a big integer and x > 0 compares values. / and %
+// truncate towards zero like the int operators. handled is false when no
+// operand is a big integer or the other operand is not an integer.
+func bigBinaryOp(op instruction.BinaryOp, left, right interface{}) (result interface{}, handled bool, err error) {
+	_, lIsBig := left.(*big.Int)
+	_, rIsBig := right.(*big.Int)
+	if !lIsBig && !rIsBig {
+		return nil, false, nil
+	}
+	l, lok := builtin.BigInt(left)
+	r, rok := builtin.BigInt(right)
+	if !lok || !rok {
+		return nil, false, nil
+	}
+
+	switch op {
+	case instruction.OpAdd:
+		return new(big.Int).Add(l, r), true, nil
+	case instruction.OpSub:
+		return new(big.Int).Sub(l, r), true, nil
+	case instruction.OpMul:
+		return new(big.Int).Mul(l, r), true, nil
+	case instruction.OpDiv, instruction.OpMod:
+		if r.Sign() == 0 {
+			return nil, true, fmt.Errorf("division by zero")
+		}
+		if op == instruction.OpDiv {
+			return new(big.Int).Quo(l, r), true, nil
+		}
+		return new(big.Int).Rem(l, r), true, nil
+	case instruction.OpEqual:
+		return l.Cmp(r) == 0, true, nil
+	case instruction.OpNotEqual:
+		return l.Cmp(r) != 0, true, nil
+	case instruction.OpLess:
+		return l.Cmp(r) < 0, true, nil
+	case instruction.OpLessEqual:
+		return l.Cmp(r) <= 0, true, nil
+	case instruction.OpGreater:
+		return l.Cmp(r) > 0, true, nil
+	case instruction.OpGreaterEqual:
+		return l.Cmp(r) >= 0, true, nil
+	}
+	return nil, true, fmt.Errorf("invalid operation: operator %s not defined on big integers", op)
+}
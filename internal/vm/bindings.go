@@ -0,0 +1,98 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Bound variables. BindVariable makes a Go variable available to scripts
+// as a global variable bound to it: each execution starts with the current
+// value of the Go variable, and the value the script leaves in the variable
+// is stored back into it, converted to its Go type, when the execution
+// ends. Go values are read as script values (see ScriptValue), with Go
+// structs read as script structs of the same type name, so scripts can use
+// their exported fields; structs are converted back by field name. Setting
+// a bound variable with SetVariable sets the Go variable too.
+
+// binding is a global variable bound to a Go variable
+type binding struct {
+	name   string
+	target reflect.Value
+}
+
+// BindVariable binds the global variable name to the Go variable ptr points
+// to. Values the script stores must be convertible to the type of the Go
+// variable, as the arguments of host object methods.
+func (vm *VM) BindVariable(name string, ptr interface{}) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot bind %s: %T is not a non-nil pointer", name, ptr)
+	}
+	target := rv.Elem()
+	if err := vm.GlobalCtx.CreateVariableWithType(name, vm.convertScriptValue(target, true), "bound"); err != nil {
+		return err
+	}
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.bindings = append(vm.bindings, binding{name: name, target: target})
+	return nil
+}
+
+// SetVariable sets a global variable, and the Go variable a bound variable
+// is bound to
+func (vm *VM) SetVariable(name string, value interface{}) error {
+	if err := vm.GlobalCtx.SetVariable(name, value); err != nil {
+		return err
+	}
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	for _, b := range vm.bindings {
+		if b.name == name {
+			return b.store(value)
+		}
+	}
+	return nil
+}
+
+// loadBindings sets the bound variables to the values of their Go variables
+func (vm *VM) loadBindings() {
+	vm.mu.RLock()
+	bindings := vm.bindings
+	vm.mu.RUnlock()
+	for _, b := range bindings {
+		vm.GlobalCtx.SetVariable(b.name, vm.convertScriptValue(b.target, true))
+	}
+}
+
+// storeBindings stores the values of the bound variables in their Go
+// variables
+func (vm *VM) storeBindings() error {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	var errs []error
+	for _, b := range vm.bindings {
+		value, _ := vm.GlobalCtx.GetVariable(b.name)
+		errs = append(errs, b.store(value))
+	}
+	return errors.Join(errs...)
+}
+
+// store stores a script value in the Go variable of a binding
+func (b binding) store(value interface{}) error {
+	converted, err := hostArg(value, b.target.Type())
+	if err != nil {
+		return fmt.Errorf("cannot store bound variable %s: %w", b.name, err)
+	}
+	if converted.Kind() == reflect.Struct {
+		// Scripts only see the exported fields; keep the others
+		for i := 0; i < converted.NumField(); i++ {
+			if converted.Type().Field(i).IsExported() {
+				b.target.Field(i).Set(converted.Field(i))
+			}
+		}
+		return nil
+	}
+	b.target.Set(converted)
+	return nil
+}
@@ -0,0 +1,115 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// BoundaryEvent describes a tagged instruction about to cross the
+// script/host boundary
+type BoundaryEvent struct {
+	// Tag identifies the kind of boundary crossing
+	Tag instruction.Tag
+
+	// Name is the function name ("len", "strings.ToUpper") or import path
+	Name string
+
+	// Instruction is the tagged instruction being executed
+	Instruction *instruction.Instruction
+}
+
+// BoundaryApprover is called before each tagged instruction executes.
+// Returning an error aborts execution with that error.
+type BoundaryApprover func(event BoundaryEvent) error
+
+// SetBoundaryApprover sets the approval callback for boundary crossings
+func (vm *VM) SetBoundaryApprover(approver BoundaryApprover) {
+	vm.boundaryApprover = approver
+}
+
+// GetBoundaryCounts returns the number of boundary crossings per tag
+// observed during the last execution
+func (vm *VM) GetBoundaryCounts() map[instruction.Tag]int64 {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	result := make(map[instruction.Tag]int64, len(vm.boundaryCounts))
+	for tag, count := range vm.boundaryCounts {
+		result[tag] = count
+	}
+	return result
+}
+
+// resetBoundaryCounts clears the boundary crossing counters
+func (vm *VM) resetBoundaryCounts() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.boundaryCounts = make(map[instruction.Tag]int64)
+}
+
+// checkBoundary records a tagged instruction and asks the approver, if any
+func (exec *Executor) checkBoundary(stack *Stack, instr *instruction.Instruction) error {
+	event := BoundaryEvent{
+		Tag:         instr.Tags,
+		Instruction: instr,
+	}
+
+	switch {
+	case instr.HasTag(instruction.TagImport):
+		event.Name, _ = instr.Arg.(string)
+	case instr.HasTag(instruction.TagModuleCall):
+		// The module receiver sits below the call arguments on the stack
+		name, _ := instr.Arg.(string)
+		argCount, _ := instr.Arg2.(int)
+		if module, ok := stack.PeekAt(argCount - 1).(string); ok {
+			name = module + "." + name
+		}
+		event.Name = name
+	default:
+		event.Name, _ = instr.Arg.(string)
+	}
+	return exec.approve(event)
+}
+
+// checkUntaggedCall audits a call that resolved to a module or host
+// function without the tag the compiler gives such calls. Tags of loaded
+// bytecode are not trusted, so a program whose tags were removed cannot
+// bypass the approver.
+func (exec *Executor) checkUntaggedCall(instr *instruction.Instruction, functionName string, callType CallType, args []interface{}) error {
+	var tag instruction.Tag
+	name := functionName
+	switch callType {
+	case callTypeModule:
+		module, _ := exec.isModuleVariable(args[0])
+		tag, name = instruction.TagModuleCall, module+"."+functionName
+	case callTypeRegular:
+		if exec.vm.scriptFunctionByName(functionName) != nil {
+			return nil
+		}
+		if _, exists := exec.vm.GetFunction(functionName); !exists {
+			return nil
+		}
+		tag = instruction.TagHostCall
+	default:
+		return nil
+	}
+	if instr.HasTag(tag) {
+		return nil
+	}
+	return exec.approve(BoundaryEvent{Tag: tag, Name: name, Instruction: instr})
+}
+
+// approve records a boundary crossing and asks the approver, if any
+func (exec *Executor) approve(event BoundaryEvent) error {
+	exec.vm.mu.Lock()
+	exec.vm.boundaryCounts[event.Tag]++
+	exec.vm.mu.Unlock()
+
+	if exec.vm.boundaryApprover != nil {
+		if err := exec.vm.boundaryApprover(event); err != nil {
+			return fmt.Errorf("%s %s denied: %w", event.Tag, event.Name, err)
+		}
+	}
+	return nil
+}
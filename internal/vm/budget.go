@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"math"
+	"sort"
+)
+
+// BudgetEvent reports that an execution has used a given fraction of its
+// instruction budget
+type BudgetEvent struct {
+	// Threshold is the fraction of the limit that was reached (e.g., 0.8)
+	Threshold float64
+
+	// Used is the number of instructions executed so far
+	Used int64
+
+	// Limit is the current instruction limit
+	Limit int64
+}
+
+// BudgetHandler is called when an execution crosses a budget threshold. It
+// returns the number of extra instructions to grant the current execution
+// (0 keeps the limit); the next execution starts again with the limit set by
+// SetMaxInstructions. Returning an error aborts execution with that error.
+type BudgetHandler func(event BudgetEvent) (extra int64, err error)
+
+// SetBudgetAlerts registers thresholds, as fractions of the instruction
+// limit (e.g., 0.5 and 0.8), at which handler is called during execution.
+// Alerts require an instruction limit; after the limit is raised, thresholds
+// apply to the new limit.
+func (vm *VM) SetBudgetAlerts(thresholds []float64, handler BudgetHandler) {
+	sorted := make([]float64, 0, len(thresholds))
+	for _, t := range thresholds {
+		if t > 0 && t <= 1 {
+			sorted = append(sorted, t)
+		}
+	}
+	sort.Float64s(sorted)
+
+	vm.budgetThresholds = sorted
+	vm.budgetHandler = handler
+	vm.armBudgetAlerts()
+}
+
+// armBudgetAlerts schedules the next threshold above the instructions
+// already executed
+func (vm *VM) armBudgetAlerts() {
+	vm.nextBudgetAlert, vm.budgetAlertIndex = 0, -1
+	limit := vm.instructionLimit()
+	if vm.budgetHandler == nil || limit <= 0 {
+		return
+	}
+	for i, t := range vm.budgetThresholds {
+		point := int64(math.Ceil(t * float64(limit)))
+		if point > vm.instructionCount {
+			vm.nextBudgetAlert, vm.budgetAlertIndex = point, i
+			return
+		}
+	}
+}
+
+// fireBudgetAlert calls the budget handler for the threshold just reached
+// and schedules the next one
+func (vm *VM) fireBudgetAlert() error {
+	event := BudgetEvent{
+		Threshold: vm.budgetThresholds[vm.budgetAlertIndex],
+		Used:      vm.instructionCount,
+		Limit:     vm.instructionLimit(),
+	}
+	extra, err := vm.budgetHandler(event)
+	if err != nil {
+		return err
+	}
+	if extra > 0 {
+		vm.budgetExtra += extra
+	}
+	vm.armBudgetAlerts()
+	return nil
+}
+
+// instructionLimit returns the instruction limit of the current execution:
+// the configured limit plus the extra instructions granted by the budget
+// handler, or 0 for no limit
+func (vm *VM) instructionLimit() int64 {
+	if vm.maxInstructions <= 0 {
+		return 0
+	}
+	return vm.maxInstructions + vm.budgetExtra
+}
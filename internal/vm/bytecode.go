@@ -0,0 +1,507 @@
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Bytecode serialization. A compiled program (its instruction sets and
+// script functions) is saved as versioned JSON so hosts can cache it and
+// run it later without parsing and compiling the source again. Opcodes are
+// stored by name and operands with their Go type, so a program decodes to
+// the same instructions it was compiled to. The operands of LOAD_CONST are
+// stored once per instruction set, in its constant pool, and the
+// instructions refer to them by index; in memory they are values again, as
+// every handler reads its operands directly.
+
+// BytecodeVersion is the version of the serialized program format. Programs
+// of another version are rejected by LoadProgram.
+const BytecodeVersion = 3
+
+// bytecodeFormat identifies serialized programs
+const bytecodeFormat = "goscript-bytecode"
+
+// ProgramMetadata is information about a program saved with its bytecode
+type ProgramMetadata struct {
+	// Package-level constants in declaration order
+	Constants []ProgramConstant
+
+	// Import paths of the script modules the program imports, which are
+	// compiled from source when the program is loaded
+	Modules []string
+}
+
+// ProgramConstant is a package-level constant of a serialized program
+type ProgramConstant struct {
+	Name  string
+	Value interface{}
+	Type  string
+}
+
+// bytecodeProgram is the serialized form of a program
+type bytecodeProgram struct {
+	Format          string                           `json:"format"`
+	Version         int                              `json:"version"`
+	InstructionSets map[string][]bytecodeInstruction `json:"instructionSets"`
+	Pools           map[string][]*bytecodeValue      `json:"pools,omitempty"`
+	Functions       map[string]bytecodeFunction      `json:"functions"`
+	Literals        map[string]bytecodeFunction      `json:"literals,omitempty"`
+	Constants       []bytecodeConstant               `json:"constants,omitempty"`
+	Modules         []string                         `json:"modules,omitempty"`
+	Structs         map[string][]bytecodeField       `json:"structs,omitempty"`
+}
+
+// bytecodeInstruction is the serialized form of an instruction
+type bytecodeInstruction struct {
+	Op        string          `json:"op"`
+	Arg       *bytecodeValue  `json:"arg,omitempty"`
+	Arg2      *bytecodeValue  `json:"arg2,omitempty"`
+	Const     *int            `json:"const,omitempty"`
+	Tags      instruction.Tag `json:"tags,omitempty"`
+	Line      int             `json:"line,omitempty"`
+	StmtStart bool            `json:"stmt,omitempty"`
+	Column    int             `json:"col,omitempty"`
+
+	// File is only stored when it differs from the file of the previous
+	// instruction of the set with a position
+	File string `json:"file,omitempty"`
+}
+
+// bytecodeFunction is the serialized form of a ScriptFunctionInfo
+type bytecodeFunction struct {
+	Name       string   `json:"name"`
+	Key        string   `json:"key"`
+	ParamCount int      `json:"paramCount"`
+	ParamNames []string `json:"paramNames,omitempty"`
+	Variadic   bool     `json:"variadic,omitempty"`
+	Pure       bool     `json:"pure,omitempty"`
+	Receiver   string   `json:"receiver,omitempty"`
+	Locals     int      `json:"locals,omitempty"`
+}
+
+// bytecodeField is the serialized form of a StructField
+type bytecodeField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	Embedded bool   `json:"embedded,omitempty"`
+}
+
+// bytecodeConstant is the serialized form of a ProgramConstant
+type bytecodeConstant struct {
+	Name  string         `json:"name"`
+	Value *bytecodeValue `json:"value,omitempty"`
+	Type  string         `json:"type,omitempty"`
+}
+
+// bytecodeValue is an operand or constant value with its Go type; nil
+// values are omitted
+type bytecodeValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v"`
+}
+
+// MarshalProgram serializes the instruction sets, script functions and
+// function literals of the VM, with the given metadata
+func (vm *VM) MarshalProgram(meta ProgramMetadata) ([]byte, error) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	program := bytecodeProgram{
+		Format:          bytecodeFormat,
+		Version:         BytecodeVersion,
+		InstructionSets: make(map[string][]bytecodeInstruction, len(vm.InstructionSets)),
+		Pools:           make(map[string][]*bytecodeValue),
+		Functions:       make(map[string]bytecodeFunction, len(vm.scriptFunctionInfos)),
+		Modules:         meta.Modules,
+	}
+	for key, instructions := range vm.InstructionSets {
+		encoded := make([]bytecodeInstruction, len(instructions))
+		pool := newConstantPool()
+		file := ""
+		for pc, instr := range instructions {
+			var err error
+			if encoded[pc], err = encodeInstruction(instr, pool); err != nil {
+				return nil, fmt.Errorf("%s: instruction %d: %w", key, pc, err)
+			}
+			if instr.Line != 0 && instr.File != file {
+				encoded[pc].File, file = instr.File, instr.File
+			}
+		}
+		program.InstructionSets[key] = encoded
+		if len(pool.values) > 0 {
+			program.Pools[key] = pool.values
+		}
+	}
+	for name, info := range vm.scriptFunctionInfos {
+		program.Functions[name] = encodeFunction(info)
+	}
+	if len(vm.functionLiterals) > 0 {
+		program.Literals = make(map[string]bytecodeFunction, len(vm.functionLiterals))
+		for key, info := range vm.functionLiterals {
+			program.Literals[key] = encodeFunction(info)
+		}
+	}
+	if len(vm.structTypes) > 0 {
+		program.Structs = make(map[string][]bytecodeField, len(vm.structTypes))
+		for name, fields := range vm.structTypes {
+			encoded := make([]bytecodeField, len(fields))
+			for i, field := range fields {
+				encoded[i] = bytecodeField(field)
+			}
+			program.Structs[name] = encoded
+		}
+	}
+	for _, constant := range meta.Constants {
+		value, err := encodeValue(constant.Value)
+		if err != nil {
+			return nil, fmt.Errorf("constant %s: %w", constant.Name, err)
+		}
+		program.Constants = append(program.Constants, bytecodeConstant{Name: constant.Name, Value: value, Type: constant.Type})
+	}
+	return json.Marshal(program)
+}
+
+// LoadProgram loads a program serialized by MarshalProgram into the VM and
+// returns its metadata. The instruction sets are verified before they are
+// added, so programs from untrusted storage cannot crash the VM.
+func (vm *VM) LoadProgram(data []byte) (ProgramMetadata, error) {
+	var program bytecodeProgram
+	if err := json.Unmarshal(data, &program); err != nil {
+		return ProgramMetadata{}, fmt.Errorf("invalid bytecode: %w", err)
+	}
+	if program.Format != bytecodeFormat {
+		return ProgramMetadata{}, fmt.Errorf("invalid bytecode: not a GoScript program")
+	}
+	if program.Version != BytecodeVersion {
+		return ProgramMetadata{}, fmt.Errorf("unsupported bytecode version %d (want %d)", program.Version, BytecodeVersion)
+	}
+
+	// Decode everything before changing the VM. Local variable slots are
+	// verified against the frame sizes recorded in the function infos.
+	locals := make(map[string]int)
+	for _, fn := range program.Functions {
+		locals[fn.Key] = fn.Locals
+	}
+	for _, fn := range program.Literals {
+		locals[fn.Key] = fn.Locals
+	}
+	pools := make(map[string][]interface{}, len(program.Pools))
+	for key, encoded := range program.Pools {
+		pool := make([]interface{}, len(encoded))
+		for i := range encoded {
+			var err error
+			if pool[i], err = decodeValue(encoded[i]); err != nil {
+				return ProgramMetadata{}, fmt.Errorf("invalid bytecode: %s: constant %d: %w", key, i, err)
+			}
+		}
+		pools[key] = pool
+	}
+	instructionSets := make(map[string][]*instruction.Instruction, len(program.InstructionSets))
+	for key, encoded := range program.InstructionSets {
+		instructions := make([]*instruction.Instruction, len(encoded))
+		file := ""
+		for pc := range encoded {
+			var err error
+			if instructions[pc], err = decodeInstruction(&encoded[pc], pools[key]); err != nil {
+				return ProgramMetadata{}, fmt.Errorf("invalid bytecode: %s: instruction %d: %w", key, pc, err)
+			}
+			if encoded[pc].File != "" {
+				file = encoded[pc].File
+			}
+			if instructions[pc].Line != 0 {
+				instructions[pc].File = file
+			}
+		}
+		if err := Verify(instructions, locals[key]); err != nil {
+			return ProgramMetadata{}, fmt.Errorf("invalid bytecode: %s: %w", key, err)
+		}
+		instructionSets[key] = instructions
+	}
+	meta := ProgramMetadata{Modules: program.Modules}
+	for _, constant := range program.Constants {
+		value, err := decodeValue(constant.Value)
+		if err != nil {
+			return ProgramMetadata{}, fmt.Errorf("invalid bytecode: constant %s: %w", constant.Name, err)
+		}
+		meta.Constants = append(meta.Constants, ProgramConstant{Name: constant.Name, Value: value, Type: constant.Type})
+	}
+
+	for key, instructions := range instructionSets {
+		vm.AddInstructionSet(key, instructions)
+	}
+	for name, fn := range program.Functions {
+		vm.RegisterScriptFunction(name, fn.info())
+	}
+	for _, fn := range program.Literals {
+		vm.RegisterFunctionLiteral(fn.info())
+	}
+	for name, encoded := range program.Structs {
+		fields := make([]StructField, len(encoded))
+		for i, field := range encoded {
+			fields[i] = StructField(field)
+		}
+		vm.RegisterStructType(name, fields)
+	}
+	return meta, nil
+}
+
+// encodeFunction converts function information to its serialized form
+func encodeFunction(info *ScriptFunctionInfo) bytecodeFunction {
+	return bytecodeFunction{
+		Name:       info.Name,
+		Key:        info.Key,
+		ParamCount: info.ParamCount,
+		ParamNames: info.ParamNames,
+		Variadic:   info.Variadic,
+		Pure:       info.Pure,
+		Receiver:   info.Receiver,
+		Locals:     info.Locals,
+	}
+}
+
+// info converts serialized function information back
+func (fn *bytecodeFunction) info() *ScriptFunctionInfo {
+	return &ScriptFunctionInfo{
+		Name:       fn.Name,
+		Key:        fn.Key,
+		ParamCount: fn.ParamCount,
+		ParamNames: fn.ParamNames,
+		Variadic:   fn.Variadic,
+		Pure:       fn.Pure,
+		Receiver:   fn.Receiver,
+		Locals:     fn.Locals,
+	}
+}
+
+// constantPool collects the LOAD_CONST operands of an instruction set
+// being serialized. Equal scalar values share an entry; lists get one each,
+// so loading does not make instructions share a slice.
+type constantPool struct {
+	values  []*bytecodeValue
+	indices map[string]int
+}
+
+// newConstantPool returns an empty constant pool
+func newConstantPool() *constantPool {
+	return &constantPool{indices: make(map[string]int)}
+}
+
+// add returns the index of a value in the pool, adding it if needed
+func (pool *constantPool) add(value *bytecodeValue) int {
+	if value.Type == "list" {
+		pool.values = append(pool.values, value)
+		return len(pool.values) - 1
+	}
+	key := value.Type + ":" + string(value.Value)
+	if index, ok := pool.indices[key]; ok {
+		return index
+	}
+	pool.indices[key] = len(pool.values)
+	pool.values = append(pool.values, value)
+	return len(pool.values) - 1
+}
+
+// encodeInstruction converts an instruction to its serialized form; the
+// operand of LOAD_CONST is added to the constant pool of its set
+func encodeInstruction(instr *instruction.Instruction, pool *constantPool) (bytecodeInstruction, error) {
+	arg, err := encodeValue(instr.Arg)
+	if err != nil {
+		return bytecodeInstruction{}, err
+	}
+	arg2, err := encodeValue(instr.Arg2)
+	if err != nil {
+		return bytecodeInstruction{}, err
+	}
+	encoded := bytecodeInstruction{
+		Op:        instr.Op.String(),
+		Arg:       arg,
+		Arg2:      arg2,
+		Tags:      instr.Tags,
+		Line:      instr.Line,
+		StmtStart: instr.StmtStart,
+		Column:    instr.Column,
+	}
+	if instr.Op == instruction.OpLoadConst && arg != nil {
+		index := pool.add(arg)
+		encoded.Arg, encoded.Const = nil, &index
+	}
+	return encoded, nil
+}
+
+// decodeInstruction converts a serialized instruction back, resolving
+// constant pool indices against the pool of its set
+func decodeInstruction(encoded *bytecodeInstruction, pool []interface{}) (*instruction.Instruction, error) {
+	op, ok := opcodesByName[encoded.Op]
+	if !ok {
+		return nil, fmt.Errorf("unknown opcode %q", encoded.Op)
+	}
+	arg, err := decodeValue(encoded.Arg)
+	if err != nil {
+		return nil, err
+	}
+	if encoded.Const != nil {
+		index := *encoded.Const
+		if op != instruction.OpLoadConst || encoded.Arg != nil {
+			return nil, fmt.Errorf("unexpected constant operand for %s", encoded.Op)
+		}
+		if index < 0 || index >= len(pool) {
+			return nil, fmt.Errorf("constant %d out of range [0, %d)", index, len(pool))
+		}
+		arg = pool[index]
+	}
+	arg2, err := decodeValue(encoded.Arg2)
+	if err != nil {
+		return nil, err
+	}
+	return &instruction.Instruction{
+		Op:        op,
+		Arg:       arg,
+		Arg2:      arg2,
+		Tags:      loadedTags(op, encoded.Tags),
+		Line:      encoded.Line,
+		StmtStart: encoded.StmtStart,
+		Column:    encoded.Column,
+	}, nil
+}
+
+// loadedTags returns the tags of a loaded instruction. Tags come from
+// storage the VM does not trust: imports are always tagged, calls keep the
+// host and module call tags, which are checked again when the call resolves
+// (see checkUntaggedCall), and other instructions have none.
+func loadedTags(op instruction.OpCode, tags instruction.Tag) instruction.Tag {
+	switch op {
+	case instruction.OpImport:
+		return instruction.TagImport
+	case instruction.OpCall:
+		return tags & (instruction.TagHostCall | instruction.TagModuleCall)
+	}
+	return 0
+}
+
+// opcodesByName maps opcode names to opcodes
+var opcodesByName = func() map[string]instruction.OpCode {
+	names := make(map[string]instruction.OpCode, int(instruction.OpCodeLast))
+	for op := instruction.OpNop; op < instruction.OpCodeLast; op++ {
+		names[op.String()] = op
+	}
+	return names
+}()
+
+// encodeValue converts an operand or constant value to its serialized form
+func encodeValue(value interface{}) (*bytecodeValue, error) {
+	var typ string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		typ = "bool"
+	case int:
+		typ = "int"
+	case int32:
+		typ = "int32"
+	case int64:
+		typ = "int64"
+	case uint64:
+		typ = "uint64"
+	case float64:
+		typ = "float64"
+	case string:
+		typ = "string"
+	case instruction.BinaryOp:
+		typ = "binop"
+	case []instruction.SelectCase:
+		cases := make([]int, len(v))
+		for i, c := range v {
+			cases[i] = int(c)
+		}
+		value, typ = cases, "cases"
+	case []interface{}:
+		list := make([]*bytecodeValue, len(v))
+		for i, elem := range v {
+			var err error
+			if list[i], err = encodeValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		value, typ = list, "list"
+	default:
+		return nil, fmt.Errorf("cannot serialize value of type %T", value)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &bytecodeValue{Type: typ, Value: data}, nil
+}
+
+// decodeValue converts a serialized value back
+func decodeValue(encoded *bytecodeValue) (interface{}, error) {
+	if encoded == nil {
+		return nil, nil
+	}
+	var value interface{}
+	var err error
+	switch encoded.Type {
+	case "bool":
+		var v bool
+		err = json.Unmarshal(encoded.Value, &v)
+		value = v
+	case "int":
+		var v int
+		err = json.Unmarshal(encoded.Value, &v)
+		value = v
+	case "int32":
+		var v int32
+		err = json.Unmarshal(encoded.Value, &v)
+		value = v
+	case "int64":
+		var v int64
+		err = json.Unmarshal(encoded.Value, &v)
+		value = v
+	case "uint64":
+		var v uint64
+		err = json.Unmarshal(encoded.Value, &v)
+		value = v
+	case "float64":
+		var v float64
+		err = json.Unmarshal(encoded.Value, &v)
+		value = v
+	case "string":
+		var v string
+		err = json.Unmarshal(encoded.Value, &v)
+		value = v
+	case "binop":
+		var v instruction.BinaryOp
+		err = json.Unmarshal(encoded.Value, &v)
+		value = v
+	case "cases":
+		var v []int
+		err = json.Unmarshal(encoded.Value, &v)
+		cases := make([]instruction.SelectCase, len(v))
+		for i, c := range v {
+			cases[i] = instruction.SelectCase(c)
+		}
+		value = cases
+	case "list":
+		var v []*bytecodeValue
+		if err = json.Unmarshal(encoded.Value, &v); err != nil {
+			break
+		}
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			if list[i], err = decodeValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		value = list
+	default:
+		return nil, fmt.Errorf("unknown value type %q", encoded.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value: %w", encoded.Type, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,42 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lengzhao/goscript/builtin"
+)
+
+// Byte slices are Go []byte values (see builtin.Bytes). Indexing a byte
+// slice or a string yields the byte as an int, and only ints and runes
+// from 0 to 255 can be stored in a byte slice. The buffers of the bytes
+// module are host objects of every VM, so scripts call their methods.
+
+// bufferType is the struct type of the buffers of the bytes module
+var bufferType = reflect.TypeOf(builtin.Buffer{})
+
+// byteIndex checks the index of a byte slice or string of length n
+func byteIndex(index interface{}, n int) (int, error) {
+	idx, ok := index.(int)
+	if !ok {
+		return 0, fmt.Errorf("index must be an integer, got %T", index)
+	}
+	if idx < 0 || idx >= n {
+		return 0, fmt.Errorf("index out of range: %d", idx)
+	}
+	return idx, nil
+}
+
+// setByte stores a value in a byte slice
+func setByte(b []byte, index, value interface{}) error {
+	idx, err := byteIndex(index, len(b))
+	if err != nil {
+		return err
+	}
+	c, err := builtin.ByteValue(value)
+	if err != nil {
+		return err
+	}
+	b[idx] = c
+	return nil
+}
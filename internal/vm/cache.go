@@ -0,0 +1,249 @@
+package vm
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// DefaultCacheSize is the default maximum number of entries of the cache
+// module
+const DefaultCacheSize = 1000
+
+// The cache module memoizes expensive calls within one execution:
+//
+//	rates := cache.GetOrCompute("rates", "fetchRates", 0)
+//	user := cache.GetOrCompute(id, "lookupUser", 5000, id)
+//
+// GetOrCompute(key, fn, ttl, args...) returns the cached value of key, or
+// calls fn (a function value or the name of a function) with args and caches its result for ttl
+// milliseconds (0 means until the execution ends). Get returns the value
+// and whether it was found (v, ok := cache.Get(key)); Set(key, value, ttl),
+// Delete and Len manage entries directly. Each execution starts with
+// an empty cache; when it is full the least recently used entry is evicted.
+
+// SetCacheSize sets the maximum number of entries of the cache module
+// (0 means no limit). It applies from the next execution.
+func (vm *VM) SetCacheSize(size int) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.cacheSize = size
+}
+
+// GetCacheSize returns the maximum number of entries of the cache module
+func (vm *VM) GetCacheSize() int {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.cacheSize
+}
+
+// cacheEntry is a cached value and its expiry (zero for none)
+type cacheEntry struct {
+	key     interface{}
+	value   interface{}
+	expires time.Time
+}
+
+// cacheInstance is the per-execution state of the cache module
+type cacheInstance struct {
+	vm      *VM
+	size    int
+	entries map[interface{}]*list.Element
+	// Most recently used entries are at the front
+	order *list.List
+}
+
+// newCacheInstance is the factory of the cache module
+func (vm *VM) newCacheInstance() types.ModuleInstance {
+	return &cacheInstance{vm: vm}
+}
+
+// Init is called with vm.mu held, so it reads the size directly
+func (c *cacheInstance) Init() error {
+	c.size = c.vm.cacheSize
+	c.entries = make(map[interface{}]*list.Element)
+	c.order = list.New()
+	return nil
+}
+
+func (c *cacheInstance) Call(entrypoint string, args ...interface{}) (interface{}, error) {
+	switch entrypoint {
+	case "GetOrCompute":
+		return c.getOrCompute(args)
+	case "Get":
+		if err := cacheArgs(entrypoint, args, 1, 1); err != nil {
+			return nil, err
+		}
+		value, found := c.get(args[0])
+		return Tuple{value, found}, nil
+	case "Set":
+		if err := cacheArgs(entrypoint, args, 2, 3); err != nil {
+			return nil, err
+		}
+		ttl, err := cacheTTL(entrypoint, args, 2)
+		if err != nil {
+			return nil, err
+		}
+		c.set(args[0], args[1], ttl)
+		return nil, nil
+	case "Delete":
+		if err := cacheArgs(entrypoint, args, 1, 1); err != nil {
+			return nil, err
+		}
+		if elem, exists := c.entries[args[0]]; exists {
+			c.remove(elem)
+		}
+		return nil, nil
+	case "Len":
+		if err := cacheArgs(entrypoint, args, 0, 0); err != nil {
+			return nil, err
+		}
+		c.expire()
+		return len(c.entries), nil
+	default:
+		return nil, fmt.Errorf("function %s not found in module cache", entrypoint)
+	}
+}
+
+// getOrCompute implements GetOrCompute(key, fn, ttl, args...). Errors of
+// fn are returned and not cached.
+func (c *cacheInstance) getOrCompute(args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("GetOrCompute function requires at least 2 arguments")
+	}
+	if err := checkMapKey(args[0]); err != nil {
+		return nil, fmt.Errorf("GetOrCompute: %w", err)
+	}
+	if value, hit := c.get(args[0]); hit {
+		return value, nil
+	}
+
+	ttl, err := cacheTTL("GetOrCompute", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	var fnArgs []interface{}
+	if len(args) > 3 {
+		fnArgs = args[3:]
+	}
+	var value interface{}
+	switch fn := args[1].(type) {
+	case string:
+		value, err = c.vm.callFunction(fn, fnArgs)
+	case *Closure, *HostFunction:
+		value, err = c.vm.callValue(fn, fnArgs)
+	default:
+		return nil, fmt.Errorf("GetOrCompute function requires a function or function name, got %T", args[1])
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.set(args[0], value, ttl)
+	return value, nil
+}
+
+// get returns the value of a live entry and marks it as recently used
+func (c *cacheInstance) get(key interface{}) (interface{}, bool) {
+	if checkMapKey(key) != nil {
+		return nil, false
+	}
+	elem, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && !time.Now().Before(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// set stores a value, evicting the least recently used entry when full
+func (c *cacheInstance) set(key, value interface{}, ttl time.Duration) {
+	if checkMapKey(key) != nil {
+		return
+	}
+	entry := &cacheEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	if elem, exists := c.entries[key]; exists {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	if c.size > 0 && len(c.entries) >= c.size {
+		c.expire()
+		if len(c.entries) >= c.size {
+			c.remove(c.order.Back())
+		}
+	}
+	c.entries[key] = c.order.PushFront(entry)
+}
+
+// expire removes the entries whose ttl has passed
+func (c *cacheInstance) expire() {
+	now := time.Now()
+	for key, elem := range c.entries {
+		expires := elem.Value.(*cacheEntry).expires
+		if !expires.IsZero() && !now.Before(expires) {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *cacheInstance) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+func (c *cacheInstance) Close() error {
+	c.entries = nil
+	c.order = nil
+	return nil
+}
+
+// callFunction calls a script, host or builtin function by name
+func (vm *VM) callFunction(name string, args []interface{}) (interface{}, error) {
+	exec := NewExecutor(vm)
+	stack := NewStackWithLimits(len(args), vm.stackMaxSize)
+	for _, arg := range args {
+		stack.Push(arg)
+	}
+	_, err := exec.handleFunctionCall(stack, vm, name, len(args), 0)
+	if err := exec.finishCall(stack, err); err != nil {
+		return nil, err
+	}
+	if stack.Len() == 0 {
+		return nil, nil
+	}
+	return stack.Pop(), nil
+}
+
+// cacheArgs checks the number of arguments of a cache function
+func cacheArgs(fn string, args []interface{}, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("%s function requires %d arguments, got %d", fn, min, len(args))
+		}
+		return fmt.Errorf("%s function requires %d to %d arguments, got %d", fn, min, max, len(args))
+	}
+	return nil
+}
+
+// cacheTTL returns the optional ttl argument, in milliseconds
+func cacheTTL(fn string, args []interface{}, pos int) (time.Duration, error) {
+	if len(args) <= pos {
+		return 0, nil
+	}
+	ms, ok := args[pos].(int)
+	if !ok || ms < 0 {
+		return 0, fmt.Errorf("%s function requires a non-negative ttl in milliseconds, got %v", fn, args[pos])
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
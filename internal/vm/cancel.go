@@ -0,0 +1,60 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+)
+
+// cancelCheckInterval is the number of instructions executed between checks
+// of the execution context
+const cancelCheckInterval = 256
+
+// ExecuteContext executes an entry point like Execute and stops it when ctx
+// is done, with an error wrapping ctx.Err() (context.Canceled or
+// context.DeadlineExceeded). The context is checked periodically while
+// instructions run, in every goroutine; host functions that block should
+// watch the host context themselves.
+func (vm *VM) ExecuteContext(ctx context.Context, entryPoint string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("execution canceled: %w", err)
+	}
+	vm.execCtx = ctx
+	defer func() { vm.execCtx = nil }()
+	return vm.Execute(entryPoint, args...)
+}
+
+// waitContext returns a context that is done when the current execution is
+// canceled or runs out of time, for operations that block outside the
+// executor loop
+func (vm *VM) waitContext() (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	if vm.execCtx != nil {
+		ctx = vm.execCtx
+	}
+	if !vm.deadline.IsZero() {
+		return context.WithDeadline(ctx, vm.deadline)
+	}
+	return context.WithCancel(ctx)
+}
+
+// waitError returns the error of the execution for a wait on a context of
+// waitContext that ended with err
+func (vm *VM) waitError(err error) error {
+	if !vm.deadline.IsZero() {
+		if deadlineErr := vm.checkDeadline(); deadlineErr != nil {
+			return deadlineErr
+		}
+	}
+	return fmt.Errorf("execution canceled: %w", err)
+}
+
+// checkCanceled returns an error when the context of the current execution
+// is done
+func (vm *VM) checkCanceled() error {
+	select {
+	case <-vm.execCtx.Done():
+		return fmt.Errorf("execution canceled: %w", vm.execCtx.Err())
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lengzhao/goscript/builtin"
+)
+
+// Scripts probe the capabilities of their host with hasFunction and
+// hasModule, so that one script can run against deployments with different
+// integrations and skip the ones that are missing:
+//
+//	if hasFunction("sendSMS") {
+//		sendSMS(phone, text)
+//	}
+//
+// Calls to missing functions only fail when they are executed.
+
+// HasFunction reports whether a function can be called by name: a host,
+// builtin or script function ("notify"), or a module function
+// ("strings.ToUpper"). For host modules only the module can be checked.
+func (vm *VM) HasFunction(name string) bool {
+	moduleName, funcName, qualified := strings.Cut(name, ".")
+	if qualified {
+		// Functions of modules the host does not allow are missing
+		if vm.hasAnyModule(moduleName) && vm.CheckModule(moduleName) != nil {
+			return false
+		}
+		if _, isBuiltin := builtin.GetModuleFunctions(moduleName); isBuiltin {
+			if _, overridden := vm.getOverride(name); overridden {
+				return true
+			}
+			return builtin.HasModuleFunction(moduleName, funcName)
+		}
+	}
+	_, exists := vm.GetFunction(name)
+	return exists
+}
+
+// HasModule reports whether a module is registered by the host or builtin,
+// and allowed
+func (vm *VM) HasModule(name string) bool {
+	return vm.CheckModule(name) == nil && vm.hasAnyModule(name)
+}
+
+// hasAnyModule reports whether a module is registered by the host or
+// builtin, allowed or not
+func (vm *VM) hasAnyModule(name string) bool {
+	if _, exists := vm.GetModule(name); exists {
+		return true
+	}
+	for _, module := range builtin.ListAllModules() {
+		if module == name {
+			return true
+		}
+	}
+	return false
+}
+
+// getOverride returns the override installed for a builtin function
+func (vm *VM) getOverride(name string) (ScriptFunction, bool) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	fn, exists := vm.overrides[name]
+	return fn, exists
+}
+
+// builtinHasFunction implements hasFunction("name")
+func (vm *VM) builtinHasFunction(args ...interface{}) (interface{}, error) {
+	name, err := capabilityName("hasFunction", args)
+	if err != nil {
+		return nil, err
+	}
+	return vm.HasFunction(name), nil
+}
+
+// builtinHasModule implements hasModule("name")
+func (vm *VM) builtinHasModule(args ...interface{}) (interface{}, error) {
+	name, err := capabilityName("hasModule", args)
+	if err != nil {
+		return nil, err
+	}
+	return vm.HasModule(name), nil
+}
+
+// capabilityName checks the single string argument of a capability probe
+func capabilityName(probe string, args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s expects 1 argument, got %d", probe, len(args))
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%s: name must be a string, got %T", probe, args[0])
+	}
+	return name, nil
+}
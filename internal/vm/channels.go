@@ -0,0 +1,344 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Channels. make(chan T, n) creates a Channel; sends block while the buffer
+// is full and, on unbuffered channels, until a goroutine is receiving.
+// Receives block until a value is available or the channel is closed, and
+// operations on a nil channel block forever. Blocking is handled by the
+// goroutine scheduler, so a channel operation that can never proceed fails
+// with a deadlock error instead of hanging the host.
+
+// Channel is a channel value
+type Channel struct {
+	elemType string
+	capacity int
+	buffer   []interface{}
+	closed   bool
+
+	// receivers and senders count the goroutines blocked on the channel
+	receivers int
+	senders   int
+}
+
+// NewChannel creates a channel of the given element type and capacity
+func NewChannel(elemType string, capacity int) *Channel {
+	return &Channel{elemType: elemType, capacity: capacity}
+}
+
+// String returns the string representation of a channel
+func (ch *Channel) String() string {
+	return fmt.Sprintf("chan %s", ch.elemType)
+}
+
+// Len returns the number of values buffered in the channel
+func (ch *Channel) Len() int {
+	return len(ch.buffer)
+}
+
+// Cap returns the capacity of the channel
+func (ch *Channel) Cap() int {
+	return ch.capacity
+}
+
+// canSend reports whether a send can proceed without blocking: the buffer
+// has room, or a goroutine is waiting for the value. Sends on a closed
+// channel proceed, to fail.
+func (ch *Channel) canSend() bool {
+	return ch.closed || len(ch.buffer) < ch.capacity || len(ch.buffer) < ch.receivers
+}
+
+// canRecv reports whether a receive can proceed without blocking
+func (ch *Channel) canRecv() bool {
+	return len(ch.buffer) > 0 || ch.closed
+}
+
+// zero returns the zero value of the element type, received from closed
+// channels
+func (ch *Channel) zero() interface{} {
+	return zeroValue(ch.elemType)
+}
+
+// registerChannelBuiltins registers the builtins operating on channels
+func (vm *VM) registerChannelBuiltins() {
+	vm.functions["close"] = vm.builtinClose
+}
+
+// builtinClose implements close(ch)
+func (vm *VM) builtinClose(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("close expects 1 argument, got %d", len(args))
+	}
+	if args[0] == nil {
+		return nil, errors.New("close of nil channel")
+	}
+	ch, ok := args[0].(*Channel)
+	if !ok {
+		return nil, fmt.Errorf("invalid operation: close of non-channel %v (%T)", args[0], args[0])
+	}
+	if ch.closed {
+		return nil, errors.New("close of closed channel")
+	}
+	ch.closed = true
+	vm.notify()
+	return nil, nil
+}
+
+// handleMakeChan handles the MAKE_CHAN opcode
+func (exec *Executor) handleMakeChan(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	elemType, ok := instr.Arg.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid element type for MAKE_CHAN")
+	}
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for MAKE_CHAN")
+	}
+	var capacity int
+	switch size := stack.Pop().(type) {
+	case int:
+		capacity = size
+	case int64:
+		capacity = int(size)
+	default:
+		return 0, fmt.Errorf("non-integer buffer argument in make(chan %s): %v (%T)", elemType, size, size)
+	}
+	if capacity < 0 {
+		return 0, fmt.Errorf("negative buffer argument in make(chan %s)", elemType)
+	}
+	stack.Push(NewChannel(elemType, capacity))
+	return pc + 1, nil
+}
+
+// handleSend handles the SEND opcode
+func (exec *Executor) handleSend(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	if stack.Len() < 2 {
+		return 0, fmt.Errorf("stack underflow for SEND")
+	}
+	value := copyValue(stack.Pop())
+	ch, err := channelOperand(stack.Pop(), "send to")
+	if err != nil {
+		return 0, err
+	}
+	if err := exec.vm.send(ch, value); err != nil {
+		return 0, err
+	}
+	return pc + 1, nil
+}
+
+// handleRecv handles the RECV opcode. With the ok form (Arg is true) it
+// pushes the value and whether it was sent, v, ok := <-ch.
+func (exec *Executor) handleRecv(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for RECV")
+	}
+	ch, err := channelOperand(stack.Pop(), "receive from")
+	if err != nil {
+		return 0, err
+	}
+	value, ok, err := exec.vm.recv(ch)
+	if err != nil {
+		return 0, err
+	}
+	stack.Push(value)
+	if withOk, _ := instr.Arg.(bool); withOk {
+		stack.Push(ok)
+	}
+	return pc + 1, nil
+}
+
+// handleSelect handles the SELECT opcode. The operands of the cases are on
+// the stack in order: the channel of a receive, the channel and value of a
+// send. It pushes the received value, whether it was sent and the index of
+// the chosen case, -1 for the default case.
+func (exec *Executor) handleSelect(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	cases, ok := instr.Arg.([]instruction.SelectCase)
+	if !ok {
+		return 0, fmt.Errorf("invalid cases for SELECT")
+	}
+	hasDefault, _ := instr.Arg2.(bool)
+
+	ops := make([]selectOp, len(cases))
+	for i := len(cases) - 1; i >= 0; i-- {
+		ops[i].dir = cases[i]
+		if cases[i] == instruction.SelectSend {
+			if stack.Len() < 1 {
+				return 0, fmt.Errorf("stack underflow for SELECT")
+			}
+			ops[i].value = stack.Pop()
+		}
+		if stack.Len() < 1 {
+			return 0, fmt.Errorf("stack underflow for SELECT")
+		}
+		ch, err := channelOperand(stack.Pop(), "select on")
+		if err != nil {
+			return 0, err
+		}
+		ops[i].ch = ch
+	}
+
+	index, value, received, err := exec.vm.selectCase(ops, hasDefault)
+	if err != nil {
+		return 0, err
+	}
+	stack.Push(value)
+	stack.Push(received)
+	stack.Push(index)
+	return pc + 1, nil
+}
+
+// channelOperand checks that a channel operand is a channel or nil
+func channelOperand(value interface{}, op string) (*Channel, error) {
+	if value == nil {
+		return nil, nil
+	}
+	ch, ok := value.(*Channel)
+	if !ok {
+		return nil, fmt.Errorf("invalid operation: cannot %s non-channel %v (%T)", op, value, value)
+	}
+	return ch, nil
+}
+
+// send sends a value on a channel, blocking until it can proceed
+func (vm *VM) send(ch *Channel, value interface{}) error {
+	for ch == nil || !ch.canSend() {
+		if ch != nil {
+			ch.senders++
+		}
+		err := vm.wait()
+		if ch != nil {
+			ch.senders--
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return vm.put(ch, value)
+}
+
+// put adds a value to a channel that can send
+func (vm *VM) put(ch *Channel, value interface{}) error {
+	if ch.closed {
+		return errors.New("send on closed channel")
+	}
+	ch.buffer = append(ch.buffer, value)
+	vm.notify()
+	return nil
+}
+
+// recv receives a value from a channel, blocking until it can proceed. ok
+// is false for the zero value received from a closed channel.
+func (vm *VM) recv(ch *Channel) (value interface{}, ok bool, err error) {
+	for ch == nil || !ch.canRecv() {
+		if ch != nil {
+			// A waiting receiver lets blocked unbuffered sends proceed
+			ch.receivers++
+			if ch.senders > 0 {
+				vm.notify()
+			}
+		}
+		err := vm.wait()
+		if ch != nil {
+			ch.receivers--
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	value, ok = vm.take(ch)
+	return value, ok, nil
+}
+
+// take removes the next value from a channel that can receive
+func (vm *VM) take(ch *Channel) (interface{}, bool) {
+	if len(ch.buffer) == 0 {
+		return ch.zero(), false
+	}
+	value := ch.buffer[0]
+	ch.buffer[0] = nil
+	ch.buffer = ch.buffer[1:]
+	vm.notify()
+	return value, true
+}
+
+// selectOp is a case of a select statement
+type selectOp struct {
+	dir   instruction.SelectCase
+	ch    *Channel
+	value interface{}
+}
+
+// ready reports whether the case can proceed without blocking
+func (op *selectOp) ready() bool {
+	if op.ch == nil {
+		return false
+	}
+	if op.dir == instruction.SelectSend {
+		return op.ch.canSend()
+	}
+	return op.ch.canRecv()
+}
+
+// selectCase runs one of the cases that can proceed, chosen at random as
+// in Go. Without such a case it runs the default case (index -1) if there
+// is one, or blocks until a case can proceed.
+func (vm *VM) selectCase(ops []selectOp, hasDefault bool) (index int, value interface{}, ok bool, err error) {
+	for {
+		var ready []int
+		for i := range ops {
+			if ops[i].ready() {
+				ready = append(ready, i)
+			}
+		}
+		if len(ready) > 0 {
+			index = ready[rand.Intn(len(ready))]
+			op := &ops[index]
+			if op.dir == instruction.SelectSend {
+				return index, nil, false, vm.put(op.ch, op.value)
+			}
+			value, ok = vm.take(op.ch)
+			return index, value, ok, nil
+		}
+		if hasDefault {
+			return -1, nil, false, nil
+		}
+
+		blockedOps(ops).register(vm, 1)
+		err = vm.wait()
+		blockedOps(ops).register(vm, -1)
+		if err != nil {
+			return 0, nil, false, err
+		}
+	}
+}
+
+// blockedOps are the cases of a select statement blocked on their channels
+type blockedOps []selectOp
+
+// register adds (delta 1) or removes (delta -1) the cases from the counts
+// of goroutines blocked on their channels. Blocked senders are woken when
+// a receiver is added, as unbuffered sends may proceed.
+func (ops blockedOps) register(vm *VM, delta int) {
+	wakeSenders := false
+	for i := range ops {
+		ch := ops[i].ch
+		if ch == nil || ops[i].dir != instruction.SelectRecv {
+			continue
+		}
+		ch.receivers += delta
+		wakeSenders = wakeSenders || ch.senders > 0
+	}
+	for i := range ops {
+		if ops[i].ch != nil && ops[i].dir == instruction.SelectSend {
+			ops[i].ch.senders += delta
+		}
+	}
+	if delta > 0 && wakeSenders {
+		vm.notify()
+	}
+}
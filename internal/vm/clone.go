@@ -0,0 +1,85 @@
+package vm
+
+// Cloning. A VM executes one script at a time: an execution keeps its
+// scopes, operand stacks, frames and counters in the VM. Clone returns a VM
+// for running the same program in another goroutine: it shares the image of
+// the program (see Image) and copies the configuration the host set up, so
+// each execution has its own state. Host functions are registered again for
+// the clone, so those bound to the VM (e.g., context functions) see the
+// executions of the clone.
+
+// Clone returns a new VM running the same program with the same host
+// functions, modules, host variables, limits and options. Execution state,
+// debugger settings (breakpoints, step and pause handlers) and watches are
+// not copied. Bound variables (see BindVariable) are copied with their
+// current value, unbound, so clones do not write to the Go variables.
+func (vm *VM) Clone() *VM {
+	clone := NewVM()
+	clone.LoadImage(vm.Image())
+
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	for name, bind := range vm.hostFunctions {
+		clone.bindFunction(name, bind)
+	}
+	for name, executor := range vm.modules {
+		if !vm.builtinLoaded[name] {
+			clone.modules[name] = executor
+		}
+	}
+	// The cache and mutex modules are bound to the VM executing them
+	for name, factory := range vm.moduleFactories {
+		if name != "cache" && !vm.builtinLoaded[name] {
+			clone.moduleFactories[name] = factory
+		}
+	}
+	for name, constants := range vm.moduleConstants {
+		clone.moduleConstants[name] = constants
+	}
+	for name, fn := range vm.overrides {
+		clone.overrides[name] = fn
+	}
+	for t, fields := range vm.allowedTypes {
+		clone.allowedTypes[t] = fields
+	}
+	for t := range vm.objectTypes {
+		clone.objectTypes[t] = true
+	}
+	clone.allowedModules = vm.allowedModules
+	clone.deniedModules = vm.deniedModules
+
+	values, valueTypes := vm.GlobalCtx.GetAllVariablesWithTypes()
+	for name, value := range values {
+		clone.GlobalCtx.CreateVariableWithType(name, value, valueTypes[name])
+	}
+
+	clone.maxInstructions = vm.maxInstructions
+	clone.budgetThresholds = vm.budgetThresholds
+	clone.budgetHandler = vm.budgetHandler
+	clone.debug = vm.debug
+	clone.coercion = vm.coercion
+	clone.divisionMode = vm.divisionMode
+	clone.strictConditions = vm.strictConditions
+	clone.boundaryApprover = vm.boundaryApprover
+	clone.stackInitialSize = vm.stackInitialSize
+	clone.stackMaxSize = vm.stackMaxSize
+	clone.maxCallDepth = vm.maxCallDepth
+	clone.timeout = vm.timeout
+	clone.maxMemory = vm.maxMemory
+	clone.profiling = vm.profiling
+	clone.SetCoverage(vm.coverage != nil)
+	clone.numberMode = vm.numberMode
+	clone.conversionMode = vm.conversionMode
+	clone.cacheSize = vm.cacheSize
+	clone.maxGoroutines = vm.maxGoroutines
+	clone.stdout = vm.stdout
+	clone.stderr = vm.stderr
+
+	// Builtin modules loaded for imports are loaded again, so they use the
+	// options of the clone, such as its output
+	for name := range vm.builtinLoaded {
+		clone.loadBuiltinModule(name, name)
+	}
+	return clone
+}
@@ -0,0 +1,227 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/context"
+)
+
+// Function values. A function literal compiles to an instruction set of its
+// own and a MAKE_CLOSURE instruction that captures the scope it is created
+// in; captured variables are shared with that scope, as in Go. Host and
+// module functions are values too: LOAD_NAME of a function name that is not
+// a variable (e.g., strings.ToUpper) pushes a HostFunction. A CALL without a
+// function name calls the function value below its arguments. Function
+// values are types.Callable, so host functions they are passed to can call
+// back into the script (e.g., sort.Slice(s, less)).
+
+// Closure is a function value: a function literal with the scope it
+// captured, or a script function used as a value (e.g., apply(double, 3))
+type Closure struct {
+	// Key is the instruction set of the function
+	Key string
+
+	// Env is the captured scope, nil for declared functions
+	Env *context.Context
+
+	// VM the function value was created in, which runs its calls
+	vm *VM
+}
+
+// String returns the string representation of a function value
+func (c *Closure) String() string {
+	return fmt.Sprintf("func(%s)", c.Key)
+}
+
+// Call calls the function from a host function it was passed to, running
+// it to completion in the VM it was created in
+func (c *Closure) Call(args ...interface{}) (interface{}, error) {
+	if c.vm == nil {
+		return nil, fmt.Errorf("cannot call %s outside of its VM", c)
+	}
+	return c.vm.callClosure(c, args)
+}
+
+// HostFunction is a function value of a registered host function, e.g., a
+// module function assigned to a variable (f := strings.ToUpper)
+type HostFunction struct {
+	// Name is the name the function is called by, e.g., "strings.ToUpper"
+	Name string
+
+	// VM the function value was created in
+	vm *VM
+}
+
+// String returns the string representation of a host function value
+func (f *HostFunction) String() string {
+	return fmt.Sprintf("func(%s)", f.Name)
+}
+
+// Call calls the function from a host function it was passed to
+func (f *HostFunction) Call(args ...interface{}) (interface{}, error) {
+	if f.vm == nil {
+		return nil, fmt.Errorf("cannot call %s outside of its VM", f)
+	}
+	return f.vm.callFunction(f.Name, args)
+}
+
+// functionValue returns the value of a function name that is not a
+// variable: a closure of a script function or a host function
+func (vm *VM) functionValue(name string) (interface{}, bool) {
+	if info := vm.lookupScriptFunction(name); info != nil {
+		return &Closure{Key: info.Key, vm: vm}, true
+	}
+	if _, exists := vm.GetFunction(name); exists {
+		return &HostFunction{Name: name, vm: vm}, true
+	}
+	return nil, false
+}
+
+// isCallable reports whether a value is a function value
+func isCallable(value interface{}) bool {
+	switch value.(type) {
+	case *Closure, *HostFunction:
+		return true
+	}
+	return false
+}
+
+// RegisterFunctionLiteral registers the parameters of a function literal
+// compiled into the instruction set info.Key
+func (vm *VM) RegisterFunctionLiteral(info *ScriptFunctionInfo) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.functionLiterals == nil {
+		vm.functionLiterals = make(map[string]*ScriptFunctionInfo)
+	}
+	vm.functionLiterals[info.Key] = info
+}
+
+// closureInfo returns the function information of a function value
+func (vm *VM) closureInfo(key string) *ScriptFunctionInfo {
+	vm.mu.RLock()
+	info, exists := vm.functionLiterals[key]
+	vm.mu.RUnlock()
+	if exists {
+		return info
+	}
+	return vm.lookupScriptFunction(key)
+}
+
+// handleMakeClosure handles the MAKE_CLOSURE opcode
+func (exec *Executor) handleMakeClosure(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	key, ok := instr.Arg.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid function key for MAKE_CLOSURE")
+	}
+	closure := &Closure{Key: key, vm: exec.vm}
+	// Function literals capture the current scope (Arg2 is true); declared
+	// functions see the scope of their caller, like direct calls
+	if capture, _ := instr.Arg2.(bool); capture {
+		closure.Env = exec.vm.currentCtx
+	}
+	stack.Push(closure)
+	return pc + 1, nil
+}
+
+// handleValueCall handles a CALL without a function name: the function
+// value is below the arguments on the stack
+func (exec *Executor) handleValueCall(stack *Stack, argCount int, pc int) (int, error) {
+	args, err := exec.prepareArguments(stack, argCount)
+	if err != nil {
+		return 0, fmt.Errorf("error preparing arguments for function value call: %w", err)
+	}
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for function value call")
+	}
+	return exec.pushValueCall(stack, stack.Pop(), args, pc)
+}
+
+// pushValueCall calls a function value and pushes its result. Closures
+// run in a frame of the executor loop.
+func (exec *Executor) pushValueCall(stack *Stack, fn interface{}, args []interface{}, pc int) (int, error) {
+	if closure, ok := fn.(*Closure); ok {
+		call, err := exec.vm.closureCall(closure, args)
+		if err != nil {
+			return 0, err
+		}
+		return pc + 1, exec.enterCall(call, "error executing function %s: %w", closure.String())
+	}
+	result, err := exec.vm.callValue(fn, args)
+	if err != nil {
+		return 0, err
+	}
+	if result != nil {
+		stack.Push(result)
+	}
+	return pc + 1, nil
+}
+
+// callValue calls a function value with the given arguments
+func (vm *VM) callValue(fn interface{}, args []interface{}) (interface{}, error) {
+	switch fn := fn.(type) {
+	case *Closure:
+		return vm.callClosure(fn, args)
+	case *HostFunction:
+		return vm.callFunction(fn.Name, args)
+	}
+	return nil, fmt.Errorf("cannot call non-function %v (%T)", fn, fn)
+}
+
+// callClosure calls a closure with the given arguments
+func (vm *VM) callClosure(closure *Closure, args []interface{}) (interface{}, error) {
+	call, err := vm.closureCall(closure, args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := vm.runCall(call)
+	if err != nil {
+		return nil, fmt.Errorf("error executing function %s: %w", closure, err)
+	}
+	return result, nil
+}
+
+// closureCall binds the arguments of a call of a closure to its parameters
+// in a new scope enclosed by the closure's environment
+func (vm *VM) closureCall(closure *Closure, args []interface{}) (scriptCall, error) {
+	info := vm.closureInfo(closure.Key)
+	instructions, exists := vm.GetInstructionSet(closure.Key)
+	if info == nil || !exists {
+		return scriptCall{}, fmt.Errorf("undefined function: %s", closure.Key)
+	}
+	if info.Variadic {
+		var err error
+		if args, err = packVariadic(info, args); err != nil {
+			return scriptCall{}, err
+		}
+	}
+	if len(args) < len(info.ParamNames) {
+		return scriptCall{}, fmt.Errorf("not enough arguments in call to %s", closure)
+	}
+	if len(args) > len(info.ParamNames) {
+		return scriptCall{}, fmt.Errorf("too many arguments in call to %s", closure)
+	}
+
+	env := closure.Env
+	if env == nil {
+		env = vm.currentCtx
+	}
+	args = copyValues(args)
+	functionCtx := context.NewContext(closure.Key, env)
+	for i, arg := range args {
+		functionCtx.CreateVariableWithType(info.ParamNames[i], arg, "unknown")
+	}
+
+	return scriptCall{key: closure.Key, instructions: instructions, ctx: functionCtx, args: args}, nil
+}
+
+// lookupFunctionValue returns the function value held by a variable, for
+// calls by name that match no function (e.g., a range variable)
+func (exec *Executor) lookupFunctionValue(name string) (interface{}, bool) {
+	value, exists := exec.vm.currentCtx.GetVariable(name)
+	if !exists || !isCallable(value) {
+		return nil, false
+	}
+	return value, true
+}
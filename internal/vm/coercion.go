@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Coercion mode (disabled by default) relaxes the strict Go-like typing of
+// binary operations for scripts ported from dynamically typed languages.
+// When enabled, operands are coerced before the operation runs:
+//
+//   - "+" with a string and a number converts the number to its string form
+//     and concatenates: "5" + 1 == "51", 1 + "5" == "15".
+//   - "-", "*", "/", "%" parse string operands as numbers: "5" - 1 == 4.
+//     A string that does not parse as a number is an error.
+//   - Ordered comparisons (<, <=, >, >=) and equality (==, !=) between a
+//     string and a number compare numerically: "5" == 5 is true. If the
+//     string does not parse, equality is false and ordering is an error.
+//   - Operations on two strings or two numbers are never coerced, except
+//     that "-", "*", "/", "%" on two numeric strings compute numerically.
+//
+// Integers that fit in int stay int; everything else becomes float64. The
+// coerced operands then follow the rules of numericOp.
+
+// SetCoercion enables or disables automatic string/number coercion
+func (vm *VM) SetCoercion(enabled bool) {
+	vm.coercion = enabled
+}
+
+// GetCoercion reports whether coercion mode is enabled
+func (vm *VM) GetCoercion() bool {
+	return vm.coercion
+}
+
+// coerceOperands applies the coercion rules to the operands of a binary op
+func coerceOperands(op instruction.BinaryOp, left, right interface{}) (interface{}, interface{}, error) {
+	ls, lIsStr := left.(string)
+	rs, rIsStr := right.(string)
+	lIsNum := isNumber(left)
+	rIsNum := isNumber(right)
+
+	switch op {
+	case instruction.OpAdd:
+		if lIsStr && rIsNum {
+			return left, formatNumber(right), nil
+		}
+		if lIsNum && rIsStr {
+			return formatNumber(left), right, nil
+		}
+
+	case instruction.OpSub, instruction.OpMul, instruction.OpDiv, instruction.OpMod:
+		if lIsStr && (rIsNum || rIsStr) {
+			n, ok := parseNumber(ls)
+			if !ok {
+				return nil, nil, fmt.Errorf("cannot coerce %q to a number", ls)
+			}
+			left = n
+		}
+		if rIsStr && (lIsNum || lIsStr) {
+			n, ok := parseNumber(rs)
+			if !ok {
+				return nil, nil, fmt.Errorf("cannot coerce %q to a number", rs)
+			}
+			right = n
+		}
+
+	case instruction.OpLess, instruction.OpLessEqual, instruction.OpGreater, instruction.OpGreaterEqual:
+		if lIsStr && rIsNum {
+			n, ok := parseNumber(ls)
+			if !ok {
+				return nil, nil, fmt.Errorf("cannot coerce %q to a number", ls)
+			}
+			left = n
+		}
+		if lIsNum && rIsStr {
+			n, ok := parseNumber(rs)
+			if !ok {
+				return nil, nil, fmt.Errorf("cannot coerce %q to a number", rs)
+			}
+			right = n
+		}
+
+	case instruction.OpEqual, instruction.OpNotEqual:
+		if lIsStr && rIsNum {
+			if n, ok := parseNumber(ls); ok {
+				left = n
+			}
+		}
+		if lIsNum && rIsStr {
+			if n, ok := parseNumber(rs); ok {
+				right = n
+			}
+		}
+	}
+
+	return left, right, nil
+}
+
+// isNumber reports whether a value is one of the VM's numeric types
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int, float64:
+		return true
+	}
+	return false
+}
+
+// parseNumber parses a string as an int if possible, otherwise as a float64
+func parseNumber(s string) (interface{}, bool) {
+	if i, err := strconv.Atoi(s); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// formatNumber converts a numeric value to its shortest string form
+func formatNumber(v interface{}) string {
+	switch n := v.(type) {
+	case int:
+		return strconv.Itoa(n)
+	case float64:
+		return strconv.FormatFloat(n, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
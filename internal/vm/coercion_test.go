@@ -0,0 +1,71 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+func TestCoercionDisabledByDefault(t *testing.T) {
+	vm := NewVM()
+
+	if _, err := vm.executeBinaryOp(instruction.OpAdd, "5", 1); err == nil {
+		t.Error("Expected error for string + int without coercion")
+	}
+	result, err := vm.executeBinaryOp(instruction.OpEqual, "5", 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != false {
+		t.Errorf("Expected \"5\" == 5 to be false without coercion, got %v", result)
+	}
+}
+
+func TestCoercionBinaryOps(t *testing.T) {
+	vm := NewVM()
+	vm.SetCoercion(true)
+
+	tests := []struct {
+		op       instruction.BinaryOp
+		left     interface{}
+		right    interface{}
+		expected interface{}
+	}{
+		{instruction.OpAdd, "5", 1, "51"},
+		{instruction.OpAdd, 1, "5", "15"},
+		{instruction.OpAdd, "x", 1.5, "x1.5"},
+		{instruction.OpSub, "5", 1, 4},
+		{instruction.OpMul, "2.5", 2, 5.0},
+		{instruction.OpDiv, "10", "2", 5},
+		{instruction.OpMod, 7, "4", 3},
+		{instruction.OpEqual, "5", 5, true},
+		{instruction.OpEqual, 5.0, 5, true},
+		{instruction.OpNotEqual, "abc", 5, true},
+		{instruction.OpLess, "3", 10, true},
+		{instruction.OpGreaterEqual, 2, "2", true},
+		{instruction.OpAdd, "a", "b", "ab"},
+	}
+
+	for _, tt := range tests {
+		result, err := vm.executeBinaryOp(tt.op, tt.left, tt.right)
+		if err != nil {
+			t.Errorf("op %d (%v, %v): unexpected error: %v", tt.op, tt.left, tt.right, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("op %d (%v, %v): expected %v (%T), got %v (%T)", tt.op, tt.left, tt.right, tt.expected, tt.expected, result, result)
+		}
+	}
+}
+
+func TestCoercionInvalidNumber(t *testing.T) {
+	vm := NewVM()
+	vm.SetCoercion(true)
+
+	if _, err := vm.executeBinaryOp(instruction.OpSub, "abc", 1); err == nil {
+		t.Error("Expected error when coercing non-numeric string")
+	}
+	if _, err := vm.executeBinaryOp(instruction.OpLess, "abc", 1); err == nil {
+		t.Error("Expected error when ordering non-numeric string against number")
+	}
+}
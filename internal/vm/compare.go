@@ -0,0 +1,279 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/types"
+)
+
+// Comparison. == and != follow Go's rules for composite values, in
+// expressions and switch cases alike:
+//
+//   - Slices, maps and functions can only be compared to nil; comparing
+//     them to anything else is an error.
+//   - Script structs are equal when they have the same type and their
+//     fields are equal, zero values standing in for fields that were never
+//     set. Structs with slice, map or function fields cannot be compared.
+//   - Struct pointers are equal when they point to the same struct.
+//   - Values of different types are not equal; other host values are
+//     compared as Go would compare them.
+//
+// < <= > >= are defined on numbers and strings only. deepEqual(a, b)
+// compares slices and maps element by element and follows pointers.
+
+// builtinDeepEqual implements deepEqual(a, b)
+func (vm *VM) builtinDeepEqual(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("deepEqual expects 2 arguments, got %d", len(args))
+	}
+	return vm.deepEqual(args[0], args[1], make(map[[2]*types.Struct]bool))
+}
+
+// equal reports whether left == right
+func (vm *VM) equal(left, right interface{}) (bool, error) {
+	if left == nil || right == nil {
+		return isNil(left) && isNil(right), nil
+	}
+	for _, v := range []interface{}{left, right} {
+		if kind := uncomparableKind(v); kind != "" {
+			return false, fmt.Errorf("invalid operation: %s can only be compared to nil", kind)
+		}
+	}
+
+	if l, ok := left.(*types.Struct); ok {
+		r, ok := right.(*types.Struct)
+		if !ok {
+			return false, nil
+		}
+		if l.Pointer || r.Pointer {
+			return l.Pointer && r.Pointer && l.Same(r), nil
+		}
+		return vm.structsEqual(l, r)
+	}
+	if reflect.TypeOf(left) != reflect.TypeOf(right) {
+		return false, nil
+	}
+	return hostEqual(left, right)
+}
+
+// structsEqual reports whether two script structs are equal under ==
+func (vm *VM) structsEqual(l, r *types.Struct) (bool, error) {
+	if l.Type != r.Type {
+		return false, nil
+	}
+	if !vm.comparableStruct(l) || !vm.comparableStruct(r) {
+		return false, fmt.Errorf("invalid operation: %s cannot be compared", l.Type)
+	}
+
+	for _, field := range vm.comparedFields(l, r) {
+		lv, rv := vm.fieldValue(l, field), vm.fieldValue(r, field)
+		result, err := vm.executeBinaryOp(instruction.OpEqual, lv, rv)
+		if err != nil {
+			return false, fmt.Errorf("comparing field %s.%s: %w", l.Type, field.Name, err)
+		}
+		if equal, _ := result.(bool); !equal {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// deepEqual reports whether two values are deeply equal: slices and maps
+// are compared element by element, struct fields are followed through
+// pointers, and other values are compared with ==. visited holds the
+// pairs of structs being compared, so cyclic values terminate.
+func (vm *VM) deepEqual(left, right interface{}, visited map[[2]*types.Struct]bool) (bool, error) {
+	switch l := left.(type) {
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false, nil
+		}
+		for i := range l {
+			if equal, err := vm.deepEqual(l[i], r[i], visited); err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case []byte:
+		r, ok := right.([]byte)
+		return ok && bytes.Equal(l, r), nil
+
+	case *types.Struct:
+		r, ok := right.(*types.Struct)
+		if !ok || l.Type != r.Type {
+			return false, nil
+		}
+		pair := [2]*types.Struct{l, r}
+		if l.Same(r) || visited[pair] {
+			return true, nil
+		}
+		visited[pair] = true
+		for _, field := range vm.comparedFields(l, r) {
+			if equal, err := vm.deepEqual(vm.fieldValue(l, field), vm.fieldValue(r, field), visited); err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	if left != nil && right != nil {
+		lv, rv := reflect.ValueOf(left), reflect.ValueOf(right)
+		if lv.Kind() == reflect.Map && rv.Kind() == reflect.Map {
+			if lv.Type() != rv.Type() || lv.Len() != rv.Len() {
+				return false, nil
+			}
+			iter := lv.MapRange()
+			for iter.Next() {
+				value := rv.MapIndex(iter.Key())
+				if !value.IsValid() {
+					return false, nil
+				}
+				if equal, err := vm.deepEqual(iter.Value().Interface(), value.Interface(), visited); err != nil || !equal {
+					return false, err
+				}
+			}
+			return true, nil
+		}
+	}
+
+	result, err := vm.executeBinaryOp(instruction.OpEqual, left, right)
+	if err != nil {
+		// Host values == does not apply to, such as []string
+		return reflect.DeepEqual(left, right), nil
+	}
+	equal, _ := result.(bool)
+	return equal, nil
+}
+
+// orderedOp applies < <= > >= to two strings. handled is false for other
+// operators and operands; ordering slices, maps, functions, structs and
+// bools is an error.
+func orderedOp(op instruction.BinaryOp, left, right interface{}) (result interface{}, handled bool, err error) {
+	switch op {
+	case instruction.OpLess, instruction.OpLessEqual, instruction.OpGreater, instruction.OpGreaterEqual:
+	default:
+		return nil, false, nil
+	}
+
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case instruction.OpLess:
+				return l < r, true, nil
+			case instruction.OpLessEqual:
+				return l <= r, true, nil
+			case instruction.OpGreater:
+				return l > r, true, nil
+			default:
+				return l >= r, true, nil
+			}
+		}
+	}
+
+	for _, v := range []interface{}{left, right} {
+		kind := uncomparableKind(v)
+		switch v.(type) {
+		case *types.Struct:
+			kind = "struct"
+		case bool:
+			kind = "bool"
+		}
+		if kind != "" {
+			return nil, true, fmt.Errorf("invalid operation: operator %s not defined on %s", op, kind)
+		}
+	}
+	return nil, false, nil
+}
+
+// comparedFields returns the fields two structs of the same type are
+// compared by: the declared fields of the type, or the fields set in
+// either struct when the type was not declared by a script
+func (vm *VM) comparedFields(l, r *types.Struct) []StructField {
+	if fields, ok := vm.StructType(l.Type); ok {
+		return fields
+	}
+
+	names := make([]string, 0, len(l.Fields))
+	for name := range l.Fields {
+		names = append(names, name)
+	}
+	for name := range r.Fields {
+		if _, ok := l.Fields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fields := make([]StructField, len(names))
+	for i, name := range names {
+		fields[i] = StructField{Name: name}
+	}
+	return fields
+}
+
+// fieldValue returns a field of a struct, or the zero value of its type
+// when the field was never set
+func (vm *VM) fieldValue(s *types.Struct, field StructField) interface{} {
+	if value, ok := s.Fields[field.Name]; ok {
+		return value
+	}
+	return vm.zeroOf(field.Type)
+}
+
+// comparableType reports whether values of a declared type can be compared
+// with ==
+func comparableType(typ string) bool {
+	return !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && !strings.HasPrefix(typ, "func")
+}
+
+// uncomparableKind returns "slice", "map" or "func" for values that can only
+// be compared to nil, and "" for other values
+func uncomparableKind(v interface{}) string {
+	if isCallable(v) {
+		return "func"
+	}
+	if v == nil {
+		return ""
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Slice:
+		return "slice"
+	case reflect.Map:
+		return "map"
+	case reflect.Func:
+		return "func"
+	}
+	return ""
+}
+
+// isNil reports whether a value is nil or a nil slice, map, function,
+// pointer or channel
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Func, reflect.Pointer, reflect.Chan, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// hostEqual compares two host values of the same type with ==, which
+// panics for struct types holding slices, maps or functions
+func hostEqual(left, right interface{}) (equal bool, err error) {
+	defer func() {
+		if recover() != nil {
+			equal, err = false, fmt.Errorf("invalid operation: %T cannot be compared", left)
+		}
+	}()
+	return left == right, nil
+}
@@ -0,0 +1,47 @@
+package vm
+
+import "fmt"
+
+// Conditions are permissive by default: where a condition is expected (if,
+// for, switch cases, && and ||) nil, false, 0, 0.0 and "" are false and
+// every other value is true. Strict mode follows Go instead and requires a
+// bool; any other value is a runtime error. The compiler also rejects
+// constant non-bool conditions (e.g., if 1 {}) when strict mode is enabled
+// before the script is compiled.
+
+// SetStrictConditions enables or disables strict (bool-only) conditions
+func (vm *VM) SetStrictConditions(enabled bool) {
+	vm.strictConditions = enabled
+}
+
+// GetStrictConditions reports whether strict conditions are enabled
+func (vm *VM) GetStrictConditions() bool {
+	return vm.strictConditions
+}
+
+// condition converts a condition value to a bool according to the mode
+func (vm *VM) condition(value interface{}) (bool, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	if vm.strictConditions {
+		return false, fmt.Errorf("non-boolean condition: %v (%T)", value, value)
+	}
+	return isTruthy(value), nil
+}
+
+// logicalOp evaluates && and || on operands that are already evaluated
+func (vm *VM) logicalOp(and bool, left, right interface{}) (interface{}, error) {
+	l, err := vm.condition(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := vm.condition(right)
+	if err != nil {
+		return nil, err
+	}
+	if and {
+		return l && r, nil
+	}
+	return l || r, nil
+}
@@ -0,0 +1,289 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Argument conversion. Script values are ints, float64s, strings, bools,
+// []interface{}, map[string]interface{} and script structs, while Go
+// functions registered with RegisterGoFunction and the methods of host
+// objects take typed parameters. Arguments are converted to the parameter
+// types, recursively for the elements of slices, arrays and maps and the
+// fields of structs; how lenient the conversion is depends on the
+// conversion mode of the VM. Conversion errors name the element or field
+// that failed, e.g. "element 2: field Port: cannot use "x" (string) as int".
+
+// ConversionMode selects how script values are converted to the parameter
+// types of Go functions and host object methods
+type ConversionMode int
+
+const (
+	// ConvertSafe converts numbers that keep their value (1 to float64, 2.0
+	// to int, but not 1.5 to int or 300 to int8), strings to named string
+	// types, and script structs and maps to Go structs by field name,
+	// matched case-insensitively if there is no exact match
+	ConvertSafe ConversionMode = iota
+
+	// ConvertStrict converts numbers only between integer types and
+	// between floating-point types, matches struct fields exactly and
+	// rejects fields the Go struct does not have
+	ConvertStrict
+
+	// ConvertLenient converts like ConvertSafe, and also truncates numbers
+	// to integers (1.9 to 1), rounds them to float32, formats numbers as
+	// strings and parses numeric strings as numbers. Numbers out of the
+	// range of the parameter type are still rejected.
+	ConvertLenient
+)
+
+// String returns the name of a conversion mode
+func (m ConversionMode) String() string {
+	switch m {
+	case ConvertSafe:
+		return "safe"
+	case ConvertStrict:
+		return "strict"
+	case ConvertLenient:
+		return "lenient"
+	default:
+		return fmt.Sprintf("ConversionMode(%d)", int(m))
+	}
+}
+
+// SetConversionMode sets how arguments are converted to the parameter types
+// of Go functions and host object methods. Defaults to ConvertSafe.
+func (vm *VM) SetConversionMode(mode ConversionMode) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.conversionMode = mode
+}
+
+// GetConversionMode returns the current argument conversion mode
+func (vm *VM) GetConversionMode() ConversionMode {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.conversionMode
+}
+
+// Convert converts a script value to a T as the arguments of Go functions
+// are converted in ConvertSafe mode, e.g. in a types.Function:
+//
+//	ports, err := vm.Convert[[]int](args[0])
+func Convert[T any](value interface{}) (T, error) {
+	return ConvertWithMode[T](value, ConvertSafe)
+}
+
+// ConvertWithMode converts a script value to a T in the given mode
+func ConvertWithMode[T any](value interface{}, mode ConversionMode) (T, error) {
+	var out T
+	converted, err := converter{mode: mode}.convert(value, reflect.TypeOf(&out).Elem())
+	if err != nil {
+		return out, err
+	}
+	reflect.ValueOf(&out).Elem().Set(converted)
+	return out, nil
+}
+
+// converter converts script values to Go types in a conversion mode
+type converter struct {
+	mode ConversionMode
+}
+
+// hostArg converts a script value to a Go type in ConvertSafe mode
+func hostArg(value interface{}, t reflect.Type) (reflect.Value, error) {
+	return converter{mode: ConvertSafe}.convert(value, t)
+}
+
+// convert converts a script value to a Go type
+func (c converter) convert(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use nil as %s", t)
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	switch {
+	case isNumberKind(rv.Kind()) && isNumberKind(t.Kind()):
+		return c.number(rv, t)
+	case rv.Kind() == reflect.String && t.Kind() == reflect.String:
+		return rv.Convert(t), nil
+	case c.mode == ConvertLenient && isNumberKind(rv.Kind()) && t.Kind() == reflect.String:
+		return reflect.ValueOf(formatNumber(value)).Convert(t), nil
+	case c.mode == ConvertLenient && rv.Kind() == reflect.String && isNumberKind(t.Kind()):
+		number, ok := parseNumber(strings.TrimSpace(rv.String()))
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("cannot use %q as %s: not a number", rv.String(), t)
+		}
+		return c.number(reflect.ValueOf(number), t)
+	case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && t.Kind() == reflect.Slice:
+		slice := reflect.MakeSlice(t, rv.Len(), rv.Len())
+		if err := c.elements(rv, slice); err != nil {
+			return reflect.Value{}, err
+		}
+		return slice, nil
+	case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && t.Kind() == reflect.Array:
+		if rv.Len() != t.Len() {
+			return reflect.Value{}, fmt.Errorf("cannot use %d elements as %s", rv.Len(), t)
+		}
+		array := reflect.New(t).Elem()
+		if err := c.elements(rv, array); err != nil {
+			return reflect.Value{}, err
+		}
+		return array, nil
+	case t.Kind() == reflect.Struct:
+		if fields, ok := types.StructFields(value); ok {
+			return c.structValue(fields, t)
+		}
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
+		if fields, ok := types.StructFields(value); ok {
+			s, err := c.structValue(fields, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			ptr := reflect.New(t.Elem())
+			ptr.Elem().Set(s)
+			return ptr, nil
+		}
+	case t.Kind() == reflect.Map:
+		if s, ok := value.(*types.Struct); ok {
+			// The fields of a script struct, e.g. for a map[string]any
+			rv = reflect.ValueOf(s.Fields)
+		}
+		if rv.Kind() != reflect.Map {
+			break
+		}
+		m := reflect.MakeMapWithSize(t, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := c.convert(iter.Key().Interface(), t.Key())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			elem, err := c.convert(iter.Value().Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			m.SetMapIndex(key, elem)
+		}
+		return m, nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %v (%T) as %s", value, value, t)
+}
+
+// elements converts the elements of a slice or array into dst
+func (c converter) elements(src, dst reflect.Value) error {
+	for i := 0; i < src.Len(); i++ {
+		elem, err := c.convert(src.Index(i).Interface(), dst.Type().Elem())
+		if err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+		dst.Index(i).Set(elem)
+	}
+	return nil
+}
+
+// number converts a number to a numeric type. The value must be kept,
+// except that ConvertLenient truncates to integers and rounds to float32,
+// and ConvertStrict does not convert between integers and floats.
+func (c converter) number(rv reflect.Value, t reflect.Type) (reflect.Value, error) {
+	fromFloat, toFloat := isFloatKind(rv.Kind()), isFloatKind(t.Kind())
+	if c.mode == ConvertStrict && fromFloat != toFloat {
+		return reflect.Value{}, fmt.Errorf("cannot use %v (%s) as %s in strict mode", rv, rv.Type(), t)
+	}
+	value := numberValue(rv)
+	if c.mode == ConvertLenient {
+		switch {
+		case math.IsNaN(value) || math.IsInf(value, 0):
+		case !toFloat:
+			value = math.Trunc(value)
+			rv = reflect.ValueOf(value)
+		case t.Kind() == reflect.Float32 && math.Abs(value) <= math.MaxFloat32:
+			return reflect.ValueOf(value).Convert(t), nil
+		}
+	}
+	converted := rv.Convert(t)
+	// Numbers must keep their value, e.g. 1.5 is not an int and 300 is not
+	// an int8
+	if numberValue(converted) != value {
+		return reflect.Value{}, fmt.Errorf("cannot use %v as %s without changing its value", rv, t)
+	}
+	return converted, nil
+}
+
+// structValue converts the fields of a script struct or map to a Go
+// struct: each exported field takes the value of the field of the same
+// name, matched case-insensitively if there is no exact match except in
+// ConvertStrict mode, where fields the Go struct does not have are errors
+func (c converter) structValue(fields map[string]interface{}, t reflect.Type) (reflect.Value, error) {
+	s := reflect.New(t).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value, found := fields[field.Name]
+		if !found && c.mode != ConvertStrict {
+			for name, v := range fields {
+				if strings.EqualFold(name, field.Name) {
+					value, found = v, true
+					break
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		converted, err := c.convert(value, field.Type)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		s.Field(i).Set(converted)
+	}
+	if c.mode == ConvertStrict {
+		for name := range fields {
+			if field, ok := t.FieldByName(name); !ok || !field.IsExported() {
+				return reflect.Value{}, fmt.Errorf("unknown field %s in %s", name, t)
+			}
+		}
+	}
+	return s, nil
+}
+
+// numberValue returns a number as a float64
+func numberValue(rv reflect.Value) float64 {
+	switch {
+	case rv.CanInt():
+		return float64(rv.Int())
+	case rv.CanUint():
+		return float64(rv.Uint())
+	default:
+		return rv.Float()
+	}
+}
+
+// isNumberKind reports whether a kind is an integer or floating-point kind
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isFloatKind reports whether a kind is a floating-point kind
+func isFloatKind(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
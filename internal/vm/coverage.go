@@ -0,0 +1,184 @@
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Coverage. When enabled, the VM counts how often each statement of the
+// program starts. Counts accumulate across executions until they are reset,
+// so calling several functions of a script library, e.g. from its tests,
+// collects one report. Script modules are compiled into VMs of their own
+// and are not covered.
+
+// Coverage is the statement coverage of a program
+type Coverage struct {
+	// Statements holds every statement of the program with the number of
+	// times it ran, sorted by position
+	Statements []StatementCoverage
+}
+
+// StatementCoverage is the coverage of a statement
+type StatementCoverage struct {
+	// Location is the first instruction of the statement and Pos its
+	// source position
+	Location Location
+	Pos      token.Position
+
+	// Hits is the number of times the statement ran
+	Hits int64
+}
+
+// LineCoverage is the coverage of a source line
+type LineCoverage struct {
+	File string
+	Line int
+
+	// Statements is the number of statements starting on the line and
+	// Covered the number of them that ran
+	Statements int
+	Covered    int
+
+	// Hits is the number of times the most frequent statement of the line
+	// ran
+	Hits int64
+}
+
+// SetCoverage enables or disables coverage counting. Enabling it keeps the
+// counts collected so far.
+func (vm *VM) SetCoverage(enabled bool) {
+	switch {
+	case !enabled:
+		vm.coverage = nil
+	case vm.coverage == nil:
+		vm.coverage = make(map[*instruction.Instruction]int64)
+	}
+}
+
+// ResetCoverage discards the counts collected so far
+func (vm *VM) ResetCoverage() {
+	if vm.coverage != nil {
+		vm.coverage = make(map[*instruction.Instruction]int64)
+	}
+}
+
+// Coverage returns the coverage collected since coverage was enabled or
+// last reset, or nil when it is disabled
+func (vm *VM) Coverage() *Coverage {
+	if vm.coverage == nil {
+		return nil
+	}
+	coverage := &Coverage{}
+	for key, instructions := range vm.InstructionSets {
+		for pc, instr := range instructions {
+			if !instr.StmtStart || instr.Line <= 0 {
+				continue
+			}
+			coverage.Statements = append(coverage.Statements, StatementCoverage{
+				Location: Location{Function: key, Index: pc},
+				Pos:      token.Position{Filename: instr.File, Line: instr.Line, Column: instr.Column},
+				Hits:     vm.coverage[instr],
+			})
+		}
+	}
+	sort.Slice(coverage.Statements, func(i, j int) bool {
+		a, b := coverage.Statements[i], coverage.Statements[j]
+		if a.Pos.Filename != b.Pos.Filename {
+			return a.Pos.Filename < b.Pos.Filename
+		}
+		if a.Pos.Line != b.Pos.Line {
+			return a.Pos.Line < b.Pos.Line
+		}
+		if a.Pos.Column != b.Pos.Column {
+			return a.Pos.Column < b.Pos.Column
+		}
+		if a.Location.Function != b.Location.Function {
+			return a.Location.Function < b.Location.Function
+		}
+		return a.Location.Index < b.Location.Index
+	})
+	return coverage
+}
+
+// countStatement records that a statement starts with instr
+func (vm *VM) countStatement(instr *instruction.Instruction) {
+	if instr.StmtStart {
+		vm.coverage[instr]++
+	}
+}
+
+// Lines returns the coverage of each line statements start on, sorted by
+// file and line
+func (c *Coverage) Lines() []LineCoverage {
+	var lines []LineCoverage
+	for _, stmt := range c.Statements {
+		n := len(lines)
+		if n == 0 || lines[n-1].File != stmt.Pos.Filename || lines[n-1].Line != stmt.Pos.Line {
+			lines = append(lines, LineCoverage{File: stmt.Pos.Filename, Line: stmt.Pos.Line})
+			n++
+		}
+		line := &lines[n-1]
+		line.Statements++
+		if stmt.Hits > 0 {
+			line.Covered++
+		}
+		if stmt.Hits > line.Hits {
+			line.Hits = stmt.Hits
+		}
+	}
+	return lines
+}
+
+// Percent returns the percentage of statements that ran (100 for a program
+// without statements)
+func (c *Coverage) Percent() float64 {
+	if len(c.Statements) == 0 {
+		return 100
+	}
+	covered := 0
+	for _, stmt := range c.Statements {
+		if stmt.Hits > 0 {
+			covered++
+		}
+	}
+	return float64(covered) * 100 / float64(len(c.Statements))
+}
+
+// WriteLCOV writes the line coverage in the LCOV tracefile format, one
+// record per source file
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	lines := c.Lines()
+	for i := 0; i < len(lines); {
+		file := lines[i].File
+		found, hit := 0, 0
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", file)
+		for ; i < len(lines) && lines[i].File == file; i++ {
+			fmt.Fprintf(bw, "DA:%d,%d\n", lines[i].Line, lines[i].Hits)
+			found++
+			if lines[i].Hits > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", found, hit)
+	}
+	return bw.Flush()
+}
+
+// WriteCoverProfile writes the statement coverage in the count mode of the
+// Go cover profile format, read by go tool cover and coverage services.
+// Statement end positions are not recorded, so each block spans from the
+// start of a statement to the start of the next line.
+func (c *Coverage) WriteCoverProfile(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "mode: count")
+	for _, stmt := range c.Statements {
+		fmt.Fprintf(bw, "%s:%d.%d,%d.1 1 %d\n", stmt.Pos.Filename, stmt.Pos.Line, stmt.Pos.Column, stmt.Pos.Line+1, stmt.Hits)
+	}
+	return bw.Flush()
+}
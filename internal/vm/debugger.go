@@ -0,0 +1,111 @@
+package vm
+
+import (
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Breakpoints. A pause handler is called when execution reaches a
+// statement on a breakpoint line, or the next statement after Step. The
+// handler receives a Debugger to inspect variables and to choose how
+// execution resumes; it may block, e.g., while an IDE waits for the user.
+
+// Debugger controls a paused execution
+type Debugger interface {
+	// Step pauses again before the next statement, entering called
+	// functions
+	Step()
+
+	// Continue runs until the next breakpoint; it is the default when the
+	// pause handler returns without choosing
+	Continue()
+
+	// Inspect returns the value of a variable visible to the paused
+	// statement
+	Inspect(name string) (interface{}, bool)
+}
+
+// PauseHandler is called when execution pauses. Returning an error stops
+// the script with that error.
+type PauseHandler func(debugger Debugger, event StepEvent) error
+
+// breakpoint is a source line of a function
+type breakpoint struct {
+	function string
+	line     int
+}
+
+// SetBreakpoint pauses execution before the statements starting on line
+// of the function with the given key (e.g., main.main). An empty key
+// matches every function.
+func (vm *VM) SetBreakpoint(funcKey string, line int) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.breakpoints == nil {
+		vm.breakpoints = make(map[breakpoint]bool)
+	}
+	vm.breakpoints[breakpoint{function: funcKey, line: line}] = true
+}
+
+// ClearBreakpoint removes a breakpoint set with SetBreakpoint
+func (vm *VM) ClearBreakpoint(funcKey string, line int) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	delete(vm.breakpoints, breakpoint{function: funcKey, line: line})
+}
+
+// SetPauseHandler sets the callback invoked when execution pauses. A nil
+// handler disables breakpoints.
+func (vm *VM) SetPauseHandler(handler PauseHandler) {
+	vm.pauseHandler = handler
+	vm.stepping = false
+}
+
+// pause calls the pause handler if instr starts a statement at a
+// breakpoint, or the debugger is stepping
+func (exec *Executor) pause(instr *instruction.Instruction, pc int) error {
+	vm := exec.vm
+	if !instr.StmtStart {
+		return nil
+	}
+	function := vm.currentFunction()
+	if !vm.stepping && !vm.isBreakpoint(function, instr.Line) {
+		return nil
+	}
+	// Execution continues to the next breakpoint unless the handler steps
+	vm.stepping = false
+	event := StepEvent{Line: instr.Line, PC: pc, Depth: vm.callDepth, Function: function, Instruction: instr}
+	return vm.pauseHandler(debugger{vm: vm}, event)
+}
+
+// isBreakpoint reports whether a breakpoint is set on a line of function
+func (vm *VM) isBreakpoint(function string, line int) bool {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.breakpoints[breakpoint{function: function, line: line}] || vm.breakpoints[breakpoint{line: line}]
+}
+
+// debugger is the Debugger of a paused VM
+type debugger struct {
+	vm *VM
+}
+
+// Step pauses again before the next statement
+func (d debugger) Step() {
+	d.vm.stepping = true
+}
+
+// Continue runs until the next breakpoint
+func (d debugger) Continue() {
+	d.vm.stepping = false
+}
+
+// Inspect returns the value of a variable visible to the paused statement
+func (d debugger) Inspect(name string) (interface{}, bool) {
+	if value, ok := d.vm.frameLocals()[name]; ok {
+		return value, true
+	}
+	if d.vm.currentCtx == nil {
+		return nil, false
+	}
+	return d.vm.currentCtx.GetVariable(name)
+}
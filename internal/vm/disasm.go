@@ -0,0 +1,67 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Disassemble returns an annotated listing of the instruction set with the
+// given key, or "" if there is none. Each line holds the index, the source
+// position and the disassembly of an instruction; jumps show their target
+// and jump targets are marked with ">":
+//
+//	main.main:
+//	     0  3:2      LOAD_CONST 0
+//	     1  3:2      STORE_NAME i
+//	>    2  4:5      LOAD_NAME i
+//	     3  4:5      LOAD_CONST 3
+//	     4  4:5      BINARY_OP <
+//	     5  4:5      JUMP_IF 9          ; -> 9
+func (vm *VM) Disassemble(key string) string {
+	instructions, exists := vm.GetInstructionSet(key)
+	if !exists {
+		return ""
+	}
+
+	targets := make(map[int]bool)
+	for _, instr := range instructions {
+		if target, ok := jumpTarget(instr); ok {
+			targets[target] = true
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n", key)
+	for pc, instr := range instructions {
+		marker := " "
+		if targets[pc] {
+			marker = ">"
+		}
+		pos := ""
+		if instr.Line > 0 {
+			pos = fmt.Sprintf("%d:%d", instr.Line, instr.Column)
+		}
+		text := instr.String()
+		if target, ok := jumpTarget(instr); ok {
+			if target == len(instructions) {
+				text = fmt.Sprintf("%-18s ; -> end", text)
+			} else {
+				text = fmt.Sprintf("%-18s ; -> %d", text, target)
+			}
+		}
+		fmt.Fprintf(&b, "%s%5d  %-8s %s\n", marker, pc, pos, text)
+	}
+	return b.String()
+}
+
+// jumpTarget returns the target of a jump instruction
+func jumpTarget(instr *instruction.Instruction) (int, bool) {
+	switch instr.Op {
+	case instruction.OpJump, instruction.OpJumpIf:
+		target, ok := instr.Arg.(int)
+		return target, ok
+	}
+	return 0, false
+}
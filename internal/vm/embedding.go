@@ -0,0 +1,212 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Embedded fields. The compiler records the fields of script struct types,
+// so fields and methods of embedded structs are promoted as in Go: a
+// selector that is not a field or method of a struct is looked up in its
+// embedded fields, the shallowest depth first, and is ambiguous if it is
+// found more than once at that depth. Embedded structs that were not set
+// in a literal are created when a promoted field is set or a promoted
+// method is called, like the zero value in Go.
+
+// StructField is a field of a script struct type
+type StructField = types.StructField
+
+// RegisterStructType records the fields of a script struct type
+func (vm *VM) RegisterStructType(name string, fields []StructField) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.structTypes == nil {
+		vm.structTypes = make(map[string][]StructField)
+	}
+	vm.structTypes[name] = fields
+}
+
+// StructType returns the fields of a script struct type, in declaration
+// order
+func (vm *VM) StructType(name string) ([]StructField, bool) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	fields, exists := vm.structTypes[name]
+	return fields, exists
+}
+
+// EmbeddedFields returns the embedded fields of a script struct type, in
+// declaration order
+func (vm *VM) EmbeddedFields(typeName string) []StructField {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	var embedded []StructField
+	for _, field := range vm.structTypes[typeName] {
+		if field.Embedded {
+			embedded = append(embedded, field)
+		}
+	}
+	return embedded
+}
+
+// selection is a field or method found through embedded fields: the path
+// of embedded fields leading to the struct that has it
+type selection struct {
+	path   []StructField
+	method bool
+}
+
+// promote looks up a field or method that a struct type does not declare
+// itself in its embedded fields. It reports whether one was found, and an
+// error if the selector is ambiguous.
+func (vm *VM) promote(typeName, name string) (selection, bool, error) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	type candidate struct {
+		typeName string
+		path     []StructField
+	}
+	seen := map[string]bool{typeName: true}
+	level := []candidate{{typeName: typeName}}
+	for len(level) > 0 {
+		var found []selection
+		var next []candidate
+		for _, cand := range level {
+			for _, field := range vm.structTypes[cand.typeName] {
+				if !field.Embedded {
+					continue
+				}
+				path := append(append([]StructField(nil), cand.path...), field)
+				if vm.declaresLocked(field.Type, name) {
+					found = append(found, selection{path: path, method: vm.methodSets[field.Type][name]})
+					continue
+				}
+				if !seen[field.Type] {
+					seen[field.Type] = true
+					next = append(next, candidate{typeName: field.Type, path: path})
+				}
+			}
+		}
+		switch {
+		case len(found) == 1:
+			return found[0], true, nil
+		case len(found) > 1:
+			return selection{}, false, fmt.Errorf("ambiguous selector %s.%s (through %s)", typeName, name, pathsString(found))
+		}
+		level = next
+	}
+	return selection{}, false, nil
+}
+
+// declaresLocked reports whether a script struct type declares a field or
+// method itself; the caller holds vm.mu
+func (vm *VM) declaresLocked(typeName, name string) bool {
+	if vm.methodSets[typeName][name] {
+		return true
+	}
+	for _, field := range vm.structTypes[typeName] {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pathsString formats the embedded fields leading to each selection, as in
+// "A, B.C"
+func pathsString(found []selection) string {
+	paths := make([]string, len(found))
+	for i, sel := range found {
+		names := make([]string, len(sel.path))
+		for j, field := range sel.path {
+			names[j] = field.Name
+		}
+		paths[i] = strings.Join(names, ".")
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ", ")
+}
+
+// embedded follows a path of embedded fields from a struct. Missing
+// embedded structs are created when create is set; otherwise it returns
+// nil for them.
+func embedded(s *types.Struct, path []StructField, create bool) *types.Struct {
+	for _, field := range path {
+		inner, ok := s.Fields[field.Name].(*types.Struct)
+		if !ok {
+			if !create {
+				return nil
+			}
+			inner = types.NewStruct(field.Type)
+			s.Fields[field.Name] = inner
+		}
+		s = inner
+	}
+	return s
+}
+
+// declares reports whether a script struct type declares a field or method
+// itself
+func (vm *VM) declares(typeName, name string) bool {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.declaresLocked(typeName, name)
+}
+
+// fieldType returns the declared type of a field of a script struct type,
+// or of the field promoted from one of its embedded structs
+func (vm *VM) fieldType(typeName, name string) (string, bool) {
+	fields, _ := vm.StructType(typeName)
+	for _, field := range fields {
+		if field.Name == name {
+			return field.Type, true
+		}
+	}
+	sel, found, err := vm.promote(typeName, name)
+	if err != nil || !found || sel.method {
+		return "", false
+	}
+	return vm.fieldType(sel.path[len(sel.path)-1].Type, name)
+}
+
+// fieldHolder returns the fields of the struct that holds a field of a
+// value: the value itself, or the embedded struct the field is promoted
+// from, or nil if that embedded struct is missing and create is not set.
+// Fields that are not found belong to the value itself.
+func (vm *VM) fieldHolder(value interface{}, fields map[string]interface{}, name string, create bool) (map[string]interface{}, error) {
+	s, ok := value.(*types.Struct)
+	if !ok {
+		return fields, nil
+	}
+	if _, exists := fields[name]; exists || vm.declares(s.Type, name) {
+		return fields, nil
+	}
+	sel, found, err := vm.promote(s.Type, name)
+	if err != nil || !found {
+		return fields, err
+	}
+	if holder := embedded(s, sel.path, create); holder != nil {
+		return holder.Fields, nil
+	}
+	return nil, nil
+}
+
+// methodReceiver returns the receiver of a method promoted from an
+// embedded struct of a value, or the value itself if its type has the
+// method or none of its embedded structs does
+func (vm *VM) methodReceiver(value interface{}, method string) (interface{}, error) {
+	s, ok := value.(*types.Struct)
+	if !ok || vm.hasMethod(value, method) {
+		return value, nil
+	}
+	sel, found, err := vm.promote(s.Type, method)
+	if err != nil || !found || !sel.method {
+		return value, err
+	}
+	return embedded(s, sel.path, true), nil
+}
@@ -5,8 +5,9 @@ import (
 	"strings"
 
 	"github.com/lengzhao/goscript/builtin"
-	execContext "github.com/lengzhao/goscript/context"
 	"github.com/lengzhao/goscript/instruction"
+	execContext "github.com/lengzhao/goscript/internal/context"
+	"github.com/lengzhao/goscript/types"
 )
 
 // ReturnError is a special error type used to return values from functions
@@ -27,6 +28,9 @@ type Executor struct {
 	// Opcode handler array for table-driven execution
 	// Using array instead of map for better performance
 	opcodeHandlers [instruction.OpCodeLast + 1]OpHandler
+
+	// call is the script call a handler prepared for the executor loop
+	call scriptCall
 }
 
 // NewExecutor creates a new executor
@@ -59,15 +63,36 @@ func (exec *Executor) initOpcodeHandlers() {
 	exec.opcodeHandlers[instruction.OpJump] = exec.handleJump
 	exec.opcodeHandlers[instruction.OpJumpIf] = exec.handleJumpIf
 	exec.opcodeHandlers[instruction.OpNewSlice] = exec.handleNewSlice
+	exec.opcodeHandlers[instruction.OpNewMap] = exec.handleNewMap
+	exec.opcodeHandlers[instruction.OpMakeClosure] = exec.handleMakeClosure
+	exec.opcodeHandlers[instruction.OpGo] = exec.handleGo
+	exec.opcodeHandlers[instruction.OpMakeChan] = exec.handleMakeChan
+	exec.opcodeHandlers[instruction.OpSend] = exec.handleSend
+	exec.opcodeHandlers[instruction.OpRecv] = exec.handleRecv
+	exec.opcodeHandlers[instruction.OpSelect] = exec.handleSelect
+	exec.opcodeHandlers[instruction.OpSpread] = exec.handleSpread
+	exec.opcodeHandlers[instruction.OpAddrOf] = exec.handleAddrOf
+	exec.opcodeHandlers[instruction.OpAddrField] = exec.handleAddrField
+	exec.opcodeHandlers[instruction.OpAddrIndex] = exec.handleAddrIndex
+	exec.opcodeHandlers[instruction.OpAddrValue] = exec.handleAddrValue
+	exec.opcodeHandlers[instruction.OpDeref] = exec.handleDeref
+	exec.opcodeHandlers[instruction.OpStoreDeref] = exec.handleStoreDeref
+	exec.opcodeHandlers[instruction.OpMakeSlice] = exec.handleMakeSlice
+	exec.opcodeHandlers[instruction.OpTypeAssert] = exec.handleTypeAssert
+	exec.opcodeHandlers[instruction.OpImplements] = exec.handleImplements
 	exec.opcodeHandlers[instruction.OpLen] = exec.handleLen
 	exec.opcodeHandlers[instruction.OpRotate] = exec.handleRotate
 	exec.opcodeHandlers[instruction.OpSwap] = exec.handleSwap
+	exec.opcodeHandlers[instruction.OpDup] = exec.handleDup
 	exec.opcodeHandlers[instruction.OpNewStruct] = exec.handleNewStruct
 	exec.opcodeHandlers[instruction.OpSetField] = exec.handleSetField
 	exec.opcodeHandlers[instruction.OpGetField] = exec.handleGetField
 	exec.opcodeHandlers[instruction.OpCallMethod] = exec.handleCallMethod
 	exec.opcodeHandlers[instruction.OpImport] = exec.handleImport
 	exec.opcodeHandlers[instruction.OpLabel] = exec.handleLabel
+	exec.opcodeHandlers[instruction.OpUnpack] = exec.handleUnpack
+	exec.opcodeHandlers[instruction.OpLoadLocal] = exec.handleLoadLocal
+	exec.opcodeHandlers[instruction.OpStoreLocal] = exec.handleStoreLocal
 }
 
 // RegisterOpHandler registers a custom opcode handler
@@ -75,55 +100,162 @@ func (exec *Executor) RegisterOpHandler(op instruction.OpCode, handler OpHandler
 	exec.opcodeHandlers[op] = handler
 }
 
-// executeInstructions executes a sequence of instructions with the given context
-func (exec *Executor) executeInstructions(instructions []*instruction.Instruction) (interface{}, error) {
-	stack := NewStack()
-	pc := 0 // program counter
-
-	// Reset instruction count for this execution
-	exec.vm.instructionCount = 0
+// executeInstructions executes a sequence of instructions with the given
+// context. Script functions it calls run in frames of the same loop (see
+// enter), so a script call uses neither a new executor nor host stack.
+func (exec *Executor) executeInstructions(instructions []*instruction.Instruction) (result interface{}, err error) {
+	vm := exec.vm
 
-	for pc < len(instructions) {
-		instr := instructions[pc]
+	// Guard against runaway recursion of calls made outside the loop
+	vm.callDepth++
+	defer func() { vm.callDepth-- }()
+	if vm.maxCallDepth > 0 && vm.callDepth > vm.maxCallDepth {
+		return nil, fmt.Errorf("maximum call depth exceeded: %d", vm.maxCallDepth)
+	}
+
+	// Frames above base are entered by this loop
+	base := len(vm.frames)
+	cur := activation{instructions: instructions, stack: exec.newStack()}
+	outer := vm.frame
+	vm.frame = &cur
+
+	// Panics of the instruction being executed fail the execution with a
+	// stack trace, like errors
+	var instr *instruction.Instruction
+	defer func() {
+		if r := recover(); r != nil {
+			if instr == nil {
+				panic(r)
+			}
+			result, err = nil, exec.unwind(&cur, base, vm.traceError(instr, newPanicError(r)))
+		}
+		exec.release(&cur)
+		vm.frame = outer
+	}()
+
+	for {
+		if cur.pc >= len(cur.instructions) {
+			// Functions without an explicit return return nil
+			if len(vm.frames) == base {
+				return nil, nil
+			}
+			exec.leave(&cur, nil)
+			continue
+		}
+		instr = cur.instructions[cur.pc]
+		stack, pc := cur.stack, cur.pc
 
 		// Check instruction limit
-		if exec.vm.maxInstructions > 0 {
-			if exec.vm.instructionCount >= exec.vm.maxInstructions {
-				return nil, fmt.Errorf("maximum instruction limit exceeded: %d instructions executed", exec.vm.instructionCount)
+		if limit := vm.instructionLimit(); limit > 0 {
+			if vm.instructionCount >= limit {
+				return nil, exec.unwind(&cur, base, fmt.Errorf("maximum instruction limit exceeded: %d instructions executed", vm.instructionCount))
 			}
 		}
 
 		// Increment instruction counter
-		exec.vm.instructionCount++
+		vm.instructionCount++
+		if vm.profile != nil {
+			vm.profile.instruction(vm.frames, instr)
+		}
+		if vm.coverage != nil {
+			vm.countStatement(instr)
+		}
+
+		// Stop when the host cancels the execution
+		if vm.execCtx != nil && vm.instructionCount%cancelCheckInterval == 0 {
+			if err := vm.checkCanceled(); err != nil {
+				return nil, exec.unwind(&cur, base, err)
+			}
+		}
+
+		// Stop when the execution runs out of time
+		if !vm.deadline.IsZero() && vm.instructionCount%cancelCheckInterval == 0 {
+			if err := vm.checkDeadline(); err != nil {
+				return nil, exec.unwind(&cur, base, err)
+			}
+		}
+
+		// Notify the host as the execution approaches its budget
+		if vm.nextBudgetAlert > 0 && vm.instructionCount >= vm.nextBudgetAlert && !vm.evaluatingWatch {
+			if err := vm.fireBudgetAlert(); err != nil {
+				return nil, exec.unwind(&cur, base, err)
+			}
+		}
+
+		// Let a debugger stop before the instruction, statement or line
+		if vm.stepHandler != nil && !vm.evaluatingWatch {
+			if err := exec.step(instr, pc, &cur.lastLine); err != nil {
+				return nil, exec.unwind(&cur, base, err)
+			}
+		}
+
+		// Let a debugger pause at a breakpoint or after a step
+		if vm.pauseHandler != nil && !vm.evaluatingWatch {
+			if err := exec.pause(instr, pc); err != nil {
+				return nil, exec.unwind(&cur, base, err)
+			}
+		}
 
 		// Debug output
-		if exec.vm.debug {
-			fmt.Printf("Executing instruction %d: %s, stack size: %d, stack: %v\n", pc, instr.String(), stack.Len(), stack.Items())
+		if vm.debug {
+			vm.tracef("Executing instruction %d: %s, stack size: %d, stack: %v\n", pc, instr.String(), stack.Len(), stack.Items())
+		}
+
+		// Audit instructions that cross the host boundary
+		if instr.Tags != 0 {
+			if err := exec.checkBoundary(stack, instr); err != nil {
+				return nil, exec.unwind(&cur, base, err)
+			}
 		}
 
 		// Look up the handler for this opcode using array for better performance
 		handler := exec.opcodeHandlers[instr.Op]
 		if handler == nil {
-			return nil, fmt.Errorf("unsupported operation: %s", instr.Op.String())
+			return nil, exec.unwind(&cur, base, fmt.Errorf("unsupported operation: %s", instr.Op.String()))
 		}
 
 		// Execute the handler
 		newPC, err := handler(stack, instr, pc)
 		if err != nil {
-			// Check if it's a return error
-			if returnErr, ok := err.(*ReturnError); ok {
-				if exec.vm.debug {
-					fmt.Printf("Return with value: %v\n", returnErr.Value)
+			// Calls of script functions continue in a new frame
+			if err == errCall {
+				if err = exec.enter(&cur, instr, newPC); err == nil {
+					continue
 				}
-				return returnErr.Value, nil
+			} else if returnErr, ok := err.(*ReturnError); ok {
+				if vm.debug {
+					vm.tracef("Return with value: %v\n", returnErr.Value)
+				}
+				if len(vm.frames) == base {
+					return returnErr.Value, nil
+				}
+				exec.leave(&cur, returnErr.Value)
+				continue
+			}
+			return nil, exec.unwind(&cur, base, vm.traceError(instr, err))
+		}
+		if err := stack.Err(); err != nil {
+			return nil, exec.unwind(&cur, base, vm.traceError(instr, err))
+		}
+		if allocatingOps[instr.Op] {
+			if err := exec.allocateResult(stack); err != nil {
+				return nil, exec.unwind(&cur, base, vm.traceError(instr, err))
+			}
+		}
+		cur.pc = newPC
+		if len(vm.watches) > 0 {
+			if err := exec.checkWatches(instr); err != nil {
+				return nil, exec.unwind(&cur, base, err)
 			}
-			return nil, err
 		}
-		pc = newPC
 	}
+}
 
-	// If we've executed all instructions without an explicit return, return nil
-	return nil, nil
+// recordStackHighWater folds a finished stack's high-water mark into the VM
+func (exec *Executor) recordStackHighWater(stack *Stack) {
+	if hw := stack.HighWater(); hw > exec.vm.stackHighWater {
+		exec.vm.stackHighWater = hw
+	}
 }
 
 // handleNop handles the NOP opcode
@@ -144,22 +276,25 @@ func (exec *Executor) handleLoadName(stack *Stack, instr *instruction.Instructio
 		return 0, fmt.Errorf("invalid argument for LOAD_NAME")
 	}
 
-	// Check if this is a field access (e.g., "p.age")
+	// Check if this is a field access (e.g., "p.age"); compiler temporaries
+	// at package level (e.g., "main.slice_lit_1") are variables
 	// if strings.Contains(name, ".") {
 	// Split the name into variable and field parts
-	parts := strings.Split(name, ".")
-	if len(parts) == 2 {
-		varName := parts[0]
-		fieldName := parts[1]
-
+	varName, fieldName, dotted := strings.Cut(name, ".")
+	if _, isVar := exec.vm.currentCtx.GetVariable(name); dotted && !strings.Contains(fieldName, ".") && !isVar {
 		// Look up the variable (struct) in the context hierarchy
 		structValue, exists := exec.vm.currentCtx.GetVariable(varName)
 		if !exists {
+			// A module function used as a value (e.g., strings.ToUpper)
+			if fn, ok := exec.vm.functionValue(name); ok {
+				stack.Push(fn)
+				return pc + 1, nil
+			}
 			return 0, fmt.Errorf("undefined variable: %s", varName)
 		}
 
-		// Check if it's a struct (map)
-		if structMap, ok := structValue.(map[string]interface{}); ok {
+		// Check if it's a struct (or map)
+		if structMap, ok := types.StructFields(structValue); ok {
 			// Get the field value
 			fieldValue, fieldExists := structMap[fieldName]
 			if !fieldExists {
@@ -184,6 +319,11 @@ func (exec *Executor) handleLoadName(stack *Stack, instr *instruction.Instructio
 			stack.Push(name)
 			return pc + 1, nil
 		}
+		// A function used as a value
+		if fn, ok := exec.vm.functionValue(name); ok {
+			stack.Push(fn)
+			return pc + 1, nil
+		}
 		return 0, fmt.Errorf("undefined variable: %s", name)
 	}
 	// Debug information
@@ -203,7 +343,7 @@ func (exec *Executor) handleStoreName(stack *Stack, instr *instruction.Instructi
 		return 0, fmt.Errorf("stack underflow")
 	}
 
-	value := stack.Pop()
+	value := copyValue(stack.Pop())
 
 	// For function parameters, they might already have values set by the caller
 	// We should update the value, not create a new variable
@@ -228,19 +368,21 @@ func (exec *Executor) handlePop(stack *Stack, instr *instruction.Instruction, pc
 // handleCall handles the CALL opcode
 func (exec *Executor) handleCall(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
 	// Get the function name and argument count
-	functionName, ok := instr.Arg.(string)
-	if !ok {
-		return 0, fmt.Errorf("invalid function name for CALL")
-	}
-
 	argCount, ok := instr.Arg2.(int)
 	if !ok {
 		return 0, fmt.Errorf("invalid argument count for CALL")
 	}
+	if instr.Arg == nil {
+		return exec.handleValueCall(stack, argCount, pc)
+	}
+	functionName, ok := instr.Arg.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid function name for CALL")
+	}
 
 	// Debug information - print stack before processing
 	if exec.vm.debug {
-		fmt.Printf("CALL %s with %d arguments, stack: %v\n", functionName, argCount, stack.Items())
+		exec.vm.tracef("CALL %s with %d arguments, stack: %v\n", functionName, argCount, stack.Items())
 	}
 
 	// Prepare arguments using the unified function
@@ -251,17 +393,34 @@ func (exec *Executor) handleCall(stack *Stack, instr *instruction.Instruction, p
 
 	// Unified call handling
 	callType := exec.determineCallType(args)
+	if callType == callTypeRegular && exec.isErrorMethodCall(functionName, args) {
+		callType = callTypeErrorValue
+	}
+	if callType == callTypeRegular && exec.isObjectMethodCall(functionName, args) {
+		callType = callTypeObjectMethod
+	}
+
+	if err := exec.checkUntaggedCall(instr, functionName, callType, args); err != nil {
+		return 0, err
+	}
 
 	switch callType {
 	case callTypeModule:
 		return exec.handleModuleCall(stack, functionName, args, pc)
 	case callTypeMethod:
 		return exec.handleMethodCallUnified(stack, functionName, args, pc)
+	case callTypeTimeValue:
+		return exec.handleTimeMethodCall(stack, functionName, args, pc)
+	case callTypeErrorValue:
+		stack.Push(args[0].(error).Error())
+		return pc + 1, nil
+	case callTypeObjectMethod:
+		return exec.handleObjectMethodCall(stack, functionName, args, pc)
 	default:
 		// Regular function call
 		// Push the arguments back to the stack for handleFunctionCall
 		exec.pushArgumentsBack(stack, args)
-		return exec.handleFunctionCall(stack, exec.vm, functionName, argCount, pc)
+		return exec.handleFunctionCall(stack, exec.vm, functionName, len(args), pc)
 	}
 }
 
@@ -272,6 +431,9 @@ const (
 	callTypeRegular CallType = iota
 	callTypeModule
 	callTypeMethod
+	callTypeTimeValue
+	callTypeErrorValue
+	callTypeObjectMethod
 )
 
 // determineCallType determines the type of call based on arguments and function name
@@ -286,6 +448,11 @@ func (exec *Executor) determineCallType(args []interface{}) CallType {
 		if exec.isStructReceiver(args[0]) {
 			return callTypeMethod
 		}
+
+		// Check if this is a method call on a host time value
+		if isTimeValue(args[0]) {
+			return callTypeTimeValue
+		}
 	}
 
 	return callTypeRegular
@@ -323,6 +490,19 @@ func (exec *Executor) handleMethodCallUnified(stack *Stack, functionName string,
 	return exec.handleCallMethod(stack, callMethodInstr, pc)
 }
 
+// handleTimeMethodCall handles method calls on time.Time and time.Duration values
+func (exec *Executor) handleTimeMethodCall(stack *Stack, functionName string, args []interface{}, pc int) (int, error) {
+	result, err := callTimeMethod(args[0], functionName, args[1:])
+	if err != nil {
+		return 0, fmt.Errorf("error calling method %s: %w", functionName, err)
+	}
+	if err := exec.vm.allocateHostResult(result, args); err != nil {
+		return 0, err
+	}
+	stack.Push(result)
+	return pc + 1, nil
+}
+
 // isModuleVariable checks if a variable is a module
 func (exec *Executor) isModuleVariable(variable interface{}) (string, bool) {
 	// In our implementation, modules are stored as variables
@@ -338,14 +518,27 @@ func (exec *Executor) isModuleVariable(variable interface{}) (string, bool) {
 
 // isStructReceiver checks if the variable is a struct receiver
 func (exec *Executor) isStructReceiver(variable interface{}) bool {
-	// Check if the variable is a struct (map)
-	_, ok := variable.(map[string]interface{})
+	// Check if the variable is a struct (or map)
+	_, ok := types.StructFields(variable)
 	return ok
 }
 
 // handleFunctionCall handles regular function calls
 func (exec *Executor) handleFunctionCall(stack *Stack, vm *VM, funcName string, argCount int, pc int) (int, error) {
-	// Check if it's a registered script function
+	// Script functions called by name run in a new frame
+	if info := vm.scriptFunctionByName(funcName); info != nil {
+		args, err := exec.prepareArguments(stack, argCount)
+		if err != nil {
+			return 0, fmt.Errorf("error preparing arguments for function %s: %w", funcName, err)
+		}
+		call, err := vm.newScriptCall(info.Key, info, args)
+		if err != nil {
+			return 0, fmt.Errorf("error calling function %s: %w", funcName, err)
+		}
+		return pc + 1, exec.enterCall(call, "error calling function %s: %w", funcName)
+	}
+
+	// Check if it's a registered host function
 	if fn, exists := vm.GetFunction(funcName); exists {
 		// Prepare arguments using the unified function
 		args, err := exec.prepareArguments(stack, argCount)
@@ -358,6 +551,10 @@ func (exec *Executor) handleFunctionCall(stack *Stack, vm *VM, funcName string,
 		if err != nil {
 			return 0, fmt.Errorf("error calling function %s: %w", funcName, err)
 		}
+		result = vm.convertHostValue(funcName, result)
+		if err := vm.allocateHostResult(result, args); err != nil {
+			return 0, err
+		}
 
 		// Push result back to stack if not nil
 		if result != nil {
@@ -371,10 +568,19 @@ func (exec *Executor) handleFunctionCall(stack *Stack, vm *VM, funcName string,
 		return exec.callScriptDefinedFunction(stack, vm, funcName, argCount, pc)
 	}
 
+	// A variable holding a function value
+	if fn, exists := exec.lookupFunctionValue(funcName); exists {
+		args, err := exec.prepareArguments(stack, argCount)
+		if err != nil {
+			return 0, fmt.Errorf("error preparing arguments for function %s: %w", funcName, err)
+		}
+		return exec.pushValueCall(stack, fn, args, pc)
+	}
+
 	return 0, fmt.Errorf("undefined function: %s", funcName)
 }
 
-// callScriptDefinedFunction calls a script-defined function
+// callScriptDefinedFunction calls a script-defined function by key
 func (exec *Executor) callScriptDefinedFunction(stack *Stack, vm *VM, funcName string, argCount int, pc int) (int, error) {
 	// Prepare arguments using the unified function
 	args, err := exec.prepareArguments(stack, argCount)
@@ -382,78 +588,13 @@ func (exec *Executor) callScriptDefinedFunction(stack *Stack, vm *VM, funcName s
 		return 0, fmt.Errorf("error preparing arguments for script function %s: %w", funcName, err)
 	}
 
-	// Create new context for the function call
-	// The function context's parent is the current context
-	functionCtx := execContext.NewContext(funcName, exec.vm.currentCtx)
-
-	// Try to get the actual parameter names from the registered script function
-	paramNames := make([]string, argCount)
-
-	// Get all script functions to find the one we're calling
-	scriptFunctions := vm.GetAllScriptFunctions()
-
-	// Try to find the function info
-	var foundFuncInfo *ScriptFunctionInfo
-	for _, fnInfo := range scriptFunctions {
-		// Check if this function matches our function name
-		if fnInfo.Key == funcName || fnInfo.Name == funcName {
-			foundFuncInfo = fnInfo
-			break
-		}
-	}
-
-	// If we found the function info and it has parameter names, use them
-	if foundFuncInfo != nil && len(foundFuncInfo.ParamNames) > 0 {
-		// Use the actual parameter names from the function definition
-		for i := 0; i < argCount && i < len(foundFuncInfo.ParamNames); i++ {
-			paramNames[i] = foundFuncInfo.ParamNames[i]
-		}
-		// Fill in any remaining parameters with default names
-		for i := len(foundFuncInfo.ParamNames); i < argCount; i++ {
-			paramNames[i] = fmt.Sprintf("arg%d", i)
-		}
-	} else {
-		// Fall back to default parameter names
-		for i := 0; i < argCount; i++ {
-			paramNames[i] = fmt.Sprintf("arg%d", i)
-		}
-	}
-
-	// Set arguments as local variables with appropriate names
-	for i, arg := range args {
-		paramName := paramNames[i]
-		// Make sure we create the variable in the function context
-		functionCtx.CreateVariableWithType(paramName, arg, "unknown")
-	}
-
-	// Execute the function using a new executor
-	functionInstructions, exists := vm.GetInstructionSet(funcName)
-	if !exists {
-		return 0, fmt.Errorf("undefined function: %s", funcName)
-	}
-
-	// Save the current context
-	previousCtx := vm.currentCtx
-
-	// Set the current context for the function execution
-	vm.currentCtx = functionCtx
-
-	// Execute the function
-	newExec := NewExecutor(vm)
-	result, err := newExec.executeInstructions(functionInstructions)
-
-	// Restore the previous context
-	vm.currentCtx = previousCtx
-
+	// Bind the arguments in a new context, whose parent is the package
+	// context, and run the function in a new frame
+	call, err := vm.newScriptCall(funcName, vm.lookupScriptFunction(funcName), args)
 	if err != nil {
-		return 0, fmt.Errorf("error executing function %s: %w", funcName, err)
-	}
-
-	// Push result back to stack if not nil
-	if result != nil {
-		stack.Push(result)
+		return 0, err
 	}
-	return pc + 1, nil
+	return pc + 1, exec.enterCall(call, "error executing function %s: %w", funcName)
 }
 
 // isModuleName checks if a name is a registered module name
@@ -470,10 +611,22 @@ func (exec *Executor) isModuleName(name string) bool {
 
 // handleReturn handles the RETURN opcode
 func (exec *Executor) handleReturn(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	// Several results are returned as a tuple
+	if n, ok := instr.Arg.(int); ok && n > 1 {
+		if stack.Len() < n {
+			return 0, fmt.Errorf("stack underflow for RETURN %d", n)
+		}
+		results := make(Tuple, n)
+		for i := n - 1; i >= 0; i-- {
+			results[i] = copyValue(stack.Pop())
+		}
+		return 0, &ReturnError{Value: results}
+	}
+
 	// Return the top of stack if it exists
 	if stack.Len() > 0 {
 		// We use a special error value to return the result
-		return 0, &ReturnError{Value: stack.Pop()}
+		return 0, &ReturnError{Value: copyValue(stack.Pop())}
 	}
 	return 0, &ReturnError{Value: nil}
 }
@@ -509,8 +662,17 @@ func (exec *Executor) handleCreateVar(stack *Stack, instr *instruction.Instructi
 		return 0, fmt.Errorf("invalid variable name")
 	}
 
-	// Create the variable with nil initial value
-	exec.vm.currentCtx.CreateVariableWithType(name, nil, "unknown")
+	// Declare a local variable resolved to a slot
+	if slot, ok := instr.Arg2.(int); ok {
+		exec.vm.declareLocal(slot, name)
+		return pc + 1, nil
+	}
+
+	// Create the variable with nil initial value, unless the scope has it
+	// (e.g., a parameter)
+	if !exec.vm.currentCtx.HasVariable(name) {
+		exec.vm.currentCtx.CreateVariableWithType(name, nil, "unknown")
+	}
 	return pc + 1, nil
 }
 
@@ -529,6 +691,9 @@ func (exec *Executor) handleExitScopeWithKey(stack *Stack, instr *instruction.In
 	// For now, we just increment the program counter
 	// In a more advanced implementation, we might manage nested scopes
 	ctx := exec.vm.currentCtx.GetParent()
+	if ctx == nil {
+		return 0, fmt.Errorf("EXIT_SCOPE_WITH_KEY without matching ENTER_SCOPE_WITH_KEY")
+	}
 	exec.vm.currentCtx = ctx
 	return pc + 1, nil
 }
@@ -539,9 +704,11 @@ func (exec *Executor) handleGetIndex(stack *Stack, instr *instruction.Instructio
 		return 0, fmt.Errorf("stack underflow for GET_INDEX")
 	}
 
-	// Pop the index and the collection
+	// Pop the index and the collection; with the argument set, a map index
+	// also pushes whether the key exists (v, ok := m[k])
 	index := stack.Pop()
 	collection := stack.Pop()
+	withOk, _ := instr.Arg.(bool)
 
 	// Handle different collection types
 	switch coll := collection.(type) {
@@ -555,6 +722,19 @@ func (exec *Executor) handleGetIndex(stack *Stack, instr *instruction.Instructio
 			return 0, fmt.Errorf("index out of range: %d", idx)
 		}
 		stack.Push(coll[idx])
+	case []byte:
+		idx, err := byteIndex(index, len(coll))
+		if err != nil {
+			return 0, err
+		}
+		stack.Push(int(coll[idx]))
+	case string:
+		// s[i] is the i-th byte of the string
+		idx, err := byteIndex(index, len(coll))
+		if err != nil {
+			return 0, err
+		}
+		stack.Push(int(coll[idx]))
 	case map[string]interface{}:
 		// Handle map indexing
 		key, ok := index.(string)
@@ -563,17 +743,47 @@ func (exec *Executor) handleGetIndex(stack *Stack, instr *instruction.Instructio
 		}
 		value, exists := coll[key]
 		if !exists {
-			stack.Push(nil)
-		} else {
-			stack.Push(value)
+			value = exec.elementZero(instr)
+		}
+		stack.Push(value)
+		if withOk {
+			stack.Push(exists)
+		}
+		return pc + 1, nil
+	case map[interface{}]interface{}:
+		// Handle maps with non-string keys
+		key, err := exec.vm.mapKey(coll, index, false)
+		if err != nil {
+			return 0, err
 		}
+		value, exists := coll[key]
+		if !exists {
+			value = exec.elementZero(instr)
+		}
+		stack.Push(value)
+		if withOk {
+			stack.Push(exists)
+		}
+		return pc + 1, nil
 	default:
 		return 0, fmt.Errorf("unsupported collection type for indexing: %T", collection)
 	}
+	if withOk {
+		return 0, fmt.Errorf("invalid operation: comma-ok index of %T, not a map", collection)
+	}
 
 	return pc + 1, nil
 }
 
+// elementZero returns the value a missing map key reads as: the zero value
+// of the element type GET_INDEX gives, or nil
+func (exec *Executor) elementZero(instr *instruction.Instruction) interface{} {
+	if typ, ok := instr.Arg2.(string); ok {
+		return exec.vm.zeroOf(typ)
+	}
+	return nil
+}
+
 // handleSetIndex handles the SET_INDEX opcode
 func (exec *Executor) handleSetIndex(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
 	if stack.Len() < 3 {
@@ -581,7 +791,7 @@ func (exec *Executor) handleSetIndex(stack *Stack, instr *instruction.Instructio
 	}
 
 	// Pop the value, index, and collection
-	value := stack.Pop()
+	value := copyValue(stack.Pop())
 	index := stack.Pop()
 	collection := stack.Pop()
 
@@ -597,12 +807,35 @@ func (exec *Executor) handleSetIndex(stack *Stack, instr *instruction.Instructio
 			return 0, fmt.Errorf("index out of range: %d", idx)
 		}
 		coll[idx] = value
+	case []byte:
+		if err := setByte(coll, index, value); err != nil {
+			return 0, err
+		}
+	case string:
+		return 0, fmt.Errorf("cannot assign to %v[%v] (strings are immutable)", coll, index)
 	case map[string]interface{}:
 		// Handle map indexing
 		key, ok := index.(string)
 		if !ok {
 			return 0, fmt.Errorf("map key must be a string, got %T", index)
 		}
+		if _, exists := coll[key]; !exists {
+			if err := exec.vm.allocate(mapEntrySize); err != nil {
+				return 0, err
+			}
+		}
+		coll[key] = value
+	case map[interface{}]interface{}:
+		// Handle maps with non-string keys
+		key, err := exec.vm.mapKey(coll, index, true)
+		if err != nil {
+			return 0, err
+		}
+		if _, exists := coll[key]; !exists {
+			if err := exec.vm.allocate(mapEntrySize); err != nil {
+				return 0, err
+			}
+		}
 		coll[key] = value
 	default:
 		return 0, fmt.Errorf("unsupported collection type for indexing: %T (value: %v, index: %v)", collection, value, index)
@@ -634,10 +867,14 @@ func (exec *Executor) handleJumpIf(stack *Stack, instr *instruction.Instruction,
 	// Pop the condition value
 	condition := stack.Pop()
 
-	// Check if condition is truthy
+	// Check if condition is true
 	// For loop conditions, we want to jump when the condition is FALSE (to exit the loop)
 	// For if statements, we want to jump when the condition is FALSE (to skip the if block)
-	if !isTruthy(condition) {
+	holds, err := exec.vm.condition(condition)
+	if err != nil {
+		return 0, err
+	}
+	if !holds {
 		return target, nil
 	}
 
@@ -690,9 +927,16 @@ func (exec *Executor) handleLen(stack *Stack, instr *instruction.Instruction, pc
 	case []interface{}:
 		// Handle slice/array length
 		stack.Push(len(coll))
+	case []byte:
+		stack.Push(len(coll))
 	case map[string]interface{}:
 		// Handle map length
 		stack.Push(len(coll))
+	case map[interface{}]interface{}:
+		stack.Push(len(coll))
+	case *types.Struct:
+		// Handle struct length (number of fields)
+		stack.Push(len(coll.Fields))
 	case string:
 		// Handle string length
 		stack.Push(len(coll))
@@ -723,6 +967,22 @@ func (exec *Executor) handleRotate(stack *Stack, instr *instruction.Instruction,
 	return pc + 1, nil
 }
 
+// handleDup handles the DUP opcode
+// Changes [a, b] to [a, b, a, b] for a count of 2
+func (exec *Executor) handleDup(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	n, ok := instr.Arg.(int)
+	if !ok || n < 1 {
+		return 0, fmt.Errorf("invalid count for DUP")
+	}
+	if stack.Len() < n {
+		return 0, fmt.Errorf("stack underflow for DUP")
+	}
+	for i := 0; i < n; i++ {
+		stack.Push(stack.PeekAt(n - 1))
+	}
+	return pc + 1, nil
+}
+
 // handleSwap handles the SWAP opcode
 // Changes [a, b] to [b, a]
 func (exec *Executor) handleSwap(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
@@ -743,15 +1003,13 @@ func (exec *Executor) handleSwap(stack *Stack, instr *instruction.Instruction, p
 
 // handleNewStruct handles the NEW_STRUCT opcode
 func (exec *Executor) handleNewStruct(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
-	// Create a new struct (represented as a map)
-	structInstance := make(map[string]interface{})
-
-	// If there's a type name in the instruction argument, store it
+	// Create a new struct of the type named by the instruction argument;
+	// literals without a type name are represented as a map
 	if typeName, ok := instr.Arg.(string); ok && typeName != "" {
-		structInstance["_type"] = typeName
+		stack.Push(types.NewStruct(typeName))
+	} else {
+		stack.Push(make(map[string]interface{}))
 	}
-
-	stack.Push(structInstance)
 	return pc + 1, nil
 }
 
@@ -770,48 +1028,31 @@ func (exec *Executor) handleSetField(stack *Stack, instr *instruction.Instructio
 	// Pop the value and struct
 	// Stack loading order: struct, value
 	// Stack popping order: value, struct
-	value := stack.Pop()
+	value := copyValue(stack.Pop())
 	structInterface := stack.Pop()
 
 	// Debug information
 	if exec.vm.debug {
-		fmt.Printf("SET_FIELD: struct = %v (type %T), field = %s, value = %v (type %T)\n",
+		exec.vm.tracef("SET_FIELD: struct = %v (type %T), field = %s, value = %v (type %T)\n",
 			structInterface, structInterface, fieldName, value, value)
 	}
 
-	// Check that the struct is a map
-	structMap, ok := structInterface.(map[string]interface{})
+	// Check that the struct is a script struct or a map
+	structMap, ok := types.StructFields(structInterface)
 	if !ok {
+		if _, _, allowed := exec.vm.reflectedStruct(structInterface); allowed {
+			return 0, fmt.Errorf("SET_FIELD: field %s of host value %T is read-only", fieldName, structInterface)
+		}
 		return 0, fmt.Errorf("SET_FIELD: struct is not a map, got %T", structInterface)
 	}
 
-	// First, try to set the field directly
-	if _, exists := structMap[fieldName]; exists {
-		// Field exists directly, set it
-		structMap[fieldName] = value
-	} else {
-		// If the field doesn0't exist directly, check for promoted fields in anonymous nested structs
-		// In Go, when a struct has an anonymous field, its fields are promoted to the outer struct
-		fieldSet := false
-		for _, nestedStruct := range structMap {
-			// Check if this key might be an anonymous field (typically it would be a struct type name)
-			// For simplicity, we'll assume any map value that is itself a map could be an anonymous nested struct
-			if nestedMap, isMap := nestedStruct.(map[string]interface{}); isMap {
-				// Check if the nested struct has the field we're looking for
-				if _, found := nestedMap[fieldName]; found {
-					// Set the promoted field in the nested struct
-					nestedMap[fieldName] = value
-					fieldSet = true
-					break
-				}
-			}
-		}
-
-		// If we couldn't find a promoted field, set it as a direct field
-		if !fieldSet {
-			structMap[fieldName] = value
-		}
+	// Set the field of the struct, or the field promoted from one of its
+	// embedded structs
+	holder, err := exec.vm.fieldHolder(structInterface, structMap, fieldName, true)
+	if err != nil {
+		return 0, fmt.Errorf("SET_FIELD: %w", err)
 	}
+	holder[fieldName] = value
 
 	return pc + 1, nil
 }
@@ -833,39 +1074,42 @@ func (exec *Executor) handleGetField(stack *Stack, instr *instruction.Instructio
 
 	// Debug information
 	if exec.vm.debug {
-		fmt.Printf("GET_FIELD: struct = %v (type %T), field = %s\n", structInterface, structInterface, fieldName)
+		exec.vm.tracef("GET_FIELD: struct = %v (type %T), field = %s\n", structInterface, structInterface, fieldName)
 	}
 
-	// Check that the struct is a map
-	structMap, ok := structInterface.(map[string]interface{})
+	// Check that the struct is a script struct or a map, or a host value
+	// readable through reflection
+	structMap, ok := types.StructFields(structInterface)
 	if !ok {
-		return 0, fmt.Errorf("GET_FIELD: struct is not a map, got %T", structInterface)
+		value, handled, err := exec.vm.getReflectedField(structInterface, fieldName)
+		if err != nil {
+			return 0, fmt.Errorf("GET_FIELD: %w", err)
+		}
+		if !handled {
+			return 0, fmt.Errorf("GET_FIELD: struct is not a map, got %T", structInterface)
+		}
+		stack.Push(value)
+		return pc + 1, nil
 	}
 
-	// First, try to get the field directly
-	value, exists := structMap[fieldName]
-	if !exists {
-		// If the field doesn't exist directly, check for promoted fields in anonymous nested structs
-		// In Go, when a struct has an anonymous field, its fields are promoted to the outer struct
-		for _, nestedStruct := range structMap {
-			// Check if this key might be an anonymous field (typically it would be a struct type name)
-			// For simplicity, we'll assume any map value that is itself a map could be an anonymous nested struct
-			if nestedMap, isMap := nestedStruct.(map[string]interface{}); isMap {
-				// Check if the nested struct has the field we're looking for
-				if promotedValue, found := nestedMap[fieldName]; found {
-					// Found the promoted field
-					stack.Push(promotedValue)
-					return pc + 1, nil
-				}
+	// Get the field of the struct, or the field promoted from one of its
+	// embedded structs. Declared fields that were never set read as the
+	// zero value of their type; a struct created for one is kept, so that
+	// setting its fields (a.b.c = v) sets them in the struct holding it.
+	holder, err := exec.vm.fieldHolder(structInterface, structMap, fieldName, false)
+	if err != nil {
+		return 0, fmt.Errorf("GET_FIELD: %w", err)
+	}
+	value, exists := holder[fieldName]
+	if s, ok := structInterface.(*types.Struct); ok && !exists {
+		if typ, declared := exec.vm.fieldType(s.Type, fieldName); declared {
+			value = exec.vm.zeroOf(typ)
+			if _, isStruct := value.(*types.Struct); isStruct && holder != nil {
+				holder[fieldName] = value
 			}
 		}
-
-		// Field doesn't exist even after checking for promoted fields, push nil
-		stack.Push(nil)
-	} else {
-		// Push the field value
-		stack.Push(value)
 	}
+	stack.Push(value)
 
 	return pc + 1, nil
 }
@@ -884,7 +1128,7 @@ func (exec *Executor) handleCallMethod(stack *Stack, instr *instruction.Instruct
 
 	// Debug information - print stack before processing
 	if exec.vm.debug {
-		fmt.Printf("Stack before CALL_METHOD %s: %v\n", methodName, stack.Items())
+		exec.vm.tracef("Stack before CALL_METHOD %s: %v\n", methodName, stack.Items())
 	}
 
 	// Check if Arg2 is a slice of arguments (direct values) or an int (arg count)
@@ -920,73 +1164,27 @@ func (exec *Executor) handleCallMethod(stack *Stack, instr *instruction.Instruct
 
 	// Debug information
 	if exec.vm.debug {
-		fmt.Printf("Calling method %s with %d arguments\n", methodName, len(args))
-		fmt.Printf("Method %s receiver: %v (type %T), args: %v\n", methodName, receiver, receiver, args)
-	}
-
-	// First, try to find a method with the qualified name (e.g., "Person.GetName")
-	// This is for our new approach where structs are treated like packages
-	qualifiedMethodName := methodName
-	if structMap, ok := receiver.(map[string]interface{}); ok {
-		// If we have a struct type name, we can create a qualified method name
-		if typeName, exists := structMap["_type"]; exists {
-			qualifiedMethodName = fmt.Sprintf("%s.%s", typeName, methodName)
-		} else {
-			// Try to infer the type name from the context
-			// This is a heuristic approach - in a real implementation we would store type info better
-			for key := range structMap {
-				if key != "width" && key != "height" && key != "radius" && key != "_type" && key != "name" && key != "age" {
-					// Assume this is the type name
-					qualifiedMethodName = fmt.Sprintf("%s.%s", key, methodName)
-					break
-				}
-			}
-		}
-	}
-
-	if exec.vm.debug {
-		fmt.Printf("Looking for registered function with key: %s\n", qualifiedMethodName)
+		exec.vm.tracef("Calling method %s with %d arguments\n", methodName, len(args))
+		exec.vm.tracef("Method %s receiver: %v (type %T), args: %v\n", methodName, receiver, receiver, args)
 	}
-	// Try to find the method by looking for a registered function with the qualified name
-	if fn, exists := vm.GetFunction(qualifiedMethodName); exists {
-		if exec.vm.debug {
-			fmt.Printf("Found registered function with key: %s\n", qualifiedMethodName)
-		}
-		// Prepare arguments including the receiver as the first argument
-		allArgs := make([]interface{}, len(args)+1)
-		allArgs[0] = receiver
-		copy(allArgs[1:], args)
-
-		// Call the method
-		result, err := fn(allArgs...)
-		if err != nil {
-			return 0, fmt.Errorf("error calling method %s: %w", methodName, err)
-		}
 
-		// Push result back to stack if not nil
-		if result != nil {
-			stack.Push(result)
-		}
-		if exec.vm.debug {
-			fmt.Printf("Stack after CALL_METHOD %s (builtin): %v\n", methodName, stack.Items())
-		}
-		return pc + 1, nil
-	} else {
-		if exec.vm.debug {
-			fmt.Printf("No registered function found with key: %s\n", qualifiedMethodName)
-		}
+	// Methods the receiver's type does not have are promoted from its
+	// embedded structs
+	receiver, err := exec.vm.methodReceiver(receiver, methodName)
+	if err != nil {
+		return 0, err
 	}
 
-	// Check if it's a script-defined method
-	// For script-defined methods, we need to find them by key
-	// The key would be something like "test.func.methodName"
-	// This is a simplified approach for testing purposes
-	// Try different function key patterns in order of preference
-	functionKeys := []string{
-		qualifiedMethodName, // Try the qualified method name first (e.g., "Rectangle.SetWidth")
-		fmt.Sprintf("*%s.%s", getStructTypeName(receiver), methodName), // Try pointer receiver (e.g., "*Rectangle.SetHeight")
-		fmt.Sprintf("test.func.%s", methodName),
-		fmt.Sprintf("main.func.%s", methodName),
+	// Methods are resolved from the type the compiler recorded in the
+	// struct: "Type.Method" for value receivers, "*Type.Method" for pointer
+	// receivers
+	var functionKeys []string
+	hostNames := []string{methodName}
+	if typeName, ok := types.StructTypeName(receiver); ok {
+		qualifiedMethodName := fmt.Sprintf("%s.%s", typeName, methodName)
+		functionKeys = []string{qualifiedMethodName, "*" + qualifiedMethodName}
+		// Host functions may implement methods of script types
+		hostNames = []string{qualifiedMethodName, methodName}
 	}
 
 	var functionInstructions []*instruction.Instruction
@@ -995,14 +1193,14 @@ func (exec *Executor) handleCallMethod(stack *Stack, instr *instruction.Instruct
 
 	for _, key := range functionKeys {
 		if exec.vm.debug {
-			fmt.Printf("Looking for function with key: %s\n", key)
+			exec.vm.tracef("Looking for function with key: %s\n", key)
 		}
 		if instructions, exists := vm.GetInstructionSet(key); exists {
 			functionInstructions = instructions
 			found = true
 			foundKey = key
 			if exec.vm.debug {
-				fmt.Printf("Found function with key: %s, %d instructions\n", key, len(instructions))
+				exec.vm.tracef("Found function with key: %s, %d instructions\n", key, len(instructions))
 			}
 			break
 		}
@@ -1010,150 +1208,73 @@ func (exec *Executor) handleCallMethod(stack *Stack, instr *instruction.Instruct
 
 	if found {
 		// Create new context for the method call
-		// The method context's parent is the current context
-		methodCtx := execContext.NewContext(methodName, vm.currentCtx)
+		// The method context's parent is the package context
+		methodCtx := execContext.NewContext(methodName, vm.functionParent())
 
 		// Set method arguments as local variables
 		// The first argument is the receiver (usually named after the receiver parameter)
 		allArgs := make([]interface{}, len(args)+1)
 		allArgs[0] = receiver
-		copy(allArgs[1:], args)
+		for i, arg := range args {
+			allArgs[i+1] = copyValue(arg)
+		}
 
 		// For value receiver methods, we need to create a copy of the struct
-		// For pointer receiver methods, we use the original struct
+		// For pointer receiver methods, we use a pointer to the original struct
 		// Check if this is a pointer receiver method
 		isPointerReceiver := strings.HasPrefix(foundKey, "*")
 		if exec.vm.debug {
-			fmt.Printf("Method %s is pointer receiver: %t\n", foundKey, isPointerReceiver)
+			exec.vm.tracef("Method %s is pointer receiver: %t\n", foundKey, isPointerReceiver)
 		}
 
-		// If it's a value receiver method, create a copy of the struct
-		if !isPointerReceiver {
-			// Create a copy of the struct for value receiver
-			if originalStruct, ok := receiver.(map[string]interface{}); ok {
-				structCopy := make(map[string]interface{})
-				for k, v := range originalStruct {
-					structCopy[k] = v
+		if originalStruct, ok := receiver.(*types.Struct); ok {
+			if isPointerReceiver {
+				if !originalStruct.Pointer {
+					allArgs[0] = originalStruct.Addr()
 				}
+			} else {
+				// Create a copy of the struct for value receiver
+				structCopy := originalStruct.Copy()
 				allArgs[0] = structCopy
 				if exec.vm.debug {
-					fmt.Printf("Created copy of struct for value receiver: %v\n", structCopy)
+					exec.vm.tracef("Created copy of struct for value receiver: %v\n", structCopy)
 				}
 			}
 		}
 
-		// Set argument names: first is receiver name, then actual parameter names
-		// Try to get parameter names from registered script function info
-		paramNames := []string{"r"} // default receiver name
-
-		// Try to get the actual parameter names from the registered script function
-		scriptFunctions := vm.GetAllScriptFunctions()
-		if exec.vm.debug {
-			fmt.Printf("Script functions: %v\n", scriptFunctions)
-		}
-		foundParamNames := false
-		for name, fnInfo := range scriptFunctions {
-			// Check if this function matches our method name
-			if exec.vm.debug {
-				fmt.Printf("Checking function %s: key=%s, paramNames=%v\n", name, fnInfo.Key, fnInfo.ParamNames)
-			}
-			if fnInfo.Key == foundKey {
-				// Use the parameter names from the function info
-				if len(fnInfo.ParamNames) > 0 {
-					paramNames = fnInfo.ParamNames
-					foundParamNames = true
-				}
-				if exec.vm.debug {
-					fmt.Printf("Using paramNames from %s: %v\n", name, paramNames)
-				}
-				break
+		// Bind the receiver and the arguments to the parameter names the
+		// compiler recorded for the method
+		var paramNames []string
+		if fnInfo := vm.lookupScriptFunction(foundKey); fnInfo != nil && fnInfo.Key == foundKey {
+			paramNames = fnInfo.ParamNames
+			var err error
+			if allArgs, err = packVariadic(fnInfo, allArgs); err != nil {
+				return 0, err
 			}
 		}
-
-		// If we still have default parameter names, try to determine them based on method name
-		if !foundParamNames && len(paramNames) == 1 && paramNames[0] == "r" {
-			// Try to extract parameter names from the function key or method name
-			// For now, we'll use a heuristic approach
-			switch methodName {
-			case "SetWidth":
-				paramNames = []string{"r", "width"}
-			case "SetHeight":
-				paramNames = []string{"r", "height"}
-			case "SetRadius":
-				paramNames = []string{"c", "radius"} // Based on the Circle.SetRadius method
-			case "Area":
-				paramNames = []string{"r"}
-			case "Add":
-				paramNames = []string{"c", "x"} // Based on our test function (c Calculator) Add(x int)
-			case "Scale":
-				paramNames = []string{"r", "factor"} // Based on our test function (r Rectangle) Scale(factor int)
-			case "GetWidth":
-				paramNames = []string{"r"}
-			default:
-				// Fallback to generic names
-				paramNames = []string{"r"} // receiver name
-				for i := 0; i < len(args); i++ {
-					paramNames = append(paramNames, fmt.Sprintf("arg%d", i))
-				}
-			}
-		}
-
-		// Make sure we have enough parameter names
-		for len(paramNames) < len(allArgs) {
-			paramNames = append(paramNames, fmt.Sprintf("arg%d", len(paramNames)-1))
-		}
-
 		for i, arg := range allArgs {
-			paramName := "unknown"
+			paramName := fmt.Sprintf("arg%d", i)
 			if i < len(paramNames) {
 				paramName = paramNames[i]
-			} else if i < 8 {
-				// Fallback to generic names a, b, c, etc.
-				genericNames := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
-				if i < len(genericNames) {
-					paramName = genericNames[i]
-				} else {
-					paramName = fmt.Sprintf("arg%d", i)
-				}
-			} else {
-				paramName = fmt.Sprintf("arg%d", i)
 			}
 			// Make sure we create the variable in the method context
 			methodCtx.CreateVariableWithType(paramName, arg, "unknown")
 			// Debug information
 			if exec.vm.debug {
-				fmt.Printf("Setting parameter %s = %v (type %T)\n", paramName, arg, arg)
+				exec.vm.tracef("Setting parameter %s = %v (type %T)\n", paramName, arg, arg)
 			}
 		}
 
-		// Execute the method using a new executor
-		newExec := NewExecutor(vm)
-		// Set the current context for the method execution
-		vm.currentCtx = methodCtx
-
-		// Debug information - print all variables in the method context
-		vars, _ := methodCtx.GetAllVariablesWithTypes()
-		if exec.vm.debug {
-			fmt.Printf("Method context variables: %v\n", vars)
-		}
-
-		result, err := newExec.executeInstructions(functionInstructions)
-		if err != nil {
-			return 0, fmt.Errorf("error executing method %s: %w", methodName, err)
-		}
-
-		// Push result back to stack if not nil
-		if result != nil {
-			stack.Push(result)
-		}
-		if exec.vm.debug {
-			fmt.Printf("Stack after CALL_METHOD %s (script): %v\n", methodName, stack.Items())
-		}
-		return pc + 1, nil
+		// Run the method in a frame of the executor loop
+		call := scriptCall{key: foundKey, instructions: functionInstructions, ctx: methodCtx, args: allArgs}
+		return pc + 1, exec.enterCall(call, "error executing method %s: %w", methodName)
 	} else {
 		// Try to find the method by looking for a registered function
-		// This would be for built-in methods if we had any
-		if fn, exists := vm.GetFunction(methodName); exists {
+		for _, name := range hostNames {
+			fn, exists := vm.GetFunction(name)
+			if !exists {
+				continue
+			}
 			// Prepare arguments including the receiver as the first argument
 			allArgs := make([]interface{}, len(args)+1)
 			allArgs[0] = receiver
@@ -1164,38 +1285,24 @@ func (exec *Executor) handleCallMethod(stack *Stack, instr *instruction.Instruct
 			if err != nil {
 				return 0, fmt.Errorf("error calling method %s: %w", methodName, err)
 			}
+			if err := vm.allocateHostResult(result, allArgs); err != nil {
+				return 0, err
+			}
 
 			// Push result back to stack if not nil
 			if result != nil {
 				stack.Push(result)
 			}
 			if exec.vm.debug {
-				fmt.Printf("Stack after CALL_METHOD %s (builtin2): %v\n", methodName, stack.Items())
+				exec.vm.tracef("Stack after CALL_METHOD %s (builtin): %v\n", methodName, stack.Items())
 			}
 			return pc + 1, nil
-		} else {
-			return 0, fmt.Errorf("undefined method: %s", methodName)
-		}
-	}
-}
-
-// getStructTypeName extracts the type name from a struct receiver
-func getStructTypeName(receiver interface{}) string {
-	if structMap, ok := receiver.(map[string]interface{}); ok {
-		// First check for explicit type field
-		if typeName, exists := structMap["_type"]; exists {
-			if name, ok := typeName.(string); ok {
-				return name
-			}
 		}
-		// Fallback: try to infer from keys
-		for key := range structMap {
-			if key != "width" && key != "height" && key != "radius" && key != "_type" && key != "name" && key != "age" {
-				return key
-			}
+		if typeName, ok := types.StructTypeName(receiver); ok {
+			return 0, fmt.Errorf("undefined method: %s (type %s has no method %s)", methodName, typeName, methodName)
 		}
+		return 0, fmt.Errorf("undefined method: %s", methodName)
 	}
-	return "unknown"
 }
 
 // handleImport handles the IMPORT opcode
@@ -1210,23 +1317,18 @@ func (exec *Executor) handleImport(stack *Stack, instr *instruction.Instruction,
 		return 0, fmt.Errorf("invalid package name")
 	}
 
-	// Check if this is a builtin module and register it on-demand
-	modules := builtin.ListAllModules()
-	for _, moduleName := range modules {
-		// Match either by module name or by import path
-		if moduleName == pkgName || moduleName == importPath {
-			if _, exists := exec.vm.GetModule(moduleName); exists {
-				break
-			}
-			// Register the module with the VM
-			moduleExecutor, exists := builtin.GetModuleExecutor(moduleName)
-			if exists {
-				exec.vm.RegisterModule(moduleName, moduleExecutor)
-			}
-			break
+	if err := exec.vm.CheckModule(importPath); err != nil {
+		return 0, err
+	}
+	if !instr.HasTag(instruction.TagImport) {
+		if err := exec.approve(BoundaryEvent{Tag: instruction.TagImport, Name: importPath, Instruction: instr}); err != nil {
+			return 0, err
 		}
 	}
 
+	// Check if this is a builtin module and register it on-demand
+	exec.vm.loadBuiltinModule(importPath, pkgName)
+
 	// In the VM context, we can't directly access the module manager
 	// The module importing should be handled at the Script level
 	// For now, we'll just create a placeholder variable
@@ -1253,7 +1355,7 @@ func (exec *Executor) prepareArguments(stack *Stack, argCount int) ([]interface{
 		args[i] = stack.Pop()
 	}
 
-	return args, nil
+	return expandSpread(args), nil
 }
 
 // pushArgumentsBack pushes arguments back to the stack
@@ -0,0 +1,51 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Finalizer releases a resource, such as a file, an HTTP response body or a
+// database cursor, held on behalf of a script
+type Finalizer func() error
+
+// OnExecutionEnd registers a finalizer that runs when the current execution
+// ends, whether it succeeds or fails. Host functions and modules call it
+// while the script runs to tie a resource to that execution. Finalizers run
+// in reverse registration order, before module instances are closed.
+func (vm *VM) OnExecutionEnd(fn Finalizer) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.finalizers = append(vm.finalizers, fn)
+}
+
+// runFinalizers runs and forgets the finalizers of the current execution
+func (vm *VM) runFinalizers() error {
+	vm.mu.Lock()
+	finalizers := vm.finalizers
+	vm.finalizers = nil
+	vm.mu.Unlock()
+
+	return RunFinalizers(finalizers)
+}
+
+// RunFinalizers runs finalizers in reverse order and joins their errors.
+// A panicking finalizer is reported as an error and does not stop the rest.
+func RunFinalizers(finalizers []Finalizer) error {
+	var errs []error
+	for i := len(finalizers) - 1; i >= 0; i-- {
+		if err := runFinalizer(finalizers[i]); err != nil {
+			errs = append(errs, fmt.Errorf("finalizer failed: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runFinalizer(fn Finalizer) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
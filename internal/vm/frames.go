@@ -0,0 +1,263 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/context"
+)
+
+// Call frames. Calls of script functions made by instructions do not
+// recurse into a new executor: the handler of the call prepares the
+// function's scope and returns errCall, and the executor loop suspends the
+// caller in a new frame (its instructions, return address, operand stack
+// and scope) and continues with the callee. Returning pops the frame and
+// resumes the caller with the result on its stack. Calls made by the host
+// or by host functions run to completion in a loop of their own.
+
+// errCall is returned by handlers that prepared a script call (see
+// Executor.enterCall) for the executor loop to run
+var errCall = errors.New("script call")
+
+// callFrame is a script function being executed
+type callFrame struct {
+	// Key of the function, e.g. main.main
+	function string
+
+	// For frames entered by the executor loop, the caller to resume when
+	// the function returns and the call, which wraps errors of the
+	// function and memoizes results of pure functions
+	caller activation
+	call   scriptCall
+	memo   memoKey
+	pure   bool
+
+	// Mutexes locked by the function, released when it returns
+	mutexes []*heldMutex
+
+	// Profiling data: the last source line executed in the frame, when the
+	// frame was entered, the time spent in its callees and the number of
+	// instructions it executed itself
+	line         int
+	start        time.Time
+	childTime    time.Duration
+	instructions int64
+}
+
+// activation is the execution state of a function in the executor loop
+type activation struct {
+	instructions []*instruction.Instruction
+	pc           int
+	stack        *Stack
+	ctx          *context.Context
+
+	// Slots of the local variables the compiler resolved (see locals.go)
+	locals []interface{}
+
+	// Last source line stepped in the function
+	lastLine int
+
+	// The call instruction a suspended caller resumes after
+	instr *instruction.Instruction
+}
+
+// scriptCall is a call of a script function prepared by a handler: the
+// instructions of the function and its scope, with the parameters bound
+type scriptCall struct {
+	key          string
+	instructions []*instruction.Instruction
+	ctx          *context.Context
+	args         []interface{}
+
+	// errFormat wraps the errors of the function with errName, e.g.,
+	// "error executing function %s: %w"
+	errFormat string
+	errName   string
+}
+
+// wrap wraps an error of the called function as the caller reports it
+func (call *scriptCall) wrap(err error) error {
+	return fmt.Errorf(call.errFormat, call.errName, err)
+}
+
+// newScriptCall binds the arguments of a call of a script function to its
+// parameters in a new scope. info may be nil for instruction sets without
+// function information, whose parameters are named arg0, arg1 and so on.
+func (vm *VM) newScriptCall(key string, info *ScriptFunctionInfo, args []interface{}) (scriptCall, error) {
+	instructions, exists := vm.GetInstructionSet(key)
+	if !exists {
+		return scriptCall{}, fmt.Errorf("undefined function: %s", key)
+	}
+	args, err := packVariadic(info, args)
+	if err != nil {
+		return scriptCall{}, err
+	}
+	args = copyValues(args)
+	ctx := context.NewContext(key, vm.functionParent())
+	for i, arg := range args {
+		ctx.CreateVariableWithType(paramName(info, i), arg, "unknown")
+	}
+	return scriptCall{key: key, instructions: instructions, ctx: ctx, args: args}, nil
+}
+
+// paramName returns the name of the i-th parameter of a script function
+func paramName(info *ScriptFunctionInfo, i int) string {
+	if info != nil && i < len(info.ParamNames) {
+		return info.ParamNames[i]
+	}
+	return "arg" + strconv.Itoa(i)
+}
+
+// enterCall hands a prepared call to the executor loop; handlers return
+// its result
+func (exec *Executor) enterCall(call scriptCall, errFormat, errName string) error {
+	call.errFormat, call.errName = errFormat, errName
+	exec.call = call
+	return errCall
+}
+
+// finishCall completes a call a handler handed to the executor loop when
+// the handler was not called by the loop (e.g., a call by the host), and
+// pushes its result
+func (exec *Executor) finishCall(stack *Stack, err error) error {
+	if err != errCall {
+		return err
+	}
+	call := exec.call
+	exec.call = scriptCall{}
+	result, err := exec.vm.runCall(call)
+	if err != nil {
+		return call.wrap(err)
+	}
+	if result != nil {
+		stack.Push(result)
+	}
+	return nil
+}
+
+// runCall runs a prepared call to completion in a loop of its own
+func (vm *VM) runCall(call scriptCall) (interface{}, error) {
+	previousCtx := vm.currentCtx
+	vm.currentCtx = call.ctx
+	defer func() { vm.currentCtx = previousCtx }()
+	return vm.runFunction(call.key, call.instructions, call.args)
+}
+
+// enter suspends the running function after the call instruction instr
+// and continues with the call its handler prepared, in a new frame.
+// Results of pure functions already memoized are pushed without a call.
+func (exec *Executor) enter(cur *activation, instr *instruction.Instruction, returnPC int) error {
+	vm := exec.vm
+	call := exec.call
+	exec.call = scriptCall{}
+
+	memo, pure := memoKey{}, false
+	if vm.functionHints(call.key).Pure {
+		memo, pure = newMemoKey(call.key, call.args)
+		if result, hit := vm.memo[memo]; pure && hit {
+			if result != nil {
+				cur.stack.Push(result)
+			}
+			cur.pc = returnPC
+			return nil
+		}
+	}
+	if vm.maxCallDepth > 0 && vm.callDepth >= vm.maxCallDepth {
+		return call.wrap(fmt.Errorf("maximum call depth exceeded: %d", vm.maxCallDepth))
+	}
+
+	cur.pc, cur.ctx, cur.instr = returnPC, vm.currentCtx, instr
+	vm.callDepth++
+	vm.pushFrame(call.key)
+	frame := &vm.frames[len(vm.frames)-1]
+	frame.caller, frame.call, frame.memo, frame.pure = *cur, call, memo, pure
+
+	*cur = activation{instructions: call.instructions, stack: exec.newStack()}
+	vm.currentCtx = call.ctx
+	return nil
+}
+
+// leave returns from the innermost frame entered by the executor loop,
+// resuming the caller with the result on its stack
+func (exec *Executor) leave(cur *activation, result interface{}) {
+	vm := exec.vm
+	frame := &vm.frames[len(vm.frames)-1]
+	if frame.pure && len(vm.memo) < maxMemoEntries {
+		if vm.memo == nil {
+			vm.memo = make(map[memoKey]interface{})
+		}
+		vm.memo[frame.memo] = result
+	}
+	caller := frame.caller
+
+	exec.release(cur)
+	vm.popFrame()
+	vm.callDepth--
+
+	*cur = caller
+	vm.currentCtx = caller.ctx
+	if result != nil {
+		cur.stack.Push(result)
+	}
+}
+
+// unwind returns an error through the frames the executor loop entered
+// above base, wrapping it as each call reports it and adding the callers
+// to its stack trace
+func (exec *Executor) unwind(cur *activation, base int, err error) error {
+	vm := exec.vm
+	for len(vm.frames) > base {
+		frame := &vm.frames[len(vm.frames)-1]
+		caller, call := frame.caller, frame.call
+
+		exec.release(cur)
+		vm.popFrame()
+		vm.callDepth--
+
+		*cur = caller
+		vm.currentCtx = caller.ctx
+		err = vm.traceError(caller.instr, call.wrap(err))
+	}
+	return err
+}
+
+// release returns the operand stack and local variable slots of a
+// finished function to the pools
+func (exec *Executor) release(cur *activation) {
+	exec.recordStackHighWater(cur.stack)
+	exec.releaseStack(cur.stack)
+	exec.vm.releaseLocals(cur.locals)
+}
+
+// pushFrame records the call of a script function on the call-frame stack
+func (vm *VM) pushFrame(function string) {
+	frame := callFrame{function: function}
+	if vm.profile != nil {
+		vm.profile.enter(vm.frames, function)
+		frame.start = time.Now()
+	}
+	vm.frames = append(vm.frames, frame)
+}
+
+// popFrame removes the innermost frame from the call-frame stack
+func (vm *VM) popFrame() {
+	if vm.profile != nil {
+		vm.profile.exit(vm.frames)
+	}
+	for _, held := range vm.frames[len(vm.frames)-1].mutexes {
+		held.release()
+	}
+	vm.frames[len(vm.frames)-1] = callFrame{}
+	vm.frames = vm.frames[:len(vm.frames)-1]
+}
+
+// currentFunction returns the key of the script function being executed
+func (vm *VM) currentFunction() string {
+	if n := len(vm.frames); n > 0 {
+		return vm.frames[n-1].function
+	}
+	return ""
+}
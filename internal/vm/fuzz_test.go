@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// decodeInstructions turns arbitrary bytes into an instruction stream.
+// Each instruction consumes three bytes: opcode, operand kind, operand value.
+func decodeInstructions(data []byte) []*instruction.Instruction {
+	names := []string{"x", "y", "len", "p", "strings", "main.main"}
+	var instructions []*instruction.Instruction
+	for i := 0; i+2 < len(data); i += 3 {
+		op := instruction.OpCode(data[i] % byte(instruction.OpCodeLast+1))
+		value := int(data[i+2])
+
+		var arg, arg2 interface{}
+		switch data[i+1] % 5 {
+		case 0:
+			arg = value
+		case 1:
+			arg = names[value%len(names)]
+			arg2 = value % 4
+		case 2:
+			arg = instruction.BinaryOp(value % 16)
+		case 3:
+			arg = names[value%len(names)]
+			arg2 = names[(value+1)%len(names)]
+		}
+		instructions = append(instructions, instruction.NewInstruction(op, arg, arg2))
+	}
+	return instructions
+}
+
+func FuzzExecute(f *testing.F) {
+	f.Add([]byte{byte(instruction.OpLoadConst), 0, 10, byte(instruction.OpLoadConst), 0, 20, byte(instruction.OpBinaryOp), 2, 0, byte(instruction.OpReturn), 4, 0})
+	f.Add([]byte{byte(instruction.OpCreateVar), 1, 0, byte(instruction.OpLoadConst), 0, 1, byte(instruction.OpStoreName), 1, 0, byte(instruction.OpJump), 0, 0})
+	f.Add([]byte{byte(instruction.OpExitScopeWithKey), 4, 0, byte(instruction.OpExitScopeWithKey), 4, 0, byte(instruction.OpLoadName), 1, 0})
+	f.Add([]byte{byte(instruction.OpCall), 1, 5})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		instructions := decodeInstructions(data)
+		if err := Verify(instructions, 256); err != nil {
+			return
+		}
+
+		vm := NewVM()
+		vm.SetMaxInstructions(1000)
+		vm.RegisterFunction("len", func(args ...interface{}) (interface{}, error) {
+			return len(args), nil
+		})
+		vm.AddInstructionSet("main.main", instructions)
+
+		// Any outcome is acceptable as long as it is not a panic, which
+		// Execute recovers into a PanicError
+		_, err := vm.Execute("main.main")
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			t.Fatalf("%v\n%s", panicErr, panicErr.Stack)
+		}
+	})
+}
+
+func TestVerifyRejectsMalformedInstructions(t *testing.T) {
+	tests := [][]*instruction.Instruction{
+		{nil},
+		{instruction.NewInstruction(instruction.OpCodeLast, nil)},
+		{instruction.NewInstruction(instruction.OpJump, 5)},
+		{instruction.NewInstruction(instruction.OpJump, "label")},
+		{instruction.NewInstruction(instruction.OpLoadName, 1)},
+		{instruction.NewInstruction(instruction.OpCall, "f", -1)},
+		{instruction.NewInstruction(instruction.OpBinaryOp, instruction.BinaryOp(99))},
+		{instruction.NewInstruction(instruction.OpNewSlice, -1)},
+		{instruction.NewInstruction(instruction.OpStoreLocal, 8000000000, "x")},
+	}
+
+	for i, instructions := range tests {
+		if err := Verify(instructions, 0); err == nil {
+			t.Errorf("case %d: expected verification error", i)
+		}
+	}
+
+	valid := []*instruction.Instruction{
+		instruction.NewInstruction(instruction.OpLoadConst, 1),
+		instruction.NewInstruction(instruction.OpJump, 2),
+		instruction.NewInstruction(instruction.OpReturn, nil),
+	}
+	if err := Verify(valid, 0); err != nil {
+		t.Errorf("Unexpected verification error: %v", err)
+	}
+}
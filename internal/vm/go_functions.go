@@ -0,0 +1,23 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// RegisterGoFunction registers a Go function of any signature, e.g.
+// func(int, string) (bool, error), that can be called from scripts. The
+// arguments are checked and converted to the parameter types like those of
+// host object methods.
+func (vm *VM) RegisterGoFunction(name string, fn interface{}) error {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		return fmt.Errorf("cannot register %s: %T is not a function", name, fn)
+	}
+	vm.bindFunction(name, func(vm *VM) ScriptFunction {
+		return func(args ...interface{}) (interface{}, error) {
+			return vm.callReflected(name, rv, args)
+		}
+	})
+	return nil
+}
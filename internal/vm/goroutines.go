@@ -0,0 +1,221 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/context"
+)
+
+// DefaultMaxGoroutines is the default limit on goroutines started by one
+// execution that have not finished
+const DefaultMaxGoroutines = 100
+
+// Goroutines. A go statement runs a call in a host goroutine of its own, but
+// only one goroutine executes instructions at a time: they share the VM and
+// take turns holding the scheduler lock, handing it over when they block on
+// a channel or finish. Scheduling is cooperative, so a goroutine that never
+// blocks runs until it returns. As in Go, the execution ends when main
+// returns and goroutines still running are stopped.
+
+// errDeadlock is returned when every goroutine is blocked
+var errDeadlock = errors.New("all goroutines are asleep - deadlock!")
+
+// errGoroutinesStopped is returned by blocked operations of goroutines that
+// are stopped because the execution ended
+var errGoroutinesStopped = errors.New("goroutines stopped")
+
+// scheduler coordinates the goroutines of one execution. It is created by
+// the first go statement.
+type scheduler struct {
+	// mu is held by the goroutine executing instructions
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// running counts the goroutines that are not blocked, including main;
+	// waiting counts those blocked on channels
+	running int
+	waiting int
+
+	// live counts the goroutines started and not finished, excluding main
+	live int
+	wg   sync.WaitGroup
+
+	// stopped is set when the execution ends or fails; err is the first
+	// error of a goroutine, or a deadlock
+	stopped bool
+	err     error
+}
+
+// SetMaxGoroutines sets the maximum number of goroutines of one execution
+// that have not finished (0 means no limit)
+func (vm *VM) SetMaxGoroutines(max int) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.maxGoroutines = max
+}
+
+// GetMaxGoroutines returns the maximum number of goroutines of one execution
+func (vm *VM) GetMaxGoroutines() int {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.maxGoroutines
+}
+
+// handleGo handles the GO opcode: the operands of a CALL are taken from the
+// stack and the call runs in a new goroutine
+func (exec *Executor) handleGo(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	argCount, ok := instr.Arg2.(int)
+	if !ok {
+		return 0, fmt.Errorf("invalid argument count for GO")
+	}
+	// Function value calls have the function below the arguments
+	n := argCount
+	if instr.Arg == nil {
+		n++
+	}
+	if stack.Len() < n {
+		return 0, fmt.Errorf("stack underflow for GO")
+	}
+	operands := make([]interface{}, n)
+	for i := n - 1; i >= 0; i-- {
+		operands[i] = stack.Pop()
+	}
+
+	call := *instr
+	call.Op = instruction.OpCall
+	if err := exec.vm.spawn(&call, operands); err != nil {
+		return 0, err
+	}
+	return pc + 1, nil
+}
+
+// spawn starts a goroutine executing call with its operands
+func (vm *VM) spawn(call *instruction.Instruction, operands []interface{}) error {
+	s := vm.sched
+	if s == nil {
+		s = &scheduler{running: 1}
+		s.cond = sync.NewCond(&s.mu)
+		s.mu.Lock()
+		vm.sched = s
+	}
+	if vm.maxGoroutines > 0 && s.live >= vm.maxGoroutines {
+		return fmt.Errorf("too many goroutines: limit is %d", vm.maxGoroutines)
+	}
+	s.live++
+	s.running++
+	s.wg.Add(1)
+	go vm.runGoroutine(s, call, operands, vm.currentCtx, vm.callDepth)
+	return nil
+}
+
+// runGoroutine executes a goroutine once it holds the scheduler lock. The
+// call sees the scope of the go statement, like a direct call.
+func (vm *VM) runGoroutine(s *scheduler, call *instruction.Instruction, operands []interface{}, ctx *context.Context, depth int) {
+	defer s.wg.Done()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		// A goroutine has a call stack of its own
+		vm.currentCtx, vm.callDepth, vm.frames, vm.frame = ctx, depth, nil, nil
+		if err := vm.runGoroutineCall(call, operands); err != nil && !s.stopped {
+			s.fail(fmt.Errorf("goroutine: %w", err))
+		}
+	}
+	s.live--
+	s.running--
+	if s.running == 0 && s.waiting > 0 {
+		s.fail(errDeadlock)
+	}
+}
+
+// runGoroutineCall executes the call of a goroutine
+func (vm *VM) runGoroutineCall(call *instruction.Instruction, operands []interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+	}()
+	exec := NewExecutor(vm)
+	stack := NewStackWithLimits(vm.stackInitialSize, vm.stackMaxSize)
+	for _, operand := range operands {
+		stack.Push(operand)
+	}
+	_, err = exec.handleCall(stack, call, 0)
+	return exec.finishCall(stack, err)
+}
+
+// wait blocks the running goroutine until another goroutine operates on a
+// channel, then restores its state. It fails when no goroutine can run.
+func (vm *VM) wait() error {
+	s := vm.sched
+	if s == nil {
+		return errDeadlock
+	}
+	if s.stopped {
+		return s.stopError()
+	}
+
+	s.running--
+	s.waiting++
+	if s.running == 0 {
+		s.fail(errDeadlock)
+		return errDeadlock
+	}
+	ctx, depth, frames, frame := vm.currentCtx, vm.callDepth, vm.frames, vm.frame
+	s.cond.Wait()
+	vm.currentCtx, vm.callDepth, vm.frames, vm.frame = ctx, depth, frames, frame
+
+	if s.stopped {
+		return s.stopError()
+	}
+	return nil
+}
+
+// notify wakes the goroutines blocked on channels so they check again
+func (vm *VM) notify() {
+	if vm.sched != nil {
+		vm.sched.wake()
+	}
+}
+
+// wake marks the blocked goroutines as running and wakes them
+func (s *scheduler) wake() {
+	s.running += s.waiting
+	s.waiting = 0
+	s.cond.Broadcast()
+}
+
+// fail stops all goroutines, recording the first error
+func (s *scheduler) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	s.stopped = true
+	s.wake()
+}
+
+// stopError returns the error that stopped the goroutines
+func (s *scheduler) stopError() error {
+	if s.err != nil {
+		return s.err
+	}
+	return errGoroutinesStopped
+}
+
+// stopGoroutines ends the goroutines of an execution and waits for them to
+// return
+func (vm *VM) stopGoroutines() {
+	s := vm.sched
+	if s == nil {
+		return
+	}
+	s.stopped = true
+	s.wake()
+	s.mu.Unlock()
+	s.wg.Wait()
+	vm.sched = nil
+}
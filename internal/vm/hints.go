@@ -0,0 +1,148 @@
+package vm
+
+import (
+	"reflect"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/types"
+)
+
+// maxMemoEntries bounds the results memoized in one execution
+const maxMemoEntries = 10000
+
+// maxFreeStacks bounds the operand stacks kept for reuse
+const maxFreeStacks = 64
+
+// FunctionHints are properties of a script function derived when its
+// instructions are linked, used to pick a faster way to call it
+type FunctionHints struct {
+	// SelfRecursive is set for functions that call themselves directly
+	SelfRecursive bool
+
+	// Pure is set for functions annotated with //goscript:pure. Their
+	// results are memoized by argument for the rest of the execution.
+	Pure bool
+}
+
+// FunctionHints returns the hints of the script function with the given
+// key (e.g., "main.func.fib") or name
+func (vm *VM) FunctionHints(keyOrName string) (FunctionHints, bool) {
+	info := vm.lookupScriptFunction(keyOrName)
+	if info == nil {
+		return FunctionHints{}, false
+	}
+	return vm.functionHints(info.Key), true
+}
+
+// functionHints returns the hints of a script function, linking all script
+// functions on first use
+func (vm *VM) functionHints(key string) FunctionHints {
+	vm.mu.RLock()
+	hints := vm.hints
+	vm.mu.RUnlock()
+
+	if hints == nil {
+		hints = vm.link()
+	}
+	return hints[key]
+}
+
+// link derives the hints of every script function from its instructions
+func (vm *VM) link() map[string]FunctionHints {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	hints := make(map[string]FunctionHints, len(vm.scriptFunctionInfos))
+	for _, info := range vm.scriptFunctionInfos {
+		h := FunctionHints{Pure: info.Pure}
+		for _, instr := range vm.InstructionSets[info.Key] {
+			// Script calls are the untagged ones; host and module calls
+			// are tagged
+			if instr.Op != instruction.OpCall || instr.Tags != 0 {
+				continue
+			}
+			if name, ok := instr.Arg.(string); ok && (name == info.Name || name == info.Key) {
+				h.SelfRecursive = true
+				break
+			}
+		}
+		hints[info.Key] = h
+	}
+	vm.hints = hints
+	return hints
+}
+
+// runFunction executes the instructions of a script function in the
+// current context, applying the function's hints
+func (vm *VM) runFunction(key string, instructions []*instruction.Instruction, args []interface{}) (interface{}, error) {
+	vm.pushFrame(key)
+	defer vm.popFrame()
+	hints := vm.functionHints(key)
+	if !hints.Pure {
+		return NewExecutor(vm).executeInstructions(instructions)
+	}
+
+	call, ok := newMemoKey(key, args)
+	if !ok {
+		return NewExecutor(vm).executeInstructions(instructions)
+	}
+	if result, hit := vm.memo[call]; hit {
+		return result, nil
+	}
+	result, err := NewExecutor(vm).executeInstructions(instructions)
+	if err == nil && len(vm.memo) < maxMemoEntries {
+		if vm.memo == nil {
+			vm.memo = make(map[memoKey]interface{})
+		}
+		vm.memo[call] = result
+	}
+	return result, err
+}
+
+// newStack returns an operand stack for a frame
+func (exec *Executor) newStack() *Stack {
+	vm := exec.vm
+	if len(vm.freeStacks) > 0 {
+		stack := vm.freeStacks[len(vm.freeStacks)-1]
+		vm.freeStacks = vm.freeStacks[:len(vm.freeStacks)-1]
+		return stack
+	}
+	return NewStackWithLimits(vm.stackInitialSize, vm.stackMaxSize)
+}
+
+// releaseStack returns the stack of a finished frame to the pool
+func (exec *Executor) releaseStack(stack *Stack) {
+	vm := exec.vm
+	if len(vm.freeStacks) < maxFreeStacks {
+		stack.Reset()
+		vm.freeStacks = append(vm.freeStacks, stack)
+	}
+}
+
+// memoKey identifies a call of a pure function with up to four comparable
+// arguments
+type memoKey struct {
+	key  string
+	argc int
+	args [4]interface{}
+}
+
+// newMemoKey builds the memo key of a call, or reports false if the call
+// cannot be memoized
+func newMemoKey(key string, args []interface{}) (memoKey, bool) {
+	k := memoKey{key: key, argc: len(args)}
+	if len(args) > len(k.args) {
+		return k, false
+	}
+	for i, arg := range args {
+		// Structs compare by identity but are mutable
+		if _, isStruct := arg.(*types.Struct); isStruct {
+			return k, false
+		}
+		if arg != nil && !reflect.TypeOf(arg).Comparable() {
+			return k, false
+		}
+		k.args[i] = arg
+	}
+	return k, true
+}
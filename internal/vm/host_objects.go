@@ -0,0 +1,239 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/types"
+)
+
+// Host objects. RegisterObject makes a Go struct available to scripts as a
+// global variable: scripts read its exported fields, like values of types
+// allowed with AllowType, and call its exported methods. Arguments are
+// converted to the parameter types of the method, and results back to
+// script values.
+
+// errorType is the reflected error interface
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterObject exposes obj, a struct or a pointer to a struct, to scripts
+// as the global variable name. Struct values are copied, so methods with
+// pointer receivers can be called as well.
+func (vm *VM) RegisterObject(name string, obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	switch {
+	case rv.Kind() == reflect.Struct:
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		rv = ptr
+	case rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct:
+		return fmt.Errorf("cannot register %s: %T is not a struct or a pointer to a struct", name, obj)
+	}
+
+	t := rv.Elem().Type()
+	vm.mu.RLock()
+	_, allowed := vm.allowedTypes[t]
+	vm.mu.RUnlock()
+	// Keep the fields of types already allowed with AllowType
+	if !allowed {
+		if err := vm.AllowType(t); err != nil {
+			return err
+		}
+	}
+
+	vm.mu.Lock()
+	vm.objectTypes[t] = true
+	vm.mu.Unlock()
+	return vm.GlobalCtx.CreateVariableWithType(name, rv.Interface(), "object")
+}
+
+// hostObject returns the reflected value of a value whose type was
+// registered with RegisterObject
+func (vm *VM) hostObject(value interface{}) (reflect.Value, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	vm.mu.RLock()
+	registered := vm.objectTypes[rv.Elem().Type()]
+	vm.mu.RUnlock()
+	return rv, registered
+}
+
+// isObjectMethodCall reports whether a call is a method call on a host
+// object, e.g. cfg.GetTimeout(): the receiver is the first argument
+func (exec *Executor) isObjectMethodCall(functionName string, args []interface{}) bool {
+	if len(args) == 0 {
+		return false
+	}
+	object, ok := exec.vm.hostObject(args[0])
+	return ok && object.MethodByName(functionName).IsValid()
+}
+
+// handleObjectMethodCall calls a method of a host object and pushes its
+// result
+func (exec *Executor) handleObjectMethodCall(stack *Stack, functionName string, args []interface{}, pc int) (int, error) {
+	object, _ := exec.vm.hostObject(args[0])
+	result, err := exec.vm.callObjectMethod(object, functionName, args[1:])
+	if err != nil {
+		return 0, fmt.Errorf("error calling method %s: %w", functionName, err)
+	}
+	if result != nil {
+		stack.Push(result)
+	}
+	return pc + 1, nil
+}
+
+// callObjectMethod calls an exported method of a host object
+func (vm *VM) callObjectMethod(object reflect.Value, methodName string, args []interface{}) (interface{}, error) {
+	method := object.MethodByName(methodName)
+	if !method.IsValid() {
+		return nil, fmt.Errorf("%s has no method %s", object.Type(), methodName)
+	}
+	return vm.callReflected(methodName, method, args)
+}
+
+// callReflected calls a Go function or method with script arguments,
+// converted to its parameter types in the conversion mode of the VM. Functions returning an error as their
+// last result fail with it; several remaining results are returned as a
+// Tuple.
+func (vm *VM) callReflected(name string, fn reflect.Value, args []interface{}) (interface{}, error) {
+	fnType := fn.Type()
+	want := fnType.NumIn()
+	if fnType.IsVariadic() {
+		want--
+		if len(args) < want {
+			return nil, fmt.Errorf("not enough arguments in call to %s: have %d, want at least %d", name, len(args), want)
+		}
+	} else if len(args) != want {
+		return nil, fmt.Errorf("wrong number of arguments in call to %s: have %d, want %d", name, len(args), want)
+	}
+	conv := converter{mode: vm.GetConversionMode()}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var paramType reflect.Type
+		if i >= want {
+			paramType = fnType.In(want).Elem()
+		} else {
+			paramType = fnType.In(i)
+		}
+		value, err := conv.convert(arg, paramType)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %w", i+1, name, err)
+		}
+		in[i] = value
+	}
+
+	out := fn.Call(in)
+	if n := len(out); n > 0 && fnType.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:n-1]
+	}
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		return vm.convertHostValue("", out[0].Interface()), nil
+	}
+	results := make(Tuple, len(out))
+	for i, value := range out {
+		results[i] = vm.convertHostValue("", value.Interface())
+	}
+	return results, nil
+}
+
+// Scan stores a script value in the Go variable ptr points to, converting
+// it as the arguments of host object methods; script structs and maps are
+// converted to Go structs by field name
+func Scan(value interface{}, ptr interface{}) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot scan into %T: not a non-nil pointer", ptr)
+	}
+	converted, err := hostArg(value, rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	rv.Elem().Set(converted)
+	return nil
+}
+
+// ScriptValue converts a Go value to a script value: integers and float32
+// become int and float64, as in NumberPreserveInt mode, and slices and
+// string-keyed maps, including nested ones, are copied to script slices and
+// maps
+func (vm *VM) ScriptValue(value interface{}) interface{} {
+	return vm.convertScriptValue(reflect.ValueOf(value), false)
+}
+
+// convertScriptValue converts a Go value as ScriptValue does, and Go
+// structs to script structs if structs is set
+func (vm *VM) convertScriptValue(rv reflect.Value, structs bool) interface{} {
+	mode := vm.GetNumberMode()
+	if mode == types.NumberFloat64 {
+		mode = types.NumberPreserveInt
+	}
+	return builtin.ConvertNumbers(scriptValue(rv, structs), mode)
+}
+
+// scriptValue copies Go slices and string-keyed maps to script slices and
+// maps. If structs is set, Go structs with exported fields, and pointers
+// to them, are copied to script structs of the same type name; other
+// structs, such as time.Time, stay host values.
+func scriptValue(rv reflect.Value, structs bool) interface{} {
+	switch {
+	case !rv.IsValid():
+		return nil
+	case structs && rv.Kind() == reflect.Struct && hasExportedFields(rv.Type()):
+		s := types.NewStruct(rv.Type().Name())
+		for i := 0; i < rv.NumField(); i++ {
+			if field := rv.Type().Field(i); field.IsExported() {
+				s.Fields[field.Name] = scriptValue(rv.Field(i), structs)
+			}
+		}
+		return s
+	case structs && rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() == reflect.Struct && hasExportedFields(rv.Type().Elem()):
+		if rv.IsNil() {
+			return nil
+		}
+		return scriptValue(rv.Elem(), structs).(*types.Struct).Addr()
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8:
+		if rv.IsNil() {
+			return []interface{}(nil)
+		}
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = scriptValue(rv.Index(i), structs)
+		}
+		return values
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		if rv.IsNil() {
+			return map[string]interface{}(nil)
+		}
+		values := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = scriptValue(iter.Value(), structs)
+		}
+		return values
+	case rv.Kind() == reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return scriptValue(rv.Elem(), structs)
+	}
+	return rv.Interface()
+}
+
+// hasExportedFields reports whether a Go struct type has exported fields
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package vm
+
+import (
+	"context"
+)
+
+// ContextFunction is a host function that receives the host context of the
+// current execution. Values attached by the host are available through
+// ctx.Value; they are never visible to the script itself.
+type ContextFunction func(ctx context.Context, args ...interface{}) (interface{}, error)
+
+// SetHostContext sets the host context passed to context-aware functions
+func (vm *VM) SetHostContext(ctx context.Context) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.hostCtx = ctx
+}
+
+// HostContext returns the host context of the current execution
+func (vm *VM) HostContext() context.Context {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	if vm.hostCtx == nil {
+		return context.Background()
+	}
+	return vm.hostCtx
+}
+
+// RegisterContextFunction registers a host function that is called with
+// the host context of the current execution
+func (vm *VM) RegisterContextFunction(name string, fn ContextFunction) {
+	vm.bindFunction(name, func(vm *VM) ScriptFunction {
+		return func(args ...interface{}) (interface{}, error) {
+			return fn(vm.HostContext(), args...)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Program images. The compiled form of a program (its instruction sets,
+// script functions, function literals and struct types) can be captured
+// from the VM it was compiled in as an Image and loaded into other VMs.
+// Instructions are not modified once the compiler has linked them, so the
+// VMs an image is loaded into share its instructions and can run
+// concurrently, each with its own globals, stacks and host functions.
+
+// Image is the immutable compiled form of a program
+type Image struct {
+	instructionSets map[string][]*instruction.Instruction
+	functions       map[string]*ScriptFunctionInfo
+	literals        map[string]*ScriptFunctionInfo
+	structs         map[string][]StructField
+}
+
+// Image captures the compiled program of the VM once it is compiled or
+// loaded. Later calls, and calls on VMs the image is loaded into, return the
+// same image.
+func (vm *VM) Image() *Image {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.image != nil {
+		return vm.image
+	}
+
+	img := &Image{
+		instructionSets: make(map[string][]*instruction.Instruction, len(vm.InstructionSets)),
+		functions:       make(map[string]*ScriptFunctionInfo, len(vm.scriptFunctionInfos)),
+		literals:        make(map[string]*ScriptFunctionInfo, len(vm.functionLiterals)),
+		structs:         make(map[string][]StructField, len(vm.structTypes)),
+	}
+	for key, instructions := range vm.InstructionSets {
+		img.instructionSets[key] = instructions
+	}
+	for name, info := range vm.scriptFunctionInfos {
+		img.functions[name] = info
+	}
+	for key, info := range vm.functionLiterals {
+		img.literals[key] = info
+	}
+	for name, fields := range vm.structTypes {
+		img.structs[name] = fields
+	}
+	vm.image = img
+	return img
+}
+
+// LoadImage loads a program image into the VM, which shares its
+// instructions
+func (vm *VM) LoadImage(img *Image) {
+	for key, instructions := range img.instructionSets {
+		vm.AddInstructionSet(key, instructions)
+	}
+	for name, info := range img.functions {
+		vm.RegisterScriptFunction(name, info)
+	}
+	for _, info := range img.literals {
+		vm.RegisterFunctionLiteral(info)
+	}
+	for name, fields := range img.structs {
+		vm.RegisterStructType(name, fields)
+	}
+	vm.mu.Lock()
+	vm.image = img
+	vm.mu.Unlock()
+}
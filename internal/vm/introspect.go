@@ -0,0 +1,110 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/types"
+)
+
+// registerUniverseBuiltins registers the builtin functions of the universe
+// block (len, cap, print, println, min, max, ...), so that scripts can call
+// them on any VM without host setup
+func (vm *VM) registerUniverseBuiltins() {
+	for name, fn := range builtin.BuiltInFunctions {
+		vm.functions[name] = ScriptFunction(fn)
+	}
+	vm.functions["append"] = vm.builtinAppend
+	vm.functions["[]byte"] = vm.builtinBytes
+	vm.functions["[]rune"] = vm.builtinRunes
+	vm.functions["deepEqual"] = vm.builtinDeepEqual
+	vm.functions["delete"] = vm.builtinDelete
+}
+
+// registerIntrospectionBuiltins registers the builtins that need access to
+// the VM: methods(v), callMethod(v, "Name", args...), hasFunction("name")
+// and hasModule("name")
+func (vm *VM) registerIntrospectionBuiltins() {
+	vm.functions["methods"] = vm.builtinMethods
+	vm.functions["callMethod"] = vm.builtinCallMethod
+	vm.functions["hasFunction"] = vm.builtinHasFunction
+	vm.functions["hasModule"] = vm.builtinHasModule
+}
+
+// MethodsOf returns the sorted names of the methods defined for a struct
+// value, including those with pointer receivers
+func (vm *VM) MethodsOf(value interface{}) []string {
+	typeName, ok := types.StructTypeName(value)
+	if !ok {
+		return []string{}
+	}
+
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for key := range vm.InstructionSets {
+		for _, prefix := range []string{typeName + ".", "*" + typeName + "."} {
+			if name, found := strings.CutPrefix(key, prefix); found && !strings.Contains(name, ".") && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// builtinMethods implements methods(v)
+func (vm *VM) builtinMethods(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("methods expects 1 argument, got %d", len(args))
+	}
+	names := vm.MethodsOf(args[0])
+	result := make([]interface{}, len(names))
+	for i, name := range names {
+		result[i] = name
+	}
+	return result, nil
+}
+
+// builtinCallMethod implements callMethod(v, "Name", args...)
+func (vm *VM) builtinCallMethod(args ...interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("callMethod expects at least 2 arguments, got %d", len(args))
+	}
+	methodName, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("callMethod: method name must be a string, got %T", args[1])
+	}
+
+	receiver := args[0]
+	methodArgs := args[2:]
+	if isTimeValue(receiver) {
+		return callTimeMethod(receiver, methodName, methodArgs)
+	}
+	if _, ok := types.StructFields(receiver); !ok {
+		return nil, fmt.Errorf("callMethod: unsupported receiver type %T", receiver)
+	}
+
+	// Dispatch exactly like a compiled method call
+	exec := NewExecutor(vm)
+	stack := NewStackWithLimits(len(args), vm.stackMaxSize)
+	stack.Push(receiver)
+	for _, arg := range methodArgs {
+		stack.Push(arg)
+	}
+	instr := instruction.NewInstruction(instruction.OpCallMethod, methodName, len(methodArgs))
+	_, err := exec.handleCallMethod(stack, instr, 0)
+	if err := exec.finishCall(stack, err); err != nil {
+		return nil, err
+	}
+	if stack.Len() == 0 {
+		return nil, nil
+	}
+	return stack.Pop(), nil
+}
@@ -0,0 +1,171 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/context"
+)
+
+// Local variable slots. The compiler resolves the local variables of a
+// function that are only loaded and stored by name to slots of the
+// function's frame: CREATE_VAR declares such a variable in the slot given
+// by its second argument, and LOAD_LOCAL and STORE_LOCAL access it by index
+// instead of looking its name up in the scope chain. Variables captured by
+// closures, addressed or called keep name-based access. Debuggers,
+// snapshots and watches find the slots declared before the current
+// instruction by name.
+
+// localSlots returns the number of local variable slots of the frame of an
+// instruction set, recorded in its function info
+func (vm *VM) localSlots(key string) int {
+	if info := vm.closureInfo(key); info != nil && info.Key == key {
+		return info.Locals
+	}
+	return 0
+}
+
+// handleLoadLocal handles the LOAD_LOCAL opcode
+func (exec *Executor) handleLoadLocal(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	slot, ok := instr.Arg.(int)
+	if !ok {
+		return 0, fmt.Errorf("invalid slot for LOAD_LOCAL")
+	}
+	var value interface{}
+	if locals := exec.vm.frame.locals; slot < len(locals) {
+		value = locals[slot]
+	}
+	stack.Push(value)
+	return pc + 1, nil
+}
+
+// handleStoreLocal handles the STORE_LOCAL opcode
+func (exec *Executor) handleStoreLocal(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	slot, ok := instr.Arg.(int)
+	if !ok {
+		return 0, fmt.Errorf("invalid slot for STORE_LOCAL")
+	}
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow")
+	}
+	exec.vm.setLocal(slot, copyValue(stack.Pop()))
+	return pc + 1, nil
+}
+
+// declareLocal declares a local variable in a slot of the current frame.
+// Parameters take the argument the call bound to their name; other
+// variables start as nil, also when a loop declares them again.
+func (vm *VM) declareLocal(slot int, name string) {
+	var value interface{}
+	if vm.currentCtx.HasVariable(name) {
+		value, _ = vm.currentCtx.GetVariable(name)
+	}
+	vm.setLocal(slot, value)
+}
+
+// setLocal stores a value in a local variable slot of the current frame
+func (vm *VM) setLocal(slot int, value interface{}) {
+	frame := vm.frame
+	if slot >= len(frame.locals) {
+		frame.locals = vm.growLocals(frame.locals, slot+1)
+	}
+	frame.locals[slot] = value
+}
+
+// growLocals extends the slots of a frame to n, reusing the slots of
+// finished frames
+func (vm *VM) growLocals(locals []interface{}, n int) []interface{} {
+	if n <= cap(locals) {
+		return locals[:n]
+	}
+	if len(locals) == 0 && len(vm.freeLocals) > 0 {
+		free := vm.freeLocals[len(vm.freeLocals)-1]
+		if n <= cap(free) {
+			vm.freeLocals = vm.freeLocals[:len(vm.freeLocals)-1]
+			return free[:n]
+		}
+	}
+	grown := make([]interface{}, n, max(n, 2*cap(locals), 8))
+	copy(grown, locals)
+	return grown
+}
+
+// releaseLocals returns the slots of a finished frame to the pool
+func (vm *VM) releaseLocals(locals []interface{}) {
+	if cap(locals) > 0 && len(vm.freeLocals) < maxFreeStacks {
+		clear(locals)
+		vm.freeLocals = append(vm.freeLocals, locals[:0])
+	}
+}
+
+// visibleLocals returns the slots of the local variables declared before
+// an instruction of a function and still in scope there, by name. Exits of
+// scopes that do not close the innermost one are left by break and
+// continue, and do not end any scope.
+func visibleLocals(instructions []*instruction.Instruction, end int) map[string]int {
+	type scope struct {
+		key    interface{}
+		locals map[string]int
+	}
+	scopes := []scope{{}}
+	for _, instr := range instructions[:min(end, len(instructions))] {
+		switch instr.Op {
+		case instruction.OpEnterScopeWithKey:
+			scopes = append(scopes, scope{key: instr.Arg})
+		case instruction.OpExitScopeWithKey:
+			if len(scopes) > 1 && scopes[len(scopes)-1].key == instr.Arg {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case instruction.OpCreateVar:
+			if slot, ok := instr.Arg2.(int); ok {
+				top := &scopes[len(scopes)-1]
+				if top.locals == nil {
+					top.locals = make(map[string]int)
+				}
+				top.locals[instr.Arg.(string)] = slot
+			}
+		}
+	}
+
+	visible := make(map[string]int)
+	for _, s := range scopes {
+		for name, slot := range s.locals {
+			visible[name] = slot
+		}
+	}
+	return visible
+}
+
+// frameLocals returns the values of the local variables in slots that are
+// in scope at the current instruction, by name
+func (vm *VM) frameLocals() map[string]interface{} {
+	frame := vm.frame
+	if frame == nil {
+		return nil
+	}
+	values := make(map[string]interface{})
+	for name, slot := range visibleLocals(frame.instructions, frame.pc) {
+		var value interface{}
+		if slot < len(frame.locals) {
+			value = frame.locals[slot]
+		}
+		values[name] = value
+	}
+	return values
+}
+
+// localsContext returns a scope holding the local variables in slots that
+// are in scope at the current instruction, nested in the current scope, for
+// evaluating expressions by name. It returns the current scope when there
+// are none.
+func (vm *VM) localsContext() *context.Context {
+	locals := vm.frameLocals()
+	if len(locals) == 0 {
+		return vm.currentCtx
+	}
+	ctx := context.NewContext("locals", vm.currentCtx)
+	for name, value := range locals {
+		ctx.CreateVariableWithType(name, value, "unknown")
+	}
+	return ctx
+}
@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/types"
+)
+
+// Maps with string keys are map[string]interface{}, the representation
+// host code and modules exchange with scripts. Maps with other key types
+// (e.g., map[int]string) are map[interface{}]interface{}.
+//
+// Script structs are *types.Struct values, so struct keys are matched by
+// value rather than by Go pointer: a key equal under == to an existing key
+// finds that key, and a new key is stored as a copy the script cannot
+// mutate afterwards. Finding a struct key scans the keys of the map.
+
+// handleNewMap handles the NEW_MAP opcode
+func (exec *Executor) handleNewMap(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	keyType, ok := instr.Arg.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid key type for NEW_MAP")
+	}
+	stack.Push(NewMap(keyType))
+	return pc + 1, nil
+}
+
+// NewMap creates an empty map for the given key type name
+func NewMap(keyType string) interface{} {
+	if keyType == "string" {
+		return make(map[string]interface{})
+	}
+	return make(map[interface{}]interface{})
+}
+
+// checkMapKey rejects keys that cannot be hashed, such as slices
+func checkMapKey(key interface{}) error {
+	if key != nil && !reflect.TypeOf(key).Comparable() {
+		return fmt.Errorf("invalid map key type %T", key)
+	}
+	return nil
+}
+
+// mapKey returns the key a value is stored under in a map with non-string
+// keys. Struct keys resolve to the equal key already in the map, or, when
+// inserting, to a copy of the struct; other keys are returned unchanged.
+func (vm *VM) mapKey(m map[interface{}]interface{}, key interface{}, insert bool) (interface{}, error) {
+	if err := checkMapKey(key); err != nil {
+		return nil, err
+	}
+	s, ok := key.(*types.Struct)
+	if !ok {
+		return key, nil
+	}
+	if !vm.comparableStruct(s) {
+		return nil, fmt.Errorf("invalid map key type %s", s.Type)
+	}
+
+	for existing := range m {
+		e, ok := existing.(*types.Struct)
+		if !ok || e.Type != s.Type {
+			continue
+		}
+		equal, err := vm.structsEqual(e, s)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			return e, nil
+		}
+	}
+	if insert {
+		return s.Copy(), nil
+	}
+	return s, nil
+}
+
+// comparableStruct reports whether a struct can be compared with == by
+// value, i.e. whether none of its fields is a slice, map or function
+func (vm *VM) comparableStruct(s *types.Struct) bool {
+	if fields, ok := vm.StructType(s.Type); ok {
+		for _, field := range fields {
+			if !comparableType(field.Type) {
+				return false
+			}
+		}
+	}
+	for _, value := range s.Fields {
+		if uncomparableKind(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// builtinDelete implements delete(m, key), resolving struct keys by value
+// before handing over to the builtin
+func (vm *VM) builtinDelete(args ...interface{}) (interface{}, error) {
+	if len(args) == 2 {
+		if m, ok := args[0].(map[interface{}]interface{}); ok {
+			key, err := vm.mapKey(m, args[1], false)
+			if err != nil {
+				return nil, fmt.Errorf("delete: %w", err)
+			}
+			args = []interface{}{m, key}
+		}
+	}
+	return builtin.Delete(args...)
+}
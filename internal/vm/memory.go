@@ -0,0 +1,220 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/types"
+)
+
+// Memory accounting. The VM estimates the bytes allocated by an execution
+// for the values it creates: slices, maps and their entries, structs and
+// concatenated strings, and for the values host functions, modules and
+// conversions such as []byte(s) return. Memory the script no longer uses
+// is not given back, so the usage only grows during an execution. When a
+// limit is set, the execution fails with ErrMemoryLimit as soon as the
+// usage exceeds it. Allocations whose size is known before they are made
+// (make, append growing a slice, string concatenation and conversions of
+// strings to []byte and []rune) are checked with reserve first, so a single
+// huge allocation fails before the host pays for it.
+
+// ErrMemoryLimit is the error of an execution exceeding its memory limit
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// Approximate sizes of the values created by the VM
+const (
+	interfaceSize    = 16
+	sliceHeaderSize  = 24
+	stringHeaderSize = 16
+	mapHeaderSize    = 48
+	mapEntrySize     = 64
+	structHeaderSize = 64
+)
+
+// allocatingOps marks the opcodes whose result is a newly created value
+var allocatingOps = [instruction.OpCodeLast]bool{
+	instruction.OpNewSlice:  true,
+	instruction.OpMakeSlice: true,
+	instruction.OpNewMap:    true,
+	instruction.OpNewStruct: true,
+	instruction.OpBinaryOp:  true,
+}
+
+// SetMaxMemory sets the approximate number of bytes an execution may
+// allocate (0 means no limit)
+func (vm *VM) SetMaxMemory(bytes int64) {
+	vm.maxMemory = bytes
+}
+
+// MemoryUsage returns the approximate number of bytes allocated by the
+// current or last execution
+func (vm *VM) MemoryUsage() int64 {
+	return vm.memoryUsage
+}
+
+// allocate accounts for bytes allocated by the execution
+func (vm *VM) allocate(bytes int64) error {
+	vm.memoryUsage += bytes
+	if vm.maxMemory > 0 && vm.memoryUsage > vm.maxMemory {
+		return fmt.Errorf("%w: %d bytes allocated, limit is %d bytes", ErrMemoryLimit, vm.memoryUsage, vm.maxMemory)
+	}
+	return nil
+}
+
+// reserve fails with ErrMemoryLimit when allocating bytes would take the
+// execution over its limit. It is called before the allocation is made;
+// the bytes are accounted by allocate once the value exists.
+func (vm *VM) reserve(bytes int64) error {
+	if vm.maxMemory > 0 && vm.memoryUsage+bytes > vm.maxMemory {
+		return fmt.Errorf("%w: %d more bytes requested, %d of %d bytes allocated", ErrMemoryLimit, bytes, vm.memoryUsage, vm.maxMemory)
+	}
+	return nil
+}
+
+// allocateResult accounts for the value an allocating instruction pushed
+func (exec *Executor) allocateResult(stack *Stack) error {
+	if stack.Len() == 0 {
+		return nil
+	}
+	return exec.vm.allocate(valueSize(stack.Peek()))
+}
+
+// allocateHostResult accounts for the result of a host function, module
+// function or conversion. Results sharing the memory of an argument, such
+// as a slice append did not grow or a substring, were accounted already.
+func (vm *VM) allocateHostResult(result interface{}, args []interface{}) error {
+	if results, ok := result.(Tuple); ok {
+		for _, r := range results {
+			if err := vm.allocateHostResult(r, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, arg := range args {
+		if sharesMemory(result, arg) {
+			return nil
+		}
+	}
+	return vm.allocate(valueSize(result))
+}
+
+// sharesMemory reports whether a value refers to the memory of another:
+// the same map or struct, or a string or slice within the other's bytes
+// or backing array
+func sharesMemory(value, other interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		o, ok := other.(string)
+		return ok && len(v) > 0 && within(unsafe.Pointer(unsafe.StringData(v)), unsafe.Pointer(unsafe.StringData(o)), len(o))
+	case *types.Struct:
+		o, ok := other.(*types.Struct)
+		return ok && v.Same(o)
+	}
+	rv, ro := reflect.ValueOf(value), reflect.ValueOf(other)
+	if !rv.IsValid() || !ro.IsValid() || rv.Kind() != ro.Kind() {
+		return false
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		return rv.UnsafePointer() == ro.UnsafePointer()
+	case reflect.Slice:
+		if rv.Type() != ro.Type() || rv.Cap() == 0 {
+			return false
+		}
+		size := int(ro.Type().Elem().Size())
+		return within(rv.UnsafePointer(), ro.UnsafePointer(), ro.Cap()*size)
+	}
+	return false
+}
+
+// within reports whether p points into the n bytes starting at base
+func within(p, base unsafe.Pointer, n int) bool {
+	return uintptr(p) >= uintptr(base) && uintptr(p) < uintptr(base)+uintptr(n)
+}
+
+// valueSize estimates the size of a value created by the VM; values that
+// do not allocate, such as numbers, have size 0
+func valueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case string:
+		return stringHeaderSize + int64(len(v))
+	case []interface{}:
+		return sliceHeaderSize + int64(cap(v))*interfaceSize
+	case []byte:
+		return sliceHeaderSize + int64(cap(v))
+	case map[string]interface{}:
+		return mapHeaderSize + int64(len(v))*mapEntrySize
+	case map[interface{}]interface{}:
+		return mapHeaderSize + int64(len(v))*mapEntrySize
+	case *types.Struct:
+		return structHeaderSize + int64(len(v.Fields))*mapEntrySize
+	default:
+		return 0
+	}
+}
+
+// builtinAppend implements append, copying the structs it appends. The
+// backing array it allocates when the slice grows is reserved first, and
+// accounted as the result of a host function.
+func (vm *VM) builtinAppend(args ...interface{}) (interface{}, error) {
+	if err := vm.reserve(appendSize(args)); err != nil {
+		return nil, err
+	}
+	return builtin.Append(copyValues(args)...)
+}
+
+// appendSize estimates the backing array append(args...) allocates, or 0
+// when the slice has room for the new elements. Like Go, append at least
+// doubles the capacity.
+func appendSize(args []interface{}) int64 {
+	if len(args) == 0 {
+		return 0
+	}
+	n := len(args) - 1
+	length, capacity, elemSize := 0, 0, int64(interfaceSize)
+	switch s := args[0].(type) {
+	case []interface{}:
+		length, capacity = len(s), cap(s)
+	case []byte:
+		length, capacity, elemSize = len(s), cap(s), 1
+	}
+	if length+n <= capacity {
+		return 0
+	}
+	return sliceHeaderSize + int64(max(2*capacity, length+n))*elemSize
+}
+
+// builtinBytes implements []byte(v), reserving the copy of a string
+func (vm *VM) builtinBytes(args ...interface{}) (interface{}, error) {
+	if len(args) == 1 {
+		if s, ok := args[0].(string); ok {
+			if err := vm.reserve(sliceHeaderSize + int64(len(s))); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return builtin.Bytes(args...)
+}
+
+// builtinRunes implements []rune(v), reserving a slice with room for a
+// rune per byte of the string
+func (vm *VM) builtinRunes(args ...interface{}) (interface{}, error) {
+	if len(args) == 1 {
+		var n int
+		switch v := args[0].(type) {
+		case string:
+			n = len(v)
+		case []byte:
+			n = len(v)
+		}
+		if err := vm.reserve(sliceHeaderSize + int64(n)*interfaceSize); err != nil {
+			return nil, err
+		}
+	}
+	return builtin.Runes(args...)
+}
@@ -0,0 +1,144 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/types"
+)
+
+// Method sets. The methods of script types are recorded when they are
+// registered (see ScriptFunctionInfo.Receiver), so interfaces can be
+// checked at runtime: IMPLEMENTS verifies that a value assigned to an
+// interface-typed variable or parameter has the methods of the interface,
+// and type switches and assertions match interfaces against them. Method
+// calls dispatch on the dynamic type of the receiver, so a call through an
+// interface value runs the method of the concrete type.
+
+// addMethodLocked records a method of a script type and whether it has a
+// pointer receiver; the caller holds vm.mu
+func (vm *VM) addMethodLocked(typeName, method string, pointer bool) {
+	typeName = strings.TrimPrefix(typeName, "*")
+	if vm.methodSets == nil {
+		vm.methodSets = make(map[string]map[string]bool)
+	}
+	if vm.methodSets[typeName] == nil {
+		vm.methodSets[typeName] = make(map[string]bool)
+	}
+	vm.methodSets[typeName][method] = true
+	if !pointer {
+		return
+	}
+	if vm.pointerMethods == nil {
+		vm.pointerMethods = make(map[string]map[string]bool)
+	}
+	if vm.pointerMethods[typeName] == nil {
+		vm.pointerMethods[typeName] = make(map[string]bool)
+	}
+	vm.pointerMethods[typeName][method] = true
+}
+
+// MethodSet returns the sorted names of the methods declared for a script
+// type, with value or pointer receivers
+func (vm *VM) MethodSet(typeName string) []string {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	methods := make([]string, 0, len(vm.methodSets[typeName]))
+	for method := range vm.methodSets[typeName] {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// hasMethod reports whether a value has the named method: a method of a
+// script struct type, a host function implementing it or a method of a
+// host object. As in Go, methods with pointer receivers belong to the
+// method set of struct pointers only.
+func (vm *VM) hasMethod(value interface{}, method string) bool {
+	if name, ok := types.StructTypeName(value); ok {
+		vm.mu.RLock()
+		declared := vm.methodSets[name][method]
+		pointerOnly := vm.pointerMethods[name][method] && !value.(*types.Struct).Pointer
+		vm.mu.RUnlock()
+		if declared {
+			return !pointerOnly
+		}
+		_, exists := vm.GetFunction(name + "." + method)
+		return exists
+	}
+	return value != nil && reflect.ValueOf(value).MethodByName(method).IsValid()
+}
+
+// hasPointerMethod reports whether a struct value lacks a method only
+// because the method has a pointer receiver
+func (vm *VM) hasPointerMethod(value interface{}, method string) bool {
+	s, ok := value.(*types.Struct)
+	if !ok || s.Pointer {
+		return false
+	}
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.pointerMethods[s.Type][method]
+}
+
+// hasPromotedMethod reports whether a value has the named method, declared
+// for its type or promoted from an embedded struct
+func (vm *VM) hasPromotedMethod(value interface{}, method string) bool {
+	if vm.hasMethod(value, method) {
+		return true
+	}
+	if name, ok := types.StructTypeName(value); ok {
+		sel, found, err := vm.promote(name, method)
+		return err == nil && found && sel.method
+	}
+	return false
+}
+
+// missingMethod returns the first method of an interface descriptor
+// ("interface{Area,Perimeter}") that a value does not have, or "" if it
+// has them all
+func (vm *VM) missingMethod(value interface{}, iface string) string {
+	methods := strings.TrimSuffix(strings.TrimPrefix(iface, "interface{"), "}")
+	if methods == "" {
+		return ""
+	}
+	for _, method := range strings.Split(methods, ",") {
+		if !vm.hasPromotedMethod(value, method) {
+			return method
+		}
+	}
+	return ""
+}
+
+// handleImplements handles the IMPLEMENTS opcode: the value on top of the
+// stack, which stays there, must be nil or have the methods of the
+// interface given by the second argument; the first is its name
+func (exec *Executor) handleImplements(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	name, ok := instr.Arg.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid interface name for IMPLEMENTS")
+	}
+	iface, ok := instr.Arg2.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid interface for IMPLEMENTS")
+	}
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for IMPLEMENTS")
+	}
+	value := stack.Peek()
+	if value == nil {
+		return pc + 1, nil
+	}
+	if method := exec.vm.missingMethod(value, iface); method != "" {
+		if exec.vm.hasPointerMethod(value, method) {
+			return 0, fmt.Errorf("%s does not implement %s (method %s has pointer receiver)", typeString(value), name, method)
+		}
+		return 0, fmt.Errorf("%s does not implement %s (missing method %s)", typeString(value), name, method)
+	}
+	return pc + 1, nil
+}
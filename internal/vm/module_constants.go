@@ -0,0 +1,32 @@
+package vm
+
+import (
+	"github.com/lengzhao/goscript/builtin"
+)
+
+// RegisterModuleConstants registers constants exported by a module, readable
+// from scripts as module selectors (e.g., config.Version). The compiler
+// resolves them to constant loads, so they must be registered before the
+// script is compiled.
+func (vm *VM) RegisterModuleConstants(moduleName string, constants map[string]interface{}) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.moduleConstants[moduleName] == nil {
+		vm.moduleConstants[moduleName] = make(map[string]interface{})
+	}
+	for name, value := range constants {
+		vm.moduleConstants[moduleName][name] = value
+	}
+}
+
+// GetModuleConstant returns a constant exported by a registered or builtin
+// module. Registered constants take precedence.
+func (vm *VM) GetModuleConstant(moduleName, name string) (interface{}, bool) {
+	vm.mu.RLock()
+	value, exists := vm.moduleConstants[moduleName][name]
+	vm.mu.RUnlock()
+	if exists {
+		return value, true
+	}
+	return builtin.GetModuleConstant(moduleName, name)
+}
@@ -0,0 +1,74 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// RegisterModuleFactory registers a module whose state is created per
+// execution. Instances are created lazily on first use and closed when
+// Execute returns, so concurrent VMs never share module state.
+func (vm *VM) RegisterModuleFactory(name string, factory types.ModuleFactory) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.moduleFactories[name] = factory
+}
+
+// factoryModuleExecutor returns an executor that dispatches to the
+// per-execution instance of a factory module. The caller must hold vm.mu.
+func (vm *VM) factoryModuleExecutor(name string) (types.ModuleExecutor, bool) {
+	if _, exists := vm.moduleFactories[name]; !exists {
+		return nil, false
+	}
+	return func(entrypoint string, args ...interface{}) (interface{}, error) {
+		instance, err := vm.moduleInstance(name)
+		if err != nil {
+			return nil, err
+		}
+		return instance.Call(entrypoint, args...)
+	}, true
+}
+
+// moduleInstance returns the instance of a factory module for the current
+// execution, creating and initializing it if needed
+func (vm *VM) moduleInstance(name string) (types.ModuleInstance, error) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if instance, exists := vm.moduleInstances[name]; exists {
+		return instance, nil
+	}
+	factory, exists := vm.moduleFactories[name]
+	if !exists {
+		return nil, fmt.Errorf("module %s not found", name)
+	}
+
+	instance := factory()
+	if instance == nil {
+		return nil, fmt.Errorf("module %s: factory returned nil", name)
+	}
+	if err := instance.Init(); err != nil {
+		return nil, fmt.Errorf("module %s: init failed: %w", name, err)
+	}
+	vm.moduleInstances[name] = instance
+	return instance, nil
+}
+
+// closeModuleInstances closes and forgets all module instances of the
+// current execution
+func (vm *VM) closeModuleInstances() error {
+	vm.mu.Lock()
+	instances := vm.moduleInstances
+	vm.moduleInstances = make(map[string]types.ModuleInstance)
+	vm.mu.Unlock()
+
+	var errs []error
+	for name, instance := range instances {
+		if err := instance.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("module %s: close failed: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
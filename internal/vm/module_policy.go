@@ -0,0 +1,77 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrModuleNotAllowed is the error of a script importing or calling a
+// module the host does not allow
+var ErrModuleNotAllowed = errors.New("module not allowed")
+
+// SetAllowedModules restricts the modules scripts may import to the given
+// import paths. nil allows every module that is not denied; an empty list
+// allows none.
+func (vm *VM) SetAllowedModules(modules []string) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.allowedModules = moduleSet(modules)
+}
+
+// SetDeniedModules forbids the given import paths, even when they are
+// allowed by SetAllowedModules
+func (vm *VM) SetDeniedModules(modules []string) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.deniedModules = moduleSet(modules)
+}
+
+// ModulePolicy returns the allowed and denied import paths, for copying
+// the policy to another VM
+func (vm *VM) ModulePolicy() (allowed, denied []string) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return moduleList(vm.allowedModules), moduleList(vm.deniedModules)
+}
+
+// CheckModule returns an error wrapping ErrModuleNotAllowed when scripts
+// may not use the module with the given import path
+func (vm *VM) CheckModule(importPath string) error {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.checkModuleLocked(importPath)
+}
+
+// checkModuleLocked is CheckModule for callers holding vm.mu
+func (vm *VM) checkModuleLocked(importPath string) error {
+	if vm.deniedModules[importPath] || (vm.allowedModules != nil && !vm.allowedModules[importPath]) {
+		return fmt.Errorf("security error: module %q: %w", importPath, ErrModuleNotAllowed)
+	}
+	return nil
+}
+
+// moduleSet converts a list of import paths to a set, keeping nil as nil
+func moduleSet(modules []string) map[string]bool {
+	if modules == nil {
+		return nil
+	}
+	set := make(map[string]bool, len(modules))
+	for _, module := range modules {
+		set[module] = true
+	}
+	return set
+}
+
+// moduleList converts a set of import paths back to a sorted list
+func moduleList(set map[string]bool) []string {
+	if set == nil {
+		return nil
+	}
+	modules := make([]string, 0, len(set))
+	for module := range set {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
@@ -0,0 +1,117 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/types"
+)
+
+// The mutex module locks named mutexes of builtin.SharedSync, shared by
+// the scripts running in the process and by the host:
+//
+//	mutex.Lock("accounts")
+//	balance := load()
+//	store(balance + amount)
+//	mutex.Unlock("accounts")
+//
+// Waiting for a lock ends with an error when the execution is canceled or
+// times out. A lock is held by the script function that took it and is
+// released when that function returns, as if it had deferred Unlock, unless
+// it was unlocked before; locks still held when the execution ends are
+// released as well, so a script that fails cannot block other scripts.
+
+// heldMutex is a mutex locked by the current execution
+type heldMutex struct {
+	instance *mutexInstance
+	name     string
+	mutex    *builtin.Mutex
+}
+
+// release unlocks the mutex unless it was already unlocked
+func (h *heldMutex) release() {
+	if h.instance.held[h.name] == h {
+		delete(h.instance.held, h.name)
+		h.mutex.Unlock()
+	}
+}
+
+// mutexInstance is the per-execution state of the mutex module
+type mutexInstance struct {
+	vm       *VM
+	registry *builtin.SyncRegistry
+	held     map[string]*heldMutex
+}
+
+// newMutexInstance is the factory of the mutex module
+func (vm *VM) newMutexInstance() types.ModuleInstance {
+	return &mutexInstance{vm: vm, registry: builtin.SharedSync}
+}
+
+func (m *mutexInstance) Init() error {
+	m.held = make(map[string]*heldMutex)
+	return nil
+}
+
+func (m *mutexInstance) Call(entrypoint string, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s function requires 1 argument", entrypoint)
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s function requires a string mutex name", entrypoint)
+	}
+
+	switch entrypoint {
+	case "Lock":
+		// Mutexes are not reentrant; locking twice would deadlock
+		if _, exists := m.held[name]; exists {
+			return nil, fmt.Errorf("mutex %s is already locked by this script", name)
+		}
+		mutex := m.registry.Mutex(name)
+		ctx, cancel := m.vm.waitContext()
+		defer cancel()
+		if err := mutex.LockContext(ctx); err != nil {
+			return nil, fmt.Errorf("locking mutex %s: %w", name, m.vm.waitError(err))
+		}
+		m.hold(name, mutex)
+		return nil, nil
+	case "TryLock":
+		if _, exists := m.held[name]; exists {
+			return false, nil
+		}
+		mutex := m.registry.Mutex(name)
+		if !mutex.TryLock() {
+			return false, nil
+		}
+		m.hold(name, mutex)
+		return true, nil
+	case "Unlock":
+		held, exists := m.held[name]
+		if !exists {
+			return nil, fmt.Errorf("mutex %s is not locked by this script", name)
+		}
+		held.release()
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("function %s not found in module mutex", entrypoint)
+	}
+}
+
+// hold records a mutex locked by the script function being executed, which
+// releases it when it returns
+func (m *mutexInstance) hold(name string, mutex *builtin.Mutex) {
+	held := &heldMutex{instance: m, name: name, mutex: mutex}
+	m.held[name] = held
+	if n := len(m.vm.frames); n > 0 {
+		m.vm.frames[n-1].mutexes = append(m.vm.frames[n-1].mutexes, held)
+	}
+}
+
+// Close releases the mutexes the script still holds
+func (m *mutexInstance) Close() error {
+	for _, held := range m.held {
+		held.release()
+	}
+	return nil
+}
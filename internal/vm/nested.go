@@ -0,0 +1,46 @@
+package vm
+
+import (
+	"time"
+)
+
+// Nested executions. A script module is compiled in a VM of its own, and
+// each call of one of its functions executes that VM. The call is part of
+// the execution of the caller: it stops when the caller is canceled, it may
+// use only what the caller has left of its instructions, memory, time and
+// call depth, and the instructions and memory it uses count against the
+// caller.
+
+// ExecuteNested executes an entry point of callee as part of the current
+// execution of vm
+func (vm *VM) ExecuteNested(callee *VM, entryPoint string, args ...interface{}) (result interface{}, err error) {
+	callee.maxInstructions = 0
+	if limit := vm.instructionLimit(); limit > 0 {
+		callee.maxInstructions = max(limit-vm.instructionCount, 1)
+	}
+	callee.maxMemory = 0
+	if vm.maxMemory > 0 {
+		callee.maxMemory = max(vm.maxMemory-vm.memoryUsage, 1)
+	}
+	callee.timeout = 0
+	if !vm.deadline.IsZero() {
+		callee.timeout = max(time.Until(vm.deadline), 1)
+	}
+	callee.maxCallDepth = 0
+	if vm.maxCallDepth > 0 {
+		callee.maxCallDepth = max(vm.maxCallDepth-vm.callDepth, 1)
+	}
+	callee.SetHostContext(vm.HostContext())
+
+	if vm.execCtx != nil {
+		result, err = callee.ExecuteContext(vm.execCtx, entryPoint, args...)
+	} else {
+		result, err = callee.Execute(entryPoint, args...)
+	}
+
+	vm.instructionCount += callee.instructionCount
+	if allocErr := vm.allocate(callee.memoryUsage); allocErr != nil && err == nil {
+		err = allocErr
+	}
+	return result, err
+}
@@ -0,0 +1,54 @@
+package vm
+
+import (
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/types"
+)
+
+// SetNumberMode sets how numbers returned by host functions and decoded by
+// the json module are converted. It must be set before the script runs.
+func (vm *VM) SetNumberMode(mode types.NumberMode) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.numberMode = mode
+}
+
+// GetNumberMode returns the current number conversion mode
+func (vm *VM) GetNumberMode() types.NumberMode {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.numberMode
+}
+
+// hostValue converts a result the script hands to the host according to
+// the number mode, so numbers leave the script in the representation they
+// enter it with, e.g., an int64 the script got from a host variable is
+// returned as int in NumberPreserveInt mode
+func (vm *VM) hostValue(value interface{}) interface{} {
+	mode := vm.GetNumberMode()
+	if mode == types.NumberFloat64 {
+		return value
+	}
+	if results, ok := value.(Tuple); ok {
+		converted := make(Tuple, len(results))
+		for i, result := range results {
+			converted[i] = builtin.ConvertNumbers(result, mode)
+		}
+		return converted
+	}
+	return builtin.ConvertNumbers(value, mode)
+}
+
+// convertHostValue converts a value returned by a host function into the
+// script according to the number mode
+func (vm *VM) convertHostValue(funcName string, value interface{}) interface{} {
+	vm.mu.RLock()
+	mode := vm.numberMode
+	_, isScriptFunction := vm.scriptFunctionInfos[funcName]
+	vm.mu.RUnlock()
+
+	if mode == types.NumberFloat64 || isScriptFunction {
+		return value
+	}
+	return builtin.ConvertNumbers(value, mode)
+}
@@ -0,0 +1,69 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lengzhao/goscript/builtin"
+)
+
+// Script output. The print and println builtins and the fmt module write to
+// the standard output of the VM, and the debug trace (see SetDebug) to its
+// standard error. They are os.Stdout and os.Stderr unless the host sets
+// other writers, e.g. to capture the output of each request.
+
+// SetStdout sets the writer the script prints to (os.Stdout if nil)
+func (vm *VM) SetStdout(w io.Writer) {
+	vm.stdout = w
+}
+
+// SetStderr sets the writer the debug trace goes to (os.Stderr if nil)
+func (vm *VM) SetStderr(w io.Writer) {
+	vm.stderr = w
+}
+
+// Stdout returns the writer the script prints to
+func (vm *VM) Stdout() io.Writer {
+	if vm.stdout == nil {
+		return os.Stdout
+	}
+	return vm.stdout
+}
+
+// Stderr returns the writer the debug trace goes to
+func (vm *VM) Stderr() io.Writer {
+	if vm.stderr == nil {
+		return os.Stderr
+	}
+	return vm.stderr
+}
+
+// StdoutWriter returns a writer to the standard output of the VM that
+// follows later SetStdout calls, for modules and script modules created
+// before the host sets the output
+func (vm *VM) StdoutWriter() io.Writer {
+	return outputWriter{vm}
+}
+
+// outputWriter writes to the current standard output of a VM
+type outputWriter struct {
+	vm *VM
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	return w.vm.Stdout().Write(p)
+}
+
+// registerOutputBuiltins registers the print and println builtins, which
+// print to the standard output of the VM
+func (vm *VM) registerOutputBuiltins() {
+	fn := ScriptFunction(builtin.NewPrint(vm.StdoutWriter()))
+	vm.functions["print"] = fn
+	vm.functions["println"] = fn
+}
+
+// tracef writes a line of the debug trace
+func (vm *VM) tracef(format string, args ...interface{}) {
+	fmt.Fprintf(vm.Stderr(), format, args...)
+}
@@ -0,0 +1,65 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/internal/context"
+)
+
+// Package initialization. Before the entry point runs, the package-level
+// code (imports and package-level variables, in dependency order) runs in
+// the package context, followed by the init functions in declaration order.
+// Functions and methods run in contexts nested in the package context, so
+// package-level variables are visible to all of them.
+
+// InitFunctionKey returns the instruction set key of the n-th init function
+// of a package, counting from 1: "main.init", "main.init2", ...
+func InitFunctionKey(packageName string, n int) string {
+	if n <= 1 {
+		return packageName + ".init"
+	}
+	return fmt.Sprintf("%s.init%d", packageName, n)
+}
+
+// initPackage runs the package-level code and the init functions of a
+// package in its context
+func (vm *VM) initPackage(packageName string, packageCtx *context.Context) error {
+	vm.packageCtx = packageCtx
+
+	if packageInstructions, exists := vm.GetInstructionSet(packageName); exists {
+		vm.currentCtx = packageCtx
+		vm.pushFrame(packageName)
+		_, err := NewExecutor(vm).executeInstructions(packageInstructions)
+		vm.popFrame()
+		if err != nil {
+			return fmt.Errorf("error executing package-level code: %w", err)
+		}
+	}
+
+	for n := 1; ; n++ {
+		key := InitFunctionKey(packageName, n)
+		initInstructions, exists := vm.GetInstructionSet(key)
+		if !exists {
+			return nil
+		}
+		// The body of an init function declares its variables in a scope
+		// of its own, so it runs directly in the package context
+		vm.currentCtx = packageCtx
+		vm.pushFrame(key)
+		_, err := NewExecutor(vm).executeInstructions(initInstructions)
+		vm.popFrame()
+		if err != nil {
+			return fmt.Errorf("error executing package init: %w", err)
+		}
+	}
+}
+
+// functionParent returns the context that function and method calls are
+// nested in: the package context, or the current context when no package
+// is executing (e.g., functions called directly by the host)
+func (vm *VM) functionParent() *context.Context {
+	if vm.packageCtx != nil {
+		return vm.packageCtx
+	}
+	return vm.currentCtx
+}
@@ -0,0 +1,212 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/context"
+	"github.com/lengzhao/goscript/types"
+)
+
+// Pointers. &x of a variable, struct field or slice element creates a
+// Pointer that reads and writes the original location, so functions can
+// update the variables of their callers. &s of a struct is a struct
+// pointer sharing the fields of s (see types.Struct), *p is the struct it
+// points to, and *p = v replaces its fields in place.
+//
+// Structs are values: assignments, arguments, results, elements and sent
+// values take a copy of a struct (see copyValue), while struct pointers
+// are shared.
+
+// Pointer is a pointer to a script variable, struct field or slice element.
+// Pointers to the same location are equal.
+type Pointer struct {
+	// A variable (the scope declaring it and its name), a struct field
+	// (the struct and the field name) or a slice element
+	ctx   *context.Context
+	strct *types.Struct
+	name  string
+	elem  *interface{}
+}
+
+// Load returns the value the pointer points to
+func (p Pointer) Load() interface{} {
+	switch {
+	case p.ctx != nil:
+		value, _ := p.ctx.GetVariable(p.name)
+		return value
+	case p.strct != nil:
+		return p.strct.Fields[p.name]
+	default:
+		return *p.elem
+	}
+}
+
+// Store sets the value the pointer points to
+func (p Pointer) Store(value interface{}) error {
+	switch {
+	case p.ctx != nil:
+		return p.ctx.SetVariable(p.name, copyValue(value))
+	case p.strct != nil:
+		p.strct.Fields[p.name] = copyValue(value)
+	default:
+		*p.elem = copyValue(value)
+	}
+	return nil
+}
+
+// String formats the pointer as & followed by the value it points to
+func (p Pointer) String() string {
+	return fmt.Sprintf("&%v", p.Load())
+}
+
+// handleAddrOf handles the ADDR_OF opcode
+func (exec *Executor) handleAddrOf(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	name := instr.Arg.(string)
+	ctx, exists := exec.vm.currentCtx.Lookup(name)
+	if !exists {
+		return 0, fmt.Errorf("undefined: %s", name)
+	}
+	value, _ := ctx.GetVariable(name)
+	stack.Push(addressOf(value, Pointer{ctx: ctx, name: name}))
+	return pc + 1, nil
+}
+
+// handleAddrField handles the ADDR_FIELD opcode
+func (exec *Executor) handleAddrField(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for ADDR_FIELD")
+	}
+	name := instr.Arg.(string)
+	operand := stack.Pop()
+	s, ok := operand.(*types.Struct)
+	if !ok {
+		return 0, fmt.Errorf("cannot take address of field %s of %v (%T)", name, operand, operand)
+	}
+	value, exists := s.Fields[name]
+	if !exists {
+		return 0, fmt.Errorf("%s has no field %s", s.Type, name)
+	}
+	stack.Push(addressOf(value, Pointer{strct: s, name: name}))
+	return pc + 1, nil
+}
+
+// handleAddrIndex handles the ADDR_INDEX opcode
+func (exec *Executor) handleAddrIndex(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	if stack.Len() < 2 {
+		return 0, fmt.Errorf("stack underflow for ADDR_INDEX")
+	}
+	index := stack.Pop()
+	collection := stack.Pop()
+	slice, ok := collection.([]interface{})
+	if !ok {
+		return 0, fmt.Errorf("cannot take address of element of %T", collection)
+	}
+	idx, ok := index.(int)
+	if !ok {
+		return 0, fmt.Errorf("index must be an integer, got %T", index)
+	}
+	if idx < 0 || idx >= len(slice) {
+		return 0, fmt.Errorf("index out of range: %d", idx)
+	}
+	stack.Push(addressOf(slice[idx], Pointer{elem: &slice[idx]}))
+	return pc + 1, nil
+}
+
+// handleAddrValue handles the ADDR_VALUE opcode. Composite literals other
+// than structs are references already and stay as they are.
+func (exec *Executor) handleAddrValue(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for ADDR_VALUE")
+	}
+	if s, ok := stack.Peek().(*types.Struct); ok && !s.Pointer {
+		stack.Pop()
+		stack.Push(s.Addr())
+	}
+	return pc + 1, nil
+}
+
+// addressOf returns the pointer to a location holding value, or a struct
+// pointer for a struct
+func addressOf(value interface{}, p Pointer) interface{} {
+	if s, ok := value.(*types.Struct); ok && !s.Pointer {
+		return s.Addr()
+	}
+	return p
+}
+
+// copyValue returns the value a location takes when value is assigned to
+// it: a copy for structs, the value itself otherwise
+func copyValue(value interface{}) interface{} {
+	if s, ok := value.(*types.Struct); ok && !s.Pointer {
+		return s.Copy()
+	}
+	return value
+}
+
+// copyValues returns values with their structs copied, or values itself
+// when it holds no structs
+func copyValues(values []interface{}) []interface{} {
+	var copied []interface{}
+	for i, value := range values {
+		if s, ok := value.(*types.Struct); ok && !s.Pointer {
+			if copied == nil {
+				copied = append([]interface{}(nil), values...)
+			}
+			copied[i] = s.Copy()
+		}
+	}
+	if copied == nil {
+		return values
+	}
+	return copied
+}
+
+// handleDeref handles the DEREF opcode
+func (exec *Executor) handleDeref(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for DEREF")
+	}
+	switch p := stack.Pop().(type) {
+	case Pointer:
+		stack.Push(p.Load())
+	case *types.Struct:
+		stack.Push(p.Elem())
+	case nil:
+		return 0, fmt.Errorf("invalid memory address or nil pointer dereference")
+	default:
+		return 0, fmt.Errorf("invalid indirect of %v (%T)", p, p)
+	}
+	return pc + 1, nil
+}
+
+// handleStoreDeref handles the STORE_DEREF opcode
+func (exec *Executor) handleStoreDeref(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	if stack.Len() < 2 {
+		return 0, fmt.Errorf("stack underflow for STORE_DEREF")
+	}
+	value := stack.Pop()
+	switch p := stack.Pop().(type) {
+	case Pointer:
+		if err := p.Store(value); err != nil {
+			return 0, err
+		}
+	case *types.Struct:
+		s, ok := value.(*types.Struct)
+		if !ok || s.Type != p.Type {
+			return 0, fmt.Errorf("cannot assign %v (%T) to *%s", value, value, p.Type)
+		}
+		fields := s.Copy().Fields
+		for name := range p.Fields {
+			delete(p.Fields, name)
+		}
+		for name, field := range fields {
+			p.Fields[name] = field
+		}
+	case nil:
+		return 0, fmt.Errorf("invalid memory address or nil pointer dereference")
+	default:
+		return 0, fmt.Errorf("invalid indirect of %v (%T)", p, p)
+	}
+	return pc + 1, nil
+}
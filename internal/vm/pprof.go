@@ -0,0 +1,122 @@
+package vm
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format read
+// by go tool pprof. Each sample is a call stack with the instructions it
+// executed and its wall time in nanoseconds.
+func (p *Profile) WritePprof(w io.Writer) error {
+	enc := &pprofEncoder{strings: map[string]int64{"": 0}, stringTable: []string{""}}
+	instructions, count := enc.str("instructions"), enc.str("count")
+	timeType, nanoseconds := enc.str("time"), enc.str("nanoseconds")
+	var out []byte
+	out = appendBytes(out, 1, valueType(instructions, count))
+	out = appendBytes(out, 1, valueType(timeType, nanoseconds))
+
+	functionIDs := make(map[string]uint64)
+	locationIDs := make(map[profileLocation]uint64)
+	var functions, locations []byte
+	for _, sample := range p.samples {
+		ids := make([]uint64, len(sample.stack))
+		for i, loc := range sample.stack {
+			id, exists := locationIDs[loc]
+			if !exists {
+				fnID, exists := functionIDs[loc.function]
+				if !exists {
+					fnID = uint64(len(functionIDs) + 1)
+					functionIDs[loc.function] = fnID
+					name := enc.str(loc.function)
+					var fn []byte
+					fn = appendVarintField(fn, 1, fnID)
+					fn = appendVarintField(fn, 2, uint64(name))
+					fn = appendVarintField(fn, 3, uint64(name))
+					functions = appendBytes(functions, 5, fn)
+				}
+				id = uint64(len(locationIDs) + 1)
+				locationIDs[loc] = id
+				var line []byte
+				line = appendVarintField(line, 1, fnID)
+				line = appendVarintField(line, 2, uint64(loc.line))
+				var location []byte
+				location = appendVarintField(location, 1, id)
+				location = appendBytes(location, 4, line)
+				locations = appendBytes(locations, 4, location)
+			}
+			ids[i] = id
+		}
+		var s []byte
+		s = appendPacked(s, 1, ids)
+		s = appendPacked(s, 2, []uint64{uint64(sample.instructions), uint64(sample.time.Nanoseconds())})
+		out = appendBytes(out, 2, s)
+	}
+	out = append(out, locations...)
+	out = append(out, functions...)
+	for _, s := range enc.stringTable {
+		out = appendBytes(out, 6, []byte(s))
+	}
+	out = appendVarintField(out, 14, uint64(timeType))
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(out); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// pprofEncoder holds the string table of a pprof profile
+type pprofEncoder struct {
+	strings     map[string]int64
+	stringTable []string
+}
+
+// str returns the index of s in the string table, adding it if needed
+func (e *pprofEncoder) str(s string) int64 {
+	if index, exists := e.strings[s]; exists {
+		return index
+	}
+	index := int64(len(e.stringTable))
+	e.strings[s] = index
+	e.stringTable = append(e.stringTable, s)
+	return index
+}
+
+// valueType encodes a ValueType message
+func valueType(typ, unit int64) []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(typ))
+	return appendVarintField(b, 2, uint64(unit))
+}
+
+// appendVarint appends a protocol buffer varint
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// appendVarintField appends a varint field
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendVarint(b, uint64(field)<<3)
+	return appendVarint(b, v)
+}
+
+// appendBytes appends a length-delimited field
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// appendPacked appends a packed repeated varint field
+func appendPacked(b []byte, field int, values []uint64) []byte {
+	var data []byte
+	for _, v := range values {
+		data = appendVarint(data, v)
+	}
+	return appendBytes(b, field, data)
+}
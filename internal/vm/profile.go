@@ -0,0 +1,224 @@
+package vm
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Profiling. When enabled, every execution records how often each script
+// function is called, the instructions it executes and the wall time spent
+// in it, the executed opcodes and the call sites between functions. The
+// profile of the last execution can be exported in pprof format.
+
+// Profile is the profile of an execution
+type Profile struct {
+	// Functions holds the profile of each function called, by key
+	Functions map[string]FunctionProfile
+
+	// Opcodes counts the executed instructions by opcode name
+	Opcodes map[string]int64
+
+	// CallSites holds the calls between script functions, most frequent
+	// first
+	CallSites []CallSite
+
+	// Samples by call stack, for the pprof export
+	samples []profileSample
+}
+
+// FunctionProfile is the profile of a script function
+type FunctionProfile struct {
+	// Calls is the number of times the function was called
+	Calls int64
+
+	// Instructions is the number of instructions the function executed,
+	// excluding those of its callees
+	Instructions int64
+
+	// Time is the cumulative wall time of the calls, including callees;
+	// SelfTime excludes the time spent in callees
+	Time     time.Duration
+	SelfTime time.Duration
+}
+
+// CallSite is a source line of a function calling another function
+type CallSite struct {
+	Caller string
+	Line   int
+	Callee string
+
+	// Count is the number of calls made at the call site
+	Count int64
+}
+
+// profileSample is the self cost of a call stack
+type profileSample struct {
+	// Stack holds the functions and lines of the stack, innermost first;
+	// the line of the innermost function is 0
+	stack        []profileLocation
+	instructions int64
+	time         time.Duration
+}
+
+// profileLocation is a line of a function in a call stack
+type profileLocation struct {
+	function string
+	line     int
+}
+
+// callSiteKey identifies a call site
+type callSiteKey struct {
+	caller string
+	line   int
+	callee string
+}
+
+// profiler collects the profile of an execution
+type profiler struct {
+	functions map[string]*FunctionProfile
+	opcodes   [instruction.OpCodeLast]int64
+	callSites map[callSiteKey]int64
+	samples   map[string]*profileSample
+}
+
+// SetProfiling enables or disables profiling, starting with the next
+// execution
+func (vm *VM) SetProfiling(enabled bool) {
+	vm.profiling = enabled
+}
+
+// resetProfile starts the profile of a new execution
+func (vm *VM) resetProfile() {
+	vm.profile = nil
+	if vm.profiling {
+		vm.profile = &profiler{
+			functions: make(map[string]*FunctionProfile),
+			callSites: make(map[callSiteKey]int64),
+			samples:   make(map[string]*profileSample),
+		}
+	}
+}
+
+// Profile returns the profile of the last execution, or nil when profiling
+// was disabled
+func (vm *VM) Profile() *Profile {
+	p := vm.profile
+	if p == nil {
+		return nil
+	}
+
+	profile := &Profile{
+		Functions: make(map[string]FunctionProfile, len(p.functions)),
+		Opcodes:   make(map[string]int64),
+	}
+	for key, fp := range p.functions {
+		profile.Functions[key] = *fp
+	}
+	for op, count := range p.opcodes {
+		if count > 0 {
+			profile.Opcodes[instruction.OpCode(op).String()] = count
+		}
+	}
+	for site, count := range p.callSites {
+		profile.CallSites = append(profile.CallSites, CallSite{Caller: site.caller, Line: site.line, Callee: site.callee, Count: count})
+	}
+	sort.Slice(profile.CallSites, func(i, j int) bool {
+		a, b := profile.CallSites[i], profile.CallSites[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Caller != b.Caller {
+			return a.Caller < b.Caller
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Callee < b.Callee
+	})
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		profile.samples = append(profile.samples, *p.samples[key])
+	}
+	return profile
+}
+
+// instruction records an instruction executed in the innermost frame
+func (p *profiler) instruction(frames []callFrame, instr *instruction.Instruction) {
+	p.opcodes[instr.Op]++
+	if n := len(frames); n > 0 {
+		frame := &frames[n-1]
+		frame.instructions++
+		if instr.Line != 0 {
+			frame.line = instr.Line
+		}
+	}
+}
+
+// enter records a call of function from the innermost frame
+func (p *profiler) enter(frames []callFrame, function string) {
+	if n := len(frames); n > 0 {
+		caller := frames[n-1]
+		p.callSites[callSiteKey{caller: caller.function, line: caller.line, callee: function}]++
+	}
+}
+
+// exit records the innermost frame when it returns
+func (p *profiler) exit(frames []callFrame) {
+	n := len(frames)
+	frame := &frames[n-1]
+	elapsed := time.Since(frame.start)
+	self := elapsed - frame.childTime
+	if n > 1 {
+		frames[n-2].childTime += elapsed
+	}
+
+	fp := p.functions[frame.function]
+	if fp == nil {
+		fp = &FunctionProfile{}
+		p.functions[frame.function] = fp
+	}
+	fp.Calls++
+	fp.Instructions += frame.instructions
+	fp.SelfTime += self
+	// The time of recursive calls is already part of the outer call
+	recursive := false
+	for _, outer := range frames[:n-1] {
+		if outer.function == frame.function {
+			recursive = true
+			break
+		}
+	}
+	if !recursive {
+		fp.Time += elapsed
+	}
+
+	// Aggregate the self cost by call stack
+	stack := make([]profileLocation, n)
+	var key strings.Builder
+	for i := n - 1; i >= 0; i-- {
+		line := 0
+		if i < n-1 {
+			line = frames[i].line
+		}
+		stack[n-1-i] = profileLocation{function: frames[i].function, line: line}
+		key.WriteString(frames[i].function)
+		key.WriteByte(':')
+		key.WriteString(strconv.Itoa(line))
+		key.WriteByte(';')
+	}
+	sample := p.samples[key.String()]
+	if sample == nil {
+		sample = &profileSample{stack: stack}
+		p.samples[key.String()] = sample
+	}
+	sample.instructions += frame.instructions
+	sample.time += self
+}
@@ -0,0 +1,81 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// AllowType lets scripts read fields of host values of the given struct type
+// (or pointers to it) through reflection, without converting the value into
+// a script map. Only the listed fields are readable; with no fields listed,
+// every exported field is. Fields of allowed types are read-only.
+func (vm *VM) AllowType(t reflect.Type, fields ...string) error {
+	if t == nil {
+		return fmt.Errorf("cannot allow a nil type")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot allow %s: not a struct type", t)
+	}
+
+	allowed := make(map[string]bool)
+	if len(fields) == 0 {
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.IsExported() {
+				allowed[field.Name] = true
+			}
+		}
+	}
+	for _, name := range fields {
+		field, exists := t.FieldByName(name)
+		if !exists {
+			return fmt.Errorf("cannot allow %s.%s: no such field", t, name)
+		}
+		if !field.IsExported() {
+			return fmt.Errorf("cannot allow %s.%s: field is not exported", t, name)
+		}
+		allowed[name] = true
+	}
+
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.allowedTypes[t] = allowed
+	return nil
+}
+
+// reflectedStruct returns the struct behind value if its type was allowed
+// with AllowType
+func (vm *VM) reflectedStruct(value interface{}) (reflect.Value, map[string]bool, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return reflect.Value{}, nil, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, nil, false
+	}
+
+	vm.mu.RLock()
+	allowed, exists := vm.allowedTypes[rv.Type()]
+	vm.mu.RUnlock()
+	return rv, allowed, exists
+}
+
+// getReflectedField reads a field of an allowed host value. Map and slice
+// fields are copied, so scripts cannot change the host value through them.
+// handled is false when the value is not of an allowed type.
+func (vm *VM) getReflectedField(value interface{}, fieldName string) (result interface{}, handled bool, err error) {
+	rv, allowed, exists := vm.reflectedStruct(value)
+	if !exists {
+		return nil, false, nil
+	}
+	if !allowed[fieldName] {
+		return nil, true, fmt.Errorf("field %s of %s is not accessible", fieldName, rv.Type())
+	}
+	field := deepCopy(rv.FieldByName(fieldName).Interface())
+	return vm.convertHostValue("", field), true, nil
+}
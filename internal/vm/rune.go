@@ -0,0 +1,40 @@
+package vm
+
+import (
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// runeBinaryOp applies a binary operation where at least one operand is a
+// rune (int32). Runes mix with ints the way Go's untyped constants do:
+// 'a' + 1 is the rune 'b' and c >= 'a' compares code points.
+// handled is false when no operand is a rune or the other operand is not
+// an integer.
+func (vm *VM) runeBinaryOp(op instruction.BinaryOp, left, right interface{}) (result interface{}, handled bool, err error) {
+	_, lIsRune := left.(int32)
+	_, rIsRune := right.(int32)
+	if !lIsRune && !rIsRune {
+		return nil, false, nil
+	}
+	l, lok := runeOperand(left)
+	r, rok := runeOperand(right)
+	if !lok || !rok {
+		return nil, false, nil
+	}
+
+	result, handled, err = numericOp(op, l, r, vm.divisionMode)
+	if n, ok := result.(int); ok {
+		return int32(n), handled, err
+	}
+	return result, handled, err
+}
+
+// runeOperand widens a rune or int operand to int
+func runeOperand(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int(n), true
+	case int:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"runtime/debug"
+	"strings"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// RuntimeError is an error raised while executing a script, located at
+// the statement that failed. Errors of nested calls keep the position of
+// the innermost statement, and record the script call stack they
+// propagated through.
+type RuntimeError struct {
+	// Pos is the source position of the statement
+	Pos token.Position
+
+	// Err is the underlying error
+	Err error
+
+	// Stack holds the script frames active when the error was raised,
+	// innermost first
+	Stack []Frame
+}
+
+// Frame is a script call frame of a stack trace
+type Frame struct {
+	// Function is the key of the function, e.g. main.div
+	Function string
+
+	// Pos is the position of the statement executing in the frame
+	Pos token.Position
+}
+
+// Error returns the error formatted as "file:line:col: message"
+func (e *RuntimeError) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// StackTrace renders the error followed by the script call stack, one
+// frame per line:
+//
+//	division by zero
+//	    main.div at script.go:4:2
+//	    main.main at script.go:9:2
+func (e *RuntimeError) StackTrace() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	for _, frame := range e.Stack {
+		fmt.Fprintf(&b, "\n    %s at %s", frame.Function, frame.Pos)
+	}
+	return b.String()
+}
+
+// PanicError is the error an execution fails with when a host function or
+// the VM itself panics. The panic is recovered so that it cannot crash the
+// host; tests and fuzz targets look for it with errors.As to report it as
+// the crash it is.
+type PanicError struct {
+	// Value is the value passed to panic
+	Value interface{}
+
+	// Stack is the Go stack of the goroutine that panicked
+	Stack []byte
+}
+
+// newPanicError records a recovered panic with the stack it was raised on
+func newPanicError(value interface{}) *PanicError {
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+// Error returns the error formatted as "runtime panic: value"
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("runtime panic: %v", e.Value)
+}
+
+// traceError locates an error raised by instr and adds the current frame
+// to its stack trace. An error from a nested call is already located and
+// only gets the frame of the caller.
+func (vm *VM) traceError(instr *instruction.Instruction, err error) error {
+	pos := token.Position{Filename: instr.File, Line: instr.Line, Column: instr.Column}
+	var located *RuntimeError
+	if !errors.As(err, &located) {
+		located = &RuntimeError{Pos: pos, Err: err}
+		err = located
+	}
+	located.Stack = append(located.Stack, Frame{Function: vm.currentFunction(), Pos: pos})
+	return err
+}
@@ -0,0 +1,108 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/types"
+)
+
+// zeroValue returns the zero value of a basic type, or nil for other types
+func zeroValue(typeName string) interface{} {
+	switch typeName {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune":
+		return 0
+	case "float32", "float64":
+		return 0.0
+	case "string":
+		return ""
+	case "bool":
+		return false
+	default:
+		return nil
+	}
+}
+
+// zeroOf returns the zero value of a type, which is a struct with zero
+// fields for script struct types
+func (vm *VM) zeroOf(typeName string) interface{} {
+	if _, ok := vm.StructType(typeName); ok {
+		return types.NewStruct(typeName)
+	}
+	return zeroValue(typeName)
+}
+
+// MaxSliceLen is the largest length and capacity make([]T) accepts, so a
+// script cannot ask the host for more memory than it can provide
+const MaxSliceLen = 1 << 26
+
+// handleMakeSlice handles the MAKE_SLICE opcode: make([]T, len, cap)
+// creates a slice of len zero values of T. The capacity is nil when it is
+// not given. The size of the slice is checked against MaxSliceLen and the
+// memory limit before it is allocated.
+func (exec *Executor) handleMakeSlice(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	elemType, ok := instr.Arg.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid element type for MAKE_SLICE")
+	}
+	if stack.Len() < 2 {
+		return 0, fmt.Errorf("stack underflow for MAKE_SLICE")
+	}
+	capArg := stack.Pop()
+	length, err := sliceSize("len", elemType, stack.Pop())
+	if err != nil {
+		return 0, err
+	}
+	capacity := length
+	if capArg != nil {
+		if capacity, err = sliceSize("cap", elemType, capArg); err != nil {
+			return 0, err
+		}
+		if length > capacity {
+			return 0, fmt.Errorf("len larger than cap in make([]%s)", elemType)
+		}
+	}
+	if capacity > MaxSliceLen {
+		return 0, fmt.Errorf("cap out of range in make([]%s): %d exceeds %d", elemType, capacity, MaxSliceLen)
+	}
+
+	// Byte slices are Go byte slices
+	isBytes := elemType == "byte" || elemType == "uint8"
+	size := int64(sliceHeaderSize) + int64(capacity)*interfaceSize
+	if isBytes {
+		size = int64(sliceHeaderSize) + int64(capacity)
+	}
+	if err := exec.vm.reserve(size); err != nil {
+		return 0, fmt.Errorf("make([]%s): %w", elemType, err)
+	}
+	if isBytes {
+		stack.Push(make([]byte, length, capacity))
+		return pc + 1, nil
+	}
+
+	slice := make([]interface{}, length, capacity)
+	if zero := zeroValue(elemType); zero != nil {
+		for i := range slice {
+			slice[i] = zero
+		}
+	}
+	stack.Push(slice)
+	return pc + 1, nil
+}
+
+// sliceSize returns the len or cap argument of make([]T)
+func sliceSize(what, elemType string, value interface{}) (int, error) {
+	var size int
+	switch v := value.(type) {
+	case int:
+		size = v
+	case int64:
+		size = int(v)
+	default:
+		return 0, fmt.Errorf("non-integer %s argument in make([]%s): %v (%T)", what, elemType, value, value)
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("negative %s argument in make([]%s)", what, elemType)
+	}
+	return size, nil
+}
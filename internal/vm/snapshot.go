@@ -0,0 +1,209 @@
+package vm
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"sort"
+
+	"github.com/lengzhao/goscript/types"
+)
+
+// Snapshot is a deep copy of the script-visible variables at one point of an
+// execution, keyed by variable name
+type Snapshot map[string]interface{}
+
+// ChangeKind classifies a difference between two snapshots
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is one difference between two snapshots. Path addresses the changed
+// value, e.g., "total", "p.name" or "items[2]".
+type Change struct {
+	Path string
+	Kind ChangeKind
+	Old  interface{}
+	New  interface{}
+}
+
+// String formats the change for display
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s = %v", c.Path, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s = %v", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.Old, c.New)
+	}
+}
+
+// Snapshot captures the variables visible from the current scope: the locals
+// of the current frame and its enclosing blocks plus the package globals.
+// A local shadows a global of the same name; compiler temporaries (names that
+// are not identifiers) are left out. Values are deep-copied, so later changes
+// made by the script do not affect the snapshot.
+func (vm *VM) Snapshot() Snapshot {
+	snapshot := make(Snapshot)
+	for name, value := range vm.frameLocals() {
+		if token.IsIdentifier(name) && !isFunctionValue(value) {
+			snapshot[name] = deepCopy(value)
+		}
+	}
+	for ctx := vm.currentCtx; ctx != nil; ctx = ctx.GetParent() {
+		for name, value := range ctx.GetAllVariables() {
+			if _, shadowed := snapshot[name]; shadowed || !token.IsIdentifier(name) || isFunctionValue(value) {
+				continue
+			}
+			snapshot[name] = deepCopy(value)
+		}
+	}
+	return snapshot
+}
+
+// DiffSnapshots computes the structural differences between two snapshots,
+// descending into maps, structs of the same type and slices. Changes are sorted
+// by path.
+func DiffSnapshots(before, after Snapshot) []Change {
+	var changes []Change
+	diffMaps("", before, after, &changes)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffValues(path string, old, new interface{}, changes *[]Change) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if n, ok := new.(map[string]interface{}); ok {
+			diffMaps(path, o, n, changes)
+			return
+		}
+	case *types.Struct:
+		if n, ok := new.(*types.Struct); ok && n.Type == o.Type {
+			diffMaps(path, o.Fields, n.Fields, changes)
+			return
+		}
+	case []interface{}:
+		if n, ok := new.([]interface{}); ok {
+			diffSlices(path, o, n, changes)
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Path: path, Kind: ChangeModified, Old: old, New: new})
+	}
+}
+
+func diffMaps(path string, old, new map[string]interface{}, changes *[]Change) {
+	for key, oldValue := range old {
+		newValue, exists := new[key]
+		if !exists {
+			*changes = append(*changes, Change{Path: joinPath(path, key), Kind: ChangeRemoved, Old: oldValue})
+			continue
+		}
+		diffValues(joinPath(path, key), oldValue, newValue, changes)
+	}
+	for key, newValue := range new {
+		if _, exists := old[key]; !exists {
+			*changes = append(*changes, Change{Path: joinPath(path, key), Kind: ChangeAdded, New: newValue})
+		}
+	}
+}
+
+func diffSlices(path string, old, new []interface{}, changes *[]Change) {
+	for i := 0; i < len(old) || i < len(new); i++ {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(new):
+			*changes = append(*changes, Change{Path: elemPath, Kind: ChangeRemoved, Old: old[i]})
+		case i >= len(old):
+			*changes = append(*changes, Change{Path: elemPath, Kind: ChangeAdded, New: new[i]})
+		default:
+			diffValues(elemPath, old[i], new[i], changes)
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// deepCopy copies maps and slices recursively so the copy shares no mutable
+// state with the original. Other values are returned as is.
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			copied[key] = deepCopy(elem)
+		}
+		return copied
+	case *types.Struct:
+		copied := types.NewStruct(v.Type)
+		for key, elem := range v.Fields {
+			copied.Fields[key] = deepCopy(elem)
+		}
+		return copied
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		copied := make([]interface{}, len(v))
+		for i, elem := range v {
+			copied[i] = deepCopy(elem)
+		}
+		return copied
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopyValue(iter.Value(), rv.Type().Elem()))
+		}
+		return copied.Interface()
+	case reflect.Slice:
+		if rv.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			copied.Index(i).Set(deepCopyValue(rv.Index(i), rv.Type().Elem()))
+		}
+		return copied.Interface()
+	}
+	return value
+}
+
+// deepCopyValue deep-copies a reflected element and converts it back to the
+// element type of its container
+func deepCopyValue(v reflect.Value, elemType reflect.Type) reflect.Value {
+	if !v.IsValid() || !v.CanInterface() {
+		return v
+	}
+	copied := deepCopy(v.Interface())
+	if copied == nil {
+		return reflect.Zero(elemType)
+	}
+	return reflect.ValueOf(copied)
+}
+
+// isFunctionValue reports whether value is a function, which snapshots skip
+func isFunctionValue(value interface{}) bool {
+	return value != nil && reflect.TypeOf(value).Kind() == reflect.Func
+}
@@ -0,0 +1,113 @@
+package vm
+
+import (
+	"go/token"
+	"sort"
+)
+
+// Source maps. Tools that observe the VM (debuggers, coverage, error
+// reporters) see instructions by function key and index, as in StepEvent
+// and Frame. A SourceMap translates these locations to the source
+// positions they were compiled from, and source lines back to the
+// statements compiled from them.
+
+// Location is an instruction of a compiled program
+type Location struct {
+	// Function is the key of the instruction set, e.g. main.main
+	Function string
+
+	// Index is the position of the instruction in the set (the PC of a
+	// StepEvent)
+	Index int
+}
+
+// SourceMap maps the instructions of a program to source positions and
+// source lines to instructions. It is immutable.
+type SourceMap struct {
+	positions map[Location]token.Position
+
+	// Statement starts by file and line, sorted by function and index
+	statements map[sourceLine][]Location
+}
+
+// sourceLine is a line of a source file
+type sourceLine struct {
+	file string
+	line int
+}
+
+// SourceMap returns the source map of the instructions of the image
+func (img *Image) SourceMap() *SourceMap {
+	m := &SourceMap{
+		positions:  make(map[Location]token.Position),
+		statements: make(map[sourceLine][]Location),
+	}
+	for key, instructions := range img.instructionSets {
+		for pc, instr := range instructions {
+			if instr.Line <= 0 {
+				continue
+			}
+			loc := Location{Function: key, Index: pc}
+			m.positions[loc] = token.Position{Filename: instr.File, Line: instr.Line, Column: instr.Column}
+			if instr.StmtStart {
+				line := sourceLine{file: instr.File, line: instr.Line}
+				m.statements[line] = append(m.statements[line], loc)
+			}
+		}
+	}
+	for _, locs := range m.statements {
+		sortLocations(locs)
+	}
+	return m
+}
+
+// Position returns the source position of the statement an instruction was
+// compiled from, or false for an unknown location or an instruction without
+// a position (such as entering a scope)
+func (m *SourceMap) Position(loc Location) (token.Position, bool) {
+	pos, ok := m.positions[loc]
+	return pos, ok
+}
+
+// Locations returns the first instruction of each statement starting on a
+// line of a file, sorted by function and index. An empty file matches every
+// file. It returns nil if no code was compiled from the line.
+func (m *SourceMap) Locations(file string, line int) []Location {
+	if file != "" {
+		return m.statements[sourceLine{file: file, line: line}]
+	}
+	var result []Location
+	for key, locs := range m.statements {
+		if key.line == line {
+			result = append(result, locs...)
+		}
+	}
+	sortLocations(result)
+	return result
+}
+
+// Lines returns the sorted lines of a file that statements were compiled
+// from, e.g. to mark executable lines in an editor. An empty file matches
+// every file.
+func (m *SourceMap) Lines(file string) []int {
+	seen := make(map[int]bool)
+	lines := make([]int, 0)
+	for key := range m.statements {
+		if (file == "" || key.file == file) && !seen[key.line] {
+			seen[key.line] = true
+			lines = append(lines, key.line)
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// sortLocations sorts locations by function and index
+func sortLocations(locs []Location) {
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].Function != locs[j].Function {
+			return locs[i].Function < locs[j].Function
+		}
+		return locs[i].Index < locs[j].Index
+	})
+}
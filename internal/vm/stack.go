@@ -0,0 +1,163 @@
+// Package vm provides the virtual machine implementation with key-based instruction execution
+package vm
+
+import "fmt"
+
+const (
+	// DefaultStackInitialSize is the initial capacity of an operand stack
+	DefaultStackInitialSize = 16
+
+	// DefaultStackMaxSize is the maximum depth of an operand stack (0 means unbounded)
+	DefaultStackMaxSize = 10000
+)
+
+// Stack represents a simple stack data structure for the VM
+type Stack struct {
+	data      []interface{}
+	top       int // Index of the top element
+	limit     int // Current capacity
+	max       int // Maximum depth allowed (0 means unbounded)
+	highWater int // Maximum depth reached
+	overflow  bool
+}
+
+// NewStack creates a new stack with the default limits
+func NewStack() *Stack {
+	return NewStackWithLimits(DefaultStackInitialSize, DefaultStackMaxSize)
+}
+
+// NewStackWithLimits creates a new stack that starts with the given capacity
+// and grows geometrically up to max items (0 means unbounded)
+func NewStackWithLimits(initial, max int) *Stack {
+	if initial <= 0 {
+		initial = DefaultStackInitialSize
+	}
+	if max > 0 && initial > max {
+		initial = max
+	}
+	return &Stack{
+		data:  make([]interface{}, initial),
+		top:   -1, // -1 indicates empty stack
+		limit: initial,
+		max:   max,
+	}
+}
+
+// Push adds an item to the top of the stack
+// Pushing past the maximum depth drops the item and marks the stack as
+// overflowed; the executor checks Err after each instruction and aborts
+// execution.
+func (s *Stack) Push(item interface{}) {
+	if s.max > 0 && s.top+1 >= s.max {
+		s.overflow = true
+		return
+	}
+
+	// Check if we need to expand the stack
+	if s.top+1 >= s.limit {
+		// Double the capacity, up to the maximum depth
+		newLimit := s.limit * 2
+		if s.max > 0 && newLimit > s.max {
+			newLimit = s.max
+		}
+		newData := make([]interface{}, newLimit)
+		copy(newData, s.data)
+		s.data = newData
+		s.limit = newLimit
+	}
+
+	s.top++
+	s.data[s.top] = item
+	if s.top+1 > s.highWater {
+		s.highWater = s.top + 1
+	}
+}
+
+// Pop removes and returns the top item from the stack
+func (s *Stack) Pop() interface{} {
+	if s.top < 0 {
+		return nil
+	}
+
+	item := s.data[s.top]
+	// Optionally clear the reference to help GC
+	s.data[s.top] = nil
+	s.top--
+
+	return item
+}
+
+// Peek returns the top item from the stack without removing it
+func (s *Stack) Peek() interface{} {
+	if s.top < 0 {
+		return nil
+	}
+
+	return s.data[s.top]
+}
+
+// PeekAt returns the item at the given depth below the top without removing it
+// (depth 0 is the top). It returns nil if the depth is out of range.
+func (s *Stack) PeekAt(depth int) interface{} {
+	if depth < 0 || depth > s.top {
+		return nil
+	}
+
+	return s.data[s.top-depth]
+}
+
+// Len returns the number of items in the stack
+func (s *Stack) Len() int {
+	return s.top + 1
+}
+
+// Cap returns the current capacity of the stack
+func (s *Stack) Cap() int {
+	return s.limit
+}
+
+// Reset empties the stack for reuse, dropping references to its items
+func (s *Stack) Reset() {
+	clear(s.data[:s.top+1])
+	s.top = -1
+	s.highWater = 0
+	s.overflow = false
+}
+
+// HighWater returns the maximum depth the stack has reached
+func (s *Stack) HighWater() int {
+	return s.highWater
+}
+
+// Err returns an error if the stack has exceeded its maximum depth
+func (s *Stack) Err() error {
+	if s.overflow {
+		return fmt.Errorf("stack overflow: maximum depth %d exceeded", s.max)
+	}
+	return nil
+}
+
+// IsEmpty returns true if the stack is empty
+func (s *Stack) IsEmpty() bool {
+	return s.top < 0
+}
+
+// Clear removes all items from the stack
+func (s *Stack) Clear() {
+	// Clear references to help GC
+	for i := 0; i <= s.top; i++ {
+		s.data[i] = nil
+	}
+	s.top = -1
+	s.overflow = false
+}
+
+// Items returns a copy of the stack items for debugging
+func (s *Stack) Items() []interface{} {
+	if s.top < 0 {
+		return []interface{}{}
+	}
+	items := make([]interface{}, s.top+1)
+	copy(items, s.data[:s.top+1])
+	return items
+}
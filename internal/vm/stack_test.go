@@ -150,4 +150,52 @@ func BenchmarkStackOperations(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		stack.Pop()
 	}
-}
\ No newline at end of file
+}
+
+func TestStackGrowthAndHighWater(t *testing.T) {
+	stack := NewStackWithLimits(2, 0)
+	if stack.Cap() != 2 {
+		t.Errorf("Expected initial capacity 2, got %d", stack.Cap())
+	}
+
+	for i := 0; i < 5; i++ {
+		stack.Push(i)
+	}
+	if stack.Cap() != 8 {
+		t.Errorf("Expected capacity 8 after geometric growth, got %d", stack.Cap())
+	}
+
+	stack.Pop()
+	stack.Pop()
+	if stack.HighWater() != 5 {
+		t.Errorf("Expected high-water mark 5, got %d", stack.HighWater())
+	}
+	if err := stack.Err(); err != nil {
+		t.Errorf("Unexpected error for unbounded stack: %v", err)
+	}
+}
+
+func TestStackMaxDepth(t *testing.T) {
+	stack := NewStackWithLimits(2, 3)
+
+	for i := 0; i < 3; i++ {
+		stack.Push(i)
+	}
+	if err := stack.Err(); err != nil {
+		t.Fatalf("Unexpected error at max depth: %v", err)
+	}
+
+	stack.Push(3)
+	if err := stack.Err(); err == nil {
+		t.Error("Expected overflow error past max depth")
+	}
+	if stack.Len() != 3 || stack.Peek() != 2 {
+		t.Errorf("Expected the push past max depth to be dropped, got %v", stack.Items())
+	}
+	if stack.Cap() != 3 {
+		t.Errorf("Expected capacity clamped to max depth 3, got %d", stack.Cap())
+	}
+	if stack.HighWater() != 3 {
+		t.Errorf("Expected high-water mark 3, got %d", stack.HighWater())
+	}
+}
@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// StepMode selects how often a step handler is called
+type StepMode int
+
+const (
+	// StepInstruction stops before every instruction
+	StepInstruction StepMode = iota
+
+	// StepStatement stops before the first instruction of every statement
+	StepStatement
+
+	// StepLine stops whenever execution enters a different source line of
+	// the current function, including when a loop comes back to its header
+	StepLine
+)
+
+// String returns the name of the step mode
+func (m StepMode) String() string {
+	switch m {
+	case StepInstruction:
+		return "instruction"
+	case StepStatement:
+		return "statement"
+	case StepLine:
+		return "line"
+	default:
+		return "unknown"
+	}
+}
+
+// StepEvent describes where execution is about to continue
+type StepEvent struct {
+	// Line is the source line of the next instruction (0 if unknown)
+	Line int
+
+	// PC is the index of the next instruction in its function and Depth the
+	// call depth of that function
+	PC    int
+	Depth int
+
+	// Function is the key of the function being executed, e.g. main.main
+	Function string
+
+	// Instruction is the instruction about to execute
+	Instruction *instruction.Instruction
+}
+
+// StepHandler is called before execution continues at a step. The handler
+// may block, e.g., while a user inspects the state with Snapshot; returning
+// an error aborts execution with that error.
+type StepHandler func(event StepEvent) error
+
+// SetStepHandler sets the debugger callback and its granularity. A nil
+// handler disables stepping. Statement and line steps rely on the source
+// positions recorded by the compiler.
+func (vm *VM) SetStepHandler(mode StepMode, handler StepHandler) {
+	vm.stepMode = mode
+	vm.stepHandler = handler
+}
+
+// step calls the step handler if instr starts a new step. lastLine tracks
+// the line of the previous line step in the current frame.
+func (exec *Executor) step(instr *instruction.Instruction, pc int, lastLine *int) error {
+	vm := exec.vm
+	switch vm.stepMode {
+	case StepStatement:
+		if !instr.StmtStart {
+			return nil
+		}
+	case StepLine:
+		if instr.Line == 0 || instr.Line == *lastLine {
+			return nil
+		}
+		*lastLine = instr.Line
+	}
+	return vm.stepHandler(StepEvent{Line: instr.Line, PC: pc, Depth: vm.callDepth, Function: vm.currentFunction(), Instruction: instr})
+}
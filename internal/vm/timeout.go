@@ -0,0 +1,32 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetTimeout limits the wall-clock time of one execution (0 means no
+// limit). Like the context of ExecuteContext, the deadline is checked every
+// cancelCheckInterval instructions; an execution that passes it fails with
+// an error wrapping context.DeadlineExceeded.
+func (vm *VM) SetTimeout(timeout time.Duration) {
+	vm.timeout = timeout
+}
+
+// armDeadline starts the timeout of an execution
+func (vm *VM) armDeadline() {
+	vm.deadline = time.Time{}
+	if vm.timeout > 0 {
+		vm.deadline = time.Now().Add(vm.timeout)
+	}
+}
+
+// checkDeadline returns an error when the current execution has run out of
+// time
+func (vm *VM) checkDeadline() error {
+	if time.Now().After(vm.deadline) {
+		return fmt.Errorf("execution timed out after %v: %w", vm.timeout, context.DeadlineExceeded)
+	}
+	return nil
+}
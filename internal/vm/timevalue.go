@@ -0,0 +1,220 @@
+package vm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// time.Time and time.Duration values passed in from the host are handled
+// natively by binary operations and method calls:
+//
+//   - t1 < t2, t1 == t2 (and the other comparisons) compare instants.
+//   - t + d, d + t and t - d yield a time.Time; t1 - t2 yields a Duration.
+//   - d1 + d2, d1 - d2 and comparisons between durations work as in Go;
+//     d * n, n * d and d / n scale a duration by an int.
+//   - Methods such as t.Format(layout), t.Unix(), t.Add(d), d.Seconds()
+//     and d.String() can be called from the script.
+
+// isTimeValue reports whether v is a time.Time or time.Duration
+func isTimeValue(v interface{}) bool {
+	switch v.(type) {
+	case time.Time, time.Duration:
+		return true
+	}
+	return false
+}
+
+// timeBinaryOp applies a binary operation involving time values.
+// handled is false when neither operand is a time value.
+func timeBinaryOp(op instruction.BinaryOp, left, right interface{}) (result interface{}, handled bool, err error) {
+	if !isTimeValue(left) && !isTimeValue(right) {
+		return nil, false, nil
+	}
+
+	switch l := left.(type) {
+	case time.Time:
+		switch r := right.(type) {
+		case time.Time:
+			switch op {
+			case instruction.OpSub:
+				return l.Sub(r), true, nil
+			case instruction.OpEqual:
+				return l.Equal(r), true, nil
+			case instruction.OpNotEqual:
+				return !l.Equal(r), true, nil
+			case instruction.OpLess:
+				return l.Before(r), true, nil
+			case instruction.OpLessEqual:
+				return !l.After(r), true, nil
+			case instruction.OpGreater:
+				return l.After(r), true, nil
+			case instruction.OpGreaterEqual:
+				return !l.Before(r), true, nil
+			}
+		case time.Duration:
+			switch op {
+			case instruction.OpAdd:
+				return l.Add(r), true, nil
+			case instruction.OpSub:
+				return l.Add(-r), true, nil
+			}
+		}
+	case time.Duration:
+		switch r := right.(type) {
+		case time.Time:
+			if op == instruction.OpAdd {
+				return r.Add(l), true, nil
+			}
+		case time.Duration:
+			switch op {
+			case instruction.OpAdd:
+				return l + r, true, nil
+			case instruction.OpSub:
+				return l - r, true, nil
+			case instruction.OpEqual:
+				return l == r, true, nil
+			case instruction.OpNotEqual:
+				return l != r, true, nil
+			case instruction.OpLess:
+				return l < r, true, nil
+			case instruction.OpLessEqual:
+				return l <= r, true, nil
+			case instruction.OpGreater:
+				return l > r, true, nil
+			case instruction.OpGreaterEqual:
+				return l >= r, true, nil
+			}
+		case int:
+			switch op {
+			case instruction.OpMul:
+				return l * time.Duration(r), true, nil
+			case instruction.OpDiv:
+				if r == 0 {
+					return nil, true, fmt.Errorf("division by zero")
+				}
+				return l / time.Duration(r), true, nil
+			}
+		}
+	case int:
+		if r, ok := right.(time.Duration); ok && op == instruction.OpMul {
+			return time.Duration(l) * r, true, nil
+		}
+	}
+
+	// Equality against unrelated types is simply false, as for other values
+	switch op {
+	case instruction.OpEqual:
+		return false, true, nil
+	case instruction.OpNotEqual:
+		return true, true, nil
+	}
+	return nil, true, fmt.Errorf("unsupported operation %d for types %T and %T", op, left, right)
+}
+
+// callTimeMethod calls a method on a time.Time or time.Duration receiver
+func callTimeMethod(receiver interface{}, name string, args []interface{}) (interface{}, error) {
+	switch v := receiver.(type) {
+	case time.Time:
+		return callTimeTimeMethod(v, name, args)
+	case time.Duration:
+		return callDurationMethod(v, name, args)
+	}
+	return nil, fmt.Errorf("method %s: unsupported receiver type %T", name, receiver)
+}
+
+// callTimeTimeMethod implements the methods available on time.Time
+func callTimeTimeMethod(t time.Time, name string, args []interface{}) (interface{}, error) {
+	switch name {
+	case "Format":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("Format requires 1 argument")
+		}
+		layout, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("Format requires a string layout, got %T", args[0])
+		}
+		return t.Format(layout), nil
+	case "Add":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("Add requires 1 argument")
+		}
+		d, ok := args[0].(time.Duration)
+		if !ok {
+			return nil, fmt.Errorf("Add requires a duration, got %T", args[0])
+		}
+		return t.Add(d), nil
+	case "Sub", "Before", "After", "Equal":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s requires 1 argument", name)
+		}
+		u, ok := args[0].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("%s requires a time, got %T", name, args[0])
+		}
+		switch name {
+		case "Sub":
+			return t.Sub(u), nil
+		case "Before":
+			return t.Before(u), nil
+		case "After":
+			return t.After(u), nil
+		default:
+			return t.Equal(u), nil
+		}
+	}
+
+	if len(args) != 0 {
+		return nil, fmt.Errorf("%s takes no arguments", name)
+	}
+	switch name {
+	case "String":
+		return t.String(), nil
+	case "Unix":
+		return int(t.Unix()), nil
+	case "UnixMilli":
+		return int(t.UnixMilli()), nil
+	case "Year":
+		return t.Year(), nil
+	case "Month":
+		return int(t.Month()), nil
+	case "Day":
+		return t.Day(), nil
+	case "Hour":
+		return t.Hour(), nil
+	case "Minute":
+		return t.Minute(), nil
+	case "Second":
+		return t.Second(), nil
+	case "Weekday":
+		return int(t.Weekday()), nil
+	case "IsZero":
+		return t.IsZero(), nil
+	case "UTC":
+		return t.UTC(), nil
+	}
+	return nil, fmt.Errorf("time.Time has no method %s", name)
+}
+
+// callDurationMethod implements the methods available on time.Duration
+func callDurationMethod(d time.Duration, name string, args []interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("%s takes no arguments", name)
+	}
+	switch name {
+	case "String":
+		return d.String(), nil
+	case "Hours":
+		return d.Hours(), nil
+	case "Minutes":
+		return d.Minutes(), nil
+	case "Seconds":
+		return d.Seconds(), nil
+	case "Milliseconds":
+		return int(d.Milliseconds()), nil
+	case "Nanoseconds":
+		return int(d.Nanoseconds()), nil
+	}
+	return nil, fmt.Errorf("time.Duration has no method %s", name)
+}
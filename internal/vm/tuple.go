@@ -0,0 +1,58 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Tuple holds the results of a script function returning several values,
+// e.g., return result, err. Scripts unpack it with a, b := f(); hosts get
+// it from Run and CallFunction, or as a slice from Results.
+type Tuple []interface{}
+
+// Results returns the values of a function result: the elements of a
+// tuple, or the value itself
+func Results(value interface{}) []interface{} {
+	if tuple, ok := value.(Tuple); ok {
+		return []interface{}(tuple)
+	}
+	return []interface{}{value}
+}
+
+// handleUnpack handles the UNPACK opcode, which replaces the tuple on top
+// of the stack by its values for an assignment to Arg variables
+func (exec *Executor) handleUnpack(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	n, ok := instr.Arg.(int)
+	if !ok {
+		return 0, fmt.Errorf("invalid value count for UNPACK")
+	}
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for UNPACK")
+	}
+
+	values := Results(stack.Pop())
+	if len(values) == 1 {
+		return 0, fmt.Errorf("assignment mismatch: %d variables but 1 value", n)
+	}
+	if len(values) != n {
+		return 0, fmt.Errorf("assignment mismatch: %d variables but %d values", n, len(values))
+	}
+	for _, value := range values {
+		stack.Push(value)
+	}
+	return pc + 1, nil
+}
+
+// isErrorMethodCall reports whether a call is err.Error() on a host error
+// value, such as one returned by errors.New
+func (exec *Executor) isErrorMethodCall(functionName string, args []interface{}) bool {
+	if functionName != "Error" || len(args) != 1 {
+		return false
+	}
+	if _, isError := args[0].(error); !isError {
+		return false
+	}
+	_, isFunction := exec.vm.GetFunction(functionName)
+	return !isFunction
+}
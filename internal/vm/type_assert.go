@@ -0,0 +1,173 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/types"
+)
+
+// Type assertions. TYPE_ASSERT checks the dynamic type of a value against
+// a type written as in Go ("int", "[]string", "map[string]int", "*Point",
+// "time.Time"), where script interfaces are given by their method names
+// ("interface{Area,Perimeter}", "interface{}" for any value). Values do
+// not carry every static type: script slices and maps have the type of
+// their elements when all elements have it, so an empty slice matches
+// any slice type, and struct values match their type with or without "*".
+
+// handleTypeAssert handles the TYPE_ASSERT opcode
+func (exec *Executor) handleTypeAssert(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	typ, ok := instr.Arg.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid type for TYPE_ASSERT")
+	}
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for TYPE_ASSERT")
+	}
+	value := stack.Pop()
+	matches := exec.vm.hasType(value, typ)
+
+	switch instr.Arg2 {
+	case instruction.AssertTest:
+		stack.Push(matches)
+	case instruction.AssertCommaOk:
+		if matches {
+			stack.Push(value)
+		} else {
+			stack.Push(zeroValue(typ))
+		}
+		stack.Push(matches)
+	default:
+		if !matches {
+			return 0, fmt.Errorf("interface conversion: interface {} is %s, not %s", typeString(value), typ)
+		}
+		stack.Push(value)
+	}
+	return pc + 1, nil
+}
+
+// hasType reports whether a value has the given type
+func (vm *VM) hasType(value interface{}, typ string) bool {
+	if typ == "nil" {
+		return value == nil
+	}
+	if value == nil {
+		return false
+	}
+
+	switch {
+	case typ == "interface{}" || typ == "any":
+		return true
+	case strings.HasPrefix(typ, "interface{"):
+		return vm.missingMethod(value, typ) == ""
+	case typ == "error":
+		_, ok := value.(error)
+		return ok
+	case strings.HasPrefix(typ, "[]"):
+		if elems, ok := value.([]interface{}); ok {
+			return vm.allHaveType(elems, typ[2:])
+		}
+	case strings.HasPrefix(typ, "map["):
+		keyType, elemType, ok := splitMapType(typ)
+		if !ok {
+			return false
+		}
+		switch m := value.(type) {
+		case map[string]interface{}:
+			if keyType != "string" {
+				return false
+			}
+			for _, elem := range m {
+				if elem != nil && !vm.hasType(elem, elemType) {
+					return false
+				}
+			}
+			return true
+		case map[interface{}]interface{}:
+			for key, elem := range m {
+				if !vm.hasType(key, keyType) || (elem != nil && !vm.hasType(elem, elemType)) {
+					return false
+				}
+			}
+			return true
+		}
+	case strings.HasPrefix(typ, "chan "):
+		_, ok := value.(*Channel)
+		return ok
+	case strings.HasPrefix(typ, "func("):
+		if isCallable(value) {
+			return true
+		}
+		return reflect.TypeOf(value).Kind() == reflect.Func
+	case strings.HasPrefix(typ, "*"):
+		if p, ok := value.(Pointer); ok {
+			return vm.hasType(p.Load(), typ[1:])
+		}
+		if s, ok := value.(*types.Struct); ok {
+			return s.Pointer && s.Type == typ[1:]
+		}
+	}
+
+	if s, ok := value.(*types.Struct); ok {
+		return !s.Pointer && s.Type == typ
+	}
+	switch typ {
+	case "rune":
+		typ = "int32"
+	case "byte":
+		typ = "uint8"
+	case "[]byte":
+		typ = "[]uint8"
+	}
+	return fmt.Sprintf("%T", value) == typ
+}
+
+// allHaveType reports whether all elements have the given type; nil
+// elements of types that can be nil are ignored
+func (vm *VM) allHaveType(elems []interface{}, typ string) bool {
+	for _, elem := range elems {
+		if elem == nil {
+			continue
+		}
+		if !vm.hasType(elem, typ) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitMapType splits "map[K]V" into K and V
+func splitMapType(typ string) (keyType, elemType string, ok bool) {
+	depth := 0
+	for i := len("map["); i < len(typ); i++ {
+		switch typ[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return typ[len("map["):i], typ[i+1:], true
+			}
+			depth--
+		}
+	}
+	return "", "", false
+}
+
+// typeString describes the dynamic type of a value in errors
+func typeString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case *types.Struct:
+		if v.Pointer {
+			return "*" + v.Type
+		}
+		return v.Type
+	case *Closure, *HostFunction:
+		return "func"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,75 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Variadic calls. A variadic script function receives its trailing
+// arguments packed into a slice bound to its last parameter. A call such as
+// f(a, xs...) marks its last argument with SPREAD; the arguments are
+// expanded before the call, so a spread slice can be passed to variadic
+// script functions and to host functions alike.
+
+// spreadArgs is the last argument of a call that spreads a slice
+type spreadArgs []interface{}
+
+// handleSpread handles the SPREAD opcode: the slice on top of the stack is
+// marked to be expanded into the arguments of the call that follows
+func (exec *Executor) handleSpread(stack *Stack, instr *instruction.Instruction, pc int) (int, error) {
+	if stack.Len() < 1 {
+		return 0, fmt.Errorf("stack underflow for SPREAD")
+	}
+	value := stack.Pop()
+	switch v := value.(type) {
+	case nil:
+		stack.Push(spreadArgs(nil))
+	case []interface{}:
+		stack.Push(spreadArgs(v))
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice {
+			return 0, fmt.Errorf("cannot use %v (%T) as variadic arguments", value, value)
+		}
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = rv.Index(i).Interface()
+		}
+		stack.Push(spreadArgs(values))
+	}
+	return pc + 1, nil
+}
+
+// expandSpread expands a spread last argument into the arguments
+func expandSpread(args []interface{}) []interface{} {
+	if len(args) == 0 {
+		return args
+	}
+	spread, ok := args[len(args)-1].(spreadArgs)
+	if !ok {
+		return args
+	}
+	expanded := make([]interface{}, 0, len(args)-1+len(spread))
+	expanded = append(expanded, args[:len(args)-1]...)
+	return append(expanded, spread...)
+}
+
+// packVariadic packs the trailing arguments of a call to a variadic script
+// function into a slice, so the arguments match its parameter names. The
+// arguments of other functions are returned unchanged.
+func packVariadic(info *ScriptFunctionInfo, args []interface{}) ([]interface{}, error) {
+	if info == nil || !info.Variadic || len(info.ParamNames) == 0 {
+		return args, nil
+	}
+	fixed := len(info.ParamNames) - 1
+	if len(args) < fixed {
+		return nil, fmt.Errorf("not enough arguments in call to %s: have %d, want at least %d", info.Name, len(args), fixed)
+	}
+	rest := make([]interface{}, len(args)-fixed)
+	copy(rest, args[fixed:])
+	packed := make([]interface{}, fixed, fixed+1)
+	copy(packed, args[:fixed])
+	return append(packed, rest), nil
+}
@@ -0,0 +1,150 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// Verify checks that an instruction set is well-formed before execution:
+// opcodes are known, operands have the types their handlers expect, jump
+// targets stay within the instruction set and local variable slots stay
+// within the frame, whose size locals is recorded in the function info of
+// the instruction set (0 for instruction sets of no function, such as
+// package-level code). Instruction sets produced by the compiler always
+// verify; hand-built or deserialized sets should be verified before being
+// executed.
+func Verify(instructions []*instruction.Instruction, locals int) error {
+	for pc, instr := range instructions {
+		if err := verifyInstruction(instr, len(instructions), locals); err != nil {
+			return fmt.Errorf("instruction %d: %w", pc, err)
+		}
+	}
+	return nil
+}
+
+// verifyInstruction checks a single instruction
+func verifyInstruction(instr *instruction.Instruction, count, locals int) error {
+	if instr == nil {
+		return fmt.Errorf("nil instruction")
+	}
+	if instr.Op >= instruction.OpCodeLast {
+		return fmt.Errorf("unknown opcode %d", instr.Op)
+	}
+
+	switch instr.Op {
+	case instruction.OpLoadName, instruction.OpStoreName, instruction.OpCreateVar,
+		instruction.OpGetField, instruction.OpSetField, instruction.OpAddrOf, instruction.OpAddrField:
+		if _, ok := instr.Arg.(string); !ok {
+			return fmt.Errorf("%s requires a string operand, got %T", instr.Op, instr.Arg)
+		}
+		if slot, ok := instr.Arg2.(int); ok && instr.Op == instruction.OpCreateVar && (slot < 0 || slot >= locals) {
+			return fmt.Errorf("%s slot %d out of range [0, %d)", instr.Op, slot, locals)
+		}
+	case instruction.OpLoadLocal, instruction.OpStoreLocal:
+		slot, ok := instr.Arg.(int)
+		if !ok {
+			return fmt.Errorf("%s requires an int slot, got %T", instr.Op, instr.Arg)
+		}
+		if slot < 0 || slot >= locals {
+			return fmt.Errorf("%s slot %d out of range [0, %d)", instr.Op, slot, locals)
+		}
+		if _, ok := instr.Arg2.(string); !ok {
+			return fmt.Errorf("%s requires a variable name, got %T", instr.Op, instr.Arg2)
+		}
+	case instruction.OpCall, instruction.OpGo:
+		// Calls without a name call a function value
+		if _, ok := instr.Arg.(string); !ok && instr.Arg != nil {
+			return fmt.Errorf("%s requires a function name, got %T", instr.Op, instr.Arg)
+		}
+		if n, ok := instr.Arg2.(int); !ok || n < 0 {
+			return fmt.Errorf("%s requires a non-negative argument count, got %v", instr.Op, instr.Arg2)
+		}
+	case instruction.OpCallMethod:
+		if _, ok := instr.Arg.(string); !ok {
+			return fmt.Errorf("%s requires a method name, got %T", instr.Op, instr.Arg)
+		}
+		switch arg2 := instr.Arg2.(type) {
+		case int:
+			if arg2 < 0 {
+				return fmt.Errorf("%s requires a non-negative argument count, got %d", instr.Op, arg2)
+			}
+		case []interface{}:
+		default:
+			return fmt.Errorf("%s requires an argument count, got %T", instr.Op, instr.Arg2)
+		}
+	case instruction.OpJump, instruction.OpJumpIf:
+		target, ok := instr.Arg.(int)
+		if !ok {
+			return fmt.Errorf("%s requires an int target, got %T", instr.Op, instr.Arg)
+		}
+		if target < 0 || target > count {
+			return fmt.Errorf("%s target %d out of range [0, %d]", instr.Op, target, count)
+		}
+	case instruction.OpBinaryOp:
+		op, ok := instr.Arg.(instruction.BinaryOp)
+		if !ok {
+			return fmt.Errorf("%s requires a binary operator, got %T", instr.Op, instr.Arg)
+		}
+		if op > instruction.OpOr {
+			return fmt.Errorf("unknown binary operator %d", op)
+		}
+	case instruction.OpNewSlice:
+		if n, ok := instr.Arg.(int); !ok || n < 0 {
+			return fmt.Errorf("%s requires a non-negative size, got %v", instr.Op, instr.Arg)
+		}
+	case instruction.OpMakeClosure:
+		if _, ok := instr.Arg.(string); !ok {
+			return fmt.Errorf("%s requires a function key, got %T", instr.Op, instr.Arg)
+		}
+	case instruction.OpDup:
+		if n, ok := instr.Arg.(int); !ok || n < 1 {
+			return fmt.Errorf("%s requires a positive count, got %v", instr.Op, instr.Arg)
+		}
+	case instruction.OpGetIndex:
+		if _, ok := instr.Arg2.(string); !ok && instr.Arg2 != nil {
+			return fmt.Errorf("%s requires an element type, got %T", instr.Op, instr.Arg2)
+		}
+	case instruction.OpNewMap:
+		if _, ok := instr.Arg.(string); !ok {
+			return fmt.Errorf("%s requires a key type, got %T", instr.Op, instr.Arg)
+		}
+	case instruction.OpMakeChan, instruction.OpMakeSlice:
+		if _, ok := instr.Arg.(string); !ok {
+			return fmt.Errorf("%s requires an element type, got %T", instr.Op, instr.Arg)
+		}
+	case instruction.OpTypeAssert:
+		if _, ok := instr.Arg.(string); !ok {
+			return fmt.Errorf("%s requires a type, got %T", instr.Op, instr.Arg)
+		}
+		if mode, ok := instr.Arg2.(int); !ok || mode < instruction.AssertValue || mode > instruction.AssertTest {
+			return fmt.Errorf("%s requires a mode, got %v", instr.Op, instr.Arg2)
+		}
+	case instruction.OpImplements:
+		if _, ok := instr.Arg.(string); !ok {
+			return fmt.Errorf("%s requires an interface name, got %T", instr.Op, instr.Arg)
+		}
+		if _, ok := instr.Arg2.(string); !ok {
+			return fmt.Errorf("%s requires an interface, got %T", instr.Op, instr.Arg2)
+		}
+	case instruction.OpSelect:
+		if _, ok := instr.Arg.([]instruction.SelectCase); !ok {
+			return fmt.Errorf("%s requires a list of cases, got %T", instr.Op, instr.Arg)
+		}
+		if _, ok := instr.Arg2.(bool); !ok {
+			return fmt.Errorf("%s requires a default flag, got %T", instr.Op, instr.Arg2)
+		}
+	case instruction.OpUnpack:
+		if n, ok := instr.Arg.(int); !ok || n < 2 {
+			return fmt.Errorf("%s requires a value count of at least 2, got %v", instr.Op, instr.Arg)
+		}
+	case instruction.OpImport:
+		if _, ok := instr.Arg.(string); !ok {
+			return fmt.Errorf("%s requires an import path, got %T", instr.Op, instr.Arg)
+		}
+		if _, ok := instr.Arg2.(string); !ok {
+			return fmt.Errorf("%s requires a package name, got %T", instr.Op, instr.Arg2)
+		}
+	}
+	return nil
+}
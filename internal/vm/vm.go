@@ -0,0 +1,755 @@
+// Package vm implements the virtual machine for GoScript
+package vm
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/context"
+	"github.com/lengzhao/goscript/types"
+)
+
+// VM represents the GoScript virtual machine
+type VM struct {
+	// Instructions organized by key (e.g., "main.main", "main.init")
+	InstructionSets map[string][]*instruction.Instruction
+
+	// Global context
+	GlobalCtx *context.Context
+
+	// Current context
+	currentCtx *context.Context
+
+	// Context of the executing package, holding its package-level
+	// variables; functions run in contexts nested in it
+	packageCtx *context.Context
+
+	// All instructions (for compatibility with compiler tests)
+	instructions []*instruction.Instruction
+
+	// Registered functions that can be called from scripts
+	functions map[string]ScriptFunction
+
+	// Host functions registered by name, bound to the VM they are called
+	// in, so clones can register them again (see Clone)
+	hostFunctions map[string]func(*VM) ScriptFunction
+
+	// Script function information for parameter names
+	scriptFunctionInfos map[string]*ScriptFunctionInfo
+
+	// Script function information indexed by key and name, built on first
+	// use (or by Warmup) and dropped when a function is registered
+	scriptFunctionIndex map[string]*ScriptFunctionInfo
+
+	// Registered modules with simplified interface
+	modules map[string]types.ModuleExecutor
+
+	// Modules with per-execution state and their instances for the current execution
+	moduleFactories map[string]types.ModuleFactory
+	moduleInstances map[string]types.ModuleInstance
+
+	// Constants exported by registered modules, keyed by module then name
+	moduleConstants map[string]map[string]interface{}
+
+	// Host-provided overrides for builtin functions, keyed by qualified name
+	// (e.g., "fmt.Println" or "len"). Overrides take precedence over both
+	// registered functions and module executors.
+	overrides map[string]ScriptFunction
+
+	// Method sets of script types: type name -> method names
+	methodSets map[string]map[string]bool
+
+	// Methods declared with pointer receivers: type name -> method names
+	pointerMethods map[string]map[string]bool
+
+	// Fields of script struct types, for promoted fields and methods
+	structTypes map[string][]StructField
+
+	// Global variables bound to Go variables (see BindVariable)
+	bindings []binding
+
+	// Standard output and error of scripts (see SetStdout)
+	stdout io.Writer
+	stderr io.Writer
+
+	// Builtin modules loaded for the imports of the program, which clones
+	// load again with their own options
+	builtinLoaded map[string]bool
+
+	// Mutex for thread safety
+	mu sync.RWMutex
+
+	// Instruction counter for security limits
+	instructionCount int64
+
+	// Maximum number of instructions allowed (0 means no limit)
+	maxInstructions int64
+
+	// Budget alert thresholds (fractions of maxInstructions, ascending), the
+	// handler notified when one is crossed, and the next scheduled alert
+	budgetThresholds []float64
+	budgetHandler    BudgetHandler
+	nextBudgetAlert  int64
+	budgetAlertIndex int
+
+	// Extra instructions the budget handler granted the current execution
+	budgetExtra int64
+
+	// Debug mode
+	debug bool
+
+	// Coercion mode for lenient string/number binary operations
+	coercion bool
+
+	// Result of dividing two ints
+	divisionMode DivisionMode
+
+	// Strict mode requires conditions to be bool
+	strictConditions bool
+
+	// Boundary crossing counters per instruction tag
+	boundaryCounts map[instruction.Tag]int64
+
+	// Optional approval callback for tagged instructions
+	boundaryApprover BoundaryApprover
+
+	// Operand stack sizing: initial capacity and maximum depth (0 means unbounded)
+	stackInitialSize int
+	stackMaxSize     int
+
+	// Deepest operand stack observed during the current execution
+	stackHighWater int
+
+	// Current and maximum nesting of instruction set executions (0 means no limit)
+	callDepth    int
+	maxCallDepth int
+
+	// Frames of the script functions being executed, outermost first, for
+	// stack traces, breakpoints and the profiler
+	frames []callFrame
+
+	// Wall-clock limit of an execution (0 means no limit), and the deadline
+	// of the current execution
+	timeout  time.Duration
+	deadline time.Time
+
+	// Approximate bytes allocated by the current execution, and the limit
+	// (0 means no limit)
+	memoryUsage int64
+	maxMemory   int64
+
+	// Profile of the current execution (nil unless profiling is enabled)
+	profiling bool
+	profile   *profiler
+
+	// Number of times each statement started, by its first instruction (nil
+	// unless coverage is enabled)
+	coverage map[*instruction.Instruction]int64
+
+	// Conversion of numbers entering the script from the host
+	numberMode types.NumberMode
+
+	// Conversion of arguments to the parameter types of Go functions
+	conversionMode ConversionMode
+
+	// Maximum number of entries of the cache module (0 means no limit)
+	cacheSize int
+
+	// Goroutines of the current execution (nil until the first go
+	// statement) and the limit on those not finished
+	sched         *scheduler
+	maxGoroutines int
+
+	// Parameters of function literals by instruction set key
+	functionLiterals map[string]*ScriptFunctionInfo
+
+	// Watch expressions evaluated after each instruction
+	watches         []*watch
+	nextWatchID     int
+	watchHandler    WatchHandler
+	evaluatingWatch bool
+
+	// Link-time hints of script functions (nil until linked), results of
+	// pure functions memoized in the current execution, and the operand
+	// stacks of finished frames kept for reuse
+	hints      map[string]FunctionHints
+	memo       map[memoKey]interface{}
+	freeStacks []*Stack
+
+	// Image of the compiled program, once captured or loaded
+	image *Image
+
+	// The function the executor loop is running, and the local variable
+	// slots of finished frames kept for reuse
+	frame      *activation
+	freeLocals [][]interface{}
+
+	// Debugger callback invoked before instructions, statements or lines
+	stepMode    StepMode
+	stepHandler StepHandler
+
+	// Breakpoints, the callback invoked when execution pauses, and whether
+	// the debugger pauses at the next statement
+	breakpoints  map[breakpoint]bool
+	pauseHandler PauseHandler
+	stepping     bool
+
+	// Host context of the current execution, passed to context-aware functions
+	hostCtx stdcontext.Context
+
+	// Context that cancels the current execution (nil if it cannot be
+	// canceled)
+	execCtx stdcontext.Context
+
+	// Finalizers registered during the current execution
+	finalizers []Finalizer
+
+	// Import paths scripts may use (nil allows all) and may not use
+	allowedModules map[string]bool
+	deniedModules  map[string]bool
+
+	// Host struct types readable through reflection, with their readable fields
+	allowedTypes map[reflect.Type]map[string]bool
+
+	// Struct types of objects registered with RegisterObject, whose methods
+	// scripts can call
+	objectTypes map[reflect.Type]bool
+}
+
+// DefaultMaxCallDepth is the default limit on nested function calls
+const DefaultMaxCallDepth = 1000
+
+// ScriptFunction represents a function that can be called from scripts
+type ScriptFunction func(args ...interface{}) (interface{}, error)
+
+// ScriptFunctionInfo represents information about a script-defined function
+type ScriptFunctionInfo struct {
+	Name       string
+	Key        string
+	ParamCount int
+	ParamNames []string // Add parameter names
+
+	// Variadic marks functions whose last parameter takes the remaining
+	// arguments as a slice
+	Variadic bool
+
+	// Pure marks functions annotated with //goscript:pure, whose results
+	// depend only on their arguments
+	Pure bool
+
+	// Receiver is the receiver type name of a method ("Point" for both
+	// func (p Point) and func (p *Point)), empty for functions
+	Receiver string
+
+	// Locals is the number of local variable slots of the function's frame
+	// (see locals.go)
+	Locals int
+}
+
+// NewVM creates a new virtual machine
+func NewVM() *VM {
+	vm := &VM{
+		InstructionSets:     make(map[string][]*instruction.Instruction),
+		functions:           make(map[string]ScriptFunction),
+		scriptFunctionInfos: make(map[string]*ScriptFunctionInfo),
+		modules:             make(map[string]types.ModuleExecutor),
+		moduleFactories:     make(map[string]types.ModuleFactory),
+		moduleInstances:     make(map[string]types.ModuleInstance),
+		moduleConstants:     make(map[string]map[string]interface{}),
+		builtinLoaded:       make(map[string]bool),
+		overrides:           make(map[string]ScriptFunction),
+		allowedTypes:        make(map[reflect.Type]map[string]bool),
+		objectTypes:         map[reflect.Type]bool{bufferType: true},
+		boundaryCounts:      make(map[instruction.Tag]int64),
+		instructions:        make([]*instruction.Instruction, 0),
+		GlobalCtx:           context.NewContext("global", nil), // Global context with no parent
+		maxInstructions:     10000,                             // Default limit of 10,000 instructions
+		stackInitialSize:    DefaultStackInitialSize,
+		stackMaxSize:        DefaultStackMaxSize,
+		maxCallDepth:        DefaultMaxCallDepth,
+		cacheSize:           DefaultCacheSize,
+		maxGoroutines:       DefaultMaxGoroutines,
+	}
+	vm.registerUniverseBuiltins()
+	vm.registerOutputBuiltins()
+	vm.registerIntrospectionBuiltins()
+	vm.registerChannelBuiltins()
+	vm.moduleFactories["cache"] = vm.newCacheInstance
+	return vm
+}
+
+// RegisterModule registers a module with a simplified interface
+func (vm *VM) RegisterModule(name string, executor types.ModuleExecutor) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.modules[name] = executor
+}
+
+// GetModule retrieves a registered module by name
+func (vm *VM) GetModule(name string) (types.ModuleExecutor, bool) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	if module, exists := vm.modules[name]; exists {
+		return module, true
+	}
+	return vm.factoryModuleExecutor(name)
+}
+
+// GetFunction retrieves a registered function by name
+// This can be a standalone function or a module function (module.function)
+func (vm *VM) GetFunction(name string) (ScriptFunction, bool) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	// Host overrides win over everything else
+	if fn, exists := vm.overrides[name]; exists {
+		return fn, true
+	}
+
+	// First check if it's a standalone function
+	fn, exists := vm.functions[name]
+	if exists {
+		return fn, true
+	}
+
+	// Check if it's a module function (format: "module.function")
+	if idx := strings.Index(name, "."); idx != -1 {
+		moduleName := name[:idx]
+		entrypoint := name[idx+1:]
+
+		// Check if the module exists
+		module, moduleExists := vm.modules[moduleName]
+		if !moduleExists {
+			module, moduleExists = vm.factoryModuleExecutor(moduleName)
+		}
+		if moduleExists {
+			if err := vm.checkModuleLocked(moduleName); err != nil {
+				return func(args ...interface{}) (interface{}, error) {
+					return nil, err
+				}, true
+			}
+			// Create a wrapper function that calls the module executor
+			wrapper := func(args ...interface{}) (interface{}, error) {
+				return module(entrypoint, args...)
+			}
+			return wrapper, true
+		}
+	}
+
+	return nil, false
+}
+
+// RegisterFunction registers a function that can be called from scripts
+func (vm *VM) RegisterFunction(name string, fn ScriptFunction) {
+	vm.bindFunction(name, func(*VM) ScriptFunction { return fn })
+}
+
+// bindFunction registers a host function made for the VM that calls it
+func (vm *VM) bindFunction(name string, bind func(*VM) ScriptFunction) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.hostFunctions == nil {
+		vm.hostFunctions = make(map[string]func(*VM) ScriptFunction)
+	}
+	vm.hostFunctions[name] = bind
+	vm.functions[name] = bind(vm)
+}
+
+// OverrideFunction installs a host-provided implementation for a builtin
+// function. The name is either a universe builtin ("len") or a qualified
+// module function ("fmt.Println").
+func (vm *VM) OverrideFunction(name string, fn ScriptFunction) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.overrides[name] = fn
+}
+
+// RemoveOverride removes a previously installed override
+func (vm *VM) RemoveOverride(name string) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	delete(vm.overrides, name)
+}
+
+// GetOverrides returns the sorted names of all active overrides
+func (vm *VM) GetOverrides() []string {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	names := make([]string, 0, len(vm.overrides))
+	for name := range vm.overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterScriptFunction registers a script-defined function
+func (vm *VM) RegisterScriptFunction(name string, info *ScriptFunctionInfo) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	// Store the function info for later use
+	vm.scriptFunctionInfos[name] = info
+	if info.Receiver != "" {
+		vm.addMethodLocked(info.Receiver, info.Name, strings.HasPrefix(name, "*"))
+	}
+	vm.scriptFunctionIndex = nil
+	vm.hints = nil
+
+	// Create a wrapper function that runs the script function when the host
+	// calls it; calls by instructions run in frames of the executor loop
+	vm.functions[name] = func(args ...interface{}) (interface{}, error) {
+		call, err := vm.newScriptCall(info.Key, info, args)
+		if err != nil {
+			return nil, err
+		}
+		return vm.runCall(call)
+	}
+}
+
+// scriptFunctionByName returns the script function registered under a
+// name, unless a host override replaces it
+func (vm *VM) scriptFunctionByName(name string) *ScriptFunctionInfo {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	if _, overridden := vm.overrides[name]; overridden {
+		return nil
+	}
+	return vm.scriptFunctionInfos[name]
+}
+
+// GetAllScriptFunctions returns all registered script function information
+func (vm *VM) GetAllScriptFunctions() map[string]*ScriptFunctionInfo {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	// Create a copy of the map to avoid race conditions
+	result := make(map[string]*ScriptFunctionInfo)
+	for name, info := range vm.scriptFunctionInfos {
+		result[name] = info
+	}
+
+	return result
+}
+
+// GetInstructions returns all instructions (for compatibility with compiler tests)
+func (vm *VM) GetInstructions() []*instruction.Instruction {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	// Collect all instructions from all instruction sets
+	var allInstructions []*instruction.Instruction
+	for key, instructions := range vm.InstructionSets {
+		if vm.debug {
+			vm.tracef("Instructions for key %s:\n", key)
+			for i, instr := range instructions {
+				vm.tracef("  %d: %s\n", i, instr.String())
+			}
+		}
+		allInstructions = append(allInstructions, instructions...)
+	}
+
+	// Also include instructions added directly
+	allInstructions = append(allInstructions, vm.instructions...)
+
+	return allInstructions
+}
+
+// AddInstruction adds an instruction to the VM (for compatibility with compiler tests)
+func (vm *VM) AddInstruction(instr *instruction.Instruction) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.instructions = append(vm.instructions, instr)
+}
+
+// SetMaxInstructions sets the maximum number of instructions allowed
+func (vm *VM) SetMaxInstructions(max int64) {
+	vm.maxInstructions = max
+	vm.armBudgetAlerts()
+}
+
+// GetInstructionCount returns the current instruction count
+func (vm *VM) GetInstructionCount() int64 {
+	return vm.instructionCount
+}
+
+// ResetInstructionCount resets the instruction counter
+func (vm *VM) ResetInstructionCount() {
+	vm.instructionCount = 0
+}
+
+// SetMaxCallDepth sets the maximum nesting of function calls (0 means no limit)
+func (vm *VM) SetMaxCallDepth(depth int) {
+	vm.maxCallDepth = depth
+}
+
+// SetStackLimits configures the initial capacity and maximum depth of the
+// operand stacks used during execution (max 0 means unbounded)
+func (vm *VM) SetStackLimits(initial, max int) {
+	vm.stackInitialSize = initial
+	vm.stackMaxSize = max
+	vm.freeStacks = nil
+}
+
+// GetStackHighWater returns the deepest operand stack observed during the
+// last execution
+func (vm *VM) GetStackHighWater() int {
+	return vm.stackHighWater
+}
+
+// AddInstructionSet adds a set of instructions with a specific key
+func (vm *VM) AddInstructionSet(key string, instructions []*instruction.Instruction) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.InstructionSets[key] = instructions
+	vm.hints = nil
+}
+
+// GetInstructionSet retrieves instructions by key
+func (vm *VM) GetInstructionSet(key string) ([]*instruction.Instruction, bool) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	instructions, exists := vm.InstructionSets[key]
+	return instructions, exists
+}
+
+// GetAllInstructionSets returns all instruction sets
+func (vm *VM) GetAllInstructionSets() map[string][]*instruction.Instruction {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	// Create a copy of the map to avoid race conditions
+	result := make(map[string][]*instruction.Instruction)
+	for key, instructions := range vm.InstructionSets {
+		result[key] = instructions
+	}
+
+	return result
+}
+
+// Execute runs the virtual machine with the given entry point
+// If entryPoint is empty, it defaults to "main.main" or tries to find another main function
+func (vm *VM) Execute(entryPoint string, args ...interface{}) (result interface{}, err error) {
+	// Malformed programs must fail with an error, never crash the host
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = newPanicError(r)
+		}
+	}()
+
+	// Finalizers and module instances live for exactly one execution
+	defer func() {
+		closeErr := errors.Join(vm.runFinalizers(), vm.closeModuleInstances())
+		if closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	// Bound variables are stored back once the execution has ended
+	defer func() {
+		if bindErr := vm.storeBindings(); bindErr != nil && err == nil {
+			err = bindErr
+		}
+	}()
+
+	// Goroutines still running when the execution ends are stopped
+	defer vm.stopGoroutines()
+	defer func() { vm.frames = nil }()
+
+	// The instruction count covers the whole execution, calls of script
+	// functions included, so it is only reset here
+	vm.ResetInstructionCount()
+	vm.budgetExtra = 0
+	vm.armBudgetAlerts()
+	vm.resetBoundaryCounts()
+	vm.resetWatches()
+	vm.stackHighWater = 0
+	vm.memo = nil
+	vm.stepping = false
+	vm.frames = nil
+	vm.resetProfile()
+	vm.memoryUsage = 0
+	vm.armDeadline()
+	vm.loadBindings()
+
+	if entryPoint == "" {
+		entryPoint = "main.main"
+		// If main.main doesn't exist, try to find another main function
+		if _, exists := vm.GetInstructionSet(entryPoint); !exists {
+			// Try to find any function ending with ".main"
+			for key := range vm.InstructionSets {
+				if len(key) >= 5 && key[len(key)-5:] == ".main" {
+					entryPoint = key
+					break
+				}
+			}
+		}
+	}
+
+	// Extract package name from entry point ("pkg.main" or "pkg.func.Name")
+	packageName := "main" // default
+	if idx := len(entryPoint) - 5; idx > 0 && entryPoint[idx:] == ".main" {
+		packageName = entryPoint[:idx]
+	} else if idx := strings.Index(entryPoint, ".func."); idx > 0 {
+		packageName = entryPoint[:idx]
+	}
+
+	// Create package context (for main package)
+	// The package context's parent is the global context, which holds the
+	// variables and objects added by the host
+	packageCtx := context.NewContext(packageName, vm.GlobalCtx)
+
+	// Initialize the package: imports, package-level variables and init
+	// functions
+	if err := vm.initPackage(packageName, packageCtx); err != nil {
+		return nil, err
+	}
+
+	// Execute the entry point function
+	instructions, exists := vm.GetInstructionSet(entryPoint)
+	if !exists {
+		return nil, fmt.Errorf("entry point %s not found", entryPoint)
+	}
+
+	// Create function context with package context as parent
+	functionCtx := context.NewContext(entryPoint, packageCtx)
+	vm.currentCtx = functionCtx
+
+	// Set function arguments as local variables
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = vm.convertHostValue("", arg)
+	}
+	// Check if this is a script function with known parameter names
+	if info := vm.lookupScriptFunction(entryPoint); info != nil && info.Key == entryPoint {
+		var err error
+		if values, err = packVariadic(info, values); err != nil {
+			return nil, err
+		}
+	}
+	paramNames := vm.getScriptFunctionParamNames(entryPoint, len(values))
+
+	// Set arguments as local variables with appropriate names
+	for i, value := range values {
+		functionCtx.CreateVariableWithType(paramNames[i], value, "unknown")
+	}
+
+	// Execute the function using the executor
+	vm.pushFrame(entryPoint)
+	defer vm.popFrame()
+	executor := NewExecutor(vm)
+
+	// Return result and error
+	result, err = executor.executeInstructions(instructions)
+	return vm.hostValue(result), err
+}
+
+// getScriptFunctionParamNames gets the parameter names for a script function
+// If the function is not a registered script function, it falls back to generic names
+func (vm *VM) getScriptFunctionParamNames(functionKey string, argCount int) []string {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	// Look for the function in script function infos
+	for _, info := range vm.scriptFunctionInfos {
+		if info.Key == functionKey && len(info.ParamNames) >= argCount {
+			return info.ParamNames[:argCount]
+		}
+	}
+
+	// Fall back to generic parameter names
+	paramNames := make([]string, argCount)
+	for i := 0; i < argCount; i++ {
+		paramNames[i] = fmt.Sprintf("arg%d", i)
+	}
+	return paramNames
+}
+
+// SetDebug enables or disables debug mode
+func (vm *VM) SetDebug(debug bool) {
+	vm.debug = debug
+}
+
+// GetDebug returns the current debug mode
+func (vm *VM) GetDebug() bool {
+	return vm.debug
+}
+
+// executeBinaryOp executes a binary operation
+func (vm *VM) executeBinaryOp(op instruction.BinaryOp, left, right interface{}) (interface{}, error) {
+	// Debug information
+	//fmt.Printf("Executing binary operation: %v with left=%v (type %T) and right=%v (type %T)\n", op, left, left, right, right)
+
+	// Apply lenient string/number coercion if enabled
+	if vm.coercion {
+		var err error
+		left, right, err = coerceOperands(op, left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Host time values have their own arithmetic and comparison rules
+	if result, handled, err := timeBinaryOp(op, left, right); handled {
+		return result, err
+	}
+	if result, handled, err := vm.runeBinaryOp(op, left, right); handled {
+		return result, err
+	}
+	if result, handled, err := bigBinaryOp(op, left, right); handled {
+		return result, err
+	}
+
+	if result, handled, err := numericOp(op, left, right, vm.divisionMode); handled {
+		return result, err
+	}
+	if result, handled, err := orderedOp(op, left, right); handled {
+		return result, err
+	}
+
+	switch op {
+	case instruction.OpAdd:
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				if err := vm.reserve(stringHeaderSize + int64(len(l)+len(r))); err != nil {
+					return nil, err
+				}
+				return l + r, nil
+			}
+		}
+
+	case instruction.OpEqual, instruction.OpNotEqual:
+		equal, err := vm.equal(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return equal == (op == instruction.OpEqual), nil
+
+	case instruction.OpAnd:
+		// Logical AND operation
+		// In Go, && is short-circuit, but in our VM implementation, both operands are already evaluated
+		// We just need to check if both are true
+		return vm.logicalOp(true, left, right)
+
+	case instruction.OpOr:
+		// Logical OR operation
+		// In Go, || is short-circuit, but in our VM implementation, both operands are already evaluated
+		// We just need to check if either is true
+		return vm.logicalOp(false, left, right)
+	}
+
+	if description, ok := binaryOpDescriptions[op]; ok {
+		return nil, fmt.Errorf("unsupported types for %s: %T and %T", description, left, right)
+	}
+	return nil, fmt.Errorf("unsupported binary operation: %d", op)
+}
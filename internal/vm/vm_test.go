@@ -125,3 +125,28 @@ func TestVMDefaultEntryPoint(t *testing.T) {
 		t.Errorf("Expected result 'Hello, World!', got %v", result)
 	}
 }
+
+func TestVMStackLimitExceeded(t *testing.T) {
+	vm := NewVM()
+	vm.SetStackLimits(1, 2)
+
+	instructions := []*instruction.Instruction{
+		instruction.NewInstruction(instruction.OpLoadConst, 1, nil),
+		instruction.NewInstruction(instruction.OpLoadConst, 2, nil),
+		instruction.NewInstruction(instruction.OpLoadConst, 3, nil),
+		instruction.NewInstruction(instruction.OpReturn, nil, nil),
+	}
+	vm.AddInstructionSet("main.main", instructions)
+
+	if _, err := vm.Execute("main.main"); err == nil {
+		t.Error("Expected stack overflow error")
+	}
+
+	vm.SetStackLimits(1, 0)
+	if _, err := vm.Execute("main.main"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if vm.GetStackHighWater() != 3 {
+		t.Errorf("Expected high-water mark 3, got %d", vm.GetStackHighWater())
+	}
+}
@@ -0,0 +1,159 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lengzhao/goscript/builtin"
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// WarmupReport summarizes what Warmup resolved ahead of execution
+type WarmupReport struct {
+	// InstructionSets and Instructions count the compiled code that was walked
+	InstructionSets int
+	Instructions    int
+
+	// ResolvedCalls counts host and script function call sites whose target
+	// was found
+	ResolvedCalls int
+
+	// Modules lists the builtin modules loaded for the program's imports
+	Modules []string
+
+	// Unresolved lists host functions called by the program that are not
+	// registered; such calls fail when reached
+	Unresolved []string
+
+	// Recursive lists the keys of the script functions that call themselves
+	// directly (see FunctionHints)
+	Recursive []string
+}
+
+// Warmup prepares the VM for execution so the first run does not pay
+// one-time costs: it loads the builtin modules the program imports, builds
+// the script function index, resolves statically known call targets and
+// links the script functions (see FunctionHints).
+// With verify set, every instruction set is also checked with Verify.
+func (vm *VM) Warmup(verify bool) (*WarmupReport, error) {
+	vm.mu.RLock()
+	keys := make([]string, 0, len(vm.InstructionSets))
+	for key := range vm.InstructionSets {
+		keys = append(keys, key)
+	}
+	vm.mu.RUnlock()
+	sort.Strings(keys)
+
+	vm.lookupScriptFunction("")
+
+	report := &WarmupReport{}
+	modules := make(map[string]bool)
+	unresolved := make(map[string]bool)
+	for _, key := range keys {
+		instructions, _ := vm.GetInstructionSet(key)
+		if verify {
+			if err := Verify(instructions, vm.localSlots(key)); err != nil {
+				return nil, fmt.Errorf("instruction set %s: %w", key, err)
+			}
+		}
+		report.InstructionSets++
+		report.Instructions += len(instructions)
+
+		for _, instr := range instructions {
+			switch instr.Op {
+			case instruction.OpImport:
+				importPath, _ := instr.Arg.(string)
+				pkgName, _ := instr.Arg2.(string)
+				if name, ok := vm.loadBuiltinModule(importPath, pkgName); ok && !modules[name] {
+					modules[name] = true
+					report.Modules = append(report.Modules, name)
+				}
+			case instruction.OpCall:
+				name, _ := instr.Arg.(string)
+				switch {
+				case instr.HasTag(instruction.TagHostCall):
+					if _, exists := vm.GetFunction(name); exists {
+						report.ResolvedCalls++
+					} else if !unresolved[name] {
+						unresolved[name] = true
+						report.Unresolved = append(report.Unresolved, name)
+					}
+				case instr.Tags == 0 && vm.lookupScriptFunction(name) != nil:
+					report.ResolvedCalls++
+				}
+			}
+		}
+	}
+	for key, hints := range vm.link() {
+		if hints.SelfRecursive {
+			report.Recursive = append(report.Recursive, key)
+		}
+	}
+	sort.Strings(report.Modules)
+	sort.Strings(report.Unresolved)
+	sort.Strings(report.Recursive)
+	return report, nil
+}
+
+// lookupScriptFunction returns the script function with the given key
+// (e.g., "main.func.add") or name, building the index on first use
+func (vm *VM) lookupScriptFunction(keyOrName string) *ScriptFunctionInfo {
+	vm.mu.RLock()
+	index := vm.scriptFunctionIndex
+	vm.mu.RUnlock()
+
+	if index == nil {
+		vm.mu.Lock()
+		index = make(map[string]*ScriptFunctionInfo, 2*len(vm.scriptFunctionInfos))
+		for _, info := range vm.scriptFunctionInfos {
+			index[info.Name] = info
+		}
+		// Keys take precedence over names
+		for _, info := range vm.scriptFunctionInfos {
+			index[info.Key] = info
+		}
+		vm.scriptFunctionIndex = index
+		vm.mu.Unlock()
+	}
+	return index[keyOrName]
+}
+
+// loadBuiltinModule registers the builtin module matching an import, if
+// any and not yet registered, and returns its name
+func (vm *VM) loadBuiltinModule(importPath, pkgName string) (string, bool) {
+	if vm.CheckModule(importPath) != nil {
+		return "", false
+	}
+	for _, moduleName := range builtin.ListAllModules() {
+		// Match either by module name or by import path
+		if moduleName != pkgName && moduleName != importPath {
+			continue
+		}
+		if _, exists := vm.GetModule(moduleName); exists {
+			return moduleName, true
+		}
+		// Modules with per-execution state are registered as factories
+		if moduleName == "mutex" {
+			vm.RegisterModuleFactory(moduleName, vm.newMutexInstance)
+			vm.mu.Lock()
+			vm.builtinLoaded[moduleName] = true
+			vm.mu.Unlock()
+			return moduleName, true
+		}
+		// Register the module with the VM
+		moduleExecutor, exists := builtin.GetModuleExecutorWithOptions(moduleName, builtin.ModuleOptions{
+			NumberMode:  vm.GetNumberMode(),
+			StructTypes: vm.StructType,
+			Stdout:      vm.StdoutWriter(),
+		})
+		if !exists {
+			return "", false
+		}
+		vm.mu.Lock()
+		vm.modules[moduleName] = moduleExecutor
+		vm.builtinLoaded[moduleName] = true
+		vm.mu.Unlock()
+		return moduleName, true
+	}
+	return "", false
+}
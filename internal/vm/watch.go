@@ -0,0 +1,131 @@
+package vm
+
+import (
+	"reflect"
+
+	"github.com/lengzhao/goscript/instruction"
+)
+
+// WatchEvent describes a watch expression that triggered
+type WatchEvent struct {
+	// ID identifies the watch, as returned by AddWatch
+	ID int
+
+	// Expr is the watched expression source
+	Expr string
+
+	// Old and New are the previous and current values of the expression
+	Old interface{}
+	New interface{}
+
+	// Instruction is the instruction that was just executed
+	Instruction *instruction.Instruction
+}
+
+// WatchHandler is called when a watch triggers. Returning an error aborts
+// execution with that error, acting as a breakpoint.
+type WatchHandler func(event WatchEvent) error
+
+// watch is a compiled watch expression and its last observed value
+type watch struct {
+	id           int
+	expr         string
+	instructions []*instruction.Instruction
+
+	// condition watches trigger when the value becomes truthy instead of
+	// on every change
+	condition bool
+
+	last    interface{}
+	hasLast bool
+}
+
+// AddWatch registers compiled watch expression instructions, evaluated after
+// every instruction. A value watch triggers when the value changes; a
+// condition watch triggers when the value becomes truthy. It returns the
+// watch ID.
+func (vm *VM) AddWatch(expr string, instructions []*instruction.Instruction, condition bool) int {
+	vm.nextWatchID++
+	vm.watches = append(vm.watches, &watch{
+		id:           vm.nextWatchID,
+		expr:         expr,
+		instructions: instructions,
+		condition:    condition,
+	})
+	return vm.nextWatchID
+}
+
+// RemoveWatch removes a watch by ID
+func (vm *VM) RemoveWatch(id int) {
+	for i, w := range vm.watches {
+		if w.id == id {
+			vm.watches = append(vm.watches[:i], vm.watches[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetWatchHandler sets the callback invoked when a watch triggers
+func (vm *VM) SetWatchHandler(handler WatchHandler) {
+	vm.watchHandler = handler
+}
+
+// resetWatches forgets the values observed by a previous execution
+func (vm *VM) resetWatches() {
+	for _, w := range vm.watches {
+		w.last, w.hasLast = nil, false
+	}
+}
+
+// checkWatches evaluates every watch after an instruction has executed.
+// Expressions that cannot be evaluated in the current scope (e.g., a
+// variable local to another function) are skipped and keep their last value.
+func (exec *Executor) checkWatches(instr *instruction.Instruction) error {
+	vm := exec.vm
+	if vm.evaluatingWatch {
+		return nil
+	}
+
+	// Watch evaluation is invisible to the script's resource accounting
+	vm.evaluatingWatch = true
+	savedCount, savedHighWater := vm.instructionCount, vm.stackHighWater
+	defer func() {
+		vm.evaluatingWatch = false
+		vm.instructionCount, vm.stackHighWater = savedCount, savedHighWater
+	}()
+
+	// Watches see the local variables in slots by name
+	ctx := vm.currentCtx
+	vm.currentCtx = vm.localsContext()
+	defer func() { vm.currentCtx = ctx }()
+
+	for _, w := range vm.watches {
+		value, err := NewExecutor(vm).executeInstructions(w.instructions)
+		if err != nil {
+			continue
+		}
+
+		triggered := false
+		switch {
+		case !w.hasLast:
+			// The first observation only establishes the baseline, except
+			// for a condition that already holds
+			triggered = w.condition && isTruthy(value)
+		case w.condition:
+			triggered = isTruthy(value) && !isTruthy(w.last)
+		default:
+			triggered = !reflect.DeepEqual(value, w.last)
+		}
+
+		event := WatchEvent{ID: w.id, Expr: w.expr, Old: w.last, New: value, Instruction: instr}
+		// Keep a copy so in-place changes to maps and slices are detected
+		w.last, w.hasLast = deepCopy(value), true
+
+		if triggered && vm.watchHandler != nil {
+			if err := vm.watchHandler(event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package goscript
+
+import (
+	"io"
+	"time"
+
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
+	"github.com/lengzhao/goscript/types"
+)
+
+// Function is the signature of host functions callable from scripts
+type Function = types.Function
+
+// ContextFunction is the signature of host functions that receive the host
+// context, including values attached with Script.WithValue
+type ContextFunction = vm.ContextFunction
+
+// Finalizer releases a resource when an execution ends or the script is
+// closed, see Script.OnExecutionEnd and Script.OnClose
+type Finalizer = vm.Finalizer
+
+// NumberMode controls number conversion at the script/host boundary
+type NumberMode = types.NumberMode
+
+// Number conversion modes, see the types package for details
+const (
+	NumberFloat64       = types.NumberFloat64
+	NumberPreserveInt   = types.NumberPreserveInt
+	NumberDecimalString = types.NumberDecimalString
+)
+
+// ConversionMode controls how arguments are converted to the parameter
+// types of Go functions, see Script.SetConversionMode
+type ConversionMode = vm.ConversionMode
+
+// Argument conversion modes: ConvertSafe converts numbers that keep their
+// value, ConvertStrict only within integer or floating-point types and
+// ConvertLenient also truncates
+const (
+	ConvertSafe    = vm.ConvertSafe
+	ConvertStrict  = vm.ConvertStrict
+	ConvertLenient = vm.ConvertLenient
+)
+
+// DivisionMode selects the result of dividing two ints, see
+// Script.SetDivisionMode
+type DivisionMode = vm.DivisionMode
+
+// Division modes: TruncatingInt divides ints as Go does, PromoteToFloat
+// divides them as float64s
+const (
+	TruncatingInt  = vm.TruncatingInt
+	PromoteToFloat = vm.PromoteToFloat
+)
+
+// Fields lists the struct fields passed to Script.AllowType
+func Fields(names ...string) []string {
+	return names
+}
+
+// WarmupReport summarizes what Program.Warmup resolved
+type WarmupReport = vm.WarmupReport
+
+// FunctionHints are link-time properties of a script function, see
+// VM.FunctionHints
+type FunctionHints = vm.FunctionHints
+
+// Constant is a package-level constant declared by a script, see
+// Script.Constants
+type Constant = compiler.Constant
+
+// Tuple holds the results of a script function returning several values,
+// see Script.RunResults and Script.CallFunctionResults
+type Tuple = vm.Tuple
+
+// Struct is a script struct value as seen by the host: its type name and
+// its fields
+type Struct = types.Struct
+
+// Pointer is a script pointer to a variable, struct field or slice element
+// as seen by the host; Load and Store access the value it points to
+type Pointer = vm.Pointer
+
+// RuntimeError is an error raised while running a script, with the source
+// position of the statement that failed; use errors.As to get it
+type RuntimeError = vm.RuntimeError
+
+// PanicError is the error a script fails with when a host function or the
+// VM panics
+type PanicError = vm.PanicError
+
+// Snapshot is a deep copy of script-visible variables, see Script.Snapshot
+type Snapshot = vm.Snapshot
+
+// Change is one difference between two snapshots
+type Change = vm.Change
+
+// Kinds of snapshot changes
+const (
+	ChangeAdded    = vm.ChangeAdded
+	ChangeRemoved  = vm.ChangeRemoved
+	ChangeModified = vm.ChangeModified
+)
+
+// DiffSnapshots returns the structural differences between two snapshots,
+// sorted by path (e.g., "total", "order.status", "items[1]")
+func DiffSnapshots(before, after Snapshot) []Change {
+	return vm.DiffSnapshots(before, after)
+}
+
+// StepMode selects the granularity of debugger steps, see Script.SetStepHandler
+type StepMode = vm.StepMode
+
+// Step granularities
+const (
+	StepInstruction = vm.StepInstruction
+	StepStatement   = vm.StepStatement
+	StepLine        = vm.StepLine
+)
+
+// StepEvent describes where a stepped script is about to continue
+type StepEvent = vm.StepEvent
+
+// StepHandler is called at every step of a stepped script
+type StepHandler = vm.StepHandler
+
+// Debugger inspects and resumes a script paused at a breakpoint, see
+// Script.SetPauseHandler
+type Debugger = vm.Debugger
+
+// PauseHandler is called when a script pauses at a breakpoint or step
+type PauseHandler = vm.PauseHandler
+
+// ErrMemoryLimit is the error of an execution exceeding its memory limit,
+// see Script.SetMaxMemory
+var ErrMemoryLimit = vm.ErrMemoryLimit
+
+// ErrModuleNotAllowed is the error of a script importing a module the host
+// does not allow, see Script.SetAllowedModules
+var ErrModuleNotAllowed = vm.ErrModuleNotAllowed
+
+// Stats is the public name for execution statistics
+type Stats = ExecutionStats
+
+// Profile is the profile of an execution, see Script.SetProfiling
+type Profile = vm.Profile
+
+// FunctionProfile is the profile of a script function
+type FunctionProfile = vm.FunctionProfile
+
+// CallSite is a line of a script function calling another function
+type CallSite = vm.CallSite
+
+// CompileLimits bounds the size of scripts, see Script.SetCompileLimits
+type CompileLimits = compiler.Limits
+
+// Coverage is the statement coverage of a script, see Script.SetCoverage
+type Coverage = vm.Coverage
+
+// StatementCoverage is the coverage of a statement
+type StatementCoverage = vm.StatementCoverage
+
+// LineCoverage is the coverage of a source line
+type LineCoverage = vm.LineCoverage
+
+// SourceMap maps instructions to source positions and back, see
+// Script.SourceMap
+type SourceMap = vm.SourceMap
+
+// Location is an instruction of a compiled program (function key and index)
+type Location = vm.Location
+
+// VM is the virtual machine running a script, see Script.GetVM
+type VM = vm.VM
+
+// Image is the immutable compiled form of a program, shared by the scripts
+// created from it, see Program.Image
+type Image = vm.Image
+
+// BytecodeVersion is the version of the format written by
+// Script.CompileToBytes; programs of another version are rejected by
+// Script.LoadProgram
+const BytecodeVersion = vm.BytecodeVersion
+
+// ScriptFunction is the signature of host functions added with
+// Script.AddFunction
+type ScriptFunction = vm.ScriptFunction
+
+// BudgetEvent reports that an execution has used a given fraction of its
+// instruction budget
+type BudgetEvent = vm.BudgetEvent
+
+// BudgetHandler is called at the alert thresholds set with
+// Script.SetBudgetAlerts
+type BudgetHandler = vm.BudgetHandler
+
+// BoundaryEvent describes a tagged instruction about to cross the
+// script/host boundary
+type BoundaryEvent = vm.BoundaryEvent
+
+// BoundaryApprover is called before each tagged instruction executes, see
+// Script.SetBoundaryApprover
+type BoundaryApprover = vm.BoundaryApprover
+
+// WatchEvent describes a watch expression that triggered
+type WatchEvent = vm.WatchEvent
+
+// WatchHandler is called when a watch triggers, see Script.SetWatchHandler
+type WatchHandler = vm.WatchHandler
+
+// Compiler compiles scripts; compile interceptors emit instructions through
+// it
+type Compiler = compiler.Compiler
+
+// CompileInterceptor is called before the compiler compiles a statement or
+// an expression, see Script.AddCompileInterceptor
+type CompileInterceptor = compiler.Interceptor
+
+// CompileDiagnostics are the problems found by the compiler, see
+// Program.Diagnostics
+type CompileDiagnostics = compiler.CompileDiagnostics
+
+// Diagnostic is a problem found by the compiler at a source position
+type Diagnostic = compiler.Diagnostic
+
+// Severity is the severity of a diagnostic
+type Severity = compiler.Severity
+
+// Diagnostic severities
+const (
+	SeverityError   = compiler.SeverityError
+	SeverityWarning = compiler.SeverityWarning
+)
+
+// Unlimited disables a limit of Options whose zero value selects the
+// default (e.g., Options{MaxInstructions: Unlimited})
+const Unlimited = -1
+
+// Options configures a Script created with NewScriptWithOptions. Limits
+// left at zero take their value from DefaultOptions, so Options{} is as
+// safe as NewScript; set them to Unlimited to disable them.
+type Options struct {
+	// MaxInstructions limits the number of executed instructions
+	// (default: 10000)
+	MaxInstructions int64
+
+	// Debug enables debug output
+	Debug bool
+
+	// Coercion enables lenient string/number coercion in binary operations
+	Coercion bool
+
+	// DivisionMode selects whether dividing two ints truncates or gives a
+	// float64
+	DivisionMode DivisionMode
+
+	// StrictConditions requires conditions to be bool, as in Go
+	StrictConditions bool
+
+	// Strict reports unused variables, unreachable code and shadowed
+	// declarations as warnings
+	Strict bool
+
+	// CompileLimits bounds the size of the source and the compiled program
+	CompileLimits CompileLimits
+
+	// StackInitialSize is the initial operand stack capacity
+	StackInitialSize int
+
+	// StackMaxSize is the maximum operand stack depth (default: 10000)
+	StackMaxSize int
+
+	// NumberMode controls number conversion from the host and the json module
+	NumberMode NumberMode
+
+	// ConversionMode controls argument conversion to Go function parameters
+	ConversionMode ConversionMode
+
+	// CacheSize is the maximum number of entries of the cache module
+	// (default: 1000)
+	CacheSize int
+
+	// MaxGoroutines is the maximum number of unfinished goroutines of one
+	// execution (default: 100)
+	MaxGoroutines int
+
+	// MaxMemory is the approximate number of bytes one execution may
+	// allocate (0 means no limit)
+	MaxMemory int64
+
+	// Timeout limits the wall-clock time of one execution (0 means no limit)
+	Timeout time.Duration
+
+	// Stdout and Stderr receive the output and debug output of the script
+	// (os.Stdout and os.Stderr if nil)
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// DefaultOptions returns the options used by NewScript
+func DefaultOptions() Options {
+	return Options{
+		MaxInstructions:  10000,
+		StackInitialSize: vm.DefaultStackInitialSize,
+		StackMaxSize:     vm.DefaultStackMaxSize,
+		CacheSize:        vm.DefaultCacheSize,
+		MaxGoroutines:    vm.DefaultMaxGoroutines,
+	}
+}
+
+// NewScriptWithOptions creates a new script configured by opts
+func NewScriptWithOptions(source []byte, opts Options) *Script {
+	defaults := DefaultOptions()
+	if opts.MaxInstructions == 0 {
+		opts.MaxInstructions = defaults.MaxInstructions
+	}
+	if opts.StackInitialSize == 0 {
+		opts.StackInitialSize = defaults.StackInitialSize
+	}
+	if opts.StackMaxSize == 0 {
+		opts.StackMaxSize = defaults.StackMaxSize
+	}
+	if opts.CacheSize == 0 {
+		opts.CacheSize = defaults.CacheSize
+	}
+	if opts.MaxGoroutines == 0 {
+		opts.MaxGoroutines = defaults.MaxGoroutines
+	}
+
+	script := NewScript(source)
+	script.SetMaxInstructions(opts.MaxInstructions)
+	script.SetDebug(opts.Debug)
+	script.SetCoercion(opts.Coercion)
+	script.SetDivisionMode(opts.DivisionMode)
+	script.SetStrictConditions(opts.StrictConditions)
+	script.SetStrict(opts.Strict)
+	script.SetCompileLimits(opts.CompileLimits)
+	script.SetStackLimits(opts.StackInitialSize, opts.StackMaxSize)
+	script.SetNumberMode(opts.NumberMode)
+	script.SetConversionMode(opts.ConversionMode)
+	script.SetCacheSize(opts.CacheSize)
+	script.SetMaxGoroutines(opts.MaxGoroutines)
+	script.SetMaxMemory(opts.MaxMemory)
+	script.SetTimeout(opts.Timeout)
+	script.SetStdout(opts.Stdout)
+	script.SetStderr(opts.Stderr)
+	return script
+}
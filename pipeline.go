@@ -0,0 +1,70 @@
+package goscript
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StageStats holds the execution statistics of one pipeline stage
+type StageStats struct {
+	// Entry is the entry point the stage ran, as passed to Pipeline
+	Entry string
+
+	ExecutionStats
+}
+
+// Pipeline runs the given script functions in order, feeding the output of
+// each stage as the single argument of the next; the first stage receives
+// initial. Entries may be plain function names (e.g., "double") or
+// instruction set keys (e.g., "main.func.double").
+//
+// Execution stops at the first failing stage. The returned stats cover every
+// stage that ran, including the failing one.
+func (s *Script) Pipeline(entries []string, initial interface{}) (interface{}, []StageStats, error) {
+	if len(entries) == 0 {
+		return nil, nil, fmt.Errorf("pipeline requires at least one entry")
+	}
+
+	program, err := s.Compile()
+	if err != nil {
+		return nil, nil, err
+	}
+	s.vm.SetMaxInstructions(s.maxInstructions)
+	s.vm.SetHostContext(s.hostContext(context.Background()))
+
+	// Resolve every entry up front so a typo fails before any stage runs
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		key, ok := program.resolveEntry(entry)
+		if !ok {
+			return nil, nil, fmt.Errorf("pipeline stage %d: entry point %s not found", i, entry)
+		}
+		keys[i] = key
+	}
+
+	stats := make([]StageStats, 0, len(entries))
+	value := initial
+	for i, key := range keys {
+		startTime := time.Now()
+		result, err := s.vm.Execute(key, value)
+
+		stage := StageStats{Entry: entries[i]}
+		stage.ExecutionTime = time.Since(startTime)
+		stage.InstructionCount = int(s.vm.GetInstructionCount())
+		stage.BoundaryCrossings = s.vm.GetBoundaryCounts()
+		stage.StackHighWater = s.vm.GetStackHighWater()
+		if err != nil {
+			stage.ErrorCount = 1
+			s.executionStats.ErrorCount++
+		}
+		stats = append(stats, stage)
+
+		if err != nil {
+			return nil, stats, fmt.Errorf("pipeline stage %d (%s): %w", i, entries[i], err)
+		}
+		value = result
+	}
+
+	return value, stats, nil
+}
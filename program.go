@@ -0,0 +1,202 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"io"
+	"sort"
+
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+// Program is a compiled script. It is obtained from Script.Compile and
+// exposes read-only information about the compiled code.
+type Program struct {
+	vm *vm.VM
+
+	// Immutable compiled form of the program, shared by the scripts made
+	// with NewScript, and the script modules it imports
+	image   *vm.Image
+	modules []programModule
+
+	// Syntax tree the program was compiled from
+	file *ast.File
+
+	// Compiler warnings, formatted as "file:line:col: warning: message",
+	// and as diagnostics
+	warnings    []string
+	diagnostics compiler.CompileDiagnostics
+
+	// Package-level constants in declaration order
+	constants []Constant
+}
+
+// programModule is a script module a program imports, compiled
+type programModule struct {
+	path    string
+	program *Program
+}
+
+// NewScript returns a new script running the program. The script shares the
+// program's compiled instructions, so scripts made from one program can run
+// concurrently without compiling the source again; each has its own globals,
+// limits, host functions and modules, which are set on it as usual. It
+// starts with the module policy the program was compiled with.
+func (p *Program) NewScript() (*Script, error) {
+	if p.image == nil {
+		return nil, fmt.Errorf("program has no image")
+	}
+	s := NewScript(nil)
+	s.vm.LoadImage(p.image)
+	allowed, denied := p.vm.ModulePolicy()
+	s.SetAllowedModules(allowed)
+	s.SetDeniedModules(denied)
+	if err := p.attach(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// attach makes the program the compiled program of a script whose VM has
+// its image loaded, with script modules of its own
+func (p *Program) attach(s *Script) error {
+	for _, module := range p.modules {
+		moduleScript, err := module.program.NewScript()
+		if err != nil {
+			return fmt.Errorf("import %q: %w", module.path, err)
+		}
+		s.addScriptModule(module.path, moduleScript)
+	}
+	s.modules = p.modules
+	s.program = &Program{
+		vm:          s.vm,
+		image:       p.image,
+		modules:     p.modules,
+		file:        p.file,
+		warnings:    p.warnings,
+		diagnostics: p.diagnostics,
+		constants:   p.constants,
+	}
+	return nil
+}
+
+// Image returns the immutable compiled form of the program
+func (p *Program) Image() *Image {
+	return p.image
+}
+
+// AST returns the syntax tree the program was compiled from, for static
+// analysis, or nil for a program loaded with LoadProgram. It must not be
+// modified.
+func (p *Program) AST() *ast.File {
+	return p.file
+}
+
+// Warnings returns the warnings reported while compiling the program, such
+// as loops without an exit or recursion without a base case
+func (p *Program) Warnings() []string {
+	return p.warnings
+}
+
+// Diagnostics returns the warnings reported while compiling the program
+// with their positions and severity, including those of strict mode (see
+// Script.SetStrict)
+func (p *Program) Diagnostics() CompileDiagnostics {
+	return p.diagnostics
+}
+
+// Constants returns the package-level constants of the program in
+// declaration order, with their compile-time values
+func (p *Program) Constants() []Constant {
+	return p.constants
+}
+
+// SourceMap returns the mapping between the instructions of the program
+// (by function key and index, as in StepEvent and RuntimeError frames) and
+// the source positions they were compiled from
+func (p *Program) SourceMap() *SourceMap {
+	return p.image.SourceMap()
+}
+
+// Functions returns the sorted names of all script-defined functions
+func (p *Program) Functions() []string {
+	infos := p.vm.GetAllScriptFunctions()
+	names := make([]string, 0, len(infos))
+	for name := range infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasFunction reports whether the program defines the named function
+func (p *Program) HasFunction(name string) bool {
+	_, exists := p.vm.GetAllScriptFunctions()[name]
+	return exists
+}
+
+// Warmup prepares the program so the first execution does not pay one-time
+// setup costs: imported builtin modules are loaded and call targets are
+// resolved up front. With verify set, all instruction sets are also checked
+// for well-formedness. The report lists host functions the program calls
+// that are not registered.
+func (p *Program) Warmup(verify bool) (*WarmupReport, error) {
+	return p.vm.Warmup(verify)
+}
+
+// resolveEntry maps a function name or instruction set key to the key to execute
+func (p *Program) resolveEntry(entry string) (string, bool) {
+	if info, exists := p.vm.GetAllScriptFunctions()[entry]; exists {
+		return info.Key, true
+	}
+	if _, exists := p.vm.GetInstructionSet(entry); exists {
+		return entry, true
+	}
+	return "", false
+}
+
+// EntryPoints returns the sorted keys of all compiled instruction sets
+// (e.g., "main", "main.main", "main.func.add")
+func (p *Program) EntryPoints() []string {
+	sets := p.vm.GetAllInstructionSets()
+	keys := make([]string, 0, len(sets))
+	for key := range sets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Disassemble writes a listing of every instruction set in the order of
+// EntryPoints: the index, source position and disassembly of each
+// instruction
+func (p *Program) Disassemble(w io.Writer) error {
+	for i, key := range p.EntryPoints() {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if err := p.disassemble(w, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DisassembleFunction writes the listing of one instruction set, named by
+// function name or key (e.g., "main.main")
+func (p *Program) DisassembleFunction(w io.Writer, entry string) error {
+	key, ok := p.resolveEntry(entry)
+	if !ok {
+		return fmt.Errorf("function %s not found", entry)
+	}
+	return p.disassemble(w, key)
+}
+
+// disassemble writes the listing of the instruction set with the given key
+func (p *Program) disassemble(w io.Writer, key string) error {
+	_, err := io.WriteString(w, p.vm.Disassemble(key))
+	return err
+}
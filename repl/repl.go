@@ -0,0 +1,482 @@
+// Package repl implements an interactive GoScript interpreter.
+//
+// Each input is either a declaration (import, type, const or func), kept
+// for the following inputs, or statements, run at once. Variables declared
+// by statements persist across inputs, and the value of an expression
+// input is printed:
+//
+//	>>> x := 20
+//	>>> func double(n int) int {
+//	...     return n * 2
+//	... }
+//	>>> double(x) + 2
+//	42
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/parser"
+)
+
+const (
+	prompt         = ">>> "
+	continuePrompt = "... "
+
+	// captureFunction is the host function reporting the variables an
+	// input declares
+	captureFunction = "__repl_capture"
+)
+
+const help = `Enter statements, expressions or declarations. Commands:
+  :vars   list the variables
+  :funcs  list the functions
+  :help   show this help
+  :quit   exit
+`
+
+// REPL is an interactive interpreter session
+type REPL struct {
+	opts goscript.Options
+
+	// Import paths, in import order
+	imports []string
+
+	// Source of the declarations by name ("double", "Point", "Point.Area")
+	// and their names in declaration order
+	decls     map[string]string
+	declOrder []string
+	funcs     map[string]bool
+
+	// Variables by name and their names in declaration order
+	vars     map[string]interface{}
+	varOrder []string
+}
+
+// New creates a session with the default options and no instruction limit
+func New() *REPL {
+	opts := goscript.DefaultOptions()
+	opts.MaxInstructions = goscript.Unlimited
+	return NewWithOptions(opts)
+}
+
+// NewWithOptions creates a session whose inputs run with opts
+func NewWithOptions(opts goscript.Options) *REPL {
+	return &REPL{
+		opts:  opts,
+		decls: make(map[string]string),
+		funcs: make(map[string]bool),
+		vars:  make(map[string]interface{}),
+	}
+}
+
+// Eval evaluates a complete input and returns the values of an expression
+// input (none for declarations and statements). A failed input leaves the
+// session unchanged.
+func (r *REPL) Eval(input string) ([]interface{}, error) {
+	file, stmts, err := parseInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		return nil, r.declare(file, input)
+	}
+	return r.run(stmts, input)
+}
+
+// Variables returns the names of the variables in declaration order
+func (r *REPL) Variables() []string {
+	return append([]string(nil), r.varOrder...)
+}
+
+// Variable returns the value of a variable
+func (r *REPL) Variable(name string) (interface{}, bool) {
+	value, exists := r.vars[name]
+	return value, exists
+}
+
+// Functions returns the sorted names of the declared functions and methods
+func (r *REPL) Functions() []string {
+	names := make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run reads inputs from in until it ends or :quit, writing results,
+// errors and what the inputs print to out. An input continues on the next
+// lines while it is incomplete, such as a function whose body is not
+// closed yet; an empty line ends it anyway.
+func (r *REPL) Run(in io.Reader, out io.Writer) error {
+	stdout := r.opts.Stdout
+	r.opts.Stdout = out
+	defer func() { r.opts.Stdout = stdout }()
+
+	lines := bufio.NewScanner(in)
+	var pending strings.Builder
+	for {
+		if pending.Len() == 0 {
+			fmt.Fprint(out, prompt)
+		} else {
+			fmt.Fprint(out, continuePrompt)
+		}
+		if !lines.Scan() {
+			fmt.Fprintln(out)
+			return lines.Err()
+		}
+		line := lines.Text()
+
+		if pending.Len() == 0 {
+			switch command := strings.TrimSpace(line); command {
+			case "":
+				continue
+			case ":quit", ":q":
+				return nil
+			}
+			if strings.HasPrefix(strings.TrimSpace(line), ":") {
+				r.command(strings.TrimSpace(line), out)
+				continue
+			}
+		}
+
+		pending.WriteString(line)
+		pending.WriteString("\n")
+		input := pending.String()
+		if line != "" && incomplete(input) {
+			continue
+		}
+		pending.Reset()
+
+		results, err := r.Eval(input)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+		if len(results) > 0 && !(len(results) == 1 && results[0] == nil) {
+			fmt.Fprintln(out, results...)
+		}
+	}
+}
+
+// command runs a ":" command
+func (r *REPL) command(command string, out io.Writer) {
+	switch command {
+	case ":vars":
+		for _, name := range r.varOrder {
+			fmt.Fprintf(out, "%s = %v\n", name, r.vars[name])
+		}
+	case ":funcs":
+		for _, name := range r.Functions() {
+			fmt.Fprintln(out, name)
+		}
+	case ":help":
+		fmt.Fprint(out, help)
+	default:
+		fmt.Fprintf(out, "unknown command %s, see :help\n", command)
+	}
+}
+
+// declare keeps the declarations of an input for the following inputs
+func (r *REPL) declare(file *ast.File, input string) error {
+	source := declPrefix + input
+
+	imports := append([]string(nil), r.imports...)
+	decls := make(map[string]string)
+	var order []string
+	funcs := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if !contains(imports, path) {
+			imports = append(imports, path)
+		}
+	}
+	for _, decl := range file.Decls {
+		text := source[int(decl.Pos())-1 : int(decl.End())-1]
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				name = receiverName(decl.Recv.List[0].Type) + "." + name
+			}
+			decls[name] = text
+			order = append(order, name)
+			funcs[name] = true
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				continue
+			}
+			// One name per declaration, so that redeclaring it replaces it
+			var names []string
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						names = append(names, name.Name)
+					}
+				}
+			}
+			name := strings.Join(names, ",")
+			decls[name] = text
+			order = append(order, name)
+		}
+	}
+
+	// Check that the declarations compile with the previous ones
+	next := *r
+	next.imports = imports
+	next.decls = mergeDecls(r.decls, decls)
+	next.declOrder = mergeOrder(r.declOrder, order)
+	script := next.script("func main() {\n}\n")
+	if _, err := script.Compile(); err != nil {
+		return err
+	}
+
+	r.imports, r.decls, r.declOrder = next.imports, next.decls, next.declOrder
+	for name := range funcs {
+		r.funcs[name] = true
+	}
+	return nil
+}
+
+// run runs the statements of an input and keeps the variables they declare
+func (r *REPL) run(stmts []ast.Stmt, input string) ([]interface{}, error) {
+	var body string
+	var declared []string
+	expr, isExpr := singleExpr(stmts)
+	if isExpr {
+		body = "\treturn " + input[int(expr.Pos())-1-len(stmtPrefix):int(expr.End())-1-len(stmtPrefix)] + "\n"
+	} else {
+		declared = declaredNames(stmts)
+		body = strings.TrimRight(input, "\n") + "\n"
+		if len(declared) > 0 {
+			args := make([]string, 0, 2*len(declared))
+			for _, name := range declared {
+				args = append(args, strconv.Quote(name), name)
+			}
+			body += "\t" + captureFunction + "(" + strings.Join(args, ", ") + ")\n"
+		}
+	}
+
+	script := r.script("func main() {\n" + body + "}\n")
+	for _, name := range r.varOrder {
+		if err := script.AddVariable(name, r.vars[name]); err != nil {
+			return nil, err
+		}
+	}
+	captured := make(map[string]interface{})
+	err := script.AddFunction(captureFunction, func(args ...interface{}) (interface{}, error) {
+		for i := 0; i+1 < len(args); i += 2 {
+			captured[args[i].(string)] = args[i+1]
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := script.RunResults()
+	if err != nil {
+		return nil, err
+	}
+
+	// Variables may be assigned by the input or the functions it calls
+	for _, name := range r.varOrder {
+		if value, exists := script.GetVariable(name); exists {
+			r.vars[name] = value
+		}
+	}
+	for _, name := range declared {
+		if _, exists := r.vars[name]; !exists {
+			r.varOrder = append(r.varOrder, name)
+		}
+		r.vars[name] = captured[name]
+	}
+	if !isExpr {
+		return nil, nil
+	}
+	return results, nil
+}
+
+// script creates the script of an input from the session's imports and
+// declarations followed by main
+func (r *REPL) script(main string) *goscript.Script {
+	var source strings.Builder
+	source.WriteString("package main\n\n")
+	for _, path := range r.imports {
+		fmt.Fprintf(&source, "import %q\n", path)
+	}
+	for _, name := range r.declOrder {
+		source.WriteString("\n")
+		source.WriteString(r.decls[name])
+		source.WriteString("\n")
+	}
+	source.WriteString("\n")
+	source.WriteString(main)
+	return goscript.NewScriptWithOptions([]byte(source.String()), r.opts)
+}
+
+// Inputs are parsed as declarations, or else as the body of a function
+const (
+	declPrefix = "package main\n"
+	stmtPrefix = "package main\nfunc main() {\n"
+)
+
+// parseInput parses an input as declarations (file) or statements
+func parseInput(input string) (*ast.File, []ast.Stmt, error) {
+	if file, err := parser.New().Parse("input", []byte(declPrefix+input), 0); err == nil && isDeclarations(file) {
+		return file, nil, nil
+	}
+	file, err := parser.New().Parse("input", []byte(stmtPrefix+input+"\n}\n"), 0)
+	if err != nil {
+		return nil, nil, inputError(err)
+	}
+	return nil, file.Decls[0].(*ast.FuncDecl).Body.List, nil
+}
+
+// isDeclarations reports whether a file parsed from an input declares
+// only imports, types, constants and functions; variable declarations
+// are statements, so that the variables persist
+func isDeclarations(file *ast.File) bool {
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.VAR {
+			return false
+		}
+	}
+	return len(file.Decls) > 0
+}
+
+// incomplete reports whether an input ends before its last construct,
+// such as a function without its closing brace: parsed either way, it
+// fails at its end
+func incomplete(input string) bool {
+	input = strings.TrimRight(input, " \t\n")
+	_, declErr := parser.New().Parse("input", []byte(declPrefix+input), 0)
+	_, stmtErr := parser.New().Parse("input", []byte(stmtPrefix+input+"\n}\n"), 0)
+	if declErr == nil || stmtErr == nil {
+		return false
+	}
+	return endsEarly(declErr, len(declPrefix+input)) || endsEarly(stmtErr, len(stmtPrefix+input))
+}
+
+// endsEarly reports whether the first error of a parse is at or after the
+// end of the input, or in an unterminated raw string
+func endsEarly(err error, end int) bool {
+	list, ok := err.(scanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return false
+	}
+	first := list[0]
+	return first.Pos.Offset >= end || strings.Contains(first.Msg, "raw string literal not terminated")
+}
+
+// inputError strips the positions of the wrapper the statements were
+// parsed in from a parse error
+func inputError(err error) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return err
+	}
+	first := list[0]
+	return fmt.Errorf("%d:%d: %s", first.Pos.Line-2, first.Pos.Column, first.Msg)
+}
+
+// singleExpr returns the expression of an input made of one expression
+func singleExpr(stmts []ast.Stmt) (ast.Expr, bool) {
+	if len(stmts) != 1 {
+		return nil, false
+	}
+	stmt, ok := stmts[0].(*ast.ExprStmt)
+	if !ok {
+		return nil, false
+	}
+	return stmt.X, true
+}
+
+// declaredNames returns the variables the top-level statements declare
+func declaredNames(stmts []ast.Stmt) []string {
+	var names []string
+	add := func(ident *ast.Ident) {
+		if ident.Name != "_" && !contains(names, ident.Name) {
+			names = append(names, ident.Name)
+		}
+	}
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.AssignStmt:
+			if stmt.Tok != token.DEFINE {
+				continue
+			}
+			for _, lhs := range stmt.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					add(ident)
+				}
+			}
+		case *ast.DeclStmt:
+			if gen, ok := stmt.Decl.(*ast.GenDecl); ok && gen.Tok == token.VAR {
+				for _, spec := range gen.Specs {
+					for _, ident := range spec.(*ast.ValueSpec).Names {
+						add(ident)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// mergeDecls returns the declarations with the new ones replacing those
+// of the same name
+func mergeDecls(decls, updates map[string]string) map[string]string {
+	merged := make(map[string]string, len(decls)+len(updates))
+	for name, text := range decls {
+		merged[name] = text
+	}
+	for name, text := range updates {
+		merged[name] = text
+	}
+	return merged
+}
+
+// mergeOrder appends the names not declared yet to the declaration order
+func mergeOrder(order, names []string) []string {
+	merged := append([]string(nil), order...)
+	for _, name := range names {
+		if !contains(merged, name) {
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
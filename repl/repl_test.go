@@ -0,0 +1,91 @@
+package repl
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	r := New()
+	steps := []struct {
+		input string
+		want  []interface{}
+	}{
+		{"x := 20", nil},
+		{"func double(n int) int {\n\treturn n * 2\n}", nil},
+		{"double(x) + 2", []interface{}{42}},
+		{"x = x + 1", nil},
+		{"x", []interface{}{21}},
+		{"import \"strings\"", nil},
+		{"var s = strings.ToUpper(\"ab\")", nil},
+		{"s + \"c\"", []interface{}{"ABc"}},
+		{"type Point struct {\n\tX int\n\tY int\n}\nfunc (p Point) Sum() int {\n\treturn p.X + p.Y\n}", nil},
+		{"p := Point{X: 1, Y: 2}", nil},
+		{"p.Sum()", []interface{}{3}},
+		{"func pair() (int, string) {\n\treturn 1, \"a\"\n}", nil},
+		{"pair()", []interface{}{1, "a"}},
+	}
+	for _, step := range steps {
+		got, err := r.Eval(step.input)
+		if err != nil {
+			t.Fatalf("Eval(%q) failed: %v", step.input, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("Eval(%q) = %v, want %v", step.input, got, step.want)
+		}
+	}
+
+	if got, want := r.Variables(), []string{"x", "s", "p"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected variables %v, got %v", want, got)
+	}
+	if got, want := r.Functions(), []string{"Point.Sum", "double", "pair"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected functions %v, got %v", want, got)
+	}
+
+	// A failed input leaves the session unchanged
+	if _, err := r.Eval("y := undefinedFunction()"); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if _, exists := r.Variable("y"); exists {
+		t.Errorf("Expected y not to be declared")
+	}
+	if _, err := r.Eval("func broken() int {\n\treturn missing\n}"); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if value, _ := r.Variable("x"); value != 21 {
+		t.Errorf("Expected x to be 21, got %v", value)
+	}
+}
+
+func TestRun(t *testing.T) {
+	input := strings.Join([]string{
+		"n := 1",
+		"func inc(v int) int {",
+		"\treturn v + 1",
+		"}",
+		"inc(n +",
+		"\t1)",
+		":vars",
+		":funcs",
+		"println(\"printed\")",
+		"bad(",
+		"",
+		":quit",
+		"n",
+	}, "\n")
+	var out bytes.Buffer
+	if err := New().Run(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	output := out.String()
+	for _, want := range []string{"... ", "3\n", "n = 1\n", "inc\n", "printed\n", "error: "} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the output:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "3\n") != 1 {
+		t.Errorf("Expected input after :quit to be ignored:\n%s", output)
+	}
+}
@@ -1,16 +1,33 @@
 // Package goscript provides the main interface for the GoScript engine
+//
+// The goscript package is the stable public API: Script, Program, Options,
+// Stats and Function follow semantic versioning, along with the types the
+// package re-exports from the engine. The virtual machine and the compiler
+// are private packages under internal/. The instruction package stays
+// public because compile interceptors (Script.AddCompileInterceptor) emit
+// instructions, and the builtin package because hosts enable and configure
+// its opt-in modules (os, http, shared sync state) with it; they and the
+// analysis package may change between minor versions.
 package goscript
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"reflect"
 	"time"
 
 	"github.com/lengzhao/goscript/builtin"
-	"github.com/lengzhao/goscript/compiler"
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
 	"github.com/lengzhao/goscript/parser"
 	"github.com/lengzhao/goscript/types"
-	"github.com/lengzhao/goscript/vm"
 )
 
 // Script represents a GoScript script
@@ -29,6 +46,49 @@ type Script struct {
 
 	// Maximum number of instructions allowed (0 means no limit)
 	maxInstructions int64
+
+	// Compiled program (nil until the script has been compiled)
+	program *Program
+
+	// Host values attached with WithValue, in the order they were added
+	values []hostValue
+
+	// Finalizers run by Close
+	finalizers []vm.Finalizer
+
+	// Parsed source and its file set (nil until parsed)
+	astFile *ast.File
+	fset    *token.FileSet
+
+	// Compile hooks passed to the compiler
+	interceptors []compiler.Interceptor
+
+	// Source files added with AddFile: those of the script's package, and
+	// those of other packages by import path
+	files    []sourceFile
+	packages map[string][]sourceFile
+
+	// Source of imported script modules, and the import paths of the
+	// modules being loaded (to detect cycles)
+	importResolver ImportResolver
+	importing      map[string]bool
+
+	// Import paths of the script modules loaded for the program, and their
+	// compiled programs
+	scriptModules []string
+	modules       []programModule
+
+	// Whether the script is compiled with the strict diagnostics pass
+	strict bool
+
+	// Size limits of the source and the compiled program
+	compileLimits CompileLimits
+}
+
+// hostValue is a key/value pair attached to the host context
+type hostValue struct {
+	key   interface{}
+	value interface{}
 }
 
 // ExecutionStats holds execution statistics
@@ -36,6 +96,12 @@ type ExecutionStats struct {
 	ExecutionTime    time.Duration
 	InstructionCount int
 	ErrorCount       int
+
+	// Boundary crossings (host calls, module calls, imports) by tag
+	BoundaryCrossings map[instruction.Tag]int64
+
+	// Deepest operand stack reached during execution
+	StackHighWater int
 }
 
 // NewScript creates a new script
@@ -48,15 +114,7 @@ func NewScript(source []byte) *Script {
 		maxInstructions: 10000, // Default limit of 10,000 instructions
 	}
 
-	// Register builtin functions with the VM
-	for name, fn := range builtin.BuiltInFunctions {
-		script.vm.RegisterFunction(name, func(f builtin.Function) func(args ...interface{}) (interface{}, error) {
-			return func(args ...interface{}) (interface{}, error) {
-				return f(args...)
-			}
-		}(fn))
-	}
-
+	// Builtin functions are registered by the VM itself
 	return script
 }
 
@@ -66,6 +124,56 @@ func (s *Script) SetMaxInstructions(max int64) {
 	s.vm.SetMaxInstructions(max)
 }
 
+// SetBudgetAlerts registers fractions of the instruction limit (e.g., 0.8)
+// at which handler is called while the script runs, so the host can log,
+// warn or grant extra instructions before the limit stops the script
+func (s *Script) SetBudgetAlerts(thresholds []float64, handler BudgetHandler) {
+	s.vm.SetBudgetAlerts(thresholds, handler)
+}
+
+// SetMaxCallDepth sets the maximum nesting of function calls (0 means no limit)
+func (s *Script) SetMaxCallDepth(depth int) {
+	s.vm.SetMaxCallDepth(depth)
+}
+
+// SetMaxGoroutines sets the maximum number of goroutines started by go
+// statements that may be unfinished at once in one execution (0 means no
+// limit)
+func (s *Script) SetMaxGoroutines(max int) {
+	s.vm.SetMaxGoroutines(max)
+}
+
+// SetTimeout limits the wall-clock time of one execution (0 means no
+// limit); a script that runs longer fails with an error wrapping
+// context.DeadlineExceeded
+func (s *Script) SetTimeout(timeout time.Duration) {
+	s.vm.SetTimeout(timeout)
+}
+
+// SetMaxMemory sets the approximate number of bytes one execution may
+// allocate (0 means no limit); see MemoryUsage
+func (s *Script) SetMaxMemory(bytes int64) {
+	s.vm.SetMaxMemory(bytes)
+}
+
+// MemoryUsage returns the approximate number of bytes allocated by the
+// last execution
+func (s *Script) MemoryUsage() int64 {
+	return s.vm.MemoryUsage()
+}
+
+// SetStackLimits sets the initial capacity and maximum depth of the operand
+// stack (max 0 means unbounded)
+func (s *Script) SetStackLimits(initial, max int) {
+	s.vm.SetStackLimits(initial, max)
+}
+
+// SetCacheSize sets the maximum number of entries the cache module keeps in
+// one execution (0 means no limit)
+func (s *Script) SetCacheSize(size int) {
+	s.vm.SetCacheSize(size)
+}
+
 // AddVariable adds a variable to the script
 func (s *Script) AddVariable(name string, value interface{}) error {
 	return s.vm.GlobalCtx.CreateVariableWithType(name, value, "unknow")
@@ -76,31 +184,121 @@ func (s *Script) GetVariable(name string) (interface{}, bool) {
 	return s.vm.GlobalCtx.GetVariable(name)
 }
 
-// SetVariable sets a variable in the script
+// SetVariable sets a variable in the script, and the Go variable of a
+// variable bound with BindVariable
 func (s *Script) SetVariable(name string, value interface{}) error {
-	return s.vm.GlobalCtx.SetVariable(name, value)
+	return s.vm.SetVariable(name, value)
+}
+
+// BindVariable adds a variable bound to the Go variable ptr points to: each
+// run starts with its current value, and the value the script leaves in the
+// variable is stored back into it when the run ends
+func (s *Script) BindVariable(name string, ptr interface{}) error {
+	return s.vm.BindVariable(name, ptr)
 }
 
 func (s *Script) RegisterModule(moduleName string, executor types.ModuleExecutor) {
 	s.vm.RegisterModule(moduleName, executor)
 }
 
+// RegisterModuleConstants registers constants exported by a module (e.g.,
+// config.Version). Constants are resolved when the script is compiled, so
+// they must be registered before Compile or Run.
+func (s *Script) RegisterModuleConstants(moduleName string, constants map[string]interface{}) {
+	s.vm.RegisterModuleConstants(moduleName, constants)
+}
+
+// RegisterModuleFactory registers a module with per-execution state. The
+// factory is called once per execution on first use; the instance is
+// initialized before its first call and closed when the execution ends.
+func (s *Script) RegisterModuleFactory(moduleName string, factory types.ModuleFactory) {
+	s.vm.RegisterModuleFactory(moduleName, factory)
+}
+
 // AddFunction adds a function to the script
-func (s *Script) AddFunction(name string, execFn vm.ScriptFunction) error {
+func (s *Script) AddFunction(name string, execFn ScriptFunction) error {
 
 	// Also register with the VM directly for immediate use
 	s.vm.RegisterFunction(name, execFn)
 
 	// Debug output
 	if s.debug {
-		fmt.Printf("Script: Added function %s\n", name)
+		fmt.Fprintf(s.vm.Stderr(), "Script: Added function %s\n", name)
 	}
 
 	return nil
 }
 
+// AddGoFunction adds a Go function of any signature, e.g.
+// func(int, string) (bool, error), without writing a ScriptFunction
+// wrapper. Calls check the number of arguments and convert them to the
+// parameter types (see SetConversionMode); a non-nil error as the last
+// result fails the call.
+func (s *Script) AddGoFunction(name string, fn interface{}) error {
+	return s.vm.RegisterGoFunction(name, fn)
+}
+
+// AddContextFunction adds a host function that receives the host context of
+// the current execution, carrying the values attached with WithValue
+func (s *Script) AddContextFunction(name string, fn ContextFunction) error {
+	s.vm.RegisterContextFunction(name, fn)
+	return nil
+}
+
+// WithValue attaches a value to every execution of the script. The value
+// is available to context-aware host functions through ctx.Value(key) and
+// is not visible to the script. It returns the script for chaining.
+func (s *Script) WithValue(key, value interface{}) *Script {
+	s.values = append(s.values, hostValue{key: key, value: value})
+	return s
+}
+
+// hostContext derives the context passed to host functions from ctx
+func (s *Script) hostContext(ctx context.Context) context.Context {
+	for _, v := range s.values {
+		ctx = context.WithValue(ctx, v.key, v.value)
+	}
+	return ctx
+}
+
+// OverrideBuiltin replaces a builtin function with a host implementation.
+// An empty moduleName targets a universe builtin such as "len" or "print";
+// otherwise the function must exist in the named builtin module.
+// Overrides take precedence over the default builtin table.
+func (s *Script) OverrideBuiltin(moduleName, funcName string, fn ScriptFunction) error {
+	if fn == nil {
+		return fmt.Errorf("override for %s.%s must not be nil", moduleName, funcName)
+	}
+
+	name := funcName
+	if moduleName == "" {
+		if _, exists := builtin.BuiltInFunctions[funcName]; !exists {
+			return fmt.Errorf("unknown builtin function: %s", funcName)
+		}
+	} else {
+		if !builtin.HasModuleFunction(moduleName, funcName) {
+			return fmt.Errorf("unknown builtin function: %s.%s", moduleName, funcName)
+		}
+		name = moduleName + "." + funcName
+	}
+
+	s.vm.OverrideFunction(name, fn)
+
+	if s.debug {
+		fmt.Fprintf(s.vm.Stderr(), "Script: Overrode builtin %s\n", name)
+	}
+	return nil
+}
+
+// ActiveOverrides returns the qualified names of all overridden builtins
+func (s *Script) ActiveOverrides() []string {
+	return s.vm.GetOverrides()
+}
+
 // CallFunction calls a function in the script
 func (s *Script) CallFunction(name string, args ...interface{}) (interface{}, error) {
+	s.vm.SetHostContext(s.hostContext(context.Background()))
+
 	// Try to call the function using VM's Execute method
 	result, err := s.vm.Execute(name, args...)
 	if err == nil {
@@ -111,11 +309,22 @@ func (s *Script) CallFunction(name string, args ...interface{}) (interface{}, er
 	return s.callFunctionInContext(name, args...)
 }
 
+// CallFunctionResults calls a function like CallFunction and returns all
+// its results: several for a function with multiple results (e.g., return
+// v, err), one otherwise
+func (s *Script) CallFunctionResults(name string, args ...interface{}) ([]interface{}, error) {
+	result, err := s.CallFunction(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return vm.Results(result), nil
+}
+
 // callFunctionInContext calls a function in the current context
 func (s *Script) callFunctionInContext(name string, args ...interface{}) (interface{}, error) {
 	// Debug output
 	if s.debug {
-		fmt.Printf("Script: Calling function %s with args %v\n", name, args)
+		fmt.Fprintf(s.vm.Stderr(), "Script: Calling function %s with args %v\n", name, args)
 	}
 
 	// Try to call the function from the VM (functions registered via AddFunction)
@@ -123,9 +332,9 @@ func (s *Script) callFunctionInContext(name string, args ...interface{}) (interf
 		result, err := fn(args...)
 		if s.debug {
 			if err != nil {
-				fmt.Printf("Script: Error calling VM function %s: %v\n", name, err)
+				fmt.Fprintf(s.vm.Stderr(), "Script: Error calling VM function %s: %v\n", name, err)
 			} else {
-				fmt.Printf("Script: Called VM function %s, result: %v\n", name, result)
+				fmt.Fprintf(s.vm.Stderr(), "Script: Called VM function %s, result: %v\n", name, result)
 			}
 		}
 		return result, err
@@ -134,67 +343,211 @@ func (s *Script) callFunctionInContext(name string, args ...interface{}) (interf
 	return nil, fmt.Errorf("function %s not found", name)
 }
 
+// Build compiles the script without executing it
 func (s *Script) Build() error {
-	sourceStr := string(s.source)
+	_, err := s.Compile()
+	return err
+}
+
+// AST parses the script and returns its syntax tree. Changes made to the
+// tree before the script is compiled are compiled in, which allows source
+// rewriting such as DSL extensions or auto-instrumentation. The files of a
+// script with several source files (see AddFile) are merged into one tree.
+func (s *Script) AST() (*ast.File, error) {
+	if s.astFile != nil {
+		return s.astFile, nil
+	}
 
-	// Create a parser
+	files := s.files
+	if len(s.source) > 0 || len(files) == 0 {
+		files = append([]sourceFile{{name: "script.go", source: s.source}}, files...)
+	}
+	if limit := s.compileLimits.MaxSourceSize; limit > 0 {
+		size := 0
+		for _, file := range files {
+			size += len(file.source)
+		}
+		if size > limit {
+			return nil, fmt.Errorf("source size %d exceeds the limit of %d bytes", size, limit)
+		}
+	}
+
+	// The syntax errors of every file are reported, the first of each line
+	// as by default but without the default cap of ten errors
 	parser := parser.New()
+	parsed := make([]*ast.File, len(files))
+	var syntaxErrors compiler.CompileDiagnostics
+	for i, file := range files {
+		astFile, err := parser.Parse(file.name, file.source, goparser.ParseComments|goparser.AllErrors)
+		var list scanner.ErrorList
+		switch {
+		case errors.As(err, &list):
+			list.RemoveMultiples()
+			for _, e := range list {
+				syntaxErrors = append(syntaxErrors, compiler.Diagnostic{Pos: e.Pos, Severity: compiler.SeverityError, Message: e.Msg})
+			}
+		case err != nil:
+			return nil, fmt.Errorf("failed to parse source code: %w", err)
+		}
+		parsed[i] = astFile
+	}
+	if len(syntaxErrors) > 0 {
+		return nil, fmt.Errorf("failed to parse source code: %w", syntaxErrors)
+	}
+	astFile, err := mergeFiles(files, parsed)
+	if err != nil {
+		return nil, err
+	}
+	s.astFile, s.fset = astFile, parser.FileSet()
+	return astFile, nil
+}
+
+// AddCompileInterceptor registers a hook called for every statement and
+// expression during compilation. The hook can emit its own instructions
+// and reuse the stock compiler for everything else. It must be added
+// before the script is compiled.
+func (s *Script) AddCompileInterceptor(interceptor CompileInterceptor) {
+	s.interceptors = append(s.interceptors, interceptor)
+}
+
+// Compile parses and compiles the script, returning the compiled program.
+// The script is compiled only once; subsequent calls return the same program.
+func (s *Script) Compile() (*Program, error) {
+	if s.program != nil {
+		return s.program, nil
+	}
 
 	// Parse the source code into an AST
-	astFile, err := parser.Parse("script.go", []byte(sourceStr), 0)
+	astFile, err := s.AST()
 	if err != nil {
-		return fmt.Errorf("failed to parse source code: %w", err)
+		return nil, err
+	}
+
+	// Load the script modules the source imports
+	if err := s.loadScriptModules(astFile); err != nil {
+		return nil, err
 	}
 
 	// Create a compiler instance
 	compiler := compiler.NewCompiler(s.vm)
+	compiler.SetFileSet(s.fset)
+	compiler.SetStrict(s.strict)
+	compiler.SetLimits(s.compileLimits)
+	for _, interceptor := range s.interceptors {
+		compiler.AddInterceptor(interceptor)
+	}
 
 	// Compile the AST to bytecode
 	err = compiler.Compile(astFile)
 	if err != nil {
-		return fmt.Errorf("failed to compile AST: %w", err)
+		return nil, fmt.Errorf("failed to compile AST: %w", err)
 	}
-	return nil
+
+	s.program = &Program{vm: s.vm, image: s.vm.Image(), modules: s.modules, file: astFile, constants: compiler.Constants()}
+	s.program.diagnostics = compiler.Diagnostics().Warnings()
+	for _, warning := range s.program.diagnostics {
+		s.program.warnings = append(s.program.warnings, warning.String())
+	}
+	return s.program, nil
 }
 
-// Run executes the script
-func (s *Script) Run() (interface{}, error) {
-	return s.RunContext(context.Background())
+// NewRunner compiles the script and returns a script running the same
+// program on a clone of its VM (see VM.Clone), with the same host
+// functions, modules, variables, host values and options. A script runs one
+// execution at a time; runners let several goroutines run the program
+// concurrently, each with its own stacks, scopes and counters, without
+// compiling it again. Script modules are given runners of their own.
+func (s *Script) NewRunner() (*Script, error) {
+	program, err := s.Compile()
+	if err != nil {
+		return nil, err
+	}
+	runner := &Script{
+		source:          s.source,
+		vm:              s.vm.Clone(),
+		debug:           s.debug,
+		executionStats:  &ExecutionStats{},
+		maxInstructions: s.maxInstructions,
+		values:          s.values[:len(s.values):len(s.values)],
+		astFile:         s.astFile,
+		fset:            s.fset,
+		interceptors:    s.interceptors[:len(s.interceptors):len(s.interceptors)],
+		importResolver:  s.importResolver,
+	}
+	if err := program.attach(runner); err != nil {
+		return nil, err
+	}
+	return runner, nil
 }
 
-// RunContext executes the script with a context
-func (s *Script) RunContext(ctx context.Context) (interface{}, error) {
-	fmt.Println("RunContext: Starting execution")
-	startTime := time.Now()
+// Constants returns the package-level constants of the script, compiling
+// it if needed. Hosts can use them to read enums and thresholds declared
+// in the script without running it.
+func (s *Script) Constants() ([]Constant, error) {
+	program, err := s.Compile()
+	if err != nil {
+		return nil, err
+	}
+	return program.Constants(), nil
+}
 
-	// Parse and compile the source code
-	sourceStr := string(s.source)
+// SourceMap returns the mapping between the instructions of the script
+// and its source positions, compiling it if needed
+func (s *Script) SourceMap() (*SourceMap, error) {
+	program, err := s.Compile()
+	if err != nil {
+		return nil, err
+	}
+	return program.SourceMap(), nil
+}
 
-	// Create a parser
-	parser := parser.New()
+// Program returns the compiled program, or nil if the script has not been compiled
+func (s *Script) Program() *Program {
+	return s.program
+}
 
-	// Parse the source code into an AST
-	astFile, err := parser.Parse("script.go", []byte(sourceStr), 0)
+// Run executes the script. If main returns several results, the result is
+// a Tuple.
+func (s *Script) Run() (interface{}, error) {
+	return s.RunContext(context.Background())
+}
+
+// RunResults executes the script like Run and returns all the results of
+// main as a slice
+func (s *Script) RunResults() ([]interface{}, error) {
+	result, err := s.Run()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse source code: %w", err)
+		return nil, err
 	}
+	return vm.Results(result), nil
+}
 
-	// Create a compiler instance
-	compiler := compiler.NewCompiler(s.vm)
+// RunContext executes the script with a context. When ctx is canceled or
+// its deadline passes, the script stops with an error wrapping ctx.Err(),
+// so errors.Is(err, context.DeadlineExceeded) reports a timeout.
+func (s *Script) RunContext(ctx context.Context) (interface{}, error) {
+	if s.debug {
+		fmt.Fprintln(s.vm.Stderr(), "RunContext: Starting execution")
+	}
+	startTime := time.Now()
 
-	// Compile the AST to bytecode
-	err = compiler.Compile(astFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to compile AST: %w", err)
+	// Parse and compile the source code
+	if _, err := s.Compile(); err != nil {
+		return nil, err
 	}
 
 	// Set max instructions in VM
 	s.vm.SetMaxInstructions(s.maxInstructions)
+	s.vm.SetHostContext(s.hostContext(ctx))
 
 	// Execute the VM
-	fmt.Println("RunContext: Executing VM")
-	result, err := s.vm.Execute("")
-	fmt.Printf("RunContext: VM execution completed, result: %v, err: %v\n", result, err)
+	if s.debug {
+		fmt.Fprintln(s.vm.Stderr(), "RunContext: Executing VM")
+	}
+	result, err := s.vm.ExecuteContext(ctx, "")
+	if s.debug {
+		fmt.Fprintf(s.vm.Stderr(), "RunContext: VM execution completed, result: %v, err: %v\n", result, err)
+	}
 
 	// Update execution statistics
 	s.executionStats.ExecutionTime = time.Since(startTime)
@@ -204,6 +557,8 @@ func (s *Script) RunContext(ctx context.Context) (interface{}, error) {
 
 	// Get instruction count from VM
 	s.executionStats.InstructionCount = int(s.vm.GetInstructionCount())
+	s.executionStats.BoundaryCrossings = s.vm.GetBoundaryCounts()
+	s.executionStats.StackHighWater = s.vm.GetStackHighWater()
 
 	if err != nil {
 		return nil, err
@@ -218,12 +573,229 @@ func (s *Script) SetDebug(debug bool) {
 	s.vm.SetDebug(debug)
 }
 
+// SetStdout sets the writer the script prints to with print, println and
+// the fmt module, e.g. to capture the output of each request. Defaults to
+// os.Stdout.
+func (s *Script) SetStdout(w io.Writer) {
+	s.vm.SetStdout(w)
+}
+
+// SetStderr sets the writer the debug output (see SetDebug) goes to.
+// Defaults to os.Stderr.
+func (s *Script) SetStderr(w io.Writer) {
+	s.vm.SetStderr(w)
+}
+
+// SetCoercion enables or disables lenient string/number coercion in binary
+// operations (e.g., "5" + 1 == "51", "5" - 1 == 4). Disabled by default.
+func (s *Script) SetCoercion(enabled bool) {
+	s.vm.SetCoercion(enabled)
+}
+
+// SetDivisionMode sets the result of dividing two ints: TruncatingInt (the
+// default) truncates like Go, 7 / 2 == 3, and PromoteToFloat divides as
+// float64s, 7 / 2 == 3.5. Script modules keep their own mode.
+func (s *Script) SetDivisionMode(mode DivisionMode) {
+	s.vm.SetDivisionMode(mode)
+}
+
+// SetStrictConditions requires conditions in if, for and switch statements
+// and the operands of && and || to be bool, as in Go. Constant non-bool
+// conditions are rejected at compile time, other ones at runtime. By
+// default conditions are permissive: nil, 0, 0.0 and "" are false and other
+// values are true. It must be set before the script is compiled.
+func (s *Script) SetStrictConditions(enabled bool) {
+	s.vm.SetStrictConditions(enabled)
+}
+
+// SetStrict enables or disables strict diagnostics: unused local
+// variables, unreachable statements and declarations shadowing an enclosing
+// variable are reported as warnings (see Program.Diagnostics). Assignments
+// to undeclared names are compile errors in both modes. It must be set
+// before the script is compiled.
+func (s *Script) SetStrict(enabled bool) {
+	s.strict = enabled
+}
+
+// SetCompileLimits bounds the size of the source, the nesting depth of its
+// syntax tree, the number of functions and the instructions compiled for
+// each function, to reject huge or malicious scripts before they exhaust
+// memory. The limits apply to the script modules it imports as well. Zero
+// fields mean no limit. It must be set before the script is compiled.
+func (s *Script) SetCompileLimits(limits CompileLimits) {
+	s.compileLimits = limits
+}
+
+// SetConversionMode sets how arguments are converted to the parameter types
+// of Go functions (AddGoFunction) and host object methods (RegisterObject).
+// Defaults to ConvertSafe, which converts numbers that keep their value,
+// the elements of slices and maps, and script structs to Go structs by
+// field name.
+func (s *Script) SetConversionMode(mode ConversionMode) {
+	s.vm.SetConversionMode(mode)
+}
+
+// SetNumberMode sets how numbers from host functions, entry point arguments
+// and the json module are converted (see NumberMode). Defaults to
+// NumberFloat64, which keeps values unchanged.
+func (s *Script) SetNumberMode(mode types.NumberMode) {
+	s.vm.SetNumberMode(mode)
+}
+
+// SetBoundaryApprover sets a callback that approves or rejects each
+// instruction crossing the script/host boundary (host calls, module calls
+// and imports). Returning an error aborts execution.
+func (s *Script) SetBoundaryApprover(approver BoundaryApprover) {
+	s.vm.SetBoundaryApprover(approver)
+}
+
+// AllowType lets scripts read the given fields of host values of struct type
+// t (or pointers to it) without converting them, e.g.:
+//
+//	script.AllowType(reflect.TypeOf(Order{}), goscript.Fields("ID", "Total"))
+//
+// A nil field list allows every exported field. Fields are read-only.
+func (s *Script) AllowType(t reflect.Type, fields []string) error {
+	return s.vm.AllowType(t, fields...)
+}
+
+// RegisterObject exposes a Go struct (or a pointer to one) to scripts as
+// the global variable name. Scripts read its exported fields and call its
+// exported methods, e.g. cfg.GetTimeout(); arguments are converted to the
+// parameter types of the methods. Methods returning an error as their last
+// result fail the call with it. Fields are read-only.
+func (s *Script) RegisterObject(name string, obj interface{}) error {
+	return s.vm.RegisterObject(name, obj)
+}
+
+// AddWatch registers a watch expression (e.g., "total" or "p.name") that is
+// evaluated after every instruction; the watch handler is called whenever
+// its value changes. It returns the watch ID.
+func (s *Script) AddWatch(expr string) (int, error) {
+	return s.addWatch(expr, false)
+}
+
+// AddWatchCondition registers a predicate expression (e.g., "count > 10")
+// that calls the watch handler each time it becomes true
+func (s *Script) AddWatchCondition(expr string) (int, error) {
+	return s.addWatch(expr, true)
+}
+
+func (s *Script) addWatch(expr string, condition bool) (int, error) {
+	instructions, err := compiler.NewCompiler(s.vm).CompileExpression(expr)
+	if err != nil {
+		return 0, err
+	}
+	return s.vm.AddWatch(expr, instructions, condition), nil
+}
+
+// RemoveWatch removes a watch by the ID returned from AddWatch
+func (s *Script) RemoveWatch(id int) {
+	s.vm.RemoveWatch(id)
+}
+
+// SetWatchHandler sets the callback invoked when a watch triggers.
+// Returning an error stops the script with that error.
+func (s *Script) SetWatchHandler(handler WatchHandler) {
+	s.vm.SetWatchHandler(handler)
+}
+
+// SetStepHandler makes the script stop at every step for a debugger: before
+// each instruction, each statement or each new source line. The handler may
+// block while the user inspects the script (e.g., with Snapshot); returning
+// an error stops the script with that error. A nil handler disables stepping.
+func (s *Script) SetStepHandler(mode StepMode, handler StepHandler) {
+	s.vm.SetStepHandler(mode, handler)
+}
+
+// SetBreakpoint pauses the script before the statements starting on a
+// line of the function with the given key (e.g., "main.main"; "" matches
+// every function) and calls the pause handler
+func (s *Script) SetBreakpoint(funcKey string, line int) {
+	s.vm.SetBreakpoint(funcKey, line)
+}
+
+// ClearBreakpoint removes a breakpoint set with SetBreakpoint
+func (s *Script) ClearBreakpoint(funcKey string, line int) {
+	s.vm.ClearBreakpoint(funcKey, line)
+}
+
+// SetPauseHandler sets the callback invoked when the script pauses at a
+// breakpoint. The handler inspects variables through the Debugger and
+// calls Step to pause again at the next statement, or Continue to run to
+// the next breakpoint; it may block while the user decides.
+func (s *Script) SetPauseHandler(handler PauseHandler) {
+	s.vm.SetPauseHandler(handler)
+}
+
+// Snapshot captures a deep copy of the variables visible to the running
+// script: the current frame's locals plus the package globals. Call it from
+// a host function or watch handler, or after Run to inspect the final state
+// of the entry function. Compare two snapshots with DiffSnapshots.
+func (s *Script) Snapshot() Snapshot {
+	return s.vm.Snapshot()
+}
+
+// OnExecutionEnd registers a finalizer that runs when the current execution
+// ends. Host functions and modules call it while the script runs to release
+// resources opened for the script, even when the script fails.
+func (s *Script) OnExecutionEnd(fn Finalizer) {
+	s.vm.OnExecutionEnd(fn)
+}
+
+// OnClose registers a finalizer that runs when the script is closed, for
+// resources that outlive a single execution
+func (s *Script) OnClose(fn Finalizer) {
+	s.finalizers = append(s.finalizers, fn)
+}
+
+// Close runs the finalizers registered with OnClose in reverse order and
+// returns their joined errors. Each finalizer runs at most once.
+func (s *Script) Close() error {
+	finalizers := s.finalizers
+	s.finalizers = nil
+	return vm.RunFinalizers(finalizers)
+}
+
 // GetExecutionStats returns execution statistics
 func (s *Script) GetExecutionStats() *ExecutionStats {
 	return s.executionStats
 }
 
+// SetProfiling enables or disables the profiler for the following
+// executions. It records calls, instructions and wall time per function,
+// executed opcodes and call sites; read them with GetProfile.
+func (s *Script) SetProfiling(enabled bool) {
+	s.vm.SetProfiling(enabled)
+}
+
+// SetCoverage enables or disables coverage counting. While enabled, every
+// execution (Run, CallFunction, handlers) adds to the number of times each
+// statement ran; read the counts with CoverageReport.
+func (s *Script) SetCoverage(enabled bool) {
+	s.vm.SetCoverage(enabled)
+}
+
+// CoverageReport returns the statement coverage collected since coverage
+// was enabled or last reset, or nil when it is disabled. Coverage.Lines
+// returns the hit counts by line, and WriteLCOV and WriteCoverProfile
+// export them.
+func (s *Script) CoverageReport() *Coverage {
+	return s.vm.Coverage()
+}
+
+// ResetCoverage discards the coverage counts collected so far
+func (s *Script) ResetCoverage() {
+	s.vm.ResetCoverage()
+}
+
+// GetProfile returns the profile of the last execution, or nil when
+// profiling was disabled. Profile.WritePprof exports it for go tool pprof.
+func (s *Script) GetProfile() *Profile {
+	return s.vm.Profile()
+}
+
 // GetVM returns the virtual machine
-func (s *Script) GetVM() *vm.VM {
+func (s *Script) GetVM() *VM {
 	return s.vm
 }
@@ -0,0 +1,39 @@
+// Package scripts embeds the GoScript standard library: modules written in
+// GoScript itself that scripts import as "std/<name>", e.g., "std/strings2".
+package scripts
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed std/*.gs
+var std embed.FS
+
+// Std returns the source of a standard library module by import path
+func Std(importPath string) ([]byte, bool) {
+	name, ok := strings.CutPrefix(importPath, "std/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return nil, false
+	}
+	source, err := std.ReadFile(path.Join("std", name+".gs"))
+	if err != nil {
+		return nil, false
+	}
+	return source, true
+}
+
+// StdModules returns the sorted import paths of the standard library modules
+func StdModules() []string {
+	entries, _ := std.ReadDir("std")
+	var modules []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".gs"); ok {
+			modules = append(modules, "std/"+name)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
@@ -0,0 +1,47 @@
+// Package dates provides calendar helpers working on year, month and day
+// numbers.
+package dates
+
+import "fmt"
+
+// IsLeapYear reports whether year is a leap year in the Gregorian calendar
+func IsLeapYear(year int) bool {
+	return (year%4 == 0 && year%100 != 0) || year%400 == 0
+}
+
+// DaysInMonth returns the number of days of a month (1-12)
+func DaysInMonth(year int, month int) int {
+	if month == 2 {
+		if IsLeapYear(year) {
+			return 29
+		}
+		return 28
+	}
+	if month == 4 || month == 6 || month == 9 || month == 11 {
+		return 30
+	}
+	return 31
+}
+
+// DayOfYear returns the day of the year of a date, starting at 1
+func DayOfYear(year int, month int, day int) int {
+	total := day
+	for m := 1; m < month; m++ {
+		total = total + DaysInMonth(year, m)
+	}
+	return total
+}
+
+// IsValidDate reports whether year, month and day form a calendar date
+func IsValidDate(year int, month int, day int) bool {
+	days := 0
+	if month >= 1 && month <= 12 {
+		days = DaysInMonth(year, month)
+	}
+	return day >= 1 && day <= days
+}
+
+// FormatDate formats a date as YYYY-MM-DD
+func FormatDate(year int, month int, day int) string {
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+}
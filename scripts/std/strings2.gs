@@ -0,0 +1,48 @@
+// Package strings2 provides string helpers missing from the strings module.
+package strings2
+
+import "strings"
+
+// PadLeft prepends pad until s is at least width bytes long
+func PadLeft(s string, width int, pad string) string {
+	if len(pad) == 0 {
+		return s
+	}
+	for len(s) < width {
+		s = pad + s
+	}
+	return s
+}
+
+// PadRight appends pad until s is at least width bytes long
+func PadRight(s string, width int, pad string) string {
+	if len(pad) == 0 {
+		return s
+	}
+	for len(s) < width {
+		s = s + pad
+	}
+	return s
+}
+
+// Repeat returns s repeated count times
+func Repeat(s string, count int) string {
+	result := ""
+	for i := 0; i < count; i++ {
+		result = result + s
+	}
+	return result
+}
+
+// IsBlank reports whether s is empty or only white space
+func IsBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+// DefaultIfBlank returns def when s is blank and s otherwise
+func DefaultIfBlank(s string, def string) string {
+	if IsBlank(s) {
+		return def
+	}
+	return s
+}
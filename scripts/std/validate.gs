@@ -0,0 +1,32 @@
+// Package validate provides predicates for validating input values.
+package validate
+
+import "strings"
+
+// NotEmpty reports whether s contains anything besides white space
+func NotEmpty(s string) bool {
+	return len(strings.TrimSpace(s)) > 0
+}
+
+// InRange reports whether min <= n <= max
+func InRange(n int, min int, max int) bool {
+	return n >= min && n <= max
+}
+
+// MaxLength reports whether s is at most max bytes long
+func MaxLength(s string, max int) bool {
+	return len(s) <= max
+}
+
+// IsEmail reports whether s looks like an email address: one "@" with a
+// non-empty local part and a domain containing a dot
+func IsEmail(s string) bool {
+	parts := strings.Split(s, "@")
+	local := ""
+	domain := ""
+	if len(parts) == 2 {
+		local = parts[0]
+		domain = parts[1]
+	}
+	return len(local) > 0 && strings.Contains(domain, ".")
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestGroupByAggregateScript(t *testing.T) {
+	scriptSource := `
+package main
+
+type Sale struct {
+	region string
+	amount int
+}
+
+func main() {
+	sales := []Sale{
+		Sale{region: "east", amount: 10},
+		Sale{region: "west", amount: 5},
+		Sale{region: "east", amount: 7},
+	}
+
+	groups := groupBy(sales, "region")
+	totals := map[string]int{}
+	totals["east"] = sum(groups["east"], "amount")
+	totals["west"] = sum(groups["west"], "amount")
+	totals["count"] = countBy(sales, "region")["east"]
+	totals["max"] = max(sales, "amount")
+	totals["large"] = countBy(sales, func(s Sale) string {
+		if s.amount > 6 {
+			return "large"
+		}
+		return "small"
+	})["large"]
+	return totals
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := map[string]interface{}{"east": 17, "west": 5, "count": 2, "max": 10, "large": 2}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
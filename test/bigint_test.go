@@ -0,0 +1,97 @@
+package test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// bigintSetup imports the bigint module
+var bigintSetup = scriptSetup{prelude: `import "bigint"`}
+
+func TestBigIntArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"beyond int", `x := bigint.Parse("9223372036854775807", 10); return x + 1`, "9223372036854775808"},
+		{"multiply", `x := bigint.New("123456789012345678901234567890"); return x * x`, "15241578753238836750495351562536198787501905199875019052100"},
+		{"mixed with int", `x := bigint.New(10); return 3 - x * 2`, "-17"},
+		{"compound assignment", `h := bigint.New(0); for i := 0; i < 3; i++ { h += 5; h *= 31 }; return h`, "153915"},
+		{"division truncates", `return bigint.New(0 - 7) / 2`, "-3"},
+		{"remainder", `return bigint.New(0 - 7) % 2`, "-1"},
+		{"pow", `return bigint.Pow(2, 100)`, "1267650600228229401496703205376"},
+		{"abs and neg", `return bigint.Abs(bigint.Neg(bigint.New(5)))`, "5"},
+		{"parse hex", `return bigint.Parse("0xff", 0)`, "255"},
+		{"operands unchanged", `x := bigint.New(1); y := x + 1; return x`, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, bigintSetup)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			n, ok := result.(*big.Int)
+			if !ok {
+				t.Fatalf("Expected a *big.Int, got %v (%T)", result, result)
+			}
+			if n.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, n)
+			}
+		})
+	}
+}
+
+func TestBigIntConversions(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"compare", `x := bigint.New("100000000000000000000"); return x > 1`, true},
+		{"equal", `return bigint.New(3) == 3`, true},
+		{"not equal", `return bigint.New(3) != bigint.New(4)`, true},
+		{"to int", `return bigint.ToInt(bigint.New("42"))`, 42},
+		{"is int", `return bigint.IsInt(bigint.Pow(2, 64))`, false},
+		{"text", `return bigint.Text(bigint.New(255), 2)`, "11111111"},
+		{"sign", `return bigint.Sign(bigint.New(0 - 2))`, -1},
+		{"cmp", `return bigint.Cmp(1, bigint.New(2))`, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, bigintSetup)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+func TestBigIntErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"division by zero", `return bigint.New(1) / 0`, "division by zero"},
+		{"overflow", `return bigint.ToInt(bigint.Pow(2, 64))`, "overflows int"},
+		{"invalid string", `return bigint.New("12ab")`, "invalid integer"},
+		{"float operand", `return bigint.New(1) + 1.5`, "unsupported types for addition"},
+		{"fractional", `return bigint.New(1.5)`, "requires an integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, bigintSetup)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestBindVariable(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	retries = retries + 1
+	name = name + "!"
+	ratio = ratio * 2
+	tags = append(tags, "new")
+	return retries
+}
+`))
+	retries := int64(2)
+	name := "svc"
+	ratio := float32(1.5)
+	tags := []string{"a"}
+	for varName, ptr := range map[string]interface{}{"retries": &retries, "name": &name, "ratio": &ratio, "tags": &tags} {
+		if err := script.BindVariable(varName, ptr); err != nil {
+			t.Fatalf("Failed to bind %s: %v", varName, err)
+		}
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 3 || retries != 3 || name != "svc!" || ratio != 3 || strings.Join(tags, ",") != "a,new" {
+		t.Errorf("Unexpected values after run: %v %d %s %v %v", result, retries, name, ratio, tags)
+	}
+
+	// The next run starts with the current host values
+	retries = 10
+	if result, err := script.Run(); err != nil || result != 11 || retries != 11 {
+		t.Errorf("Expected 11, got %v (%v), retries %d", result, err, retries)
+	}
+
+	// Setting a bound variable sets the host variable
+	if err := script.SetVariable("name", "api"); err != nil || name != "api" {
+		t.Errorf("Expected api, got %s (%v)", name, err)
+	}
+
+	if err := script.BindVariable("bad", retries); err == nil {
+		t.Errorf("Expected binding a non-pointer to fail")
+	}
+}
+
+func TestBindVariableTypeMismatch(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	count = "many"
+}
+`))
+	count := 1
+	script.BindVariable("count", &count)
+	if _, err := script.Run(); err == nil || !strings.Contains(err.Error(), "cannot store bound variable count") {
+		t.Errorf("Expected a store error, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected count to keep its value, got %d", count)
+	}
+}
+
+type boundLimits struct {
+	Max int
+}
+
+type boundConfig struct {
+	Limit  int
+	Name   string
+	Tags   []string
+	Inner  boundLimits
+	Parent *boundLimits
+	secret int
+}
+
+func TestBindVariableStruct(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	before := cfg.Limit
+	cfg.Limit = cfg.Limit * 2
+	cfg.Name = cfg.Name + "!"
+	cfg.Inner.Max++
+	cfg.Parent.Max = cfg.Inner.Max + len(cfg.Tags)
+	return before
+}
+`))
+	cfg := boundConfig{Limit: 5, Name: "svc", Tags: []string{"a"}, Inner: boundLimits{Max: 1}, Parent: &boundLimits{}, secret: 7}
+	if err := script.BindVariable("cfg", &cfg); err != nil {
+		t.Fatalf("Failed to bind cfg: %v", err)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 5 || cfg.Limit != 10 || cfg.Name != "svc!" || cfg.Inner.Max != 2 || cfg.Parent.Max != 3 || cfg.secret != 7 {
+		t.Errorf("Unexpected values after run: %v %+v %+v", result, cfg, *cfg.Parent)
+	}
+
+	// The next run starts with the current host value
+	cfg.Limit = 1
+	if result, err := script.Run(); err != nil || result != 1 || cfg.Limit != 2 {
+		t.Errorf("Expected 1, got %v (%v), limit %d", result, err, cfg.Limit)
+	}
+}
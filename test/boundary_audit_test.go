@@ -0,0 +1,128 @@
+package test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/instruction"
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+const boundaryScript = `
+package main
+
+import "strings"
+
+func double(x int) int {
+	return x * 2
+}
+
+func main() {
+	s := strings.ToUpper("abc")
+	n := double(len(s))
+	return hostAdd(n, 1)
+}
+`
+
+func TestBoundaryCounters(t *testing.T) {
+	script := goscript.NewScript([]byte(boundaryScript))
+	script.AddFunction("hostAdd", func(args ...interface{}) (interface{}, error) {
+		return args[0].(int) + args[1].(int), nil
+	})
+
+	var events []string
+	script.SetBoundaryApprover(func(event vm.BoundaryEvent) error {
+		events = append(events, event.Tag.String()+":"+event.Name)
+		return nil
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 7 {
+		t.Errorf("Expected 7, got %v", result)
+	}
+
+	expected := "import:strings,module_call:strings.ToUpper,host_call:len,host_call:hostAdd"
+	if got := strings.Join(events, ","); got != expected {
+		t.Errorf("Expected events %s, got %s", expected, got)
+	}
+
+	stats := script.GetExecutionStats()
+	if stats.BoundaryCrossings[instruction.TagHostCall] != 2 {
+		t.Errorf("Expected 2 host calls, got %d", stats.BoundaryCrossings[instruction.TagHostCall])
+	}
+	if stats.BoundaryCrossings[instruction.TagModuleCall] != 1 {
+		t.Errorf("Expected 1 module call, got %d", stats.BoundaryCrossings[instruction.TagModuleCall])
+	}
+	if stats.BoundaryCrossings[instruction.TagImport] != 1 {
+		t.Errorf("Expected 1 import, got %d", stats.BoundaryCrossings[instruction.TagImport])
+	}
+}
+
+func TestBoundaryApproverDenies(t *testing.T) {
+	script := goscript.NewScript([]byte(boundaryScript))
+	script.AddFunction("hostAdd", func(args ...interface{}) (interface{}, error) {
+		return args[0].(int) + args[1].(int), nil
+	})
+
+	denied := errors.New("not allowed for this tenant")
+	script.SetBoundaryApprover(func(event vm.BoundaryEvent) error {
+		if event.Name == "hostAdd" {
+			return denied
+		}
+		return nil
+	})
+
+	_, err := script.Run()
+	if err == nil {
+		t.Fatal("Expected error from denied boundary crossing")
+	}
+	if !errors.Is(err, denied) {
+		t.Errorf("Expected denial error, got %v", err)
+	}
+}
+
+func TestBoundaryApproverLoadedBytecode(t *testing.T) {
+	data, err := goscript.NewScript([]byte(boundaryScript)).CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize program: %v", err)
+	}
+	// Tags stored with the program are not trusted
+	stripped := regexp.MustCompile(`,"tags":\d+`).ReplaceAll(data, nil)
+	if len(stripped) == len(data) {
+		t.Fatal("Expected the serialized program to contain tags")
+	}
+
+	script := goscript.NewScript(nil)
+	script.AddFunction("hostAdd", func(args ...interface{}) (interface{}, error) {
+		return args[0].(int) + args[1].(int), nil
+	})
+	var events []string
+	script.SetBoundaryApprover(func(event vm.BoundaryEvent) error {
+		events = append(events, event.Tag.String()+":"+event.Name)
+		return nil
+	})
+	if _, err := script.LoadProgram(stripped); err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run loaded program: %v", err)
+	}
+	expected := "import:strings,module_call:strings.ToUpper,host_call:len,host_call:hostAdd"
+	if got := strings.Join(events, ","); got != expected {
+		t.Errorf("Expected events %s, got %s", expected, got)
+	}
+
+	denied := errors.New("denied")
+	script.SetBoundaryApprover(func(event vm.BoundaryEvent) error {
+		return denied
+	})
+	if _, err := script.Run(); !errors.Is(err, denied) {
+		t.Errorf("Expected the loaded program to be denied, got %v", err)
+	}
+}
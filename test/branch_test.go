@@ -0,0 +1,385 @@
+package test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBreakAndContinue(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"break and continue in for", `
+	total := 0
+	for i := 0; i < 10; i++ {
+		if i == 2 {
+			continue
+		}
+		if i == 6 {
+			break
+		}
+		total += i
+	}
+	return total`, 13},
+		{"break infinite loop", `
+	n := 0
+	for {
+		n++
+		if n == 5 {
+			break
+		}
+	}
+	return n`, 5},
+		{"condition-only loop", `
+	n := 0
+	for n < 10 {
+		n++
+		if n == 2 {
+			continue
+		}
+		if n == 6 {
+			break
+		}
+	}
+	return n`, 6},
+		{"loop without condition", `
+	total := 0
+	for i := 0; ; i++ {
+		if i == 4 {
+			break
+		}
+		total += i
+	}
+	return total`, 6},
+		{"break in switch inside infinite loop", `
+	n := 0
+	for {
+		n++
+		switch n {
+		case 2:
+			break
+		case 4:
+			return n
+		}
+	}`, 4},
+		{"continue in range", `
+	total := 0
+	for _, v := range []int{1, 2, 3, 4, 5} {
+		if v%2 == 0 {
+			continue
+		}
+		total += v
+	}
+	return total`, 9},
+		{"break in nested loop leaves inner only", `
+	count := 0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if j == 1 {
+				break
+			}
+			count++
+		}
+	}
+	return count`, 3},
+		{"break in switch inside loop", `
+	n := 0
+	for i := 0; i < 4; i++ {
+		switch i {
+		case 1:
+			break
+		default:
+			n += 10
+		}
+		n++
+	}
+	return n`, 34},
+		{"labeled break and continue", `
+	total := 0
+outer:
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if j == 1 {
+				continue outer
+			}
+			if i == 2 {
+				break outer
+			}
+			total += 10
+		}
+	}
+	return total`, 20},
+		{"labeled break out of infinite loops", `
+	n := 0
+outer:
+	for {
+		for {
+			n++
+			break outer
+		}
+	}
+	return n`, 1},
+		{"labeled branches across range loops", `
+	total := 0
+outer:
+	for _, row := range [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}} {
+		for _, v := range row {
+			if v == 5 {
+				continue outer
+			}
+			if v == 8 {
+				break outer
+			}
+			total += v
+		}
+	}
+	return total`, 17},
+		{"labeled continue from switch in nested loops", `
+	n := 0
+outer:
+	for i := 0; i < 3; i++ {
+	inner:
+		for j := 0; j < 3; j++ {
+			x := j
+			switch x {
+			case 1:
+				continue inner
+			case 2:
+				continue outer
+			}
+			n++
+		}
+	}
+	return n`, 3},
+		{"labeled break out of switch", `
+	n := 0
+loop:
+	for {
+		switch n {
+		case 3:
+			break loop
+		}
+		n++
+	}
+	return n`, 3},
+		{"break in select", `
+	ch := make(chan int, 1)
+	ch <- 7
+	got := 0
+	select {
+	case v := <-ch:
+		if v > 0 {
+			break
+		}
+		got = v
+	}
+	return got`, 0},
+		{"variables declared in the loop are released", `
+	total := 0
+	for i := 0; i < 3; i++ {
+		x := i * 2
+		if x > 0 {
+			y := x
+			total += y
+			continue
+		}
+	}
+	return total`, 6},
+		{"closure in loop body", `
+	total := 0
+	for i := 0; i < 3; i++ {
+		add := func(v int) int {
+			for {
+				break
+			}
+			return v + 1
+		}
+		if i == 1 {
+			continue
+		}
+		total += add(i)
+	}
+	return total`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFallthrough(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"fallthrough skips the next condition", `
+	result := 0
+	switch 1 {
+	case 1:
+		result += 1
+		fallthrough
+	case 2:
+		result += 10
+	case 3:
+		result += 100
+	}
+	return result`, 11},
+		{"chained fallthrough into default", `
+	result := 0
+	switch x := 2; x {
+	case 1:
+		result += 1
+	case 2:
+		result += 10
+		fallthrough
+	case 3:
+		result += 100
+		fallthrough
+	default:
+		result += 1000
+	}
+	return result`, 1110},
+		{"fallthrough from default", `
+	result := 0
+	switch 5 {
+	default:
+		result += 1
+		fallthrough
+	case 1:
+		result += 10
+	}
+	return result`, 11},
+		{"fallthrough in tagless switch", `
+	result := 0
+	x := 7
+	switch {
+	case x > 5:
+		result += 1
+		fallthrough
+	case x > 100:
+		result += 10
+	}
+	return result`, 11},
+		{"fallthrough in loop", `
+	total := 0
+	for i := 0; i < 3; i++ {
+		switch i {
+		case 0:
+			total += 1
+			fallthrough
+		case 1:
+			total += 10
+		default:
+			total += 100
+		}
+	}
+	return total`, 121},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBranchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"break outside loop", `
+	break`, "break is not in a loop, switch, or select"},
+		{"continue outside loop", `
+	continue`, "continue is not in a loop"},
+		{"continue in switch", `
+	switch 1 {
+	case 1:
+		continue
+	}`, "continue is not in a loop"},
+		{"unknown break label", `
+	for {
+		break missing
+	}`, "invalid break label missing"},
+		{"continue label on switch", `
+sw:
+	switch 1 {
+	case 1:
+		for {
+			continue sw
+		}
+	}`, "invalid continue label sw"},
+		{"break label of enclosing function", `
+outer:
+	for i := 0; i < 3; i++ {
+		f := func() {
+			for {
+				break outer
+			}
+		}
+		f()
+	}`, "invalid break label outer"},
+		{"break label of sibling loop", `
+first:
+	for i := 0; i < 3; i++ {
+	}
+	for {
+		break first
+	}`, "invalid break label first"},
+		{"fallthrough in final case", `
+	switch 1 {
+	case 1:
+		fallthrough
+	}`, "cannot fallthrough final case in switch"},
+		{"fallthrough not last in case", `
+	switch 1 {
+	case 1:
+		fallthrough
+		x := 1
+		_ = x
+	case 2:
+	}`, "fallthrough statement out of place"},
+		{"fallthrough outside switch", `
+	for i := 0; i < 3; i++ {
+		fallthrough
+	}`, "fallthrough statement out of place"},
+		{"fallthrough in type switch", `
+	var v interface{} = 1
+	switch v.(type) {
+	case int:
+		fallthrough
+	case string:
+	}`, "fallthrough statement out of place"},
+		{"break out of function literal", `
+	for {
+		f := func() {
+			break
+		}
+		f()
+	}`, "break is not in a loop, switch, or select"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, scriptSetup{})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
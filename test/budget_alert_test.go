@@ -0,0 +1,113 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+const budgetSource = `
+package main
+
+func main() {
+	total := 0
+	for i := 0; i < 100; i++ {
+		total = total + i
+	}
+	return total
+}
+`
+
+func TestBudgetAlertsFireBeforeLimit(t *testing.T) {
+	script := goscript.NewScript([]byte(budgetSource))
+	script.SetMaxInstructions(200)
+
+	var events []vm.BudgetEvent
+	script.SetBudgetAlerts([]float64{0.8, 0.5}, func(event vm.BudgetEvent) (int64, error) {
+		events = append(events, event)
+		return 0, nil
+	})
+
+	_, err := script.Run()
+	if err == nil || !strings.Contains(err.Error(), "maximum instruction limit exceeded") {
+		t.Fatalf("Expected the instruction limit to stop the script, got %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 alerts, got %v", events)
+	}
+	if events[0].Threshold != 0.5 || events[0].Used != 100 || events[0].Limit != 200 {
+		t.Errorf("Unexpected first alert: %+v", events[0])
+	}
+	if events[1].Threshold != 0.8 || events[1].Used != 160 {
+		t.Errorf("Unexpected second alert: %+v", events[1])
+	}
+}
+
+func TestBudgetAlertExtendsLimit(t *testing.T) {
+	script := goscript.NewScript([]byte(budgetSource))
+	script.SetMaxInstructions(200)
+
+	extensions := 0
+	script.SetBudgetAlerts([]float64{0.9}, func(event vm.BudgetEvent) (int64, error) {
+		extensions++
+		return event.Limit, nil
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Expected the extended budget to be enough, got %v", err)
+	}
+	if result != 4950 {
+		t.Errorf("Expected 4950, got %v", result)
+	}
+	if extensions == 0 {
+		t.Error("Expected the budget to be extended")
+	}
+
+	// The extension applies to one execution only
+	count := extensions
+	script.SetBudgetAlerts([]float64{0.9}, func(event vm.BudgetEvent) (int64, error) {
+		return 0, nil
+	})
+	if _, err := script.Run(); err == nil {
+		t.Errorf("Expected the next run to use the configured limit again (after %d extensions)", count)
+	}
+}
+
+func TestBudgetExtensionPerExecution(t *testing.T) {
+	script := goscript.NewScript([]byte(budgetSource))
+	script.SetMaxInstructions(200)
+
+	var limits []int64
+	script.SetBudgetAlerts([]float64{0.9}, func(event vm.BudgetEvent) (int64, error) {
+		limits = append(limits, event.Limit)
+		return 200, nil
+	})
+
+	for run := 0; run < 3; run++ {
+		limits = nil
+		if _, err := script.Run(); err != nil {
+			t.Fatalf("Run %d: expected the extended budget to be enough, got %v", run, err)
+		}
+		if len(limits) == 0 || limits[0] != 200 {
+			t.Errorf("Run %d: expected the first alert at the configured limit of 200, got %v", run, limits)
+		}
+	}
+}
+
+func TestBudgetAlertAbort(t *testing.T) {
+	script := goscript.NewScript([]byte(budgetSource))
+	script.SetMaxInstructions(1000)
+
+	stop := errors.New("tenant over quota")
+	script.SetBudgetAlerts([]float64{0.25}, func(event vm.BudgetEvent) (int64, error) {
+		return 0, stop
+	})
+
+	if _, err := script.Run(); !errors.Is(err, stop) {
+		t.Errorf("Expected the handler error, got %v", err)
+	}
+}
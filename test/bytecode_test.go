@@ -0,0 +1,255 @@
+package test
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	source := []byte(`
+package main
+
+import "geometry"
+
+const (
+	Low = iota
+	High
+)
+
+const Greeting = "hi"
+
+type Counter struct {
+	n int
+}
+
+func (c *Counter) Add(delta int) {
+	c.n = c.n + delta
+}
+
+func square(x int) int {
+	return x * x
+}
+
+func main() {
+	c := &Counter{n: 0}
+	c.Add(square(3))
+	add := func(v int) int {
+		return v + High
+	}
+	ch := make(chan int, 1)
+	ch <- add(c.n)
+	m := map[rune]int{'a': 1}
+	total := <-ch + m['a'] + geometry.Double(2)
+	if total > 1 {
+		return Greeting + "!"
+	}
+	return total
+}
+`)
+	resolver := func(importPath string) ([]byte, bool, error) {
+		if importPath != "geometry" {
+			return nil, false, nil
+		}
+		return []byte(`
+package geometry
+
+func Double(x int) int {
+	return x * 2
+}
+`), true, nil
+	}
+
+	compiled := goscript.NewScript(source)
+	compiled.SetImportResolver(resolver)
+	data, err := compiled.CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize program: %v", err)
+	}
+	expected, err := compiled.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+
+	loaded := goscript.NewScript(nil)
+	loaded.SetImportResolver(resolver)
+	program, err := loaded.LoadProgram(data)
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	result, err := loaded.Run()
+	if err != nil {
+		t.Fatalf("Failed to run loaded program: %v", err)
+	}
+	if result != expected || result != "hi!" {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	constants := program.Constants()
+	if len(constants) != 3 || constants[1].Name != "High" || constants[1].Value != 1 || constants[2].Value != "hi" {
+		t.Errorf("Unexpected constants: %v", constants)
+	}
+	if !program.HasFunction("square") {
+		t.Errorf("Expected the loaded program to define square")
+	}
+
+	// The format is stable: serializing the loaded program gives the same
+	// instruction sets
+	again, err := loaded.CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize loaded program: %v", err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("Expected the loaded program to serialize identically")
+	}
+}
+
+func TestBytecodeErrors(t *testing.T) {
+	data, err := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	return 1
+}
+`)).CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize program: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"not json", "garbage", "invalid bytecode"},
+		{"other format", `{"format":"other","version":1}`, "not a GoScript program"},
+		{"other version", regexp.MustCompile(`"version":\d+`).ReplaceAllString(string(data), `"version":99`), "unsupported bytecode version 99"},
+		{"unknown opcode", strings.Replace(string(data), `"op":"OpReturn"`, `"op":"OpExplode"`, 1), "unknown opcode"},
+		{"ill-typed operand", strings.Replace(string(data), `"op":"OpReturn"`, `"op":"OpJump"`, 1), "requires an int target"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := goscript.NewScript(nil).LoadProgram([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	script := goscript.NewScript([]byte("package main\n\nfunc main() {\n\treturn 1\n}\n"))
+	if _, err := script.Compile(); err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	if _, err := script.LoadProgram(data); err == nil {
+		t.Errorf("Expected an error loading into a compiled script")
+	}
+}
+
+func TestBytecodeLocalSlots(t *testing.T) {
+	data, err := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	x := 1
+	return x
+}
+`)).CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize program: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"huge slot", strings.Replace(string(data), `{"op":"OpStoreLocal","arg":{"t":"int","v":0}`, `{"op":"OpStoreLocal","arg":{"t":"int","v":8000000000}`, 1)},
+		{"slot beyond the frame", strings.Replace(string(data), `{"op":"OpLoadLocal","arg":{"t":"int","v":0}`, `{"op":"OpLoadLocal","arg":{"t":"int","v":1}`, 1)},
+		{"declared slot beyond the frame", strings.Replace(string(data), `"arg2":{"t":"int","v":0}`, `"arg2":{"t":"int","v":5}`, 1)},
+		{"frame size removed", strings.Replace(string(data), `,"locals":1`, ``, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.data == string(data) {
+				t.Fatalf("Failed to tamper with the bytecode")
+			}
+			_, err := goscript.NewScript(nil).LoadProgram([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), "out of range") {
+				t.Errorf("Expected a slot out of range error, got %v", err)
+			}
+		})
+	}
+
+	script := goscript.NewScript(nil)
+	if _, err := script.LoadProgram(data); err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if result, err := script.Run(); err != nil || result != 1 {
+		t.Errorf("Expected 1, got %v (%v)", result, err)
+	}
+}
+
+func TestBytecodeConstantPool(t *testing.T) {
+	data, err := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	a := "pooled"
+	b := "pooled"
+	c := "pooled"
+	return len(a + b + c)
+}
+`)).CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize program: %v", err)
+	}
+	if count := strings.Count(string(data), `"pooled"`); count != 1 {
+		t.Errorf("Expected the constant to be stored once, got %d copies", count)
+	}
+
+	tampered := strings.Replace(string(data), `"const":0`, `"const":7`, 1)
+	if tampered == string(data) {
+		t.Fatalf("Failed to tamper with the bytecode")
+	}
+	if _, err := goscript.NewScript(nil).LoadProgram([]byte(tampered)); err == nil || !strings.Contains(err.Error(), "constant 7 out of range") {
+		t.Errorf("Expected a constant out of range error, got %v", err)
+	}
+
+	script := goscript.NewScript(nil)
+	if _, err := script.LoadProgram(data); err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if result, err := script.Run(); err != nil || result != 18 {
+		t.Errorf("Expected 18, got %v (%v)", result, err)
+	}
+}
+
+func TestDumpBytecode(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	n := 0
+	for i := 0; i < 3; i++ {
+		n = n + i
+	}
+	return n
+}
+`))
+	var out strings.Builder
+	if err := script.DumpBytecode(&out); err != nil {
+		t.Fatalf("Failed to dump bytecode: %v", err)
+	}
+	listing := out.String()
+	for _, want := range []string{"main.main:\n", "BINARY_OP <", "BINARY_OP +", "; -> ", "\n>", " 7:3 "} {
+		if !strings.Contains(listing, want) {
+			t.Errorf("Expected %q in the listing:\n%s", want, listing)
+		}
+	}
+
+	if got := script.GetVM().Disassemble("main.none"); got != "" {
+		t.Errorf("Expected no listing for an unknown key, got %q", got)
+	}
+}
@@ -0,0 +1,172 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+// bytesSetup imports the bytes module
+var bytesSetup = scriptSetup{prelude: `import "bytes"`}
+
+func TestByteSlices(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"conversion", `return []byte("hi")`, []byte("hi")},
+		{"back to string", `b := []byte("hi"); return string(b)`, "hi"},
+		{"len", `return len([]byte("héllo"))`, 6},
+		{"index", `b := []byte("hi"); return b[1]`, int('i')},
+		{"string index", `s := "hi"; return s[0]`, int('h')},
+		{"set index", `b := []byte("hi"); b[0] = 'H'; return string(b)`, "Hi"},
+		{"range", `sum := 0; for _, c := range []byte{1, 2, 3} { sum = sum + c }; return sum`, 6},
+		{"literal", `return []byte{0, 127, 255}`, []byte{0, 127, 255}},
+		{"make", `b := make([]byte, 2, 8); return cap(b)`, 8},
+		{"append", `b := []byte("a"); b = append(b, 'b', 99); return string(b)`, "abc"},
+		{"append spread", `b := append([]byte("a"), []byte("bc")...); return string(b)`, "abc"},
+		{"copy from string", `b := make([]byte, 3); n := copy(b, "abcdef"); return string(b) + string(rune('0' + n))`, "abc3"},
+		{"conversion copies", `s := []byte("a"); b := []byte(s); b[0] = 'b'; return string(s)`, "a"},
+		{"type assertion", `var v interface{} = []byte("a"); _, ok := v.([]byte); return ok`, true},
+		{"runes", `r := []rune("héllo"); return len(r)`, 5},
+		{"rune element", `r := []rune("héllo"); return r[1]`, int32('é')},
+		{"runes to string", `r := []rune("héllo"); return string(r)`, "héllo"},
+		{"rune conversion", `return rune(65)`, int32('A')},
+		{"string of rune", `return string(rune(19990))`, "世"},
+		{"byte conversion", `return byte(300)`, 44},
+		{"string escapes", `return "x\ny\t\"q\" \u4e16"`, "x\ny\t\"q\" 世"},
+		{"binary escapes", `return []byte("\x00\x01\xff")`, []byte{0, 1, 255}},
+		{"escape length", `return len("\x00\n")`, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, bytesSetup)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if b, ok := tt.expected.([]byte); ok {
+				got, isBytes := result.([]byte)
+				if !isBytes || !bytes.Equal(got, b) {
+					t.Errorf("Expected %v ([]byte), got %v (%T)", b, result, result)
+				}
+				return
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+func TestByteSliceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"overflow", `b := []byte("a"); b[0] = 256; return b`, "overflows"},
+		{"negative", `b := []byte("a"); b = append(b, 0 - 1); return b`, "overflows"},
+		{"out of range", `b := []byte("a"); return b[1]`, "index out of range: 1"},
+		{"literal element", `return []byte{1, "x"}`, "element 1"},
+		{"immutable string", `s := "a"; s[0] = 'b'; return s`, "immutable"},
+		{"unsupported conversion", `return []int("a")`, "unsupported conversion to []int"},
+		{"module argument", `return bytes.Equal("a", []byte("a"))`, "requires []byte arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, bytesSetup)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestBytesModule(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"contains", `return bytes.Contains([]byte("payload"), []byte("load"))`, true},
+		{"equal", `return bytes.Equal([]byte("ab"), []byte{97, 98})`, true},
+		{"compare", `return bytes.Compare([]byte("a"), []byte("b"))`, -1},
+		{"index", `return bytes.Index([]byte("a=b"), []byte("="))`, 1},
+		{"prefix", `return bytes.HasPrefix([]byte("GIF89a"), []byte("GIF"))`, true},
+		{"split", `parts := bytes.Split([]byte("a,b,c"), []byte(",")); return string(parts[1]) + string(rune('0' + len(parts)))`, "b3"},
+		{"join", `parts := []interface{}{[]byte("a"), []byte("b")}; return string(bytes.Join(parts, []byte("-")))`, "a-b"},
+		{"trim", `return string(bytes.ToUpper(bytes.TrimSpace([]byte(" ok "))))`, "OK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, bytesSetup)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+func TestBytesBuffer(t *testing.T) {
+	result, err := runScript(t, `
+	buf := bytes.NewBufferString("GET ")
+	buf.WriteString("/index")
+	buf.WriteByte(32)
+	buf.Write([]byte("HTTP"))
+	buf.WriteRune('✓')
+	if buf.Len() != 18 {
+		return buf.Len()
+	}
+	data := buf.Bytes()
+	data[0] = 'X'
+	buf.Truncate(15)
+	return buf.String()`, bytesSetup)
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "GET /index HTTP" {
+		t.Errorf("Expected %q, got %v", "GET /index HTTP", result)
+	}
+
+	_, err = runScript(t, `buf := bytes.NewBuffer(nil); buf.WriteByte(256); return buf`, bytesSetup)
+	if err == nil || !strings.Contains(err.Error(), "WriteByte") {
+		t.Errorf("Expected an error writing 256 as a byte, got %v", err)
+	}
+}
+
+func TestByteSliceHostBoundary(t *testing.T) {
+	source := []byte(`package main
+
+func checksum(data []byte) int {
+	sum := 0
+	for _, c := range data {
+		sum = (sum + c) % 256
+	}
+	return sum
+}
+
+func main() {
+	return 0
+}
+`)
+	script := goscript.NewScript(source)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	result, err := script.CallFunction("checksum", []byte{200, 100, 1})
+	if err != nil {
+		t.Fatalf("Failed to call checksum: %v", err)
+	}
+	if result != 45 {
+		t.Errorf("Expected 45, got %v (%T)", result, result)
+	}
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestCacheModule(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "cache"
+
+func square(n int) int {
+	return fetch(n) * n
+}
+
+func main() {
+	total := 0
+	for i := 0; i < 5; i++ {
+		total = total + cache.GetOrCompute("rate", "fetch", 0, 10)
+		total = total + cache.GetOrCompute(i%2, "square", 0, i%2+2)
+	}
+	return total
+}
+`))
+	calls := 0
+	script.AddFunction("fetch", func(args ...interface{}) (interface{}, error) {
+		calls++
+		return args[0], nil
+	})
+
+	// Each execution starts with an empty cache
+	for run := 1; run <= 2; run++ {
+		result, err := script.Run()
+		if err != nil {
+			t.Fatalf("Failed to run script: %v", err)
+		}
+		// 5*10 + 3*square(2) + 2*square(3)
+		if result != 50+3*4+2*9 {
+			t.Errorf("Expected %d, got %v", 50+3*4+2*9, result)
+		}
+		if calls != 3*run {
+			t.Errorf("Run %d: expected %d calls, got %d", run, 3*run, calls)
+		}
+	}
+}
+
+func TestCacheEntries(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"set and get", `
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	a, _ := cache.Get("a")
+	b, _ := cache.Get("b")
+	return a + b`, 3},
+		{"missing", `
+	_, found := cache.Get("a")
+	return found`, false},
+		{"delete", `
+	cache.Set("a", 1)
+	cache.Delete("a")
+	return cache.Len()`, 0},
+		{"least recently used is evicted", `
+	cache.Set(1, "one")
+	cache.Set(2, "two")
+	cache.Get(1)
+	cache.Set(3, "three")
+	_, found := cache.Get(2)
+	one, _ := cache.Get(1)
+	return found == false && one == "one" && cache.Len() == 2`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte("package main\n\nimport \"cache\"\n\nfunc main() {\n" + tt.body + "\n}\n"))
+			script.SetCacheSize(2)
+			result, err := script.Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "cache"
+
+func main() {
+	first := cache.GetOrCompute("now", "tick", 20)
+	second := cache.GetOrCompute("now", "tick", 20)
+	wait()
+	third := cache.GetOrCompute("now", "tick", 20)
+	return first*100 + second*10 + third
+}
+`))
+	ticks := 0
+	script.AddFunction("tick", func(args ...interface{}) (interface{}, error) {
+		ticks++
+		return ticks, nil
+	})
+	script.AddFunction("wait", func(args ...interface{}) (interface{}, error) {
+		time.Sleep(30 * time.Millisecond)
+		return nil, nil
+	})
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 112 {
+		t.Errorf("Expected 112, got %v", result)
+	}
+
+	script = goscript.NewScript([]byte(`
+package main
+
+import "cache"
+
+func main() {
+	return cache.GetOrCompute([]int{1}, "tick", 0)
+}
+`))
+	if _, err := script.Run(); err == nil || !strings.Contains(err.Error(), "invalid map key type") {
+		t.Errorf("Expected a key error, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const notifySource = `
+package main
+
+import "payments"
+
+func main() {
+	sent := 0
+	if hasFunction("sendSMS") {
+		sent = sent + sendSMS("hello")
+	}
+	if hasModule("payments") {
+		sent = sent + payments.Charge(10)
+	}
+	return sent
+}
+`
+
+func TestCapabilityChecks(t *testing.T) {
+	// Without the integrations the guarded calls are skipped
+	script := goscript.NewScript([]byte(notifySource))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 0 {
+		t.Errorf("Expected 0, got %v", result)
+	}
+
+	script = goscript.NewScript([]byte(notifySource))
+	script.AddFunction("sendSMS", func(args ...interface{}) (interface{}, error) {
+		return 1, nil
+	})
+	script.RegisterModule("payments", func(entrypoint string, args ...interface{}) (interface{}, error) {
+		return 10, nil
+	})
+	result, err = script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 11 {
+		t.Errorf("Expected 11, got %v", result)
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`hasFunction("len")`, true},
+		{`hasFunction("helper")`, true},
+		{`hasFunction("missing")`, false},
+		{`hasFunction("strings.ToUpper")`, true},
+		{`hasFunction("strings.Missing")`, false},
+		{`hasFunction("missing.Func")`, false},
+		{`hasModule("strings")`, true},
+		{`hasModule("missing")`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			script := goscript.NewScript([]byte(`
+package main
+
+func helper() int {
+	return 1
+}
+
+func main() {
+	return ` + tt.expr + `
+}
+`))
+			result, err := script.Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	script = goscript.NewScript([]byte(`
+package main
+
+func main() {
+	return hasModule(1)
+}
+`))
+	if _, err := script.Run(); err == nil || !strings.Contains(err.Error(), "must be a string") {
+		t.Errorf("Expected a name error, got %v", err)
+	}
+}
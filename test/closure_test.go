@@ -0,0 +1,239 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestFunctionLiterals(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func apply(f func(int) int, x int) int {
+	return f(x)
+}
+
+func double(n int) int {
+	return n * 2
+}
+
+func makeCounter() func() int {
+	count := 0
+	return func() int {
+		count++
+		return count
+	}
+}
+
+func makeAdder(n int) func(int) int {
+	return func(x int) int { return x + n }
+}
+
+func main() {
+	next := makeCounter()
+	next()
+	next()
+	other := makeCounter()
+	return next()*1000 + other()*100 + apply(double, 3)*10 + makeAdder(2)(1)
+}
+`))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	// Each counter keeps its own captured variable
+	if result != 3000+100+60+3 {
+		t.Errorf("Expected %d, got %v", 3000+100+60+3, result)
+	}
+}
+
+func TestFunctionValues(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "strings"
+
+func mapStrings(items []string, f func(string) string) []string {
+	result := []string{}
+	for _, item := range items {
+		result = append(result, f(item))
+	}
+	return result
+}
+
+func sortInts(items []int, less func(int, int) bool) []int {
+	for i := 1; i < len(items); i++ {
+		for j := i; j > 0; j-- {
+			if less(items[j], items[j-1]) == false {
+				break
+			}
+			tmp := items[j]
+			items[j] = items[j-1]
+			items[j-1] = tmp
+		}
+	}
+	return items
+}
+
+func descending(a int, b int) bool {
+	return a > b
+}
+
+func main() {
+	upper := strings.ToUpper
+	compare := descending
+	hello := greet
+	return []interface{}{upper("x"), mapStrings([]string{"A", "B"}, strings.ToLower), hello("c"), sortInts([]int{2, 3, 1}, compare)}
+}
+`))
+	script.AddFunction("greet", func(args ...interface{}) (interface{}, error) {
+		return "hi " + args[0].(string), nil
+	})
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	// Module, host and script functions are all values
+	if fmt.Sprint(result) != "[X [a b] hi c [3 2 1]]" {
+		t.Errorf("Expected [X [a b] hi c [3 2 1]], got %v", result)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"immediate call", `
+	return func(a int, b int) int { return a * b }(6, 7)`, 42},
+		{"captured variables are shared", `
+	total := 0
+	add := func(v int) {
+		total += v
+	}
+	add(5)
+	add(6)
+	return total`, 11},
+		{"recursive literal", `
+	var fib func(int) int
+	fib = func(n int) int {
+		if n < 2 {
+			return n
+		}
+		return fib(n-1) + fib(n-2)
+	}
+	return fib(10)`, 55},
+		{"nested literals", `
+	x := 1
+	outer := func() int {
+		inner := func() int {
+			return x * 10
+		}
+		return inner() + x
+	}
+	x = 2
+	return outer()`, 22},
+		{"slice of functions", `
+	ops := []func(int) int{
+		func(v int) int { return v + 1 },
+		func(v int) int { return v * 3 },
+	}
+	return ops[1](ops[0](4))`, 15},
+		{"function value in range loop", `
+	sum := 0
+	for _, f := range []func() int{func() int { return 2 }, func() int { return 5 }} {
+		sum += f()
+	}
+	return sum`, 7},
+		{"cached callback", `
+	calls := 0
+	load := func(id int) int {
+		calls++
+		return id * 100
+	}
+	a := cache.GetOrCompute(1, load, 0, 1)
+	b := cache.GetOrCompute(1, load, 0, 1)
+	return a + b + calls`, 201},
+		{"for loop variable per iteration", `
+	var fs []func() int
+	for i := 0; i < 3; i++ {
+		fs = append(fs, func() int { return i })
+	}
+	return fs[0]()*100 + fs[1]()*10 + fs[2]()`, 12},
+		{"loop variable changed in body", `
+	var fs []func() int
+	for i := 0; i < 6; i++ {
+		if i == 1 {
+			i++
+			continue
+		}
+		fs = append(fs, func() int { return i })
+	}
+	return fs[0]()*100 + fs[1]()*10 + len(fs)`, 34},
+		{"pointers to loop variable", `
+	var ps []*int
+	for i := 0; i < 3; i++ {
+		ps = append(ps, &i)
+	}
+	return *ps[0]*100 + *ps[1]*10 + *ps[2]`, 12},
+		{"range variables per iteration", `
+	var fs []func() int
+	for k, v := range []int{10, 20, 30} {
+		fs = append(fs, func() int { return k + v })
+	}
+	return fs[0]() + fs[2]()`, 42},
+		{"continue outer loop", `
+	var fs []func() int
+outer:
+	for j := 0; j < 3; j++ {
+		for k := 0; k < 3; k++ {
+			fs = append(fs, func() int { return j*10 + k })
+			if k == 1 {
+				continue outer
+			}
+		}
+	}
+	return fs[1]()*100 + fs[5]()`, 121},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte("package main\n\nimport \"cache\"\n\nfunc main() {\n" + tt.body + "\n}\n"))
+			result, err := script.Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestClosureErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"non-function", `
+	f := 1
+	return f(2)`, "cannot call non-function"},
+		{"too many arguments", `
+	f := func(a int) int { return a }
+	return f(1, 2)`, "too many arguments"},
+		{"not enough arguments", `
+	f := func(a int, b int) int { return a + b }
+	return f(1)`, "not enough arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := runScript(t, tt.body, scriptSetup{}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package test
+
+import (
+	"strings"
+	"testing"
+)
+
+// compareSetup declares the types the comparison tests compare
+var compareSetup = scriptSetup{prelude: `type P struct {
+	X int
+	Y string
+}
+
+type Node struct {
+	Val  int
+	Next *Node
+	Tags []string
+}
+
+type Link struct {
+	Val  int
+	Next *Link
+}`}
+
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"equal structs", `return P{X: 1} == P{X: 1}`, true},
+		{"different fields", `return P{X: 1} != P{X: 2}`, true},
+		{"unset field is zero", `return P{X: 1} == P{X: 1, Y: ""}`, true},
+		{"same struct", `a := P{X: 1}; b := a; return a == b`, true},
+		{"pointer fields by identity", `n := &Link{Val: 1}; return Link{Next: n} == Link{Next: &Link{Val: 1}}`, false},
+		{"same pointer field", `n := &Link{Val: 1}; return Link{Next: n} == Link{Next: n}`, true},
+		{"pointers by identity", `return &P{X: 1} == &P{X: 1}`, false},
+		{"pointers to the same struct", `a := P{X: 1}; p := &a; q := &a; return p == q`, true},
+		{"uncomparable fields by identity", `a := &Node{Val: 1}; b := &Node{Val: 1}; return a == b || a != a`, false},
+		{"struct and nil", `a := &Node{}; return a != nil`, true},
+		{"nil slice", `var s []int; return s == nil`, true},
+		{"slice not nil", `s := []int{}; return s != nil`, true},
+		{"different types", `return P{X: 1} == 1`, false},
+		{"string ordering", `return "apple" < "banana" && "b" >= "a"`, true},
+		{"switch on struct", `switch (P{X: 2}) { case P{X: 1}: return "one"; case P{X: 2}: return "two" }; return "none"`, "two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, compareSetup)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+func TestComparisonErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"slices", `a := []int{1}; b := []int{1}; return a == b`, "slice can only be compared to nil"},
+		{"maps", `a := map[string]int{}; return a != map[string]int{}`, "map can only be compared to nil"},
+		{"funcs", `f := func() {}; return f == f`, "func can only be compared to nil"},
+		{"uncomparable structs", `return Node{Val: 1} == Node{Val: 1}`, "Node cannot be compared"},
+		{"ordered structs", `return P{X: 1} < P{X: 2}`, "operator < not defined on struct"},
+		{"ordered slices", `a := []int{1}; return a >= a`, "operator >= not defined on slice"},
+		{"ordered bools", `v := []interface{}{true}; return v[0] > v[0]`, "operator > not defined on bool"},
+		{"mixed ordering", `v := []interface{}{"a", 1}; return v[0] > v[1]`, "unsupported types for greater than comparison"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, compareSetup)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDeepEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"slices", `return deepEqual([]int{1, 2}, []int{1, 2})`, true},
+		{"slice lengths", `return deepEqual([]int{1, 2}, []int{1})`, false},
+		{"numbers by value", `return deepEqual([]interface{}{1}, []interface{}{1.0})`, true},
+		{"maps", `return deepEqual(map[string]int{"a": 1}, map[string]int{"a": 1})`, true},
+		{"map values", `return deepEqual(map[string]int{"a": 1}, map[string]int{"a": 2})`, false},
+		{"nested", `return deepEqual(map[string][]int{"a": {1}}, map[string][]int{"a": {1}})`, true},
+		{"structs through pointers", `return deepEqual(&Node{Next: &Node{Val: 2}}, &Node{Next: &Node{Val: 2}})`, true},
+		{"struct fields", `return deepEqual(Node{Tags: []string{"a"}}, Node{Tags: []string{"b"}})`, false},
+		{"cycles", `a := &Node{Val: 1}; a.Next = a; b := &Node{Val: 1}; b.Next = b; return deepEqual(a, b)`, true},
+		{"byte slices", `return deepEqual([]byte("ab"), []byte("ab"))`, true},
+		{"different kinds", `return deepEqual([]int{1}, map[string]int{})`, false},
+		{"scalars", `return deepEqual("a", "a")`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, compareSetup)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+
+	_, err := runScript(t, `return deepEqual(1)`, compareSetup)
+	if err == nil || !strings.Contains(err.Error(), "deepEqual expects 2 arguments") {
+		t.Errorf("Expected an argument count error, got %v", err)
+	}
+}
+
+func TestStructMapKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"equal key", `m := map[P]int{}; m[P{X: 1}] = 5; return m[P{X: 1}]`, 5},
+		{"literal", `m := map[P]string{P{X: 1}: "a", P{X: 2}: "b"}; return m[P{X: 2}]`, "b"},
+		{"update", `m := map[P]int{}; m[P{X: 1}] = 1; m[P{X: 1}] += 2; return m[P{X: 1}]*10 + len(m)`, 31},
+		{"unset field is zero", `m := map[P]int{}; m[P{X: 1}] = 5; return m[P{X: 1, Y: ""}]`, 5},
+		{"comma ok", `m := map[P]int{P{X: 1}: 1}; _, ok := m[P{X: 2}]; return ok`, false},
+		{"delete", `m := map[P]int{P{X: 1}: 1, P{X: 2}: 2}; delete(m, P{X: 1}); _, ok := m[P{X: 1}]; return ok || len(m) != 1`, false},
+		{"key is copied", `k := P{X: 1}; m := map[P]int{}; m[k] = 5; k.X = 2; return m[P{X: 1}]`, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, compareSetup)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+
+	_, err := runScript(t, `m := map[Node]int{}; m[Node{}] = 1; return 0`, compareSetup)
+	if err == nil || !strings.Contains(err.Error(), "invalid map key type Node") {
+		t.Errorf("Expected an invalid key error, got %v", err)
+	}
+}
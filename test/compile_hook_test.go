@@ -0,0 +1,127 @@
+package test
+
+import (
+	"go/ast"
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/instruction"
+)
+
+func TestScriptASTRewrite(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	return legacyTotal(2, 3)
+}
+`))
+	script.AddFunction("total", func(args ...interface{}) (interface{}, error) {
+		return args[0].(int) + args[1].(int), nil
+	})
+
+	file, err := script.AST()
+	if err != nil {
+		t.Fatalf("Failed to parse script: %v", err)
+	}
+
+	// Redirect calls of a retired function to its replacement
+	ast.Inspect(file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "legacyTotal" {
+				ident.Name = "total"
+			}
+		}
+		return true
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 5 {
+		t.Errorf("Expected 5, got %v", result)
+	}
+}
+
+func TestCompileInterceptorDSL(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	x := 21
+	return twice(x)
+}
+`))
+
+	// twice(x) is compiled inline as x * 2 instead of a function call
+	script.AddCompileInterceptor(func(c *goscript.Compiler, node ast.Node) (bool, error) {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return false, nil
+		}
+		if ident, ok := call.Fun.(*ast.Ident); !ok || ident.Name != "twice" {
+			return false, nil
+		}
+		if err := c.CompileExpr(call.Args[0]); err != nil {
+			return true, err
+		}
+		c.Emit(instruction.NewInstruction(instruction.OpLoadConst, 2, nil))
+		c.Emit(instruction.NewInstruction(instruction.OpBinaryOp, instruction.OpMul, nil))
+		return true, nil
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 42 {
+		t.Errorf("Expected 42, got %v", result)
+	}
+}
+
+func TestCompileInterceptorInstrumentation(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	a := 1
+	b := a + 1
+	return a + b
+}
+`))
+
+	var traced []interface{}
+	script.AddFunction("trace", func(args ...interface{}) (interface{}, error) {
+		traced = append(traced, args[0])
+		return nil, nil
+	})
+
+	// After every := statement, report the new value of the variable
+	script.AddCompileInterceptor(func(c *goscript.Compiler, node ast.Node) (bool, error) {
+		assign, ok := node.(*ast.AssignStmt)
+		if !ok {
+			return false, nil
+		}
+		if err := c.CompileStmt(assign); err != nil {
+			return true, err
+		}
+		if err := c.CompileExpr(assign.Lhs[0]); err != nil {
+			return true, err
+		}
+		c.Emit(instruction.NewInstruction(instruction.OpCall, "trace", 1).WithTags(instruction.TagHostCall))
+		return true, nil
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 3 {
+		t.Errorf("Expected 3, got %v", result)
+	}
+	if !reflect.DeepEqual(traced, []interface{}{1, 2}) {
+		t.Errorf("Expected trace [1 2], got %v", traced)
+	}
+}
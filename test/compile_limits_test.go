@@ -0,0 +1,112 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/internal/compiler"
+)
+
+func TestSyntaxErrorsReportedTogether(t *testing.T) {
+	// More errors than the ten the Go parser reports by default
+	var body strings.Builder
+	for i := 0; i < 12; i++ {
+		body.WriteString("\tif {\n\t}\n")
+	}
+	script := goscript.NewScript([]byte("package main\n\nfunc main() {\n" + body.String() + "}\n"))
+	script.AddFile("extra.go", []byte("package main\n\nfunc helper() {\n\tif {\n\t}\n}\n"))
+
+	_, err := script.Compile()
+	var diagnostics compiler.CompileDiagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected compile diagnostics, got %v", err)
+	}
+	if len(diagnostics) != 13 {
+		t.Fatalf("Expected 13 syntax errors, got %d:\n%v", len(diagnostics), err)
+	}
+	for i, diag := range diagnostics[:12] {
+		if diag.Pos.Filename != "script.go" || diag.Pos.Line != 4+2*i || diag.Severity != compiler.SeverityError {
+			t.Errorf("Expected an error on script.go line %d, got %v", 4+2*i, diag)
+		}
+	}
+	if last := diagnostics[12]; last.Pos.Filename != "extra.go" || last.Pos.Line != 4 {
+		t.Errorf("Expected the error of the second file, got %v", last)
+	}
+	if !strings.Contains(err.Error(), "script.go:4:5: error: missing condition in if statement") {
+		t.Errorf("Expected positioned messages, got %v", err)
+	}
+}
+
+func TestCompileLimits(t *testing.T) {
+	source := `
+package main
+
+func double(x int) int {
+	return x * 2
+}
+
+func main() {
+	f := func(x int) int {
+		return ((x + 1) * 2)
+	}
+	return f(double(1))
+}
+`
+	tests := []struct {
+		name     string
+		limits   goscript.CompileLimits
+		expected string
+	}{
+		{"no limits", goscript.CompileLimits{}, ""},
+		{"within limits", goscript.CompileLimits{MaxSourceSize: 1000, MaxASTDepth: 20, MaxFunctions: 3, MaxFunctionInstructions: 100}, ""},
+		{"source size", goscript.CompileLimits{MaxSourceSize: 100}, "exceeds the limit of 100 bytes"},
+		{"nesting depth", goscript.CompileLimits{MaxASTDepth: 8}, "script.go:9:23: error: syntax tree nesting exceeds the limit of 8 levels"},
+		{"functions", goscript.CompileLimits{MaxFunctions: 2}, "script.go:9:7: error: script declares more than 2 functions"},
+		{"instructions", goscript.CompileLimits{MaxFunctionInstructions: 5}, "script.go:9:2: error: function main.main compiles to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte(source))
+			script.SetCompileLimits(tt.limits)
+			result, err := script.Run()
+			if tt.expected == "" {
+				if err != nil || result != 6 {
+					t.Errorf("Expected 6, got %v (%v)", result, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestCompileLimitsApplyToModules(t *testing.T) {
+	opts := goscript.DefaultOptions()
+	opts.CompileLimits = goscript.CompileLimits{MaxFunctions: 3}
+	script := goscript.NewScriptWithOptions([]byte(`
+package main
+
+import "big"
+
+func main() {
+	return big.F0()
+}
+`), opts)
+	script.SetImportResolver(func(importPath string) ([]byte, bool, error) {
+		var source strings.Builder
+		source.WriteString("package big\n")
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(&source, "\nfunc F%d() int {\n\treturn %d\n}\n", i, i)
+		}
+		return []byte(source.String()), importPath == "big", nil
+	})
+	_, err := script.Run()
+	if err == nil || !strings.Contains(err.Error(), "script declares more than 3 functions") {
+		t.Errorf("Expected the module to exceed the function limit, got %v", err)
+	}
+}
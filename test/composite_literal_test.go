@@ -1,6 +1,7 @@
 package test
 
 import (
+	"strings"
 	"testing"
 
 	goscript "github.com/lengzhao/goscript"
@@ -90,3 +91,70 @@ func main() {
 		t.Errorf("Expected Beijing, got %v", result)
 	}
 }
+
+// elidedSetup declares the element types of the elided composite literal
+// tests
+var elidedSetup = scriptSetup{prelude: `type P struct {
+	X int
+	Y int
+}
+
+func (p P) Sum() int {
+	return p.X + p.Y
+}
+
+type Counter struct {
+	n int
+}
+
+func (c *Counter) Inc() int {
+	c.n++
+	return c.n
+}`}
+
+func TestElidedCompositeLiteralTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"positional struct", `p := P{1, 2}; return p.Sum()`, 3},
+		{"slice elements", `cs := []Counter{{n: 1}, {n: 2}}; return cs[1].Inc()`, 3},
+		{"array elements", `cs := [2]Counter{{n: 4}, {n: 5}}; return cs[0].Inc()`, 5},
+		{"map values", `m := map[string]P{"a": {1, 2}}; return m["a"].Sum()`, 3},
+		{"map keys", `m := map[P]int{{1, 2}: 5}; return m[P{1, 2}]`, 5},
+		{"pointer elements", `ps := []*Counter{{n: 3}}; c := ps[0]; c.Inc(); return ps[0].n`, 4},
+		{"nested slices", `grid := [][]P{{{1, 2}}, {{3, 4}}}; return grid[1][0].Sum()`, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, elidedSetup)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestStructLiteralErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"too few values", `p := P{1}; return p.X`, "wrong number of values"},
+		{"too many values", `p := P{1, 2, 3}; return p.X`, "wrong number of values"},
+		{"mixed elements", `p := P{1, Y: 2}; return p.X`, "mixture of field:value and value elements"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, elidedSetup)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestConditionModes(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		permissive interface{}
+		strictErr  string
+	}{
+		{"bool conditions", `
+func main() {
+	x := 5
+	if x > 3 && x < 10 {
+		return 1
+	}
+	return 0
+}`, 1, ""},
+		{"tagless switch", `
+func main() {
+	x := 5
+	switch {
+	case x > 10:
+		return 1
+	case x > 3:
+		return 2
+	}
+	return 3
+}`, 2, ""},
+		{"int if condition", `
+func main() {
+	n := 2
+	if n {
+		return "nonzero"
+	}
+	return "zero"
+}`, "nonzero", "non-boolean condition: 2 (int)"},
+		{"string for condition", `
+func main() {
+	s := ""
+	count := 0
+	for s {
+		count++
+	}
+	return count
+}`, 0, "non-boolean condition:  (string)"},
+		{"int operands of &&", `
+func main() {
+	a := 1
+	b := 0
+	if a && b {
+		return 1
+	}
+	return 0
+}`, 0, "non-boolean condition: 1 (int)"},
+		{"constant if condition", `
+func main() {
+	if 1 {
+		return 1
+	}
+	return 0
+}`, 1, "non-boolean condition in if statement"},
+		{"constant case clause", `
+const Enabled = "yes"
+
+func main() {
+	switch {
+	case Enabled:
+		return 1
+	}
+	return 0
+}`, 1, "non-boolean condition in case clause"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := []byte("package main\n" + tt.source)
+
+			result, err := goscript.NewScript(source).Run()
+			if err != nil {
+				t.Fatalf("Permissive: failed to run script: %v", err)
+			}
+			if result != tt.permissive {
+				t.Errorf("Permissive: expected %v, got %v", tt.permissive, result)
+			}
+
+			opts := goscript.DefaultOptions()
+			opts.StrictConditions = true
+			result, err = goscript.NewScriptWithOptions(source, opts).Run()
+			if tt.strictErr == "" {
+				if err != nil {
+					t.Fatalf("Strict: failed to run script: %v", err)
+				}
+				if result != tt.permissive {
+					t.Errorf("Strict: expected %v, got %v", tt.permissive, result)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.strictErr) {
+				t.Errorf("Strict: expected error containing %q, got %v", tt.strictErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const constantsSource = `
+package main
+
+import "math"
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+const (
+	MaxRetries = 3
+	Timeout    = MaxRetries * 10
+	Ratio      = Timeout / 4
+	Scale      = 1 << 10
+	Name       = "orders" + "-v2"
+	Strict     = MaxRetries > 2
+	HalfPi     = math.Pi / 2
+)
+
+func classify(n int) int {
+	const limit = 100
+	if n > limit {
+		return Error
+	}
+	return Info
+}
+
+func main() {
+	const MaxRetries = 5
+	total := MaxRetries + Timeout
+	return total + classify(200) + Scale
+}
+`
+
+func TestScriptConstants(t *testing.T) {
+	script := goscript.NewScript([]byte(constantsSource))
+
+	// Constants are available without running the script
+	constants, err := script.Constants()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	expected := []goscript.Constant{
+		{Name: "Debug", Value: 0, Type: "Level"},
+		{Name: "Info", Value: 1, Type: "Level"},
+		{Name: "Warn", Value: 2, Type: "Level"},
+		{Name: "Error", Value: 3, Type: "Level"},
+		{Name: "MaxRetries", Value: 3},
+		{Name: "Timeout", Value: 30},
+		{Name: "Ratio", Value: 7},
+		{Name: "Scale", Value: 1024},
+		{Name: "Name", Value: "orders-v2"},
+		{Name: "Strict", Value: true},
+		{Name: "HalfPi", Value: 1.5707963267948966},
+	}
+	if !reflect.DeepEqual(constants, expected) {
+		t.Errorf("Expected %v, got %v", expected, constants)
+	}
+
+	// Local constants shadow package-level ones
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 5+30+3+1024 {
+		t.Errorf("Expected %d, got %v", 5+30+3+1024, result)
+	}
+}
+
+func TestConstantDeclarationOrder(t *testing.T) {
+	// Constants may refer to constants declared after them, as in Go
+	script := goscript.NewScript([]byte(`
+package main
+
+const A = B + 1
+const B = 2
+
+const (
+	Mask = Bits - 1
+	Bits = 1 << Shift
+)
+
+const Shift = iota + 4
+
+func main() {
+	return A*100 + Mask
+}
+`))
+	constants, err := script.Constants()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	expected := []goscript.Constant{
+		{Name: "A", Value: 3},
+		{Name: "B", Value: 2},
+		{Name: "Mask", Value: 15},
+		{Name: "Bits", Value: 16},
+		{Name: "Shift", Value: 4},
+	}
+	if !reflect.DeepEqual(constants, expected) {
+		t.Errorf("Expected %v in declaration order, got %v", expected, constants)
+	}
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 315 {
+		t.Errorf("Expected 315, got %v", result)
+	}
+}
+
+func TestConstantErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		errMsg string
+	}{
+		{"assignment", `
+const Limit = 10
+
+func main() {
+	Limit = 20
+	return Limit
+}`, "cannot assign to Limit"},
+		{"increment", `
+func main() {
+	const n = 1
+	n++
+	return n
+}`, "cannot assign to n"},
+		{"not constant", `
+func main() {
+	x := 1
+	const y = x + 1
+	return y
+}`, "x is not constant"},
+		{"division by zero", `
+const Bad = 1 / 0
+
+func main() {
+	return Bad
+}`, "division by zero"},
+		{"mismatched types", `
+const Bad = "a" + 1
+
+func main() {
+	return Bad
+}`, "mismatched types"},
+		{"cycle", `
+const A = B + 1
+const B = A * 2
+
+func main() {
+	return A
+}`, "initialization cycle for A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte("package main\n" + tt.source))
+			_, err := script.Compile()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestContainerModule(t *testing.T) {
+	scriptSource := `
+package main
+
+import "container"
+
+func main() {
+	queue := container.NewQueue()
+	container.Push(queue, 1)
+	container.Push(queue, 2)
+	container.Push(queue, 3)
+	first := container.Pop(queue)
+
+	stack := container.NewStack()
+	container.Push(stack, "a")
+	container.Push(stack, "b")
+	top := container.Peek(stack)
+
+	deque := container.NewDeque()
+	container.PushBack(deque, 2)
+	container.PushFront(deque, 1)
+	container.PushBack(deque, 3)
+	container.PopBack(deque)
+
+	return []interface{}{first, len(queue), top, container.ToSlice(deque)}
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []interface{}{1, 2, "b", []interface{}{1, 2}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestContainerModuleCapacity(t *testing.T) {
+	scriptSource := `
+package main
+
+import "container"
+
+func main() {
+	stack := container.NewStack(2)
+	container.Push(stack, 1)
+	container.Push(stack, 2)
+	container.Push(stack, 3)
+	return 0
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	_, err := script.Run()
+	if err == nil || !strings.Contains(err.Error(), "container is full") {
+		t.Errorf("Expected capacity error, got %v", err)
+	}
+}
@@ -2,8 +2,10 @@ package test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/lengzhao/goscript"
 )
@@ -144,3 +146,98 @@ func main() {
 
 	// TODO: Add global variable inspection when we have access to the execution context
 }
+
+func TestRunContextCancellation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"loop in main", `
+	n := 0
+	for n >= 0 {
+		n++
+	}
+	return n`},
+		{"loop in a function", `
+	return spin()`},
+		{"loop in a goroutine", `
+	done := make(chan bool)
+	go func() {
+		spin()
+		done <- true
+	}()
+	<-done
+	return 0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte(`
+package main
+
+func spin() int {
+	n := 0
+	for n >= 0 {
+		n++
+	}
+	return n
+}
+
+func main() {` + tt.body + `
+}
+`))
+			script.SetMaxInstructions(0)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err := script.RunContext(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected a deadline error, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Expected the script to stop soon after the deadline, took %v", elapsed)
+			}
+		})
+	}
+
+	// A context canceled before the run stops it before the first instruction
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := goscript.NewScript([]byte("package main\n\nfunc main() {\n\treturn 1\n}\n")).RunContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a canceled error, got %v", err)
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	script := goscript.NewScriptWithOptions([]byte(`
+package main
+
+func main() {
+	n := 0
+	for n >= 0 {
+		n++
+	}
+	return n
+}
+`), goscript.Options{Timeout: 50 * time.Millisecond, MaxInstructions: goscript.Unlimited})
+
+	// Every execution gets the full timeout
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		_, err := script.Run()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected a deadline error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > 2*time.Second {
+			t.Errorf("Expected the script to stop soon after the timeout, took %v", elapsed)
+		}
+	}
+
+	// A script that finishes in time is not affected
+	quick := goscript.NewScript([]byte("package main\n\nfunc main() {\n\treturn 1\n}\n"))
+	quick.SetTimeout(time.Second)
+	if result, err := quick.Run(); err != nil || result != 1 {
+		t.Errorf("Expected 1, got %v, %v", result, err)
+	}
+}
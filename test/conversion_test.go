@@ -0,0 +1,135 @@
+package test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+type conversionPoint struct {
+	X, Y int
+}
+
+// conversionFunctions are Go functions with typed parameters
+var conversionFunctions = map[string]interface{}{
+	"sum": func(values []float64) float64 {
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		return total
+	},
+	"count": func(counts map[string]int) int {
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		return total
+	},
+	"pair": func(values [2]int) int {
+		return values[0] * values[1]
+	},
+	"area": func(points []conversionPoint) int {
+		return (points[1].X - points[0].X) * (points[1].Y - points[0].Y)
+	},
+	"toInt": func(n int) int {
+		return n
+	},
+	"toInt8": func(n int8) int8 {
+		return n
+	},
+	"label": func(s string) string {
+		return "#" + s
+	},
+}
+
+// conversionSetup runs the conversion tests in the given mode with the
+// conversion functions
+func conversionSetup(mode goscript.ConversionMode) scriptSetup {
+	return scriptSetup{
+		prelude:   "type Point struct {\n\tX int\n\tY int\n}",
+		options:   &goscript.Options{ConversionMode: mode},
+		functions: conversionFunctions,
+	}
+}
+
+func TestConversionModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     goscript.ConversionMode
+		body     string
+		expected interface{}
+	}{
+		{"ints to floats", goscript.ConvertSafe, `return sum([]interface{}{1, 2.5})`, 3.5},
+		{"integral floats to int", goscript.ConvertSafe, `return toInt(4.0)`, 4},
+		{"map values", goscript.ConvertSafe, `return count(map[string]interface{}{"a": 1, "b": 2.0})`, 3},
+		{"array", goscript.ConvertSafe, `return pair([]interface{}{3, 4})`, 12},
+		{"structs and maps as structs", goscript.ConvertSafe, `return area([]interface{}{Point{X: 1, Y: 1}, map[string]interface{}{"x": 3, "y": 4}})`, 6},
+		{"strict integers", goscript.ConvertStrict, `return toInt8(100)`, int8(100)},
+		{"strict floats", goscript.ConvertStrict, `return sum([]interface{}{1.5, 2.5})`, 4.0},
+		{"lenient truncation", goscript.ConvertLenient, `return toInt(1.9)`, 1},
+		{"lenient numeric string", goscript.ConvertLenient, `return toInt(" 12 ") + toInt("3.7")`, 15},
+		{"lenient number to string", goscript.ConvertLenient, `return label(42) + label(0.5)`, "#42#0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, conversionSetup(tt.mode))
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+func TestConversionErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     goscript.ConversionMode
+		body     string
+		expected string
+	}{
+		{"element", goscript.ConvertSafe, `return sum([]interface{}{1, "x"})`, `argument 1 of sum: element 1: cannot use x (string) as float64`},
+		{"nested field", goscript.ConvertSafe, `return area([]interface{}{Point{X: 1, Y: 1}, map[string]interface{}{"X": "a"}})`, "element 1: field X: cannot use a (string) as int"},
+		{"map value", goscript.ConvertSafe, `return count(map[string]interface{}{"a": 1.5})`, "key a: cannot use 1.5 as int"},
+		{"array length", goscript.ConvertSafe, `return pair([]interface{}{1, 2, 3})`, "cannot use 3 elements as [2]int"},
+		{"precision", goscript.ConvertSafe, `return toInt(1.5)`, "cannot use 1.5 as int without changing its value"},
+		{"range", goscript.ConvertSafe, `return toInt8(300)`, "cannot use 300 as int8"},
+		{"number as string", goscript.ConvertSafe, `return label(42)`, "cannot use 42 (int) as string"},
+		{"strict int to float", goscript.ConvertStrict, `return sum([]interface{}{1, 2})`, "cannot use 1 (int) as float64 in strict mode"},
+		{"strict field names", goscript.ConvertStrict, `return area([]interface{}{Point{X: 1, Y: 1}, map[string]interface{}{"x": 3}})`, "unknown field x in test.conversionPoint"},
+		{"lenient range", goscript.ConvertLenient, `return toInt8(1000.5)`, "cannot use 1000 as int8"},
+		{"lenient not a number", goscript.ConvertLenient, `return toInt("twelve")`, `cannot use "twelve" as int: not a number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, conversionSetup(tt.mode))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	ports, err := vm.Convert[[]int]([]interface{}{80, 443.0})
+	if err != nil || !reflect.DeepEqual(ports, []int{80, 443}) {
+		t.Errorf("Expected [80 443], got %v (%v)", ports, err)
+	}
+	point, err := vm.Convert[*conversionPoint](map[string]interface{}{"X": 1, "Y": 2})
+	if err != nil || *point != (conversionPoint{X: 1, Y: 2}) {
+		t.Errorf("Expected {1 2}, got %v (%v)", point, err)
+	}
+	if _, err := vm.Convert[int]("1"); err == nil {
+		t.Error("Expected strings not to convert to int in safe mode")
+	}
+	n, err := vm.ConvertWithMode[int]("1", vm.ConvertLenient)
+	if err != nil || n != 1 {
+		t.Errorf("Expected 1 in lenient mode, got %v (%v)", n, err)
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const coverageSource = `
+package main
+
+func sign(x int) int {
+	if x > 0 {
+		return 1
+	}
+	if x < 0 {
+		return 0 - 1
+	}
+	return 0
+}
+
+func main() {
+	total := 0
+	for i := 0; i < 3; i++ {
+		total += sign(i)
+	}
+	return total
+}
+`
+
+// lineHits returns the hits of each covered line by line number
+func lineHits(coverage *goscript.Coverage) map[int]int64 {
+	hits := make(map[int]int64)
+	for _, line := range coverage.Lines() {
+		hits[line.Line] = line.Hits
+	}
+	return hits
+}
+
+func TestCoverage(t *testing.T) {
+	script := goscript.NewScript([]byte(coverageSource))
+	if script.CoverageReport() != nil {
+		t.Error("Expected no coverage report while coverage is disabled")
+	}
+	script.SetCoverage(true)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+
+	coverage := script.CoverageReport()
+	expected := map[int]int64{5: 3, 6: 2, 8: 1, 9: 0, 11: 1, 15: 1, 16: 3, 17: 3, 19: 1}
+	if hits := lineHits(coverage); !reflect.DeepEqual(hits, expected) {
+		t.Errorf("Expected line hits %v, got %v", expected, hits)
+	}
+	if percent := coverage.Percent(); percent != 90 {
+		t.Errorf("Expected 90%% of the statements to be covered, got %v", percent)
+	}
+
+	// Counts accumulate across executions
+	if _, err := script.CallFunction("sign", -5); err != nil {
+		t.Fatalf("Failed to call sign: %v", err)
+	}
+	hits := lineHits(script.CoverageReport())
+	if hits[9] != 1 || hits[5] != 4 || hits[15] != 1 {
+		t.Errorf("Expected the call to add to the counts, got %v", hits)
+	}
+	if percent := script.CoverageReport().Percent(); percent != 100 {
+		t.Errorf("Expected full coverage, got %v", percent)
+	}
+
+	script.ResetCoverage()
+	for line, count := range lineHits(script.CoverageReport()) {
+		if count != 0 {
+			t.Errorf("Expected no hits after a reset, got %d on line %d", count, line)
+		}
+	}
+
+	script.SetCoverage(false)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if script.CoverageReport() != nil {
+		t.Error("Expected no coverage report after disabling coverage")
+	}
+}
+
+func TestCoverageExport(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	x := 1
+	if x > 1 {
+		x = 2
+	}
+	return x
+}
+`))
+	script.SetCoverage(true)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	coverage := script.CoverageReport()
+
+	var lcov bytes.Buffer
+	if err := coverage.WriteLCOV(&lcov); err != nil {
+		t.Fatalf("Failed to write LCOV: %v", err)
+	}
+	expectedLCOV := "TN:\nSF:script.go\nDA:5,1\nDA:6,1\nDA:7,0\nDA:9,1\nLF:4\nLH:3\nend_of_record\n"
+	if lcov.String() != expectedLCOV {
+		t.Errorf("Expected LCOV %q, got %q", expectedLCOV, lcov.String())
+	}
+
+	var profile bytes.Buffer
+	if err := coverage.WriteCoverProfile(&profile); err != nil {
+		t.Fatalf("Failed to write cover profile: %v", err)
+	}
+	expectedProfile := "mode: count\nscript.go:5.2,6.1 1 1\nscript.go:6.2,7.1 1 1\nscript.go:7.3,8.1 1 0\nscript.go:9.2,10.1 1 1\n"
+	if profile.String() != expectedProfile {
+		t.Errorf("Expected cover profile %q, got %q", expectedProfile, profile.String())
+	}
+}
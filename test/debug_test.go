@@ -3,9 +3,9 @@ package test
 import (
 	"testing"
 
-	"github.com/lengzhao/goscript/compiler"
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
 	"github.com/lengzhao/goscript/parser"
-	"github.com/lengzhao/goscript/vm"
 )
 
 func TestSimpleAssignment(t *testing.T) {
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+// divisionSetup runs the division tests in the given mode, with an untyped
+// constant division at package level
+func divisionSetup(mode goscript.DivisionMode) scriptSetup {
+	return scriptSetup{
+		prelude: "const half = 7 / 2",
+		options: &goscript.Options{MaxInstructions: 10000, DivisionMode: mode},
+	}
+}
+
+// divisionOperands declares the int operands the division tests return
+// expressions of
+const divisionOperands = "\ta, b := 7, 2\n\treturn "
+
+func TestDivisionMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     goscript.DivisionMode
+		expr     string
+		expected interface{}
+	}{
+		{"truncating", goscript.TruncatingInt, "a / b", 3},
+		{"truncating negative", goscript.TruncatingInt, "(0 - a) / b", -3},
+		{"truncating exact", goscript.TruncatingInt, "6 / b", 3},
+		{"promote", goscript.PromoteToFloat, "a / b", 3.5},
+		{"promote exact", goscript.PromoteToFloat, "6 / b", 3.0},
+		{"promote compound", goscript.PromoteToFloat, "func() float64 { x := 1; x /= 4; return x }()", 0.25},
+		{"promote keeps modulo", goscript.PromoteToFloat, "a % b", 1},
+		{"promote keeps multiplication", goscript.PromoteToFloat, "a * b", 14},
+		{"promote runes", goscript.PromoteToFloat, "'a' / 2", 48.5},
+		{"constants", goscript.PromoteToFloat, "half", 3},
+		{"mixed operands", goscript.TruncatingInt, "a / 2.0", 3.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, divisionOperands+tt.expr, divisionSetup(tt.mode))
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	for _, mode := range []goscript.DivisionMode{goscript.TruncatingInt, goscript.PromoteToFloat} {
+		for _, expr := range []string{"a / 0", "a / 0.0", "a % 0"} {
+			_, err := runScript(t, divisionOperands+expr, divisionSetup(mode))
+			if err == nil || !strings.Contains(err.Error(), "by zero") {
+				t.Errorf("%v: expected %s to fail with a division by zero, got %v", mode, expr, err)
+			}
+		}
+	}
+}
+
+func TestNumericOperands(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"1 == 1.0", true},
+		{"2.5 != 2", true},
+		{"a < 7.5", true},
+		{"a + 0.5", 7.5},
+		{"a - b", 5},
+		{"1.5 * b", 3.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, err := runScript(t, divisionOperands+tt.expr, divisionSetup(goscript.TruncatingInt))
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+
+	_, err := runScript(t, divisionOperands+"1.5 % b", divisionSetup(goscript.TruncatingInt))
+	if err == nil || !strings.Contains(err.Error(), "unsupported types for modulo: float64 and int") {
+		t.Errorf("Expected %% on a float to fail, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const embeddingSource = `
+package main
+
+type Named interface {
+	Name() string
+}
+
+type Base struct {
+	ID  int
+	Tag string
+}
+
+func (b Base) Name() string {
+	return "base-" + b.Tag
+}
+
+func (b *Base) SetTag(tag string) {
+	b.Tag = tag
+}
+
+type Middle struct {
+	Base
+	Level int
+}
+
+type Top struct {
+	*Middle
+	Tag string
+}
+
+func describe(n Named) string {
+	return n.Name()
+}
+
+func main() {
+	m := Middle{Base: Base{ID: 1, Tag: "a"}, Level: 2}
+	m.SetTag("b")
+	m.ID = 7
+	z := Middle{}
+	z.ID = 3
+	t := Top{Middle: &m, Tag: "top"}
+	return []interface{}{m.Base.ID, m.Tag, describe(m), z.Base.ID, t.Tag, t.Level, t.ID, t.Name()}
+}
+`
+
+func TestEmbeddedFieldAndMethodPromotion(t *testing.T) {
+	result, err := goscript.NewScript([]byte(embeddingSource)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if fmt.Sprint(result) != "[7 b base-b 3 top 2 7 base-b]" {
+		t.Errorf("Expected [7 b base-b 3 top 2 7 base-b], got %v", result)
+	}
+}
+
+func TestEmbeddedFieldsSerialized(t *testing.T) {
+	script := goscript.NewScript([]byte(embeddingSource))
+	data, err := script.CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize program: %v", err)
+	}
+	loaded := goscript.NewScript(nil)
+	if _, err := loaded.LoadProgram(data); err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	embedded := loaded.GetVM().EmbeddedFields("Top")
+	if len(embedded) != 1 || embedded[0].Name != "Middle" {
+		t.Errorf("Expected Top to embed Middle, got %v", embedded)
+	}
+	result, err := loaded.Run()
+	if err != nil {
+		t.Fatalf("Failed to run loaded program: %v", err)
+	}
+	if fmt.Sprint(result) != "[7 b base-b 3 top 2 7 base-b]" {
+		t.Errorf("Expected [7 b base-b 3 top 2 7 base-b], got %v", result)
+	}
+}
+
+func TestShallowestEmbeddedFieldWins(t *testing.T) {
+	source := `
+package main
+
+type A struct {
+	Name string
+}
+
+type B struct {
+	Name string
+}
+
+type Inner struct {
+	A
+}
+
+type C struct {
+	Inner
+	B
+}
+
+func main() {
+	c := C{Inner: Inner{A: A{Name: "deep"}}, B: B{Name: "shallow"}}
+	return c.Name
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "shallow" {
+		t.Errorf("Expected shallow, got %v", result)
+	}
+}
+
+func TestAmbiguousSelector(t *testing.T) {
+	source := `
+package main
+
+type A struct {
+	Name string
+}
+
+type B struct {
+	Name string
+}
+
+type D struct {
+	A
+	B
+}
+
+func main() {
+	d := D{A: A{Name: "a"}, B: B{Name: "b"}}
+	return d.Name
+}
+`
+	_, err := goscript.NewScript([]byte(source)).Run()
+	if err == nil || !strings.Contains(err.Error(), "ambiguous selector D.Name") {
+		t.Errorf("Expected ambiguous selector D.Name, got %v", err)
+	}
+}
+
+func TestZeroValueFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"unset number", `c := Acc{}; return c.N + 1`, 1},
+		{"unset string", `c := Acc{N: 1}; return c.Label + "x"`, "x"},
+		{"var struct", `var b Acc; return b.N`, 0},
+		{"var struct field set", `var b Acc; b.N = 4; b.N++; return b.N`, 5},
+		{"nested struct", `var b Acc; b.In.X = 5; return b.In.X`, 5},
+		{"promoted field", `var w Wrapper; return w.N + 2`, 2},
+		{"pointer field", `c := Acc{}; return c.Next == nil`, true},
+		{"zero structs are equal", `var b Acc; return b == Acc{In: Inner{}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "package main\n\ntype Inner struct {\n\tX int\n}\n\ntype Acc struct {\n\tN     int\n\tLabel string\n\tIn    Inner\n\tNext  *Acc\n}\n\ntype Wrapper struct {\n\tAcc\n}\n\nfunc main() {\n" + tt.body + "\n}\n"
+			result, err := goscript.NewScript([]byte(source)).Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestNewScriptWithOptions(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return "5" + 1
+}
+`
+
+	opts := goscript.DefaultOptions()
+	opts.Coercion = true
+	script := goscript.NewScriptWithOptions([]byte(scriptSource), opts)
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "51" {
+		t.Errorf("Expected \"51\", got %v", result)
+	}
+
+	var stats *goscript.Stats = script.GetExecutionStats()
+	if stats.InstructionCount == 0 {
+		t.Error("Expected instruction count to be recorded")
+	}
+}
+
+func TestZeroOptionsUseDefaults(t *testing.T) {
+	loop := `
+package main
+
+func main() {
+	n := 0
+	for i := 0; i < 20000; i++ {
+		n++
+	}
+	return n
+}
+`
+	_, err := goscript.NewScriptWithOptions([]byte(loop), goscript.Options{}).Run()
+	if err == nil || !strings.Contains(err.Error(), "instruction limit") {
+		t.Errorf("Expected the default instruction limit, got %v", err)
+	}
+
+	script := goscript.NewScriptWithOptions([]byte(loop), goscript.Options{MaxInstructions: goscript.Unlimited})
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script without an instruction limit: %v", err)
+	}
+	if result != 20000 {
+		t.Errorf("Expected 20000, got %v", result)
+	}
+
+	if max := goscript.NewScriptWithOptions(nil, goscript.Options{}).GetVM().GetMaxGoroutines(); max != goscript.DefaultOptions().MaxGoroutines {
+		t.Errorf("Expected the default goroutine limit, got %d", max)
+	}
+}
+
+func TestScriptCompileProgram(t *testing.T) {
+	scriptSource := `
+package main
+
+func add(a, b int) int {
+	return a + b
+}
+
+func main() {
+	return add(1, 2)
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	if script.Program() != nil {
+		t.Error("Expected no program before compilation")
+	}
+
+	program, err := script.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	if !program.HasFunction("add") || !program.HasFunction("main") {
+		t.Errorf("Expected add and main functions, got %v", program.Functions())
+	}
+
+	// Compiling again returns the same program
+	again, err := script.Compile()
+	if err != nil || again != program {
+		t.Errorf("Expected cached program, got %v, %v", again, err)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 3 {
+		t.Errorf("Expected 3, got %v", result)
+	}
+}
+
+func TestProgramWarnings(t *testing.T) {
+	scriptSource := `
+package main
+
+func spin(n int) int {
+	return spin(n + 1)
+}
+
+func main() {
+	return 1
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	program, err := script.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+
+	warnings := program.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "recursive function spin has no base case") {
+		t.Errorf("Expected a recursion warning, got %v", warnings)
+	}
+}
+
+func TestProgramWarmup(t *testing.T) {
+	scriptSource := `
+package main
+
+import "strings"
+
+func shout(s string) string {
+	return strings.ToUpper(s) + suffix()
+}
+
+func main() {
+	return shout("hi") + audit()
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	script.AddFunction("suffix", func(args ...interface{}) (interface{}, error) {
+		return "!", nil
+	})
+	program, err := script.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+
+	report, err := program.Warmup(true)
+	if err != nil {
+		t.Fatalf("Failed to warm up program: %v", err)
+	}
+	if !reflect.DeepEqual(report.Modules, []string{"strings"}) {
+		t.Errorf("Expected strings module to be loaded, got %v", report.Modules)
+	}
+	if !reflect.DeepEqual(report.Unresolved, []string{"audit"}) {
+		t.Errorf("Expected audit to be unresolved, got %v", report.Unresolved)
+	}
+	if report.ResolvedCalls < 2 {
+		t.Errorf("Expected at least 2 resolved calls, got %d", report.ResolvedCalls)
+	}
+
+	// Registering the missing function afterwards makes the program runnable
+	script.AddFunction("audit", func(args ...interface{}) (interface{}, error) {
+		return "?", nil
+	})
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "HI!?" {
+		t.Errorf("Expected HI!?, got %v", result)
+	}
+}
@@ -65,6 +65,8 @@ func main() {
 
 			script := goscript.NewScript([]byte(scriptSource))
 			script.SetDebug(false) // Disable debug to reduce output
+			// Recursive fibonacci needs far more than the default instruction budget
+			script.SetMaxInstructions(0)
 			start = time.Now()
 			scriptResult, err := script.Run()
 			scriptDuration := time.Since(start)
@@ -131,6 +133,8 @@ func main() {
 `
 	script := goscript.NewScript([]byte(scriptSource))
 	script.SetDebug(false) // Disable debug to reduce output
+	// Recursive fibonacci needs far more than the default instruction budget
+	script.SetMaxInstructions(0)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+// openResource registers a host function that "opens" a resource tied to
+// the current execution and records when it is released
+func openResource(script *goscript.Script, released *[]string) {
+	script.AddFunction("open", func(args ...interface{}) (interface{}, error) {
+		name := args[0].(string)
+		script.OnExecutionEnd(func() error {
+			*released = append(*released, name)
+			return nil
+		})
+		return name, nil
+	})
+}
+
+func TestExecutionFinalizersRunInReverseOrder(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	a := open("file")
+	b := open("cursor")
+	return a + "," + b
+}
+`))
+	var released []string
+	openResource(script, &released)
+
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if !reflect.DeepEqual(released, []string{"cursor", "file"}) {
+		t.Errorf("Expected [cursor file], got %v", released)
+	}
+
+	// Finalizers are per execution and do not run again
+	released = nil
+	script.Run()
+	if !reflect.DeepEqual(released, []string{"cursor", "file"}) {
+		t.Errorf("Expected finalizers of the second run only, got %v", released)
+	}
+}
+
+func TestExecutionFinalizersRunOnFailure(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	open("body")
+	return fail()
+}
+`))
+	var released []string
+	openResource(script, &released)
+	script.AddFunction("fail", func(args ...interface{}) (interface{}, error) {
+		return nil, errors.New("request failed")
+	})
+
+	if _, err := script.Run(); err == nil {
+		t.Fatal("Expected the script to fail")
+	}
+	if !reflect.DeepEqual(released, []string{"body"}) {
+		t.Errorf("Expected [body] to be released, got %v", released)
+	}
+}
+
+func TestExecutionFinalizerError(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	return open("db")
+}
+`))
+	script.AddFunction("open", func(args ...interface{}) (interface{}, error) {
+		script.OnExecutionEnd(func() error {
+			return errors.New("close db")
+		})
+		return args[0], nil
+	})
+
+	_, err := script.Run()
+	if err == nil || !strings.Contains(err.Error(), "finalizer failed: close db") {
+		t.Errorf("Expected finalizer error, got %v", err)
+	}
+}
+
+func TestScriptClose(t *testing.T) {
+	script := goscript.NewScript([]byte("package main\nfunc main() {}"))
+
+	var closed []string
+	script.OnClose(func() error {
+		closed = append(closed, "pool")
+		return nil
+	})
+	script.OnClose(func() error {
+		closed = append(closed, "cache")
+		panic("cache broken")
+	})
+
+	err := script.Close()
+	if err == nil || !strings.Contains(err.Error(), "panic: cache broken") {
+		t.Errorf("Expected panic to be reported, got %v", err)
+	}
+	if !reflect.DeepEqual(closed, []string{"cache", "pool"}) {
+		t.Errorf("Expected [cache pool], got %v", closed)
+	}
+
+	if err := script.Close(); err != nil {
+		t.Errorf("Expected second Close to be a no-op, got %v", err)
+	}
+	if len(closed) != 2 {
+		t.Errorf("Expected finalizers to run once, got %v", closed)
+	}
+}
@@ -0,0 +1,166 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/builtin"
+)
+
+func TestStructFormatting(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected interface{}
+	}{
+		{"value verb", `fmt.Sprintf("%v", p)`, "Person{age: 30, name: Alice}"},
+		{"plus verb", `fmt.Sprintf("%+v", p)`, "Person{age: 30, name: Alice}"},
+		{"type verb", `fmt.Sprintf("%T and %T", p, 5)`, "Person and int"},
+		{"mixed verbs", `fmt.Sprintf("%s is %d: %T %%", p.name, p.age, p)`, "Alice is 30: Person %"},
+		{"width", `fmt.Sprintf("[%-8T][%5d]", p, 42)`, "[Person  ][   42]"},
+		{"nested", `fmt.Sprint(Team{lead: p, size: 3})`, "Team{lead: Person{age: 30, name: Alice}, size: 3}"},
+		{"in slice", `fmt.Sprintf("%v", []Person{p, p})`, "[Person{age: 30, name: Alice} Person{age: 30, name: Alice}]"},
+		{"len", `len(p)`, 2},
+		{"json", `json.Marshal(p)`, `{"name":"Alice","age":30}`},
+		{"plain values unchanged", `fmt.Sprintf("%v %T %5.1f", []int{1, 2}, "x", 2.25)`, "[1 2] string   2.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte(`
+package main
+
+import (
+	"fmt"
+	"json"
+)
+
+type Person struct {
+	name string
+	age  int
+}
+
+type Team struct {
+	lead Person
+	size int
+}
+
+func main() {
+	p := Person{name: "Alice", age: 30}
+	return ` + tt.expr + `
+}
+`))
+			result, err := script.Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFmtVerbs(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected string
+	}{
+		{"value", `fmt.Sprintf("%v %v %v %v", 1, 2.5, "s", true)`, "1 2.5 s true"},
+		{"int as float", `fmt.Sprintf("%f %.2f %g", 3, 4, 5)`, "3.000000 4.00 5"},
+		{"integral float as int", `fmt.Sprintf("%d %5d", n, n)`, "42    42"},
+		{"fractional float with d", `fmt.Sprintf("%d", 2.5)`, "%!d(float64=2.5)"},
+		{"string", `fmt.Sprintf("%s|%q", "a b", "a b")`, `a b|"a b"`},
+		{"bool", `fmt.Sprintf("%t %t", true, 1 > 2)`, "true false"},
+		{"percent", `fmt.Sprintf("100%%")`, "100%"},
+		{"missing operand", `fmt.Sprintf("%d %d", 1)`, "1 %!d(MISSING)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte(`
+package main
+
+import (
+	"fmt"
+	"json"
+)
+
+func main() {
+	n := json.Unmarshal("42")
+	return ` + tt.expr + `
+}
+`))
+			result, err := script.Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFmtPrintWriter(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Print("a", 1)
+	fmt.Printf(" %s=%d;", "n", 2)
+	n := fmt.Println(" done")
+	return n
+}
+`))
+	var out bytes.Buffer
+	fmtModule, _ := builtin.GetModuleExecutorWithOptions("fmt", builtin.ModuleOptions{Stdout: &out})
+	script.RegisterModule("fmt", fmtModule)
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if out.String() != "a1 n=2; done\n" {
+		t.Errorf("Expected the output to be captured, got %q", out.String())
+	}
+	if result != 6 {
+		t.Errorf("Expected Println to return the bytes written, got %v", result)
+	}
+}
+
+func TestFmtErrorf(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "fmt"
+
+func load(name string) error {
+	return fmt.Errorf("load %s: %w", name, notFound())
+}
+
+func main() {
+	return load("config")
+}
+`))
+	sentinel := errors.New("not found")
+	script.AddFunction("notFound", func(args ...interface{}) (interface{}, error) {
+		return sentinel, nil
+	})
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	loadErr, ok := result.(error)
+	if !ok {
+		t.Fatalf("Expected an error value, got %T", result)
+	}
+	if loadErr.Error() != "load config: not found" {
+		t.Errorf("Unexpected message %q", loadErr.Error())
+	}
+	if !errors.Is(loadErr, sentinel) {
+		t.Errorf("Expected the error to wrap the host error")
+	}
+}
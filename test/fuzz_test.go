@@ -0,0 +1,34 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func FuzzCompile(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("data", "*.gs"))
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil {
+			f.Add(data)
+		}
+	}
+	f.Add([]byte("package main\nfunc main() { return 1 }"))
+	f.Add([]byte("package main\nfunc f() int { return f() }\nfunc main() { return f() }"))
+
+	f.Fuzz(func(t *testing.T, source []byte) {
+		script := goscript.NewScript(source)
+		script.SetMaxInstructions(2000)
+
+		// Arbitrary source must produce a result or an error, never a panic,
+		// which the VM recovers into a PanicError
+		_, err := script.Run()
+		var panicErr *goscript.PanicError
+		if errors.As(err, &panicErr) {
+			t.Fatalf("%v\n%s", panicErr, panicErr.Stack)
+		}
+	})
+}
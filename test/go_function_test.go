@@ -0,0 +1,92 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestAddGoFunction(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	ok := isLong("hello", 3)
+	q, r := divmod(7, 2)
+	return []interface{}{ok, q, r, join("-", "a", "b"), scale(2.5)}
+}
+`))
+	functions := map[string]interface{}{
+		"isLong": func(s string, n int) (bool, error) {
+			return len(s) > n, nil
+		},
+		"divmod": func(a, b int) (int, int) {
+			return a / b, a % b
+		},
+		"join": func(sep string, parts ...string) string {
+			return strings.Join(parts, sep)
+		},
+		"scale": func(f float64) float64 {
+			return f * 2
+		},
+	}
+	for name, fn := range functions {
+		if err := script.AddGoFunction(name, fn); err != nil {
+			t.Fatalf("Failed to add function %s: %v", name, err)
+		}
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []interface{}{true, 3, 1, "a-b", 5.0}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("Value %d: expected %v (%T), got %v (%T)", i, expected[i], expected[i], values[i], values[i])
+		}
+	}
+}
+
+func TestAddGoFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"returned error", `
+	return check(0 - 1)`, "negative value"},
+		{"argument count", `
+	return check(1, 2)`, "wrong number of arguments in call to check: have 2, want 1"},
+		{"argument type", `
+	return check("one")`, "cannot use one (string) as int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte("package main\n\nfunc main() {" + tt.body + "\n}\n"))
+			err := script.AddGoFunction("check", func(n int) (int, error) {
+				if n < 0 {
+					return 0, errors.New("negative value")
+				}
+				return n, nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to add function: %v", err)
+			}
+			_, err = script.Run()
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if err := goscript.NewScript(nil).AddGoFunction("f", 1); err == nil {
+		t.Errorf("Expected an error adding a non-function")
+	}
+}
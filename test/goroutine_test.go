@@ -0,0 +1,300 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestGoroutinesAndChannels(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func worker(id int, jobs chan int, results chan int) {
+	for {
+		n, ok := <-jobs
+		if ok == false {
+			return
+		}
+		results <- n * n
+	}
+}
+
+func main() {
+	jobs := make(chan int, 10)
+	results := make(chan int)
+	for w := 1; w <= 3; w++ {
+		go worker(w, jobs, results)
+	}
+	for i := 1; i <= 5; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	sum := 0
+	for i := 0; i < 5; i++ {
+		sum += <-results
+	}
+
+	done := make(chan bool)
+	total := 0
+	go func() {
+		total = sum * 2
+		done <- true
+	}()
+	<-done
+	return total
+}
+`))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 110 {
+		t.Errorf("Expected 110, got %v", result)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"ready case", `
+	a := make(chan int, 1)
+	b := make(chan string, 1)
+	b <- "b"
+	select {
+	case v := <-a:
+		return v
+	case s := <-b:
+		return s
+	}`, "b"},
+		{"default when nothing is ready", `
+	a := make(chan int)
+	select {
+	case v := <-a:
+		return v
+	default:
+		return "default"
+	}`, "default"},
+		{"send case", `
+	a := make(chan int, 1)
+	select {
+	case a <- 7:
+	}
+	return <-a`, 7},
+		{"blocks until a goroutine sends", `
+	a := make(chan int)
+	quit := make(chan bool)
+	go func() {
+		a <- 1
+		a <- 2
+		quit <- true
+	}()
+	sum := 0
+	for i := 0; i < 3; i++ {
+		select {
+		case v := <-a:
+			sum += v
+		case <-quit:
+			sum += 10
+		}
+	}
+	return sum`, 13},
+		{"closed channel", `
+	a := make(chan string, 1)
+	close(a)
+	select {
+	case v, ok := <-a:
+		return v == "" && ok == false
+	}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRangeOverChannel(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"receives until closed", `
+	ch := make(chan int)
+	go func() {
+		for i := 1; i <= 4; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+	sum := 0
+	for v := range ch {
+		sum += v
+	}
+	return sum`, 10},
+		{"buffered values", `
+	ch := make(chan string, 3)
+	ch <- "a"
+	ch <- "b"
+	close(ch)
+	s := ""
+	for v := range ch {
+		s += v
+	}
+	return s`, "ab"},
+		{"without variable", `
+	ch := make(chan bool, 2)
+	ch <- true
+	ch <- false
+	close(ch)
+	n := 0
+	for range ch {
+		n++
+	}
+	return n`, 2},
+		{"break and continue", `
+	ch := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		ch <- i
+	}
+	close(ch)
+	sum := 0
+	for v := range ch {
+		if v == 1 {
+			continue
+		}
+		if v == 3 {
+			break
+		}
+		sum += v
+	}
+	return sum`, 2},
+		{"captured values", `
+	ch := make(chan int, 2)
+	ch <- 1
+	ch <- 2
+	close(ch)
+	var fs []func() int
+	for v := range ch {
+		fs = append(fs, func() int { return v })
+	}
+	return fs[0]()*10 + fs[1]()`, 12},
+		{"slices are still indexed", `
+	n := 0
+	for i := range []string{"a", "b", "c"} {
+		n += i
+	}
+	return n`, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGoroutineErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"deadlock without goroutines", `
+	a := make(chan int)
+	a <- 1
+	return 0`, "deadlock"},
+		{"deadlock with goroutines", `
+	a := make(chan int)
+	b := make(chan int)
+	go func() {
+		<-b
+	}()
+	return <-a`, "deadlock"},
+		{"range over a channel that is never closed", `
+	a := make(chan int, 1)
+	a <- 1
+	for v := range a {
+		_ = v
+	}
+	return 0`, "deadlock"},
+		{"send on closed channel", `
+	a := make(chan int, 1)
+	close(a)
+	a <- 1
+	return 0`, "send on closed channel"},
+		{"close of closed channel", `
+	a := make(chan int)
+	close(a)
+	close(a)
+	return 0`, "close of closed channel"},
+		{"goroutine error stops the execution", `
+	done := make(chan bool)
+	go func() {
+		undefinedFunction()
+		done <- true
+	}()
+	<-done
+	return 0`, "undefinedFunction"},
+		{"go requires a call", `
+	go make(map[string]int)
+	return 0`, "must be function call"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, scriptSetup{})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestMaxGoroutines(t *testing.T) {
+	source := []byte(`
+package main
+
+func main() {
+	block := make(chan bool)
+	for i := 0; i < 5; i++ {
+		go func() {
+			<-block
+		}()
+	}
+	return "started"
+}
+`)
+	script := goscript.NewScript(source)
+	script.SetMaxGoroutines(4)
+	if _, err := script.Run(); err == nil || !strings.Contains(err.Error(), "too many goroutines") {
+		t.Errorf("Expected a goroutine limit error, got %v", err)
+	}
+
+	// Goroutines still blocked when main returns are stopped
+	script = goscript.NewScript(source)
+	script.SetMaxGoroutines(5)
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "started" {
+		t.Errorf("Expected started, got %v", result)
+	}
+}
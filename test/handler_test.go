@@ -0,0 +1,78 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const handlerSource = `
+package main
+
+import "errors"
+
+type Order struct {
+	ID    int
+	Total float64
+	Tags  []string
+}
+
+func onOrder(id int64, prices []float64) (Order, error) {
+	if len(prices) == 0 {
+		return Order{}, errors.New("empty order")
+	}
+	total := 0.0
+	for _, p := range prices {
+		total = total + p
+	}
+	return Order{ID: id, Total: total, Tags: []string{"new"}}, nil
+}
+
+func greet(name string) string {
+	return "hello " + name
+}
+
+func main() {}
+`
+
+type handlerOrder struct {
+	ID    int
+	Total float64
+	Tags  []string
+}
+
+func TestHandler(t *testing.T) {
+	script := goscript.NewScript([]byte(handlerSource))
+
+	var order handlerOrder
+	onOrder, err := script.Handler("onOrder", &order)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	if err := onOrder(int64(7), []float64{1.5, 2}); err != nil {
+		t.Fatalf("Handler failed: %v", err)
+	}
+	if order.ID != 7 || order.Total != 3.5 || len(order.Tags) != 1 || order.Tags[0] != "new" {
+		t.Errorf("Unexpected order: %+v", order)
+	}
+	if err := onOrder(int64(8), []float64{}); err == nil || err.Error() != "empty order" {
+		t.Errorf("Expected the script error, got %v", err)
+	}
+
+	greet, err := goscript.Func[string](script, "greet")
+	if err != nil {
+		t.Fatalf("Failed to create func: %v", err)
+	}
+	if result, err := greet("ann"); err != nil || result != "hello ann" {
+		t.Errorf("Expected hello ann, got %q (%v)", result, err)
+	}
+
+	if _, err := script.Handler("missing", nil); err == nil || !strings.Contains(err.Error(), "function missing not found") {
+		t.Errorf("Expected a missing function error, got %v", err)
+	}
+	wrongType, _ := goscript.Func[int](script, "greet")
+	if _, err := wrongType("ann"); err == nil {
+		t.Errorf("Expected a result conversion error")
+	}
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+// scriptSetup configures a script run by runScript
+type scriptSetup struct {
+	// Declarations between the package clause and main, e.g., imports and
+	// types
+	prelude string
+
+	// Options of the script; nil keeps the defaults of NewScript
+	options *goscript.Options
+
+	// Go functions added to the script by name
+	functions map[string]interface{}
+}
+
+// runScript runs body as the main function of a script in package main
+func runScript(t *testing.T, body string, setup scriptSetup) (interface{}, error) {
+	t.Helper()
+	source := "package main\n\n"
+	if setup.prelude != "" {
+		source += setup.prelude + "\n\n"
+	}
+	source += "func main() {\n" + body + "\n}\n"
+
+	script := goscript.NewScript([]byte(source))
+	if setup.options != nil {
+		script = goscript.NewScriptWithOptions([]byte(source), *setup.options)
+	}
+	for name, fn := range setup.functions {
+		if err := script.AddGoFunction(name, fn); err != nil {
+			t.Fatalf("Failed to add function %s: %v", name, err)
+		}
+	}
+	return script.Run()
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lengzhao/goscript"
+)
+
+type hostConfig struct {
+	Name    string
+	Retries int
+	Timeout time.Duration
+	secret  string
+}
+
+func (c hostConfig) GetTimeout() int {
+	return int(c.Timeout / time.Second)
+}
+
+func (c *hostConfig) SetRetries(n int) {
+	c.Retries = n
+}
+
+func (c *hostConfig) Scale(factor float64, tags []string) string {
+	return strings.Repeat("x", int(factor*float64(c.Retries))) + strings.Join(tags, ",")
+}
+
+func (c *hostConfig) Split(s string) (string, string) {
+	parts := strings.SplitN(s, ":", 2)
+	return parts[0], parts[1]
+}
+
+func (c *hostConfig) Check(limit int) (bool, error) {
+	if c.Retries > limit {
+		return false, errors.New("too many retries")
+	}
+	return true, nil
+}
+
+func (c *hostConfig) Sum(values ...int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func TestRegisterObject(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	cfg.SetRetries(3)
+	key, value := cfg.Split("a:b")
+	ok := cfg.Check(5)
+	return []interface{}{
+		cfg.Name,
+		cfg.GetTimeout(),
+		cfg.Retries,
+		cfg.Scale(2, []string{"p", "q"}),
+		key + value,
+		ok,
+		cfg.Sum(1, 2, 3),
+	}
+}
+`))
+	cfg := &hostConfig{Name: "svc", Timeout: 5 * time.Second, secret: "s"}
+	if err := script.RegisterObject("cfg", cfg); err != nil {
+		t.Fatalf("Failed to register object: %v", err)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []interface{}{"svc", 5, 3, "xxxxxxp,q", "ab", true, 6}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("Value %d: expected %v (%T), got %v (%T)", i, expected[i], expected[i], values[i], values[i])
+		}
+	}
+	// Methods with pointer receivers update the registered object
+	if cfg.Retries != 3 {
+		t.Errorf("Expected the host object to be updated, got %d retries", cfg.Retries)
+	}
+}
+
+func TestRegisterObjectErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"method error", `
+	cfg.SetRetries(9)
+	return cfg.Check(5)`, "too many retries"},
+		{"argument conversion", `
+	return cfg.SetRetries("many")`, "cannot use many (string) as int"},
+		{"fractional integer", `
+	return cfg.SetRetries(1.5)`, "cannot use 1.5 as int"},
+		{"argument count", `
+	return cfg.GetTimeout(1)`, "wrong number of arguments in call to GetTimeout"},
+		{"unexported field", `
+	return cfg.secret`, "not accessible"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte("package main\n\nfunc main() {" + tt.body + "\n}\n"))
+			// A struct value is copied, so pointer receiver methods work too
+			if err := script.RegisterObject("cfg", hostConfig{Name: "svc"}); err != nil {
+				t.Fatalf("Failed to register object: %v", err)
+			}
+			_, err := script.Run()
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if err := goscript.NewScript(nil).RegisterObject("n", 1); err == nil {
+		t.Errorf("Expected an error registering a non-struct")
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+type authTokenKey struct{}
+
+func TestHostValuePassthrough(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return whoami()
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	script.WithValue(authTokenKey{}, "token-123")
+	script.AddContextFunction("whoami", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		token, ok := ctx.Value(authTokenKey{}).(string)
+		if !ok {
+			return nil, fmt.Errorf("no auth token")
+		}
+		return "user:" + token, nil
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "user:token-123" {
+		t.Errorf("Expected 'user:token-123', got %v", result)
+	}
+}
+
+func TestHostValueFromRunContext(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return requestID()
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	script.AddContextFunction("requestID", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return ctx.Value("request"), nil
+	})
+
+	ctx := context.WithValue(context.Background(), "request", "req-7")
+	result, err := script.RunContext(ctx)
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "req-7" {
+		t.Errorf("Expected 'req-7', got %v", result)
+	}
+}
+
+func TestHostValueNotVisibleToScript(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return token
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	script.WithValue("token", "secret")
+
+	if _, err := script.Run(); err == nil {
+		t.Error("Expected host value to be invisible to the script")
+	}
+}
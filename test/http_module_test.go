@@ -0,0 +1,121 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/builtin"
+)
+
+func TestHTTPModule(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Method", r.Method)
+			w.Write([]byte(r.Header.Get("Content-Type") + "|" + r.Header.Get("Authorization") + "|" + string(body)))
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/redirect":
+			http.Redirect(w, r, "http://example.com/", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	t.Cleanup(func() { builtin.UnregisterModule("http") })
+
+	run := func(body string) (interface{}, error) {
+		script := goscript.NewScript([]byte(`
+package main
+
+import (
+	"fmt"
+	"http"
+)
+
+var base = "` + server.URL + `"
+
+func main() {
+	` + body + `
+}
+`))
+		return script.Run()
+	}
+
+	builtin.RegisterModule("http", builtin.NewHTTPModule(builtin.HTTPOptions{
+		AllowedHosts:    []string{serverURL.Host},
+		MaxResponseSize: 50,
+		Timeout:         100 * time.Millisecond,
+	}))
+
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"get", `
+	resp := http.Get(base + "/echo")
+	return fmt.Sprint(resp.StatusCode, " ", resp.Header["X-Method"], " ", resp.Body)`, "200 GET ||"},
+		{"post", `
+	resp := http.Post(base+"/echo", "text/plain", "hello")
+	return resp.Body`, "text/plain||hello"},
+		{"request builder", `
+	req := http.NewRequest("PUT", base+"/echo", "data")
+	req.Header["Authorization"] = "Bearer t"
+	resp := http.Do(req)
+	return resp.Header["X-Method"] + " " + resp.Body`, "PUT |Bearer t|data"},
+		{"error status", `
+	resp := http.Get(base + "/missing")
+	return resp.StatusCode`, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := run(tt.body)
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"host not allowed", `return http.Get("http://example.com/")`, "host example.com is not allowed"},
+		{"scheme not allowed", `return http.Get("file:///etc/passwd")`, "unsupported scheme"},
+		{"redirect to host not allowed", `return http.Get(base + "/redirect")`, "host example.com is not allowed"},
+		{"response too large", `return http.Get(base + "/large")`, "response body exceeds 50 bytes"},
+		{"timeout", `return http.Get(base + "/slow")`, "deadline exceeded"},
+		{"do requires a request", `return http.Do("x")`, "requires an http.Request"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(tt.body)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// The zero options allow no host
+	builtin.RegisterModule("http", builtin.NewHTTPModule(builtin.HTTPOptions{}))
+	if _, err := run(`return http.Get(base + "/echo")`); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected requests to be denied, got %v", err)
+	}
+}
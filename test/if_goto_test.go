@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/lengzhao/goscript/compiler"
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
 	"github.com/lengzhao/goscript/parser"
-	"github.com/lengzhao/goscript/vm"
 )
 
 func TestIfWithGoto(t *testing.T) {
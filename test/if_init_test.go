@@ -0,0 +1,98 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestIfInitStatement(t *testing.T) {
+	source := `
+package main
+
+func score(n int) int {
+	return n * 3
+}
+
+func classify(n int) string {
+	if v := score(n); v > 20 {
+		return "high"
+	} else if w := v + 5; w > 15 {
+		return "medium"
+	} else {
+		return "low"
+	}
+}
+
+func main() {
+	return classify(10) + "," + classify(4) + "," + classify(1)
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "high,medium,low" {
+		t.Errorf("Expected high,medium,low, got %v", result)
+	}
+}
+
+func TestIfInitShadowsOuterVariable(t *testing.T) {
+	source := `
+package main
+
+func main() {
+	v := 1
+	if v := 100; v > 10 {
+		v = v + 1
+	}
+	return v
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 1 {
+		t.Errorf("Expected the outer v to stay 1, got %v", result)
+	}
+}
+
+func TestIfInitNotVisibleAfterIf(t *testing.T) {
+	source := `
+package main
+
+func main() {
+	if v := 5; v > 1 {
+	}
+	return v
+}
+`
+	_, err := goscript.NewScript([]byte(source)).Run()
+	if err == nil || !strings.Contains(err.Error(), "undefined: v") {
+		t.Errorf("Expected undefined variable error, got %v", err)
+	}
+}
+
+func TestForInitScopedToLoop(t *testing.T) {
+	source := `
+package main
+
+func main() {
+	i := 100
+	total := 0
+	for i := 0; i < 4; i++ {
+		total = total + i
+	}
+	return i + total
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 106 {
+		t.Errorf("Expected 106, got %v", result)
+	}
+}
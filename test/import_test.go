@@ -51,7 +51,7 @@ import "fmt"
 func main() {
     s := "hello world"
     upper := strings.ToUpper(s)
-    msg := fmt.Printf("Uppercase: %s", upper)
+    msg := fmt.Sprintf("Uppercase: %s", upper)
     return msg
 }
 `)
@@ -94,6 +94,70 @@ func main() {
 	}
 }
 
+func TestInstructionLimitAcrossCalls(t *testing.T) {
+	// The limit covers the whole execution: each call is short, but
+	// together they exceed it
+	scriptSource := `
+package test
+
+func step(x int) int {
+	return x + 1
+}
+
+func main() {
+	total := 0
+	for i := 0; i < 200; i++ {
+		total = step(total)
+	}
+	return total
+}
+`
+	script := goscript.NewScript([]byte(scriptSource))
+	script.SetMaxInstructions(500)
+	_, err := script.Run()
+	if err == nil || !strings.Contains(err.Error(), "maximum instruction limit exceeded") {
+		t.Fatalf("Expected the calls to exceed the limit together, got %v", err)
+	}
+
+	script.SetMaxInstructions(0)
+	result, err := script.Run()
+	if err != nil || result != 200 {
+		t.Fatalf("Expected 200 without a limit, got %v, %v", result, err)
+	}
+	if count := script.GetExecutionStats().InstructionCount; count < 1000 {
+		t.Errorf("Expected the count to include the calls, got %d", count)
+	}
+}
+
+func TestInstructionLimitInLoopForms(t *testing.T) {
+	// 空循环体和只有条件的循环同样受指令数限制保护
+	loops := map[string]string{
+		"empty infinite loop": `for {
+	}`,
+		"condition-only loop": `x := 0
+	for x < 10 {
+	}`,
+		"loop without condition": `for i := 0; ; i++ {
+	}`,
+	}
+	for name, loop := range loops {
+		t.Run(name, func(t *testing.T) {
+			script := goscript.NewScript([]byte(`
+package test
+
+func main() {
+	` + loop + `
+}
+`))
+			script.SetMaxInstructions(500)
+			_, err := script.RunContext(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "maximum instruction limit exceeded") {
+				t.Errorf("Expected instruction limit error, but got: %v", err)
+			}
+		})
+	}
+}
+
 func TestNormalExecutionWithinLimit(t *testing.T) {
 	// 测试正常脚本在指令数限制内能够正常执行
 	scriptSource := `
@@ -0,0 +1,240 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const shapesSource = `
+package main
+
+type Shape interface {
+	Area() float64
+}
+
+type Named interface {
+	Shape
+	Name() string
+}
+
+type Rect struct {
+	W float64
+	H float64
+}
+
+func (r Rect) Area() float64 {
+	return r.W * r.H
+}
+
+func (r *Rect) Name() string {
+	return "rect"
+}
+
+type Circle struct {
+	R float64
+}
+
+func (c Circle) Area() float64 {
+	return c.R * c.R * 3
+}
+
+func total(shapes []Shape) float64 {
+	sum := 0.0
+	for _, s := range shapes {
+		sum = sum + s.Area()
+	}
+	return sum
+}
+
+func describe(n Named) string {
+	return n.Name()
+}
+
+func main() {
+	var s Shape = Rect{W: 2, H: 3}
+	first := s.Area()
+	s = Circle{R: 1}
+	var n Named = &Rect{W: 1, H: 1}
+	return []interface{}{first, s.Area(), total([]Shape{Rect{W: 1, H: 2}, Circle{R: 1}}), describe(n)}
+}
+`
+
+func TestInterfaceDispatch(t *testing.T) {
+	result, err := goscript.NewScript([]byte(shapesSource)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if fmt.Sprint(result) != "[6 3 5 rect]" {
+		t.Errorf("Expected [6 3 5 rect], got %v", result)
+	}
+}
+
+func TestInterfaceMethodSets(t *testing.T) {
+	script := goscript.NewScript([]byte(shapesSource))
+	if err := script.Build(); err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+	methods := script.GetVM().MethodSet("Rect")
+	if strings.Join(methods, ",") != "Area,Name" {
+		t.Errorf("Expected the methods of Rect to be Area,Name, got %v", methods)
+	}
+
+	// Method sets are part of the serialized program
+	data, err := script.CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize program: %v", err)
+	}
+	loaded := goscript.NewScript(nil)
+	if _, err := loaded.LoadProgram(data); err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if methods := loaded.GetVM().MethodSet("Circle"); strings.Join(methods, ",") != "Area" {
+		t.Errorf("Expected the methods of Circle to be Area, got %v", methods)
+	}
+}
+
+func TestInterfaceNotImplementedAtCompileTime(t *testing.T) {
+	source := `
+package main
+
+type Named interface {
+	Name() string
+}
+
+type Circle struct {
+	R float64
+}
+
+func main() {
+	var n Named = Circle{R: 1}
+	return n.Name()
+}
+`
+	err := goscript.NewScript([]byte(source)).Build()
+	if err == nil {
+		t.Fatal("Expected a compile error")
+	}
+	if !strings.Contains(err.Error(), "Circle does not implement Named (missing method Name)") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestInterfaceNotImplementedAtRuntime(t *testing.T) {
+	source := `
+package main
+
+type Named interface {
+	Name() string
+}
+
+type Circle struct {
+	R float64
+}
+
+func anything() interface{} {
+	return Circle{R: 2}
+}
+
+func describe(n Named) string {
+	return n.Name()
+}
+
+func main() {
+	return describe(anything())
+}
+`
+	_, err := goscript.NewScript([]byte(source)).Run()
+	if err == nil {
+		t.Fatal("Expected a runtime error")
+	}
+	if !strings.Contains(err.Error(), "Circle does not implement Named (missing method Name)") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestInterfaceUndefinedMethod(t *testing.T) {
+	source := `
+package main
+
+type Named interface {
+	Name() string
+}
+
+func describe(n Named) int {
+	return n.Size()
+}
+
+func main() {
+	return 0
+}
+`
+	err := goscript.NewScript([]byte(source)).Build()
+	if err == nil || !strings.Contains(err.Error(), "n.Size undefined (type Named has no field or method Size)") {
+		t.Errorf("Expected n.Size undefined, got %v", err)
+	}
+}
+
+func TestPointerReceiverMethodSets(t *testing.T) {
+	const decls = `
+package main
+
+type Named interface {
+	Name() string
+}
+
+type Circle struct {
+	R float64
+}
+
+func (c *Circle) Name() string {
+	return "circle"
+}
+
+func anything(pointer bool) interface{} {
+	if pointer {
+		return &Circle{R: 2}
+	}
+	return Circle{R: 2}
+}
+
+func describe(n Named) string {
+	return n.Name()
+}
+`
+	err := goscript.NewScript([]byte(decls + `
+func main() {
+	var n Named = Circle{R: 1}
+	return n.Name()
+}
+`)).Build()
+	if err == nil || !strings.Contains(err.Error(), "Circle does not implement Named (method Name has pointer receiver)") {
+		t.Errorf("Expected a compile error, got %v", err)
+	}
+
+	_, err = goscript.NewScript([]byte(decls + `
+func main() {
+	return describe(anything(false))
+}
+`)).Run()
+	if err == nil || !strings.Contains(err.Error(), "Circle does not implement Named (method Name has pointer receiver)") {
+		t.Errorf("Expected a runtime error, got %v", err)
+	}
+
+	result, err := goscript.NewScript([]byte(decls + `
+func main() {
+	if _, isNamed := anything(false).(Named); isNamed {
+		return "value is Named"
+	}
+	return describe(anything(true))
+}
+`)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "circle" {
+		t.Errorf("Expected circle, got %v", result)
+	}
+}
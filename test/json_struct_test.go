@@ -0,0 +1,78 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const jsonStructSource = `
+package main
+
+import (
+	"fmt"
+	"json"
+)
+
+type Base struct {
+	ID int ` + "`json:\"id\"`" + `
+}
+
+type Item struct {
+	Name  string
+	Price float64
+}
+
+type Order struct {
+	Base
+	Customer string ` + "`json:\"customer\"`" + `
+	Note     string ` + "`json:\"note,omitempty\"`" + `
+	Secret   string ` + "`json:\"-\"`" + `
+	Items    []Item ` + "`json:\"items\"`" + `
+	Paid     bool
+	Extra    interface{}
+}
+
+func main() {
+	order := Order{Customer: "ann", Secret: "x", Items: []Item{Item{Name: "pen", Price: 1.5}}}
+	order.ID = 7
+	data := json.Marshal(order)
+
+	decoded := Order{}
+	json.Unmarshal(data, &decoded)
+	json.Unmarshal(` + "`{\"extra\": 3, \"paid\": true}`" + `, &decoded)
+	return data + " " + fmt.Sprintf("%T %d %s %T %.1f %v %T", decoded.Items[0], decoded.ID, decoded.Customer, decoded.Extra, decoded.Items[0].Price, decoded.Paid, decoded.ID)
+}
+`
+
+func TestJSONStructs(t *testing.T) {
+	result, err := goscript.NewScript([]byte(jsonStructSource)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := `{"id":7,"customer":"ann","items":[{"Name":"pen","Price":1.5}],"Paid":false,"Extra":null} Item 7 ann int 1.5 true int`
+	if result != expected {
+		t.Errorf("Expected %s, got %v", expected, result)
+	}
+}
+
+func TestJSONUnmarshalTypeMismatch(t *testing.T) {
+	_, err := goscript.NewScript([]byte(`
+package main
+
+import "json"
+
+type Point struct {
+	X int
+}
+
+func main() {
+	p := Point{}
+	json.Unmarshal(` + "`{\"X\": 1.5}`" + `, &p)
+}
+`)).Run()
+	if err == nil || !strings.Contains(err.Error(), "cannot unmarshal number 1.5 into int") {
+		t.Errorf("Expected a type mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/instruction"
+)
+
+const localsSource = `
+package main
+
+var x = 100
+
+func shadow(n int) []int {
+	a := x
+	x := n
+	if n > 0 {
+		x := x * 2
+		a = a + x
+	}
+	var count int
+	for i := 0; i < 3; i++ {
+		var fresh int
+		fresh = fresh + i
+		count = count + fresh
+	}
+	return []int{a, x, count}
+}
+
+func addressed() int {
+	v := 1
+	p := &v
+	*p = 5
+	return v
+}
+
+func captured() int {
+	c := 0
+	inc := func() {
+		c = c + 1
+	}
+	inc()
+	inc()
+	return c
+}
+
+func main() {
+	return []interface{}{shadow(4), addressed(), captured(), x}
+}
+`
+
+func TestLocalSlots(t *testing.T) {
+	script := goscript.NewScript([]byte(localsSource))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if fmt.Sprint(result) != "[[108 4 3] 5 2 100]" {
+		t.Errorf("Expected [[108 4 3] 5 2 100], got %v", result)
+	}
+
+	// Locals only loaded and stored use slots; addressed and captured ones
+	// keep name-based access
+	cases := []struct {
+		key, name string
+		slotted   bool
+	}{
+		{"main.func.shadow", "x", true},
+		{"main.func.shadow", "fresh", true},
+		{"main.func.addressed", "p", true},
+		{"main.func.addressed", "v", false},
+		{"main.func.captured", "c", false},
+	}
+	for _, tc := range cases {
+		instructions, _ := script.GetVM().GetInstructionSet(tc.key)
+		slotted := false
+		for _, instr := range instructions {
+			if (instr.Op == instruction.OpLoadLocal || instr.Op == instruction.OpStoreLocal) && instr.Arg2 == tc.name {
+				slotted = true
+			}
+		}
+		if slotted != tc.slotted {
+			t.Errorf("%s: expected %s in a slot to be %v", tc.key, tc.name, tc.slotted)
+		}
+	}
+}
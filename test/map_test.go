@@ -0,0 +1,141 @@
+package test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMapLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"string keys", `
+	ages := map[string]int{"ann": 30, "bob": 25}
+	return ages["ann"] + ages["bob"]`, 55},
+		{"int keys", `
+	names := map[int]string{1: "one", 2: "two"}
+	return names[2]`, "two"},
+		{"bool keys", `
+	labels := map[bool]string{true: "yes", false: "no"}
+	return labels[1 > 2]`, "no"},
+		{"missing key", `
+	names := map[int]string{1: "one"}
+	return names[3] == ""`, true},
+		{"missing key increments from zero", `
+	counts := map[string]int{}
+	counts["x"]++
+	counts["x"] += 2
+	return counts["x"]`, 3},
+		{"missing key of made map", `
+	m := make(map[string]float64)
+	return m["a"] + 0.5`, 0.5},
+		{"missing key with comma ok", `
+	m := map[string]bool{}
+	v, ok := m["a"]
+	return v == false && ok == false`, true},
+		{"missing key of nested map", `
+	m := map[string]map[string]int{"a": {}}
+	m["a"]["x"] += 4
+	return m["a"]["x"] + m["a"]["y"]`, 4},
+		{"missing key of parameter", `
+	get := func(m map[string]string, k string) string {
+		return m[k] + "!"
+	}
+	return get(map[string]string{}, "a")`, "!"},
+		{"index evaluated once", `
+	calls := 0
+	key := func() string {
+		calls++
+		return "k"
+	}
+	m := map[string]int{}
+	m[key()] += 5
+	m[key()]++
+	return m["k"]*10 + calls`, 62},
+		{"field receiver evaluated once", `
+	type Box struct {
+		N int
+	}
+	calls := 0
+	b := &Box{}
+	get := func() *Box {
+		calls++
+		return b
+	}
+	get().N += 3
+	get().N++
+	return b.N*10 + calls`, 42},
+		{"assign and update", `
+	counts := map[int]int{}
+	counts[7] = 1
+	counts[7] += 2
+	counts[7]++
+	return counts[7]`, 4},
+		{"len", `
+	m := map[string]int{"a": 1, "b": 2}
+	n := map[float64]string{1.5: "x"}
+	return len(m)*10 + len(n)`, 21},
+		{"delete", `
+	m := map[int]string{1: "one", 2: "two"}
+	s := map[string]int{"a": 1}
+	delete(m, 1)
+	delete(s, "a")
+	return len(m)*10 + len(s)`, 10},
+		{"make", `
+	m := make(map[int]int)
+	s := make(map[string]bool, 10)
+	m[1] = 5
+	s["x"] = true
+	return m[1] + len(s)`, 6},
+		{"nested values", `
+	groups := map[string][]int{"odd": {1, 3}, "even": {2}}
+	return len(groups["odd"]) + groups["even"][0]`, 4},
+		{"computed keys", `
+	k := 3
+	m := map[int]int{k * 2: k}
+	return m[6]`, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMapErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"duplicate key", `
+	m := map[string]int{"a": 1, "a": 2}
+	return m`, "duplicate key"},
+		{"missing key", `
+	m := map[string]int{1}
+	return m`, "missing key"},
+		{"unhashable key", `
+	m := map[int]int{}
+	m[[]int{1}] = 1
+	return m`, "invalid map key type"},
+		{"wrong key type for string map", `
+	m := map[string]int{}
+	m[1] = 1
+	return m`, "map key must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := runScript(t, tt.body, scriptSetup{}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
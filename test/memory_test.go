@@ -0,0 +1,158 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// Whether the allocation is refused before it is made, leaving the
+		// usage within the limit
+		reserved bool
+	}{
+		{"append", `
+	var items []int
+	for i := 0; i < 100000; i++ {
+		items = append(items, i)
+	}`, true},
+		{"map", `
+	m := map[int]int{}
+	for i := 0; i < 100000; i++ {
+		m[i] = i
+	}`, false},
+		{"string", `
+	s := "x"
+	for i := 0; i < 100; i++ {
+		s = s + s
+	}`, true},
+		{"conversion", `
+	s := "x"
+	for i := 0; i < 10; i++ {
+		s = s + s
+	}
+	n := 0
+	for i := 0; i < 100; i++ {
+		n += len([]byte(s))
+	}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte("package main\n\nfunc main() {" + tt.body + "\n}\n"))
+			script.SetMaxInstructions(0)
+			script.SetMaxMemory(64 * 1024)
+			_, err := script.Run()
+			if !errors.Is(err, goscript.ErrMemoryLimit) {
+				t.Fatalf("Expected ErrMemoryLimit, got %v", err)
+			}
+			usage := script.MemoryUsage()
+			if tt.reserved && usage > 64*1024 {
+				t.Errorf("Expected the allocation to be refused within the limit, got usage %d", usage)
+			}
+			if !tt.reserved && usage <= 64*1024 {
+				t.Errorf("Expected usage above the limit, got %d", usage)
+			}
+		})
+	}
+}
+
+func TestMemoryLimitHostResults(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	total := 0
+	for i := 0; i < 100; i++ {
+		total += len(makeBuffer(1000))
+	}
+	return total
+}
+`))
+	script.AddFunction("makeBuffer", func(args ...interface{}) (interface{}, error) {
+		return make([]interface{}, args[0].(int)), nil
+	})
+	script.SetMaxMemory(64 * 1024)
+	if _, err := script.Run(); !errors.Is(err, goscript.ErrMemoryLimit) {
+		t.Fatalf("Expected ErrMemoryLimit, got %v", err)
+	}
+
+	// Results sharing an argument's memory are not counted again
+	script = goscript.NewScript([]byte(`
+package main
+
+func main() {
+	items := make([]int, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		items = append(items, i)
+	}
+	return len(items)
+}
+`))
+	script.SetMaxInstructions(0)
+	script.SetMaxMemory(64 * 1024)
+	if result, err := script.Run(); err != nil || result != 1000 {
+		t.Fatalf("Expected 1000, got %v (%v)", result, err)
+	}
+}
+
+func TestMemoryLimitMake(t *testing.T) {
+	// The slice is refused before it is allocated
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	items := make([]int, 1000000)
+	return len(items)
+}
+`))
+	script.SetMaxMemory(1 << 20)
+	if _, err := script.Run(); !errors.Is(err, goscript.ErrMemoryLimit) {
+		t.Fatalf("Expected ErrMemoryLimit, got %v", err)
+	}
+	if usage := script.MemoryUsage(); usage > 1<<20 {
+		t.Errorf("Expected the slice not to be allocated, got usage %d", usage)
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	script := goscript.NewScriptWithOptions([]byte(`
+package main
+
+type Point struct {
+	X int
+	Y int
+}
+
+func main() {
+	items := make([]int, 0, 10)
+	items = append(items, 1, 2, 3)
+	m := map[string]int{"a": 1}
+	m["b"] = 2
+	p := Point{X: 1, Y: 2}
+	return len(items) + len(m) + p.X
+}
+`), goscript.Options{MaxMemory: 1024 * 1024})
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 6 {
+		t.Errorf("Expected 6, got %v", result)
+	}
+	first := script.MemoryUsage()
+	if first <= 0 {
+		t.Fatalf("Expected a positive memory usage, got %d", first)
+	}
+
+	// Usage is counted per execution
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to rerun script: %v", err)
+	}
+	if usage := script.MemoryUsage(); usage != first {
+		t.Errorf("Expected usage %d on rerun, got %d", first, usage)
+	}
+}
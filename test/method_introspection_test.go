@@ -0,0 +1,70 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const introspectionSource = `
+package main
+
+type Rectangle struct {
+	width  int
+	height int
+}
+
+func (r Rectangle) Area() int {
+	return r.width * r.height
+}
+
+func (r *Rectangle) Scale(factor int) {
+	r.width = r.width * factor
+	r.height = r.height * factor
+}
+
+func main() {
+	rect := Rectangle{width: 2, height: 3}
+	callMethod(rect, "Scale", 2)
+	return []interface{}{methods(rect), callMethod(rect, "Area")}
+}
+`
+
+func TestMethodIntrospection(t *testing.T) {
+	script := goscript.NewScript([]byte(introspectionSource))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		t.Fatalf("Expected two results, got %v", result)
+	}
+	if !reflect.DeepEqual(values[0], []interface{}{"Area", "Scale"}) {
+		t.Errorf("Expected [Area Scale], got %v", values[0])
+	}
+	if values[1] != 24 {
+		t.Errorf("Expected area 24 after scaling, got %v", values[1])
+	}
+}
+
+func TestCallMethodUnknownMethod(t *testing.T) {
+	scriptSource := `
+package main
+
+type Point struct {
+	x int
+}
+
+func main() {
+	return callMethod(Point{x: 1}, "Missing")
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	if _, err := script.Run(); err == nil {
+		t.Error("Expected error when calling an undefined method")
+	}
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestBuiltinModuleConstants(t *testing.T) {
+	// 64-bit limits are ints where they fit an int
+	var maxInt64, maxUint32 interface{} = int64(math.MaxInt64), uint64(math.MaxUint32)
+	if strconv.IntSize == 64 {
+		maxInt64, maxUint32 = int(maxInt64.(int64)), int(maxUint32.(uint64))
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		imports  string
+		expected interface{}
+	}{
+		{"Pi", "math.Pi", `"math"`, math.Pi},
+		{"MaxInt", "math.MaxInt", `"math"`, math.MaxInt},
+		{"MinInt32", "math.MinInt32", `"math"`, math.MinInt32},
+		{"expression", "math.MaxInt8 + 1", `"math"`, 128},
+		{"IntSize", "strconv.IntSize", `"strconv"`, strconv.IntSize},
+		{"aliased import", "m.E", `m "math"`, math.E},
+		{"MaxInt64", "math.MaxInt64", `"math"`, maxInt64},
+		{"MaxUint32", "math.MaxUint32", `"math"`, maxUint32},
+		{"MaxUint64", "math.MaxUint64", `"math"`, uint64(math.MaxUint64)},
+		{"constant expression", "math.MinInt64 + 1 < 0", `"math"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := `
+package main
+
+import ` + tt.imports + `
+
+func main() {
+	return ` + tt.expr + `
+}
+`
+			result, err := goscript.NewScript([]byte(source)).Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+func TestModuleConstantsWithFunctions(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "math"
+
+func main() {
+	r := 2.0
+	return math.Max(math.Pi*r*r, 1.0)
+}
+`))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != math.Pi*4 {
+		t.Errorf("Expected %v, got %v", math.Pi*4, result)
+	}
+}
+
+func TestRegisteredModuleConstants(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "config"
+
+func main() {
+	return config.Name + " v" + config.Version
+}
+`))
+	script.RegisterModule("config", func(entrypoint string, args ...interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	script.RegisterModuleConstants("config", map[string]interface{}{
+		"Name":    "rules",
+		"Version": "1.2",
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "rules v1.2" {
+		t.Errorf("Expected rules v1.2, got %v", result)
+	}
+}
+
+func TestUnknownModuleMembers(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"constant", "math.Pie", "undefined: math.Pie"},
+		{"function value", "m.Sqrtt", "undefined: m.Sqrtt"},
+		{"call", "m.Sqrtt(2)", "undefined: m.Sqrtt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := `
+package main
+
+import "math"
+import m "math"
+
+func main() {
+	return ` + tt.expr + `
+}
+`
+			_, err := goscript.NewScript([]byte(source)).Run()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected a compile error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/types"
+)
+
+// counterModule is a module with per-execution state
+type counterModule struct {
+	count  int
+	inited bool
+	closed *int
+}
+
+func (m *counterModule) Init() error {
+	m.inited = true
+	return nil
+}
+
+func (m *counterModule) Call(entrypoint string, args ...interface{}) (interface{}, error) {
+	if !m.inited {
+		return nil, fmt.Errorf("module used before Init")
+	}
+	switch entrypoint {
+	case "Next":
+		m.count++
+		return m.count, nil
+	default:
+		return nil, fmt.Errorf("function %s not found in module counter", entrypoint)
+	}
+}
+
+func (m *counterModule) Close() error {
+	*m.closed++
+	return nil
+}
+
+func TestModuleFactoryPerExecutionState(t *testing.T) {
+	scriptSource := `
+package main
+
+import "counter"
+
+func main() {
+	counter.Next()
+	counter.Next()
+	return counter.Next()
+}
+`
+
+	created := 0
+	closed := 0
+	script := goscript.NewScript([]byte(scriptSource))
+	script.RegisterModuleFactory("counter", func() types.ModuleInstance {
+		created++
+		return &counterModule{closed: &closed}
+	})
+
+	for run := 1; run <= 2; run++ {
+		result, err := script.Run()
+		if err != nil {
+			t.Fatalf("Run %d failed: %v", run, err)
+		}
+		// Each execution starts from a fresh instance
+		if result != 3 {
+			t.Errorf("Run %d: expected 3, got %v", run, result)
+		}
+		if created != run || closed != run {
+			t.Errorf("Run %d: expected %d created/closed, got %d/%d", run, run, created, closed)
+		}
+	}
+}
+
+func TestModuleFactoryNotInstantiatedWhenUnused(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return 1
+}
+`
+
+	created := 0
+	script := goscript.NewScript([]byte(scriptSource))
+	script.RegisterModuleFactory("counter", func() types.ModuleInstance {
+		created++
+		return &counterModule{closed: new(int)}
+	})
+
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if created != 0 {
+		t.Errorf("Expected no instance for an unused module, got %d", created)
+	}
+}
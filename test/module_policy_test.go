@@ -0,0 +1,107 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestModulePolicy(t *testing.T) {
+	source := []byte(`
+package main
+
+import "strings"
+import "json"
+
+func main() {
+	return json.Marshal(strings.ToUpper("a"))
+}
+`)
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		wantErr bool
+	}{
+		{"no policy", nil, nil, false},
+		{"allowed", []string{"strings", "json"}, nil, false},
+		{"not in allowlist", []string{"strings"}, nil, true},
+		{"empty allowlist", []string{}, nil, true},
+		{"denied", nil, []string{"json"}, true},
+		{"denied wins", []string{"strings", "json"}, []string{"json"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript(source)
+			script.SetAllowedModules(tt.allowed)
+			script.SetDeniedModules(tt.denied)
+			_, err := script.Run()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Failed to run script: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, goscript.ErrModuleNotAllowed) {
+				t.Fatalf("Expected ErrModuleNotAllowed, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "script.go:") {
+				t.Errorf("Expected the position of the import, got %v", err)
+			}
+		})
+	}
+}
+
+func TestModulePolicyAtRunTime(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "json"
+
+func main() {
+	return json.Marshal(1)
+}
+`))
+	if result, err := script.Run(); err != nil || result != "1" {
+		t.Fatalf("Expected \"1\", got %v, %v", result, err)
+	}
+
+	// A policy set after compilation applies to the next execution
+	script.SetDeniedModules([]string{"json"})
+	if _, err := script.Run(); !errors.Is(err, goscript.ErrModuleNotAllowed) {
+		t.Errorf("Expected ErrModuleNotAllowed, got %v", err)
+	}
+}
+
+func TestModulePolicyScriptModules(t *testing.T) {
+	resolver := func(importPath string) ([]byte, bool, error) {
+		if importPath != "lib/codec" {
+			return nil, false, nil
+		}
+		return []byte(`
+package codec
+
+import "json"
+
+func Encode(v interface{}) string {
+	return json.Marshal(v)
+}
+`), true, nil
+	}
+	script := goscript.NewScript([]byte(`
+package main
+
+import "lib/codec"
+
+func main() {
+	return codec.Encode(1)
+}
+`))
+	script.SetImportResolver(resolver)
+	script.SetDeniedModules([]string{"json"})
+	if _, err := script.Run(); !errors.Is(err, goscript.ErrModuleNotAllowed) {
+		t.Errorf("Expected the script module to inherit the policy, got %v", err)
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"testing"
 
 	"github.com/lengzhao/goscript/builtin"
-	"github.com/lengzhao/goscript/compiler"
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
 	"github.com/lengzhao/goscript/parser"
-	"github.com/lengzhao/goscript/vm"
 )
 
 // TestModuleVsMethodCall tests the distinction between module calls and method calls
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultipleAssignment(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"swap", `
+	a, b := 1, 2
+	a, b = b, a
+	return a*10 + b`, 21},
+		{"mixed define reuses existing variable", `
+	a := 1
+	a, b := 5, 6
+	return a + b`, 11},
+		{"mixed define in inner scope shadows", `
+	a := 1
+	{
+		a, b := 5, 6
+		a = a + b
+	}
+	return a`, 1},
+		{"blank identifier", `
+	_, b := 1, 2
+	return b`, 2},
+		{"parameter reused", `
+	return f(3)
+}
+
+func f(n int) int {
+	n, m := n+1, 10
+	return n + m`, 14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestShortVarDeclErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  string
+	}{
+		{"no new variables", `
+	a, b := 1, 2
+	a, b := 3, 4
+	return a + b`, "no new variables on left side of :="},
+		{"single redeclaration", `
+	a := 1
+	a := 2
+	return a`, "no new variables on left side of :="},
+		{"repeated name", `
+	a, a := 1, 2
+	return a`, "a repeated on left side of :="},
+		{"count mismatch", `
+	a, b := 1, 2, 3
+	return a + b`, "assignment mismatch: 2 variables but 3 values"},
+		{"single-valued call", `
+	a, err := len("x")
+	return a`, "assignment mismatch: 2 variables but len() returns 1 value"},
+		{"var redeclared", `
+	var a = 1
+	var a = 2
+	return a`, "a redeclared in this block"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, scriptSetup{})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestScriptWithSeveralFiles(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "fmt"
+
+func main() {
+	s := Stack{}
+	s.Push(2)
+	s.Push(3)
+	return fmt.Sprint(sum(s.items)) + label
+}
+`))
+	script.AddFile("stack.gs", []byte(`
+package main
+
+type Stack struct {
+	items []int
+}
+
+func (s *Stack) Push(v int) {
+	s.items = append(s.items, v)
+}
+`))
+	script.AddFile("util.gs", []byte(`
+package main
+
+import "fmt"
+
+var label = fmt.Sprint("!")
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total = total + v
+	}
+	return total
+}
+`))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "5!" {
+		t.Errorf("Expected 5!, got %v", result)
+	}
+}
+
+func TestScriptFilesOfDifferentPackages(t *testing.T) {
+	script := goscript.NewScript([]byte("package main\n\nfunc main() {\n\treturn 1\n}\n"))
+	script.AddFile("other.gs", []byte("package other\n"))
+	_, err := script.Run()
+	if err == nil || !strings.Contains(err.Error(), "found packages main (script.go) and other (other.gs)") {
+		t.Errorf("Expected a package mismatch error, got %v", err)
+	}
+}
+
+func TestScriptFromDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.gs": `
+package main
+
+import "lib/pricing"
+
+func main() {
+	return pricing.Total(100)
+}
+`,
+		"lib/pricing/pricing.gs": `
+package pricing
+
+import "lib/tax"
+
+func Total(amount int) int {
+	return amount + tax.Of(amount) + fee
+}
+`,
+		"lib/pricing/fee.gs": `
+package pricing
+
+var fee = 5
+`,
+		"lib/tax/tax.gs": `
+package tax
+
+func Of(amount int) int {
+	return amount / 10
+}
+`,
+		"notes.txt": "not a script",
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script, err := goscript.NewScriptFromDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to load scripts: %v", err)
+	}
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 115 {
+		t.Errorf("Expected 115, got %v", result)
+	}
+
+	// Compile errors report the file they occur in
+	os.WriteFile(filepath.Join(dir, "lib", "tax", "tax.gs"), []byte("package tax\n\nfunc Of(amount int) int {\n\treturn amount +\n}\n"), 0o644)
+	script, err = goscript.NewScriptFromDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to load scripts: %v", err)
+	}
+	if _, err := script.Run(); err == nil || !strings.Contains(err.Error(), "lib/tax/tax.gs:5") {
+		t.Errorf("Expected an error in lib/tax/tax.gs, got %v", err)
+	}
+}
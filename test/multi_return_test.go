@@ -0,0 +1,117 @@
+package test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const divideSource = `
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func divide(a int, b int) (int, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+func describe(a int, b int) string {
+	q, err := divide(a, b)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return fmt.Sprintf("%d", q)
+}
+
+func wrap(a int, b int) (int, error) {
+	q, err := divide(a, b)
+	if err != nil {
+		return 0, fmt.Errorf("wrap: %w", err)
+	}
+	return q, nil
+}
+
+func main() (string, string) {
+	return describe(10, 2), describe(1, 0)
+}
+`
+
+func TestMultipleReturnValues(t *testing.T) {
+	script := goscript.NewScript([]byte(divideSource))
+
+	results, err := script.RunResults()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []interface{}{"5", "error: division by zero"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v", expected, results)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if _, ok := result.(goscript.Tuple); !ok {
+		t.Errorf("Expected a tuple from Run, got %T", result)
+	}
+
+	results, err = script.CallFunctionResults("divide", 7, 2)
+	if err != nil {
+		t.Fatalf("Failed to call divide: %v", err)
+	}
+	if !reflect.DeepEqual(results, []interface{}{3, nil}) {
+		t.Errorf("Expected [3 <nil>], got %v", results)
+	}
+
+	results, err = script.CallFunctionResults("wrap", 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to call wrap: %v", err)
+	}
+	if wrapped, ok := results[1].(error); !ok || wrapped.Error() != "wrap: division by zero" {
+		t.Errorf("Expected a wrapped error, got %v", results[1])
+	}
+}
+
+func TestMultipleReturnErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		errMsg string
+	}{
+		{"too few variables", `
+func pair() (int, int) {
+	return 1, 2
+}
+
+func main() {
+	a, b, c := pair()
+	return a + b + c
+}`, "assignment mismatch: 3 variables but pair() returns 2 values"},
+		{"host function result", `
+func main() {
+	a, b := host()
+	return a
+}`, "assignment mismatch: 2 variables but 1 value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte("package main\n" + tt.source))
+			script.AddFunction("host", func(args ...interface{}) (interface{}, error) {
+				return 1, nil
+			})
+			_, err := script.Run()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const jsonNumberSource = `
+package main
+
+import "json"
+
+func main() {
+	data := json.Unmarshal(input())
+	return data["count"]
+}
+`
+
+func runJSONNumberScript(t *testing.T, mode goscript.NumberMode, input string) interface{} {
+	t.Helper()
+	script := goscript.NewScript([]byte(jsonNumberSource))
+	script.SetNumberMode(mode)
+	addHostValue(script, "input", input)
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	return result
+}
+
+func TestNumberModeJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     goscript.NumberMode
+		input    string
+		expected interface{}
+	}{
+		{"default float64", goscript.NumberFloat64, `{"count": 3}`, float64(3)},
+		{"preserve int", goscript.NumberPreserveInt, `{"count": 3}`, 3},
+		{"preserve int keeps floats", goscript.NumberPreserveInt, `{"count": 1.5}`, 1.5},
+		{"decimal string for big int", goscript.NumberDecimalString, `{"count": 123456789012345678901}`, "123456789012345678901"},
+		{"decimal string for fraction", goscript.NumberDecimalString, `{"count": 0.1}`, "0.1"},
+		{"decimal string keeps small int", goscript.NumberDecimalString, `{"count": 7}`, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runJSONNumberScript(t, tt.mode, tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+func TestNumberModeHostValues(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return small() + 1
+}
+`
+
+	// Without conversion an int64 from the host cannot mix with script ints
+	script := goscript.NewScript([]byte(scriptSource))
+	addHostValue(script, "small", int64(41))
+	if _, err := script.Run(); err == nil {
+		t.Error("Expected int64 + int to fail in the default mode")
+	}
+
+	script = goscript.NewScriptWithOptions([]byte(scriptSource), goscript.Options{
+		MaxInstructions: 10000,
+		NumberMode:      goscript.NumberPreserveInt,
+	})
+	addHostValue(script, "small", int64(41))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 42 {
+		t.Errorf("Expected 42, got %v (%T)", result, result)
+	}
+}
+
+func TestNumberModeLargeUnsigned(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return big()
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	script.SetNumberMode(goscript.NumberDecimalString)
+	addHostValue(script, "big", uint64(math.MaxUint64))
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "18446744073709551615" {
+		t.Errorf("Expected exact decimal string, got %v (%T)", result, result)
+	}
+}
+
+func TestNumberModeHostValuesAreCopied(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	values := list()
+	return values
+}
+`
+
+	values := []interface{}{int64(1), "a"}
+	script := goscript.NewScript([]byte(scriptSource))
+	script.SetNumberMode(goscript.NumberPreserveInt)
+	addHostValue(script, "list", values)
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	converted, ok := result.([]interface{})
+	if !ok || len(converted) != 2 || converted[0] != 1 {
+		t.Errorf("Expected [1 a] with an int, got %#v", result)
+	}
+	if values[0] != int64(1) {
+		t.Errorf("Expected the host slice to be left alone, got %#v", values)
+	}
+}
+
+func TestNumberModeResults(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return values["count"]
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	script.SetNumberMode(goscript.NumberPreserveInt)
+	values := map[string]interface{}{"count": int16(3)}
+	if err := script.AddVariable("values", values); err != nil {
+		t.Fatalf("Failed to add variable: %v", err)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 3 {
+		t.Errorf("Expected 3 as an int, got %v (%T)", result, result)
+	}
+	if values["count"] != int16(3) {
+		t.Errorf("Expected the host map to be left alone, got %#v", values)
+	}
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const outputSource = `
+package main
+
+import (
+	"fmt"
+	"greeter"
+)
+
+func main() {
+	println("a", 1)
+	print("b")
+	fmt.Println("c", true)
+	fmt.Printf("%s-%d", "d", 2)
+	greeter.Greet("e")
+	return 0
+}
+`
+
+func outputResolver(importPath string) ([]byte, bool, error) {
+	if importPath != "greeter" {
+		return nil, false, nil
+	}
+	return []byte(`
+package greeter
+
+import "fmt"
+
+func Greet(name string) {
+	fmt.Println("hello", name)
+}
+`), true, nil
+}
+
+func TestScriptOutput(t *testing.T) {
+	script := goscript.NewScript([]byte(outputSource))
+	script.SetImportResolver(outputResolver)
+	var stdout bytes.Buffer
+	script.SetStdout(&stdout)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := "a 1\nb\nc true\nd-2hello e\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, stdout.String())
+	}
+
+	// The output can be changed between executions
+	var second bytes.Buffer
+	script.SetStdout(&second)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script again: %v", err)
+	}
+	if second.String() != expected || stdout.String() != expected {
+		t.Errorf("Expected the second run to print to the new writer only, got %q and %q", stdout.String(), second.String())
+	}
+}
+
+func TestRunnerOutput(t *testing.T) {
+	script := goscript.NewScript([]byte(outputSource))
+	script.SetImportResolver(outputResolver)
+	var stdout bytes.Buffer
+	script.SetStdout(&stdout)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+
+	runner, err := script.NewRunner()
+	if err != nil {
+		t.Fatalf("Failed to create runner: %v", err)
+	}
+	var runnerOut bytes.Buffer
+	runner.SetStdout(&runnerOut)
+	if _, err := runner.Run(); err != nil {
+		t.Fatalf("Failed to run runner: %v", err)
+	}
+	expected := "a 1\nb\nc true\nd-2hello e\n"
+	if runnerOut.String() != expected {
+		t.Errorf("Expected the runner output %q, got %q", expected, runnerOut.String())
+	}
+	if stdout.String() != expected {
+		t.Errorf("Expected the script output to be unchanged, got %q", stdout.String())
+	}
+}
+
+func TestDebugOutputGoesToStderr(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	println("out")
+	return 1
+}
+`))
+	var stdout, stderr bytes.Buffer
+	script.SetStdout(&stdout)
+	script.SetStderr(&stderr)
+	script.SetDebug(true)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if stdout.String() != "out\n" {
+		t.Errorf("Expected only the script output on stdout, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "Executing instruction") {
+		t.Errorf("Expected the debug trace on stderr, got %q", stderr.String())
+	}
+}
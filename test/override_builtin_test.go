@@ -0,0 +1,85 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestOverrideBuiltinModuleFunction(t *testing.T) {
+	scriptSource := `
+package main
+
+import "strings"
+
+func main() {
+	return strings.ToUpper("hello")
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	err := script.OverrideBuiltin("strings", "ToUpper", func(args ...interface{}) (interface{}, error) {
+		return "tenant:" + strings.ToUpper(args[0].(string)), nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to override builtin: %v", err)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "tenant:HELLO" {
+		t.Errorf("Expected overridden result, got %v", result)
+	}
+
+	overrides := script.ActiveOverrides()
+	if len(overrides) != 1 || overrides[0] != "strings.ToUpper" {
+		t.Errorf("Expected [strings.ToUpper], got %v", overrides)
+	}
+}
+
+func TestOverrideBuiltinUniverseFunction(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return len("abc")
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	err := script.OverrideBuiltin("", "len", func(args ...interface{}) (interface{}, error) {
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to override builtin: %v", err)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 42 {
+		t.Errorf("Expected 42, got %v", result)
+	}
+}
+
+func TestOverrideBuiltinUnknown(t *testing.T) {
+	script := goscript.NewScript([]byte{})
+	fn := func(args ...interface{}) (interface{}, error) { return nil, nil }
+
+	if err := script.OverrideBuiltin("fmt", "NoSuchFunc", fn); err == nil {
+		t.Error("Expected error for unknown module function")
+	}
+	if err := script.OverrideBuiltin("nosuchmodule", "Println", fn); err == nil {
+		t.Error("Expected error for unknown module")
+	}
+	if err := script.OverrideBuiltin("", "nosuch", fn); err == nil {
+		t.Error("Expected error for unknown builtin")
+	}
+	if len(script.ActiveOverrides()) != 0 {
+		t.Errorf("Expected no active overrides, got %v", script.ActiveOverrides())
+	}
+}
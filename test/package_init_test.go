@@ -0,0 +1,135 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestPackageVariablesInitializedInDependencyOrder(t *testing.T) {
+	source := `
+package main
+
+var total = double(base) + offset
+var base = 20
+var offset int
+
+func double(n int) int {
+	return n * 2
+}
+
+func main() {
+	return total
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 40 {
+		t.Errorf("Expected 40, got %v", result)
+	}
+}
+
+func TestInitFunctionsRunBeforeMain(t *testing.T) {
+	source := `
+package main
+
+import "strings"
+
+var steps = []string{}
+
+func init() {
+	steps = append(steps, "first")
+}
+
+func init() {
+	steps = append(steps, "second")
+}
+
+func main() {
+	steps = append(steps, "main")
+	return strings.Join(steps, ",")
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "first,second,main" {
+		t.Errorf("Expected first,second,main, got %v", result)
+	}
+}
+
+func TestPackageVariablesVisibleToAllFunctions(t *testing.T) {
+	source := `
+package main
+
+var counter int
+
+func bump() {
+	counter = counter + 1
+}
+
+func main() {
+	counter := 100
+	bump()
+	bump()
+	return counter + readCounter()
+}
+
+func readCounter() int {
+	return counter * 1000
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	// main's local counter shadows the package variable only in main
+	if result != 2100 {
+		t.Errorf("Expected 2100, got %v", result)
+	}
+}
+
+func TestPackageInitializationCycle(t *testing.T) {
+	source := `
+package main
+
+var a = next()
+var b = a + 1
+
+func next() int {
+	return b
+}
+
+func main() {
+	return a
+}
+`
+	err := goscript.NewScript([]byte(source)).Build()
+	if err == nil {
+		t.Fatal("Expected an initialization cycle to be a compile error")
+	}
+	if !strings.Contains(err.Error(), "initialization cycle for a") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestInitCannotBeCalled(t *testing.T) {
+	source := `
+package main
+
+func init() {
+}
+
+func main() {
+	init()
+}
+`
+	err := goscript.NewScript([]byte(source)).Build()
+	if err == nil || !strings.Contains(err.Error(), "undefined: init") {
+		t.Errorf("Expected undefined: init, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const pipelineSource = `
+package main
+
+func double(x int) int {
+	return x * 2
+}
+
+func increment(x int) int {
+	return x + 1
+}
+
+func fail(x int) int {
+	return x / 0
+}
+
+func main() {
+	return 0
+}
+`
+
+func TestPipelineChainsStages(t *testing.T) {
+	script := goscript.NewScript([]byte(pipelineSource))
+
+	result, stats, err := script.Pipeline([]string{"double", "increment", "main.func.double"}, 5)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if result != 22 {
+		t.Errorf("Expected 22, got %v", result)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("Expected 3 stage stats, got %d", len(stats))
+	}
+	for i, stage := range stats {
+		if stage.InstructionCount == 0 {
+			t.Errorf("Stage %d (%s) reported no instructions", i, stage.Entry)
+		}
+	}
+	if stats[1].Entry != "increment" {
+		t.Errorf("Expected stage 1 entry 'increment', got %s", stats[1].Entry)
+	}
+}
+
+func TestPipelineShortCircuitsOnError(t *testing.T) {
+	script := goscript.NewScript([]byte(pipelineSource))
+
+	_, stats, err := script.Pipeline([]string{"double", "fail", "increment"}, 5)
+	if err == nil {
+		t.Fatal("Expected pipeline error")
+	}
+	if !strings.Contains(err.Error(), "stage 1 (fail)") {
+		t.Errorf("Expected error to name the failing stage, got %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 stages, got %d", len(stats))
+	}
+	if stats[1].ErrorCount != 1 {
+		t.Errorf("Expected failing stage to record an error, got %d", stats[1].ErrorCount)
+	}
+}
+
+func TestPipelineUnknownEntry(t *testing.T) {
+	script := goscript.NewScript([]byte(pipelineSource))
+
+	_, stats, err := script.Pipeline([]string{"double", "missing"}, 5)
+	if err == nil {
+		t.Fatal("Expected error for unknown entry")
+	}
+	if len(stats) != 0 {
+		t.Errorf("Expected no stages to run, got %d", len(stats))
+	}
+}
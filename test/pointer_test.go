@@ -0,0 +1,162 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestPointers(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"update variable through pointer", `
+	n := 1
+	p := &n
+	*p = 5
+	*p += 2
+	*p++
+	return n`, 8},
+		{"dereference", `
+	n := 3
+	p := &n
+	n = 4
+	return *p * 10`, 40},
+		{"pointers to the same variable are equal", `
+	n := 1
+	m := 1
+	return &n == &n && &n != &m`, true},
+		{"pointer to field", `
+	type Point struct {
+		X int
+		Y int
+	}
+	pt := Point{X: 1, Y: 2}
+	y := &pt.Y
+	*y = 9
+	return pt.Y`, 9},
+		{"pointer to slice element", `
+	xs := []int{1, 2, 3}
+	e := &xs[2]
+	*e *= 10
+	return xs[2]`, 30},
+		{"pointer outlives its scope", `
+	var p *int
+	{
+		n := 7
+		p = &n
+	}
+	*p = *p + 1
+	return *p`, 8},
+		{"struct pointer is the struct", `
+	type Point struct {
+		X int
+	}
+	pt := Point{X: 1}
+	q := &pt
+	q.X = 2
+	*q = Point{X: (*q).X + 10}
+	return pt.X`, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPointerParameters(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+type Counter struct {
+	n int
+}
+
+func (c *Counter) Inc() {
+	c.n++
+}
+
+func (c Counter) IncCopy() {
+	c.n++
+}
+
+func swap(a *int, b *int) {
+	tmp := *a
+	*a = *b
+	*b = tmp
+}
+
+func reset(c *Counter) {
+	*c = Counter{n: 0}
+}
+
+func main() {
+	x, y := 1, 2
+	swap(&x, &y)
+
+	// The receiver is named like a variable of the caller
+	c := 100
+	counter := Counter{n: 0}
+	counter.Inc()
+	counter.Inc()
+	counter.IncCopy()
+	incremented := counter.n
+	reset(&counter)
+	return []interface{}{x, y, c, incremented, counter.n}
+}
+`))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []interface{}{2, 1, 100, 2, 0}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("Value %d: expected %v, got %v", i, expected[i], values[i])
+		}
+	}
+}
+
+func TestPointerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"nil dereference", `
+	var p *int
+	return *p`, "nil pointer dereference"},
+		{"dereference of non-pointer", `
+	n := 1
+	return *n`, "invalid indirect"},
+		{"address of constant", `
+	const c = 1
+	p := &c
+	return p`, "cannot take address of c"},
+		{"address of call", `
+	p := &len("a")
+	return p`, "cannot take address of len()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, scriptSetup{})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const profileSource = `package main
+
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+func square(x int) int {
+	return x * x
+}
+
+func main() {
+	total := 0
+	for i := 0; i < 10; i++ {
+		total += square(i)
+	}
+	return fib(5) + total
+}
+`
+
+func TestProfile(t *testing.T) {
+	script := goscript.NewScript([]byte(profileSource))
+	script.SetProfiling(true)
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	profile := script.GetProfile()
+	if profile == nil {
+		t.Fatalf("Expected a profile")
+	}
+
+	calls := map[string]int64{"main.main": 1, "main.func.square": 10, "main.func.fib": 15}
+	for key, expected := range calls {
+		fp := profile.Functions[key]
+		if fp.Calls != expected {
+			t.Errorf("%s: expected %d calls, got %d", key, expected, fp.Calls)
+		}
+		if fp.Instructions == 0 || fp.Time < fp.SelfTime {
+			t.Errorf("%s: unexpected profile %+v", key, fp)
+		}
+	}
+	if main := profile.Functions["main.main"]; main.Time < profile.Functions["main.func.fib"].Time {
+		t.Errorf("Expected the time of main to include its callees")
+	}
+
+	// Call sites, most frequent first
+	expected := []goscript.CallSite{
+		{Caller: "main.func.fib", Line: 7, Callee: "main.func.fib", Count: 14},
+		{Caller: "main.main", Line: 17, Callee: "main.func.square", Count: 10},
+		{Caller: "main.main", Line: 19, Callee: "main.func.fib", Count: 1},
+	}
+	if len(profile.CallSites) != len(expected) {
+		t.Fatalf("Expected call sites %v, got %v", expected, profile.CallSites)
+	}
+	for i := range expected {
+		if profile.CallSites[i] != expected[i] {
+			t.Errorf("Call site %d: expected %+v, got %+v", i, expected[i], profile.CallSites[i])
+		}
+	}
+	if profile.Opcodes["OpCall"] != 10+15 {
+		t.Errorf("Expected 25 calls in the opcode histogram, got %d", profile.Opcodes["OpCall"])
+	}
+
+	var buf bytes.Buffer
+	if err := profile.WritePprof(&buf); err != nil {
+		t.Fatalf("Failed to write pprof profile: %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Expected a gzipped profile: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Failed to read profile: %v", err)
+	}
+	for key := range calls {
+		if !bytes.Contains(data, []byte(key)) {
+			t.Errorf("Expected %s in the pprof profile", key)
+		}
+	}
+}
+
+func TestProfilingDisabled(t *testing.T) {
+	script := goscript.NewScript([]byte(profileSource))
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if profile := script.GetProfile(); profile != nil {
+		t.Errorf("Expected no profile without profiling, got %+v", profile)
+	}
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const imageSource = `
+package main
+
+import (
+	"fmt"
+	"geometry"
+)
+
+var calls = 0
+
+func label(n int) string {
+	calls = calls + 1
+	return "item"
+}
+
+func main() {
+	total := 0
+	for i := 0; i < 10; i++ {
+		total = total + geometry.Double(scale())
+	}
+	return label(total) + ":" + fmt.Sprint(total) + ":" + fmt.Sprint(calls)
+}
+`
+
+func imageResolver(importPath string) ([]byte, bool, error) {
+	if importPath != "geometry" {
+		return nil, false, nil
+	}
+	return []byte(`
+package geometry
+
+func Double(x int) int {
+	return x * 2
+}
+`), true, nil
+}
+
+func TestProgramSharedAcrossScripts(t *testing.T) {
+	compiled := goscript.NewScript([]byte(imageSource))
+	compiled.SetImportResolver(imageResolver)
+	program, err := compiled.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+
+	// Scripts of one program run concurrently, each with its own host
+	// functions and globals
+	var wg sync.WaitGroup
+	results := make([]interface{}, 8)
+	errs := make([]error, 8)
+	for i := range results {
+		script, err := program.NewScript()
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		scale := i
+		script.AddFunction("scale", func(args ...interface{}) (interface{}, error) {
+			return scale, nil
+		})
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = script.Run()
+		}(i)
+	}
+	wg.Wait()
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatalf("Script %d failed: %v", i, errs[i])
+		}
+		if expected := fmt.Sprintf("item:%d:1", 20*i); result != expected {
+			t.Errorf("Script %d: expected %s, got %v", i, expected, result)
+		}
+	}
+}
+
+func TestProgramImageShared(t *testing.T) {
+	source := `
+package main
+
+import "fmt"
+
+func main() {
+	a := "shared"
+	b := "shared"
+	c := 42
+	d := 42
+	return a + b + fmt.Sprint(c+d)
+}
+`
+	program, err := goscript.NewScript([]byte(source)).Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	script, err := program.NewScript()
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if script.GetVM().Image() != program.Image() {
+		t.Errorf("Expected the script to share the program image")
+	}
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "sharedshared84" {
+		t.Errorf("Expected sharedshared84, got %v", result)
+	}
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const fibSource = `
+package main
+
+//goscript:pure
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+func slowFib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return slowFib(n-1) + slowFib(n-2)
+}
+
+func double(n int) int {
+	return n * 2
+}
+
+func main() {
+	return double(fib(30))
+}
+`
+
+func TestFunctionHints(t *testing.T) {
+	script := goscript.NewScript([]byte(fibSource))
+	program, err := script.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expected goscript.FunctionHints
+	}{
+		{"fib", goscript.FunctionHints{SelfRecursive: true, Pure: true}},
+		{"slowFib", goscript.FunctionHints{SelfRecursive: true}},
+		{"double", goscript.FunctionHints{}},
+	}
+	for _, tt := range tests {
+		hints, exists := script.GetVM().FunctionHints(tt.name)
+		if !exists {
+			t.Fatalf("Expected hints for %s", tt.name)
+		}
+		if hints != tt.expected {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, hints)
+		}
+	}
+
+	report, err := program.Warmup(false)
+	if err != nil {
+		t.Fatalf("Failed to warm up program: %v", err)
+	}
+	if !reflect.DeepEqual(report.Recursive, []string{"main.func.fib", "main.func.slowFib"}) {
+		t.Errorf("Expected fib and slowFib to be recursive, got %v", report.Recursive)
+	}
+}
+
+func TestPureFunctionsAreMemoized(t *testing.T) {
+	// Without memoization fib(30) takes millions of instructions
+	script := goscript.NewScript([]byte(fibSource))
+	script.SetMaxInstructions(5000)
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 1664080 {
+		t.Errorf("Expected 1664080, got %v", result)
+	}
+
+	// Results are only remembered for one execution
+	script = goscript.NewScript([]byte(`
+package main
+
+//goscript:pure
+func square(n int) int {
+	return observe(n) * n
+}
+
+func main() {
+	return square(3) + square(3) + square(4)
+}
+`))
+	calls := 0
+	script.AddFunction("observe", func(args ...interface{}) (interface{}, error) {
+		calls++
+		return args[0], nil
+	})
+	for i := 1; i <= 2; i++ {
+		result, err := script.Run()
+		if err != nil {
+			t.Fatalf("Failed to run script: %v", err)
+		}
+		if result != 34 {
+			t.Errorf("Expected 34, got %v", result)
+		}
+		if calls != 2*i {
+			t.Errorf("Run %d: expected %d calls, got %d", i, 2*i, calls)
+		}
+	}
+}
+
+func TestRecursiveFramesAreReused(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func sum(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return n + sum(n-1)
+}
+
+func main() {
+	return sum(200) + sum(10) + slowFib(15)
+}
+
+func slowFib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return slowFib(n-1) + slowFib(n-2)
+}
+`))
+	script.SetMaxInstructions(0)
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 20100+55+610 {
+		t.Errorf("Expected %d, got %v", 20100+55+610, result)
+	}
+}
+
+func TestDeepRecursionWithoutCallDepthLimit(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func count(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return 1 + count(n-1)
+}
+
+func main() {
+	return count(100000)
+}
+`))
+	script.SetMaxInstructions(0)
+	script.SetMaxCallDepth(0)
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 100000 {
+		t.Errorf("Expected 100000, got %v", result)
+	}
+}
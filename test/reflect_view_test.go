@@ -0,0 +1,159 @@
+package test
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+type hostCustomer struct {
+	Name  string
+	Tier  int
+	Notes []string
+	token string
+}
+
+type hostOrder struct {
+	ID       string
+	Total    float64
+	Customer *hostCustomer
+	Secret   string
+}
+
+func newHostOrder() *hostOrder {
+	return &hostOrder{
+		ID:       "A-1",
+		Total:    42.5,
+		Customer: &hostCustomer{Name: "Ann", Tier: 2, token: "t"},
+		Secret:   "hidden",
+	}
+}
+
+func TestAllowTypeReadsFields(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	o := order()
+	return o.ID + " " + o.Customer.Name
+}
+`))
+	addHostValue(script, "order", newHostOrder())
+	if err := script.AllowType(reflect.TypeOf(hostOrder{}), goscript.Fields("ID", "Customer")); err != nil {
+		t.Fatalf("Failed to allow type: %v", err)
+	}
+	if err := script.AllowType(reflect.TypeOf(&hostCustomer{}), nil); err != nil {
+		t.Fatalf("Failed to allow type: %v", err)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "A-1 Ann" {
+		t.Errorf("Expected A-1 Ann, got %v", result)
+	}
+}
+
+func TestAllowTypeRejectsUnlistedField(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	o := order()
+	return o.Secret
+}
+`))
+	addHostValue(script, "order", newHostOrder())
+	script.AllowType(reflect.TypeOf(hostOrder{}), goscript.Fields("ID"))
+
+	_, err := script.Run()
+	if err == nil || !strings.Contains(err.Error(), "field Secret of test.hostOrder is not accessible") {
+		t.Errorf("Expected inaccessible field error, got %v", err)
+	}
+}
+
+func TestAllowTypeIsReadOnly(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	o := order()
+	o.ID = "B-2"
+	return o.ID
+}
+`))
+	order := newHostOrder()
+	addHostValue(script, "order", order)
+	script.AllowType(reflect.TypeOf(hostOrder{}), goscript.Fields("ID"))
+
+	_, err := script.Run()
+	if err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("Expected read-only error, got %v", err)
+	}
+	if order.ID != "A-1" {
+		t.Errorf("Expected host value to be unchanged, got %s", order.ID)
+	}
+}
+
+func TestAllowTypeWithoutOptIn(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	o := order()
+	return o.ID
+}
+`))
+	addHostValue(script, "order", newHostOrder())
+
+	if _, err := script.Run(); err == nil {
+		t.Error("Expected an error reading a field of a host value that was not allowed")
+	}
+}
+
+func TestAllowTypeValidation(t *testing.T) {
+	script := goscript.NewScript([]byte("package main\nfunc main() {}"))
+	if err := script.AllowType(reflect.TypeOf(hostCustomer{}), goscript.Fields("Missing")); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+	if err := script.AllowType(reflect.TypeOf(hostCustomer{}), goscript.Fields("token")); err == nil {
+		t.Error("Expected an error for an unexported field")
+	}
+	if err := script.AllowType(reflect.TypeOf(1), nil); err == nil {
+		t.Error("Expected an error for a non-struct type")
+	}
+}
+
+func TestAllowTypeCopiesSliceFields(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	c := customer()
+	sortStrings(c.Notes)
+	return c.Notes
+}
+`))
+	customer := &hostCustomer{Name: "Ann", Notes: []string{"vip", "late"}}
+	addHostValue(script, "customer", customer)
+	script.AddFunction("sortStrings", func(args ...interface{}) (interface{}, error) {
+		sort.Strings(args[0].([]string))
+		return nil, nil
+	})
+	script.AllowType(reflect.TypeOf(hostCustomer{}), goscript.Fields("Notes"))
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if notes, ok := result.([]string); !ok || notes[0] != "vip" {
+		t.Errorf("Expected [vip late], got %v", result)
+	}
+	if customer.Notes[0] != "vip" {
+		t.Errorf("Expected host value to be unchanged, got %v", customer.Notes)
+	}
+}
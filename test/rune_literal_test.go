@@ -0,0 +1,38 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestRuneLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected interface{}
+	}{
+		{"simple", "'a'", int32('a')},
+		{"newline escape", "'\\n'", int32('\n')},
+		{"quote escape", "'\\''", int32('\'')},
+		{"unicode escape", "'\\u00e9'", int32('é')},
+		{"utf8 literal", "'世'", int32('世')},
+		{"arithmetic", "'a' + 1", int32('b')},
+		{"comparison", "'z' > 'a'", true},
+		{"compare with int", "'a' == 97", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scriptSource := "package main\n\nfunc main() {\n\treturn " + tt.expr + "\n}\n"
+			script := goscript.NewScript([]byte(scriptSource))
+			result, err := script.Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
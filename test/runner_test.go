@@ -0,0 +1,112 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+type runnerKey struct{}
+
+const runnerSource = `
+package main
+
+import (
+	"fmt"
+	"geometry"
+)
+
+var runs = 0
+
+func main() {
+	runs = runs + 1
+	total := 0
+	for i := 0; i < 50; i++ {
+		total = total + geometry.Double(base)
+	}
+	return fmt.Sprint(requestID()) + ":" + fmt.Sprint(total) + ":" + fmt.Sprint(runs)
+}
+`
+
+func TestRunnersExecuteConcurrently(t *testing.T) {
+	script := goscript.NewScript([]byte(runnerSource))
+	script.SetImportResolver(imageResolver)
+	script.SetMaxInstructions(0)
+	script.AddVariable("base", 3)
+	script.AddContextFunction("requestID", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return ctx.Value(runnerKey{}), nil
+	})
+
+	// Each runner has its own host values and package variables; the
+	// script itself runs alongside them
+	const n = 8
+	runners := make([]*goscript.Script, n)
+	for i := range runners {
+		runner, err := script.NewRunner()
+		if err != nil {
+			t.Fatalf("Failed to create runner: %v", err)
+		}
+		runners[i] = runner.WithValue(runnerKey{}, i)
+	}
+	script.WithValue(runnerKey{}, "main")
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, n+1)
+	errs := make([]error, n+1)
+	for i, s := range append(runners, script) {
+		wg.Add(1)
+		go func(i int, s *goscript.Script) {
+			defer wg.Done()
+			for round := 0; round < 3; round++ {
+				results[i], errs[i] = s.Run()
+			}
+		}(i, s)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatalf("Run %d failed: %v", i, errs[i])
+		}
+		id := fmt.Sprint(i)
+		if i == n {
+			id = "main"
+		}
+		if expected := id + ":300:1"; result != expected {
+			t.Errorf("Run %d: expected %s, got %v", i, expected, result)
+		}
+	}
+}
+
+func TestRunnerHasOwnConfiguration(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	return greet()
+}
+`))
+	script.AddFunction("greet", func(args ...interface{}) (interface{}, error) {
+		return "hello", nil
+	})
+	runner, err := script.NewRunner()
+	if err != nil {
+		t.Fatalf("Failed to create runner: %v", err)
+	}
+	runner.AddFunction("greet", func(args ...interface{}) (interface{}, error) {
+		return "bonjour", nil
+	})
+
+	if result, err := script.Run(); err != nil || result != "hello" {
+		t.Errorf("Expected hello, got %v (%v)", result, err)
+	}
+	if result, err := runner.Run(); err != nil || result != "bonjour" {
+		t.Errorf("Expected bonjour, got %v (%v)", result, err)
+	}
+	if runner.GetVM().Image() != script.GetVM().Image() {
+		t.Errorf("Expected the runner to share the program image")
+	}
+}
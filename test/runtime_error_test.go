@@ -0,0 +1,136 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestRuntimeErrorPosition(t *testing.T) {
+	source := []byte(`package main
+
+func div(a, b int) int {
+	return a / b
+}
+
+func main() {
+	x := 1
+	y := div(x, 0)
+	return y
+}
+`)
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"compiled", func() error {
+			_, err := goscript.NewScript(source).Run()
+			return err
+		}},
+		{"loaded from bytecode", func() error {
+			data, err := goscript.NewScript(source).CompileToBytes()
+			if err != nil {
+				return err
+			}
+			loaded := goscript.NewScript(nil)
+			if _, err := loaded.LoadProgram(data); err != nil {
+				return err
+			}
+			_, err = loaded.Run()
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			var runtimeErr *goscript.RuntimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("Expected a runtime error, got %v", err)
+			}
+			// The error is located at the innermost failing statement
+			if runtimeErr.Pos.Line != 4 || runtimeErr.Pos.Column != 2 {
+				t.Errorf("Expected the error at 4:2, got %v", runtimeErr.Pos)
+			}
+			if !strings.Contains(err.Error(), "script.go:4:2: division by zero") {
+				t.Errorf("Expected the position in the message, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRuntimeErrorStackTrace(t *testing.T) {
+	script := goscript.NewScript([]byte(`package main
+
+type Acc struct {
+	n int
+}
+
+func (a *Acc) Div(d int) int {
+	return a.n / d
+}
+
+func helper(d int) int {
+	acc := Acc{n: 10}
+	f := func() int {
+		return acc.Div(d)
+	}
+	return f()
+}
+
+func main() {
+	x := helper(0)
+	return x
+}
+`))
+	_, err := script.Run()
+	var runtimeErr *goscript.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("Expected a runtime error, got %v", err)
+	}
+
+	lines := []int{8, 14, 16, 20}
+	if len(runtimeErr.Stack) != len(lines) {
+		t.Fatalf("Expected %d frames, got %v", len(lines), runtimeErr.Stack)
+	}
+	for i, line := range lines {
+		if runtimeErr.Stack[i].Pos.Line != line {
+			t.Errorf("Frame %d: expected line %d, got %v", i, line, runtimeErr.Stack[i])
+		}
+	}
+	if outer := runtimeErr.Stack[len(lines)-1].Function; outer != "main.main" {
+		t.Errorf("Expected main.main as the outermost frame, got %s", outer)
+	}
+	trace := runtimeErr.StackTrace()
+	if !strings.HasPrefix(trace, "division by zero\n") || !strings.Contains(trace, "main.main at script.go:20:2") {
+		t.Errorf("Unexpected stack trace:\n%s", trace)
+	}
+}
+
+func TestRuntimeErrorPanic(t *testing.T) {
+	script := goscript.NewScript([]byte(`package main
+
+func run() {
+	boom()
+}
+
+func main() {
+	run()
+}
+`))
+	script.AddFunction("boom", func(args ...interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	_, err := script.Run()
+	var runtimeErr *goscript.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("Expected a runtime error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "script.go:4:2: runtime panic: boom") {
+		t.Errorf("Expected the panic at the call of boom, got %v", err)
+	}
+	if len(runtimeErr.Stack) != 2 || runtimeErr.Stack[1].Pos.Line != 8 {
+		t.Errorf("Expected the frames of run and main, got %v", runtimeErr.Stack)
+	}
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lengzhao/goscript"
+)
+
+const loopModuleSource = `
+package loop
+
+func Spin(n int) int {
+	total := 0
+	for i := 0; i < n; i++ {
+		total += i
+	}
+	return total
+}
+
+func Forever() {
+	for {
+	}
+}
+`
+
+func newLoopScript(body string) *goscript.Script {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "lib/loop"
+
+func main() {
+` + body + `
+}
+`))
+	script.SetImportResolver(func(importPath string) ([]byte, bool, error) {
+		if importPath != "lib/loop" {
+			return nil, false, nil
+		}
+		return []byte(loopModuleSource), true, nil
+	})
+	return script
+}
+
+func TestScriptModuleInstructionLimit(t *testing.T) {
+	// Without a limit on the script, the module has none either
+	script := newLoopScript(`return loop.Spin(20000)`)
+	script.SetMaxInstructions(0)
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 199990000 {
+		t.Errorf("Expected 199990000, got %v", result)
+	}
+
+	// Calls that each fit the limit exceed it together
+	script = newLoopScript(`total := 0
+	for i := 0; i < 20; i++ {
+		total += loop.Spin(50)
+	}
+	return total`)
+	script.SetMaxInstructions(2000)
+	_, err = script.Run()
+	if err == nil || !strings.Contains(err.Error(), "maximum instruction limit exceeded") {
+		t.Errorf("Expected the module calls to count against the limit, got %v", err)
+	}
+}
+
+func TestScriptModuleCancellation(t *testing.T) {
+	script := newLoopScript(`loop.Forever()
+	return 0`)
+	script.SetMaxInstructions(0)
+	script.SetTimeout(20 * time.Millisecond)
+	if _, err := script.Run(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the module call to time out, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	script = newLoopScript(`loop.Forever()
+	return 0`)
+	script.SetMaxInstructions(0)
+	if _, err := script.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the module call to stop with the context, got %v", err)
+	}
+}
+
+func TestScriptModuleMemoryLimit(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "lib/grow"
+
+func main() {
+	return len(grow.Make(1000))
+}
+`))
+	script.SetImportResolver(func(importPath string) ([]byte, bool, error) {
+		return []byte(`
+package grow
+
+func Make(n int) []int {
+	return make([]int, n)
+}
+`), importPath == "lib/grow", nil
+	})
+	script.SetMaxMemory(1024)
+	if _, err := script.Run(); !errors.Is(err, goscript.ErrMemoryLimit) {
+		t.Errorf("Expected the module allocation to count against the limit, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestSetBuiltins(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	seen := newSet("a", "b")
+	add(seen, "c", "a")
+	delete(seen, "b")
+
+	other := newSet("c", "d")
+	both := intersect(seen, other)
+	if has(both, "c") {
+		return []interface{}{len(seen), toSlice(union(seen, other)), toSlice(difference(seen, other))}
+	}
+	return nil
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []interface{}{2, []interface{}{"a", "c", "d"}, []interface{}{"a"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSliceBuiltins(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"make with length", `
+	s := make([]int, 3)
+	return s`, []interface{}{0, 0, 0}},
+		{"make with capacity", `
+	s := make([]string, 1, 10)
+	return []interface{}{len(s), cap(s), s[0]}`, []interface{}{1, 10, ""}},
+		{"append to nil slice", `
+	var s []int
+	for i := 0; i < 5; i++ {
+		s = append(s, i*i)
+	}
+	return s`, []interface{}{0, 1, 4, 9, 16}},
+		{"append grows capacity", `
+	s := make([]int, 0, 2)
+	s = append(s, 1, 2, 3)
+	return len(s) == 3 && cap(s) >= 3`, true},
+		{"append spread", `
+	a := []int{1, 2}
+	b := []int{3, 4}
+	return append(a, b...)`, []interface{}{1, 2, 3, 4}},
+		{"copy", `
+	dst := make([]int, 2)
+	n := copy(dst, []int{7, 8, 9})
+	return []interface{}{n, dst}`, []interface{}{2, []interface{}{7, 8}}},
+		{"len and cap of nil slice", `
+	var s []int
+	return len(s) + cap(s) + copy(s, []int{1})`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSliceBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"make without length", `
+	return make([]int)`, "expects 2 or 3 arguments"},
+		{"negative length", `
+	n := 0 - 1
+	return make([]int, n)`, "negative len argument"},
+		{"length larger than capacity", `
+	return make([]int, 3, 2)`, "len larger than cap"},
+		{"huge length", `
+	return make([]int, 8000000000)`, "cap out of range"},
+		{"huge capacity", `
+	return make([]byte, 0, 8000000000)`, "cap out of range"},
+		{"append to non-slice", `
+	return append(1, 2)`, "first argument must be a slice"},
+		{"copy with one argument", `
+	return copy([]int{1})`, "not enough arguments in call to copy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runScript(t, tt.body, scriptSetup{})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
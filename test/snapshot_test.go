@@ -0,0 +1,87 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const snapshotSource = `
+package main
+
+type Order struct {
+	Status string
+	Total  int
+}
+
+func main() {
+	order := Order{Status: "new", Total: 10}
+	items := []int{1, 2}
+	processed := 0
+	checkpoint()
+
+	order.Status = "paid"
+	items[1] = 5
+	processed = processed + 1
+	note := "done"
+	checkpoint()
+	return note
+}
+`
+
+func TestSnapshotDiff(t *testing.T) {
+	script := goscript.NewScript([]byte(snapshotSource))
+
+	var snapshots []goscript.Snapshot
+	script.AddFunction("checkpoint", func(args ...interface{}) (interface{}, error) {
+		snapshots = append(snapshots, script.Snapshot())
+		return nil, nil
+	})
+
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %d", len(snapshots))
+	}
+
+	changes := goscript.DiffSnapshots(snapshots[0], snapshots[1])
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	expected := []string{
+		"~ items[1]: 2 -> 5",
+		"+ note = done",
+		"~ order.Status: new -> paid",
+		"~ processed: 0 -> 1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected changes %v, got %v", expected, got)
+	}
+}
+
+func TestSnapshotIsDeepCopy(t *testing.T) {
+	script := goscript.NewScript([]byte(snapshotSource))
+
+	var first goscript.Snapshot
+	script.AddFunction("checkpoint", func(args ...interface{}) (interface{}, error) {
+		if first == nil {
+			first = script.Snapshot()
+		}
+		return nil, nil
+	})
+
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+
+	order, ok := first["order"].(*goscript.Struct)
+	if !ok {
+		t.Fatalf("Expected order to be a struct, got %T", first["order"])
+	}
+	if order.Fields["Status"] != "new" {
+		t.Errorf("Expected snapshot to keep Status new, got %v", order.Fields["Status"])
+	}
+}
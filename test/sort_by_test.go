@@ -0,0 +1,38 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestSortByStructField(t *testing.T) {
+	scriptSource := `
+package main
+
+type Task struct {
+	name     string
+	priority int
+}
+
+func main() {
+	tasks := []Task{
+		Task{name: "write", priority: 2},
+		Task{name: "test", priority: 3},
+		Task{name: "plan", priority: 1},
+	}
+	sortBy(tasks, "priority")
+	return []string{tasks[0].name, tasks[1].name, tasks[2].name}
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if !reflect.DeepEqual(result, []interface{}{"plan", "write", "test"}) {
+		t.Errorf("Expected [plan write test], got %v", result)
+	}
+}
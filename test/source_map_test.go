@@ -0,0 +1,71 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestSourceMap(t *testing.T) {
+	script := goscript.NewScript([]byte(stepSource))
+	sourceMap, err := script.SourceMap()
+	if err != nil {
+		t.Fatalf("Failed to get the source map: %v", err)
+	}
+
+	expectedLines := []int{5, 9, 10, 11, 13, 14, 16}
+	if lines := sourceMap.Lines("script.go"); !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("Expected lines %v, got %v", expectedLines, lines)
+	}
+	if lines := sourceMap.Lines("other.go"); len(lines) != 0 {
+		t.Errorf("Expected no lines in another file, got %v", lines)
+	}
+
+	// Lines map to the statements compiled from them, and back
+	locations := sourceMap.Locations("script.go", 5)
+	if len(locations) != 1 || locations[0].Function != "main.func.add" {
+		t.Fatalf("Expected one statement of add on line 5, got %v", locations)
+	}
+	pos, ok := sourceMap.Position(locations[0])
+	if !ok || pos.Filename != "script.go" || pos.Line != 5 || pos.Column != 2 {
+		t.Errorf("Expected script.go:5:2, got %v", pos)
+	}
+	if locations := sourceMap.Locations("", 14); len(locations) != 1 || locations[0].Function != "main.main" {
+		t.Errorf("Expected one statement of main on line 14, got %v", locations)
+	}
+	if locations := sourceMap.Locations("script.go", 6); locations != nil {
+		t.Errorf("Expected no statement on line 6, got %v", locations)
+	}
+	if _, ok := sourceMap.Position(goscript.Location{Function: "main.main", Index: 1000}); ok {
+		t.Error("Expected no position for an unknown instruction")
+	}
+
+	// Every step of an execution maps to the line it reports
+	script.SetStepHandler(goscript.StepInstruction, func(event goscript.StepEvent) error {
+		pos, ok := sourceMap.Position(goscript.Location{Function: event.Function, Index: event.PC})
+		if event.Line != 0 && (!ok || pos.Line != event.Line) {
+			t.Errorf("Expected %s:%d to map to line %d, got %v", event.Function, event.PC, event.Line, pos)
+		}
+		return nil
+	})
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+}
+
+func TestSourceMapOfLoadedProgram(t *testing.T) {
+	data, err := goscript.NewScript([]byte(stepSource)).CompileToBytes()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	program, err := goscript.NewScript(nil).LoadProgram(data)
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	sourceMap := program.SourceMap()
+	expected := []int{5, 9, 10, 11, 13, 14, 16}
+	if lines := sourceMap.Lines(""); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected lines %v, got %v", expected, lines)
+	}
+}
@@ -0,0 +1,213 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/scripts"
+)
+
+func TestStdModulesCompile(t *testing.T) {
+	modules := scripts.StdModules()
+	if len(modules) == 0 {
+		t.Fatal("Expected standard library modules")
+	}
+	for _, module := range modules {
+		source, found := scripts.Std(module)
+		if !found {
+			t.Fatalf("Module %s is listed but not found", module)
+		}
+		if _, err := goscript.NewScript(source).Compile(); err != nil {
+			t.Errorf("Failed to compile %s: %v", module, err)
+		}
+	}
+}
+
+func TestStdImports(t *testing.T) {
+	tests := []struct {
+		name     string
+		imports  string
+		expr     string
+		expected interface{}
+	}{
+		{"pad left", `"std/strings2"`, `strings2.PadLeft("7", 3, "0")`, "007"},
+		{"pad right", `"std/strings2"`, `strings2.PadRight("ab", 4, ".")`, "ab.."},
+		{"repeat", `"std/strings2"`, `strings2.Repeat("ab", 3)`, "ababab"},
+		{"default if blank", `"std/strings2"`, `strings2.DefaultIfBlank("  ", "n/a")`, "n/a"},
+		{"leap year", `"std/dates"`, `dates.IsLeapYear(2000) && dates.IsLeapYear(2024)`, true},
+		{"not leap year", `"std/dates"`, `dates.IsLeapYear(1900)`, false},
+		{"day of year", `"std/dates"`, `dates.DayOfYear(2024, 3, 1)`, 61},
+		{"invalid date", `"std/dates"`, `dates.IsValidDate(2023, 2, 29)`, false},
+		{"format date", `"std/dates"`, `dates.FormatDate(2024, 3, 1)`, "2024-03-01"},
+		{"email", `"std/validate"`, `validate.IsEmail("ann@example.com")`, true},
+		{"not email", `"std/validate"`, `validate.IsEmail("ann@example")`, false},
+		{"in range", `"std/validate"`, `validate.InRange(5, 1, 10)`, true},
+		{"aliased import", `v "std/validate"`, `v.NotEmpty(" x ")`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte(`
+package main
+
+import ` + tt.imports + `
+
+func main() {
+	return ` + tt.expr + `
+}
+`))
+			result, err := script.Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestImportResolver(t *testing.T) {
+	modules := map[string]string{
+		"lib/pricing": `
+package pricing
+
+import "std/validate"
+
+func Total(price int, qty int) int {
+	if validate.InRange(qty, 10, 1000) {
+		return discount(price * qty)
+	}
+	return price * qty
+}
+
+func discount(amount int) int {
+	return amount * 9 / 10
+}
+`,
+		"lib/a": `
+package a
+
+import "lib/b"
+
+func A() int {
+	return b.B()
+}
+`,
+		"lib/b": `
+package b
+
+import "lib/a"
+
+func B() int {
+	return a.A()
+}
+`,
+	}
+	resolver := func(importPath string) ([]byte, bool, error) {
+		source, found := modules[importPath]
+		return []byte(source), found, nil
+	}
+
+	script := goscript.NewScript([]byte(`
+package main
+
+import "lib/pricing"
+
+func main() {
+	return pricing.Total(10, 20) + pricing.Total(10, 2)
+}
+`))
+	script.SetImportResolver(resolver)
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 200 {
+		t.Errorf("Expected 200, got %v", result)
+	}
+
+	// Unexported functions are not reachable from the importing script
+	script = goscript.NewScript([]byte(`
+package main
+
+import "lib/pricing"
+
+func main() {
+	return pricing.discount(100)
+}
+`))
+	script.SetImportResolver(resolver)
+	if _, err := script.Run(); err == nil || !strings.Contains(err.Error(), "unexported") {
+		t.Errorf("Expected an unexported function error, got %v", err)
+	}
+
+	script = goscript.NewScript([]byte(`
+package main
+
+import "lib/a"
+
+func main() {
+	return a.A()
+}
+`))
+	script.SetImportResolver(resolver)
+	if _, err := script.Compile(); err == nil || !strings.Contains(err.Error(), "import cycle") {
+		t.Errorf("Expected an import cycle error, got %v", err)
+	}
+}
+
+func TestModuleResolver(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "lib"), 0o755)
+	os.WriteFile(filepath.Join(dir, "lib", "helpers.gs"), []byte(`
+package helpers
+
+import (
+	"fmt"
+	"std/strings2"
+)
+
+func Label(n int) string {
+	return strings2.PadLeft(fmt.Sprint(n), 3, "0")
+}
+
+func unexported() int {
+	return 1
+}
+`), 0o644)
+
+	newScript := func(body string) *goscript.Script {
+		script := goscript.NewScript([]byte(`
+package main
+
+import (
+	"lib/helpers"
+	"std/dates"
+)
+
+func main() {
+	return ` + body + `
+}
+`))
+		script.SetModuleResolver(func(path string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(dir, path+".gs"))
+		})
+		return script
+	}
+
+	result, err := newScript(`helpers.Label(7) + ":" + dates.FormatDate(2024, 3, 1)`).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "007:2024-03-01" {
+		t.Errorf("Expected 007:2024-03-01, got %v", result)
+	}
+
+	_, err = newScript(`helpers.unexported()`).Run()
+	if err == nil || !strings.Contains(err.Error(), "cannot refer to unexported function unexported") {
+		t.Errorf("Expected an unexported function error, got %v", err)
+	}
+}
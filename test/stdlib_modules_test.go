@@ -0,0 +1,237 @@
+package test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/builtin"
+)
+
+func TestStdlibModules(t *testing.T) {
+	builtin.RegisterStdModules()
+	t.Cleanup(func() {
+		for _, name := range []string{"time", "strconv", "regexp", "sort"} {
+			builtin.UnregisterModule(name)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		imports  string
+		expr     string
+		expected interface{}
+	}{
+		{"date", `"time"`, `time.Date(2024, 3, 1).Format(time.DateOnly)`, "2024-03-01"},
+		{"unix", `"time"`, `time.Unix(86400).Format(time.RFC3339)`, "1970-01-02T00:00:00Z"},
+		{"parse duration", `"time"`, `(time.ParseDuration("90s") * 2).String()`, "3m0s"},
+		{"since", `"time"`, `time.Since(time.Now()) < time.ParseDuration("1m")`, true},
+		{"atoi", `"strconv"`, `strconv.Atoi("42") + 1`, 43},
+		{"parse int", `"strconv"`, `strconv.ParseInt("ff", 16)`, 255},
+		{"format float", `"strconv"`, `strconv.FormatFloat(3.14159, 'f', 2)`, "3.14"},
+		{"quote", `"strconv"`, `strconv.Quote("hi") + strconv.Unquote(strconv.Quote("hi"))`, `"hi"hi`},
+		{"match", `"regexp"`, `regexp.MatchString("^[a-z]+[0-9]$", "abc1")`, true},
+		{"replace", `"regexp"`, `regexp.ReplaceAllString("[0-9]+", "a1b22", "#")`, "a#b#"},
+		{"find all", `"regexp"`, `len(regexp.FindAllString("[0-9]+", "1 22 333", 2))`, 2},
+		{"sort ints", `"sort"`, `sorted([]int{3, 1, 2})`, "[1 2 3]"},
+		{"sorted", `"sort"`, `sort.StringsAreSorted([]string{"a", "b"})`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte(`
+package main
+
+import (
+	"fmt"
+	` + tt.imports + `
+)
+
+func sorted(values []int) string {
+	sort.Ints(values)
+	return fmt.Sprint(values)
+}
+
+func main() {
+	return ` + tt.expr + `
+}
+`))
+			result, err := script.Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	_, err := goscript.NewScript([]byte(`
+package main
+
+import "strconv"
+
+func main() {
+	return strconv.Atoi("x")
+}
+`)).Run()
+	if err == nil || !strings.Contains(err.Error(), `parsing "x": invalid syntax`) {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+}
+
+func TestSortSlice(t *testing.T) {
+	builtin.RegisterModule("sort", builtin.SortModule)
+	t.Cleanup(func() { builtin.UnregisterModule("sort") })
+
+	result, err := goscript.NewScript([]byte(`
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type Person struct {
+	Name string
+	Age  int
+}
+
+var words = []string{"ccc", "a", "bb"}
+
+func shorter(i, j int) bool {
+	return len(words[i]) < len(words[j])
+}
+
+func main() {
+	people := []Person{{Name: "Bob", Age: 31}, {Name: "Ann", Age: 25}, {Name: "Cid", Age: 31}}
+	sort.SliceStable(people, func(i, j int) bool {
+		return people[i].Age < people[j].Age
+	})
+	sort.Slice(words, shorter)
+	return fmt.Sprint(people[0].Name, people[1].Name, people[2].Name, words)
+}
+`)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "AnnBobCid[a bb ccc]" {
+		t.Errorf("Expected AnnBobCid[a bb ccc], got %v", result)
+	}
+
+	_, err = goscript.NewScript([]byte(`
+package main
+
+import "sort"
+
+func main() {
+	values := []int{2, 1}
+	sort.Slice(values, func(i, j int) int { return values[i] - values[j] })
+}
+`)).Run()
+	if err == nil || !strings.Contains(err.Error(), "less to return a bool") {
+		t.Errorf("Expected a less result error, got %v", err)
+	}
+}
+
+func TestOSModule(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "in.txt"), []byte("hello"), 0o644)
+	t.Cleanup(func() { builtin.UnregisterModule("os") })
+
+	run := func(body string) (interface{}, error) {
+		return goscript.NewScript([]byte(`
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	` + body + `
+}
+`)).Run()
+	}
+
+	// Reading is allowed in the directory only
+	builtin.RegisterModule("os", builtin.NewOSModule(builtin.OSOptions{Dir: dir}))
+	if result, err := run(`return os.ReadFile("in.txt")`); err != nil || result != "hello" {
+		t.Errorf("Expected hello, got %v (%v)", result, err)
+	}
+	if _, err := run(`return os.ReadFile("../in.txt")`); err == nil {
+		t.Errorf("Expected reading outside the directory to fail")
+	}
+	if _, err := run(`os.WriteFile("out.txt", "x")`); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected writing to be denied, got %v", err)
+	}
+	if _, err := run(`return os.Getenv("HOME")`); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected the environment to be denied, got %v", err)
+	}
+
+	builtin.RegisterModule("os", builtin.NewOSModule(builtin.OSOptions{Dir: dir, Write: true}))
+	result, err := run(`
+	os.Mkdir("sub")
+	os.WriteFile("sub/out.txt", "written")
+	return os.ReadFile("sub/out.txt") + fmt.Sprint(os.ReadDir("."))`)
+	if err != nil || result != "written[in.txt sub]" {
+		t.Errorf("Expected written[in.txt sub], got %v (%v)", result, err)
+	}
+}
+
+func TestOSModuleEnvAndArgs(t *testing.T) {
+	t.Setenv("GOSCRIPT_ALLOWED", "yes")
+	t.Setenv("GOSCRIPT_SECRET", "no")
+	t.Cleanup(func() { builtin.UnregisterModule("os") })
+
+	run := func(body string) (interface{}, error) {
+		return goscript.NewScript([]byte(`
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	` + body + `
+}
+`)).Run()
+	}
+
+	// Only the listed variables can be read
+	builtin.RegisterModule("os", builtin.NewOSModule(builtin.OSOptions{EnvVars: []string{"GOSCRIPT_ALLOWED"}}))
+	if result, err := run(`return os.Getenv("GOSCRIPT_ALLOWED")`); err != nil || result != "yes" {
+		t.Errorf("Expected yes, got %v (%v)", result, err)
+	}
+	if _, err := run(`return os.Getenv("GOSCRIPT_SECRET")`); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected an unlisted variable to be denied, got %v", err)
+	}
+	if result, err := run(`return fmt.Sprint(os.Environ())`); err != nil || result != "[GOSCRIPT_ALLOWED=yes]" {
+		t.Errorf("Expected only the listed variable, got %v (%v)", result, err)
+	}
+	if _, err := run(`return os.Args()`); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected the arguments to be denied, got %v", err)
+	}
+	if _, err := run(`return os.ReadFile("in.txt")`); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected reading files to be denied, got %v", err)
+	}
+
+	builtin.RegisterModule("os", builtin.NewOSModule(builtin.OSOptions{Args: []string{"report", "-v"}}))
+	if result, err := run(`
+	args := os.Args()
+	return fmt.Sprint(len(args)) + ":" + args[1]`); err != nil || result != "2:-v" {
+		t.Errorf("Expected 2:-v, got %v (%v)", result, err)
+	}
+	if _, err := run(`return os.Environ()`); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected the environment to be denied, got %v", err)
+	}
+
+	builtin.RegisterModule("os", builtin.NewOSModule(builtin.OSOptions{Env: true}))
+	if result, err := run(`return os.Getenv("GOSCRIPT_SECRET")`); err != nil || result != "no" {
+		t.Errorf("Expected no, got %v (%v)", result, err)
+	}
+}
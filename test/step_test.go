@@ -0,0 +1,158 @@
+package test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+// stepSource is laid out so that line numbers are easy to follow: the
+// leading newline makes "package main" line 2
+const stepSource = `
+package main
+
+func add(a, b int) int {
+	return a + b
+}
+
+func main() {
+	total := 0
+	for i := 0; i < 2; i++ {
+		total = add(total, i)
+	}
+	if total > 0 {
+		total = total * 10
+	}
+	return total
+}
+`
+
+// recordSteps runs stepSource and returns the lines of the steps taken
+func recordSteps(t *testing.T, mode goscript.StepMode) []int {
+	t.Helper()
+	script := goscript.NewScript([]byte(stepSource))
+	var lines []int
+	script.SetStepHandler(mode, func(event goscript.StepEvent) error {
+		lines = append(lines, event.Line)
+		return nil
+	})
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 10 {
+		t.Fatalf("Expected 10, got %v", result)
+	}
+	return lines
+}
+
+func TestStepLine(t *testing.T) {
+	// Each loop iteration comes back to the header; calls step into add,
+	// starting at its declaration
+	expected := []int{9, 10, 11, 4, 5, 10, 11, 4, 5, 10, 13, 14, 16}
+	if lines := recordSteps(t, goscript.StepLine); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected lines %v, got %v", expected, lines)
+	}
+}
+
+func TestStepStatement(t *testing.T) {
+	// The loop header stops for the init and post statements only
+	expected := []int{9, 10, 11, 5, 10, 11, 5, 10, 13, 14, 16}
+	if lines := recordSteps(t, goscript.StepStatement); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected statements at lines %v, got %v", expected, lines)
+	}
+}
+
+func TestStepInstruction(t *testing.T) {
+	statements := recordSteps(t, goscript.StepStatement)
+	instructions := recordSteps(t, goscript.StepInstruction)
+	if len(instructions) <= 2*len(statements) {
+		t.Errorf("Expected several instructions per statement, got %d instructions for %d statements", len(instructions), len(statements))
+	}
+}
+
+func TestStepHandlerAbortsExecution(t *testing.T) {
+	script := goscript.NewScript([]byte(stepSource))
+	stop := errors.New("stopped by debugger")
+	var snapshot goscript.Snapshot
+	script.SetStepHandler(goscript.StepLine, func(event goscript.StepEvent) error {
+		if event.Line == 13 {
+			snapshot = script.Snapshot()
+			return stop
+		}
+		return nil
+	})
+
+	if _, err := script.Run(); !errors.Is(err, stop) {
+		t.Fatalf("Expected the debugger error, got %v", err)
+	}
+	if snapshot["total"] != 1 {
+		t.Errorf("Expected total to be 1 when stopped at the if, got %v", snapshot["total"])
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	script := goscript.NewScript([]byte(stepSource))
+	// The body of add, and the statement after the loop
+	script.SetBreakpoint("main.func.add", 5)
+	script.SetBreakpoint("main.main", 13)
+
+	type pause struct {
+		function string
+		line     int
+		value    interface{}
+	}
+	var pauses []pause
+	script.SetPauseHandler(func(debugger goscript.Debugger, event goscript.StepEvent) error {
+		switch event.Line {
+		case 5:
+			b, _ := debugger.Inspect("b")
+			pauses = append(pauses, pause{event.Function, event.Line, b})
+			if b == 1 {
+				// Step out of add, back into the loop
+				debugger.Step()
+			}
+		default:
+			total, _ := debugger.Inspect("total")
+			pauses = append(pauses, pause{event.Function, event.Line, total})
+			debugger.Continue()
+		}
+		return nil
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 10 {
+		t.Errorf("Expected 10, got %v", result)
+	}
+	expected := []pause{
+		{"main.func.add", 5, 0},
+		{"main.func.add", 5, 1},
+		{"main.main", 10, 1},
+		{"main.main", 13, 1},
+	}
+	if !reflect.DeepEqual(pauses, expected) {
+		t.Errorf("Expected pauses %v, got %v", expected, pauses)
+	}
+}
+
+func TestClearBreakpoint(t *testing.T) {
+	script := goscript.NewScript([]byte(stepSource))
+	script.SetBreakpoint("", 5)
+	script.ClearBreakpoint("", 5)
+	paused := false
+	script.SetPauseHandler(func(debugger goscript.Debugger, event goscript.StepEvent) error {
+		paused = true
+		return nil
+	})
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if paused {
+		t.Errorf("Expected a cleared breakpoint not to pause")
+	}
+}
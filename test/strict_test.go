@@ -0,0 +1,92 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/internal/compiler"
+)
+
+const strictSource = `
+package main
+
+func main() {
+	unused := 1
+	total := 2
+	if total > 1 {
+		total := 3
+		return total
+	}
+	return total
+	total = 4
+}
+`
+
+func TestStrictMode(t *testing.T) {
+	script := goscript.NewScript([]byte(strictSource))
+	script.SetStrict(true)
+	program, err := script.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+
+	diagnostics := program.Diagnostics()
+	expected := []string{
+		`script.go:8:3: warning: declaration of "total" shadows declaration at line 6`,
+		`script.go:12:2: warning: unreachable code`,
+		`script.go:5:2: warning: declared and not used: unused`,
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, diag := range diagnostics {
+		if diag.Severity != compiler.SeverityWarning || diag.String() != expected[i] {
+			t.Errorf("Expected diagnostic %q, got %q", expected[i], diag)
+		}
+	}
+	if len(program.Warnings()) != len(expected) {
+		t.Errorf("Expected the warnings to include the strict diagnostics, got %v", program.Warnings())
+	}
+
+	// Strict diagnostics are warnings: the script still runs
+	result, err := script.Run()
+	if err != nil || result != 3 {
+		t.Errorf("Expected 3, got %v (%v)", result, err)
+	}
+
+	// They are not reported by default
+	program, err = goscript.NewScript([]byte(strictSource)).Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	if len(program.Diagnostics()) != 0 {
+		t.Errorf("Expected no diagnostics without strict mode, got %v", program.Diagnostics())
+	}
+
+	// Options.Strict enables them too
+	opts := goscript.DefaultOptions()
+	opts.Strict = true
+	program, err = goscript.NewScriptWithOptions([]byte(strictSource), opts).Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	if len(program.Diagnostics()) != len(expected) {
+		t.Errorf("Expected %d diagnostics with Options.Strict, got %v", len(expected), program.Diagnostics())
+	}
+}
+
+func TestAssignUndeclared(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	count = 1
+	return count
+}
+`))
+	_, err := script.Compile()
+	if err == nil || !strings.Contains(err.Error(), "undefined: count") {
+		t.Errorf("Expected an undefined error, got %v", err)
+	}
+}
@@ -3,9 +3,10 @@ package test
 import (
 	"testing"
 
-	"github.com/lengzhao/goscript/compiler"
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
 	"github.com/lengzhao/goscript/parser"
-	"github.com/lengzhao/goscript/vm"
 )
 
 // TestStructMethodCall tests struct method calls
@@ -92,3 +93,53 @@ func main() {
 		// t.Logf("Stack size: %d", vmInstance.StackSize()) // Not implemented in current VM
 	}
 }
+
+// TestMethodsResolvedByDeclaredType tests that methods are found from the
+// declared type of the receiver, whatever its fields and parameter names
+func TestMethodsResolvedByDeclaredType(t *testing.T) {
+	result, err := goscript.NewScript([]byte(`
+package main
+
+type Invoice struct {
+	total int
+}
+
+type Ledger struct {
+	entries int
+}
+
+func (inv Invoice) Describe(prefix string) string {
+	return prefix + "invoice"
+}
+
+func (l *Ledger) Describe(prefix string) string {
+	l.entries = l.entries + 1
+	return prefix + "ledger"
+}
+
+func (Ledger) Kind() string {
+	return "book"
+}
+
+func (inv *Invoice) Add(amount int, times int) {
+	inv.total = inv.total + amount*times
+}
+
+func main() {
+	inv := &Invoice{total: 1}
+	inv.Add(2, 3)
+	l := &Ledger{entries: 0}
+	s := inv.Describe("a ") + ", " + l.Describe("b ") + ", " + l.Kind()
+	if inv.total != 7 || l.entries != 1 {
+		return "pointer receivers not updated"
+	}
+	return s
+}
+`)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "a invoice, b ledger, book" {
+		t.Errorf("Expected %q, got %v", "a invoice, b ledger, book", result)
+	}
+}
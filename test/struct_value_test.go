@@ -0,0 +1,169 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+const structValueSource = `
+package main
+
+import "json"
+
+type Event struct {
+	_type string
+	id    int
+}
+
+func (e Event) Describe() string {
+	return e._type
+}
+
+func (e *Event) Retype(kind string) {
+	e._type = kind
+}
+
+func main() {
+	e := Event{_type: "click", id: 7}
+	before := e.Describe()
+	e.Retype("tap")
+	return before + " " + e.Describe() + " " + json.Marshal(e)
+}
+`
+
+func TestStructFieldNamedType(t *testing.T) {
+	// The type of a struct is not stored among its fields, so a field may
+	// be called _type
+	result, err := goscript.NewScript([]byte(structValueSource)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := `click tap {"_type":"tap","id":7}`
+	if result != expected {
+		t.Errorf("Expected %s, got %v", expected, result)
+	}
+}
+
+func TestStructReturnedToHost(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+type Point struct {
+	X int
+	Y int
+}
+
+func main() {
+	return Point{X: 1, Y: 2}
+}
+`))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	point, ok := result.(*goscript.Struct)
+	if !ok {
+		t.Fatalf("Expected a struct, got %T", result)
+	}
+	if point.Type != "Point" || len(point.Fields) != 2 || point.Fields["X"] != 1 || point.Fields["Y"] != 2 {
+		t.Errorf("Expected Point{X: 1, Y: 2}, got %v", point)
+	}
+}
+
+func TestStructValueSemantics(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"assignment copies", `
+	a := P{X: 1}
+	b := a
+	b.X = 2
+	return a.X*10 + b.X`, 12},
+		{"argument copies", `
+	set := func(p P) {
+		p.X = 9
+	}
+	a := P{X: 1}
+	set(a)
+	return a.X`, 1},
+		{"result copies", `
+	a := P{X: 1}
+	get := func() P {
+		return a
+	}
+	r := get()
+	r.X = 5
+	return a.X`, 1},
+		{"nested structs copy", `
+	o := Outer{In: P{X: 1}}
+	o2 := o
+	o2.In.X = 2
+	return o.In.X`, 1},
+		{"slice elements copy", `
+	a := P{X: 1}
+	s := []P{a}
+	s[0].X = 3
+	for _, v := range s {
+		v.X = 9
+	}
+	return a.X*10 + s[0].X`, 13},
+		{"map values copy", `
+	a := P{X: 1}
+	m := map[string]P{"k": a}
+	a.X = 4
+	return m["k"].X`, 1},
+		{"append copies", `
+	a := P{X: 1}
+	s := append([]P{}, a)
+	a.X = 2
+	return s[0].X`, 1},
+		{"pointers share", `
+	a := P{X: 1}
+	p := &a
+	q := p
+	q.X = 2
+	return a.X`, 2},
+		{"pointer argument", `
+	set := func(p *P) {
+		p.X = 9
+	}
+	a := P{X: 1}
+	set(&a)
+	return a.X`, 9},
+		{"deref copies", `
+	a := P{X: 1}
+	p := &a
+	b := *p
+	b.X = 7
+	(*p).X += 10
+	return a.X*10 + b.X`, 117},
+		{"pointer receiver of a value", `
+	c := Counter{}
+	c.Incr()
+	c.Incr()
+	return c.N`, 2},
+		{"value receiver of a pointer", `
+	c := &Counter{N: 3}
+	return c.Get()`, 3},
+		{"literal pointers are distinct", `
+	return &P{X: 1} == &P{X: 1}`, false},
+		{"pointers to a variable are equal", `
+	a := P{X: 1}
+	return &a == &a`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "package main\n\ntype P struct {\n\tX int\n}\n\ntype Outer struct {\n\tIn P\n}\n\ntype Counter struct {\n\tN int\n}\n\nfunc (c *Counter) Incr() {\n\tc.N++\n}\n\nfunc (c Counter) Get() int {\n\treturn c.N\n}\n\nfunc main() {\n" + tt.body + "\n}\n"
+			result, err := goscript.NewScript([]byte(source)).Run()
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -5,9 +5,9 @@ import (
 	"testing"
 
 	"github.com/lengzhao/goscript"
-	"github.com/lengzhao/goscript/compiler"
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
 	"github.com/lengzhao/goscript/parser"
-	"github.com/lengzhao/goscript/vm"
 )
 
 func TestSwitchWithGoto(t *testing.T) {
@@ -0,0 +1,97 @@
+package test
+
+import "testing"
+
+func TestSwitchForms(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"tagless switch picks the first true case", `
+	x := 15
+	switch {
+	case x > 10:
+		return "big"
+	case x > 5:
+		return "medium"
+	default:
+		return "small"
+	}`, "big"},
+		{"tagless switch falls back to default", `
+	x := 1
+	switch {
+	case x > 10:
+		return "big"
+	case x > 5:
+		return "medium"
+	default:
+		return "small"
+	}`, "small"},
+		{"tagless switch with init", `
+	switch x := 7; {
+	case x > 10:
+		return 1
+	case x < 0, x > 5:
+		return 2
+	}
+	return 0`, 2},
+		{"multi-value cases", `
+	total := 0
+	for i := 0; i < 6; i++ {
+		switch i {
+		case 1, 2, 3:
+			total += 10
+		case 4, 5:
+			total += 100
+		default:
+			total += 1
+		}
+	}
+	return total`, 231},
+		{"multi-value string cases", `
+	switch s := "b"; s {
+	case "a", "b":
+		return "ab"
+	case "c":
+		return "c"
+	}
+	return ""`, "ab"},
+		{"later case values are not evaluated after a match", `
+	calls := 0
+	f := func(v int) int {
+		calls++
+		return v
+	}
+	switch 2 {
+	case f(1), f(2), f(3):
+	}
+	return calls`, 2},
+		{"tag is evaluated once", `
+	calls := 0
+	f := func() int {
+		calls++
+		return 3
+	}
+	switch f() {
+	case 1, 2:
+	case 3:
+	}
+	return calls`, 1},
+		{"empty switch", `
+	switch {
+	}
+	return 1`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runScript(t, tt.body, scriptSetup{})
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
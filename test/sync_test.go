@@ -0,0 +1,233 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/builtin"
+)
+
+func TestMutexProtectsSharedHostState(t *testing.T) {
+	source := []byte(`
+package main
+
+import "mutex"
+
+func main() {
+	mutex.Lock("store")
+	v := load()
+	store(v + 1)
+	mutex.Unlock("store")
+	return v
+}
+`)
+	// The store is deliberately unsynchronized on the host side; only the
+	// script mutex keeps the read-modify-write sequences apart
+	balance := 0
+	const runs = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		script := goscript.NewScript(source)
+		script.AddFunction("load", func(args ...interface{}) (interface{}, error) {
+			return balance, nil
+		})
+		script.AddFunction("store", func(args ...interface{}) (interface{}, error) {
+			balance = args[0].(int)
+			return nil, nil
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := script.Run(); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+
+	if balance != runs {
+		t.Errorf("Expected balance %d, got %d", runs, balance)
+	}
+}
+
+func TestMutexReleasedWhenExecutionEnds(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "mutex"
+
+func main() {
+	mutex.Lock("release-test")
+	return fail()
+}
+`))
+	script.AddFunction("fail", func(args ...interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	if _, err := script.Run(); err == nil {
+		t.Fatal("Expected the script to fail")
+	}
+
+	m := builtin.SharedSync.Mutex("release-test")
+	if !m.TryLock() {
+		t.Fatal("Expected the mutex to be released when the execution ended")
+	}
+	m.Unlock()
+}
+
+func TestMutexReleasedWhenFunctionReturns(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+import "mutex"
+
+func update() {
+	mutex.Lock("scope-test")
+	store(load() + 1)
+}
+
+func main() {
+	update()
+	return mutex.TryLock("scope-test")
+}
+`))
+	script.AddFunction("load", func(args ...interface{}) (interface{}, error) {
+		return 1, nil
+	})
+	script.AddFunction("store", func(args ...interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != true {
+		t.Errorf("Expected the mutex to be released when update returned, got %v", result)
+	}
+}
+
+func TestMutexLockHonorsTimeoutAndContext(t *testing.T) {
+	source := []byte(`
+package main
+
+import "mutex"
+
+func main() {
+	mutex.Lock("wait-test")
+	return 0
+}
+`)
+	m := builtin.SharedSync.Mutex("wait-test")
+	m.Lock()
+	defer m.Unlock()
+
+	script := goscript.NewScript(source)
+	script.SetTimeout(20 * time.Millisecond)
+	if _, err := script.Run(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the lock to time out, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	script = goscript.NewScript(source)
+	if _, err := script.RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the lock to be canceled, got %v", err)
+	}
+}
+
+func TestMutexMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"double lock", `mutex.Lock("misuse")
+	mutex.Lock("misuse")`},
+		{"unlock without lock", `mutex.Unlock("misuse")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := goscript.NewScript([]byte(`
+package main
+
+import "mutex"
+
+func main() {
+	` + tt.body + `
+	return 0
+}
+`))
+			if _, err := script.Run(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestAtomicCounters(t *testing.T) {
+	source := []byte(`
+package main
+
+import "atomic"
+
+func main() {
+	for i := 0; i < 10; i++ {
+		atomic.AddInt("hits", 1)
+	}
+	return atomic.LoadInt("hits")
+}
+`)
+	builtin.SharedSync.SetCounter("hits", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		script := goscript.NewScript(source)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := script.Run(); err != nil {
+				t.Errorf("Failed to run script: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := builtin.SharedSync.Counter("hits"); got != 80 {
+		t.Errorf("Expected 80 hits, got %d", got)
+	}
+
+	script := goscript.NewScript([]byte(`
+package main
+
+import "atomic"
+
+func main() {
+	atomic.StoreInt("version", 3)
+	if atomic.CompareAndSwapInt("version", 2, 9) {
+		return 100
+	}
+	if atomic.CompareAndSwapInt("version", 3, 4) {
+		return atomic.LoadInt("version")
+	}
+	return 200
+}
+`))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 4 {
+		t.Errorf("Expected 4, got %v", result)
+	}
+}
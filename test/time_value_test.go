@@ -0,0 +1,86 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestTimeValueComparisonAndArithmetic(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	start := startTime()
+	now := currentTime()
+	deadline := start + timeout()
+	if deadline > now {
+		return "ok " + (deadline - now).String()
+	}
+	return "expired"
+}
+`
+
+	start := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	script := goscript.NewScript([]byte(scriptSource))
+	addHostValue(script, "startTime", start)
+	addHostValue(script, "currentTime", start.Add(10*time.Second))
+	addHostValue(script, "timeout", time.Minute)
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "ok 50s" {
+		t.Errorf("Expected 'ok 50s', got %v", result)
+	}
+}
+
+func TestTimeValueMethods(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	start := startTime()
+	later := start.Add(timeout() * 2)
+	return later.Format("2006-01-02 15:04") + " " + later.Sub(start).String()
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	addHostValue(script, "startTime", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC))
+	addHostValue(script, "timeout", 30*time.Minute)
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "2024-01-02 16:04 1h0m0s" {
+		t.Errorf("Expected '2024-01-02 16:04 1h0m0s', got %v", result)
+	}
+}
+
+func TestTimeValueUnsupportedOperation(t *testing.T) {
+	scriptSource := `
+package main
+
+func main() {
+	return startTime() + startTime()
+}
+`
+
+	script := goscript.NewScript([]byte(scriptSource))
+	addHostValue(script, "startTime", time.Now())
+
+	if _, err := script.Run(); err == nil {
+		t.Error("Expected error when adding two times")
+	}
+}
+
+// addHostValue exposes a host value to the script through a function
+func addHostValue(script *goscript.Script, name string, value interface{}) {
+	script.AddFunction(name, func(args ...interface{}) (interface{}, error) {
+		return value, nil
+	})
+}
@@ -0,0 +1,204 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestIfInitCommaOkMapIndex(t *testing.T) {
+	source := `
+package main
+
+func main() {
+	m := map[string]int{"a": 1, "b": 0}
+	found := 0
+	if v, ok := m["a"]; ok {
+		found = found + v
+	}
+	if _, ok := m["b"]; ok {
+		found = found + 10
+	}
+	if _, ok := m["c"]; ok {
+		found = found + 100
+	}
+	return found
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 11 {
+		t.Errorf("Expected 11, got %v", result)
+	}
+}
+
+func TestSwitchInitStatement(t *testing.T) {
+	source := `
+package main
+
+func main() {
+	n := 100
+	switch n := 2; n {
+	case 1:
+		return "one"
+	case 2:
+		return "two"
+	}
+	return n
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != "two" {
+		t.Errorf("Expected two, got %v", result)
+	}
+}
+
+func TestTypeAssertion(t *testing.T) {
+	source := `
+package main
+
+func main() {
+	var x interface{} = 42
+	n := x.(int)
+	s, ok := x.(string)
+	if ok {
+		return "unexpected"
+	}
+	if s != "" {
+		return "expected the zero value"
+	}
+	return n + 1
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 43 {
+		t.Errorf("Expected 43, got %v", result)
+	}
+}
+
+func TestTypeAssertionFailure(t *testing.T) {
+	source := `
+package main
+
+func main() {
+	var x interface{} = 42
+	return x.(string)
+}
+`
+	_, err := goscript.NewScript([]byte(source)).Run()
+	if err == nil {
+		t.Fatal("Expected a failed type assertion to return an error")
+	}
+	if !strings.Contains(err.Error(), "interface conversion: interface {} is int, not string") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestTypeSwitch(t *testing.T) {
+	source := `
+package main
+
+type Shape interface {
+	Area() float64
+}
+
+type Rect struct {
+	W float64
+	H float64
+}
+
+func (r Rect) Area() float64 {
+	return r.W * r.H
+}
+
+type Point struct {
+	X int
+}
+
+func describe(x interface{}) interface{} {
+	switch v := x.(type) {
+	case nil:
+		return "nil"
+	case int:
+		return v + 1
+	case string, bool:
+		return "string or bool"
+	case []int:
+		return len(v)
+	case map[string]int:
+		return "map"
+	case Shape:
+		return v.Area()
+	case Point:
+		return v.X
+	default:
+		return "other"
+	}
+}
+
+func main() {
+	results := []interface{}{
+		describe(nil),
+		describe(1),
+		describe("a"),
+		describe(true),
+		describe([]int{1, 2}),
+		describe(map[string]int{"a": 1}),
+		describe(Rect{W: 2.5, H: 3}),
+		describe(Point{X: 7}),
+		describe(1.5),
+	}
+	return results
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []interface{}{"nil", 2, "string or bool", "string or bool", 2, "map", 7.5, 7, "other"}
+	results, ok := result.([]interface{})
+	if !ok || len(results) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("Case %d: expected %v, got %v", i, expected[i], results[i])
+		}
+	}
+}
+
+func TestTypeSwitchBreakAndInit(t *testing.T) {
+	source := `
+package main
+
+func main() {
+	count := 0
+	for i := 0; i < 3; i++ {
+		switch x := i; x.(type) {
+		case int:
+			if i == 1 {
+				break
+			}
+			count = count + 1
+		}
+	}
+	return count
+}
+`
+	result, err := goscript.NewScript([]byte(source)).Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 2 {
+		t.Errorf("Expected 2, got %v", result)
+	}
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/internal/compiler"
+	"github.com/lengzhao/goscript/internal/vm"
+	"github.com/lengzhao/goscript/parser"
+)
+
+func TestUniverseBuiltinsWithoutHostSetup(t *testing.T) {
+	script := `
+package main
+
+func main() {
+	items := []int{4, 8, 15}
+	println("items:", len(items))
+	print("capacity", cap(items))
+	return min(9, 3, 7) + max(len("abc"), 2) + max(items) + cap(items)
+}
+`
+	astFile, err := parser.New().Parse("test.go", []byte(script), 0)
+	if err != nil {
+		t.Fatalf("Failed to parse script: %v", err)
+	}
+
+	// A bare VM, without the registration done by goscript.NewScript
+	vmInstance := vm.NewVM()
+	if err := compiler.NewCompiler(vmInstance).Compile(astFile); err != nil {
+		t.Fatalf("Failed to compile script: %v", err)
+	}
+	result, err := vmInstance.Execute("")
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if result != 3+3+15+3 {
+		t.Errorf("Expected %d, got %v", 3+3+15+3, result)
+	}
+}
+
+func TestUniverseBuiltinArity(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		errMsg string
+	}{
+		{"len without arguments", `
+func main() {
+	return len()
+}`, "not enough arguments in call to len"},
+		{"cap with two arguments", `
+func main() {
+	s := []int{1}
+	return cap(s, s)
+}`, "too many arguments in call to cap"},
+		{"min without arguments", `
+func main() {
+	return min()
+}`, "not enough arguments in call to min"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := goscript.NewScript([]byte("package main\n" + tt.source)).Compile()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+
+	// Script functions with the same name are not checked
+	script := goscript.NewScript([]byte(`
+package main
+
+func max() int {
+	return 42
+}
+
+func main() {
+	return max()
+}
+`))
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 42 {
+		t.Errorf("Expected 42, got %v", result)
+	}
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+)
+
+func TestVariadicFunctions(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+type Bag struct {
+	total int
+}
+
+func (b *Bag) Add(values ...int) {
+	for _, v := range values {
+		b.total += v
+	}
+}
+
+func sum(values ...int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func join(sep string, parts ...string) string {
+	result := ""
+	for i, p := range parts {
+		if i > 0 {
+			result += sep
+		}
+		result += p
+	}
+	return result
+}
+
+func count(values ...int) int {
+	return len(values)
+}
+
+func main() {
+	nums := []int{4, 5, 6}
+	max := func(first int, rest ...int) int {
+		m := first
+		for _, v := range rest {
+			if v > m {
+				m = v
+			}
+		}
+		return m
+	}
+	b := &Bag{total: 0}
+	b.Add(1, 2)
+	b.Add(nums...)
+	return []interface{}{
+		sum(),
+		sum(1, 2, 3),
+		sum(nums...),
+		join("-", "a", "b", "c"),
+		join(","),
+		count(nums...),
+		max(3, 9, 2),
+		max(1, nums...),
+		b.total,
+		describe(nums...),
+		describe(1, 2),
+	}
+}
+`))
+	if err := script.AddFunction("describe", func(args ...interface{}) (interface{}, error) {
+		return fmt.Sprintf("%d:%v", len(args), args), nil
+	}); err != nil {
+		t.Fatalf("Failed to add function: %v", err)
+	}
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []interface{}{0, 6, 15, "a-b-c", "", 3, 9, 6, 18, "3:[4 5 6]", "2:[1 2]"}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("Value %d: expected %v, got %v", i, expected[i], values[i])
+		}
+	}
+}
+
+func TestVariadicCallFromHost(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func total(scale int, values ...int) int {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return sum * scale
+}
+
+func main() {
+	return 0
+}
+`))
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	result, err := script.CallFunction("total", 2, 1, 2, 3)
+	if err != nil {
+		t.Fatalf("Failed to call function: %v", err)
+	}
+	if result != 12 {
+		t.Errorf("Expected 12, got %v", result)
+	}
+	if result, err = script.CallFunction("total", 3); err != nil || result != 0 {
+		t.Errorf("Expected 0, got %v (%v)", result, err)
+	}
+	if _, err = script.CallFunction("total"); err == nil || !strings.Contains(err.Error(), "not enough arguments") {
+		t.Errorf("Expected a not enough arguments error, got %v", err)
+	}
+}
+
+func TestSpreadNonSlice(t *testing.T) {
+	_, err := runScript(t, `
+	f := func(values ...int) int {
+		return len(values)
+	}
+	n := 3
+	return f(n...)`, scriptSetup{})
+	if err == nil || !strings.Contains(err.Error(), "as variadic arguments") {
+		t.Errorf("Expected a variadic arguments error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lengzhao/goscript"
+	"github.com/lengzhao/goscript/internal/vm"
+)
+
+const watchSource = `
+package main
+
+func main() {
+	total := 0
+	for i := 1; i <= 4; i++ {
+		total = total + i
+	}
+	return total
+}
+`
+
+func TestWatchValueChanges(t *testing.T) {
+	script := goscript.NewScript([]byte(watchSource))
+	if _, err := script.AddWatch("total"); err != nil {
+		t.Fatalf("Failed to add watch: %v", err)
+	}
+
+	var values []interface{}
+	script.SetWatchHandler(func(event vm.WatchEvent) error {
+		values = append(values, event.New)
+		return nil
+	})
+
+	result, err := script.Run()
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	if result != 10 {
+		t.Errorf("Expected 10, got %v", result)
+	}
+	if !reflect.DeepEqual(values, []interface{}{0, 1, 3, 6, 10}) {
+		t.Errorf("Expected changes [0 1 3 6 10], got %v", values)
+	}
+}
+
+func TestWatchInPlaceChanges(t *testing.T) {
+	script := goscript.NewScript([]byte(`
+package main
+
+func main() {
+	items := []int{1, 2}
+	items[0] = 5
+	counts := map[string]int{"a": 1}
+	counts["a"] = 2
+	return len(items)
+}
+`))
+	for _, expr := range []string{"items", "counts"} {
+		if _, err := script.AddWatch(expr); err != nil {
+			t.Fatalf("Failed to add watch: %v", err)
+		}
+	}
+
+	var changes []string
+	script.SetWatchHandler(func(event vm.WatchEvent) error {
+		if event.Old != nil {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", event.Expr, event.Old, event.New))
+		}
+		return nil
+	})
+	if _, err := script.Run(); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+	expected := []string{"items: [1 2] -> [5 2]", "counts: map[a:1] -> map[a:2]"}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected in-place changes %v, got %v", expected, changes)
+	}
+}
+
+func TestWatchConditionStopsExecution(t *testing.T) {
+	script := goscript.NewScript([]byte(watchSource))
+	if _, err := script.AddWatchCondition("total > 5"); err != nil {
+		t.Fatalf("Failed to add watch: %v", err)
+	}
+
+	breakpoint := errors.New("breakpoint")
+	var seen interface{}
+	script.SetWatchHandler(func(event vm.WatchEvent) error {
+		seen = event.New
+		return breakpoint
+	})
+
+	_, err := script.Run()
+	if !errors.Is(err, breakpoint) {
+		t.Fatalf("Expected breakpoint error, got %v", err)
+	}
+	if seen != true {
+		t.Errorf("Expected condition value true, got %v", seen)
+	}
+	if count := script.GetVM().GetInstructionCount(); count == 0 {
+		t.Error("Expected instructions to have run before the breakpoint")
+	}
+}
+
+func TestWatchRejectsCalls(t *testing.T) {
+	script := goscript.NewScript([]byte(watchSource))
+	_, err := script.AddWatch("len(total)")
+	if err == nil || !strings.Contains(err.Error(), "function calls are not allowed") {
+		t.Errorf("Expected function call error, got %v", err)
+	}
+}
@@ -2,15 +2,177 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 // ModuleExecutor defines the interface for executing module entry points
 type ModuleExecutor func(entrypoint string, args ...interface{}) (interface{}, error)
 
+// ModuleInstance is a module with per-execution state (e.g., a seeded
+// random source or an HTTP client). The VM creates one instance per
+// execution on first use, calls Init before the first Call and Close when
+// the execution finishes.
+type ModuleInstance interface {
+	// Init prepares the instance for use
+	Init() error
+
+	// Call executes an entry point of the module
+	Call(entrypoint string, args ...interface{}) (interface{}, error)
+
+	// Close releases the resources held by the instance
+	Close() error
+}
+
+// ModuleFactory creates a new ModuleInstance for an execution
+type ModuleFactory func() ModuleInstance
+
+// NumberMode controls how numbers are converted when they cross the
+// script/host boundary and when the json module decodes them
+type NumberMode int
+
+const (
+	// NumberFloat64 keeps the legacy behavior: JSON numbers decode to
+	// float64 and host values are passed through unchanged
+	NumberFloat64 NumberMode = iota
+
+	// NumberPreserveInt decodes integral JSON numbers to int and converts
+	// host integer types (int64, uint32, ...) to int when they fit
+	NumberPreserveInt
+
+	// NumberDecimalString behaves like NumberPreserveInt, but integers that
+	// do not fit in int and non-integral JSON numbers become their exact
+	// decimal string instead of a lossy float64
+	NumberDecimalString
+)
+
+// Struct is a script struct value: the name of its type and its fields.
+// The type is kept out of the field map, so a field may have any name and
+// only the fields are marshaled to JSON.
+//
+// A pointer to a struct is a Struct with Pointer set that shares the field
+// map of the struct it points to, so writes through the pointer update the
+// struct and two pointers are equal when they share their fields.
+type Struct struct {
+	Type    string
+	Fields  map[string]interface{}
+	Pointer bool
+}
+
+// NewStruct creates a struct value of the given type without fields
+func NewStruct(typeName string) *Struct {
+	return &Struct{Type: typeName, Fields: make(map[string]interface{})}
+}
+
+// Copy returns a copy of the struct value, as assignment makes in Go:
+// struct fields are copied in turn, while pointers, slices and maps are
+// shared. The copy of a pointer is a copy of the struct it points to.
+func (s *Struct) Copy() *Struct {
+	fields := make(map[string]interface{}, len(s.Fields))
+	for name, value := range s.Fields {
+		if field, ok := value.(*Struct); ok && !field.Pointer {
+			value = field.Copy()
+		}
+		fields[name] = value
+	}
+	return &Struct{Type: s.Type, Fields: fields}
+}
+
+// Addr returns a pointer to the struct
+func (s *Struct) Addr() *Struct {
+	return &Struct{Type: s.Type, Fields: s.Fields, Pointer: true}
+}
+
+// Elem returns the struct a pointer points to, sharing its fields
+func (s *Struct) Elem() *Struct {
+	return &Struct{Type: s.Type, Fields: s.Fields}
+}
+
+// Same reports whether two structs share their fields, i.e. whether they
+// are the same struct
+func (s *Struct) Same(other *Struct) bool {
+	return reflect.ValueOf(s.Fields).UnsafePointer() == reflect.ValueOf(other.Fields).UnsafePointer()
+}
+
+// String formats the struct as TypeName{field: value, ...} with the fields
+// sorted by name
+func (s *Struct) String() string {
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(s.Type + "{")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s: %v", name, s.Fields[name])
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// MarshalJSON marshals the fields of the struct
+func (s *Struct) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Fields)
+}
+
+// StructField is a field of a declared script struct type
+type StructField struct {
+	// Field name; the type name for embedded fields
+	Name string
+
+	// Type of the field as declared, e.g., "int" or "[]Item"; the type
+	// name of an embedded field, without "*"
+	Type string
+
+	// Tag of the field without its quotes, e.g., json:"name,omitempty"
+	Tag string
+
+	// Whether the field is embedded
+	Embedded bool
+}
+
+// StructTypes looks up the fields of the declared script struct types, in
+// declaration order
+type StructTypes func(typeName string) ([]StructField, bool)
+
+// StructTypeName returns the type name of a script struct value
+func StructTypeName(value interface{}) (string, bool) {
+	if s, ok := value.(*Struct); ok {
+		return s.Type, true
+	}
+	return "", false
+}
+
+// StructFields returns the fields of a script struct value, or the map
+// itself for a map value, which scripts access with the same syntax
+func StructFields(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case *Struct:
+		return v.Fields, true
+	case map[string]interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
 // Function represents a callable function
 type Function func(args ...interface{}) (interface{}, error)
 
+// Callable is a function value of a script (e.g., a function literal)
+// passed to a host function, which can call back into the script with it
+// while it runs
+type Callable interface {
+	Call(args ...interface{}) (interface{}, error)
+}
+
 // Method represents a method signature
 type Method struct {
 	Name    string